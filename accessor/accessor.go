@@ -0,0 +1,257 @@
+// Package accessor generates exported getter and setter methods for the
+// unexported fields of struct types.
+//
+// Fields are selected by directives in their doc or line comments:
+//
+//	name  string   //accessor:get
+//	tags  []string //accessor:getset copy
+//	limit int      //accessor:getset name=MaxItems
+//
+// A get directive makes the field read-only, with a getter named by the
+// field, such as Name for a field name. A getset directive adds a setter
+// prefixed with Set, such as SetName. The name argument gives the name used
+// in place of the field's. The copy argument applies to slice and map fields:
+// the getter returns a copy of the field and the setter stores a copy of its
+// argument, so that callers cannot modify the field through the shared
+// backing array or map. Fields without a directive have no accessors.
+package accessor
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/iand/gen"
+)
+
+// Prefix is the prefix of the directives that select fields.
+const Prefix = "accessor:"
+
+// field is a struct field selected by a directive.
+type field struct {
+	*gen.FieldModel
+	name   string // the exported name used by the accessors
+	setter bool
+	copy   bool
+}
+
+// Generate writes accessors to o for the fields of each of the named struct
+// types that have accessor directives, or for every struct type declared in
+// fs with such fields if no names are given. It returns an error if a named
+// type has no selected fields, a directive is applied to an exported field or
+// an accessor would conflict with a field or method of the type.
+func Generate(fs *gen.FileSet, o *gen.Output, typeNames ...string) error {
+	var named []*types.Named
+	if len(typeNames) == 0 {
+		for _, tm := range fs.Types() {
+			n, ok := tm.Object.Type().(*types.Named)
+			if !ok || tm.Object.IsAlias() {
+				continue
+			}
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				named = append(named, n)
+			}
+		}
+	} else {
+		for _, name := range typeNames {
+			tn, ok := fs.Lookup(name).(*types.TypeName)
+			if !ok {
+				return fmt.Errorf("type %s not found", name)
+			}
+			n, ok := tn.Type().(*types.Named)
+			if !ok || tn.IsAlias() {
+				return fmt.Errorf("%s is not a named type", name)
+			}
+			if _, ok := n.Underlying().(*types.Struct); !ok {
+				return fmt.Errorf("%s is not a struct type", name)
+			}
+			named = append(named, n)
+		}
+	}
+
+	imports := gen.NewImports()
+	qual := imports.Qualifier(fs.Package)
+	var body bytes.Buffer
+	for _, n := range named {
+		fields, err := selectFields(fs, n)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			if len(typeNames) > 0 {
+				return fmt.Errorf("no fields of %s have accessor directives", n.Obj().Name())
+			}
+			continue
+		}
+		for _, f := range fields {
+			writeAccessors(&body, imports, qual, n, f)
+		}
+	}
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	if imports.Len() > 0 {
+		o.Printf("%s\n", imports.Block())
+	}
+	o.Write(body.Bytes())
+	return nil
+}
+
+// selectFields returns the fields of n that have accessor directives.
+func selectFields(fs *gen.FileSet, n *types.Named) ([]*field, error) {
+	typeName := n.Obj().Name()
+	models, err := fs.FieldsOf(typeName)
+	if err != nil {
+		return nil, err
+	}
+	var fields []*field
+	for _, fm := range models {
+		d, ok := directive(fm)
+		if !ok {
+			continue
+		}
+		if fm.Exported {
+			return nil, fmt.Errorf("%s.%s: accessor directive on exported field", typeName, fm.Name)
+		}
+		f := &field{FieldModel: fm, name: gen.PascalCase(fm.Name)}
+		switch d.Name {
+		case "get":
+		case "getset":
+			f.setter = true
+		default:
+			return nil, fmt.Errorf("%s.%s: unknown directive %s%s", typeName, fm.Name, Prefix, d.Name)
+		}
+		if name, ok := d.Arg("name"); ok {
+			if !token.IsIdentifier(name) || !token.IsExported(name) {
+				return nil, fmt.Errorf("%s.%s: invalid accessor name %q", typeName, fm.Name, name)
+			}
+			f.name = name
+		}
+		if _, ok := d.Arg("copy"); ok {
+			switch fm.Type.Underlying().(type) {
+			case *types.Slice, *types.Map:
+				f.copy = true
+			default:
+				return nil, fmt.Errorf("%s.%s: copy applies only to slices and maps", typeName, fm.Name)
+			}
+		}
+
+		names := []string{f.name}
+		if f.setter {
+			names = append(names, "Set"+f.name)
+		}
+		for _, name := range names {
+			if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(n), true, n.Obj().Pkg(), name); obj != nil {
+				return nil, fmt.Errorf("%s.%s: accessor %s conflicts with %s", typeName, fm.Name, name, obj)
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// directive returns the accessor directive of the field.
+func directive(f *gen.FieldModel) (*gen.Directive, bool) {
+	if f.Field == nil {
+		return nil, false
+	}
+	for _, cg := range []*ast.CommentGroup{f.Field.Doc, f.Field.Comment} {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			if d, ok := gen.ParseDirective(Prefix, c.Text); ok {
+				return d, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// writeAccessors writes the getter and any setter of f, a field of n, to w.
+func writeAccessors(w *bytes.Buffer, imports *gen.Imports, qual types.Qualifier, n *types.Named, f *field) {
+	typeName := n.Obj().Name()
+	recv := gen.ReceiverName(typeName)
+	recvType := typeName
+	if tparams := n.TypeParams(); tparams.Len() > 0 {
+		names := make([]string, tparams.Len())
+		for i := range names {
+			names[i] = tparams.At(i).Obj().Name()
+		}
+		recvType += "[" + strings.Join(names, ", ") + "]"
+	}
+	typ := types.TypeString(f.Type, qual)
+	value := recv + "." + f.Name
+	if f.copy {
+		value = cloneExpr(imports, value, f.Type)
+	}
+
+	fmt.Fprintf(w, "\n// %s returns the %s of the %s.", f.name, fieldNoun(f), typeName)
+	if f.copy {
+		w.WriteString(" The result is a copy that may be modified freely.")
+	}
+	w.WriteString("\n")
+	writeDoc(w, f)
+	fmt.Fprintf(w, "func (%s *%s) %s() %s {\n\treturn %s\n}\n", recv, recvType, f.name, typ, value)
+
+	if !f.setter {
+		return
+	}
+	param := gen.CamelCase(f.name)
+	if param == "" || token.IsKeyword(param) || param == recv || types.Universe.Lookup(param) != nil {
+		param = "v"
+		if recv == "v" {
+			param = "value"
+		}
+	}
+	value = param
+	if f.copy {
+		value = cloneExpr(imports, param, f.Type)
+	}
+	fmt.Fprintf(w, "\n// Set%s sets the %s of the %s.", f.name, fieldNoun(f), typeName)
+	if f.copy {
+		fmt.Fprintf(w, " The %s stores a copy of %s.", typeName, param)
+	}
+	w.WriteString("\n")
+	writeDoc(w, f)
+	fmt.Fprintf(w, "func (%s *%s) Set%s(%s %s) {\n\t%s.%s = %s\n}\n", recv, recvType, f.name, param, typ, recv, f.Name, value)
+}
+
+// fieldNoun describes the field f in a doc comment, using the words of its
+// accessor name in lower case, such as max items for MaxItems.
+func fieldNoun(f *field) string {
+	words := gen.Words(f.name)
+	for i, w := range words {
+		if strings.ToUpper(w) != w {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// writeDoc writes the doc comment of the field f, if it has one, as a
+// further paragraph of an accessor's doc comment.
+func writeDoc(w *bytes.Buffer, f *field) {
+	doc := f.Doc
+	if doc == "" {
+		doc = f.Comment
+	}
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	w.WriteString("//\n")
+	for _, line := range strings.Split(doc, "\n") {
+		w.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+}
+
+// cloneExpr returns an expression that copies the slice or map x of type t.
+func cloneExpr(imports *gen.Imports, x string, t types.Type) string {
+	if _, ok := t.Underlying().(*types.Map); ok {
+		return imports.Add("maps", "") + ".Clone(" + x + ")"
+	}
+	return imports.Add("slices", "") + ".Clone(" + x + ")"
+}
@@ -0,0 +1,169 @@
+package accessor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+type Account struct {
+	// id identifies the account.
+	id int64 //accessor:get
+
+	owner string //accessor:getset
+
+	tags []string //accessor:getset copy
+
+	limits map[string]int //accessor:get copy
+
+	maxItems int //accessor:getset name=Limit
+
+	internal bool
+}
+
+type Stack[T any] struct {
+	items []T //accessor:get copy
+}
+
+type Plain struct {
+	n int
+}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("accessor")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"// ID returns the ID of the Account.\n//\n// id identifies the account.\nfunc (a *Account) ID() int64 {\n\treturn a.id\n}",
+		"func (a *Account) Owner() string {",
+		"func (a *Account) SetOwner(owner string) {\n\ta.owner = owner\n}",
+		"func (a *Account) Tags() []string {\n\treturn slices.Clone(a.tags)\n}",
+		"func (a *Account) SetTags(tags []string) {\n\ta.tags = slices.Clone(tags)\n}",
+		"func (a *Account) Limits() map[string]int {\n\treturn maps.Clone(a.limits)\n}",
+		"func (a *Account) Limit() int {\n\treturn a.maxItems\n}",
+		"func (a *Account) SetLimit(limit int) {",
+		"func (s *Stack[T]) Items() []T {",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	for _, unwanted := range []string{"SetID", "SetLimits", "Internal", "Plain"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated source contains %q:\n%s", unwanted, src)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+	}{
+		{name: "missing", src: "package p\n"},
+		{name: "not struct", src: "package p\ntype T int\n"},
+		{name: "no directives", src: "package p\ntype T struct{ v int }\n"},
+		{name: "exported", src: "package p\ntype T struct{\n\tV int //accessor:get\n}\n"},
+		{name: "unknown", src: "package p\ntype T struct{\n\tv int //accessor:set\n}\n"},
+		{name: "copy scalar", src: "package p\ntype T struct{\n\tv int //accessor:get copy\n}\n"},
+		{name: "bad name", src: "package p\ntype T struct{\n\tv int //accessor:get name=lower\n}\n"},
+		{name: "conflict", src: "package p\ntype T struct{\n\tv int //accessor:getset\n}\nfunc (T) SetV(int) {}\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := gen.NewFileSetFromTexts(tc.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := Generate(fs, gen.NewOutput("accessor"), "T"); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+const testProgram = `package p
+
+import "testing"
+
+func TestAccessors(t *testing.T) {
+	var a Account
+	tags := []string{"x", "y"}
+	a.SetTags(tags)
+	tags[0] = "changed"
+	if got := a.Tags(); got[0] != "x" {
+		t.Errorf("setter did not copy: got %v", got)
+	}
+	a.Tags()[1] = "changed"
+	if a.tags[1] != "y" {
+		t.Errorf("getter did not copy: got %v", a.tags)
+	}
+	a.SetOwner("ann")
+	a.SetLimit(3)
+	if a.Owner() != "ann" || a.Limit() != 3 || a.ID() != 0 || a.Limits() != nil {
+		t.Errorf("got %+v", a)
+	}
+	s := Stack[int]{items: []int{1}}
+	if s.Items()[0] != 1 {
+		t.Errorf("got %v", s.Items())
+	}
+}
+`
+
+func TestGeneratedAccessors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.21\n",
+		"p.go":      testSrc,
+		"p_test.go": testProgram,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "p.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("accessor")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "accessor_gen.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated accessors failed: %v\n%s\n%s", err, out, src)
+	}
+}
@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// FileSetFromPass returns a FileSet for the package analyzed by pass, so
+// that code written against the traversals and models of a FileSet can be
+// used in an analyzer run by a go/analysis driver such as go vet. The
+// FileSet shares the syntax trees and type information of the pass; nothing
+// is parsed or type checked again. The FileSet's options are the defaults,
+// so imports needed by lookups of other packages, such as
+// FileSet.LookupPath, are resolved with the ImportAuto mode.
+func FileSetFromPass(pass *analysis.Pass) *FileSet {
+	fs := &FileSet{
+		Dir:      currentDir,
+		FileSet:  pass.Fset,
+		AstFiles: pass.Files,
+		TypeInfo: pass.TypesInfo,
+		Package:  pass.Pkg,
+		pkgPath:  pass.Pkg.Path(),
+	}
+	for _, f := range pass.Files {
+		fs.Files = append(fs.Files, pass.Fset.File(f.Pos()).Name())
+	}
+	if len(fs.Files) > 0 {
+		fs.Dir = filepath.Dir(fs.Files[0])
+	}
+	return fs
+}
+
+// RunAnalyzer runs the analyzer a on the package in fs, after the analyzers
+// it requires, and returns the diagnostics it reports in the order they were
+// reported. It lets analyzers written for go vet be used by a generator,
+// for example to check its input, without loading the package again.
+//
+// Facts are supported only within the package: an analyzer can import the
+// facts it exported about the package and its objects, but facts about
+// imported packages are never available. If fs has errors, because it was
+// loaded with WithLenient, RunAnalyzer returns an error unless a and the
+// analyzers it requires have RunDespiteErrors set.
+func (fs *FileSet) RunAnalyzer(a *analysis.Analyzer) ([]analysis.Diagnostic, error) {
+	if err := analysis.Validate([]*analysis.Analyzer{a}); err != nil {
+		return nil, err
+	}
+	var typeErrors []types.Error
+	for _, e := range fs.Errors {
+		var te types.Error
+		if errors.As(e, &te) {
+			typeErrors = append(typeErrors, te)
+		}
+	}
+
+	r := &analysisRun{
+		fs:         fs,
+		root:       a,
+		typeErrors: typeErrors,
+		results:    make(map[*analysis.Analyzer]any),
+		facts:      make(map[analysisFactKey]analysis.Fact),
+	}
+	if _, err := r.run(a); err != nil {
+		return nil, err
+	}
+	return r.diagnostics, nil
+}
+
+// analysisRun holds the state of a RunAnalyzer call.
+type analysisRun struct {
+	fs          *FileSet
+	root        *analysis.Analyzer
+	typeErrors  []types.Error
+	results     map[*analysis.Analyzer]any
+	facts       map[analysisFactKey]analysis.Fact
+	diagnostics []analysis.Diagnostic
+}
+
+// analysisFactKey identifies a fact of a given type about an object, or
+// about the package if obj is nil.
+type analysisFactKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+// run runs a, after the analyzers it requires, and returns its result. Only
+// the diagnostics of the analyzer passed to RunAnalyzer are kept.
+func (r *analysisRun) run(a *analysis.Analyzer) (any, error) {
+	if result, ok := r.results[a]; ok {
+		return result, nil
+	}
+	for _, req := range a.Requires {
+		if _, err := r.run(req); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.fs.Errors) > 0 && !a.RunDespiteErrors {
+		return nil, fmt.Errorf("analyzer %s: package has errors: %w", a.Name, r.fs.Errors)
+	}
+
+	fs := r.fs
+	pass := &analysis.Pass{
+		Analyzer:   a,
+		Fset:       fs.FileSet,
+		Files:      fs.AstFiles,
+		Pkg:        fs.Package,
+		TypesInfo:  fs.TypeInfo,
+		TypesSizes: types.SizesFor("gc", fs.opts.buildContext().GOARCH),
+		TypeErrors: r.typeErrors,
+		ResultOf:   make(map[*analysis.Analyzer]any, len(a.Requires)),
+		Report: func(d analysis.Diagnostic) {
+			if a == r.root {
+				r.diagnostics = append(r.diagnostics, d)
+			}
+		},
+		ReadFile: func(filename string) ([]byte, error) {
+			if src, ok := fs.contents[filename]; ok {
+				return src, nil
+			}
+			return fs.readFile(filename)
+		},
+		ImportObjectFact:  func(obj types.Object, fact analysis.Fact) bool { return r.importFact(obj, fact) },
+		ImportPackageFact: func(pkg *types.Package, fact analysis.Fact) bool { return pkg == fs.Package && r.importFact(nil, fact) },
+		ExportObjectFact:  func(obj types.Object, fact analysis.Fact) { r.exportFact(obj, fact) },
+		ExportPackageFact: func(fact analysis.Fact) { r.exportFact(nil, fact) },
+		AllObjectFacts:    func() []analysis.ObjectFact { return r.objectFacts(a) },
+		AllPackageFacts:   func() []analysis.PackageFact { return r.packageFacts(a) },
+	}
+	for _, req := range a.Requires {
+		pass.ResultOf[req] = r.results[req]
+	}
+	result, err := a.Run(pass)
+	if err != nil {
+		return nil, fmt.Errorf("analyzer %s: %w", a.Name, err)
+	}
+	r.results[a] = result
+	return result, nil
+}
+
+// importFact copies the stored fact of the type of fact about obj into fact,
+// reporting whether there is one.
+func (r *analysisRun) importFact(obj types.Object, fact analysis.Fact) bool {
+	stored, ok := r.facts[analysisFactKey{obj: obj, typ: reflect.TypeOf(fact)}]
+	if ok {
+		reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(stored).Elem())
+	}
+	return ok
+}
+
+// exportFact stores fact about obj, which must belong to the package.
+func (r *analysisRun) exportFact(obj types.Object, fact analysis.Fact) {
+	if obj != nil && obj.Pkg() != r.fs.Package {
+		panic(fmt.Sprintf("fact about %s exported by analysis of package %s", obj, r.fs.Package.Path()))
+	}
+	r.facts[analysisFactKey{obj: obj, typ: reflect.TypeOf(fact)}] = fact
+}
+
+// objectFacts returns the facts about objects of the types used by a.
+func (r *analysisRun) objectFacts(a *analysis.Analyzer) []analysis.ObjectFact {
+	var facts []analysis.ObjectFact
+	for key, fact := range r.facts {
+		if key.obj != nil && usesFact(a, key.typ) {
+			facts = append(facts, analysis.ObjectFact{Object: key.obj, Fact: fact})
+		}
+	}
+	return facts
+}
+
+// packageFacts returns the facts about the package of the types used by a.
+func (r *analysisRun) packageFacts(a *analysis.Analyzer) []analysis.PackageFact {
+	var facts []analysis.PackageFact
+	for key, fact := range r.facts {
+		if key.obj == nil && usesFact(a, key.typ) {
+			facts = append(facts, analysis.PackageFact{Package: r.fs.Package, Fact: fact})
+		}
+	}
+	return facts
+}
+
+// usesFact reports whether typ is one of the fact types of a.
+func usesFact(a *analysis.Analyzer, typ reflect.Type) bool {
+	for _, f := range a.FactTypes {
+		if reflect.TypeOf(f) == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package gen
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// exportedFact records that a function is exported.
+type exportedFact struct{}
+
+func (*exportedFact) AFact()         {}
+func (*exportedFact) String() string { return "exported" }
+
+// undocumented reports the exported functions without doc comments, using
+// the result of the inspect analyzer and a fact exported for each exported
+// function.
+var undocumented = &analysis.Analyzer{
+	Name:      "undocumented",
+	Doc:       "report undocumented exported functions",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	FactTypes: []analysis.Fact{new(exportedFact)},
+	Run: func(pass *analysis.Pass) (any, error) {
+		insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+			decl := n.(*ast.FuncDecl)
+			if obj := pass.TypesInfo.Defs[decl.Name]; obj.Exported() {
+				pass.ExportObjectFact(obj, new(exportedFact))
+			}
+		})
+		insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+			decl := n.(*ast.FuncDecl)
+			if pass.ImportObjectFact(pass.TypesInfo.Defs[decl.Name], new(exportedFact)) && decl.Doc == nil {
+				pass.Reportf(decl.Pos(), "%s is undocumented", decl.Name.Name)
+			}
+		})
+		return len(pass.AllObjectFacts()), nil
+	},
+}
+
+func TestRunAnalyzer(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+// A is documented.
+func A() {}
+
+func B() {}
+
+func c() {}
+
+func D() {}
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	diags, err := fs.RunAnalyzer(undocumented)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	if want := []string{"B is undocumented", "D is undocumented"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n\nvar X = undefined\n",
+	})
+	lenient, err := FileSetFromDir(dir, WithLenient(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := lenient.RunAnalyzer(undocumented); err == nil {
+		t.Errorf("got no error for a package with errors, wanted one")
+	}
+}
+
+func TestFileSetFromPass(t *testing.T) {
+	fs, err := NewFileSetFromTexts("package p\n\ntype Color int\n\ntype Size struct{ W, H int }\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	var shared bool
+	a := &analysis.Analyzer{
+		Name: "types",
+		Doc:  "list the types of the package",
+		Run: func(pass *analysis.Pass) (any, error) {
+			pfs := FileSetFromPass(pass)
+			shared = pfs.TypeInfo == fs.TypeInfo && len(pfs.AstFiles) == len(fs.AstFiles)
+			for _, tm := range pfs.Types() {
+				names = append(names, tm.Name)
+			}
+			return nil, nil
+		},
+	}
+	if _, err := fs.RunAnalyzer(a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !shared {
+		t.Errorf("FileSet from pass does not share the syntax and type information of the pass")
+	}
+	if want := []string{"Color", "Size"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got types %v, wanted %v", names, want)
+	}
+}
@@ -0,0 +1,221 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+)
+
+// API describes the exported API surface of a package as a set of symbols,
+// each with a description of its declaration. Two APIs may be compared with
+// DiffAPI to find the symbols that were added, removed or changed. An API
+// can be saved as a snapshot with WriteJSON and read back with ReadAPI, so
+// that the API of a release can be compared with the current source, for
+// example in CI.
+type API struct {
+	// Package is the name of the package.
+	Package string `json:"package"`
+
+	// Symbols maps the name of each exported symbol to a description of its
+	// declaration. Package level declarations are named by their identifier.
+	// Methods and struct fields are named by the type and member name
+	// separated by a dot, such as "Client.Do".
+	Symbols map[string]string `json:"symbols"`
+}
+
+// ReadAPI reads an API snapshot written by API.WriteJSON from r.
+func ReadAPI(r io.Reader) (*API, error) {
+	var api API
+	if err := json.NewDecoder(r).Decode(&api); err != nil {
+		return nil, fmt.Errorf("read API: %w", err)
+	}
+	if api.Symbols == nil {
+		api.Symbols = make(map[string]string)
+	}
+	return &api, nil
+}
+
+// WriteJSON writes the API to w as indented JSON with its symbols in sorted
+// order, so that snapshots of the same API are identical and snapshots
+// checked into version control diff cleanly.
+func (a *API) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// Names returns the names of the symbols in the API in sorted order.
+func (a *API) Names() []string {
+	names := make([]string, 0, len(a.Symbols))
+	for name := range a.Symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// API returns the exported API surface of the package in fs. It includes
+// exported constants, variables, functions and types, the exported methods
+// declared on exported types and the exported fields of exported struct
+// types. Promoted methods and fields are not included since they are
+// described by the embedded type.
+func (fs *FileSet) API() *API {
+	api := &API{
+		Package: fs.Package.Name(),
+		Symbols: make(map[string]string),
+	}
+	q := types.RelativeTo(fs.Package)
+
+	scope := fs.Package.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+
+		switch obj := obj.(type) {
+		case *types.Const, *types.Var, *types.Func:
+			api.Symbols[name] = types.ObjectString(obj, q)
+		case *types.TypeName:
+			api.Symbols[name] = typeDescription(obj, q)
+			if obj.IsAlias() {
+				continue
+			}
+			n, ok := obj.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			if st, ok := n.Underlying().(*types.Struct); ok {
+				for i := 0; i < st.NumFields(); i++ {
+					if f := st.Field(i); f.Exported() {
+						api.Symbols[name+"."+f.Name()] = "field " + name + "." + f.Name() + " " + types.TypeString(f.Type(), q)
+					}
+				}
+			}
+			for i := 0; i < n.NumMethods(); i++ {
+				if m := n.Method(i); m.Exported() {
+					api.Symbols[name+"."+m.Name()] = methodDescription(m, q)
+				}
+			}
+		}
+	}
+	return api
+}
+
+// typeDescription describes the declaration of a type. Struct types are
+// described without their fields, which are listed as separate symbols.
+func typeDescription(obj *types.TypeName, q types.Qualifier) string {
+	if obj.IsAlias() {
+		return fmt.Sprintf("type %s = %s", obj.Name(), types.TypeString(types.Unalias(obj.Type()), q))
+	}
+
+	var b strings.Builder
+	b.WriteString("type ")
+	b.WriteString(obj.Name())
+	if n, ok := obj.Type().(*types.Named); ok && n.TypeParams().Len() > 0 {
+		b.WriteString("[")
+		for i := 0; i < n.TypeParams().Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			tp := n.TypeParams().At(i)
+			fmt.Fprintf(&b, "%s %s", tp.Obj().Name(), types.TypeString(tp.Constraint(), q))
+		}
+		b.WriteString("]")
+	}
+	b.WriteString(" ")
+	if _, ok := obj.Type().Underlying().(*types.Struct); ok {
+		b.WriteString("struct")
+	} else {
+		b.WriteString(types.TypeString(obj.Type().Underlying(), q))
+	}
+	return b.String()
+}
+
+// methodDescription describes a method in the form of its declaration, such
+// as func (*T) M(x int) error.
+func methodDescription(m *types.Func, q types.Qualifier) string {
+	sig := m.Type().(*types.Signature)
+	recv := types.TypeString(sig.Recv().Type(), q)
+	return fmt.Sprintf("func (%s) %s%s", recv, m.Name(), strings.TrimPrefix(types.TypeString(sig, q), "func"))
+}
+
+// ChangeKind describes how a symbol differs between two APIs.
+type ChangeKind int
+
+const (
+	// Added means the symbol is only present in the newer API.
+	Added ChangeKind = iota
+
+	// Removed means the symbol is only present in the older API.
+	Removed
+
+	// Changed means the symbol is present in both APIs with different
+	// declarations.
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// APIChange describes a difference between two APIs.
+type APIChange struct {
+	// Kind is the kind of change.
+	Kind ChangeKind
+
+	// Symbol is the name of the symbol that changed.
+	Symbol string
+
+	// Old is the description of the symbol in the older API. It is empty
+	// for added symbols.
+	Old string
+
+	// New is the description of the symbol in the newer API. It is empty
+	// for removed symbols.
+	New string
+}
+
+func (c APIChange) String() string {
+	switch c.Kind {
+	case Added:
+		return "added " + c.New
+	case Removed:
+		return "removed " + c.Old
+	default:
+		return fmt.Sprintf("changed %s: %s => %s", c.Symbol, c.Old, c.New)
+	}
+}
+
+// DiffAPI compares two APIs and returns the symbols that were added, removed
+// or changed between old and new, ordered by symbol name.
+func DiffAPI(old, new *API) []APIChange {
+	var changes []APIChange
+	for _, name := range old.Names() {
+		desc, ok := new.Symbols[name]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Kind: Removed, Symbol: name, Old: old.Symbols[name]})
+		case desc != old.Symbols[name]:
+			changes = append(changes, APIChange{Kind: Changed, Symbol: name, Old: old.Symbols[name], New: desc})
+		}
+	}
+	for _, name := range new.Names() {
+		if _, ok := old.Symbols[name]; !ok {
+			changes = append(changes, APIChange{Kind: Added, Symbol: name, New: new.Symbols[name]})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Symbol < changes[j].Symbol })
+	return changes
+}
@@ -0,0 +1,149 @@
+package gen
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const apiV1 = `package p
+
+	const Version = "1"
+
+	var Debug bool
+
+	type Client struct {
+		Name    string
+		Timeout int
+		secret  string
+	}
+
+	func (c *Client) Do(req string) error { return nil }
+
+	func (c *Client) close() {}
+
+	type Handler interface {
+		Serve(string) error
+	}
+
+	type Set[T comparable] map[T]struct{}
+
+	func New(name string) *Client { return nil }
+
+	func Deprecated() {}
+
+	type internal struct{}
+`
+
+const apiV2 = `package p
+
+	const Version = "2"
+
+	var Debug bool
+
+	type Client struct {
+		Name    string
+		Timeout int64
+		Retries int
+	}
+
+	func (c *Client) Do(req string) error { return nil }
+
+	type Handler interface {
+		Serve(string) error
+		Close()
+	}
+
+	type Set[T comparable] map[T]struct{}
+
+	type Option = func(*Client)
+
+	func New(name string, opts ...Option) *Client { return nil }
+`
+
+func TestAPI(t *testing.T) {
+	fs, err := NewFileSetFromTexts(apiV1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := fs.API().Symbols
+	want := map[string]string{
+		"Client":         "type Client struct",
+		"Client.Do":      "func (*Client) Do(req string) error",
+		"Client.Name":    "field Client.Name string",
+		"Client.Timeout": "field Client.Timeout int",
+		"Debug":          "var Debug bool",
+		"Deprecated":     "func Deprecated()",
+		"Handler":        "type Handler interface{Serve(string) error}",
+		"New":            "func New(name string) *Client",
+		"Set":            "type Set[T comparable] map[T]struct{}",
+		"Version":        "const Version untyped string",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestDiffAPI(t *testing.T) {
+	v1, err := NewFileSetFromTexts(apiV1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v2, err := NewFileSetFromTexts(apiV2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []string{}
+	for _, c := range DiffAPI(v1.API(), v2.API()) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"added field Client.Retries int",
+		"changed Client.Timeout: field Client.Timeout int => field Client.Timeout int64",
+		"removed func Deprecated()",
+		"changed Handler: type Handler interface{Serve(string) error} => type Handler interface{Close(); Serve(string) error}",
+		"changed New: func New(name string) *Client => func New(name string, opts ...Option) *Client",
+		"added type Option = func(*Client)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if changes := DiffAPI(v1.API(), v1.API()); len(changes) != 0 {
+		t.Errorf("got %d changes between identical APIs, wanted none", len(changes))
+	}
+}
+
+func TestAPISnapshot(t *testing.T) {
+	fs, err := NewFileSetFromTexts(apiV1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	api := fs.API()
+
+	var first, second bytes.Buffer
+	if err := api.WriteJSON(&first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.WriteJSON(&second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("snapshots of the same API differ:\n%s\n%s", first.Bytes(), second.Bytes())
+	}
+
+	got, err := ReadAPI(&first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, api) {
+		t.Errorf("got %+v, wanted %+v", got, api)
+	}
+
+	if _, err := ReadAPI(strings.NewReader("{")); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+}
@@ -0,0 +1,178 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Budget limits the amount of code generated into a single package so that
+// table driven generators cannot silently inflate binary size. A zero limit
+// means the corresponding measure is unlimited.
+type Budget struct {
+	// MaxBytes is the maximum total size in bytes of the generated files in
+	// a package.
+	MaxBytes int
+
+	// MaxDecls is the maximum number of top level declarations in the
+	// generated files in a package. Each function, method, type, constant
+	// and variable counts as one declaration.
+	MaxDecls int
+}
+
+// Contribution measures the size of a single generated file.
+type Contribution struct {
+	// Filename is the name of the generated file.
+	Filename string
+
+	// Bytes is the size of the file's formatted source.
+	Bytes int
+
+	// Decls is the number of top level declarations in the file.
+	Decls int
+}
+
+// BudgetError is returned when the generated files in a package exceed a
+// Budget.
+type BudgetError struct {
+	// Dir is the directory of the package that exceeded the budget.
+	Dir string
+
+	// Budget is the budget that was exceeded.
+	Budget Budget
+
+	// Bytes is the total size of the package's generated files.
+	Bytes int
+
+	// Decls is the total number of declarations in the package's generated
+	// files.
+	Decls int
+
+	// Contributions holds the measurements of each generated file in the
+	// package, largest first.
+	Contributions []Contribution
+}
+
+// maxReportedContributions is the number of contributions listed in the error
+// message of a BudgetError.
+const maxReportedContributions = 3
+
+func (e *BudgetError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "generated code in %s exceeds budget:", e.Dir)
+	if e.Budget.MaxBytes > 0 && e.Bytes > e.Budget.MaxBytes {
+		fmt.Fprintf(&b, " %d bytes (limit %d)", e.Bytes, e.Budget.MaxBytes)
+	}
+	if e.Budget.MaxDecls > 0 && e.Decls > e.Budget.MaxDecls {
+		fmt.Fprintf(&b, " %d declarations (limit %d)", e.Decls, e.Budget.MaxDecls)
+	}
+	b.WriteString("; largest contributors:")
+	for i, c := range e.Contributions {
+		if i == maxReportedContributions {
+			fmt.Fprintf(&b, " and %d more", len(e.Contributions)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, " %s (%d bytes, %d declarations)", c.Filename, c.Bytes, c.Decls)
+	}
+	return b.String()
+}
+
+// Check measures the outputs, keyed by the filename they will be written to,
+// and returns a *BudgetError for the first package, in directory order, whose
+// generated files exceed the budget.
+func (b Budget) Check(outputs map[string]*Output) error {
+	byDir := make(map[string][]Contribution)
+	for filename, o := range outputs {
+		c, err := Measure(filename, o)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(filename)
+		byDir[dir] = append(byDir[dir], c)
+	}
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		if err := b.checkPackage(dir, byDir[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPackage checks the contributions of the generated files in one package
+// against the budget.
+func (b Budget) checkPackage(dir string, contributions []Contribution) error {
+	e := &BudgetError{Dir: dir, Budget: b, Contributions: contributions}
+	for _, c := range contributions {
+		e.Bytes += c.Bytes
+		e.Decls += c.Decls
+	}
+
+	if (b.MaxBytes <= 0 || e.Bytes <= b.MaxBytes) && (b.MaxDecls <= 0 || e.Decls <= b.MaxDecls) {
+		return nil
+	}
+
+	sort.Slice(e.Contributions, func(i, j int) bool {
+		ci, cj := e.Contributions[i], e.Contributions[j]
+		if ci.Bytes != cj.Bytes {
+			return ci.Bytes > cj.Bytes
+		}
+		return ci.Filename < cj.Filename
+	})
+	return e
+}
+
+// Measure formats the source accumulated by o and measures its size.
+func Measure(filename string, o *Output) (Contribution, error) {
+	src, err := o.Source()
+	if err != nil {
+		return Contribution{}, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return Contribution{}, err
+	}
+
+	return Contribution{
+		Filename: filename,
+		Bytes:    len(src),
+		Decls:    countDecls(f),
+	}, nil
+}
+
+// countDecls counts the top level declarations in f, excluding imports.
+func countDecls(f *ast.File) int {
+	n := 0
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			n++
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if vs, ok := spec.(*ast.ValueSpec); ok {
+					n += len(vs.Names)
+				} else {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
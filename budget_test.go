@@ -0,0 +1,68 @@
+package gen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBudgetCheck(t *testing.T) {
+	small := NewOutput("test")
+	small.Printf("package p\n\nconst A = 1\n")
+
+	large := NewOutput("test")
+	large.Printf("package p\n\nvar (\n")
+	for i := 0; i < 20; i++ {
+		large.Printf("\tV%d = %d\n", i, i)
+	}
+	large.Printf(")\n\nfunc F() {}\n")
+
+	other := NewOutput("test")
+	other.Printf("package q\n\nfunc G() {}\n")
+
+	outputs := map[string]*Output{
+		"p/small_gen.go": small,
+		"p/large_gen.go": large,
+		"q/other_gen.go": other,
+	}
+
+	testCases := []struct {
+		budget  Budget
+		wantErr bool
+	}{
+		{budget: Budget{}, wantErr: false},
+		{budget: Budget{MaxDecls: 22}, wantErr: false},
+		{budget: Budget{MaxDecls: 21}, wantErr: true},
+		{budget: Budget{MaxBytes: 10000}, wantErr: false},
+		{budget: Budget{MaxBytes: 100}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			err := tc.budget.Check(outputs)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, wanted error: %v", err, tc.wantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			var be *BudgetError
+			if !errors.As(err, &be) {
+				t.Fatalf("got error of type %T, wanted *BudgetError", err)
+			}
+			if be.Dir != "p" {
+				t.Errorf("got dir %q, wanted p", be.Dir)
+			}
+			if be.Decls != 22 {
+				t.Errorf("got %d declarations, wanted 22", be.Decls)
+			}
+			if be.Contributions[0].Filename != "p/large_gen.go" {
+				t.Errorf("got largest contributor %q, wanted p/large_gen.go", be.Contributions[0].Filename)
+			}
+			if !strings.Contains(err.Error(), "largest contributors: p/large_gen.go") {
+				t.Errorf("error message does not report largest contributor: %v", err)
+			}
+		})
+	}
+}
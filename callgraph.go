@@ -0,0 +1,251 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/ssa"
+)
+
+// CallGraph is a call graph of the functions declared in the package formed by
+// a FileSet. Functions are identified by name; methods are named using the
+// receiver's base type name and the method name separated by a dot, such as
+// "T.String". The instances of a generic function are merged into the node
+// for the function itself.
+type CallGraph struct {
+	// Graph is the underlying call graph. It includes nodes for functions in
+	// imported packages that are called from the package.
+	Graph *callgraph.Graph
+
+	pkg   *ssa.Package
+	nodes map[string][]*callgraph.Node
+}
+
+// CallGraph builds a call graph of the package in fs. Dynamic calls through
+// interfaces and function values are resolved conservatively using class
+// hierarchy analysis so that every function that could possibly be called
+// is treated as reachable.
+func (fs *FileSet) CallGraph() (*CallGraph, error) {
+	pkg, err := fs.BuildSSA(ssa.InstantiateGenerics)
+	if err != nil {
+		return nil, err
+	}
+	return newCallGraph(cha.CallGraph(pkg.Prog), pkg), nil
+}
+
+// StaticCallGraph builds a call graph of the package in fs that holds only
+// static calls, those whose callee is known at compile time. Calls through
+// interfaces and function values are omitted, so unlike CallGraph it records
+// only the calls that certainly take place when their call sites execute.
+func (fs *FileSet) StaticCallGraph() (*CallGraph, error) {
+	pkg, err := fs.BuildSSA(ssa.InstantiateGenerics)
+	if err != nil {
+		return nil, err
+	}
+	return newCallGraph(static.CallGraph(pkg.Prog), pkg), nil
+}
+
+// CallsFrom returns the names of the package functions called statically by
+// the named function, sorted alphabetically. It is a shorthand for the
+// Callees of the StaticCallGraph. It returns an error if the package has no
+// function of that name.
+func (fs *FileSet) CallsFrom(funcName string) ([]string, error) {
+	cg, err := fs.StaticCallGraph()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cg.nodes[funcName]; !ok {
+		return nil, fmt.Errorf("function %s not found", funcName)
+	}
+	return cg.Callees(funcName), nil
+}
+
+// newCallGraph returns a CallGraph of the functions of pkg in g.
+func newCallGraph(g *callgraph.Graph, pkg *ssa.Package) *CallGraph {
+	cg := &CallGraph{
+		Graph: g,
+		pkg:   pkg,
+		nodes: make(map[string][]*callgraph.Node),
+	}
+
+	for fn, n := range cg.Graph.Nodes {
+		if fn = cg.packageFunc(fn); fn != nil {
+			name := ssaFuncName(fn)
+			cg.nodes[name] = append(cg.nodes[name], n)
+		}
+	}
+
+	return cg
+}
+
+// packageFunc returns the function of the package that fn is, or that fn is
+// an instance of if fn is an instantiation of a generic function, or nil if
+// fn is not declared in the package.
+func (cg *CallGraph) packageFunc(fn *ssa.Function) *ssa.Function {
+	if fn == nil {
+		return nil
+	}
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	if fn.Pkg != cg.pkg || fn.Synthetic != "" {
+		return nil
+	}
+	return fn
+}
+
+// Funcs returns the names of all functions in the package that appear in the
+// call graph, sorted alphabetically.
+func (cg *CallGraph) Funcs() []string {
+	names := make([]string, 0, len(cg.nodes))
+	for name := range cg.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Callees returns the names of the package functions that may be called
+// directly by the named function, sorted alphabetically.
+func (cg *CallGraph) Callees(name string) []string {
+	nodes, ok := cg.nodes[name]
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, n := range nodes {
+		for _, e := range n.Out {
+			fn := cg.packageFunc(e.Callee.Func)
+			if fn == nil {
+				continue
+			}
+			callee := ssaFuncName(fn)
+			if !seen[callee] {
+				seen[callee] = true
+				names = append(names, callee)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Leaves returns the names of the package functions that call no other
+// package functions, sorted alphabetically. Calls to functions of other
+// packages are not counted, so that a generator adding instrumentation to
+// the package can skip the functions at the bottom of its call chains.
+func (cg *CallGraph) Leaves() []string {
+	names := []string{}
+	for _, name := range cg.Funcs() {
+		if len(cg.Callees(name)) == 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Reachable reports whether the function named to may be called, directly
+// or indirectly, by the function named from.
+func (cg *CallGraph) Reachable(from, to string) bool {
+	roots, ok := cg.nodes[from]
+	if !ok {
+		return false
+	}
+	return cg.reachesAny(roots, cg.nodes[to])
+}
+
+// ReachableFromExported reports whether the named function may be called,
+// directly or indirectly, from the exported API of the package. The exported
+// API consists of exported functions, exported methods of exported types and
+// the package's init functions.
+func (cg *CallGraph) ReachableFromExported(name string) bool {
+	targets, ok := cg.nodes[name]
+	if !ok {
+		return false
+	}
+
+	roots := []*callgraph.Node{}
+	for fn, n := range cg.Graph.Nodes {
+		if fn = cg.packageFunc(fn); fn != nil && isExportedRoot(fn) {
+			roots = append(roots, n)
+		}
+	}
+	return cg.reachesAny(roots, targets)
+}
+
+// reachesAny reports whether any of the targets is reachable from roots.
+func (cg *CallGraph) reachesAny(roots, targets []*callgraph.Node) bool {
+	seen := cg.reachableFrom(roots)
+	for _, n := range targets {
+		if seen[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableFrom returns the set of nodes reachable from roots, including the
+// roots themselves.
+func (cg *CallGraph) reachableFrom(roots []*callgraph.Node) map[*callgraph.Node]bool {
+	seen := make(map[*callgraph.Node]bool)
+	stack := append([]*callgraph.Node{}, roots...)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		for _, e := range n.Out {
+			stack = append(stack, e.Callee)
+		}
+	}
+	return seen
+}
+
+// isExportedRoot reports whether fn is part of the exported API of its package.
+func isExportedRoot(fn *ssa.Function) bool {
+	if fn.Parent() != nil || fn.Synthetic != "" {
+		return false
+	}
+	if fn.Name() == "init" || fn.Name() == "main" {
+		return true
+	}
+	if !ast.IsExported(fn.Name()) {
+		return false
+	}
+	recv := fn.Signature.Recv()
+	if recv == nil {
+		return true
+	}
+	return ast.IsExported(recvTypeName(recv.Type()))
+}
+
+// ssaFuncName returns the name used to identify fn in a CallGraph.
+func ssaFuncName(fn *ssa.Function) string {
+	if fn.Parent() != nil {
+		return ssaFuncName(fn.Parent()) + "$" + fn.Name()[len(fn.Parent().Name())+1:]
+	}
+	if recv := fn.Signature.Recv(); recv != nil {
+		return recvTypeName(recv.Type()) + "." + fn.Name()
+	}
+	return fn.Name()
+}
+
+// recvTypeName returns the name of the named type underlying a method receiver.
+func recvTypeName(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if n, ok := t.(*types.Named); ok {
+		return n.Obj().Name()
+	}
+	return t.String()
+}
@@ -0,0 +1,223 @@
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+const callGraphSrc = `package p
+
+type Shape interface {
+	Area() int
+}
+
+type Square struct{ n int }
+
+func (s *Square) Area() int { return mul(s.n, s.n) }
+
+func mul(a, b int) int { return a * b }
+
+func Total(shapes []Shape) int {
+	t := 0
+	for _, s := range shapes {
+		t += s.Area()
+	}
+	return t
+}
+
+func helper() int { return unused() }
+
+func unused() int { return 0 }
+
+func Run() int {
+	f := func() int { return mul(2, 3) }
+	return f()
+}
+`
+
+func TestCallGraphCallees(t *testing.T) {
+	fs, err := NewFileSetFromTexts(callGraphSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cg, err := fs.CallGraph()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		want []string
+	}{
+		{name: "Square.Area", want: []string{"mul"}},
+		{name: "Total", want: []string{"Square.Area"}},
+		{name: "helper", want: []string{"unused"}},
+		{name: "Run", want: []string{"Run$1"}},
+		{name: "missing", want: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := cg.Callees(tc.name)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCallGraphReachable(t *testing.T) {
+	fs, err := NewFileSetFromTexts(callGraphSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cg, err := fs.CallGraph()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cg.Reachable("Total", "mul") {
+		t.Errorf("mul should be reachable from Total")
+	}
+	if cg.Reachable("Total", "unused") {
+		t.Errorf("unused should not be reachable from Total")
+	}
+
+	testCases := []struct {
+		name string
+		want bool
+	}{
+		{name: "mul", want: true},
+		{name: "Square.Area", want: true},
+		{name: "Run$1", want: true},
+		{name: "helper", want: false},
+		{name: "unused", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cg.ReachableFromExported(tc.name); got != tc.want {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStaticCallGraph(t *testing.T) {
+	fs, err := NewFileSetFromTexts(callGraphSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cg, err := fs.StaticCallGraph()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		want []string
+	}{
+		{name: "Square.Area", want: []string{"mul"}},
+		{name: "Total", want: []string{}},
+		{name: "helper", want: []string{"unused"}},
+		{name: "Run$1", want: []string{"mul"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cg.Callees(tc.name); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+			got, err := fs.CallsFrom(tc.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("CallsFrom: got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+
+	if want := []string{"Total", "mul", "unused"}; !reflect.DeepEqual(cg.Leaves(), want) {
+		t.Errorf("got leaves %v, wanted %v", cg.Leaves(), want)
+	}
+	if _, err := fs.CallsFrom("missing"); err == nil {
+		t.Errorf("got no error for missing function, wanted one")
+	}
+}
+
+const genericCallGraphSrc = `package p
+
+func identity[T any](v T) T {
+	f := func() T { return v }
+	return f()
+}
+
+func helper() {}
+
+func Use() int {
+	helper()
+	return identity(3)
+}
+
+func Dyn() string { return identity("x") }
+`
+
+func TestCallGraphGenerics(t *testing.T) {
+	fs, err := NewFileSetFromTexts(genericCallGraphSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cg, err := fs.CallGraph()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := cg.Callees("Use"), []string{"helper", "identity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got callees %v, wanted %v", got, want)
+	}
+	if got, want := cg.Callees("identity"), []string{"identity$1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got callees %v, wanted %v", got, want)
+	}
+	if !cg.Reachable("Use", "identity") {
+		t.Errorf("identity should be reachable from Use")
+	}
+	if !cg.Reachable("Dyn", "identity$1") {
+		t.Errorf("identity$1 should be reachable from Dyn")
+	}
+	for _, name := range []string{"identity", "identity$1"} {
+		if !cg.ReachableFromExported(name) {
+			t.Errorf("%s should be reachable from the exported API", name)
+		}
+	}
+	if want := []string{"Dyn", "Use", "helper", "identity", "identity$1"}; !reflect.DeepEqual(cg.Funcs(), want) {
+		t.Errorf("got funcs %v, wanted %v", cg.Funcs(), want)
+	}
+}
+
+func TestStaticCallGraphGenerics(t *testing.T) {
+	fs, err := NewFileSetFromTexts(genericCallGraphSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cg, err := fs.StaticCallGraph()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := fs.CallsFrom("Dyn")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"identity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+	if want := []string{"helper", "identity$1"}; !reflect.DeepEqual(cg.Leaves(), want) {
+		t.Errorf("got leaves %v, wanted %v", cg.Leaves(), want)
+	}
+}
@@ -0,0 +1,72 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChangelogFragment renders a human readable summary of API changes for a
+// release as a Markdown fragment. Changes are grouped into added, changed and
+// removed sections, each of which is omitted if empty. Release tooling can
+// assemble the fragments of several packages into a complete changelog.
+func ChangelogFragment(pkg, release string, changes []APIChange) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "## %s %s\n", pkg, release)
+	if len(changes) == 0 {
+		b.WriteString("\nNo API changes.\n")
+		return b.Bytes()
+	}
+
+	sections := []struct {
+		kind  ChangeKind
+		title string
+	}{
+		{Added, "Added"},
+		{Changed, "Changed"},
+		{Removed, "Removed"},
+	}
+	for _, s := range sections {
+		first := true
+		for _, c := range changes {
+			if c.Kind != s.kind {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&b, "\n### %s\n\n", s.title)
+				first = false
+			}
+			switch c.Kind {
+			case Added:
+				fmt.Fprintf(&b, "- `%s`\n", c.New)
+			case Removed:
+				fmt.Fprintf(&b, "- `%s`\n", c.Old)
+			case Changed:
+				fmt.Fprintf(&b, "- `%s`: `%s` is now `%s`\n", c.Symbol, c.Old, c.New)
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// WriteChangelogFragment writes the changelog fragment describing the changes
+// between the old and new APIs to the directory dir, creating it if
+// necessary. The fragment is named after the package and release, such as
+// dir/mypkg-v1.2.0.md, and its filename is returned.
+func WriteChangelogFragment(dir, release string, old, new *API) (string, error) {
+	if release == "" || strings.ContainsAny(release, `/\`) {
+		return "", fmt.Errorf("invalid release name %q", release)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(dir, new.Package+"-"+release+".md")
+	data := ChangelogFragment(new.Package, release, DiffAPI(old, new))
+	if err := writeFileAtomic(filename, data, 0o644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
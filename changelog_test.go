@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangelogFragment(t *testing.T) {
+	changes := []APIChange{
+		{Kind: Added, Symbol: "Client.Retries", New: "field Client.Retries int"},
+		{Kind: Removed, Symbol: "Deprecated", Old: "func Deprecated()"},
+		{Kind: Changed, Symbol: "New", Old: "func New() *Client", New: "func New(opts ...Option) *Client"},
+	}
+
+	got := string(ChangelogFragment("p", "v1.2.0", changes))
+	want := "## p v1.2.0\n" +
+		"\n### Added\n\n- `field Client.Retries int`\n" +
+		"\n### Changed\n\n- `New`: `func New() *Client` is now `func New(opts ...Option) *Client`\n" +
+		"\n### Removed\n\n- `func Deprecated()`\n"
+	if got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	got = string(ChangelogFragment("p", "v1.2.1", nil))
+	want = "## p v1.2.1\n\nNo API changes.\n"
+	if got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestWriteChangelogFragment(t *testing.T) {
+	v1, err := NewFileSetFromTexts(apiV1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v2, err := NewFileSetFromTexts(apiV2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "changes")
+	filename, err := WriteChangelogFragment(dir, "v2.0.0", v1.API(), v2.API())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "p-v2.0.0.md"); filename != want {
+		t.Errorf("got filename %q, wanted %q", filename, want)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := ChangelogFragment("p", "v2.0.0", DiffAPI(v1.API(), v2.API())); string(data) != string(want) {
+		t.Errorf("got %q, wanted %q", data, want)
+	}
+
+	if _, err := WriteChangelogFragment(dir, "../v2", v1.API(), v2.API()); err == nil {
+		t.Errorf("got no error for invalid release name")
+	}
+}
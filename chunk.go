@@ -0,0 +1,184 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"runtime"
+	"sync"
+)
+
+// declChunker splits Go source code, supplied a line at a time, into chunks
+// of whole top-level declarations that can be formatted independently. The
+// first chunk holds the package clause and anything that precedes it.
+//
+// A chunk ends only where a line starting a declaration or a comment at the
+// start of a line follows a blank line and the source so far parses, so each
+// chunk is separated from the next by a blank line. Since gofmt aligns
+// columns only within runs of consecutive lines and reduces runs of blank
+// lines between declarations to one, formatting each chunk separately and
+// joining them with a single blank line gives the same result as formatting
+// the whole source.
+type declChunker struct {
+	emit    func(chunk []byte, first bool) error
+	pending bytes.Buffer
+	first   bool // the pending chunk is the first
+	hasCode bool // the pending chunk holds more than comments and blank lines
+	blank   bool // the last line of the pending chunk is blank
+
+	// unchecked disables checking that chunks are syntactically complete
+	// before ending them, for callers that detect incomplete chunks when
+	// formatting them and can recover.
+	unchecked bool
+}
+
+func newDeclChunker(emit func(chunk []byte, first bool) error) *declChunker {
+	return &declChunker{emit: emit, first: true}
+}
+
+// declStarts lists the prefixes of lines that may begin a new chunk.
+var declStarts = [][]byte{
+	[]byte("func "), []byte("func("),
+	[]byte("type "), []byte("type("),
+	[]byte("var "), []byte("var("),
+	[]byte("const "), []byte("const("),
+	[]byte("import "), []byte("import("),
+	[]byte("//"), []byte("/*"),
+}
+
+// line adds a line of source, including its trailing newline if it has one.
+func (c *declChunker) line(l []byte) error {
+	trimmed := bytes.TrimSpace(l)
+	if c.hasCode && c.blank && len(trimmed) > 0 && isDeclStart(l) && (c.unchecked || c.complete()) {
+		if err := c.flush(); err != nil {
+			return err
+		}
+	}
+
+	c.pending.Write(l)
+	c.blank = len(trimmed) == 0
+	if len(trimmed) > 0 && !bytes.HasPrefix(trimmed, []byte("//")) {
+		c.hasCode = true
+	}
+	return nil
+}
+
+// close emits the final chunk.
+func (c *declChunker) close() error {
+	if c.pending.Len() == 0 && !c.first {
+		return nil
+	}
+	return c.flush()
+}
+
+func (c *declChunker) flush() error {
+	chunk := bytes.Clone(c.pending.Bytes())
+	first := c.first
+	c.pending.Reset()
+	c.first, c.hasCode, c.blank = false, false, false
+	return c.emit(chunk, first)
+}
+
+func isDeclStart(l []byte) bool {
+	for _, p := range declStarts {
+		if bytes.HasPrefix(l, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete reports whether the pending chunk is syntactically complete, so
+// that it does not end inside a declaration or a raw string literal.
+func (c *declChunker) complete() bool {
+	src := c.pending.Bytes()
+	if !c.first {
+		src = append([]byte(chunkPrefix), src...)
+	}
+	_, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	return err == nil
+}
+
+// chunkPrefix is prepended to chunks other than the first so that they can be
+// formatted as a file.
+const chunkPrefix = "package p\n\n"
+
+// formatChunk formats a chunk produced by a declChunker. The result ends with
+// a newline and does not begin with blank lines.
+func formatChunk(chunk []byte, first bool) ([]byte, error) {
+	if first {
+		return format.Source(chunk)
+	}
+	formatted, err := format.Source(append([]byte(chunkPrefix), chunk...))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(formatted, []byte(chunkPrefix)) {
+		return nil, fmt.Errorf("unexpected formatting of declaration")
+	}
+	return bytes.TrimLeft(formatted[len(chunkPrefix):], "\n"), nil
+}
+
+// formatParallel formats src by splitting it into chunks of declarations and
+// formatting the chunks concurrently. If any chunk fails to format the whole
+// of src is formatted with format.Source instead, which also provides an
+// error message that refers to the correct line.
+func formatParallel(src []byte) ([]byte, error) {
+	var chunks [][]byte
+	c := newDeclChunker(func(chunk []byte, first bool) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	// A chunk that ends within a declaration fails to format, so there is no
+	// need to parse the chunks while splitting.
+	c.unchecked = true
+	for rest := src; len(rest) > 0; {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			i = len(rest) - 1
+		}
+		c.line(rest[:i+1])
+		rest = rest[i+1:]
+	}
+	c.close()
+	if len(chunks) < 2 {
+		return format.Source(src)
+	}
+
+	formatted := make([][]byte, len(chunks))
+	errs := make([]error, len(chunks))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for n := min(runtime.GOMAXPROCS(0), len(chunks)); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				formatted[i], errs[i] = formatChunk(chunks[i], i == 0)
+			}
+		}()
+	}
+	for i := range chunks {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	size := 0
+	for i, f := range formatted {
+		if errs[i] != nil {
+			return format.Source(src)
+		}
+		size += len(f) + 1
+	}
+	out := make([]byte, 0, size)
+	for i, f := range formatted {
+		if i > 0 {
+			out = append(out, '\n')
+		}
+		out = append(out, f...)
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+// Command gensize reports how much of the compiled size of one or more
+// packages is attributable to their generated files. A file is treated as
+// generated if it carries the standard "Code generated ... DO NOT EDIT."
+// header. Each package must still compile when its generated files are
+// removed.
+//
+// Usage:
+//
+//	gensize [dir...]
+//
+// If no directories are given the current directory is used.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iand/gen"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gensize [dir...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	failed := false
+	for _, dir := range dirs {
+		s, err := gen.MeasureSizeImpact(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gensize: %s: %v\n", dir, err)
+			failed = true
+			continue
+		}
+		fmt.Println(s)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,104 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrNotCompiled is wrapped by the errors returned by CheckCompiles when the
+// generated code does not type check with the package.
+var ErrNotCompiled = errors.New("generated code does not compile")
+
+// CheckCompiles type checks the package of fs together with generated
+// source, keyed by the filename it would be written to, without writing
+// anything. The generated files are overlaid on the files of the package: a
+// file that already exists, such as the output of an earlier run, is
+// replaced by its generated contents and a new file is added to the package.
+// Files outside the directory of fs, and test files unless fs was loaded
+// with WithTests, are not part of the package and are ignored. The package is
+// checked as it was loaded, except that errors are not tolerated even if it
+// was loaded with WithLenient.
+//
+// CheckCompiles lets a generator fail before writing files that would break
+// the build. If the generated code does not compile, the error wraps
+// ErrNotCompiled and the ErrorList of type errors, whose positions refer to
+// lines of the generated files.
+func (fs *FileSet) CheckCompiles(sources map[string][]byte) error {
+	check := &FileSet{
+		Dir:       fs.Dir,
+		opts:      fs.opts,
+		importer:  fs.importer,
+		base:      fs.base,
+		importDir: fs.importDir,
+		pkgPath:   fs.pkgPath,
+		fsys:      fs.fsys,
+		contents:  make(map[string][]byte),
+	}
+	check.opts.lenient = false
+	for name, src := range fs.contents {
+		check.contents[name] = src
+	}
+
+	check.Files = append(check.Files, fs.Files...)
+	if len(check.Files) == 0 {
+		// The package was loaded from texts, whose source is not kept, so
+		// print its files to overlay them.
+		for _, f := range fs.AstFiles {
+			var buf bytes.Buffer
+			if err := format.Node(&buf, fs.FileSet, f); err != nil {
+				return err
+			}
+			name := fs.FileSet.File(f.Pos()).Name()
+			check.contents[name] = buf.Bytes()
+			check.Files = append(check.Files, name)
+		}
+	}
+
+	for _, filename := range SortedKeys(sources) {
+		if !inPackage(fs, filename) {
+			continue
+		}
+		i := slices.IndexFunc(check.Files, func(f string) bool { return samePath(f, filename) })
+		if i < 0 {
+			i = len(check.Files)
+			check.Files = append(check.Files, filename)
+		}
+		check.contents[check.Files[i]] = sources[filename]
+	}
+
+	if _, err := check.ParseFiles(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotCompiled, err)
+	}
+	return nil
+}
+
+// inPackage reports whether the file filename would be part of the package
+// of fs.
+func inPackage(fs *FileSet, filename string) bool {
+	if filepath.Ext(filename) != ".go" {
+		return false
+	}
+	if strings.HasSuffix(filename, "_test.go") && !fs.opts.tests {
+		return false
+	}
+	return samePath(filepath.Dir(filename), fs.Dir)
+}
+
+// outputSources returns the formatted source of each of the outputs, keyed
+// by filename.
+func outputSources(outputs map[string]*Output) (map[string][]byte, error) {
+	sources := make(map[string][]byte, len(outputs))
+	for _, filename := range SortedKeys(outputs) {
+		src, err := outputs[filename].Source()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		sources[filename] = src
+	}
+	return sources, nil
+}
@@ -0,0 +1,66 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCompiles(t *testing.T) {
+	fs, err := NewFileSetFromTexts("package p\n\n// Color is a color.\ntype Color int\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	good := map[string][]byte{"color_string.go": []byte("package p\n\nfunc (c Color) String() string { return \"color\" }\n")}
+	if err := fs.CheckCompiles(good); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	bad := map[string][]byte{"color_string.go": []byte("package p\n\nfunc (c Colour) String() string { return \"color\" }\n")}
+	err = fs.CheckCompiles(bad)
+	if !errors.Is(err, ErrNotCompiled) {
+		t.Fatalf("got error %v, wanted %v", err, ErrNotCompiled)
+	}
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) == 0 || !strings.HasSuffix(list[0].Pos.Filename, "color_string.go") {
+		t.Errorf("got error %v, wanted an ErrorList positioned in the generated file", err)
+	}
+	if !strings.HasPrefix(err.Error(), "generated code does not compile: ") || !strings.Contains(err.Error(), "Colour") {
+		t.Errorf("got error %q, wanted it to report the undefined type", err)
+	}
+}
+
+func TestCheckCompilesOverlay(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go":            "package p\n\ntype Color int\n",
+		"color_string.go": "package p\n\nfunc (Color) String() string { return \"old\" }\n",
+	})
+	fs, err := NewFileSet([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A regenerated file replaces the existing one rather than redeclaring
+	// its methods, and files outside the package are ignored.
+	sources := map[string][]byte{
+		filepath.Join(dir, "color_string.go"):   []byte("package p\n\nfunc (Color) String() string { return \"new\" }\n"),
+		filepath.Join(dir, "color_test.go"):     []byte("package p\n\nvar _ = undefined\n"),
+		filepath.Join(dir, "sub", "other.go"):   []byte("package other\n\nvar _ = undefined\n"),
+		filepath.Join(dir, "testdata", "x.txt"): []byte("not go"),
+		filepath.Join(dir, "color_marshal.go"):  []byte("package p\n\nfunc (c Color) MarshalText() ([]byte, error) { return []byte(c.String()), nil }\n"),
+	}
+	if err := fs.CheckCompiles(sources); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	sources[filepath.Join(dir, "color_dup.go")] = []byte("package p\n\nfunc (Color) String() string { return \"dup\" }\n")
+	if err := fs.CheckCompiles(sources); !errors.Is(err, ErrNotCompiled) {
+		t.Errorf("got error %v, wanted %v", err, ErrNotCompiled)
+	}
+
+	if got, err := os.ReadFile(filepath.Join(dir, "color_string.go")); err != nil || !strings.Contains(string(got), "old") {
+		t.Errorf("existing file changed by type check: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,260 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Declaration locates the declaration of an identifier in a file that is
+// about to be generated or that already exists.
+type Declaration struct {
+	// Filename is the name of the file containing the declaration.
+	Filename string
+
+	// Line is the line of the declaration within the file.
+	Line int
+
+	// Generator is the name of the generator producing the file or owning
+	// the declaration. It is empty for existing files that are not being
+	// regenerated unless they record ownership.
+	Generator string
+}
+
+func (d Declaration) String() string {
+	if d.Generator == "" {
+		return fmt.Sprintf("%s:%d", d.Filename, d.Line)
+	}
+	return fmt.Sprintf("%s:%d (%s)", d.Filename, d.Line, d.Generator)
+}
+
+// Conflict describes an identifier that is declared more than once in a
+// package.
+type Conflict struct {
+	// Dir is the directory of the package.
+	Dir string
+
+	// Name is the conflicting identifier. Methods are named by their
+	// receiver's base type name and method name separated by a dot, such as
+	// "T.String".
+	Name string
+
+	// Decls holds the conflicting declarations, ordered by file name and
+	// line.
+	Decls []Declaration
+}
+
+// ConflictError is returned when generated files declare identifiers that
+// are declared by other generated files or by existing files in the same
+// package.
+type ConflictError struct {
+	// Conflicts holds the conflicting identifiers, ordered by directory and
+	// name.
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	b.WriteString("generated code declares conflicting identifiers:")
+	for i, c := range e.Conflicts {
+		if i > 0 {
+			b.WriteString(";")
+		}
+		fmt.Fprintf(&b, " %s in %s declared by", c.Name, c.Dir)
+		for j, d := range c.Decls {
+			if j > 0 {
+				b.WriteString(" and")
+			}
+			fmt.Fprintf(&b, " %s", d)
+		}
+	}
+	return b.String()
+}
+
+// CheckConflicts looks for identifiers that the outputs, keyed by the
+// filename they will be written to, are about to declare more than once in
+// a package, such as when two generators targeting the same package emit a
+// function with the same name. Existing Go files in the directories of the
+// outputs are included in the check unless they are about to be overwritten,
+// but a name is only reported if at least one of its declarations is in an
+// output, since existing files that conflict only with each other are not
+// the generators' concern. CheckConflicts returns a *ConflictError listing
+// every conflict found, so that the generators responsible can be reported
+// before the files are written rather than leaving the compiler to report a
+// redeclaration later.
+//
+// Packages are distinguished by directory and package name, so declarations
+// in an external test package do not conflict with those in the package
+// under test. Only the files that are part of the package in the default
+// build context, as decided by their names and build constraints, are
+// checked, so files for different platforms that declare the same name do
+// not conflict.
+func CheckConflicts(outputs map[string]*Output) error {
+	sources, err := outputSources(outputs)
+	if err != nil {
+		return err
+	}
+	return checkConflicts(&build.Default, sources, outputGenerators(outputs), nil)
+}
+
+// outputGenerators returns the generator of each of the outputs, keyed by
+// filename.
+func outputGenerators(outputs map[string]*Output) map[string]string {
+	generators := make(map[string]string, len(outputs))
+	for filename, o := range outputs {
+		generators[filename] = o.Generator
+	}
+	return generators
+}
+
+// checkConflicts checks the formatted sources of outputs, keyed by
+// filename, for conflicts as CheckConflicts does, in the build context
+// ctxt. The generator of each output is given by generators. Existing files
+// for which ignore, if not nil, returns true are left out of the check, such
+// as those about to be pruned.
+func checkConflicts(ctxt *build.Context, sources map[string][]byte, generators map[string]string, ignore func(filename string, src []byte) bool) error {
+	type scope struct {
+		dir, pkg string
+	}
+	decls := make(map[scope]map[string][]Declaration)
+	generated := make(map[scope]map[string]bool) // names declared by an output
+	add := func(filename string, src []byte, generator string, output bool) error {
+		if !matchContext(*ctxt, filename, src) {
+			return nil
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		s := scope{dir: filepath.Dir(filename), pkg: f.Name.Name}
+		if decls[s] == nil {
+			decls[s] = make(map[string][]Declaration)
+			generated[s] = make(map[string]bool)
+		}
+		ow := ReadOwnership(src)
+		eachDeclaredName(f, func(name string, pos token.Pos) {
+			d := Declaration{
+				Filename:  filename,
+				Line:      fset.Position(pos).Line,
+				Generator: generator,
+			}
+			if g, ok := ow[name]; ok {
+				d.Generator = g
+			}
+			decls[s][name] = append(decls[s][name], d)
+			generated[s][name] = generated[s][name] || output
+		})
+		return nil
+	}
+
+	outputs := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for filename, src := range sources {
+		if err := add(filename, src, generators[filename], true); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		outputs[filepath.Clean(filename)] = true
+		dirs[filepath.Dir(filename)] = true
+	}
+
+	for dir := range dirs {
+		existing, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return err
+		}
+		for _, filename := range existing {
+			if outputs[filepath.Clean(filename)] {
+				continue
+			}
+			src, err := os.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			if ignore != nil && ignore(filename, src) {
+				continue
+			}
+			// Existing files that do not parse are left for the compiler
+			// to report. The generators of existing generated files are
+			// known if they recorded ownership.
+			add(filename, src, "", false)
+		}
+	}
+
+	e := &ConflictError{}
+	for s, names := range decls {
+		for name, ds := range names {
+			if len(ds) < 2 || !generated[s][name] {
+				continue
+			}
+			sort.Slice(ds, func(i, j int) bool {
+				if ds[i].Filename != ds[j].Filename {
+					return ds[i].Filename < ds[j].Filename
+				}
+				return ds[i].Line < ds[j].Line
+			})
+			e.Conflicts = append(e.Conflicts, Conflict{Dir: s.dir, Name: name, Decls: ds})
+		}
+	}
+	if len(e.Conflicts) == 0 {
+		return nil
+	}
+	sort.Slice(e.Conflicts, func(i, j int) bool {
+		ci, cj := e.Conflicts[i], e.Conflicts[j]
+		if ci.Dir != cj.Dir {
+			return ci.Dir < cj.Dir
+		}
+		return ci.Name < cj.Name
+	})
+	return e
+}
+
+// matchContext reports whether the file filename with contents src would be
+// part of its package in the build context ctxt, given its name and build
+// constraints.
+func matchContext(ctxt build.Context, filename string, src []byte) bool {
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(src)), nil
+	}
+	ok, err := ctxt.MatchFile(filepath.Dir(filename), filepath.Base(filename))
+	return err == nil && ok
+}
+
+// eachDeclaredName calls fn with the name and position of each package level
+// identifier and method declared in f. Blank identifiers and init functions,
+// which may be declared more than once, are omitted.
+func eachDeclaredName(f *ast.File, fn func(name string, pos token.Pos)) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.Name == "_" || (decl.Recv == nil && decl.Name.Name == "init") {
+				continue
+			}
+			fn(funcDeclName(decl), decl.Name.Pos())
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					fn(spec.Name.Name, spec.Name.Pos())
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						if id.Name != "_" {
+							fn(id.Name, id.Pos())
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckConflicts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write("p.go", "package p\n\ntype T struct{}\n\nfunc (T) Manual() {}\n\nfunc init() {}\n")
+	write("old_gen.go", "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n\nfunc Stale() {}\n")
+	write("p_test.go", "package p_test\n\nfunc Helper() {}\n")
+	write("owned_gen.go", "// Code generated by x; DO NOT EDIT.\n\npackage p\n\nfunc Owned() {}\n\n//gen:owner enumer Owned\n")
+	write("open_ignore.go", "//go:build ignore\n\npackage p\n\nfunc open() {}\n\nfunc Manual() {}\n")
+	write("open_other.go", "//go:build !ignore\n\npackage p\n\nfunc open() {}\n")
+	write("open_windows.go", "package p\n\nfunc platform() {}\n")
+	write("open_darwin.go", "package p\n\nfunc platform() {}\n")
+	write("dup1.go", "package p\n\nfunc dup() {}\n")
+	write("dup2.go", "package p\n\nfunc dup() {}\n")
+
+	output := func(generator, src string) *Output {
+		o := NewOutput(generator)
+		o.Printf("%s", src)
+		return o
+	}
+
+	testCases := []struct {
+		name    string
+		outputs map[string]*Output
+		want    []string
+	}{
+		{
+			name: "no conflicts",
+			outputs: map[string]*Output{
+				filepath.Join(dir, "a_gen.go"): output("enumer", "package p\n\nfunc (T) String() string { return \"\" }\n\nfunc init() {}\n\nvar _ = 1\n"),
+				filepath.Join(dir, "b_gen.go"): output("mockgen", "package p\n\ntype Mock struct{}\n\nfunc (*Mock) String() string { return \"\" }\n"),
+			},
+			want: []string{},
+		},
+		{
+			name: "between generators",
+			outputs: map[string]*Output{
+				filepath.Join(dir, "a_gen.go"): output("enumer", "package p\n\nfunc (T) String() string { return \"\" }\n\nvar Values = 1\n"),
+				filepath.Join(dir, "b_gen.go"): output("stringer", "package p\n\nfunc (t *T) String() string { return \"\" }\n"),
+				filepath.Join(dir, "c_gen.go"): output("tables", "package p\n\nvar (\n\tA, Values = 1, 2\n)\n"),
+			},
+			want: []string{"T.String: a_gen.go (enumer), b_gen.go (stringer)", "Values: a_gen.go (enumer), c_gen.go (tables)"},
+		},
+		{
+			name: "with existing files",
+			outputs: map[string]*Output{
+				filepath.Join(dir, "a_gen.go"):   output("enumer", "package p\n\ntype T int\n\nfunc Helper() {}\n"),
+				filepath.Join(dir, "old_gen.go"): output("stringer", "package p\n\nfunc Stale() {}\n"),
+				filepath.Join(dir, "x_gen.go"):   output("other", "package p\n\nfunc (T) Manual() {}\n"),
+			},
+			want: []string{"T: a_gen.go (enumer), p.go", "T.Manual: p.go, x_gen.go (other)"},
+		},
+		{
+			name: "with recorded ownership",
+			outputs: map[string]*Output{
+				filepath.Join(dir, "a_gen.go"): output("stringer", "package p\n\nfunc Owned() {}\n"),
+			},
+			want: []string{"Owned: a_gen.go (stringer), owned_gen.go (enumer)"},
+		},
+		{
+			name: "between existing files",
+			outputs: map[string]*Output{
+				filepath.Join(dir, "a_gen.go"): output("enumer", "package p\n\nfunc Gen() {}\n"),
+			},
+			want: []string{},
+		},
+		{
+			name: "with build constraints",
+			outputs: map[string]*Output{
+				filepath.Join(dir, "a_gen.go"): output("enumer", "package p\n\nfunc open() {}\n\nfunc Manual() {}\n"),
+			},
+			want: []string{"open: a_gen.go (enumer), open_other.go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckConflicts(tc.outputs)
+			got := []string{}
+			if err != nil {
+				var ce *ConflictError
+				if !errors.As(err, &ce) {
+					t.Fatalf("got error of type %T, wanted *ConflictError", err)
+				}
+				for _, c := range ce.Conflicts {
+					if c.Dir != dir {
+						t.Errorf("got dir %q, wanted %q", c.Dir, dir)
+					}
+					sites := []string{}
+					for _, d := range c.Decls {
+						s := filepath.Base(d.Filename)
+						if d.Generator != "" {
+							s += " (" + d.Generator + ")"
+						}
+						sites = append(sites, s)
+					}
+					got = append(got, c.Name+": "+strings.Join(sites, ", "))
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConflictErrorMessage(t *testing.T) {
+	e := &ConflictError{Conflicts: []Conflict{{
+		Dir:  "p",
+		Name: "F",
+		Decls: []Declaration{
+			{Filename: "p/a_gen.go", Line: 3, Generator: "a"},
+			{Filename: "p/p.go", Line: 7},
+		},
+	}}}
+	want := "generated code declares conflicting identifiers: F in p declared by p/a_gen.go:3 (a) and p/p.go:7"
+	if got := e.Error(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,342 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+)
+
+// ConstraintMode controls how a TypeParamStyle renders type constraints.
+type ConstraintMode int
+
+const (
+	// InlineConstraints writes constraints in the type parameter list, such
+	// as [T ~int | ~string].
+	InlineConstraints ConstraintMode = iota
+
+	// NamedConstraints declares each composite constraint as a named
+	// constraint interface and refers to it by name, such as [T Constraint1].
+	NamedConstraints
+
+	// SharedConstraints declares named constraint interfaces only for
+	// composite constraints registered for more than one declaration and
+	// writes the others inline.
+	SharedConstraints
+)
+
+// TypeParamStyle controls how generated generic code names its type
+// parameters and renders their constraints. A composite constraint is an
+// interface other than any, such as ~int | ~string or interface{ String()
+// string }; named constraints such as comparable or fmt.Stringer are always
+// written by name. Constraints that refer to type parameters, such as ~[]E,
+// are always written inline since a named constraint cannot refer to them.
+//
+// Named constraint interfaces are collected by the style as type parameter
+// lists are rendered and are written by WriteConstraints, typically to a
+// shared constraints file in the package. With SharedConstraints the type
+// parameters of every declaration must be registered with Register before
+// any list is rendered. A TypeParamStyle is safe for concurrent use.
+type TypeParamStyle struct {
+	// Rename, if not nil, returns the name to use for the type parameter
+	// with the given index and original name. Uses of the parameter in the
+	// constraints of the list are renamed too.
+	Rename func(index int, name string) string
+
+	// Mode controls how composite constraints are rendered.
+	Mode ConstraintMode
+
+	// ConstraintName, if not nil, returns the name of the nth named
+	// constraint interface, counting from one, in the order the
+	// constraints are first used. By default they are named Constraint1,
+	// Constraint2 and so on.
+	ConstraintName func(n int) string
+
+	mu    sync.Mutex
+	named []*NamedConstraint
+	uses  []*constraintUse // constraints registered with Register
+}
+
+// NamedConstraint is a constraint interface declared by a TypeParamStyle.
+type NamedConstraint struct {
+	// Name is the name of the interface.
+	Name string
+
+	// Constraint is the constraint it declares.
+	Constraint types.Type
+}
+
+// constraintUse counts the declarations using a constraint.
+type constraintUse struct {
+	constraint types.Type
+	decls      int
+}
+
+// ShortTypeParamNames is a TypeParamStyle.Rename function that names type
+// parameters T, U, V and W, followed by T4, T5 and so on.
+func ShortTypeParamNames(index int, name string) string {
+	if index < 4 {
+		return string("TUVW"[index])
+	}
+	return fmt.Sprintf("T%d", index)
+}
+
+// Register records that a declaration uses the constraints of the type
+// parameters tps, for use by SharedConstraints.
+func (s *TypeParamStyle) Register(tps []*TypeParamModel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var seen []types.Type
+	for _, tp := range tps {
+		if !isComposite(tp.Constraint) || containsType(seen, tp.Constraint) {
+			continue
+		}
+		seen = append(seen, tp.Constraint)
+		if u := s.use(tp.Constraint); u != nil {
+			u.decls++
+			continue
+		}
+		s.uses = append(s.uses, &constraintUse{constraint: tp.Constraint, decls: 1})
+	}
+}
+
+// ParamList returns the type parameter list tps written in the style, such as
+// "[T any, U Constraint1]", using q to qualify the names of types in
+// constraints. It returns an empty string if tps is empty.
+func (s *TypeParamStyle) ParamList(tps []*TypeParamModel, q types.Qualifier) string {
+	if len(tps) == 0 {
+		return ""
+	}
+	names := s.names(tps)
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, tp := range tps {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(names[tp.Name])
+		if i+1 < len(tps) && types.Identical(tp.Constraint, tps[i+1].Constraint) {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(s.constraint(tp, names, q))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// ArgList returns the names of the type parameters tps written in the style
+// as a type argument list, such as "[T, U]". It returns an empty string if tps
+// is empty.
+func (s *TypeParamStyle) ArgList(tps []*TypeParamModel) string {
+	if len(tps) == 0 {
+		return ""
+	}
+	names := s.names(tps)
+	args := make([]string, len(tps))
+	for i, tp := range tps {
+		args[i] = names[tp.Name]
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+// Constraints returns the named constraint interfaces used so far, in the
+// order they were first used.
+func (s *TypeParamStyle) Constraints() []*NamedConstraint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*NamedConstraint(nil), s.named...)
+}
+
+// WriteConstraints writes the declarations of the named constraint
+// interfaces used so far to o, using q to qualify the names of types. It
+// writes nothing if none have been used. Call it once every type parameter
+// list has been rendered.
+func (s *TypeParamStyle) WriteConstraints(o *Output, q types.Qualifier) {
+	for _, nc := range s.Constraints() {
+		iface := nc.Constraint.Underlying().(*types.Interface)
+		if iface.IsImplicit() && iface.NumEmbeddeds() == 1 {
+			o.Printf("type %s interface {\n\t%s\n}\n\n", nc.Name, types.TypeString(iface.EmbeddedType(0), q))
+			continue
+		}
+		o.Printf("type %s %s\n\n", nc.Name, types.TypeString(iface, q))
+	}
+}
+
+// names returns the names to use for the type parameters tps, keyed by their
+// original names.
+func (s *TypeParamStyle) names(tps []*TypeParamModel) map[string]string {
+	names := make(map[string]string, len(tps))
+	for i, tp := range tps {
+		names[tp.Name] = tp.Name
+		if s.Rename != nil {
+			names[tp.Name] = s.Rename(i, tp.Name)
+		}
+	}
+	return names
+}
+
+// constraint returns the constraint of tp written in the style.
+func (s *TypeParamStyle) constraint(tp *TypeParamModel, names map[string]string, q types.Qualifier) string {
+	if isComposite(tp.Constraint) && !refersToTypeParam(tp.Constraint) {
+		if name, ok := s.constraintName(tp.Constraint); ok {
+			return name
+		}
+	}
+	c := constraintString(tp.Constraint, q)
+	if s.Rename == nil {
+		return c
+	}
+	return renameIdents(c, names)
+}
+
+// constraintName returns the name of the constraint interface declared for
+// the composite constraint c, declaring it if necessary. The boolean result
+// is false if c is written inline.
+func (s *TypeParamStyle) constraintName(c types.Type) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.Mode {
+	case InlineConstraints:
+		return "", false
+	case SharedConstraints:
+		if u := s.use(c); u == nil || u.decls < 2 {
+			return "", false
+		}
+	}
+	for _, nc := range s.named {
+		if types.Identical(nc.Constraint, c) {
+			return nc.Name, true
+		}
+	}
+	name := fmt.Sprintf("Constraint%d", len(s.named)+1)
+	if s.ConstraintName != nil {
+		name = s.ConstraintName(len(s.named) + 1)
+	}
+	s.named = append(s.named, &NamedConstraint{Name: name, Constraint: c})
+	return name, true
+}
+
+// use returns the registered use of the constraint c, if any. s.mu must be
+// held.
+func (s *TypeParamStyle) use(c types.Type) *constraintUse {
+	for _, u := range s.uses {
+		if types.Identical(u.constraint, c) {
+			return u
+		}
+	}
+	return nil
+}
+
+// isComposite reports whether the constraint t is an interface written
+// literally, other than any.
+func isComposite(t types.Type) bool {
+	iface, ok := types.Unalias(t).(*types.Interface)
+	return ok && !iface.Empty()
+}
+
+// refersToTypeParam reports whether t refers to a type parameter.
+func refersToTypeParam(t types.Type) bool {
+	found := false
+	var visit func(t types.Type)
+	seen := make(map[types.Type]bool)
+	visit = func(t types.Type) {
+		if found || seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t := t.(type) {
+		case *types.TypeParam:
+			found = true
+		case *types.Interface:
+			for i := 0; i < t.NumEmbeddeds(); i++ {
+				visit(t.EmbeddedType(i))
+			}
+			for i := 0; i < t.NumExplicitMethods(); i++ {
+				visit(t.ExplicitMethod(i).Type())
+			}
+		case *types.Union:
+			for i := 0; i < t.Len(); i++ {
+				visit(t.Term(i).Type())
+			}
+		case *types.Signature:
+			for i := 0; i < t.Params().Len(); i++ {
+				visit(t.Params().At(i).Type())
+			}
+			for i := 0; i < t.Results().Len(); i++ {
+				visit(t.Results().At(i).Type())
+			}
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case interface{ Elem() types.Type }:
+			visit(t.Elem())
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				visit(t.Field(i).Type())
+			}
+		case *types.Named:
+			for i := 0; i < t.TypeArgs().Len(); i++ {
+				visit(t.TypeArgs().At(i))
+			}
+		}
+	}
+	visit(t)
+	return found
+}
+
+// containsType reports whether ts contains a type identical to t.
+func containsType(ts []types.Type, t types.Type) bool {
+	for _, u := range ts {
+		if types.Identical(u, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// renameIdents renames the identifiers in the type expression expr according
+// to names, leaving field and method names and qualified identifiers
+// unchanged. If expr cannot be parsed it is returned unchanged.
+func renameIdents(expr string, names map[string]string) string {
+	// Constraints may be unions, which are not expressions, so parse the
+	// constraint as the embedded element of an interface.
+	src := "package p; type _ interface{ " + expr + " }"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return expr
+	}
+	elem := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.InterfaceType).Methods.List[0].Type
+
+	skip := make(map[*ast.Ident]bool)
+	ast.Inspect(elem, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			for _, id := range n.Names {
+				skip[id] = true
+			}
+		case *ast.SelectorExpr:
+			skip[n.Sel] = true
+			if id, ok := n.X.(*ast.Ident); ok {
+				skip[id] = true
+			}
+		case *ast.Ident:
+			if to, ok := names[n.Name]; ok && !skip[n] {
+				n.Name = to
+			}
+		}
+		return true
+	})
+
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, fset, elem); err != nil {
+		return expr
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package gen
+
+import (
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestTypeParamStyle(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "fmt"
+
+		type Number interface{ ~int | ~float64 }
+
+		type Set[K comparable, V any] struct{}
+
+		type Sum[N ~int | ~float64] struct{}
+
+		type Avg[N ~int | ~float64, S fmt.Stringer] struct{}
+
+		type Printer[T interface{ String() string }] struct{}
+
+		type Slice[S ~[]E, E any] struct{}
+
+		type Named[N Number] struct{}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+	tps := func(name string) []*TypeParamModel {
+		m, ok := fs.Type(name)
+		if !ok {
+			t.Fatalf("type %s not found", name)
+		}
+		return m.TypeParams
+	}
+	names := []string{"Set", "Sum", "Avg", "Printer", "Slice", "Named"}
+
+	testCases := []struct {
+		name        string
+		style       *TypeParamStyle
+		want        []string
+		constraints string
+	}{
+		{
+			name:  "inline",
+			style: &TypeParamStyle{},
+			want: []string{
+				"[K comparable, V any]",
+				"[N ~int | ~float64]",
+				"[N ~int | ~float64, S fmt.Stringer]",
+				"[T interface{String() string}]",
+				"[S ~[]E, E any]",
+				"[N Number]",
+			},
+		},
+		{
+			name:  "renamed",
+			style: &TypeParamStyle{Rename: ShortTypeParamNames},
+			want: []string{
+				"[T comparable, U any]",
+				"[T ~int | ~float64]",
+				"[T ~int | ~float64, U fmt.Stringer]",
+				"[T interface{ String() string }]",
+				"[T ~[]U, U any]",
+				"[T Number]",
+			},
+		},
+		{
+			name:  "named",
+			style: &TypeParamStyle{Mode: NamedConstraints},
+			want: []string{
+				"[K comparable, V any]",
+				"[N Constraint1]",
+				"[N Constraint1, S fmt.Stringer]",
+				"[T Constraint2]",
+				"[S ~[]E, E any]",
+				"[N Number]",
+			},
+			constraints: "type Constraint1 interface {\n\t~int | ~float64\n}\n\ntype Constraint2 interface{String() string}\n\n",
+		},
+		{
+			name: "shared",
+			style: &TypeParamStyle{Mode: SharedConstraints, ConstraintName: func(n int) string {
+				return []string{"", "Numeric"}[n]
+			}},
+			want: []string{
+				"[K comparable, V any]",
+				"[N Numeric]",
+				"[N Numeric, S fmt.Stringer]",
+				"[T interface{String() string}]",
+				"[S ~[]E, E any]",
+				"[N Number]",
+			},
+			constraints: "type Numeric interface {\n\t~int | ~float64\n}\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range names {
+				tc.style.Register(tps(name))
+			}
+			for i, name := range names {
+				if got := tc.style.ParamList(tps(name), q); got != tc.want[i] {
+					t.Errorf("%s: got %q, wanted %q", name, got, tc.want[i])
+				}
+			}
+			o := NewOutput("gen")
+			tc.style.WriteConstraints(o, q)
+			if got := string(o.Bytes()); got != tc.constraints {
+				t.Errorf("got constraints %q, wanted %q", got, tc.constraints)
+			}
+		})
+	}
+
+	style := &TypeParamStyle{Rename: ShortTypeParamNames}
+	if got, want := style.ArgList(tps("Slice")), "[T, U]"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if got := style.ParamList(nil, q); got != "" {
+		t.Errorf("got %q for no type parameters", got)
+	}
+
+	// The constraints file is valid Go.
+	style = &TypeParamStyle{Mode: NamedConstraints}
+	style.ParamList(tps("Avg"), q)
+	style.ParamList(tps("Printer"), q)
+	o := NewOutput("gen")
+	o.Printf("package p\n\n")
+	style.WriteConstraints(o, q)
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(src), "type Constraint2 interface{ String() string }") {
+		t.Errorf("got:\n%s", src)
+	}
+}
@@ -0,0 +1,146 @@
+package gen
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+)
+
+// ConstModel describes a constant and its evaluated value.
+type ConstModel struct {
+	// Name is the name of the constant.
+	Name string
+
+	// Type is the type of the constant. It is an untyped basic type for
+	// untyped constants.
+	Type types.Type
+
+	// Value is the evaluated value of the constant.
+	Value constant.Value
+
+	// Literal is the value written as a Go literal, such as 3 or "red".
+	Literal string
+
+	// Iota is the value of iota in the constant's specification, which is
+	// its index within its declaration.
+	Iota int
+
+	// Expr is the expression that gives the constant its value. For a
+	// specification without values, which repeats the values of the previous
+	// specification in its declaration, Expr is the repeated expression and
+	// is evaluated with the constant's own value of iota. Expr is nil if the
+	// constant has no corresponding expression.
+	Expr ast.Expr
+
+	// Doc is the text of the constant's doc comment. For a declaration
+	// containing a single specification it falls back to the declaration's
+	// doc comment.
+	Doc string
+
+	// Comment is the text of the constant's line comment.
+	Comment string
+
+	// Spec is the specification that declares the constant.
+	Spec *ast.ValueSpec
+
+	// Object is the type checked constant object.
+	Object *types.Const
+}
+
+// Consts returns models of the package level constants declared in fs, in
+// file and position order. Blank constants are not included.
+func (fs *FileSet) Consts() []*ConstModel {
+	models := []*ConstModel{}
+	for _, f := range fs.AstFiles {
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.CONST {
+				models = fs.appendConstModels(models, gd, nil)
+			}
+		}
+	}
+	return models
+}
+
+// Const returns a model of the named package level constant. The boolean
+// result is false if no such constant is declared.
+func (fs *FileSet) Const(name string) (*ConstModel, bool) {
+	for _, m := range fs.Consts() {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// ConstValues returns models of the constants declared by vs, such as a
+// specification passed to EachConst, in the order they are named. Blank
+// constants are not included. ConstValues returns nil if vs is not a constant
+// specification in fs.
+func (fs *FileSet) ConstValues(vs *ast.ValueSpec) []*ConstModel {
+	var gd *ast.GenDecl
+	fs.Inspect(func(node ast.Node) bool {
+		if gd != nil {
+			return false
+		}
+		if d, ok := node.(*ast.GenDecl); ok && d.Tok == token.CONST {
+			for _, spec := range d.Specs {
+				if spec == vs {
+					gd = d
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if gd == nil {
+		return nil
+	}
+	return fs.appendConstModels(nil, gd, vs)
+}
+
+// appendConstModels appends models of the constants declared by gd to models.
+// If only is not nil, just the constants declared by that specification are
+// appended.
+func (fs *FileSet) appendConstModels(models []*ConstModel, gd *ast.GenDecl, only *ast.ValueSpec) []*ConstModel {
+	// A specification without values repeats the values of the previous
+	// specification, so track the values in effect.
+	var values []ast.Expr
+	for i, spec := range gd.Specs {
+		vs := spec.(*ast.ValueSpec)
+		if len(vs.Values) > 0 {
+			values = vs.Values
+		}
+		if only != nil && vs != only {
+			continue
+		}
+
+		doc := vs.Doc.Text()
+		if doc == "" && len(gd.Specs) == 1 {
+			doc = gd.Doc.Text()
+		}
+		for j, id := range vs.Names {
+			c, ok := fs.TypeInfo.Defs[id].(*types.Const)
+			if !ok || id.Name == "_" {
+				continue
+			}
+			var expr ast.Expr
+			if j < len(values) {
+				expr = values[j]
+			}
+			models = append(models, &ConstModel{
+				Name:    id.Name,
+				Type:    c.Type(),
+				Value:   c.Val(),
+				Literal: constLiteral(c.Val()),
+				Iota:    i,
+				Expr:    expr,
+				Doc:     doc,
+				Comment: vs.Comment.Text(),
+				Spec:    vs,
+				Object:  c,
+			})
+		}
+	}
+	return models
+}
@@ -0,0 +1,109 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestConsts(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type Weekday int
+
+		const (
+			Sunday Weekday = iota
+			Monday
+			// Tuesday is the third day.
+			Tuesday
+		)
+
+		const (
+			_  = iota
+			KB = 1 << (10 * iota)
+			MB
+		)
+
+		// Greeting is used to say hello.
+		const Greeting = "hello " + "world"
+
+		const Pi, Third = 3.14159, 1.0 / 3
+
+		const (
+			A, B = iota * 10, "b" // pair
+			C, D
+		)
+
+		func f() {
+			const local = 7
+		}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+
+	type summary struct {
+		Name, Type, Literal, Doc, Comment string
+		Iota                              int
+		Expr                              string
+	}
+	summarize := func(models []*ConstModel) []summary {
+		s := []summary{}
+		for _, m := range models {
+			expr := ""
+			if m.Expr != nil {
+				expr = types.ExprString(m.Expr)
+			}
+			s = append(s, summary{
+				Name:    m.Name,
+				Type:    types.TypeString(m.Type, q),
+				Literal: m.Literal,
+				Doc:     m.Doc,
+				Comment: m.Comment,
+				Iota:    m.Iota,
+				Expr:    expr,
+			})
+		}
+		return s
+	}
+
+	want := []summary{
+		{Name: "Sunday", Type: "Weekday", Literal: "0", Iota: 0, Expr: "iota"},
+		{Name: "Monday", Type: "Weekday", Literal: "1", Iota: 1, Expr: "iota"},
+		{Name: "Tuesday", Type: "Weekday", Literal: "2", Doc: "Tuesday is the third day.\n", Iota: 2, Expr: "iota"},
+		{Name: "KB", Type: "untyped int", Literal: "1024", Iota: 1, Expr: "1 << (10 * iota)"},
+		{Name: "MB", Type: "untyped int", Literal: "1048576", Iota: 2, Expr: "1 << (10 * iota)"},
+		{Name: "Greeting", Type: "untyped string", Literal: `"hello world"`, Doc: "Greeting is used to say hello.\n", Expr: `"hello " + "world"`},
+		{Name: "Pi", Type: "untyped float", Literal: "3.14159", Expr: "3.14159"},
+		{Name: "Third", Type: "untyped float", Literal: "1.0/3", Expr: "1.0 / 3"},
+		{Name: "A", Type: "untyped int", Literal: "0", Comment: "pair\n", Expr: "iota * 10"},
+		{Name: "B", Type: "untyped string", Literal: `"b"`, Comment: "pair\n", Expr: `"b"`},
+		{Name: "C", Type: "untyped int", Literal: "10", Iota: 1, Expr: "iota * 10"},
+		{Name: "D", Type: "untyped string", Literal: `"b"`, Iota: 1, Expr: `"b"`},
+	}
+	if got := summarize(fs.Consts()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if m, ok := fs.Const("MB"); !ok || m.Literal != "1048576" {
+		t.Errorf("got %+v, %v for MB", m, ok)
+	}
+	if _, ok := fs.Const("local"); ok {
+		t.Errorf("got model for local constant, wanted none")
+	}
+
+	got := []summary{}
+	fs.EachConst(func(vs *ast.ValueSpec) bool {
+		got = append(got, summarize(fs.ConstValues(vs))...)
+		return true
+	})
+	if len(got) != len(want)+1 || got[len(got)-1].Name != "local" || got[len(got)-1].Literal != "7" {
+		t.Errorf("got %+v from EachConst", got)
+	}
+
+	if got := fs.ConstValues(&ast.ValueSpec{}); got != nil {
+		t.Errorf("got %+v for unknown spec, wanted nil", got)
+	}
+}
@@ -0,0 +1,180 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ImportCycleError is returned when generated code would import a package
+// that depends on the package the code is written to.
+type ImportCycleError struct {
+	// Cycle holds the import paths of the packages in the cycle, starting
+	// and ending with the package the code is written to.
+	Cycle []string
+}
+
+func (e *ImportCycleError) Error() string {
+	return "import cycle not allowed: " + strings.Join(e.Cycle, " -> ")
+}
+
+// CheckImports reports whether a file that imports the packages with the
+// given paths may be added to the package of fs. It returns an
+// *ImportCycleError if any of the packages depends, directly or indirectly,
+// on the package of fs, since generated code that imports one could not be
+// compiled. The dependencies of the packages are listed by the go command
+// using the FileSet's build configuration, so fs must be in a module or
+// GOPATH.
+func (fs *FileSet) CheckImports(paths ...string) error {
+	self, err := fs.importPath()
+	if err != nil {
+		return err
+	}
+	graph, err := fs.importGraph(paths)
+	if err != nil {
+		return err
+	}
+	if cycle := findCycle(graph, self, paths); cycle != nil {
+		return &ImportCycleError{Cycle: cycle}
+	}
+	return nil
+}
+
+// OutputPackage returns the directory and import path of the package to
+// which to write a file that imports the packages with the given paths. It
+// is the package of fs unless that would create an import cycle, as reported
+// by CheckImports, in which case it is the sub-package of fs in the
+// directory sub, relative to fs.Dir. Moving the generated code to a
+// sub-package breaks the cycle since the sub-package may import both the
+// package of fs and the packages that depend on it. It is an error if the
+// sub-package would also create a cycle.
+func (fs *FileSet) OutputPackage(sub string, paths ...string) (dir, importPath string, err error) {
+	self, err := fs.importPath()
+	if err != nil {
+		return "", "", err
+	}
+	graph, err := fs.importGraph(paths)
+	if err != nil {
+		return "", "", err
+	}
+	if findCycle(graph, self, paths) == nil {
+		return fs.Dir, self, nil
+	}
+
+	subPath := path.Join(self, filepath.ToSlash(sub))
+	if cycle := findCycle(graph, subPath, paths); cycle != nil {
+		return "", "", &ImportCycleError{Cycle: cycle}
+	}
+	return filepath.Join(fs.Dir, sub), subPath, nil
+}
+
+// importPath returns the import path of the package of fs. It is only known
+// for packages loaded from files on disk.
+func (fs *FileSet) importPath() (string, error) {
+	if fs.pkgPath != "" {
+		return fs.pkgPath, nil
+	}
+	if len(fs.Files) > 0 && fs.fsys == nil {
+		if p := dirImportPath(fs.Dir); p != "" {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%s: cannot determine import path", fs.Dir)
+}
+
+// importGraph returns the imports of the packages with the given paths and
+// all of their dependencies, keyed by import path.
+func (fs *FileSet) importGraph(paths []string) (map[string][]string, error) {
+	var list []string
+	for _, p := range paths {
+		if p != "unsafe" && p != "C" {
+			list = append(list, p)
+		}
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	dir := fs.importDir
+	if dir == "" {
+		dir = fs.Dir
+	}
+	return listImports(dir, fs.opts.buildContext(), list...)
+}
+
+// findCycle returns the import cycle created by adding imports of paths to
+// the package self, or nil if there is none. The cycle is the shortest path
+// through graph from one of paths to self, preceded by self.
+func findCycle(graph map[string][]string, self string, paths []string) []string {
+	parent := make(map[string]string)
+	var queue []string
+	for _, p := range paths {
+		if _, ok := parent[p]; !ok {
+			parent[p] = ""
+			queue = append(queue, p)
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == self {
+			cycle := []string{self}
+			for ; p != ""; p = parent[p] {
+				cycle = append(cycle, p)
+			}
+			// The path was followed back from self, so all but the
+			// first element are reversed.
+			for i, j := 1, len(cycle)-1; i < j; i, j = i+1, j-1 {
+				cycle[i], cycle[j] = cycle[j], cycle[i]
+			}
+			return cycle
+		}
+		for _, imp := range graph[p] {
+			if _, ok := parent[imp]; !ok {
+				parent[imp] = p
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return nil
+}
+
+// listImports runs the go command in dir to list the imports of the packages
+// with the given paths and all of their dependencies using the build
+// configuration of ctxt. The imports are keyed by import path.
+func listImports(dir string, ctxt *build.Context, paths ...string) (map[string][]string, error) {
+	args := []string{"list", "-deps", "-f", "{{.ImportPath}}{{range .Imports}} {{.}}{{end}}"}
+	if len(ctxt.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(ctxt.BuildTags, ","))
+	}
+	args = append(args, paths...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS="+ctxt.GOOS, "GOARCH="+ctxt.GOARCH)
+	if !ctxt.CgoEnabled {
+		cmd.Env = append(cmd.Env, "CGO_ENABLED=0")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list %s: %w: %s", strings.Join(paths, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	graph := make(map[string][]string)
+	s := bufio.NewScanner(&stdout)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 0 {
+			graph[fields[0]] = fields[1:]
+		}
+	}
+	return graph, s.Err()
+}
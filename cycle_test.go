@@ -0,0 +1,59 @@
+package gen
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckImports(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":   "package p\n\ntype T int\n",
+		"q/q.go": "package q\n\nimport \"example.com/p/r\"\n\nvar _ = r.X\n",
+		"r/r.go": "package r\n\nimport \"example.com/p\"\n\nvar X p.T\n",
+		"s/s.go": "package s\n\nconst Y = 1\n",
+	})
+	fs, err := FileSetFromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := fs.CheckImports("fmt", "example.com/p/s"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = fs.CheckImports("fmt", "example.com/p/q")
+	var cycle *ImportCycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("got error %v, wanted import cycle", err)
+	}
+	want := []string{"example.com/p", "example.com/p/q", "example.com/p/r", "example.com/p"}
+	if !reflect.DeepEqual(cycle.Cycle, want) {
+		t.Errorf("got %+v, wanted %+v", cycle.Cycle, want)
+	}
+
+	gotDir, gotPath, err := fs.OutputPackage("pgen", "example.com/p/s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotDir != dir || gotPath != "example.com/p" {
+		t.Errorf("got %s %s, wanted the package itself", gotDir, gotPath)
+	}
+	gotDir, gotPath, err = fs.OutputPackage("pgen", "example.com/p/r")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotDir != filepath.Join(dir, "pgen") || gotPath != "example.com/p/pgen" {
+		t.Errorf("got %s %s, wanted the sub-package pgen", gotDir, gotPath)
+	}
+
+	// The sub-package r is itself a dependency of q.
+	if _, _, err := fs.OutputPackage("r", "example.com/p/q"); !errors.As(err, &cycle) {
+		t.Errorf("got error %v, wanted import cycle", err)
+	}
+}
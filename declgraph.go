@@ -0,0 +1,316 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// DeclGraph is a graph of the references between the package level
+// declarations of a FileSet. Declarations are identified by name as in a
+// CallGraph, so that a method is named using its receiver's base type name
+// and the method name separated by a dot, such as "T.String". Blank
+// declarations and init functions are not included.
+type DeclGraph struct {
+	names   []string
+	index   map[string]int
+	objs    []types.Object
+	refs    [][]int // sorted by declaration order
+	referrs [][]int // sorted by declaration order
+}
+
+// DeclCycleError is returned when the declarations of a DeclGraph cannot be
+// ordered because they refer to each other.
+type DeclCycleError struct {
+	// Cycle holds the names of the declarations in the cycle, starting and
+	// ending with the same declaration.
+	Cycle []string
+}
+
+func (e *DeclCycleError) Error() string {
+	return "declaration cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+// DeclGraph builds a graph of the references between the package level
+// types, functions, methods, variables and constants declared in fs, using
+// the identifiers resolved by the type checker. A declaration refers to
+// another if the other's name is used anywhere in it: in a function's
+// signature or body, a type's definition or a variable's type or initial
+// value. A method is referred to by any selector that resolves to it. A
+// declaration's references to itself are ignored.
+func (fs *FileSet) DeclGraph() *DeclGraph {
+	g := &DeclGraph{index: make(map[string]int)}
+	var nodes []ast.Node
+	add := func(name string, obj types.Object, n ast.Node) {
+		if name == "_" || name == "init" {
+			return
+		}
+		if _, dup := g.index[name]; dup {
+			return
+		}
+		g.index[name] = len(g.names)
+		g.names = append(g.names, name)
+		g.objs = append(g.objs, obj)
+		nodes = append(nodes, n)
+	}
+
+	for _, file := range fs.AstFiles {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if fn, ok := fs.TypeInfo.Defs[decl.Name].(*types.Func); ok {
+					add(declName(fn), fn, decl)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if obj := fs.TypeInfo.Defs[spec.Name]; obj != nil {
+							add(obj.Name(), obj, spec)
+						}
+					case *ast.ValueSpec:
+						for i, id := range spec.Names {
+							obj := fs.TypeInfo.Defs[id]
+							if obj == nil {
+								continue
+							}
+							// A name takes its part of the values, unless
+							// they are the results of a single call.
+							var n ast.Node = spec
+							if len(spec.Values) == len(spec.Names) {
+								n = &ast.ValueSpec{Type: spec.Type, Values: spec.Values[i : i+1]}
+							}
+							add(obj.Name(), obj, n)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	g.refs = make([][]int, len(g.names))
+	g.referrs = make([][]int, len(g.names))
+	for i, n := range nodes {
+		seen := make(map[int]bool)
+		ast.Inspect(n, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			j, ok := g.lookup(fs.Package, fs.TypeInfo.Uses[id])
+			if ok && j != i && !seen[j] {
+				seen[j] = true
+				g.refs[i] = append(g.refs[i], j)
+				g.referrs[j] = append(g.referrs[j], i)
+			}
+			return true
+		})
+		sort.Ints(g.refs[i])
+	}
+	return g
+}
+
+// lookup returns the index of the declaration of obj, if it is a package
+// level declaration of pkg in the graph.
+func (g *DeclGraph) lookup(pkg *types.Package, obj types.Object) (int, bool) {
+	if obj == nil || obj.Pkg() != pkg {
+		return 0, false
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		obj = fn.Origin()
+	}
+	i, ok := g.index[declName(obj)]
+	if !ok || g.objs[i] != obj {
+		return 0, false
+	}
+	return i, true
+}
+
+// declName returns the name used to identify obj in a DeclGraph.
+func declName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			return recvTypeName(recv.Type()) + "." + fn.Name()
+		}
+	}
+	return obj.Name()
+}
+
+// Decls returns the names of the declarations in the graph in the order they
+// are declared.
+func (g *DeclGraph) Decls() []string {
+	return append([]string(nil), g.names...)
+}
+
+// Object returns the type checked object of the named declaration, or nil if
+// there is no such declaration.
+func (g *DeclGraph) Object(name string) types.Object {
+	i, ok := g.index[name]
+	if !ok {
+		return nil
+	}
+	return g.objs[i]
+}
+
+// Refs returns the names of the declarations that the named declaration
+// refers to, in the order they are declared.
+func (g *DeclGraph) Refs(name string) []string {
+	i, ok := g.index[name]
+	if !ok {
+		return nil
+	}
+	return g.namesOf(g.refs[i])
+}
+
+// Referrers returns the names of the declarations that refer to the named
+// declaration, in the order they are declared.
+func (g *DeclGraph) Referrers(name string) []string {
+	i, ok := g.index[name]
+	if !ok {
+		return nil
+	}
+	return g.namesOf(g.referrs[i])
+}
+
+func (g *DeclGraph) namesOf(idx []int) []string {
+	names := make([]string, len(idx))
+	for k, i := range idx {
+		names[k] = g.names[i]
+	}
+	return names
+}
+
+// TopoSort returns the names of the declarations ordered so that each
+// follows the declarations it refers to. Among declarations whose
+// references are satisfied, the one declared first comes first, so the
+// order is deterministic and follows the source where it can. It returns a
+// *DeclCycleError if declarations refer to each other, directly or
+// indirectly.
+func (g *DeclGraph) TopoSort() ([]string, error) {
+	pending := make([]int, len(g.names))
+	var ready []int // sorted by declaration order
+	for i, refs := range g.refs {
+		pending[i] = len(refs)
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := make([]string, 0, len(g.names))
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, g.names[i])
+		for _, j := range g.referrs[i] {
+			if pending[j]--; pending[j] == 0 {
+				k := sort.SearchInts(ready, j)
+				ready = append(ready, 0)
+				copy(ready[k+1:], ready[k:])
+				ready[k] = j
+			}
+		}
+	}
+	if len(order) < len(g.names) {
+		return nil, &DeclCycleError{Cycle: g.cycle(g.Cycles()[0])}
+	}
+	return order, nil
+}
+
+// Cycles returns the groups of declarations that refer to each other,
+// directly or indirectly. Each group lists its declarations in the order
+// they are declared, and the groups are ordered by their first declaration.
+// Recursive functions and types that refer only to themselves do not form a
+// group.
+func (g *DeclGraph) Cycles() [][]string {
+	// Tarjan's algorithm finds the strongly connected components.
+	n := len(g.names)
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	var sccs [][]int
+	next := 0
+
+	var visit func(int)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.refs[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sort.Ints(scc)
+			sccs = append(sccs, scc)
+		}
+	}
+	for v := range n {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+
+	sort.Slice(sccs, func(i, j int) bool { return sccs[i][0] < sccs[j][0] })
+	cycles := make([][]string, len(sccs))
+	for i, scc := range sccs {
+		cycles[i] = g.namesOf(scc)
+	}
+	return cycles
+}
+
+// cycle returns a path through the graph from the first of the named
+// declarations, which refer to each other, back to itself.
+func (g *DeclGraph) cycle(names []string) []string {
+	in := make(map[int]bool)
+	for _, name := range names {
+		in[g.index[name]] = true
+	}
+	start := g.index[names[0]]
+	parent := map[int]int{start: -1}
+	queue := []int{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range g.refs[v] {
+			if w == start {
+				path := []string{g.names[start]}
+				for ; v >= 0; v = parent[v] {
+					path = append(path, g.names[v])
+				}
+				// The path was followed back to start, so reverse it.
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			if _, ok := parent[w]; !ok && in[w] {
+				parent[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,135 @@
+package gen
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDeclGraph(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		var registry = map[string]Handler{
+			"a": newA(),
+		}
+
+		type Handler interface{ Handle(*Request) }
+
+		type Request struct {
+			Path string
+			next *Request
+		}
+
+		type a struct{ prefix string }
+
+		func (h a) Handle(r *Request) { h.log(r.Path) }
+
+		func (h a) log(s string) { _ = defaultPrefix + s }
+
+		func newA() a { return a{prefix: defaultPrefix} }
+
+		const defaultPrefix = "/" + name
+
+		const name = "a"
+
+		var _ = newA
+
+		func init() { registry["b"] = newA() }
+
+		func count(n int) int {
+			if n == 0 {
+				return 0
+			}
+			registry := 1
+			return registry + count(n-1)
+		}
+
+		type Box[T any] struct{ v T }
+
+		func (b Box[T]) Get() T { return b.v }
+
+		func unbox() int { return Box[int]{}.Get() }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g := fs.DeclGraph()
+	if want := []string{"registry", "Handler", "Request", "a", "a.Handle", "a.log", "newA", "defaultPrefix", "name", "count", "Box", "Box.Get", "unbox"}; !reflect.DeepEqual(g.Decls(), want) {
+		t.Errorf("got decls %v, wanted %v", g.Decls(), want)
+	}
+
+	refs := map[string][]string{
+		"registry":      {"Handler", "newA"},
+		"Handler":       {"Request"},
+		"Request":       {},
+		"a.Handle":      {"Request", "a", "a.log"},
+		"a.log":         {"a", "defaultPrefix"},
+		"newA":          {"a", "defaultPrefix"},
+		"defaultPrefix": {"name"},
+		"count":         {},
+		"Box.Get":       {"Box"},
+		"unbox":         {"Box", "Box.Get"},
+	}
+	for name, want := range refs {
+		if got := g.Refs(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got refs %v, wanted %v", name, got, want)
+		}
+	}
+	if got, want := g.Referrers("defaultPrefix"), []string{"a.log", "newA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got referrers %v, wanted %v", got, want)
+	}
+	if g.Object("a.Handle") == nil || g.Object("missing") != nil || g.Refs("missing") != nil {
+		t.Errorf("unexpected result for Object or Refs")
+	}
+
+	order, err := g.TopoSort()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Request", "Handler", "a", "name", "defaultPrefix", "a.log", "a.Handle", "newA", "registry", "count", "Box", "Box.Get", "unbox"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("got order %v, wanted %v", order, want)
+	}
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("got cycles %v, wanted none", cycles)
+	}
+}
+
+func TestDeclGraphCycles(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		func even(n int) bool { return n == 0 || odd(n-1) }
+
+		func odd(n int) bool { return n != 0 && even(n-1) }
+
+		type Node struct{ Children []*Node }
+
+		type First struct{ next *Second }
+
+		type Second struct{ next *Third }
+
+		type Third struct{ next *First }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g := fs.DeclGraph()
+	want := [][]string{{"even", "odd"}, {"First", "Second", "Third"}}
+	if got := g.Cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got cycles %v, wanted %v", got, want)
+	}
+
+	_, err = g.TopoSort()
+	var cycleErr *DeclCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("got error %v, wanted a *DeclCycleError", err)
+	}
+	if want := []string{"even", "odd", "even"}; !reflect.DeepEqual(cycleErr.Cycle, want) {
+		t.Errorf("got cycle %v, wanted %v", cycleErr.Cycle, want)
+	}
+	if got, want := err.Error(), "declaration cycle: even -> odd -> even"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
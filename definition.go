@@ -0,0 +1,352 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Definition describes a data model in a definition file rather than in Go
+// source, for schema-first generation. A FileSet created from a definition
+// with NewFileSetFromDefinition provides the same models as one loaded from
+// the equivalent Go source, so the same generators and templates can be used
+// for both. A definition written in YAML looks like:
+//
+//	package: api
+//	imports: [time]
+//	types:
+//	  - name: User
+//	    doc: User is a registered user.
+//	    fields:
+//	      - name: Name
+//	        type: string
+//	        tags: {json: name}
+//	      - name: Created
+//	        type: time.Time
+//	        comment: when the user registered
+//	  - name: Color
+//	    type: int
+//	    values: [Red, Green, Blue]
+//
+// The same structure may be written in TOML or JSON.
+type Definition struct {
+	// Package is the name of the package declaring the types.
+	Package string `json:"package" yaml:"package" toml:"package"`
+
+	// Imports holds the import paths of the packages whose types are used by
+	// fields, such as time for time.Time.
+	Imports []string `json:"imports,omitempty" yaml:"imports,omitempty" toml:"imports,omitempty"`
+
+	// Types holds the types of the model in declaration order.
+	Types []TypeDefinition `json:"types" yaml:"types" toml:"types"`
+}
+
+// TypeDefinition describes a named type of a Definition. A type with fields
+// is a struct type; otherwise Type gives its underlying type.
+type TypeDefinition struct {
+	// Name is the name of the type.
+	Name string `json:"name" yaml:"name" toml:"name"`
+
+	// Doc is the text of the type's doc comment.
+	Doc string `json:"doc,omitempty" yaml:"doc,omitempty" toml:"doc,omitempty"`
+
+	// Type is the underlying type of a type that is not a struct, written as
+	// a Go type expression such as int or map[string]string.
+	Type string `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+
+	// Fields holds the fields of a struct type in declaration order.
+	Fields []FieldDefinition `json:"fields,omitempty" yaml:"fields,omitempty" toml:"fields,omitempty"`
+
+	// Values holds the names of the constants of an enumeration, declared
+	// with consecutive values starting from zero using iota.
+	Values []string `json:"values,omitempty" yaml:"values,omitempty" toml:"values,omitempty"`
+}
+
+// FieldDefinition describes a field of a struct type of a Definition.
+type FieldDefinition struct {
+	// Name is the name of the field. It is empty for an embedded field.
+	Name string `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+
+	// Type is the type of the field written as a Go type expression, such
+	// as []*User or time.Time.
+	Type string `json:"type" yaml:"type" toml:"type"`
+
+	// Doc is the text of the field's doc comment.
+	Doc string `json:"doc,omitempty" yaml:"doc,omitempty" toml:"doc,omitempty"`
+
+	// Comment is the text of the field's line comment.
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty" toml:"comment,omitempty"`
+
+	// Tags holds the field's struct tag as a map from key to value, such as
+	// {json: "name,omitempty"}. Keys are written in sorted order.
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+}
+
+// ReadDefinition reads and validates the definition in filename, whose format
+// is chosen by its extension: .yaml, .yml, .toml or .json.
+func ReadDefinition(filename string) (*Definition, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ParseDefinition(data, strings.TrimPrefix(filepath.Ext(filename), "."))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return d, nil
+}
+
+// ParseDefinition parses and validates a definition in the given format:
+// yaml, yml, toml or json. Unknown keys are reported as errors.
+func ParseDefinition(data []byte, format string) (*Definition, error) {
+	d := new(Definition)
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(d); err != nil {
+			return nil, err
+		}
+	case "toml":
+		md, err := toml.Decode(string(data), d)
+		if err != nil {
+			return nil, err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown key %s", undecoded[0])
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(d); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported definition format %q", format)
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Validate checks that the definition is well formed: names are valid,
+// distinct identifiers, types are valid type expressions and every type
+// either has fields or an underlying type. All the problems found are
+// reported, each prefixed by its location in the definition such as
+// types[1].fields[0]. Whether the types referred to exist is checked when a
+// FileSet is created from the definition.
+func (d *Definition) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !token.IsIdentifier(d.Package) {
+		fail("package: invalid package name %q", d.Package)
+	}
+	for i, path := range d.Imports {
+		if path == "" || strings.ContainsAny(path, "\" \t") {
+			fail("imports[%d]: invalid import path %q", i, path)
+		}
+	}
+
+	declared := make(map[string]string) // name to location
+	declare := func(name, loc string) {
+		if !token.IsIdentifier(name) || name == "_" {
+			fail("%s: invalid name %q", loc, name)
+			return
+		}
+		if prev, ok := declared[name]; ok {
+			fail("%s: %s already declared at %s", loc, name, prev)
+			return
+		}
+		declared[name] = loc
+	}
+	checkType := func(expr, loc string) {
+		if expr == "" {
+			fail("%s: missing type", loc)
+		} else if _, err := parser.ParseExpr(expr); err != nil {
+			fail("%s: invalid type %q", loc, expr)
+		}
+	}
+
+	for i, td := range d.Types {
+		loc := fmt.Sprintf("types[%d]", i)
+		declare(td.Name, loc)
+		switch {
+		case len(td.Fields) > 0 && td.Type != "":
+			fail("%s: %s has both fields and a type", loc, td.Name)
+		case len(td.Fields) == 0 && td.Type == "" && len(td.Values) == 0:
+			fail("%s: %s has neither fields nor a type", loc, td.Name)
+		case td.Type != "":
+			checkType(td.Type, loc)
+		}
+		if len(td.Values) > 0 && len(td.Fields) > 0 {
+			fail("%s: struct type %s cannot have values", loc, td.Name)
+		}
+		for j, v := range td.Values {
+			declare(v, fmt.Sprintf("%s.values[%d]", loc, j))
+		}
+
+		fields := make(map[string]bool)
+		for j, fd := range td.Fields {
+			floc := fmt.Sprintf("%s.fields[%d]", loc, j)
+			checkType(fd.Type, floc)
+			name := fd.Name
+			if name == "" {
+				name = embeddedName(fd.Type)
+				if name == "" {
+					fail("%s: embedded field type %q is not a type name", floc, fd.Type)
+					continue
+				}
+			} else if !token.IsIdentifier(name) {
+				fail("%s: invalid field name %q", floc, name)
+				continue
+			}
+			if fields[name] && name != "_" {
+				fail("%s: duplicate field %s", floc, name)
+			}
+			fields[name] = true
+			for key := range fd.Tags {
+				if key == "" || strings.ContainsAny(key, " \t:\"`") {
+					fail("%s: invalid tag key %q", floc, key)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// embeddedName returns the name of an embedded field of type expr, or an
+// empty string if expr cannot be embedded.
+func embeddedName(expr string) string {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return ""
+	}
+	if id := embeddedIdent(e); id != nil {
+		return id.Name
+	}
+	return ""
+}
+
+// Source returns Go source code declaring the definition's types.
+func (d *Definition) Source() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\n", d.Package)
+	if len(d.Imports) > 0 {
+		b.WriteString("import (\n")
+		for _, path := range d.Imports {
+			fmt.Fprintf(&b, "\t%s\n", strconv.Quote(path))
+		}
+		b.WriteString(")\n\n")
+	}
+
+	for _, td := range d.Types {
+		writeDoc(&b, "", td.Doc)
+		switch {
+		case len(td.Fields) > 0:
+			fmt.Fprintf(&b, "type %s struct {\n", td.Name)
+			for _, fd := range td.Fields {
+				writeDoc(&b, "\t", fd.Doc)
+				b.WriteByte('\t')
+				if fd.Name != "" {
+					b.WriteString(fd.Name + " ")
+				}
+				b.WriteString(fd.Type)
+				if tag := fd.tag(); tag != "" {
+					b.WriteString(" " + strconv.Quote(tag))
+				}
+				if fd.Comment != "" {
+					b.WriteString(" // " + strings.ReplaceAll(fd.Comment, "\n", " "))
+				}
+				b.WriteByte('\n')
+			}
+			b.WriteString("}\n\n")
+		case td.Type != "":
+			fmt.Fprintf(&b, "type %s %s\n\n", td.Name, td.Type)
+		default:
+			fmt.Fprintf(&b, "type %s int\n\n", td.Name)
+		}
+
+		if len(td.Values) > 0 {
+			b.WriteString("const (\n")
+			for i, v := range td.Values {
+				if i == 0 {
+					fmt.Fprintf(&b, "\t%s %s = iota\n", v, td.Name)
+				} else {
+					fmt.Fprintf(&b, "\t%s\n", v)
+				}
+			}
+			b.WriteString(")\n\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// tag returns the struct tag of the field.
+func (fd *FieldDefinition) tag() string {
+	keys := SortedKeys(fd.Tags)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + ":" + strconv.Quote(fd.Tags[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+// writeDoc writes text as a comment, one line per line of text.
+func writeDoc(b *bytes.Buffer, indent, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(indent + "//")
+		if line != "" {
+			b.WriteString(" " + line)
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// NewFileSetFromDefinition creates a FileSet holding the types described by
+// d, as if they had been declared in a single Go source file named filename
+// in the directory dir. Positions in the FileSet and in type checking errors
+// refer to the source returned by d.Source. Options control how imported
+// packages are loaded.
+func NewFileSetFromDefinition(d *Definition, dir, filename string, opts ...Option) (*FileSet, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	name := filepath.Join(dir, filename)
+	src := d.Source()
+	fs := &FileSet{
+		Dir:     dir,
+		Files:   []string{name},
+		FileSet: token.NewFileSet(),
+		opts:    newOptions(opts),
+		pkgPath: dirImportPath(dir),
+		sources: sources{name: src},
+	}
+	f, err := parser.ParseFile(fs.FileSet, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("definition: %w", syntaxErrors(err, PhaseParse, fs.sources))
+	}
+	fs.AstFiles = []*ast.File{f}
+	if _, err := fs.Parse(); err != nil {
+		return nil, fmt.Errorf("definition: %w", err)
+	}
+	return fs, nil
+}
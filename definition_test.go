@@ -0,0 +1,261 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testDefinitions = map[string]string{
+	"yaml": `package: api
+imports: [time]
+types:
+  - name: User
+    doc: User is a registered user.
+    fields:
+      - name: Name
+        type: string
+        tags: {json: name, db: user_name}
+      - name: Created
+        type: time.Time
+        comment: when the user registered
+      - type: Audit
+  - name: Audit
+    fields:
+      - name: By
+        type: '*User'
+        doc: |-
+          By is the user who made the change.
+          It may be nil.
+  - name: Color
+    type: int
+    values: [Red, Green, Blue]
+`,
+	"toml": `package = "api"
+imports = ["time"]
+
+[[types]]
+name = "User"
+doc = "User is a registered user."
+
+[[types.fields]]
+name = "Name"
+type = "string"
+tags = { json = "name", db = "user_name" }
+
+[[types.fields]]
+name = "Created"
+type = "time.Time"
+comment = "when the user registered"
+
+[[types.fields]]
+type = "Audit"
+
+[[types]]
+name = "Audit"
+
+[[types.fields]]
+name = "By"
+type = "*User"
+doc = "By is the user who made the change.\nIt may be nil."
+
+[[types]]
+name = "Color"
+type = "int"
+values = ["Red", "Green", "Blue"]
+`,
+	"json": `{
+	"package": "api",
+	"imports": ["time"],
+	"types": [
+		{"name": "User", "doc": "User is a registered user.", "fields": [
+			{"name": "Name", "type": "string", "tags": {"json": "name", "db": "user_name"}},
+			{"name": "Created", "type": "time.Time", "comment": "when the user registered"},
+			{"type": "Audit"}
+		]},
+		{"name": "Audit", "fields": [
+			{"name": "By", "type": "*User", "doc": "By is the user who made the change.\nIt may be nil."}
+		]},
+		{"name": "Color", "type": "int", "values": ["Red", "Green", "Blue"]}
+	]
+}`,
+}
+
+const testDefinitionSource = `package api
+
+import (
+	"time"
+)
+
+// User is a registered user.
+type User struct {
+	Name string "db:\"user_name\" json:\"name\""
+	Created time.Time // when the user registered
+	Audit
+}
+
+type Audit struct {
+	// By is the user who made the change.
+	// It may be nil.
+	By *User
+}
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+`
+
+func TestParseDefinition(t *testing.T) {
+	for format, text := range testDefinitions {
+		t.Run(format, func(t *testing.T) {
+			d, err := ParseDefinition([]byte(text), format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := string(d.Source()); got != testDefinitionSource {
+				t.Errorf("got:\n%s\nwanted:\n%s", got, testDefinitionSource)
+			}
+		})
+	}
+}
+
+func TestNewFileSetFromDefinition(t *testing.T) {
+	d, err := ParseDefinition([]byte(testDefinitions["yaml"]), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fs, err := NewFileSetFromDefinition(d, t.TempDir(), "api.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user, ok := fs.Type("User")
+	if !ok {
+		t.Fatalf("User not found")
+	}
+	if user.Doc != "User is a registered user.\n" {
+		t.Errorf("got doc %q, wanted the definition's", user.Doc)
+	}
+	if len(user.Fields) != 3 {
+		t.Fatalf("got %d fields, wanted 3", len(user.Fields))
+	}
+	if tag, ok := user.Fields[0].Tags.Get("db"); !ok || tag.Name != "user_name" {
+		t.Errorf("got db tag %+v, wanted user_name", tag)
+	}
+	if ref := user.Fields[1].TypeRef(); ref.ImportPath != "time" {
+		t.Errorf("got import path %q, wanted time", ref.ImportPath)
+	}
+	if !user.Fields[2].Embedded {
+		t.Errorf("Audit field is not embedded")
+	}
+	consts := fs.Consts()
+	if len(consts) != 3 || consts[2].Name != "Blue" || consts[2].Literal != "2" {
+		t.Errorf("got constants %+v, wanted Red, Green and Blue", consts)
+	}
+
+	d.Types[0].Fields[0].Type = "Missing"
+	if _, err := NewFileSetFromDefinition(d, t.TempDir(), "api.go"); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("got error %v, wanted one naming the undefined type", err)
+	}
+}
+
+func TestDefinitionErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		text   string
+		want   []string
+	}{
+		{
+			name:   "unknown yaml key",
+			format: "yaml",
+			text:   "package: p\ntypes:\n  - name: T\n    type: int\n    colour: red\n",
+			want:   []string{"colour"},
+		},
+		{
+			name:   "unknown toml key",
+			format: "toml",
+			text:   "package = \"p\"\n[[types]]\nname = \"T\"\ntype = \"int\"\ncolour = \"red\"\n",
+			want:   []string{"colour"},
+		},
+		{
+			name:   "unknown json key",
+			format: "json",
+			text:   `{"package": "p", "typs": []}`,
+			want:   []string{"typs"},
+		},
+		{
+			name:   "unsupported format",
+			format: "xml",
+			want:   []string{"unsupported"},
+		},
+		{
+			name:   "invalid definition",
+			format: "yaml",
+			text: `package: 1p
+types:
+  - name: T
+  - name: T
+    type: "map[string"
+  - name: S
+    fields:
+      - name: A
+        type: int
+      - name: A
+        type: int
+      - type: "[]int"
+      - name: B
+        type: int
+        tags: {"bad key": x}
+`,
+			want: []string{
+				`package: invalid package name "1p"`,
+				"types[0]: T has neither fields nor a type",
+				"types[1]: T already declared at types[0]",
+				`types[1]: invalid type "map[string"`,
+				"types[2].fields[1]: duplicate field A",
+				`types[2].fields[2]: embedded field type "[]int" is not a type name`,
+				`types[2].fields[3]: invalid tag key "bad key"`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseDefinition([]byte(tc.text), tc.format)
+			if err == nil {
+				t.Fatalf("got no error, wanted one")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got error %q, wanted it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerDefinition(t *testing.T) {
+	dir := t.TempDir()
+	def := filepath.Join(dir, "model.yaml")
+	if err := os.WriteFile(def, []byte("package: model\ntypes:\n  - name: Shape\n    type: int\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := stringerRunner()
+	if err := r.Run([]string{"-definition", def, "-type", "Shape"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "shape_stringer.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `func (Shape) String() string { return "Shape" }`; !strings.Contains(string(got), want) {
+		t.Errorf("got:\n%s\nwanted it to contain %q", got, want)
+	}
+}
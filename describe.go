@@ -0,0 +1,233 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PackageModel describes the package loaded in a FileSet: its types, with
+// their fields and methods, and its functions. It is encoded by MarshalJSON
+// and MarshalYAML in a stable schema for consumption by tools not written in
+// Go, such as template engines in other languages:
+//
+//	name        the package name
+//	path        the import path of the package
+//	types       the named types, in declaration order, each with
+//	  name        the type name
+//	  kind        the kind of its underlying type, as given by TypeKind
+//	  underlying  the underlying type
+//	  doc         the doc comment, if any
+//	  typeParams  the type parameters, each with a name and constraint
+//	  fields      the struct fields, each with a name, type, embedded,
+//	              exported, doc, comment, tag and tags, which lists the key
+//	              and value of each tag in order
+//	  methods     the methods declared on the type, as for funcs
+//	funcs       the functions, in declaration order, each with
+//	  name        the function name
+//	  doc         the doc comment, if any
+//	  pointerReceiver  true for methods with a pointer receiver
+//	  typeParams  the type parameters
+//	  params      the parameters, each with a name and type
+//	  results     the results, each with a name and type
+//	  variadic    true if the last parameter is variadic
+//	  signature   the function's signature
+//
+// Types are written as in Go source code, qualified by the import path of
+// the package declaring them unless that is the described package. Fields
+// whose values are empty or false are omitted.
+type PackageModel struct {
+	// Name is the name of the package.
+	Name string
+
+	// Path is the import path of the package, or the path it was type
+	// checked with if the import path cannot be determined.
+	Path string
+
+	// Types holds the named types declared at package level, as returned by
+	// FileSet.Types.
+	Types []*TypeModel
+
+	// Funcs holds the functions and methods declared in the package, as
+	// returned by FileSet.Funcs.
+	Funcs []*FuncModel
+
+	pkg *types.Package
+}
+
+// Model returns a model of the package loaded in fs.
+func (fs *FileSet) Model() *PackageModel {
+	path, err := fs.importPath()
+	if err != nil {
+		path = fs.Package.Path()
+	}
+	return &PackageModel{
+		Name:  fs.Package.Name(),
+		Path:  path,
+		Types: fs.Types(),
+		Funcs: fs.Funcs(),
+		pkg:   fs.Package,
+	}
+}
+
+// MarshalJSON encodes the model in the schema described for PackageModel.
+func (m *PackageModel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.schema())
+}
+
+// MarshalYAML encodes the model in the schema described for PackageModel.
+func (m *PackageModel) MarshalYAML() (interface{}, error) {
+	return m.schema(), nil
+}
+
+// Describe writes a description of the package loaded in fs to w in the
+// given format, json or yaml, using the schema described for PackageModel.
+// JSON is indented for reading.
+func Describe(w io.Writer, fs *FileSet, format string) error {
+	m := fs.Model()
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// The schema of the encoded PackageModel.
+type (
+	packageSchema struct {
+		Name  string       `json:"name" yaml:"name"`
+		Path  string       `json:"path" yaml:"path"`
+		Types []typeSchema `json:"types" yaml:"types"`
+		Funcs []funcSchema `json:"funcs" yaml:"funcs"`
+	}
+
+	typeSchema struct {
+		Name       string            `json:"name" yaml:"name"`
+		Kind       string            `json:"kind" yaml:"kind"`
+		Underlying string            `json:"underlying" yaml:"underlying"`
+		Doc        string            `json:"doc,omitempty" yaml:"doc,omitempty"`
+		TypeParams []typeParamSchema `json:"typeParams,omitempty" yaml:"typeParams,omitempty"`
+		Fields     []fieldSchema     `json:"fields,omitempty" yaml:"fields,omitempty"`
+		Methods    []funcSchema      `json:"methods,omitempty" yaml:"methods,omitempty"`
+	}
+
+	typeParamSchema struct {
+		Name       string `json:"name" yaml:"name"`
+		Constraint string `json:"constraint" yaml:"constraint"`
+	}
+
+	fieldSchema struct {
+		Name     string      `json:"name" yaml:"name"`
+		Type     string      `json:"type" yaml:"type"`
+		Embedded bool        `json:"embedded,omitempty" yaml:"embedded,omitempty"`
+		Exported bool        `json:"exported,omitempty" yaml:"exported,omitempty"`
+		Doc      string      `json:"doc,omitempty" yaml:"doc,omitempty"`
+		Comment  string      `json:"comment,omitempty" yaml:"comment,omitempty"`
+		Tag      string      `json:"tag,omitempty" yaml:"tag,omitempty"`
+		Tags     []tagSchema `json:"tags,omitempty" yaml:"tags,omitempty"`
+	}
+
+	tagSchema struct {
+		Key   string `json:"key" yaml:"key"`
+		Value string `json:"value" yaml:"value"`
+	}
+
+	funcSchema struct {
+		Name            string            `json:"name" yaml:"name"`
+		Doc             string            `json:"doc,omitempty" yaml:"doc,omitempty"`
+		PointerReceiver bool              `json:"pointerReceiver,omitempty" yaml:"pointerReceiver,omitempty"`
+		TypeParams      []typeParamSchema `json:"typeParams,omitempty" yaml:"typeParams,omitempty"`
+		Params          []paramSchema     `json:"params,omitempty" yaml:"params,omitempty"`
+		Results         []paramSchema     `json:"results,omitempty" yaml:"results,omitempty"`
+		Variadic        bool              `json:"variadic,omitempty" yaml:"variadic,omitempty"`
+		Signature       string            `json:"signature" yaml:"signature"`
+	}
+
+	paramSchema struct {
+		Name string `json:"name,omitempty" yaml:"name,omitempty"`
+		Type string `json:"type" yaml:"type"`
+	}
+)
+
+// schema returns the model in its encoded form.
+func (m *PackageModel) schema() *packageSchema {
+	q := types.RelativeTo(m.pkg)
+	s := &packageSchema{Name: m.Name, Path: m.Path, Types: []typeSchema{}, Funcs: []funcSchema{}}
+
+	methods := make(map[string][]funcSchema)
+	for _, fn := range m.Funcs {
+		desc := funcToSchema(fn, q)
+		if fn.Recv != "" {
+			methods[fn.Recv] = append(methods[fn.Recv], desc)
+			continue
+		}
+		s.Funcs = append(s.Funcs, desc)
+	}
+
+	for _, t := range m.Types {
+		under := t.Object.Type().Underlying()
+		ts := typeSchema{
+			Name:       t.Name,
+			Kind:       NewTypeInfo(under).Kind.String(),
+			Underlying: types.TypeString(under, q),
+			Doc:        t.Doc,
+			TypeParams: typeParamsToSchema(t.TypeParams, q),
+			Methods:    methods[t.Name],
+		}
+		for _, f := range t.Fields {
+			desc := fieldSchema{
+				Name:     f.Name,
+				Type:     types.TypeString(f.Type, q),
+				Embedded: f.Embedded,
+				Exported: f.Exported,
+				Doc:      f.Doc,
+				Comment:  f.Comment,
+				Tag:      f.Tag,
+			}
+			for _, tag := range f.Tags {
+				desc.Tags = append(desc.Tags, tagSchema{Key: tag.Key, Value: tag.Value})
+			}
+			ts.Fields = append(ts.Fields, desc)
+		}
+		s.Types = append(s.Types, ts)
+	}
+	return s
+}
+
+func funcToSchema(fn *FuncModel, q types.Qualifier) funcSchema {
+	s := funcSchema{
+		Name:            fn.Name,
+		Doc:             fn.Doc,
+		PointerReceiver: fn.PointerRecv,
+		TypeParams:      typeParamsToSchema(fn.TypeParams, q),
+		Variadic:        fn.Variadic,
+		Signature:       types.TypeString(fn.Signature(), q),
+	}
+	for _, p := range fn.Params {
+		s.Params = append(s.Params, paramSchema{Name: p.Name, Type: types.TypeString(p.Type, q)})
+	}
+	for _, p := range fn.Results {
+		s.Results = append(s.Results, paramSchema{Name: p.Name, Type: types.TypeString(p.Type, q)})
+	}
+	return s
+}
+
+func typeParamsToSchema(params []*TypeParamModel, q types.Qualifier) []typeParamSchema {
+	var s []typeParamSchema
+	for _, p := range params {
+		s = append(s, typeParamSchema{Name: p.Name, Constraint: types.TypeString(p.Constraint, q)})
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const describeSrc = `package p
+
+import "time"
+
+// User is a user.
+type User struct {
+	ID      int       ` + "`json:\"id\" db:\"user_id\"`" + `
+	Created time.Time // when created
+	name    string
+}
+
+// Name returns the name.
+func (u *User) Name() string { return u.name }
+
+type Set[T comparable] map[T]struct{}
+
+// Join joins parts.
+func Join(sep string, parts ...string) (s string) { return "" }
+`
+
+func TestModelMarshalJSON(t *testing.T) {
+	fs, err := NewFileSetFromTexts(describeSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := json.Marshal(fs.Model())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"name":"p","path":".","types":[` +
+		`{"name":"User","kind":"struct","underlying":"struct{ID int \"json:\\\"id\\\" db:\\\"user_id\\\"\"; Created time.Time; name string}","doc":"User is a user.\n",` +
+		`"fields":[{"name":"ID","type":"int","exported":true,"tag":"json:\"id\" db:\"user_id\"","tags":[{"key":"json","value":"id"},{"key":"db","value":"user_id"}]},` +
+		`{"name":"Created","type":"time.Time","exported":true,"comment":"when created\n"},{"name":"name","type":"string"}],` +
+		`"methods":[{"name":"Name","doc":"Name returns the name.\n","pointerReceiver":true,"results":[{"type":"string"}],"signature":"func() string"}]},` +
+		`{"name":"Set","kind":"map","underlying":"map[T]struct{}","typeParams":[{"name":"T","constraint":"comparable"}]}],` +
+		`"funcs":[{"name":"Join","doc":"Join joins parts.\n","params":[{"name":"sep","type":"string"},{"name":"parts","type":"[]string"}],"results":[{"name":"s","type":"string"}],"variadic":true,"signature":"func(sep string, parts ...string) (s string)"}]}`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	fs, err := NewFileSetFromTexts(describeSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Describe(&buf, fs, "yaml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fromYAML map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fromYAML["name"] != "p" || len(fromYAML["types"].([]any)) != 2 {
+		t.Errorf("got %v, wanted package p with 2 types", fromYAML)
+	}
+
+	buf.Reset()
+	if err := Describe(&buf, fs, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) || !bytes.HasPrefix(buf.Bytes(), []byte("{\n  \"name\": \"p\"")) {
+		t.Errorf("got %s, wanted indented JSON", buf.Bytes())
+	}
+
+	if err := Describe(&buf, fs, "xml"); err == nil {
+		t.Errorf("got no error for an unknown format")
+	}
+}
@@ -0,0 +1,128 @@
+package gen
+
+import (
+	"fmt"
+	"text/template"
+	"text/template/parse"
+)
+
+// TreeHook rewrites the parse tree of a template before the template is
+// executed. It may modify the tree in place.
+type TreeHook func(tree *parse.Tree) error
+
+// Dialect extends the template language with directives: shorthand actions
+// that look like function calls, such as {{fields "json"}}, and that are
+// expanded into standard actions by the dialect's hooks before the template
+// is executed. Dialects let an organization build its own template language
+// on top of the engine.
+type Dialect struct {
+	// Directives lists the names of the dialect's directives. Templates may
+	// call them in actions as if they were functions. A directive left
+	// unexpanded by the hooks fails when the template is executed.
+	Directives []string
+
+	// Hooks are applied in order to the parse tree of each template, and of
+	// each template it defines, once it has been parsed.
+	Hooks []TreeHook
+}
+
+// NewTemplateTypeDialect parses text as a template with the given name, as
+// NewTemplateType does, allowing the directives of dialect to be used and
+// applying its hooks to the parse trees.
+func NewTemplateTypeDialect(name, text string, funcs template.FuncMap, dialect *Dialect) (*TemplateType, error) {
+	tmpl := template.New(name).Funcs(importFuncs(NewImports()))
+	if funcs != nil {
+		tmpl = tmpl.Funcs(funcs)
+	}
+	placeholders := make(template.FuncMap, len(dialect.Directives))
+	for _, d := range dialect.Directives {
+		placeholders[d] = func(...any) (string, error) {
+			return "", fmt.Errorf("directive %s was not expanded", d)
+		}
+	}
+	tmpl = tmpl.Funcs(placeholders)
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range sortedTemplates(tmpl) {
+		if t.Tree == nil {
+			continue
+		}
+		for _, hook := range dialect.Hooks {
+			if err := hook(t.Tree); err != nil {
+				return nil, fmt.Errorf("template %s: %w", t.Name(), err)
+			}
+		}
+	}
+
+	return &TemplateType{
+		Template: tmpl,
+		Format:   true,
+	}, nil
+}
+
+// ExpandActions returns a TreeHook that calls expand for each action in the
+// tree, including those nested in if, range and with actions, and replaces
+// the action with the nodes of the list expand returns. Actions for which
+// expand returns nil are left unchanged. Expansions are not themselves
+// expanded. ParseTemplateFragment is a convenient way to build expansions.
+func ExpandActions(expand func(*parse.ActionNode) (*parse.ListNode, error)) TreeHook {
+	return func(tree *parse.Tree) error {
+		return expandList(tree, tree.Root, expand)
+	}
+}
+
+// expandList expands the actions in list, which belongs to tree.
+func expandList(tree *parse.Tree, list *parse.ListNode, expand func(*parse.ActionNode) (*parse.ListNode, error)) error {
+	if list == nil {
+		return nil
+	}
+	nodes := make([]parse.Node, 0, len(list.Nodes))
+	for _, n := range list.Nodes {
+		var branch *parse.BranchNode
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			repl, err := expand(n)
+			if err != nil {
+				location, _ := tree.ErrorContext(n)
+				return fmt.Errorf("%s: %w", location, err)
+			}
+			if repl != nil {
+				nodes = append(nodes, repl.Nodes...)
+				continue
+			}
+		case *parse.IfNode:
+			branch = &n.BranchNode
+		case *parse.RangeNode:
+			branch = &n.BranchNode
+		case *parse.WithNode:
+			branch = &n.BranchNode
+		}
+		if branch != nil {
+			if err := expandList(tree, branch.List, expand); err != nil {
+				return err
+			}
+			if err := expandList(tree, branch.ElseList, expand); err != nil {
+				return err
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	list.Nodes = nodes
+	return nil
+}
+
+// ParseTemplateFragment parses text as a fragment of a template, such as the
+// expansion of a directive, and returns its nodes. Function names are not
+// checked since the fragment is executed with the functions of the template
+// it is inserted into.
+func ParseTemplateFragment(text string) (*parse.ListNode, error) {
+	tree := parse.New("fragment")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", make(map[string]*parse.Tree)); err != nil {
+		return nil, err
+	}
+	return tree.Root, nil
+}
@@ -0,0 +1,92 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"text/template/parse"
+)
+
+// testDialect expands {{getters}} into a getter for each field of a struct
+// and {{zero "T"}} into the zero value literal of type T.
+var testDialect = &Dialect{
+	Directives: []string{"getters", "zero", "unexpanded"},
+	Hooks: []TreeHook{ExpandActions(func(a *parse.ActionNode) (*parse.ListNode, error) {
+		if len(a.Pipe.Cmds) != 1 {
+			return nil, nil
+		}
+		args := a.Pipe.Cmds[0].Args
+		id, ok := args[0].(*parse.IdentifierNode)
+		if !ok {
+			return nil, nil
+		}
+		switch id.Ident {
+		case "getters":
+			return ParseTemplateFragment(`{{range .Fields}}
+func (x *{{$.Name}}) Get{{.Name}}() {{.Type}} { return x.{{.Name}} }
+{{end}}`)
+		case "zero":
+			if len(args) != 2 {
+				return nil, fmt.Errorf("zero takes one argument")
+			}
+			s, ok := args[1].(*parse.StringNode)
+			if !ok {
+				return nil, fmt.Errorf("zero argument must be a string")
+			}
+			return ParseTemplateFragment(s.Text + "{}")
+		}
+		return nil, nil
+	})},
+}
+
+func TestDialect(t *testing.T) {
+	type field struct{ Name, Type string }
+	data := struct {
+		Name   string
+		Fields []field
+	}{
+		Name:   "User",
+		Fields: []field{{"Name", "string"}, {"Age", "int"}},
+	}
+
+	tt, err := NewTemplateTypeDialect("test", `package p
+{{define "body"}}{{getters}}{{end}}
+var Empty = {{zero "User"}}
+{{if .Fields}}{{template "body" .}}{{end}}`, nil, testDialect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := tt.Render(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `package p
+
+var Empty = User{}
+
+func (x *User) GetName() string { return x.Name }
+
+func (x *User) GetAge() int { return x.Age }
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestDialectErrors(t *testing.T) {
+	if _, err := NewTemplateTypeDialect("test", `{{zero 1}}`, nil, testDialect); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Errorf("got error %v, wanted one from the hook", err)
+	}
+	if _, err := NewTemplateTypeDialect("test", `{{undefined}}`, nil, testDialect); err == nil {
+		t.Errorf("got no error for an undefined function, wanted one")
+	}
+
+	tt, err := NewTemplateTypeDialect("test", "package p\n{{unexpanded}}", nil, testDialect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tt.Render(nil); err == nil || !strings.Contains(err.Error(), "directive unexpanded was not expanded") {
+		t.Errorf("got error %v, wanted one naming the unexpanded directive", err)
+	}
+}
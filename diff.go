@@ -0,0 +1,227 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a
+// unified diff.
+const diffContext = 3
+
+// maxDiffEdits limits the number of edits searched for when comparing two
+// files. Files that differ by more are treated as entirely replaced, which
+// bounds the time and memory taken to compare very different large files.
+const maxDiffEdits = 2000
+
+// UnifiedDiff returns the differences between old and new in the unified
+// diff format, labelling the files oldName and newName. It returns nil if
+// old and new are the same.
+func UnifiedDiff(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		// Find the next change and the extent of the hunk containing it,
+		// which ends when more than twice the context lines are unchanged.
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				last = i
+			} else if i-last > 2*diffContext {
+				break
+			}
+		}
+		lo := max(first-diffContext, start)
+		hi := min(last+diffContext+1, len(edits))
+		writeHunk(&b, edits[lo:hi])
+		start = hi
+	}
+	return b.Bytes()
+}
+
+// edit is a line of a diff: unchanged (' '), deleted ('-') or inserted ('+').
+// oldLine and newLine are the one-based numbers of the line before it in the
+// old and new files.
+type edit struct {
+	op      byte
+	text    string
+	oldLine int
+	newLine int
+}
+
+// writeHunk writes a hunk containing edits to b.
+func writeHunk(b *bytes.Buffer, edits []edit) {
+	oldCount, newCount := 0, 0
+	for _, e := range edits {
+		if e.op != '+' {
+			oldCount++
+		}
+		if e.op != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(edits[0].oldLine, oldCount), hunkRange(edits[0].newLine, newCount))
+	for _, e := range edits {
+		b.WriteByte(e.op)
+		b.WriteString(e.text)
+		if !strings.HasSuffix(e.text, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the range of lines of a hunk, given the number of the line
+// before it.
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits src into lines, each including its newline.
+func splitLines(src []byte) []string {
+	lines := strings.SplitAfter(string(src), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edits that turn the lines a into the lines b.
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]byte, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, ' ')
+	}
+	ops = append(ops, shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, ' ')
+	}
+
+	edits := make([]edit, len(ops))
+	x, y := 0, 0
+	for i, op := range ops {
+		e := edit{op: op, oldLine: x, newLine: y}
+		switch op {
+		case ' ':
+			e.text = a[x]
+			x++
+			y++
+		case '-':
+			e.text = a[x]
+			x++
+		case '+':
+			e.text = b[y]
+			y++
+		}
+		edits[i] = e
+	}
+	return edits
+}
+
+// shortestEdit returns the operations of a shortest edit script turning a
+// into b, found with Myers' algorithm. If more than maxDiffEdits edits are
+// needed every line of a is deleted and every line of b inserted instead.
+func shortestEdit(a, b []string) []byte {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxDiffEdits)
+
+	// v[limit+k] holds the furthest x reached on diagonal k = x - y. The
+	// state before each step is kept in trace to recover the path.
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[limit-d:limit+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[limit+k-1] < v[limit+k+1]) {
+				x = v[limit+k+1]
+			} else {
+				x = v[limit+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[limit+k] = x
+			if x >= n && y >= m {
+				return backtrackEdit(trace, n, m)
+			}
+		}
+	}
+
+	ops := make([]byte, 0, n+m)
+	for i := 0; i < n; i++ {
+		ops = append(ops, '-')
+	}
+	for i := 0; i < m; i++ {
+		ops = append(ops, '+')
+	}
+	return ops
+}
+
+// backtrackEdit recovers the edit operations from the trace of Myers'
+// algorithm that reached (n, m) after len(trace)-1 edits.
+func backtrackEdit(trace [][]int, n, m int) []byte {
+	var ops []byte
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, ' ')
+			x--
+			y--
+		}
+		if prevK == k+1 {
+			ops = append(ops, '+')
+		} else {
+			ops = append(ops, '-')
+		}
+		x, y = prevX, prevY
+	}
+	for ; x > 0; x-- {
+		ops = append(ops, ' ')
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
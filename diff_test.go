@@ -0,0 +1,159 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+
+	testCases := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "same",
+			old:  old,
+			new:  old,
+			want: "",
+		},
+		{
+			name: "two hunks",
+			old:  old,
+			new:  new,
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -11,3 +11,4 @@\n k\n l\n m\n+n\n",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "package p\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1 @@\n+package p\n",
+		},
+		{
+			name: "removed file",
+			old:  "package p\n\nconst X = 1\n",
+			new:  "",
+			want: "--- old\n+++ new\n@@ -1,3 +0,0 @@\n-package p\n-\n-const X = 1\n",
+		},
+		{
+			name: "missing newline",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(UnifiedDiff("old", "new", []byte(tc.old), []byte(tc.new)))
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwanted:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffApplies(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	lines := func(n int) []string {
+		s := make([]string, n)
+		for i := range s {
+			s[i] = strconv.Itoa(rnd.Intn(8)) + "\n"
+		}
+		return s
+	}
+
+	for i := 0; i < 200; i++ {
+		a := lines(rnd.Intn(40))
+		b := append([]string(nil), a...)
+		for j := rnd.Intn(6); j > 0; j-- {
+			pos := rnd.Intn(len(b) + 1)
+			switch rnd.Intn(3) {
+			case 0:
+				b = append(b[:pos], append(lines(1+rnd.Intn(3)), b[pos:]...)...)
+			case 1:
+				if pos < len(b) {
+					b = append(b[:pos], b[pos+1:]...)
+				}
+			case 2:
+				if pos < len(b) {
+					b[pos] = "x\n"
+				}
+			}
+		}
+		old, new := strings.Join(a, ""), strings.Join(b, "")
+		diff := UnifiedDiff("old", "new", []byte(old), []byte(new))
+		got, err := applyDiff(old, string(diff))
+		if err != nil {
+			t.Fatalf("case %d: %v\n%s", i, err, diff)
+		}
+		if got != new {
+			t.Fatalf("case %d: got %q, wanted %q\n%s", i, got, new, diff)
+		}
+	}
+}
+
+// applyDiff applies a unified diff produced by UnifiedDiff to old.
+func applyDiff(old, diff string) (string, error) {
+	if diff == "" {
+		return old, nil
+	}
+	src := splitLines([]byte(old))
+	var out []string
+	next := 0 // index of the next line of src to copy
+	for _, line := range splitLines([]byte(diff))[2:] {
+		switch line[0] {
+		case '@':
+			var start int
+			if _, err := fmt.Sscanf(line, "@@ -%d", &start); err != nil {
+				return "", err
+			}
+			if !strings.HasPrefix(line, fmt.Sprintf("@@ -%d,0 ", start)) {
+				start--
+			}
+			out = append(out, src[next:start]...)
+			next = start
+		case ' ':
+			if src[next] != line[1:] {
+				return "", fmt.Errorf("context %q does not match %q", line[1:], src[next])
+			}
+			out = append(out, src[next])
+			next++
+		case '-':
+			if src[next] != line[1:] {
+				return "", fmt.Errorf("deleted line %q does not match %q", line[1:], src[next])
+			}
+			next++
+		case '+':
+			out = append(out, line[1:])
+		default:
+			return "", fmt.Errorf("unexpected line %q", line)
+		}
+	}
+	out = append(out, src[next:]...)
+	return strings.Join(out, ""), nil
+}
+
+func BenchmarkUnifiedDiff(b *testing.B) {
+	var old, new strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&old, "line %d\n", i)
+		if i%1000 == 0 {
+			fmt.Fprintf(&new, "changed %d\n", i)
+			continue
+		}
+		fmt.Fprintf(&new, "line %d\n", i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UnifiedDiff("old", "new", []byte(old.String()), []byte(new.String()))
+	}
+}
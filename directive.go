@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// Directive is a magic comment that controls code generation for the
+// declaration it documents, such as //gen:stringer or //+mygen key=value.
+// A directive is a line comment whose text starts immediately after the //
+// with a prefix chosen by the generator. The text following the prefix is
+// split into fields separated by spaces. The first field is the directive's
+// name unless it contains an equals sign. Remaining fields are arguments of
+// the form key=value, or a bare key which is given an empty value. Values may
+// be quoted using Go string literal syntax to include spaces.
+type Directive struct {
+	// Prefix is the prefix that identified the directive.
+	Prefix string
+
+	// Name is the first field following the prefix, or empty if the first
+	// field is an argument.
+	Name string
+
+	// Args holds the directive's arguments.
+	Args map[string]string
+
+	// Keys holds the argument keys in the order they appear.
+	Keys []string
+
+	// Text is the complete text of the comment.
+	Text string
+
+	// Pos is the position of the comment.
+	Pos token.Pos
+
+	// Node is the declaration the directive documents. It is a *ast.FuncDecl,
+	// *ast.TypeSpec or *ast.ValueSpec, or a *ast.GenDecl for a directive
+	// attached to a parenthesized group of declarations.
+	Node ast.Node
+
+	// Object is the object declared by Node, or nil if Node is a group of
+	// declarations. For a *ast.ValueSpec it is the object declared by the
+	// first name.
+	Object types.Object
+}
+
+// Arg returns the value of the argument with the given key and whether it was
+// present.
+func (d *Directive) Arg(key string) (string, bool) {
+	v, ok := d.Args[key]
+	return v, ok
+}
+
+// ParseDirective parses the text of a comment as a directive with the given
+// prefix. The boolean result is false if the comment is not a directive with
+// that prefix.
+func ParseDirective(prefix, text string) (*Directive, bool) {
+	if !strings.HasPrefix(text, "//"+prefix) {
+		return nil, false
+	}
+
+	d := &Directive{
+		Prefix: prefix,
+		Args:   make(map[string]string),
+		Text:   text,
+	}
+
+	fields := splitDirectiveFields(text[2+len(prefix):])
+	if len(fields) > 0 && !strings.Contains(fields[0], "=") {
+		d.Name = fields[0]
+		fields = fields[1:]
+	}
+
+	for _, field := range fields {
+		key, value, _ := strings.Cut(field, "=")
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "`") {
+			if uq, err := strconv.Unquote(value); err == nil {
+				value = uq
+			}
+		}
+		if _, dup := d.Args[key]; !dup {
+			d.Keys = append(d.Keys, key)
+		}
+		d.Args[key] = value
+	}
+
+	return d, true
+}
+
+// splitDirectiveFields splits s at spaces that are not within a quoted string.
+func splitDirectiveFields(s string) []string {
+	var fields []string
+	var quote byte
+	start := -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == ' ' || c == '\t':
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				start = -1
+			}
+			continue
+		case c == '"' || c == '`':
+			quote = c
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}
+
+// EachDirective traverses all the files in fs calling f for each directive
+// with the given prefix found in the doc comment of a declaration, in file and
+// position order. The traversal will stop if f returns false.
+func (fs *FileSet) EachDirective(prefix string, f func(*Directive) bool) {
+	done := false
+
+	visit := func(doc *ast.CommentGroup, node ast.Node, obj types.Object) {
+		if doc == nil || done {
+			return
+		}
+		for _, c := range doc.List {
+			d, ok := ParseDirective(prefix, c.Text)
+			if !ok {
+				continue
+			}
+			d.Pos = c.Slash
+			d.Node = node
+			d.Object = obj
+			if !f(d) {
+				done = true
+				return
+			}
+		}
+	}
+
+	for _, file := range fs.AstFiles {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				visit(decl.Doc, decl, fs.TypeInfo.Defs[decl.Name])
+			case *ast.GenDecl:
+				if len(decl.Specs) == 1 && !decl.Lparen.IsValid() {
+					node, obj := fs.specObject(decl.Specs[0])
+					visit(decl.Doc, node, obj)
+				} else {
+					visit(decl.Doc, decl, nil)
+				}
+				for _, spec := range decl.Specs {
+					node, obj := fs.specObject(spec)
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						visit(spec.Doc, node, obj)
+					case *ast.ValueSpec:
+						visit(spec.Doc, node, obj)
+					}
+				}
+			}
+			if done {
+				return
+			}
+		}
+	}
+}
+
+// specObject returns the object declared by spec.
+func (fs *FileSet) specObject(spec ast.Spec) (ast.Node, types.Object) {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec, fs.TypeInfo.Defs[spec.Name]
+	case *ast.ValueSpec:
+		if len(spec.Names) > 0 {
+			return spec, fs.TypeInfo.Defs[spec.Names[0]]
+		}
+		return spec, nil
+	}
+	return spec, nil
+}
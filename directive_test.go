@@ -0,0 +1,151 @@
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDirective(t *testing.T) {
+	testCases := []struct {
+		prefix string
+		text   string
+		ok     bool
+		name   string
+		args   map[string]string
+		keys   []string
+	}{
+		{
+			prefix: "gen:",
+			text:   "//gen:stringer",
+			ok:     true,
+			name:   "stringer",
+			args:   map[string]string{},
+		},
+		{
+			prefix: "+",
+			text:   "//+myGen key=value flag",
+			ok:     true,
+			name:   "myGen",
+			args:   map[string]string{"key": "value", "flag": ""},
+			keys:   []string{"key", "flag"},
+		},
+		{
+			prefix: "+myGen",
+			text:   "//+myGen  b=2 a=1",
+			ok:     true,
+			args:   map[string]string{"a": "1", "b": "2"},
+			keys:   []string{"b", "a"},
+		},
+		{
+			prefix: "gen:",
+			text:   `//gen:mock name="My Mock" raw=` + "`a b`" + ` esc="a\"b"`,
+			ok:     true,
+			name:   "mock",
+			args:   map[string]string{"name": "My Mock", "raw": "a b", "esc": `a"b`},
+			keys:   []string{"name", "raw", "esc"},
+		},
+		{
+			prefix: "gen:",
+			text:   "// gen:stringer",
+			ok:     false,
+		},
+		{
+			prefix: "gen:",
+			text:   "//go:generate stringer",
+			ok:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			d, ok := ParseDirective(tc.prefix, tc.text)
+			if ok != tc.ok {
+				t.Fatalf("got ok=%v, wanted %v", ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			if d.Name != tc.name {
+				t.Errorf("got name %q, wanted %q", d.Name, tc.name)
+			}
+			if !reflect.DeepEqual(d.Args, tc.args) {
+				t.Errorf("got args %+v, wanted %+v", d.Args, tc.args)
+			}
+			if !reflect.DeepEqual(d.Keys, tc.keys) {
+				t.Errorf("got keys %+v, wanted %+v", d.Keys, tc.keys)
+			}
+		})
+	}
+}
+
+func TestEachDirective(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		// Color is a color.
+		//gen:stringer trim=Color
+		type Color int
+
+		//gen:enum
+		const (
+			Red Color = iota
+			//gen:skip
+			Green
+		)
+
+		type (
+			//gen:mock
+			Service interface{ Do() }
+		)
+
+		// Helper has a directive with another prefix.
+		//other:ignored
+		func Helper() {}
+
+		//gen:trace level=debug
+		func (c Color) String() string { return "" }
+
+		//gen:var
+		var x = 1
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type summary struct {
+		Name   string
+		Object string
+		Args   map[string]string
+	}
+
+	got := []summary{}
+	fs.EachDirective("gen:", func(d *Directive) bool {
+		s := summary{Name: d.Name, Args: d.Args}
+		if d.Object != nil {
+			s.Object = d.Object.Name()
+		}
+		got = append(got, s)
+		return true
+	})
+
+	want := []summary{
+		{Name: "stringer", Object: "Color", Args: map[string]string{"trim": "Color"}},
+		{Name: "enum", Args: map[string]string{}},
+		{Name: "skip", Object: "Green", Args: map[string]string{}},
+		{Name: "mock", Object: "Service", Args: map[string]string{}},
+		{Name: "trace", Object: "String", Args: map[string]string{"level": "debug"}},
+		{Name: "var", Object: "x", Args: map[string]string{}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	count := 0
+	fs.EachDirective("gen:", func(d *Directive) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Errorf("traversal did not stop, got %d calls", count)
+	}
+}
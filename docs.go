@@ -0,0 +1,86 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// DocFor returns the text of the doc comment associated with the declaration
+// of obj, or an empty string if obj is not declared in fs or has no doc
+// comment. Doc comments are associated with declarations as go/doc
+// associates them:
+//
+//   - a type, constant or variable takes the doc comment of its
+//     specification, or of its declaration if the declaration contains a
+//     single specification;
+//   - a function or method takes the doc comment of its declaration;
+//   - a struct field or interface method takes the doc comment of its field.
+//
+// Line comments are not doc comments and are not returned. Objects of
+// instantiated generic types, such as their fields and methods, take the doc
+// comment of their generic declaration.
+func (fs *FileSet) DocFor(obj types.Object) string {
+	switch o := obj.(type) {
+	case nil:
+		return ""
+	case *types.Var:
+		obj = o.Origin()
+	case *types.Func:
+		obj = o.Origin()
+	}
+	if obj.Pkg() != fs.Package || !obj.Pos().IsValid() {
+		return ""
+	}
+
+	pos := obj.Pos()
+	for _, f := range fs.AstFiles {
+		if pos < f.FileStart || pos >= f.FileEnd {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+		if len(path) == 0 {
+			return ""
+		}
+		if _, ok := path[0].(*ast.Ident); !ok {
+			return ""
+		}
+		return docOf(path[1:])
+	}
+	return ""
+}
+
+// docOf returns the doc comment for the identifier whose enclosing nodes,
+// innermost first, are path.
+func docOf(path []ast.Node) string {
+	if len(path) == 0 {
+		return ""
+	}
+	switch n := path[0].(type) {
+	case *ast.FuncDecl:
+		return n.Doc.Text()
+	case *ast.Field:
+		return n.Doc.Text()
+	case *ast.TypeSpec:
+		return specDoc(n.Doc, path[1:])
+	case *ast.ValueSpec:
+		return specDoc(n.Doc, path[1:])
+	}
+	return ""
+}
+
+// specDoc returns the doc comment of a specification, falling back to the
+// doc comment of its declaration, the first node in path, if the declaration
+// contains a single specification.
+func specDoc(doc *ast.CommentGroup, path []ast.Node) string {
+	if doc != nil {
+		return doc.Text()
+	}
+	if len(path) > 0 {
+		if gd, ok := path[0].(*ast.GenDecl); ok && len(gd.Specs) == 1 {
+			return gd.Doc.Text()
+		}
+	}
+	return ""
+}
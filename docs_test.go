@@ -0,0 +1,109 @@
+package gen
+
+import (
+	"go/types"
+	"testing"
+)
+
+func TestDocFor(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "strings"
+
+		// Shape is a geometric shape.
+		type Shape interface {
+			// Area returns the area of the shape.
+			Area() float64
+			Perimeter() float64 // not a doc comment
+		}
+
+		// Shapes are grouped.
+		type (
+			// Square is a shape with four equal sides.
+			Square struct {
+				// Side is the length of a side.
+				Side float64
+			}
+
+			Circle struct{ Radius float64 }
+		)
+
+		// Area returns the area of the square.
+		func (s Square) Area() float64 { return s.Side * s.Side }
+
+		// Max is the largest size.
+		const Max = 10
+
+		var (
+			// Count counts things.
+			Count int
+			Other int
+		)
+
+		// Box holds a value.
+		type Box[T any] struct {
+			// Value is the boxed value.
+			Value T
+		}
+
+		// Get returns the boxed value.
+		func (b Box[T]) Get() T { return b.Value }
+
+		var IntBox Box[int]
+
+		func F(x int) { _ = strings.ToUpper }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lookup := func(name string) types.Object { return fs.Lookup(name) }
+	member := func(typ, name string) types.Object {
+		obj, _, _ := types.LookupFieldOrMethod(fs.Lookup(typ).Type(), true, fs.Package, name)
+		return obj
+	}
+	intBox := fs.Lookup("IntBox").Type()
+	instance := func(name string) types.Object {
+		obj, _, _ := types.LookupFieldOrMethod(intBox, true, fs.Package, name)
+		return obj
+	}
+
+	testCases := []struct {
+		name string
+		obj  types.Object
+		want string
+	}{
+		{name: "interface", obj: lookup("Shape"), want: "Shape is a geometric shape.\n"},
+		{name: "interface method", obj: member("Shape", "Area"), want: "Area returns the area of the shape.\n"},
+		{name: "line comment", obj: member("Shape", "Perimeter"), want: ""},
+		{name: "grouped type", obj: lookup("Square"), want: "Square is a shape with four equal sides.\n"},
+		{name: "grouped type without doc", obj: lookup("Circle"), want: ""},
+		{name: "field", obj: member("Square", "Side"), want: "Side is the length of a side.\n"},
+		{name: "method", obj: member("Square", "Area"), want: "Area returns the area of the square.\n"},
+		{name: "const", obj: lookup("Max"), want: "Max is the largest size.\n"},
+		{name: "var", obj: lookup("Count"), want: "Count counts things.\n"},
+		{name: "var without doc", obj: lookup("Other"), want: ""},
+		{name: "instantiated field", obj: instance("Value"), want: "Value is the boxed value.\n"},
+		{name: "instantiated method", obj: instance("Get"), want: "Get returns the boxed value.\n"},
+		{name: "func without doc", obj: lookup("F"), want: ""},
+		{name: "param", obj: lookup("F").Type().(*types.Signature).Params().At(0), want: ""},
+		{name: "universe", obj: types.Universe.Lookup("error"), want: ""},
+		{name: "nil", obj: nil, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fs.DocFor(tc.obj); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+
+	ms, err := fs.MethodsOf("Square")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := ms.Value[0].Doc, "Area returns the area of the square.\n"; got != want {
+		t.Errorf("got method doc %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// Engine produces generated source code by executing named templates. It
+// lets generators choose how code is produced, whether with text/template,
+// with a compiled template language such as quicktemplate or with a
+// programmatic builder such as jennifer, while writing the results through a
+// Renderer. Execute runs the template with the given name, or the engine's
+// default template if name is empty, with data and writes the result to w.
+type Engine interface {
+	Execute(name string, data any, w io.Writer) error
+}
+
+// Engine returns an Engine that executes the templates of tt, declaring
+// imports and formatting the generated code as RenderTemplate does.
+func (tt *TemplateType) Engine() Engine {
+	return templateEngine{tt: tt}
+}
+
+type templateEngine struct {
+	tt *TemplateType
+}
+
+func (e templateEngine) Execute(name string, data any, w io.Writer) error {
+	if name == "" {
+		name = e.tt.Template.Name()
+	}
+	src, err := e.tt.RenderTemplate(name, data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// TextEngine returns an Engine that executes the templates associated with
+// tmpl, using tmpl itself when no name is given. Unlike a TemplateType the
+// templates have no import function and their output is not formatted.
+func TextEngine(tmpl *template.Template) Engine {
+	return textEngine{tmpl: tmpl}
+}
+
+type textEngine struct {
+	tmpl *template.Template
+}
+
+func (e textEngine) Execute(name string, data any, w io.Writer) error {
+	if name == "" {
+		name = e.tmpl.Name()
+	}
+	if err := e.tmpl.ExecuteTemplate(w, name, data); err != nil {
+		return templateError(err)
+	}
+	return nil
+}
+
+// EngineFuncs is an Engine whose templates are Go functions, keyed by name.
+// It adapts code written with programmatic builders or generated by compiled
+// template languages. For example, a jennifer file may be built and rendered
+// with
+//
+//	gen.EngineFuncs{
+//		"enum": func(w io.Writer, data any) error {
+//			f := jen.NewFile("p")
+//			// ...
+//			return f.Render(w)
+//		},
+//	}
+//
+// The function with the empty name, if any, is the default.
+type EngineFuncs map[string]func(w io.Writer, data any) error
+
+func (e EngineFuncs) Execute(name string, data any, w io.Writer) error {
+	fn, ok := e[name]
+	if !ok {
+		return fmt.Errorf("no template named %q", name)
+	}
+	return fn(w, data)
+}
@@ -0,0 +1,70 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+func TestEngines(t *testing.T) {
+	tt, err := NewTemplateType("root", `{{define "var"}}package p
+
+var   X = {{import "fmt"}}.Sprint({{.}}){{end}}`, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := template.Must(template.New("root").Parse(`{{define "var"}}package p; var X = {{.}}{{end}}`))
+	funcs := EngineFuncs{
+		"var": func(w io.Writer, data any) error {
+			_, err := fmt.Fprintf(w, "package p; var X = %v", data)
+			return err
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		engine Engine
+		want   string
+	}{
+		{name: "template type", engine: tt.Engine(), want: "package p\n\nimport (\n\t\"fmt\"\n)\n\nvar X = fmt.Sprint(1)\n"},
+		{name: "text", engine: TextEngine(text), want: "package p; var X = 1"},
+		{name: "funcs", engine: funcs, want: "package p; var X = 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.engine.Execute("var", 1, &buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+			if err := tc.engine.Execute("missing", 1, &buf); err == nil {
+				t.Errorf("got no error for a missing template")
+			}
+		})
+	}
+}
+
+func TestEngineRenderer(t *testing.T) {
+	r := NewEngineRenderer("gentool", EngineFuncs{
+		"": func(w io.Writer, data any) error {
+			_, err := fmt.Fprintf(w, "package p\nconst   Name = %q\n", data)
+			return err
+		},
+	})
+	filename := filepath.Join(t.TempDir(), "name_gen.go")
+	if err := r.Emit(filename, "", "alpha"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "// Code generated by gentool; DO NOT EDIT.\n\npackage p\n\nconst Name = \"alpha\"\n"
+	if got := mustReadFile(t, filename); got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
@@ -0,0 +1,190 @@
+package gen
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// EnumModel describes a named type used as an enumeration: a type with a
+// basic underlying type, such as int or string, for which the package declares
+// a set of constants.
+type EnumModel struct {
+	// Name is the name of the type.
+	Name string
+
+	// Doc is the text of the type's doc comment.
+	Doc string
+
+	// Iota is true if the values are defined using iota.
+	Iota bool
+
+	// Values holds the constants of the type in declaration order.
+	Values []*EnumValueModel
+
+	// Type is the model of the enumeration's type.
+	Type *TypeModel
+}
+
+// EnumValueModel describes a constant of an enumeration type.
+type EnumValueModel struct {
+	// Name is the name of the constant.
+	Name string
+
+	// Value is the constant's value.
+	Value constant.Value
+
+	// Literal is the value written as a Go literal, such as 3 or "red".
+	Literal string
+
+	// Doc is the text of the constant's doc comment.
+	Doc string
+
+	// Comment is the text of the constant's line comment.
+	Comment string
+
+	// Object is the type checked constant object.
+	Object *types.Const
+}
+
+// Enums returns models of the enumerations declared in fs in the order their
+// types are declared. A named type declared in fs is treated as an
+// enumeration if its underlying type is a boolean, numeric or string type and
+// at least two constants of the type are declared at package level. Blank
+// constants, such as a leading _ = iota, are not included in the values.
+func (fs *FileSet) Enums() []*EnumModel {
+	enums := make(map[*types.TypeName]*EnumModel)
+	for _, tm := range fs.Types() {
+		if _, ok := tm.Object.Type().Underlying().(*types.Basic); !ok || tm.Object.IsAlias() {
+			continue
+		}
+		enums[tm.Object] = &EnumModel{
+			Name: tm.Name,
+			Doc:  tm.Doc,
+			Type: tm,
+		}
+	}
+
+	for _, f := range fs.AstFiles {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			fs.addEnumValues(gd, enums)
+		}
+	}
+
+	models := []*EnumModel{}
+	for _, tm := range fs.Types() {
+		if e := enums[tm.Object]; e != nil && len(e.Values) > 1 {
+			models = append(models, e)
+		}
+	}
+	return models
+}
+
+// Enum returns a model of the named enumeration. The boolean result is false
+// if the type is not declared or is not an enumeration.
+func (fs *FileSet) Enum(name string) (*EnumModel, bool) {
+	for _, e := range fs.Enums() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// addEnumValues adds the constants declared by gd to the enumerations of
+// their types.
+func (fs *FileSet) addEnumValues(gd *ast.GenDecl, enums map[*types.TypeName]*EnumModel) {
+	// A spec without values repeats the values of the previous spec, so
+	// track whether the expressions in effect use iota.
+	usesIota := false
+	for _, spec := range gd.Specs {
+		vs := spec.(*ast.ValueSpec)
+		if len(vs.Values) > 0 {
+			usesIota = false
+			for _, v := range vs.Values {
+				if containsIota(v) {
+					usesIota = true
+				}
+			}
+		}
+
+		for _, id := range vs.Names {
+			c, ok := fs.TypeInfo.Defs[id].(*types.Const)
+			if !ok || id.Name == "_" {
+				continue
+			}
+			n, ok := types.Unalias(c.Type()).(*types.Named)
+			if !ok {
+				continue
+			}
+			e := enums[n.Obj()]
+			if e == nil {
+				continue
+			}
+
+			doc := vs.Doc.Text()
+			if doc == "" && len(gd.Specs) == 1 {
+				doc = gd.Doc.Text()
+			}
+			e.Values = append(e.Values, &EnumValueModel{
+				Name:    id.Name,
+				Value:   c.Val(),
+				Literal: constLiteral(c.Val()),
+				Doc:     doc,
+				Comment: vs.Comment.Text(),
+				Object:  c,
+			})
+			e.Iota = e.Iota || usesIota
+		}
+	}
+}
+
+// containsIota reports whether expr refers to iota.
+func containsIota(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// constLiteral returns v written as a Go literal. Floating point values are
+// written in decimal when that is exact, otherwise as a fraction such as
+// 1.0/3, and always with a decimal point or exponent so that they remain
+// floating point constants rather than integers.
+func constLiteral(v constant.Value) string {
+	switch v.Kind() {
+	case constant.String:
+		return strconv.Quote(constant.StringVal(v))
+	case constant.Float:
+		s := v.String()
+		if lit := constant.MakeFromLiteral(s, token.FLOAT, 0); lit.Kind() != constant.Unknown && constant.Compare(lit, token.EQL, v) {
+			return floatLiteral(s)
+		}
+		num, denom := constant.Num(v), constant.Denom(v)
+		if num.Kind() == constant.Int && denom.Kind() == constant.Int {
+			return floatLiteral(num.ExactString()) + "/" + denom.ExactString()
+		}
+		return floatLiteral(v.ExactString())
+	}
+	return v.ExactString()
+}
+
+// floatLiteral returns the decimal literal s with a decimal point added if it
+// has neither a decimal point nor an exponent.
+func floatLiteral(s string) string {
+	if strings.ContainsAny(s, ".eEpP") {
+		return s
+	}
+	return s + ".0"
+}
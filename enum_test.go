@@ -0,0 +1,177 @@
+package gen
+
+import (
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestEnums(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		// Color is a primary color.
+		type Color int
+
+		const (
+			_ Color = iota
+			// Red is the color of blood.
+			Red
+			Green // the color of grass
+			Blue
+		)
+
+		type Level string
+
+		const (
+			Debug Level = "debug"
+			Info  Level = "info"
+		)
+
+		const Warn Level = "warn"
+
+		type Timeout int
+
+		const DefaultTimeout Timeout = 5
+
+		type Flags uint8
+
+		const (
+			FlagA Flags = 1 << iota
+			FlagB
+		)
+
+		type Point struct{ X, Y int }
+
+		const untyped = 1
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type value struct {
+		Name, Literal, Doc, Comment string
+	}
+	type enum struct {
+		Name   string
+		Doc    string
+		Iota   bool
+		Values []value
+	}
+
+	got := []enum{}
+	for _, e := range fs.Enums() {
+		ge := enum{Name: e.Name, Doc: e.Doc, Iota: e.Iota, Values: []value{}}
+		for _, v := range e.Values {
+			ge.Values = append(ge.Values, value{Name: v.Name, Literal: v.Literal, Doc: v.Doc, Comment: v.Comment})
+		}
+		got = append(got, ge)
+	}
+
+	want := []enum{
+		{
+			Name: "Color",
+			Doc:  "Color is a primary color.\n",
+			Iota: true,
+			Values: []value{
+				{Name: "Red", Literal: "1", Doc: "Red is the color of blood.\n"},
+				{Name: "Green", Literal: "2", Comment: "the color of grass\n"},
+				{Name: "Blue", Literal: "3"},
+			},
+		},
+		{
+			Name: "Level",
+			Values: []value{
+				{Name: "Debug", Literal: `"debug"`},
+				{Name: "Info", Literal: `"info"`},
+				{Name: "Warn", Literal: `"warn"`},
+			},
+		},
+		{
+			Name: "Flags",
+			Iota: true,
+			Values: []value{
+				{Name: "FlagA", Literal: "1"},
+				{Name: "FlagB", Literal: "2"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if _, ok := fs.Enum("Timeout"); ok {
+		t.Errorf("got enum for type with a single constant")
+	}
+	if e, ok := fs.Enum("Level"); !ok || e.Type.Name != "Level" {
+		t.Errorf("Level enum not found")
+	}
+}
+
+func TestEnumsAlias(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type Mode int
+
+		type M = Mode
+
+		const (
+			Read M = iota
+			Write
+			Exec Mode = 2
+		)
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e, ok := fs.Enum("Mode")
+	if !ok {
+		t.Fatalf("Mode enum not found")
+	}
+	var names []string
+	for _, v := range e.Values {
+		names = append(names, v.Name)
+	}
+	if want := []string{"Read", "Write", "Exec"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got values %v, wanted %v", names, want)
+	}
+}
+
+func TestConstLiteral(t *testing.T) {
+	testCases := []struct {
+		expr string
+		want string
+	}{
+		{expr: "2.0", want: "2.0"},
+		{expr: "2.5", want: "2.5"},
+		{expr: "1.0 / 3", want: "1.0/3"},
+		{expr: "-2.0 / 3", want: "-2.0/3"},
+		{expr: "1e6", want: "1e+06"},
+		{expr: "7", want: "7"},
+		{expr: `"a"`, want: `"a"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := constLiteral(tv.Value)
+			if got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+
+			// The literal must evaluate to the same constant of the same kind.
+			lit, err := types.Eval(token.NewFileSet(), nil, token.NoPos, got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lit.Type != tv.Type || !constant.Compare(lit.Value, token.EQL, tv.Value) {
+				t.Errorf("literal %s is %s %s, wanted %s %s", got, lit.Type, lit.Value, tv.Type, tv.Value)
+			}
+		})
+	}
+}
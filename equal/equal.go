@@ -0,0 +1,482 @@
+// Package equal generates Equal and Hash methods for struct types, for value
+// types that are compared in tests or used as keys of hash based containers.
+//
+// For each struct type T the generated methods are
+//
+//	func (t T) Equal(other T) bool
+//	func (t T) Hash() uint64
+//
+// Equal compares the fields of T in declaration order. Fields of comparable
+// types are compared with ==, byte slices with bytes.Equal, other slices and
+// maps element by element, pointers by the values they point to, and fields
+// whose types have an Equal method, including the types being generated, by
+// calling it. Hash combines the fields with the 64 bit FNV-1a hash so that
+// values that are Equal have the same hash. Maps are hashed independently of
+// their iteration order.
+//
+// Fields are controlled by directives in their doc or line comments:
+//
+//	//equal:skip
+//	//equal:custom equal=sameName hash=hashName
+//
+// A skipped field is ignored by both methods. A custom field is compared by
+// calling the named function of the package with the two field values, which
+// must report whether they are equal, and hashed by calling the named hash
+// function with the field value, which must return a uint64. A custom field
+// without a hash function is left out of the hash.
+package equal
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/iand/gen"
+)
+
+// Prefix is the prefix of the directives that control fields.
+const Prefix = "equal:"
+
+// offset is the offset basis of the 64 bit FNV-1a hash.
+const offset = "equalHashOffset"
+
+// generator writes the methods of a set of struct types.
+type generator struct {
+	fs      *gen.FileSet
+	imports *gen.Imports
+	qual    types.Qualifier
+	gen     map[*types.Named]bool // types whose methods are being generated
+	vars    int                   // counter for the names of loop variables
+}
+
+// Generate writes Equal and Hash methods to o for each of the named struct
+// types, or for every non-generic struct type declared in fs if no names are
+// given. It returns an error if a field's type cannot be compared or hashed
+// and the field is not skipped or given custom functions.
+func Generate(fs *gen.FileSet, o *gen.Output, typeNames ...string) error {
+	var named []*types.Named
+	if len(typeNames) == 0 {
+		for _, tm := range fs.Types() {
+			n, ok := tm.Object.Type().(*types.Named)
+			if !ok || tm.Object.IsAlias() || n.TypeParams().Len() > 0 {
+				continue
+			}
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				named = append(named, n)
+			}
+		}
+	} else {
+		for _, name := range typeNames {
+			tn, ok := fs.Lookup(name).(*types.TypeName)
+			if !ok {
+				return fmt.Errorf("type %s not found", name)
+			}
+			n, ok := tn.Type().(*types.Named)
+			if !ok || tn.IsAlias() {
+				return fmt.Errorf("%s is not a named type", name)
+			}
+			if n.TypeParams().Len() > 0 {
+				return fmt.Errorf("cannot generate methods for generic type %s", name)
+			}
+			if _, ok := n.Underlying().(*types.Struct); !ok {
+				return fmt.Errorf("%s is not a struct type", name)
+			}
+			named = append(named, n)
+		}
+	}
+
+	imports := gen.NewImports()
+	g := &generator{
+		fs:      fs,
+		imports: imports,
+		qual:    imports.Qualifier(fs.Package),
+		gen:     make(map[*types.Named]bool),
+	}
+	for _, n := range named {
+		g.gen[n] = true
+	}
+
+	var body bytes.Buffer
+	for _, n := range named {
+		if err := g.methods(&body, n); err != nil {
+			return err
+		}
+	}
+	if len(named) > 0 {
+		body.WriteString(helpers)
+	}
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	o.Printf("%s\n", imports.Block())
+	o.Write(body.Bytes())
+	return nil
+}
+
+// field is a struct field together with the directive that controls it.
+type field struct {
+	*gen.FieldModel
+	skip      bool
+	equalFunc string
+	hashFunc  string
+}
+
+// fields returns the fields of the struct type n with their directives.
+func (g *generator) fields(n *types.Named) ([]*field, error) {
+	models, err := g.fs.FieldsOf(n.Obj().Name())
+	if err != nil {
+		return nil, err
+	}
+	var fields []*field
+	for _, fm := range models {
+		f := &field{FieldModel: fm}
+		if fm.Field != nil {
+			for _, cg := range []*ast.CommentGroup{fm.Field.Doc, fm.Field.Comment} {
+				if cg == nil {
+					continue
+				}
+				for _, c := range cg.List {
+					d, ok := gen.ParseDirective(Prefix, c.Text)
+					if !ok {
+						continue
+					}
+					switch d.Name {
+					case "skip":
+						f.skip = true
+					case "custom":
+						f.equalFunc, _ = d.Arg("equal")
+						f.hashFunc, _ = d.Arg("hash")
+						if f.equalFunc == "" {
+							return nil, fmt.Errorf("%s.%s: custom directive has no equal function", n.Obj().Name(), fm.Name)
+						}
+					default:
+						return nil, fmt.Errorf("%s.%s: unknown directive %s%s", n.Obj().Name(), fm.Name, Prefix, d.Name)
+					}
+				}
+			}
+		}
+		if fm.Name == "_" {
+			f.skip = true
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// methods writes the Equal and Hash methods of n to w.
+func (g *generator) methods(w *bytes.Buffer, n *types.Named) error {
+	fields, err := g.fields(n)
+	if err != nil {
+		return err
+	}
+	name := n.Obj().Name()
+	recv := gen.ReceiverName(name)
+
+	var eqs []string
+	var hash bytes.Buffer
+	for _, f := range fields {
+		if f.skip {
+			continue
+		}
+		a, b := recv+"."+f.Name, "other."+f.Name
+		if f.equalFunc != "" {
+			eqs = append(eqs, fmt.Sprintf("%s(%s, %s)", f.equalFunc, a, b))
+			if f.hashFunc != "" {
+				fmt.Fprintf(&hash, "\thash = equalHashUint64(hash, %s(%s))\n", f.hashFunc, a)
+			}
+			continue
+		}
+		eq, err := g.equal(a, b, f.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", name, f.Name, err)
+		}
+		eqs = append(eqs, eq)
+		if err := g.hash(&hash, "\t", "hash", a, f.Type); err != nil {
+			return fmt.Errorf("%s.%s: %w", name, f.Name, err)
+		}
+	}
+
+	fmt.Fprintf(w, "\n// Equal reports whether %s and other are equal.\n", recv)
+	fmt.Fprintf(w, "func (%s %s) Equal(other %s) bool {\n", recv, name, name)
+	if len(eqs) == 0 {
+		w.WriteString("\treturn true\n}\n")
+	} else {
+		fmt.Fprintf(w, "\treturn %s\n}\n", joinConditions(eqs))
+	}
+
+	fmt.Fprintf(w, "\n// Hash returns a hash of %s. Values that are Equal have the same hash.\n", recv)
+	fmt.Fprintf(w, "func (%s %s) Hash() uint64 {\n", recv, name)
+	fmt.Fprintf(w, "\thash := uint64(%s)\n", offset)
+	w.Write(hash.Bytes())
+	w.WriteString("\treturn hash\n}\n")
+	return nil
+}
+
+// joinConditions joins boolean expressions with &&, one per line.
+func joinConditions(conds []string) string {
+	var b bytes.Buffer
+	for i, c := range conds {
+		if i > 0 {
+			b.WriteString(" &&\n\t\t")
+		}
+		b.WriteString(c)
+	}
+	return b.String()
+}
+
+// method reports whether values of type t have a method named Equal with the
+// signature func(T) bool, or named Hash with the signature func() uint64.
+func method(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() != 1 {
+		return false
+	}
+	res, ok := sig.Results().At(0).Type().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch name {
+	case "Equal":
+		return sig.Params().Len() == 1 && types.Identical(sig.Params().At(0).Type(), t) && res.Kind() == types.Bool
+	case "Hash":
+		return sig.Params().Len() == 0 && res.Kind() == types.Uint64
+	}
+	return false
+}
+
+// hasMethod reports whether values of type t have the named method, either
+// declared or to be generated.
+func (g *generator) hasMethod(t types.Type, name string) bool {
+	if n, ok := t.(*types.Named); ok && g.gen[n] {
+		return true
+	}
+	return method(t, name)
+}
+
+// deep reports whether values of type t must be compared by something other
+// than the == operator.
+func (g *generator) deep(t types.Type) bool {
+	if g.hasMethod(t, "Equal") || !types.Comparable(t) {
+		return true
+	}
+	if a, ok := t.Underlying().(*types.Array); ok {
+		return g.deep(a.Elem())
+	}
+	return false
+}
+
+// equal returns an expression reporting whether the values a and b of type t
+// are equal.
+func (g *generator) equal(a, b string, t types.Type) (string, error) {
+	if g.hasMethod(t, "Equal") {
+		return fmt.Sprintf("%s.Equal(%s)", operand(a), b), nil
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			return fmt.Sprintf("%s.Equal(%s, %s)", g.imports.Add("bytes", ""), a, b), nil
+		}
+		return g.equalFunc("slices", a, b, u.Elem())
+	case *types.Map:
+		return g.equalFunc("maps", a, b, u.Elem())
+	case *types.Array:
+		if !g.deep(u.Elem()) {
+			return fmt.Sprintf("%s == %s", a, b), nil
+		}
+		return g.equalFunc("slices", operand(a)+"[:]", operand(b)+"[:]", u.Elem())
+	case *types.Pointer:
+		eq, err := g.equal("*"+a, "*"+b, u.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s == %s || %s != nil && %s != nil && %s)", a, b, a, b, eq), nil
+	case *types.Signature:
+		return "", fmt.Errorf("cannot compare values of type %s", types.TypeString(t, g.qual))
+	}
+	if !types.Comparable(t) {
+		return "", fmt.Errorf("cannot compare values of type %s", types.TypeString(t, g.qual))
+	}
+	return fmt.Sprintf("%s == %s", a, b), nil
+}
+
+// equalFunc returns a call of the Equal or EqualFunc function of pkg, slices
+// or maps, comparing a and b whose elements have type elem.
+func (g *generator) equalFunc(pkg, a, b string, elem types.Type) (string, error) {
+	name := g.imports.Add(pkg, "")
+	if !g.deep(elem) {
+		return fmt.Sprintf("%s.Equal(%s, %s)", name, a, b), nil
+	}
+	g.vars++
+	x, y := fmt.Sprintf("x%d", g.vars), fmt.Sprintf("y%d", g.vars)
+	eq, err := g.equal(x, y, elem)
+	if err != nil {
+		return "", err
+	}
+	typ := types.TypeString(elem, g.qual)
+	return fmt.Sprintf("%s.EqualFunc(%s, %s, func(%s, %s %s) bool { return %s })", name, a, b, x, y, typ, eq), nil
+}
+
+// hash writes statements to w that combine the value x of type t into the
+// hash held by the variable h.
+func (g *generator) hash(w *bytes.Buffer, indent, h, x string, t types.Type) error {
+	if g.hasMethod(t, "Hash") {
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, %s.Hash())\n", indent, h, h, operand(x))
+		return nil
+	}
+	if isTime(t) {
+		// Times that are Equal denote the same instant.
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, uint64(%s.UnixNano()))\n", indent, h, h, operand(x))
+		return nil
+	}
+	if g.hasMethod(t, "Equal") {
+		return fmt.Errorf("type %s has an Equal method but no Hash method", types.TypeString(t, g.qual))
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return g.hashBasic(w, indent, h, x, u)
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			fmt.Fprintf(w, "%s%s = equalHashString(%s, string(%s))\n", indent, h, h, x)
+			return nil
+		}
+		return g.hashElems(w, indent, h, x, u.Elem())
+	case *types.Array:
+		return g.hashElems(w, indent, h, x, u.Elem())
+	case *types.Map:
+		g.vars++
+		sum := fmt.Sprintf("sum%d", g.vars)
+		k, v, e := fmt.Sprintf("k%d", g.vars), fmt.Sprintf("v%d", g.vars), fmt.Sprintf("e%d", g.vars)
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, uint64(len(%s)))\n", indent, h, h, x)
+		fmt.Fprintf(w, "%svar %s uint64\n", indent, sum)
+		fmt.Fprintf(w, "%sfor %s, %s := range %s {\n", indent, k, v, x)
+		fmt.Fprintf(w, "%s\t%s := uint64(%s)\n", indent, e, offset)
+		if err := g.hash(w, indent+"\t", e, k, u.Key()); err != nil {
+			return err
+		}
+		if err := g.hash(w, indent+"\t", e, v, u.Elem()); err != nil {
+			return err
+		}
+		// Entries are summed so that the hash does not depend on the order
+		// of iteration.
+		fmt.Fprintf(w, "%s\t%s += %s\n", indent, sum, e)
+		fmt.Fprintf(w, "%s}\n", indent)
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, %s)\n", indent, h, h, sum)
+		return nil
+	case *types.Pointer:
+		fmt.Fprintf(w, "%sif %s != nil {\n", indent, x)
+		fmt.Fprintf(w, "%s\t%s = equalHashUint64(%s, 1)\n", indent, h, h)
+		if err := g.hash(w, indent+"\t", h, "*"+x, u.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s} else {\n", indent)
+		fmt.Fprintf(w, "%s\t%s = equalHashUint64(%s, 0)\n", indent, h, h)
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Struct:
+		n, ok := t.(*types.Named)
+		if ok && n.Obj().Pkg() != g.fs.Package {
+			return fmt.Errorf("cannot hash values of type %s", types.TypeString(t, g.qual))
+		}
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if f.Name() == "_" {
+				continue
+			}
+			if err := g.hash(w, indent, h, operand(x)+"."+f.Name(), f.Type()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot hash values of type %s", types.TypeString(t, g.qual))
+}
+
+// hashElems writes statements that hash the length and elements of the slice
+// or array x.
+func (g *generator) hashElems(w *bytes.Buffer, indent, h, x string, elem types.Type) error {
+	g.vars++
+	v := fmt.Sprintf("v%d", g.vars)
+	fmt.Fprintf(w, "%s%s = equalHashUint64(%s, uint64(len(%s)))\n", indent, h, h, x)
+	fmt.Fprintf(w, "%sfor _, %s := range %s {\n", indent, v, x)
+	if err := g.hash(w, indent+"\t", h, v, elem); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+	return nil
+}
+
+// hashBasic writes statements that hash the value x of the basic type t.
+func (g *generator) hashBasic(w *bytes.Buffer, indent, h, x string, t *types.Basic) error {
+	info := t.Info()
+	switch {
+	case info&types.IsBoolean != 0:
+		fmt.Fprintf(w, "%sif %s {\n%s\t%s = equalHashUint64(%s, 1)\n%s} else {\n%s\t%s = equalHashUint64(%s, 0)\n%s}\n",
+			indent, x, indent, h, h, indent, indent, h, h, indent)
+	case info&types.IsInteger != 0:
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, uint64(%s))\n", indent, h, h, x)
+	case info&types.IsFloat != 0:
+		// Adding zero makes negative zero positive, since the two are equal.
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, %s.Float64bits(float64(%s)+0))\n", indent, h, h, g.imports.Add("math", ""), x)
+	case info&types.IsComplex != 0:
+		m := g.imports.Add("math", "")
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, %s.Float64bits(real(complex128(%s))+0))\n", indent, h, h, m, x)
+		fmt.Fprintf(w, "%s%s = equalHashUint64(%s, %s.Float64bits(imag(complex128(%s))+0))\n", indent, h, h, m, x)
+	case info&types.IsString != 0:
+		fmt.Fprintf(w, "%s%s = equalHashString(%s, string(%s))\n", indent, h, h, x)
+	default:
+		return fmt.Errorf("cannot hash values of type %s", t)
+	}
+	return nil
+}
+
+// operand returns the expression x in a form that may be followed by a
+// selector or index, parenthesizing a pointer indirection.
+func operand(x string) string {
+	if strings.HasPrefix(x, "*") {
+		return "(" + x + ")"
+	}
+	return x
+}
+
+func isByte(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+func isTime(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time"
+}
+
+// helpers holds the functions shared by the generated Hash methods.
+const helpers = `
+// equalHashOffset is the offset basis of the 64 bit FNV-1a hash.
+const equalHashOffset = 14695981039346656037
+
+// equalHashUint64 combines the bytes of v into the FNV-1a hash h.
+func equalHashUint64(h, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= 1099511628211
+		v >>= 8
+	}
+	return h
+}
+
+// equalHashString combines the length and bytes of s into the FNV-1a hash h.
+func equalHashString(h uint64, s string) uint64 {
+	h = equalHashUint64(h, uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+`
@@ -0,0 +1,210 @@
+package equal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"strings"
+	"time"
+)
+
+type Point struct {
+	X, Y float64
+}
+
+type Shape struct {
+	Name    string
+	Points  []Point
+	Origin  *Point
+	Labels  map[string][]byte
+	Created time.Time
+	Grid    [2][]int
+	Flags   map[Point]bool
+	Kind    Kind
+
+	// Title is compared ignoring case.
+	//equal:custom equal=sameFold hash=foldHash
+	Title string
+
+	Cache func() //equal:skip
+}
+
+type Kind uint8
+
+func sameFold(a, b string) bool { return strings.EqualFold(a, b) }
+
+func foldHash(s string) uint64 {
+	var h uint64
+	for _, r := range strings.ToLower(s) {
+		h = h*31 + uint64(r)
+	}
+	return h
+}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("equal")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"func (p Point) Equal(other Point) bool",
+		"func (s Shape) Hash() uint64",
+		"slices.EqualFunc(s.Points, other.Points, func(x1, y1 Point) bool { return x1.Equal(y1) })",
+		"(s.Origin == other.Origin || s.Origin != nil && other.Origin != nil && (*s.Origin).Equal(*other.Origin))",
+		"maps.Equal(s.Flags, other.Flags)",
+		"bytes.Equal(",
+		"s.Created.Equal(other.Created)",
+		"sameFold(s.Title, other.Title)",
+		"hash = equalHashUint64(hash, foldHash(s.Title))",
+		"math.Float64bits(float64(p.X)+0)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "Cache") {
+		t.Errorf("skipped field Cache was compared:\n%s", src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		typ  string
+	}{
+		{name: "missing", src: "package p\n", typ: "T"},
+		{name: "not struct", src: "package p\ntype T int\n", typ: "T"},
+		{name: "generic", src: "package p\ntype T[E any] struct{ V E }\n", typ: "T"},
+		{name: "func field", src: "package p\ntype T struct{ F func() }\n", typ: "T"},
+		{name: "interface field", src: "package p\ntype T struct{ V any }\n", typ: "T"},
+		{name: "unknown directive", src: "package p\ntype T struct{\n\tV int //equal:ignore\n}\n", typ: "T"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := gen.NewFileSetFromTexts(tc.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := Generate(fs, gen.NewOutput("equal"), tc.typ); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+const testProgram = `package p
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerated(t *testing.T) {
+	now := time.Now()
+	a := Shape{
+		Name:    "a",
+		Points:  []Point{{1, 2}, {3, 4}},
+		Origin:  &Point{0, 0},
+		Labels:  map[string][]byte{"x": []byte("1"), "y": []byte("2")},
+		Created: now,
+		Grid:    [2][]int{{1}, {2, 3}},
+		Flags:   map[Point]bool{{1, 1}: true, {2, 2}: false},
+		Title:   "Hello",
+		Cache:   func() {},
+	}
+	b := a
+	b.Origin = &Point{0, 0}
+	b.Labels = map[string][]byte{"y": []byte("2"), "x": []byte("1")}
+	b.Created = now.UTC()
+	b.Title = "HELLO"
+	b.Cache = nil
+	if !a.Equal(b) {
+		t.Fatalf("equal values reported unequal")
+	}
+	if a.Hash() != b.Hash() {
+		t.Fatalf("equal values have different hashes")
+	}
+
+	for name, change := range map[string]func(s *Shape){
+		"name":   func(s *Shape) { s.Name = "b" },
+		"points": func(s *Shape) { s.Points = s.Points[:1] },
+		"origin": func(s *Shape) { s.Origin = nil },
+		"labels": func(s *Shape) { s.Labels = map[string][]byte{"x": []byte("1"), "y": []byte("3")} },
+		"time":   func(s *Shape) { s.Created = now.Add(1) },
+		"grid":   func(s *Shape) { s.Grid = [2][]int{{1}, {2}} },
+		"flags":  func(s *Shape) { s.Flags = map[Point]bool{{1, 1}: true} },
+		"kind":   func(s *Shape) { s.Kind = 1 },
+		"title":  func(s *Shape) { s.Title = "Goodbye" },
+	} {
+		c := a
+		change(&c)
+		if a.Equal(c) {
+			t.Errorf("%s: unequal values reported equal", name)
+		}
+		if a.Hash() == c.Hash() {
+			t.Errorf("%s: unequal values have the same hash", name)
+		}
+	}
+}
+`
+
+func TestGeneratedMethods(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.21\n",
+		"p.go":      testSrc,
+		"p_test.go": testProgram,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "p.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("equal")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "equal_gen.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated methods failed: %v\n%s\n%s", err, out, src)
+	}
+}
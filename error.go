@@ -0,0 +1,251 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Phase identifies the stage of generation in which an Error occurred.
+type Phase int
+
+const (
+	// PhaseParse is the parsing of the source files of a package.
+	PhaseParse Phase = iota
+
+	// PhaseTypeCheck is the type checking of a package.
+	PhaseTypeCheck
+
+	// PhaseTemplate is the execution of a template.
+	PhaseTemplate
+
+	// PhaseFormat is the formatting of generated source code, which fails
+	// when a generator produces code that does not parse.
+	PhaseFormat
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseParse:
+		return "parse"
+	case PhaseTypeCheck:
+		return "typecheck"
+	case PhaseTemplate:
+		return "template"
+	case PhaseFormat:
+		return "format"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// Error is an error located in source code: a syntax or type error in the
+// package being loaded, an error executing a template, or a syntax error in
+// the generated code. Loading a FileSet and rendering a template report all
+// the errors they find, as an ErrorList, rather than stopping at the first.
+type Error struct {
+	// Phase is the stage of generation in which the error occurred.
+	Phase Phase
+
+	// Pos is the position of the error. Its Filename is empty for errors in
+	// generated code that has not been written to a file, and it is not valid
+	// if the position is not known.
+	Pos token.Position
+
+	// Msg is the error message, without the position.
+	Msg string
+
+	// Snippet is the line of source code containing the error, without its
+	// line ending, or empty if the source is not available.
+	Snippet string
+
+	// Err is the underlying error, such as a types.Error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostic returns a description of the error for display to a user: its
+// position, phase and message followed by the line of source code containing
+// the error, if known, with a caret marking the column.
+//
+//	api.go:12:9: typecheck: undefined: Usr
+//		return Usr{}
+//		       ^
+func (e *Error) Diagnostic() string {
+	var b strings.Builder
+	if e.Pos.IsValid() {
+		b.WriteString(e.Pos.String() + ": ")
+	}
+	b.WriteString(e.Phase.String() + ": " + e.Msg)
+	if e.Snippet != "" {
+		b.WriteString("\n\t" + e.Snippet)
+		if e.Pos.Column > 0 && e.Pos.Column <= len(e.Snippet)+1 {
+			// Keep tabs so that the caret lines up with the snippet.
+			pad := []byte(e.Snippet[:e.Pos.Column-1])
+			for i, c := range pad {
+				if c != '\t' {
+					pad[i] = ' '
+				}
+			}
+			b.WriteString("\n\t" + string(pad) + "^")
+		}
+	}
+	return b.String()
+}
+
+// ErrorList is a list of Errors. The type errors of a package are sorted by
+// position.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// sort sorts l by position. Errors without a position come first.
+func (l ErrorList) sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Pos, l[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// err returns l as an error, or nil if l is empty.
+func (l ErrorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Errors returns the located errors in err, which may be an *Error or an
+// ErrorList, possibly wrapped. It returns nil if err holds neither.
+func Errors(err error) []*Error {
+	var list ErrorList
+	if errors.As(err, &list) {
+		return list
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return []*Error{e}
+	}
+	return nil
+}
+
+// sources maps filenames to their source code, to provide the lines of
+// source that errors refer to. Files that are not in the map are read from
+// disk when needed.
+type sources map[string][]byte
+
+// line returns the line of source at pos, or an empty string if it is not
+// available.
+func (s sources) line(pos token.Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	src, ok := s[pos.Filename]
+	if !ok && pos.Filename != "" {
+		var err error
+		if src, err = os.ReadFile(pos.Filename); err != nil {
+			return ""
+		}
+		s[pos.Filename] = src
+	}
+	return sourceLine(src, pos.Line)
+}
+
+// sourceLine returns the line of src with the given 1-based number, or an
+// empty string if there is no such line.
+func sourceLine(src []byte, n int) string {
+	for i := 1; i < n; i++ {
+		j := bytes.IndexByte(src, '\n')
+		if j < 0 {
+			return ""
+		}
+		src = src[j+1:]
+	}
+	if j := bytes.IndexByte(src, '\n'); j >= 0 {
+		src = src[:j]
+	}
+	return strings.TrimSuffix(string(src), "\r")
+}
+
+// syntaxErrors converts the syntax errors in err, as returned by the parser,
+// to an ErrorList in the given phase. Other errors are returned unchanged.
+func syntaxErrors(err error, phase Phase, src sources) error {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return err
+	}
+	var errs ErrorList
+	for _, e := range list {
+		errs = append(errs, &Error{Phase: phase, Pos: e.Pos, Msg: e.Msg, Snippet: src.line(e.Pos), Err: e})
+	}
+	return errs
+}
+
+// typeError converts err, as reported to the Error function of a
+// types.Config, to an Error.
+func typeError(err error, src sources) *Error {
+	e := &Error{Phase: PhaseTypeCheck, Msg: err.Error(), Err: err}
+	var te types.Error
+	if errors.As(err, &te) {
+		e.Pos, e.Msg = te.Fset.Position(te.Pos), te.Msg
+		e.Snippet = src.line(e.Pos)
+	}
+	return e
+}
+
+// templateErrorRx matches the location that text/template gives at the start
+// of its error messages, such as "template: name:3:12: ".
+var templateErrorRx = regexp.MustCompile(`^template: ([^:]*):(\d+):(?:(\d+):)? `)
+
+// templateError converts an error executing a template to an Error, taking
+// its position from the location that text/template puts at the start of the
+// message. The snippet is left empty since the template's text is not kept.
+func templateError(err error) *Error {
+	e := &Error{Phase: PhaseTemplate, Msg: err.Error(), Err: err}
+	if m := templateErrorRx.FindStringSubmatch(e.Msg); m != nil {
+		e.Pos.Filename = m[1]
+		e.Pos.Line, _ = strconv.Atoi(m[2])
+		e.Pos.Column, _ = strconv.Atoi(m[3])
+		e.Msg = e.Msg[len(m[0]):]
+	}
+	return e
+}
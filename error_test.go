@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"a.go": "package p\n\nfunc A() {\n\treturn 1 +\n}\n",
+		"b.go": "package p\n\nvar B = [\n",
+		"c.go": "package p\n\nfunc C() {}\n",
+	})
+	_, err := FileSetFromDir(dir)
+	errs := Errors(err)
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, wanted one for each of a.go and b.go", err)
+	}
+	for i, want := range []string{"a.go", "b.go"} {
+		e := errs[i]
+		if e.Phase != PhaseParse || e.Pos.Filename != filepath.Join(dir, want) || e.Snippet == "" {
+			t.Errorf("got %+v, wanted parse error with snippet in %s", e, want)
+		}
+	}
+}
+
+func TestTypeCheckErrors(t *testing.T) {
+	_, err := NewFileSetFromTexts("package p\n\nfunc F() int {\n\treturn Missing\n}\n\nvar V string = 1\n")
+	errs := Errors(err)
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, wanted 2", err)
+	}
+	e := errs[0]
+	if e.Phase != PhaseTypeCheck || e.Pos.Line != 4 || e.Pos.Column != 9 || e.Msg != "undefined: Missing" {
+		t.Errorf("got %+v, wanted undefined: Missing at 4:9", e)
+	}
+	var te types.Error
+	if !errors.As(e, &te) {
+		t.Errorf("got error without types.Error")
+	}
+	want := "0.go:4:9: typecheck: undefined: Missing\n\t\treturn Missing\n\t\t       ^"
+	if got := e.Diagnostic(); got != want {
+		t.Errorf("got diagnostic:\n%s\nwanted:\n%s", got, want)
+	}
+	if got, want := err.Error(), "0.go:4:9: undefined: Missing (and 1 more errors)"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestTemplateAndFormatErrors(t *testing.T) {
+	tt, err := NewTemplateType("gen.tmpl", "package p\n\nvar X = {{.Missing}}\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = tt.Render(struct{}{})
+	errs := Errors(err)
+	if len(errs) != 1 || errs[0].Phase != PhaseTemplate || errs[0].Pos.Filename != "gen.tmpl" || errs[0].Pos.Line != 3 {
+		t.Errorf("got %v, wanted template error at gen.tmpl:3", err)
+	}
+
+	tt, err = NewTemplateType("gen.tmpl", "package p\n\nvar X = {{.}}\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = tt.Render("[")
+	errs = Errors(err)
+	if len(errs) == 0 || errs[0].Phase != PhaseFormat || errs[0].Snippet != "var X = [" {
+		t.Errorf("got %v, wanted format error in var X = [", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	e := &Error{Phase: PhaseFormat, Msg: "oops"}
+	wrapped := fmt.Errorf("context: %w", ErrorList{e})
+	if got := Errors(wrapped); len(got) != 1 || got[0] != e {
+		t.Errorf("got %v, wanted the wrapped error", got)
+	}
+	if got := Errors(fmt.Errorf("context: %w", e)); len(got) != 1 || got[0] != e {
+		t.Errorf("got %v, wanted the wrapped error", got)
+	}
+	if got := Errors(errors.New("plain")); got != nil {
+		t.Errorf("got %v, wanted nil", got)
+	}
+	if got, want := e.Error(), "oops"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	e.Pos = token.Position{Filename: "f.go", Line: 2, Column: 3}
+	if got, want := e.Error(), "f.go:2:3: oops"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EscapeKind classifies the result of the compiler's escape analysis for a
+// single expression or variable.
+type EscapeKind int
+
+const (
+	// EscapesToHeap indicates that a value is allocated on the heap.
+	EscapesToHeap EscapeKind = iota
+
+	// MovedToHeap indicates that a variable is moved to the heap because its
+	// address outlives the function.
+	MovedToHeap
+
+	// DoesNotEscape indicates that a value stays on the stack.
+	DoesNotEscape
+
+	// LeakingParam indicates that a parameter, or something it points to,
+	// outlives the call.
+	LeakingParam
+)
+
+func (k EscapeKind) String() string {
+	switch k {
+	case EscapesToHeap:
+		return "escapes to heap"
+	case MovedToHeap:
+		return "moved to heap"
+	case DoesNotEscape:
+		return "does not escape"
+	case LeakingParam:
+		return "leaking param"
+	default:
+		return "EscapeKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// EscapeHint is a single result of the compiler's escape analysis.
+type EscapeHint struct {
+	// Pos is the position of the expression or variable in the source. The
+	// filename is absolute.
+	Pos token.Position
+
+	// Kind classifies the hint.
+	Kind EscapeKind
+
+	// Message is the complete message reported by the compiler.
+	Message string
+}
+
+// Allocates reports whether the hint describes a heap allocation.
+func (h EscapeHint) Allocates() bool {
+	return h.Kind == EscapesToHeap || h.Kind == MovedToHeap
+}
+
+// compilerDiagnostic is a positioned message printed by the compiler.
+type compilerDiagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+var diagnosticRx = regexp.MustCompile(`^(.+\.go):(\d+):(\d+): (.*)$`)
+
+// compilerDiagnostics builds the package in dir with the supplied compiler
+// flags and returns the diagnostics printed for files in that package. The
+// build output is discarded so that building a main package does not leave a
+// binary in dir.
+func compilerDiagnostics(dir string, gcflags string) ([]compilerDiagnostic, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("go", "build", "-o", os.DevNull, "-gcflags="+gcflags, ".")
+	cmd.Dir = absDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go build: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var diags []compilerDiagnostic
+	s := bufio.NewScanner(&stderr)
+	for s.Scan() {
+		m := diagnosticRx.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		filename := m[1]
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(absDir, filename)
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		diags = append(diags, compilerDiagnostic{
+			Pos:     token.Position{Filename: filename, Line: line, Column: col},
+			Message: m[4],
+		})
+	}
+	return diags, s.Err()
+}
+
+// EscapeAnalysis runs the compiler's escape analysis on the package in dir and
+// returns the hints it reports. It requires the go command and a package that
+// builds successfully.
+func EscapeAnalysis(dir string) ([]EscapeHint, error) {
+	diags, err := compilerDiagnostics(dir, "-m")
+	if err != nil {
+		return nil, err
+	}
+
+	var hints []EscapeHint
+	for _, d := range diags {
+		h := EscapeHint{Pos: d.Pos, Message: d.Message}
+		switch {
+		case strings.HasPrefix(d.Message, "moved to heap:"):
+			h.Kind = MovedToHeap
+		case strings.HasSuffix(d.Message, "escapes to heap"):
+			h.Kind = EscapesToHeap
+		case strings.HasSuffix(d.Message, "does not escape"):
+			h.Kind = DoesNotEscape
+		case strings.HasPrefix(d.Message, "leaking param"):
+			h.Kind = LeakingParam
+		default:
+			continue
+		}
+		hints = append(hints, h)
+	}
+	return hints, nil
+}
+
+// EscapeHints runs the compiler's escape analysis on the directory of fs and
+// returns the hints reported within the body of the named function. Methods
+// are named using their receiver type, such as "T.Reset". A generator can use
+// this to warn when code it emitted to avoid allocation still allocates.
+func (fs *FileSet) EscapeHints(funcName string) ([]EscapeHint, error) {
+	fm, ok := fs.Func(funcName)
+	if !ok {
+		return nil, fmt.Errorf("function %s not found", funcName)
+	}
+
+	all, err := EscapeAnalysis(fs.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	start := fs.FileSet.Position(fm.Decl.Pos())
+	end := fs.FileSet.Position(fm.Decl.End())
+	filename, err := filepath.Abs(start.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var hints []EscapeHint
+	for _, h := range all {
+		if h.Pos.Filename != filename || h.Pos.Line < start.Line || h.Pos.Line > end.Line {
+			continue
+		}
+		hints = append(hints, h)
+	}
+	return hints, nil
+}
@@ -0,0 +1,93 @@
+package gen
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEscapeHints(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go": `package p
+
+type Buffer struct{ b []byte }
+
+func NewBuffer() *Buffer {
+	return &Buffer{b: make([]byte, 0, 64)}
+}
+
+func (b *Buffer) Reset() {
+	b.b = b.b[:0]
+}
+
+func Sum(xs []int) int {
+	t := 0
+	for _, x := range xs {
+		t += x
+	}
+	return t
+}
+`,
+	})
+
+	fs, err := NewFileSet([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hints, err := fs.EscapeHints("NewBuffer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	allocs := 0
+	for _, h := range hints {
+		if h.Allocates() {
+			allocs++
+		}
+	}
+	if allocs != 2 {
+		t.Errorf("got %d allocations in NewBuffer, wanted 2: %+v", allocs, hints)
+	}
+
+	for _, name := range []string{"Buffer.Reset", "Sum"} {
+		hints, err := fs.EscapeHints(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, h := range hints {
+			if h.Allocates() {
+				t.Errorf("%s: unexpected allocation: %s", name, h.Message)
+			}
+		}
+	}
+
+	if _, err := fs.EscapeHints("Missing"); err == nil {
+		t.Errorf("got no error for missing function")
+	}
+}
+
+func TestEscapeAnalysisMain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"main.go": "package main\n\nfunc main() { println(new(int)) }\n",
+	})
+	if _, err := EscapeAnalysis(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range entries {
+		if name := e.Name(); name != "go.mod" && name != "main.go" {
+			t.Errorf("go build left %s in the package directory", name)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package example generates skeleton example functions for the exported API
+// of a package to improve its documentation.
+//
+// An example is written for every exported function and for every exported
+// method of an exported type. Each example is a compilable skeleton that
+// calls the function with zero value arguments and assigns its results,
+// annotated with TODO comments inviting the author to replace the zero
+// values with meaningful ones. Generic functions are skipped since their type
+// arguments cannot be inferred from zero values. The examples have no output
+// comment so they are compiled but not run by go test.
+package example
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iand/gen"
+)
+
+// Name returns the name of the example function for the function or method
+// fm, following the conventions of the testing package.
+func Name(fm *gen.FuncModel) string {
+	if fm.Recv == "" {
+		return "Example" + fm.Name
+	}
+	return "Example" + fm.Recv + "_" + fm.Name
+}
+
+// Generate writes examples for the exported API of the package in fs to o.
+// The examples belong to the package's external test package. Examples whose
+// names appear in exclude, typically because they have been written by hand,
+// are skipped.
+func Generate(fs *gen.FileSet, o *gen.Output, exclude ...string) error {
+	skip := make(map[string]bool)
+	for _, name := range exclude {
+		skip[name] = true
+	}
+
+	imports := gen.NewImports()
+	pkgName := imports.Add(fs.ImportPath(), fs.Package.Name())
+	qual := func(p *types.Package) string {
+		if p == fs.Package {
+			return pkgName
+		}
+		return imports.Add(p.Path(), p.Name())
+	}
+
+	var body bytes.Buffer
+	for _, fm := range fs.Funcs() {
+		if !fm.Object.Exported() || skip[Name(fm)] {
+			continue
+		}
+		if fm.Recv != "" && !token.IsExported(fm.Recv) {
+			continue
+		}
+		sig := fm.Signature()
+		if sig.TypeParams().Len() > 0 {
+			continue
+		}
+		if recv := sig.Recv(); recv != nil {
+			if n := gen.NamedOf(recv.Type()); n == nil || n.TypeParams().Len() > 0 {
+				continue
+			}
+		}
+		writeExample(&body, fm, pkgName, qual)
+	}
+
+	if body.Len() == 0 {
+		return fmt.Errorf("no exported functions or methods found in package %s", fs.Package.Name())
+	}
+
+	o.Printf("package %s_test\n\n", fs.Package.Name())
+	o.Printf("%s\n", imports.Block())
+	o.Write(body.Bytes())
+	return nil
+}
+
+// writeExample writes the example for fm to b.
+func writeExample(b *bytes.Buffer, fm *gen.FuncModel, pkgName string, qual types.Qualifier) {
+	used := map[string]bool{pkgName: true}
+
+	fmt.Fprintf(b, "\nfunc %s() {\n", Name(fm))
+
+	callee := pkgName + "." + fm.Name
+	if fm.Recv != "" {
+		recv := uniqueName(lowerFirst(fm.Recv), used)
+		fmt.Fprintf(b, "\tvar %s %s.%s\n", recv, pkgName, fm.Recv)
+		callee = recv + "." + fm.Name
+	}
+
+	params := fm.Params
+	if fm.Variadic {
+		params = params[:len(params)-1]
+	}
+	args := make([]string, len(params))
+	for i, p := range params {
+		args[i] = gen.ZeroValue(p.Type, qual)
+	}
+	if len(args) > 0 {
+		b.WriteString("\t// TODO: replace the zero values with meaningful arguments.\n")
+	}
+	call := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+
+	if len(fm.Results) == 0 {
+		fmt.Fprintf(b, "\t%s\n}\n", call)
+		return
+	}
+
+	names := make([]string, len(fm.Results))
+	for i, r := range fm.Results {
+		names[i] = uniqueName(resultName(r), used)
+	}
+	fmt.Fprintf(b, "\t%s := %s\n", strings.Join(names, ", "), call)
+	for i, r := range fm.Results {
+		if isError(r.Type) {
+			fmt.Fprintf(b, "\tif %s != nil {\n\t\t// TODO: handle the error.\n\t}\n", names[i])
+		} else {
+			fmt.Fprintf(b, "\t_ = %s // TODO: use the result.\n", names[i])
+		}
+	}
+	b.WriteString("}\n")
+}
+
+// resultName chooses a variable name for a function result.
+func resultName(r *gen.ParamModel) string {
+	if r.Name != "" && r.Name != "_" {
+		return r.Name
+	}
+	if isError(r.Type) {
+		return "err"
+	}
+	if n := gen.NamedOf(r.Type); n != nil {
+		return lowerFirst(n.Obj().Name())
+	}
+	return "result"
+}
+
+// uniqueName returns name, or name with a numeric suffix if it has already
+// been used, and records the result as used.
+func uniqueName(name string, used map[string]bool) string {
+	if isKeyword(name) {
+		name += "_"
+	}
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// lowerFirst lowercases the leading run of upper case letters in s so that
+// Client becomes client and HTTPServer becomes httpServer.
+func lowerFirst(s string) string {
+	r := []rune(s)
+	i := 0
+	for i < len(r) && unicode.IsUpper(r[i]) {
+		i++
+	}
+	if i > 1 && i < len(r) {
+		i-- // keep the first letter of the next word upper case
+	}
+	for j := 0; j < i; j++ {
+		r[j] = unicode.ToLower(r[j])
+	}
+	return string(r)
+}
+
+var keywords = func() []string {
+	kw := []string{"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+		"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return",
+		"select", "struct", "switch", "type", "var"}
+	sort.Strings(kw)
+	return kw
+}()
+
+func isKeyword(s string) bool {
+	i := sort.SearchStrings(keywords, s)
+	return i < len(keywords) && keywords[i] == s
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
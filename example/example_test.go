@@ -0,0 +1,158 @@
+package example
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type Client struct {
+	Timeout time.Duration
+}
+
+func NewClient(ctx context.Context, name string, opts ...Option) (*Client, error) {
+	return &Client{}, nil
+}
+
+func (c *Client) Fetch(key string, w io.Writer) (n int, err error) { return 0, nil }
+
+func (c Client) Close() {}
+
+func (c Client) private() {}
+
+type Option func(*Client)
+
+type Point struct{ X, Y int }
+
+func Distance(a, b Point, scale [2]float64) float64 { return 0 }
+
+func Map[T any](xs []T, f func(T) T) []T { return xs }
+
+type server struct{}
+
+func (server) Serve() {}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("example")
+	if err := Generate(fs, o, "ExampleClient_Close"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"func ExampleNewClient() {",
+		"client, err := p.NewClient(nil, \"\")",
+		"func ExampleClient_Fetch() {",
+		"var client p.Client",
+		"n, err := client.Fetch(\"\", nil)",
+		"func ExampleDistance() {",
+		"p.Distance(p.Point{}, p.Point{}, [2]float64{})",
+		"// TODO: handle the error.",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+
+	for _, unwanted := range []string{"ExampleClient_Close", "ExampleClient_private", "ExampleMap", "Serve"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated source unexpectedly contains %q:\n%s", unwanted, src)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	testCases := []struct {
+		fm   *gen.FuncModel
+		want string
+	}{
+		{fm: &gen.FuncModel{Name: "New"}, want: "ExampleNew"},
+		{fm: &gen.FuncModel{Name: "Close", Recv: "Client"}, want: "ExampleClient_Close"},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			if got := Name(tc.fm); got != tc.want {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLowerFirst(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{in: "Client", want: "client"},
+		{in: "HTTPServer", want: "httpServer"},
+		{in: "ID", want: "id"},
+		{in: "x", want: "x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			if got := lowerFirst(tc.in); got != tc.want {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedExamplesCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/p\n\ngo 1.21\n",
+		"p.go":   testSrc,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("example")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "example_gen_test.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated examples do not compile: %v\n%s\n%s", err, out, src)
+	}
+}
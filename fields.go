@@ -0,0 +1,153 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// FieldOption is an option for FieldsOf.
+type FieldOption func(*fieldOptions)
+
+type fieldOptions struct {
+	flatten bool
+}
+
+// FlattenEmbedded controls whether FieldsOf includes the fields promoted from
+// embedded structs in addition to the fields declared by the struct itself.
+func FlattenEmbedded(flatten bool) FieldOption {
+	return func(o *fieldOptions) {
+		o.flatten = flatten
+	}
+}
+
+// FieldsOf returns models of the fields of the named package level struct
+// type in declaration order.
+//
+// With FlattenEmbedded(true) the result is the effective field list of the
+// struct: each embedded field is followed by the fields promoted from it,
+// recursively, with Promoted set and Path holding the embedded fields through
+// which they are reached. Promoted fields are resolved as the compiler
+// resolves selectors, so fields shadowed by a field or method at a shallower
+// depth, fields that are ambiguous because they are promoted through several
+// embedded fields at the same depth, and unexported fields of other packages
+// are omitted. Promoted fields declared by other packages have no Doc,
+// Comment or Field.
+func (fs *FileSet) FieldsOf(typeName string, opts ...FieldOption) ([]*FieldModel, error) {
+	var o fieldOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	tm, ok := fs.Type(typeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", typeName)
+	}
+	root := tm.Object.Type()
+	st, ok := root.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct", typeName)
+	}
+	if !o.flatten {
+		return tm.Fields, nil
+	}
+
+	fl := &fieldFlattener{
+		fs:       fs,
+		root:     root,
+		declared: make(map[*types.Var]*FieldModel),
+		visiting: make(map[types.Type]bool),
+		fields:   []*FieldModel{},
+	}
+	for _, f := range tm.Fields {
+		fl.declared[f.Object] = f
+	}
+	fl.visiting[root] = true
+	fl.walk(st, nil)
+	return fl.fields, nil
+}
+
+// fieldFlattener collects the effective fields of a struct type.
+type fieldFlattener struct {
+	fs       *FileSet
+	root     types.Type
+	declared map[*types.Var]*FieldModel // models of fields declared in fs
+	visiting map[types.Type]bool        // embedded types being walked
+	loaded   bool                       // declared holds all fields declared in fs
+	fields   []*FieldModel
+}
+
+func (fl *fieldFlattener) walk(st *types.Struct, path []string) {
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		obj, _, _ := types.LookupFieldOrMethod(fl.root, true, fl.fs.Package, v.Name())
+		if obj == v {
+			m := fl.model(st, i)
+			if len(path) > 0 {
+				m.Promoted = true
+				m.Path = append([]string(nil), path...)
+			}
+			fl.fields = append(fl.fields, m)
+		}
+
+		if !v.Embedded() {
+			continue
+		}
+		t := Deref(v.Type())
+		est, ok := t.Underlying().(*types.Struct)
+		if !ok || fl.visiting[t] {
+			continue
+		}
+		fl.visiting[t] = true
+		fl.walk(est, append(path, v.Name()))
+		delete(fl.visiting, t)
+	}
+}
+
+// model returns a model of field i of st. Models of fields declared in the
+// package are copied from the model of their struct so they include details
+// from the declaration.
+func (fl *fieldFlattener) model(st *types.Struct, i int) *FieldModel {
+	v := st.Field(i)
+	if m, ok := fl.declared[v]; ok {
+		c := *m
+		return &c
+	}
+	if m := fl.declaredModel(v.Origin()); m != nil {
+		c := *m
+		c.Type, c.Object = v.Type(), v
+		return &c
+	}
+
+	tag := st.Tag(i)
+	tags, err := ParseTags(tag)
+	if err != nil {
+		tags = Tags{}
+	}
+	return &FieldModel{
+		Name:     v.Name(),
+		Type:     v.Type(),
+		Embedded: v.Embedded(),
+		Exported: v.Exported(),
+		Tag:      tag,
+		Tags:     tags,
+		Object:   v,
+	}
+}
+
+// declaredModel returns the model of the field v if it is declared by a
+// package level struct type in the package.
+func (fl *fieldFlattener) declaredModel(v *types.Var) *FieldModel {
+	if m, ok := fl.declared[v]; ok {
+		return m
+	}
+	if v.Pkg() != fl.fs.Package || fl.loaded {
+		return nil
+	}
+	fl.loaded = true
+	for _, tm := range fl.fs.Types() {
+		for _, f := range tm.Fields {
+			fl.declared[f.Object] = f
+		}
+	}
+	return fl.declared[v]
+}
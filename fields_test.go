@@ -0,0 +1,115 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFieldsOf(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "sync"
+
+		type Base struct {
+			// ID identifies the record.
+			ID      int ` + "`json:\"id\"`" + `
+			Name    string
+			Created int64
+			hidden  bool
+		}
+
+		type Audit struct {
+			Created int64
+			By      string
+		}
+
+		type Node struct {
+			*Node
+			Value int
+		}
+
+		type Wrapper[T any] struct {
+			Item T
+		}
+
+		type Record struct {
+			Base
+			*Audit
+			sync.Mutex
+			Wrapper[string]
+			Node
+			Name string
+		}
+
+		func (Record) By() string { return "" }
+
+		type NotStruct int
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+
+	summarize := func(fields []*FieldModel) []string {
+		s := []string{}
+		for _, f := range fields {
+			name := f.Name
+			if f.Promoted {
+				name = strings.Join(f.Path, ".") + "." + name
+			}
+			s = append(s, name+" "+types.TypeString(f.Type, q))
+		}
+		return s
+	}
+
+	fields, err := fs.FieldsOf("Record")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Base Base", "Audit *Audit", "Mutex sync.Mutex", "Wrapper Wrapper[string]", "Node Node", "Name string"}
+	if got := summarize(fields); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	fields, err = fs.FieldsOf("Record", FlattenEmbedded(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{
+		"Base Base",
+		"Base.ID int",
+		"Base.hidden bool",
+		"Audit *Audit",
+		"Mutex sync.Mutex",
+		"Wrapper Wrapper[string]",
+		"Wrapper.Item string",
+		"Node Node",
+		"Node.Value int",
+		"Name string",
+	}
+	if got := summarize(fields); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	for _, f := range fields {
+		switch f.Name {
+		case "ID":
+			if f.Doc != "ID identifies the record.\n" || f.Tag != `json:"id"` || f.Field == nil {
+				t.Errorf("got promoted field %+v without its declaration details", f)
+			}
+		case "Item":
+			if f.Field == nil {
+				t.Errorf("got field of instantiated type without its declaration")
+			}
+		}
+	}
+
+	if _, err := fs.FieldsOf("NotStruct", FlattenEmbedded(true)); err == nil {
+		t.Errorf("got no error for non-struct type, wanted one")
+	}
+	if _, err := fs.FieldsOf("Missing"); err == nil {
+		t.Errorf("got no error for missing type, wanted one")
+	}
+}
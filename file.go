@@ -1,15 +1,24 @@
 package gen
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/build"
-	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/ssa"
 )
 
 // FileSet is a parsed set of Go source files which are assumed to form a package.
@@ -23,7 +32,9 @@ type FileSet struct {
 	// FileSet holds the positions of each token in the set of parsed Go source files.
 	FileSet *token.FileSet
 
-	// AstFiles are the parsed versions of each source file.
+	// AstFiles are the parsed versions of each source file, in the same order
+	// as Files. Traversals such as Inspect and the Each functions visit the
+	// files in this order.
 	AstFiles []*ast.File
 
 	// TypeInfo holds result type information for the source files.
@@ -31,6 +42,44 @@ type FileSet struct {
 
 	// Package holds information about the package formed from the files in the FileSet.
 	Package *types.Package
+
+	// Errors holds the syntax and type errors found in the package when it
+	// was loaded with the WithLenient option. It is empty otherwise, since
+	// errors then cause loading to fail.
+	Errors ErrorList
+
+	// XTest holds the external test package for the directory, if the FileSet
+	// was loaded using the WithTests option and the directory contains one.
+	XTest *FileSet
+
+	opts     options
+	importer types.Importer
+
+	// importDir is the directory in which the go command is run to resolve
+	// imports. If empty, the FileSet's directory is used.
+	importDir string
+
+	// pkgPath is the path used to type check the package. If empty, the
+	// FileSet's directory is used.
+	pkgPath string
+
+	// base is the importer used to resolve imports, created on first use.
+	base types.Importer
+
+	// sources holds the source of the files being loaded, to show in
+	// errors. It is cleared once the package has been type checked.
+	sources sources
+
+	// contents holds the source of files that are loaded from memory rather
+	// than read from disk, such as stubs.
+	contents map[string][]byte
+
+	// fsys is the file system from which the files are read, if the FileSet
+	// was created by NewFileSetFromFS. Files are read from disk otherwise.
+	fsys fs.FS
+
+	// ssa is the SSA form of the package, built if requested with WithSSA.
+	ssa *ssa.Package
 }
 
 const currentDir = "."
@@ -40,21 +89,23 @@ const currentDir = "."
 // If a single name is provided that matches a directory then the fileset will
 // be initialised to contain the Go source files in that directory. If no
 // names are provided then the current working directory is assumed.
-func NewFileSet(names []string) (*FileSet, error) {
+// Options control how the files are loaded.
+func NewFileSet(names []string, opts ...Option) (*FileSet, error) {
 	// No names supplied so assume current directory
 	if len(names) == 0 {
-		return FileSetFromDir(currentDir)
+		return FileSetFromDir(currentDir, opts...)
 	}
 
 	// One name supplied could be a directory or a single file
 	// Find out which
-	if len(names) == 1 {
+	o := newOptions(opts)
+	if _, overlaid := o.overlaid(names[0]); len(names) == 1 && !overlaid {
 		info, err := os.Stat(names[0])
 		if err != nil {
 			return nil, err
 		}
 		if info.IsDir() {
-			return FileSetFromDir(names[0])
+			return FileSetFromDir(names[0], opts...)
 		}
 	}
 
@@ -62,32 +113,132 @@ func NewFileSet(names []string) (*FileSet, error) {
 	fs := &FileSet{
 		Dir:   filepath.Dir(names[0]),
 		Files: names,
+		opts:  o,
 	}
 
 	return fs.ParseFiles()
 }
 
+// NewFileSetContext is like NewFileSet but stops loading and returns the
+// context's error once ctx is done. It is equivalent to passing the
+// WithContext option.
+func NewFileSetContext(ctx context.Context, names []string, opts ...Option) (*FileSet, error) {
+	return NewFileSet(names, append(opts[:len(opts):len(opts)], WithContext(ctx))...)
+}
+
 // FileSetFromDir creates a FileSet consisting of the Go source files
-// in the directory d
-func FileSetFromDir(d string) (*FileSet, error) {
-	fs := FileSet{
-		Dir: d,
+// in the directory d. Options control which files are included.
+func FileSetFromDir(d string, opts ...Option) (*FileSet, error) {
+	fs := &FileSet{
+		Dir:  d,
+		opts: newOptions(opts),
 	}
-	pkg, err := build.Default.ImportDir(d, 0)
-	if err != nil {
+	return fs.loadDir()
+}
+
+// NewFileSetFromFS creates a FileSet consisting of the Go source files in the
+// directory dir of the file system fsys, such as an embed.FS, a zip archive
+// or a testing/fstest.MapFS. dir is a slash-separated path, as used by
+// io/fs, and the names in Files are paths in fsys. Options control which
+// files are included, except that WithOverlay does not apply.
+//
+// Imports are resolved as though the package were in the current directory,
+// so the package may import the standard library and packages available to
+// the module in the current directory, but not other packages in fsys.
+func NewFileSetFromFS(fsys fs.FS, dir string, opts ...Option) (*FileSet, error) {
+	fs := &FileSet{
+		Dir:  dir,
+		opts: newOptions(opts),
+		fsys: fsys,
+	}
+	return fs.loadDir()
+}
+
+// loadDir parses and type checks the Go source files in fs.Dir that are
+// selected by the FileSet's options.
+func (fs *FileSet) loadDir() (*FileSet, error) {
+	d := fs.Dir
+	ctxt := fs.opts.buildContext()
+	if fs.fsys != nil {
+		fsContext(ctxt, fs.fsys)
+	}
+	pkg, err := ctxt.ImportDir(d, 0)
+	var noGo *build.NoGoError
+	if err != nil && !(errors.As(err, &noGo) && len(fs.opts.stubs) > 0) {
 		return nil, err
 	}
 
-	fs.Files = append(fs.Files, pkg.GoFiles...)
-	if d == currentDir {
-		return fs.Parse()
+	names := pkg.GoFiles
+	if fs.opts.tests {
+		names = append(names, pkg.TestGoFiles...)
+	}
+	loaded := make(map[string]bool)
+	for _, name := range names {
+		if fs.opts.excluded(name) {
+			continue
+		}
+		loaded[name] = true
+		fs.Files = append(fs.Files, fs.join(name))
+	}
+	stubs := make([]string, 0, len(fs.opts.stubs))
+	for name := range fs.opts.stubs {
+		if !loaded[name] {
+			stubs = append(stubs, name)
+		}
+	}
+	sort.Strings(stubs)
+	for _, name := range stubs {
+		filename := fs.join(name)
+		if fs.contents == nil {
+			fs.contents = make(map[string][]byte)
+		}
+		fs.contents[filename] = fs.opts.stubs[name]
+		fs.Files = append(fs.Files, filename)
 	}
 
-	for i, f := range fs.Files {
-		fs.Files[i] = filepath.Join(d, f)
+	if _, err := fs.ParseFiles(); err != nil {
+		return nil, err
 	}
 
-	return fs.ParseFiles()
+	if fs.opts.tests && len(pkg.XTestGoFiles) > 0 {
+		if err := fs.loadXTest(pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
+
+// loadXTest loads the external test package of the package described by pkg.
+// Imports of the package under test are resolved to fs.Package so that the
+// external tests see the declarations in the package's own test files.
+func (fs *FileSet) loadXTest(pkg *build.Package) error {
+	xt := &FileSet{
+		Dir:       fs.Dir,
+		opts:      fs.opts,
+		importDir: fs.importDir,
+		fsys:      fs.fsys,
+	}
+	for _, f := range pkg.XTestGoFiles {
+		if !fs.opts.excluded(f) {
+			xt.Files = append(xt.Files, fs.join(f))
+		}
+	}
+
+	self := map[string]*types.Package{}
+	if path := dirImportPath(fs.Dir); path != "" {
+		self[path] = fs.Package
+	}
+	if pkg.ImportPath != "" && pkg.ImportPath != currentDir {
+		self[pkg.ImportPath] = fs.Package
+	}
+	xt.importer = &overrideImporter{base: fs.baseImporter(), pkgs: self}
+
+	if _, err := xt.ParseFiles(); err != nil {
+		return err
+	}
+	fs.XTest = xt
+	return nil
 }
 
 // FileSetFromDir creates a FileSet consisting of the Go source texts
@@ -96,38 +247,138 @@ func NewFileSetFromTexts(texts ...string) (*FileSet, error) {
 	fs := &FileSet{
 		Dir:     currentDir,
 		FileSet: token.NewFileSet(),
+		sources: make(sources),
 	}
 
+	var errs ErrorList
 	for i, text := range texts {
-		p, err := parser.ParseFile(fs.FileSet, fmt.Sprintf("%d.go", i), text, 0)
+		name := fmt.Sprintf("%d.go", i)
+		fs.sources[name] = []byte(text)
+		p, err := fs.parseFile(name, []byte(text), &errs)
 		if err != nil {
 			return nil, err
 		}
 		fs.AstFiles = append(fs.AstFiles, p)
 	}
+	if err := fs.syntaxErrors(errs); err != nil {
+		return nil, err
+	}
 
 	return fs.Parse()
 }
 
+// ParseFiles parses and type checks the files named by fs.Files. All the
+// syntax errors found in the files are reported together, as an ErrorList.
 func (fs *FileSet) ParseFiles() (*FileSet, error) {
+	start := time.Now()
+	log := fs.opts.logger()
 	fs.FileSet = token.NewFileSet()
+	fs.sources = make(sources)
+	var errs ErrorList
 	for _, f := range fs.Files {
-		p, err := parser.ParseFile(fs.FileSet, f, nil, 0)
+		if err := fs.opts.canceled(); err != nil {
+			return nil, err
+		}
+		src, ok := fs.contents[f]
+		if !ok && fs.fsys == nil {
+			src, ok = fs.opts.overlaid(f)
+		}
+		if !ok {
+			var err error
+			if src, err = fs.readFile(f); err != nil {
+				return nil, err
+			}
+		}
+		fs.sources[f] = src
+		p, err := fs.parseFile(f, src, &errs)
 		if err != nil {
 			return nil, err
 		}
 		fs.AstFiles = append(fs.AstFiles, p)
+		log.Debug("parsed file", "file", f, "bytes", len(src))
+	}
+	if err := fs.syntaxErrors(errs); err != nil {
+		return nil, err
 	}
 
-	return fs.Parse()
+	if _, err := fs.Parse(); err != nil {
+		return nil, err
+	}
+	fs.buildSSA()
+	log.Info("loaded package", "dir", fs.Dir, "package", fs.Package.Name(), "files", len(fs.Files), "types", countTypes(fs.Package), "duration", time.Since(start))
+	return fs, nil
+}
+
+// countTypes returns the number of named types declared at package level in
+// pkg.
+func countTypes(pkg *types.Package) int {
+	n := 0
+	for _, name := range pkg.Scope().Names() {
+		if _, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// syntaxErrors returns the syntax errors found while parsing the files of
+// the package, or records them in fs.Errors and returns nil when loading
+// leniently.
+func (fs *FileSet) syntaxErrors(errs ErrorList) error {
+	if fs.opts.lenient {
+		fs.Errors = append(fs.Errors, errs...)
+		return nil
+	}
+	return errs.err()
+}
+
+// parseFile parses the file filename with the given source, appending any
+// syntax errors to errs and returning as much of the file as could be
+// parsed. It returns an error only for other failures.
+func (fs *FileSet) parseFile(filename string, src []byte, errs *ErrorList) (*ast.File, error) {
+	p, err := parser.ParseFile(fs.FileSet, filename, src, parser.ParseComments)
+	if err != nil {
+		list, ok := syntaxErrors(err, PhaseParse, fs.sources).(ErrorList)
+		if !ok || p == nil {
+			return nil, err
+		}
+		*errs = append(*errs, list...)
+	}
+	return p, nil
 }
 
 // Parse verifies whether fs represents a valid, compilable set of Go
 // source files and sets the parsed versions of each file in the fileset.
+// All the type errors found are reported together, as an ErrorList, unless
+// the FileSet is being loaded with the WithLenient option, when they are
+// recorded in fs.Errors instead.
 func (fs *FileSet) Parse() (*FileSet, error) {
 	var err error
 
-	config := types.Config{Importer: importer.Default()}
+	if err := fs.opts.canceled(); err != nil {
+		return nil, err
+	}
+	imp := fs.importer
+	if imp == nil {
+		imp = fs.baseImporter()
+	}
+	if fs.opts.ctx != nil {
+		imp = &contextImporter{ctx: fs.opts.ctx, base: imp}
+	}
+	src := fs.sources
+	if src == nil {
+		src = make(sources)
+	}
+	fs.sources = nil
+	var errs ErrorList
+	config := types.Config{
+		Importer: imp,
+		Sizes:    types.SizesFor("gc", fs.opts.buildContext().GOARCH),
+		Error: func(err error) {
+			errs = append(errs, typeError(err, src))
+		},
+		FakeImportC: fs.opts.lenient,
+	}
 	fs.TypeInfo = &types.Info{
 		Types:      make(map[ast.Expr]types.TypeAndValue),
 		Defs:       make(map[*ast.Ident]types.Object),
@@ -135,16 +386,138 @@ func (fs *FileSet) Parse() (*FileSet, error) {
 		Implicits:  make(map[ast.Node]types.Object),
 		Selections: make(map[*ast.SelectorExpr]*types.Selection),
 		Scopes:     make(map[ast.Node]*types.Scope),
+		Instances:  make(map[*ast.Ident]types.Instance),
 	}
 
-	fs.Package, err = config.Check(fs.Dir, fs.FileSet, fs.AstFiles, fs.TypeInfo)
+	path := fs.Dir
+	if fs.pkgPath != "" {
+		path = fs.pkgPath
+	}
+	fs.Package, err = config.Check(path, fs.FileSet, fs.AstFiles, fs.TypeInfo)
+	if err := fs.opts.canceled(); err != nil {
+		return nil, err
+	}
+	errs.sort()
+	if fs.opts.lenient && fs.Package != nil {
+		fs.Errors = append(fs.Errors, errs...)
+		return fs, nil
+	}
 	if err != nil {
+		if len(errs) > 0 {
+			return nil, errs
+		}
 		return nil, err
 	}
 
 	return fs, nil
 }
 
+// ImportPath returns the import path of the package in fs, determined from the
+// go.mod file of the enclosing module. If the FileSet's directory is not within
+// a module the path used to type check the package is returned.
+func (fs *FileSet) ImportPath() string {
+	if path := dirImportPath(fs.Dir); path != "" {
+		return path
+	}
+	return fs.Package.Path()
+}
+
+// baseImporter returns the importer used to resolve the imports of the package,
+// chosen according to the FileSet's import mode. The importer is created on
+// first use and reused for later lookups.
+func (fs *FileSet) baseImporter() types.Importer {
+	if fs.base == nil {
+		fs.base = fs.newBaseImporter()
+	}
+	return fs.base
+}
+
+// overrideImporter resolves specific import paths to already loaded packages
+// and delegates all others to a base importer.
+type overrideImporter struct {
+	base types.Importer
+	pkgs map[string]*types.Package
+}
+
+func (imp *overrideImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := imp.pkgs[path]; ok {
+		return pkg, nil
+	}
+	return imp.base.Import(path)
+}
+
+// contextImporter delegates to a base importer until its context is done,
+// after which imports fail with the context's error.
+type contextImporter struct {
+	ctx  context.Context
+	base types.Importer
+}
+
+func (imp *contextImporter) Import(path string) (*types.Package, error) {
+	if err := imp.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return imp.base.Import(path)
+}
+
+func (imp *contextImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if err := imp.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if from, ok := imp.base.(types.ImporterFrom); ok {
+		return from.ImportFrom(path, dir, mode)
+	}
+	return imp.base.Import(path)
+}
+
+// dirImportPath returns the import path of the package in dir by locating the
+// enclosing module's go.mod file. It returns an empty string if dir is not
+// within a module.
+func dirImportPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	root := moduleDir(abs)
+	if root == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	mod := modfile.ModulePath(data)
+	if mod == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return ""
+	}
+	if rel == currentDir {
+		return mod
+	}
+	return path.Join(mod, filepath.ToSlash(rel))
+}
+
+// moduleDir returns the root directory of the module enclosing dir, which is
+// the nearest directory containing a go.mod file. It returns an empty string
+// if dir is not within a module.
+func moduleDir(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
+
 // Walk traverses all the files in fs invoking v.Visit on each file in turn.
 func (fs *FileSet) Walk(v ast.Visitor) {
 	for _, astFile := range fs.AstFiles {
@@ -155,13 +528,20 @@ func (fs *FileSet) Walk(v ast.Visitor) {
 // Inspect traverses all the files in fs calling f on each file in turn. If f
 // returns true Inspect invokes f recursively for each of the non-nil
 // children of the file's root node, followed by a call of f(nil).
+//
+// Inspect and the Each functions built on it are guaranteed to visit the
+// files in the order of AstFiles and the nodes of each file in depth-first
+// order, so that nodes are visited in order of their position. Generators may
+// rely on this order to produce deterministic output. ParallelInspect trades
+// this guarantee for speed.
 func (fs *FileSet) Inspect(f func(ast.Node) bool) {
 	for _, astFile := range fs.AstFiles {
 		ast.Inspect(astFile, f)
 	}
 }
 
-// EachType traverses all the files in fs calling f for each type found.
+// EachType traverses all the files in fs calling f for each type found, in
+// file and position order. The traversal will stop if f returns false.
 func (fs *FileSet) EachType(f func(*ast.TypeSpec) bool) {
 	done := false
 	fs.Inspect(func(node ast.Node) bool {
@@ -176,7 +556,9 @@ func (fs *FileSet) EachType(f func(*ast.TypeSpec) bool) {
 	})
 }
 
-// EachConst traverses all the files in fs calling f for each constant declaration found.
+// EachConst traverses all the files in fs calling f for each constant declaration found, in file
+// and position order. The traversal will stop if f returns false. Use ConstValues to obtain the
+// evaluated values of the constants declared by a specification.
 func (fs *FileSet) EachConst(f func(*ast.ValueSpec) bool) {
 	done := false
 	fs.Inspect(func(node ast.Node) bool {
@@ -198,8 +580,8 @@ func (fs *FileSet) EachConst(f func(*ast.ValueSpec) bool) {
 	})
 }
 
-// EachVar traverses all the files in fs calling f for each variable declaration found. The traversal
-// will stop if f returns false.
+// EachVar traverses all the files in fs calling f for each variable declaration found, in file and
+// position order. The traversal will stop if f returns false.
 func (fs *FileSet) EachVar(f func(*ast.ValueSpec) bool) {
 	done := false
 	fs.Inspect(func(node ast.Node) bool {
@@ -221,20 +603,68 @@ func (fs *FileSet) EachVar(f func(*ast.ValueSpec) bool) {
 	})
 }
 
-// EachFunc traverses all the files in fs calling f for each function declaration found. The traversal
-// will stop if f returns false.
+// EachFunc traverses all the files in fs calling f for each function declaration found, in file and
+// position order. The traversal will stop if f returns false.
 func (fs *FileSet) EachFunc(f func(*ast.FuncDecl) bool) {
-	done := false
-	fs.Inspect(func(node ast.Node) bool {
-		if done {
-			return false
+	// Function declarations only appear at package level so there is no need
+	// to inspect the bodies of declarations.
+	for _, file := range fs.AstFiles {
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && !f(fd) {
+				return
+			}
 		}
-		if decl, ok := node.(*ast.FuncDecl); ok {
-			if !f(decl) {
-				done = true
-				return false
+	}
+}
+
+// EachImport traverses all the files in fs calling f for each import
+// declaration found, in file and position order, with the imported path, the
+// name given to the package by the declaration, which is empty if the
+// package's own name is used, and the file containing the declaration. The
+// traversal will stop if f returns false.
+func (fs *FileSet) EachImport(f func(path, alias string, file *ast.File) bool) {
+	for _, file := range fs.AstFiles {
+		for _, spec := range file.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			alias := ""
+			if spec.Name != nil {
+				alias = spec.Name.Name
 			}
+			if !f(p, alias, file) {
+				return
+			}
+		}
+	}
+}
+
+// Imports returns the sorted, distinct paths of the packages imported by the
+// files of fs, including the pseudo packages unsafe and C.
+func (fs *FileSet) Imports() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	fs.EachImport(func(path, alias string, file *ast.File) bool {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
 		}
 		return true
 	})
+	sort.Strings(paths)
+	return paths
+}
+
+// HasImport reports whether any file of fs imports the package with the
+// given path. Generators can use it to avoid emitting an import of a package
+// that would be redundant or, for a package that itself imports fs, would
+// create an import cycle.
+func (fs *FileSet) HasImport(path string) bool {
+	found := false
+	fs.EachImport(func(p, alias string, file *ast.File) bool {
+		found = p == path
+		return !found
+	})
+	return found
 }
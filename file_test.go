@@ -1,7 +1,11 @@
 package gen
 
 import (
+	"context"
+	"errors"
 	"go/ast"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
@@ -199,3 +203,113 @@ func TestEachFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestEachImport(t *testing.T) {
+	fs, err := NewFileSetFromTexts(
+		"package p\n\nimport (\n\t\"fmt\"\n\tstr \"strings\"\n)\n\nvar _ = fmt.Sprint(str.ToUpper(\"\"))\n",
+		"package p\n\nimport (\n\t_ \"embed\"\n\t\"fmt\"\n)\n\nvar _ = fmt.Sprint()\n",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	fs.EachImport(func(path, alias string, file *ast.File) bool {
+		got = append(got, fs.FileSet.File(file.Pos()).Name()+":"+alias+":"+path)
+		return true
+	})
+	want := []string{"0.go::fmt", "0.go:str:strings", "1.go:_:embed", "1.go::fmt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if got, want := fs.Imports(), []string{"embed", "fmt", "strings"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+	if !fs.HasImport("strings") || fs.HasImport("os") {
+		t.Errorf("got HasImport strings %v, os %v, wanted true, false", fs.HasImport("strings"), fs.HasImport("os"))
+	}
+}
+
+// writeTestModule writes files to a temporary directory, adding a go.mod
+// file if none is supplied, and returns the name of the directory.
+func writeTestModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module example.com/p\n\ngo 1.21\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestWithTests(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go":             "package p\n\nfunc X() int { return 1 }\n",
+		"p_test.go":        "package p\n\nfunc helper() int { return X() }\n",
+		"export_test.go":   "package p\n\nvar Helper = helper\n",
+		"p_ext_test.go":    "package p_test\n\nimport \"example.com/p\"\n\nvar _ = p.Helper() + p.X()\n",
+		"other/ignored.go": "package other\n",
+	})
+
+	fs, err := FileSetFromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fs.AstFiles) != 1 {
+		t.Errorf("got %d files without tests, wanted 1", len(fs.AstFiles))
+	}
+	if fs.XTest != nil {
+		t.Errorf("got external test package without tests")
+	}
+
+	fs, err = FileSetFromDir(dir, WithTests(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fs.AstFiles) != 3 {
+		t.Errorf("got %d files with tests, wanted 3", len(fs.AstFiles))
+	}
+	if fs.Package.Scope().Lookup("helper") == nil {
+		t.Errorf("test helper not found in package scope")
+	}
+
+	if fs.XTest == nil {
+		t.Fatalf("external test package not loaded")
+	}
+	if got, want := fs.XTest.Package.Name(), "p_test"; got != want {
+		t.Errorf("got package name %q, wanted %q", got, want)
+	}
+}
+
+func TestNewFileSetContext(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"a.go": "package p\n\nimport \"strings\"\n\nvar A = strings.ToUpper(\"a\")\n",
+		"b.go": "package p\n\nvar B = A\n",
+	})
+
+	fs, err := NewFileSetContext(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fs.Files) != 2 {
+		t.Errorf("got %d files, wanted 2", len(fs.Files))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewFileSetContext(ctx, []string{dir}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+	if _, err := NewFileSet([]string{filepath.Join(dir, "a.go")}, WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+}
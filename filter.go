@@ -0,0 +1,155 @@
+package gen
+
+import (
+	"go/ast"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A NameFilter selects declarations by name for the Matching variants of the
+// traversal functions, such as EachTypeMatching, so that generators driven by
+// naming conventions need not filter inside their callbacks. Methods are
+// named by their receiver's base type name and method name separated by a
+// dot, such as "T.String".
+type NameFilter func(name string) bool
+
+// MatchGlob selects names that match the shell pattern, using the syntax of
+// path.Match. For example, "*Request" selects names ending in Request. A
+// malformed pattern matches nothing.
+func MatchGlob(pattern string) NameFilter {
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+}
+
+// MatchRegexp selects names that contain a match of re. Anchor the
+// expression to match whole names.
+func MatchRegexp(re *regexp.Regexp) NameFilter {
+	return re.MatchString
+}
+
+// ExportedOnly selects exported names. For a method it considers the name of
+// the method rather than its receiver.
+func ExportedOnly() NameFilter {
+	return func(name string) bool {
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		return ast.IsExported(name)
+	}
+}
+
+// Exclude selects names other than those given.
+func Exclude(names ...string) NameFilter {
+	excluded := make(map[string]bool, len(names))
+	for _, name := range names {
+		excluded[name] = true
+	}
+	return func(name string) bool {
+		return !excluded[name]
+	}
+}
+
+// matchAll reports whether name is selected by all of the filters.
+func matchAll(name string, filters []NameFilter) bool {
+	for _, filter := range filters {
+		if !filter(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// EachTypeMatching is like EachType but calls f only for the types whose
+// names are selected by all of the filters.
+func (fs *FileSet) EachTypeMatching(f func(*ast.TypeSpec) bool, filters ...NameFilter) {
+	fs.EachType(func(ts *ast.TypeSpec) bool {
+		if !matchAll(ts.Name.Name, filters) {
+			return true
+		}
+		return f(ts)
+	})
+}
+
+// EachFuncMatching is like EachFunc but calls f only for the functions and
+// methods whose names are selected by all of the filters.
+func (fs *FileSet) EachFuncMatching(f func(*ast.FuncDecl) bool, filters ...NameFilter) {
+	fs.EachFunc(func(decl *ast.FuncDecl) bool {
+		if !matchAll(funcDeclName(decl), filters) {
+			return true
+		}
+		return f(decl)
+	})
+}
+
+// EachConstMatching is like EachConst but calls f only for the constant
+// declarations that declare at least one name selected by all of the
+// filters.
+func (fs *FileSet) EachConstMatching(f func(*ast.ValueSpec) bool, filters ...NameFilter) {
+	fs.EachConst(func(vs *ast.ValueSpec) bool {
+		if !matchAny(vs.Names, filters) {
+			return true
+		}
+		return f(vs)
+	})
+}
+
+// EachVarMatching is like EachVar but calls f only for the variable
+// declarations that declare at least one name selected by all of the
+// filters.
+func (fs *FileSet) EachVarMatching(f func(*ast.ValueSpec) bool, filters ...NameFilter) {
+	fs.EachVar(func(vs *ast.ValueSpec) bool {
+		if !matchAny(vs.Names, filters) {
+			return true
+		}
+		return f(vs)
+	})
+}
+
+// TypesMatching returns models of the package level types whose names are
+// selected by all of the filters, in the order they are declared.
+func (fs *FileSet) TypesMatching(filters ...NameFilter) []*TypeModel {
+	models := []*TypeModel{}
+	for _, m := range fs.Types() {
+		if matchAll(m.Name, filters) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// FuncsMatching returns models of the functions and methods whose names are
+// selected by all of the filters, in the order they are declared.
+func (fs *FileSet) FuncsMatching(filters ...NameFilter) []*FuncModel {
+	models := []*FuncModel{}
+	for _, m := range fs.Funcs() {
+		if matchAll(m.FullName(), filters) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// matchAny reports whether any of ids has a name selected by all of the
+// filters.
+func matchAny(ids []*ast.Ident, filters []NameFilter) bool {
+	for _, id := range ids {
+		if matchAll(id.Name, filters) {
+			return true
+		}
+	}
+	return false
+}
+
+// funcDeclName returns the name of a function, qualified by its receiver's
+// base type name if it is a method.
+func funcDeclName(decl *ast.FuncDecl) string {
+	if decl.Recv != nil && len(decl.Recv.List) == 1 {
+		if id := embeddedIdent(decl.Recv.List[0].Type); id != nil {
+			return id.Name + "." + decl.Name.Name
+		}
+	}
+	return decl.Name.Name
+}
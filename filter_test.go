@@ -0,0 +1,100 @@
+package gen
+
+import (
+	"go/ast"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestEachMatching(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type GetRequest struct{}
+		type PutRequest struct{}
+		type internalRequest struct{}
+		type Response struct{}
+
+		func (GetRequest) Validate() error { return nil }
+		func (PutRequest) validate() error { return nil }
+		func NewGetRequest() GetRequest { return GetRequest{} }
+
+		const (
+			MaxRequests, minRequests = 10, 1
+			timeout                  = 5
+		)
+
+		var DefaultRequest, other = GetRequest{}, 1
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	typeNames := func(filters ...NameFilter) []string {
+		names := []string{}
+		fs.EachTypeMatching(func(ts *ast.TypeSpec) bool {
+			names = append(names, ts.Name.Name)
+			return true
+		}, filters...)
+		return names
+	}
+	funcNames := func(filters ...NameFilter) []string {
+		names := []string{}
+		fs.EachFuncMatching(func(decl *ast.FuncDecl) bool {
+			names = append(names, funcDeclName(decl))
+			return true
+		}, filters...)
+		return names
+	}
+	valueNames := func(each func(func(*ast.ValueSpec) bool, ...NameFilter), filters ...NameFilter) []string {
+		names := []string{}
+		each(func(vs *ast.ValueSpec) bool {
+			names = append(names, vs.Names[0].Name)
+			return true
+		}, filters...)
+		return names
+	}
+
+	testCases := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{name: "all types", got: typeNames(), want: []string{"GetRequest", "PutRequest", "internalRequest", "Response"}},
+		{name: "glob", got: typeNames(MatchGlob("*Request")), want: []string{"GetRequest", "PutRequest", "internalRequest"}},
+		{name: "glob exported", got: typeNames(MatchGlob("*Request"), ExportedOnly()), want: []string{"GetRequest", "PutRequest"}},
+		{name: "glob excluded", got: typeNames(MatchGlob("*Request"), Exclude("PutRequest", "Missing")), want: []string{"GetRequest", "internalRequest"}},
+		{name: "regexp", got: typeNames(MatchRegexp(regexp.MustCompile(`^(Get|Put)`))), want: []string{"GetRequest", "PutRequest"}},
+		{name: "bad glob", got: typeNames(MatchGlob("[")), want: []string{}},
+		{name: "exported methods", got: funcNames(ExportedOnly()), want: []string{"GetRequest.Validate", "NewGetRequest"}},
+		{name: "methods of type", got: funcNames(MatchGlob("PutRequest.*")), want: []string{"PutRequest.validate"}},
+		{name: "consts", got: valueNames(fs.EachConstMatching, MatchGlob("*Requests")), want: []string{"MaxRequests"}},
+		{name: "exported consts", got: valueNames(fs.EachConstMatching, ExportedOnly()), want: []string{"MaxRequests"}},
+		{name: "vars", got: valueNames(fs.EachVarMatching, Exclude("DefaultRequest")), want: []string{"DefaultRequest"}},
+		{name: "no vars", got: valueNames(fs.EachVarMatching, Exclude("DefaultRequest", "other")), want: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", tc.got, tc.want)
+			}
+		})
+	}
+
+	models := []string{}
+	for _, m := range fs.TypesMatching(MatchGlob("*Request"), ExportedOnly()) {
+		models = append(models, m.Name)
+	}
+	if want := []string{"GetRequest", "PutRequest"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("got types %+v, wanted %+v", models, want)
+	}
+
+	models = []string{}
+	for _, m := range fs.FuncsMatching(MatchGlob("*.*")) {
+		models = append(models, m.FullName())
+	}
+	if want := []string{"GetRequest.Validate", "PutRequest.validate"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("got funcs %+v, wanted %+v", models, want)
+	}
+}
@@ -0,0 +1,82 @@
+package gen
+
+import (
+	"go/build"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fsContext sets the file system hooks of ctxt so that directories are
+// listed and files read from fsys, with paths given as slash-separated
+// paths in fsys.
+func fsContext(ctxt *build.Context, fsys fs.FS) {
+	ctxt.JoinPath = path.Join
+	ctxt.SplitPathList = func(list string) []string {
+		return nil
+	}
+	ctxt.IsAbsPath = func(p string) bool {
+		return false
+	}
+	ctxt.HasSubdir = func(root, dir string) (string, bool) {
+		return "", false
+	}
+	ctxt.IsDir = func(p string) bool {
+		info, err := fs.Stat(fsys, fsPath(p))
+		return err == nil && info.IsDir()
+	}
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		entries, err := fs.ReadDir(fsys, fsPath(dir))
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]fs.FileInfo, 0, len(entries))
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	}
+	ctxt.OpenFile = func(p string) (io.ReadCloser, error) {
+		return fsys.Open(fsPath(p))
+	}
+}
+
+// fsPath returns the path p cleaned for use with an fs.FS, in which the
+// root is named ".".
+func fsPath(p string) string {
+	p = strings.TrimPrefix(path.Clean(p), "/")
+	if p == "" {
+		return currentDir
+	}
+	return p
+}
+
+// join returns the name of the file with the given base name in the
+// directory of the FileSet.
+func (fs *FileSet) join(name string) string {
+	if fs.fsys != nil {
+		return path.Join(fs.Dir, name)
+	}
+	return filepath.Join(fs.Dir, name)
+}
+
+// readFile returns the contents of the file filename, read from the
+// FileSet's file system or from disk.
+func (fs *FileSet) readFile(filename string) ([]byte, error) {
+	if fs.fsys == nil {
+		return os.ReadFile(filename)
+	}
+	return readFS(fs.fsys, filename)
+}
+
+// readFS returns the contents of the file with the given path in fsys.
+func readFS(fsys fs.FS, name string) ([]byte, error) {
+	return fs.ReadFile(fsys, fsPath(name))
+}
@@ -0,0 +1,41 @@
+package gen
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewFileSetFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"p/p.go":       {Data: []byte("package p\n\nimport \"strings\"\n\nfunc Upper(s string) string { return strings.ToUpper(s) }\n")},
+		"p/ignored.go": {Data: []byte("//go:build ignore\n\npackage p\n\nconst Ignored = 1\n")},
+		"p/p_test.go":  {Data: []byte("package p\n\nconst Helper = 1\n")},
+		"p/x_test.go":  {Data: []byte("package p_test\n")},
+		"other/o.go":   {Data: []byte("package other\n")},
+	}
+	fs, err := NewFileSetFromFS(fsys, "p")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := fs.Files, []string{"p/p.go"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got files %q, wanted %q", got, want)
+	}
+	if fs.Lookup("Upper") == nil {
+		t.Errorf("got no Upper")
+	}
+	if pos := fs.FileSet.Position(fs.Lookup("Upper").Pos()); pos.Filename != "p/p.go" || pos.Line != 5 {
+		t.Errorf("got position %v, wanted p/p.go:5", pos)
+	}
+
+	fs, err = NewFileSetFromFS(fsys, "p", WithTests(true), WithStub("p_gen.go", []byte("package p\n\nconst Gen = 1\n")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.Lookup("Helper") == nil || fs.Lookup("Gen") == nil || fs.XTest == nil {
+		t.Errorf("got files %q, wanted test files and stub", fs.Files)
+	}
+
+	if _, err := NewFileSetFromFS(fsys, "missing"); err == nil {
+		t.Errorf("got no error for a missing directory")
+	}
+}
@@ -0,0 +1,202 @@
+// Package funcopts generates the functional options pattern for a struct
+// type that holds configuration.
+//
+// For a struct type Config the generated code is an Option type, a function
+// returning an Option for each field and a constructor applying them:
+//
+//	type Option func(*Config)
+//
+//	func WithAddr(addr string) Option
+//
+//	func NewConfig(opts ...Option) *Config
+//
+// The option functions are named by the fields, so a field named addr or
+// Addr gives WithAddr, and are documented by the fields' doc or line
+// comments. A slice field is set from a variadic parameter. The constructor
+// of an unexported type is unexported.
+//
+// Fields are controlled by their struct tags and by directives in their doc
+// or line comments:
+//
+//	Timeout time.Duration `default:"5 * time.Second"`
+//	cache   *Cache //funcopts:skip
+//
+// The default tag holds a Go expression, evaluated in the scope of the file
+// declaring the struct, that the constructor assigns to the field before
+// applying options. A skipped field has no option.
+package funcopts
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/iand/gen"
+)
+
+// Prefix is the prefix of the directives that control fields.
+const Prefix = "funcopts:"
+
+// Generate writes the Option type, option functions and constructor of the
+// named struct type to o. It returns an error if the type is not a struct or
+// a default tag does not hold a valid expression.
+func Generate(fs *gen.FileSet, o *gen.Output, typeName string) error {
+	tn, ok := fs.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("type %s not found", typeName)
+	}
+	n, ok := tn.Type().(*types.Named)
+	if !ok || tn.IsAlias() {
+		return fmt.Errorf("%s is not a named type", typeName)
+	}
+	if n.TypeParams().Len() > 0 {
+		return fmt.Errorf("cannot generate options for generic type %s", typeName)
+	}
+	if _, ok := n.Underlying().(*types.Struct); !ok {
+		return fmt.Errorf("%s is not a struct type", typeName)
+	}
+	fields, err := fs.FieldsOf(typeName)
+	if err != nil {
+		return err
+	}
+
+	imports := gen.NewImports()
+	qual := imports.Qualifier(fs.Package)
+	recv := gen.ReceiverName(typeName)
+
+	var body, defaults bytes.Buffer
+	fmt.Fprintf(&body, "\n// Option configures a %s.\n", typeName)
+	fmt.Fprintf(&body, "type Option func(*%s)\n", typeName)
+	for _, f := range fields {
+		if f.Name == "_" || skipped(f) {
+			continue
+		}
+		if tag, ok := f.Tags.Get("default"); ok {
+			expr, err := defaultExpr(fs, imports, f, tag.Value)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", typeName, f.Name, err)
+			}
+			fmt.Fprintf(&defaults, "\t\t%s: %s,\n", f.Name, expr)
+		}
+
+		param := paramName(f.Name, recv)
+		typ := types.TypeString(f.Type, qual)
+		if s, ok := f.Type.(*types.Slice); ok {
+			typ = "..." + types.TypeString(s.Elem(), qual)
+		}
+		name := "With" + gen.Export(f.Name)
+		fmt.Fprintf(&body, "\n// %s returns an Option that sets %s.\n", name, f.Name)
+		doc := f.Doc
+		if doc == "" {
+			doc = f.Comment
+		}
+		if doc = strings.TrimSpace(doc); doc != "" {
+			body.WriteString("//\n")
+			for _, line := range strings.Split(doc, "\n") {
+				body.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+			}
+		}
+		fmt.Fprintf(&body, "func %s(%s %s) Option {\n", name, param, typ)
+		fmt.Fprintf(&body, "\treturn func(%s *%s) {\n\t\t%s.%s = %s\n\t}\n}\n", recv, typeName, recv, f.Name, param)
+	}
+
+	constructor := "New" + gen.Export(typeName)
+	if !token.IsExported(typeName) {
+		constructor = "new" + gen.Export(typeName)
+	}
+	fmt.Fprintf(&body, "\n// %s returns a %s configured by opts.\n", constructor, typeName)
+	fmt.Fprintf(&body, "func %s(opts ...Option) *%s {\n", constructor, typeName)
+	if defaults.Len() > 0 {
+		fmt.Fprintf(&body, "\t%s := &%s{\n", recv, typeName)
+		body.Write(defaults.Bytes())
+		body.WriteString("\t}\n")
+	} else {
+		fmt.Fprintf(&body, "\t%s := &%s{}\n", recv, typeName)
+	}
+	fmt.Fprintf(&body, "\tfor _, opt := range opts {\n\t\topt(%s)\n\t}\n\treturn %s\n}\n", recv, recv)
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	if imports.Len() > 0 {
+		o.Printf("%s\n", imports.Block())
+	}
+	o.Write(body.Bytes())
+	return nil
+}
+
+// skipped reports whether the field has a skip directive.
+func skipped(f *gen.FieldModel) bool {
+	if f.Field == nil {
+		return false
+	}
+	for _, cg := range []*ast.CommentGroup{f.Field.Doc, f.Field.Comment} {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			if d, ok := gen.ParseDirective(Prefix, c.Text); ok && d.Name == "skip" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// paramName returns the name of the parameter of the option function for the
+// named field, avoiding keywords, predeclared names and the name of the
+// parameter of the returned function.
+func paramName(field, recv string) string {
+	for _, name := range []string{gen.CamelCase(field), "v", "value"} {
+		if name != "" && !token.IsKeyword(name) && name != recv && types.Universe.Lookup(name) == nil {
+			return name
+		}
+	}
+	return "value_"
+}
+
+// defaultExpr type checks the default expression src of field f in the scope
+// of the file declaring it, adds the packages it refers to to imports and
+// returns the expression as written in the generated file.
+func defaultExpr(fs *gen.FileSet, imports *gen.Imports, f *gen.FieldModel, src string) (string, error) {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return "", fmt.Errorf("invalid default %q: %w", src, err)
+	}
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	if err := types.CheckExpr(fs.FileSet, fs.Package, f.Object.Pos(), expr, info); err != nil {
+		return "", fmt.Errorf("invalid default %q: %w", src, err)
+	}
+
+	// Rename the packages the expression refers to as they are imported by
+	// the generated file.
+	renamed := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if pn, ok := info.Uses[id].(*types.PkgName); ok {
+			alias := ""
+			if pn.Name() != pn.Imported().Name() {
+				alias = pn.Name()
+			}
+			if name := imports.Add(pn.Imported().Path(), alias); name != id.Name {
+				id.Name = name
+				renamed = true
+			}
+		}
+		return true
+	})
+	if !renamed {
+		return src, nil
+	}
+	var b bytes.Buffer
+	if err := format.Node(&b, token.NewFileSet(), expr); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,177 @@
+package funcopts
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"net/http"
+	tm "time"
+)
+
+const defaultAddr = ":8080"
+
+// Server holds the configuration of a server.
+type Server struct {
+	// Addr is the address to listen on.
+	Addr string ` + "`default:\"defaultAddr\"`" + `
+
+	// Timeout bounds the time taken
+	// to serve a request.
+	Timeout tm.Duration ` + "`default:\"5 * tm.Second\"`" + `
+
+	Handler http.Handler // Handler serves requests.
+
+	Hosts []string
+
+	Type string
+
+	client *http.Client //funcopts:skip
+}
+
+type options struct {
+	verbose bool
+}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("funcopts")
+	if err := Generate(fs, o, "Server"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"type Option func(*Server)",
+		"// WithAddr returns an Option that sets Addr.\n//\n// Addr is the address to listen on.\nfunc WithAddr(addr string) Option {",
+		"// Timeout bounds the time taken\n// to serve a request.\nfunc WithTimeout(timeout tm.Duration) Option {",
+		"// Handler serves requests.\nfunc WithHandler(handler http.Handler) Option {",
+		"func WithHosts(hosts ...string) Option {",
+		"func WithType(v string) Option {",
+		"\t\ts.Hosts = hosts\n",
+		"func NewServer(opts ...Option) *Server {",
+		"Addr:    defaultAddr,",
+		"Timeout: 5 * tm.Second,",
+		`tm "time"`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "WithClient") {
+		t.Errorf("skipped field has an option:\n%s", src)
+	}
+
+	o = gen.NewOutput("funcopts")
+	if err := Generate(fs, o, "options"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err = o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"func WithVerbose(verbose bool) Option {", "func newOptions(opts ...Option) *options {"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+	}{
+		{name: "missing", src: "package p\n"},
+		{name: "not struct", src: "package p\ntype T int\n"},
+		{name: "generic", src: "package p\ntype T[E any] struct{ V E }\n"},
+		{name: "syntax", src: "package p\ntype T struct{ V int `default:\"1 +\"` }\n"},
+		{name: "undefined", src: "package p\ntype T struct{ V int `default:\"missing\"` }\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := gen.NewFileSetFromTexts(tc.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := Generate(fs, gen.NewOutput("funcopts"), "T"); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+const testProgram = `package p
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	s := NewServer()
+	if s.Addr != ":8080" || s.Timeout != 5*time.Second {
+		t.Errorf("got %+v, wanted defaults", s)
+	}
+	s = NewServer(WithAddr(":9090"), WithHosts("a", "b"), WithType("x"))
+	if s.Addr != ":9090" || len(s.Hosts) != 2 || s.Type != "x" || s.Timeout != 5*time.Second {
+		t.Errorf("got %+v, wanted options applied", s)
+	}
+}
+`
+
+func TestGeneratedOptions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.21\n",
+		"p.go":      testSrc,
+		"p_test.go": testProgram,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "p.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("funcopts")
+	if err := Generate(fs, o, "Server"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "options_gen.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated options failed: %v\n%s\n%s", err, out, src)
+	}
+}
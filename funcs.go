@@ -0,0 +1,279 @@
+package gen
+
+import (
+	"fmt"
+	"go/constant"
+	"go/types"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// FuncMap returns functions commonly needed by templates that generate Go
+// source code, suitable for passing to NewTemplateType. The functions are:
+//
+//	export     makes an identifier exported: userID becomes UserID
+//	unexport   makes an identifier unexported: HTTPServer becomes httpServer
+//	camelCase  joins the words of a name in camel case: user_id becomes userID
+//	pascalCase joins the words of a name in Pascal case: user_id becomes UserID
+//	snakeCase  joins the words of a name in snake case: HTTPServer becomes http_server
+//	plural     returns the plural of a name: Entry becomes Entries
+//	singular   returns the singular of a name: Entries becomes Entry
+//	receiver   derives a receiver name from a type: *pkg.Client becomes c
+//	zero       returns the zero value of a types.Type: for a struct T it is T{}
+//	literal    returns an example composite literal of a types.Type: T{A: 0}
+//	quote      writes a value as a Go literal: a string s becomes "s"
+//
+// Words are split at underscores, hyphens, spaces and changes of case, and
+// common initialisms such as ID, URL and HTTP are kept in upper case when not
+// at the start of a camel case name. The zero and literal functions qualify
+// types from other packages by their package name.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"export":     Export,
+		"unexport":   Unexport,
+		"camelCase":  CamelCase,
+		"pascalCase": PascalCase,
+		"snakeCase":  SnakeCase,
+		"plural":     Plural,
+		"singular":   Singular,
+		"receiver":   ReceiverName,
+		"zero": func(t types.Type) string {
+			return ZeroValue(t, func(p *types.Package) string { return p.Name() })
+		},
+		"literal": func(t types.Type) string {
+			return CompositeLiteral(t, func(p *types.Package) string { return p.Name() })
+		},
+		"quote": Literal,
+	}
+}
+
+// commonInitialisms lists the words that Go style writes in a single case.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "URI": true,
+	"URL": true, "UTF8": true, "UUID": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// Words splits a name into words at underscores, hyphens, spaces and changes
+// of case. A run of upper case letters is a single word, so HTTPServer splits
+// into HTTP and Server. Digits belong to the preceding word.
+func Words(name string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = cur[:0]
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(cur) > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+// Export returns name with its first letter in upper case, making it an
+// exported identifier.
+func Export(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// Unexport returns name with its leading word in lower case, making it an
+// unexported identifier. A leading initialism is lowered entirely, so
+// HTTPServer becomes httpServer and ID becomes id.
+func Unexport(name string) string {
+	words := Words(name)
+	if len(words) == 0 || strings.ContainsAny(name, "_- ") {
+		if name == "" {
+			return name
+		}
+		r := []rune(name)
+		r[0] = unicode.ToLower(r[0])
+		return string(r)
+	}
+	return strings.ToLower(words[0]) + name[len(words[0]):]
+}
+
+// CamelCase joins the words of name in camel case, such as userID.
+func CamelCase(name string) string {
+	words := Words(name)
+	if len(words) == 0 {
+		return ""
+	}
+	return strings.ToLower(words[0]) + joinTitle(words[1:])
+}
+
+// PascalCase joins the words of name in Pascal case, such as UserID.
+func PascalCase(name string) string {
+	return joinTitle(Words(name))
+}
+
+// SnakeCase joins the words of name in lower case separated by underscores,
+// such as user_id.
+func SnakeCase(name string) string {
+	words := Words(name)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, "_")
+}
+
+// joinTitle joins words with the first letter of each in upper case, writing
+// common initialisms entirely in upper case.
+func joinTitle(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(strings.ToLower(w))
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+// irregularPlurals maps irregular singular nouns to their plurals.
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"datum":  "data",
+	"index":  "indices",
+	"man":    "men",
+	"matrix": "matrices",
+	"person": "people",
+	"schema": "schemas",
+	"status": "statuses",
+	"woman":  "women",
+}
+
+// Plural returns the English plural of the last word of name, preserving the
+// case of its first letter, such as Entries for Entry.
+func Plural(name string) string {
+	prefix, word := splitLastWord(name)
+	if word == "" {
+		return name
+	}
+	lower := strings.ToLower(word)
+	if p, ok := irregularPlurals[lower]; ok {
+		return prefix + matchCase(word, p)
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return prefix + word[:len(word)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return prefix + word + "es"
+	}
+	return prefix + word + "s"
+}
+
+// Singular returns the English singular of the last word of name, preserving
+// the case of its first letter, such as Entry for Entries.
+func Singular(name string) string {
+	prefix, word := splitLastWord(name)
+	if word == "" {
+		return name
+	}
+	lower := strings.ToLower(word)
+	for s, p := range irregularPlurals {
+		if lower == p {
+			return prefix + matchCase(word, s)
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		return prefix + word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return prefix + word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"):
+		return name
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		return prefix + word[:len(word)-1]
+	}
+	return name
+}
+
+// splitLastWord splits name into everything before its last word and the
+// last word.
+func splitLastWord(name string) (string, string) {
+	words := Words(name)
+	if len(words) == 0 {
+		return name, ""
+	}
+	last := words[len(words)-1]
+	i := strings.LastIndex(name, last)
+	return name[:i], last
+}
+
+// matchCase returns replacement with the case of the first letter of word, or
+// entirely in upper case if word is.
+func matchCase(word, replacement string) string {
+	if word == strings.ToUpper(word) {
+		return strings.ToUpper(replacement)
+	}
+	if unicode.IsUpper([]rune(word)[0]) {
+		return Export(replacement)
+	}
+	return replacement
+}
+
+// ReceiverName derives a conventional receiver name from a type expression:
+// the lower case first letter of the type's name. Pointers, package
+// qualifiers and type arguments are ignored, so *pkg.Client[T] gives c.
+func ReceiverName(typ string) string {
+	typ = strings.TrimLeft(typ, "*")
+	if i := strings.IndexByte(typ, '['); i >= 0 {
+		typ = typ[:i]
+	}
+	if i := strings.LastIndexByte(typ, '.'); i >= 0 {
+		typ = typ[i+1:]
+	}
+	for _, r := range typ {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToLower(r))
+		}
+	}
+	return "x"
+}
+
+// Literal returns v written as a Go literal. Strings are quoted, constant
+// values are written exactly and other values are formatted with the %#v
+// verb of the fmt package.
+func Literal(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case constant.Value:
+		return constLiteral(v)
+	}
+	return fmt.Sprintf("%#v", v)
+}
@@ -0,0 +1,165 @@
+package gen
+
+import (
+	"bytes"
+	"go/constant"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestWords(t *testing.T) {
+	testCases := []struct {
+		name string
+		want []string
+	}{
+		{name: "user", want: []string{"user"}},
+		{name: "userID", want: []string{"user", "ID"}},
+		{name: "HTTPServer", want: []string{"HTTP", "Server"}},
+		{name: "UTF8Reader", want: []string{"UTF8", "Reader"}},
+		{name: "user_id", want: []string{"user", "id"}},
+		{name: "first-name last", want: []string{"first", "name", "last"}},
+		{name: "v2Api", want: []string{"v2", "Api"}},
+		{name: "", want: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Words(tc.name); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCaseFuncs(t *testing.T) {
+	testCases := []struct {
+		name                                   string
+		export, unexport, camel, pascal, snake string
+	}{
+		{name: "user", export: "User", unexport: "user", camel: "user", pascal: "User", snake: "user"},
+		{name: "userID", export: "UserID", unexport: "userID", camel: "userID", pascal: "UserID", snake: "user_id"},
+		{name: "HTTPServer", export: "HTTPServer", unexport: "httpServer", camel: "httpServer", pascal: "HTTPServer", snake: "http_server"},
+		{name: "ID", export: "ID", unexport: "id", camel: "id", pascal: "ID", snake: "id"},
+		{name: "user_url", export: "User_url", unexport: "user_url", camel: "userURL", pascal: "UserURL", snake: "user_url"},
+		{name: "created-at", export: "Created-at", unexport: "created-at", camel: "createdAt", pascal: "CreatedAt", snake: "created_at"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Export(tc.name); got != tc.export {
+				t.Errorf("Export: got %q, wanted %q", got, tc.export)
+			}
+			if got := Unexport(tc.name); got != tc.unexport {
+				t.Errorf("Unexport: got %q, wanted %q", got, tc.unexport)
+			}
+			if got := CamelCase(tc.name); got != tc.camel {
+				t.Errorf("CamelCase: got %q, wanted %q", got, tc.camel)
+			}
+			if got := PascalCase(tc.name); got != tc.pascal {
+				t.Errorf("PascalCase: got %q, wanted %q", got, tc.pascal)
+			}
+			if got := SnakeCase(tc.name); got != tc.snake {
+				t.Errorf("SnakeCase: got %q, wanted %q", got, tc.snake)
+			}
+		})
+	}
+}
+
+func TestPlural(t *testing.T) {
+	testCases := []struct {
+		singular, plural string
+	}{
+		{singular: "User", plural: "Users"},
+		{singular: "Entry", plural: "Entries"},
+		{singular: "Key", plural: "Keys"},
+		{singular: "Box", plural: "Boxes"},
+		{singular: "Address", plural: "Addresses"},
+		{singular: "Match", plural: "Matches"},
+		{singular: "Person", plural: "People"},
+		{singular: "Status", plural: "Statuses"},
+		{singular: "URL", plural: "URLs"},
+		{singular: "userEntry", plural: "userEntries"},
+		{singular: "child", plural: "children"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.singular, func(t *testing.T) {
+			if got := Plural(tc.singular); got != tc.plural {
+				t.Errorf("Plural: got %q, wanted %q", got, tc.plural)
+			}
+			if got := Singular(tc.plural); got != tc.singular {
+				t.Errorf("Singular: got %q, wanted %q", got, tc.singular)
+			}
+		})
+	}
+}
+
+func TestReceiverName(t *testing.T) {
+	testCases := []struct {
+		typ  string
+		want string
+	}{
+		{typ: "Client", want: "c"},
+		{typ: "*Client", want: "c"},
+		{typ: "*pkg.Client[T]", want: "c"},
+		{typ: "HTTPServer", want: "h"},
+		{typ: "", want: "x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.typ, func(t *testing.T) {
+			if got := ReceiverName(tc.typ); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	testCases := []struct {
+		v    interface{}
+		want string
+	}{
+		{v: "a\"b", want: `"a\"b"`},
+		{v: 42, want: "42"},
+		{v: true, want: "true"},
+		{v: constant.MakeString("x"), want: `"x"`},
+		{v: constant.MakeInt64(7), want: "7"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := Literal(tc.v); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+		import "time"
+		type Entry struct {
+			When time.Time
+		}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, _ := fs.Type("Entry")
+
+	tmpl, err := template.New("t").Funcs(FuncMap()).Parse(
+		`{{receiver .Name}} {{plural .Name}} {{snakeCase .Name}} {{zero (index .Fields 0).Type}} {{literal (index .Fields 0).Type}} {{quote .Name}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), `e Entries entry time.Time{} time.Time{} "Entry"`; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// TypeParamModel describes a type parameter of a generic type or function.
+type TypeParamModel struct {
+	// Name is the name of the type parameter.
+	Name string
+
+	// Constraint is the type constraint of the type parameter, which is an
+	// interface.
+	Constraint types.Type
+
+	// Object is the type checked type parameter.
+	Object *types.TypeParam
+}
+
+// InstanceModel describes an instantiation of a generic type or function, such
+// as Set[int] for the generic type Set.
+type InstanceModel struct {
+	// TypeArgs holds the type arguments of the instantiation, in the order of
+	// the type parameters.
+	TypeArgs []types.Type
+
+	// Type is the instantiated type. It is a *types.Named for a generic type
+	// and a *types.Signature for a generic function.
+	Type types.Type
+}
+
+// typeParamModels creates models of each type parameter in a list, returning
+// nil if the list is empty.
+func typeParamModels(list *types.TypeParamList) []*TypeParamModel {
+	if list.Len() == 0 {
+		return nil
+	}
+	models := make([]TypeParamModel, list.Len())
+	ptrs := make([]*TypeParamModel, list.Len())
+	for i := range models {
+		tp := list.At(i)
+		models[i] = TypeParamModel{Name: tp.Obj().Name(), Constraint: tp.Constraint(), Object: tp}
+		ptrs[i] = &models[i]
+	}
+	return ptrs
+}
+
+// TypeParamList returns the type parameter list of the type as it would be
+// written in its declaration, such as "[K comparable, V any]", using q to
+// qualify the names of types in constraints. It returns an empty string if
+// the type is not generic.
+func (m *TypeModel) TypeParamList(q types.Qualifier) string {
+	return typeParamList(m.TypeParams, q)
+}
+
+// TypeArgList returns the type parameters of the type written as the type
+// arguments of an instantiation with them, such as "[K, V]", suitable for
+// naming the type in the declaration of a method. It returns an empty string
+// if the type is not generic.
+func (m *TypeModel) TypeArgList() string {
+	return typeArgList(m.TypeParams)
+}
+
+// TypeParamList returns the type parameter list of the function as it would
+// be written in its declaration, such as "[T any]", using q to qualify the
+// names of types in constraints. It returns an empty string if the function
+// is not generic. Methods cannot declare type parameters, so for a method the
+// list is always empty.
+func (m *FuncModel) TypeParamList(q types.Qualifier) string {
+	if m.Recv != "" {
+		return ""
+	}
+	return typeParamList(m.TypeParams, q)
+}
+
+// typeParamList writes the type parameters in tps as a bracketed type
+// parameter list, combining consecutive parameters with identical constraints.
+func typeParamList(tps []*TypeParamModel, q types.Qualifier) string {
+	if len(tps) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, tp := range tps {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(tp.Name)
+		if i+1 < len(tps) && types.Identical(tp.Constraint, tps[i+1].Constraint) {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(constraintString(tp.Constraint, q))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// constraintString writes a type constraint in the form it would normally be
+// written, omitting the interface keyword from implicit interfaces such as
+// ~int | ~string.
+func constraintString(t types.Type, q types.Qualifier) string {
+	if iface, ok := t.(*types.Interface); ok && iface.IsImplicit() && iface.NumEmbeddeds() == 1 {
+		return types.TypeString(iface.EmbeddedType(0), q)
+	}
+	return types.TypeString(t, q)
+}
+
+// typeArgList writes the names of the type parameters in tps as a bracketed
+// type argument list.
+func typeArgList(tps []*TypeParamModel) string {
+	if len(tps) == 0 {
+		return ""
+	}
+	names := make([]string, len(tps))
+	for i, tp := range tps {
+		names[i] = tp.Name
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// Instances returns the distinct instantiations of the generic type or
+// function obj that appear in fs, in the order they first appear. It returns
+// nil if obj is not generic or is never instantiated. Instantiations whose
+// type arguments are themselves type parameters, such as those within the
+// declaration of a generic type's methods, are included.
+func (fs *FileSet) Instances(obj types.Object) []*InstanceModel {
+	var ids []*ast.Ident
+	for id := range fs.TypeInfo.Instances {
+		if fs.TypeInfo.Uses[id] == obj {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Pos() < ids[j].Pos() })
+
+	var models []*InstanceModel
+	for _, id := range ids {
+		inst := fs.TypeInfo.Instances[id]
+		if containsInstance(models, inst.TypeArgs) {
+			continue
+		}
+		args := make([]types.Type, inst.TypeArgs.Len())
+		for i := range args {
+			args[i] = inst.TypeArgs.At(i)
+		}
+		models = append(models, &InstanceModel{TypeArgs: args, Type: inst.Type})
+	}
+	return models
+}
+
+// containsInstance reports whether models contains an instantiation with type
+// arguments identical to args.
+func containsInstance(models []*InstanceModel, args *types.TypeList) bool {
+	for _, m := range models {
+		if len(m.TypeArgs) != args.Len() {
+			continue
+		}
+		same := true
+		for i, t := range m.TypeArgs {
+			if !types.Identical(t, args.At(i)) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
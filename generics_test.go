@@ -0,0 +1,148 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestTypeParams(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "fmt"
+
+		type Number interface{ ~int | ~float64 }
+
+		type Pair[K comparable, V any] struct {
+			Key   K
+			Value V
+		}
+
+		type Set[T comparable] map[T]struct{}
+
+		func (s Set[E]) Add(v E) { s[v] = struct{}{} }
+
+		type Plain struct{}
+
+		func Sum[N Number](ns ...N) N { return 0 }
+
+		func Max[A, B ~int | ~string](a A, b B) {}
+
+		func Show[S fmt.Stringer](s S) string { return s.String() }
+
+		var (
+			_ = Sum[int]
+			_ = Sum(1.5, 2)
+			_ = Sum[int]
+			_ Pair[string, int]
+			_ Set[string]
+		)
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+
+	typeCases := []struct {
+		name   string
+		params string
+		args   string
+	}{
+		{name: "Pair", params: "[K comparable, V any]", args: "[K, V]"},
+		{name: "Set", params: "[T comparable]", args: "[T]"},
+		{name: "Plain", params: "", args: ""},
+	}
+	for _, tc := range typeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tm, ok := fs.Type(tc.name)
+			if !ok {
+				t.Fatalf("type not found")
+			}
+			if got := tm.TypeParamList(q); got != tc.params {
+				t.Errorf("got params %q, wanted %q", got, tc.params)
+			}
+			if got := tm.TypeArgList(); got != tc.args {
+				t.Errorf("got args %q, wanted %q", got, tc.args)
+			}
+		})
+	}
+
+	funcCases := []struct {
+		name   string
+		params string
+		names  []string
+	}{
+		{name: "Sum", params: "[N Number]", names: []string{"N"}},
+		{name: "Max", params: "[A, B ~int | ~string]", names: []string{"A", "B"}},
+		{name: "Show", params: "[S fmt.Stringer]", names: []string{"S"}},
+		{name: "Set.Add", params: "", names: []string{"E"}},
+	}
+	for _, tc := range funcCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fm, ok := fs.Func(tc.name)
+			if !ok {
+				t.Fatalf("func not found")
+			}
+			if got := fm.TypeParamList(q); got != tc.params {
+				t.Errorf("got params %q, wanted %q", got, tc.params)
+			}
+			names := []string{}
+			for _, tp := range fm.TypeParams {
+				names = append(names, tp.Name)
+			}
+			if !reflect.DeepEqual(names, tc.names) {
+				t.Errorf("got names %+v, wanted %+v", names, tc.names)
+			}
+		})
+	}
+}
+
+func TestInstances(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type Set[T comparable] map[T]struct{}
+
+		func Sum[N ~int | ~float64](ns ...N) N { return 0 }
+
+		var (
+			_ = Sum[int]
+			_ = Sum(1.5, 2)
+			_ = Sum[int]
+			_ Set[string]
+		)
+
+		func NotGeneric() {}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+
+	testCases := []struct {
+		name string
+		want []string
+	}{
+		{name: "Sum", want: []string{"int: func(ns ...int) int", "float64: func(ns ...float64) float64"}},
+		{name: "Set", want: []string{"string: Set[string]"}},
+		{name: "NotGeneric", want: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for _, inst := range fs.Instances(fs.Lookup(tc.name)) {
+				s := ""
+				for i, arg := range inst.TypeArgs {
+					if i > 0 {
+						s += ", "
+					}
+					s += types.TypeString(arg, q)
+				}
+				got = append(got, s+": "+types.TypeString(inst.Type, q))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
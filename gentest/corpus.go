@@ -0,0 +1,56 @@
+// Package gentest provides helpers for testing code generators built using
+// the gen package.
+package gentest
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed testdata/corpus
+var corpus embed.FS
+
+const corpusDir = "testdata/corpus"
+
+// Corpus returns a filesystem containing a curated set of Go source files
+// holding declarations that are known to be awkward for code generators:
+// generic containers, recursive types, structs embedding types with
+// unexported fields from other packages and methods shadowed through
+// embedding. Each file is a complete, type checkable source file of package
+// corpus.
+func Corpus() fs.FS {
+	sub, err := fs.Sub(corpus, corpusDir)
+	if err != nil {
+		panic(err) // only possible if the embedded directory is renamed
+	}
+	return sub
+}
+
+// CorpusNames returns the names of the files in the corpus, sorted
+// alphabetically.
+func CorpusNames() []string {
+	entries, err := fs.ReadDir(Corpus(), ".")
+	if err != nil {
+		panic(err)
+	}
+
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CorpusText returns the source text of the named corpus file.
+func CorpusText(name string) (string, error) {
+	b, err := fs.ReadFile(Corpus(), name)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
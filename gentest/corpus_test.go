@@ -0,0 +1,47 @@
+package gentest
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+func TestCorpusNames(t *testing.T) {
+	want := []string{"embedded.go", "generics.go", "recursive.go", "shadowing.go"}
+	if got := CorpusNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestCorpusTypeChecks(t *testing.T) {
+	for _, name := range CorpusNames() {
+		t.Run(name, func(t *testing.T) {
+			src, err := CorpusText(name)
+			if err != nil {
+				t.Fatalf("unexpected error reading corpus: %v", err)
+			}
+
+			fs, err := gen.NewFileSetFromTexts(src)
+			if err != nil {
+				t.Fatalf("unexpected error parsing corpus: %v", err)
+			}
+
+			types := 0
+			fs.EachType(func(ts *ast.TypeSpec) bool {
+				types++
+				return true
+			})
+			if types == 0 {
+				t.Errorf("no types found in %s", name)
+			}
+		})
+	}
+}
+
+func TestCorpusTextMissing(t *testing.T) {
+	if _, err := CorpusText("missing.go"); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+}
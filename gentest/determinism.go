@@ -0,0 +1,30 @@
+package gentest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+// AssertDeterministic calls render twice and reports a test failure with a
+// unified diff if the two results differ, which usually means that the
+// generator ranges over a map without sorting its keys. Since the order of
+// map iteration is randomized, a nondeterministic generator may still
+// produce the same output twice by chance, so the check is most effective
+// when render's data has several map entries. AssertDeterministic reports a
+// fatal error if render returns an error.
+func AssertDeterministic(t testing.TB, render func() ([]byte, error)) {
+	t.Helper()
+	first, err := render()
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	second, err := render()
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("output differs between renders:\n%s", gen.UnifiedDiff("first", "second", first, second))
+	}
+}
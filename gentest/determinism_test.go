@@ -0,0 +1,38 @@
+package gentest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+func TestAssertDeterministic(t *testing.T) {
+	tmpl, err := gen.NewTemplateType("fields", fieldsTemplate, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	AssertDeterministic(t, func() ([]byte, error) { return tmpl.Render(userFixture()) })
+
+	n := 0
+	r := &recorder{TB: t}
+	r.run(func() {
+		AssertDeterministic(r, func() ([]byte, error) {
+			n++
+			return []byte(fmt.Sprintf("package p\n\nconst N = %d\n", n)), nil
+		})
+	})
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "+const N = 2") {
+		t.Errorf("got failures %q, wanted one with a diff", r.failures)
+	}
+
+	r = &recorder{TB: t}
+	r.run(func() {
+		AssertDeterministic(r, func() ([]byte, error) { return nil, errors.New("boom") })
+	})
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "boom") {
+		t.Errorf("got failures %q, wanted one for the render error", r.failures)
+	}
+}
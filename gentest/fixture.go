@@ -0,0 +1,151 @@
+package gentest
+
+import (
+	"go/token"
+	"go/types"
+
+	"github.com/iand/gen"
+)
+
+// FixturePackage is the package that declares the types built by TypeBuilder
+// unless another is given with TypeBuilder.Package.
+var FixturePackage = types.NewPackage("example.com/fixture", "fixture")
+
+// FieldBuilder builds a FieldModel by hand so that templates can be tested
+// without parsing Go source.
+type FieldBuilder struct {
+	m gen.FieldModel
+}
+
+// NewField starts building a field with the given name and type. The field is
+// exported if its name is.
+func NewField(name string, typ types.Type) *FieldBuilder {
+	return &FieldBuilder{m: gen.FieldModel{
+		Name:     name,
+		Type:     typ,
+		Exported: token.IsExported(name),
+		Tags:     gen.Tags{},
+	}}
+}
+
+// NewEmbedded starts building an embedded field of the given type. The field
+// is named after the type.
+func NewEmbedded(typ types.Type) *FieldBuilder {
+	name := ""
+	if n := gen.NamedOf(typ); n != nil {
+		name = n.Obj().Name()
+	} else if b, ok := typ.(*types.Basic); ok {
+		name = b.Name()
+	}
+	b := NewField(name, typ)
+	b.m.Embedded = true
+	return b
+}
+
+// Doc sets the text of the field's doc comment.
+func (b *FieldBuilder) Doc(doc string) *FieldBuilder {
+	b.m.Doc = doc
+	return b
+}
+
+// Comment sets the text of the field's line comment.
+func (b *FieldBuilder) Comment(comment string) *FieldBuilder {
+	b.m.Comment = comment
+	return b
+}
+
+// Tag sets the field's struct tag, which may be supplied with or without the
+// surrounding backquotes. The parsed tags are empty if the tag is malformed,
+// as they are for a field parsed from source.
+func (b *FieldBuilder) Tag(tag string) *FieldBuilder {
+	tags, err := gen.ParseTags(tag)
+	if err != nil {
+		tags = gen.Tags{}
+	}
+	if len(tag) >= 2 && tag[0] == '`' && tag[len(tag)-1] == '`' {
+		tag = tag[1 : len(tag)-1]
+	}
+	b.m.Tag, b.m.Tags = tag, tags
+	return b
+}
+
+// Build returns the field's model. Its Object is created in FixturePackage
+// and Field is nil since the field has no declaration.
+func (b *FieldBuilder) Build() *gen.FieldModel {
+	return b.build(FixturePackage)
+}
+
+func (b *FieldBuilder) build(pkg *types.Package) *gen.FieldModel {
+	m := b.m
+	m.Object = types.NewField(token.NoPos, pkg, m.Name, m.Type, m.Embedded)
+	return &m
+}
+
+// TypeBuilder builds a TypeModel by hand so that templates can be tested
+// without parsing Go source. A type is a struct type unless another
+// underlying type is given with Underlying.
+type TypeBuilder struct {
+	name       string
+	doc        string
+	pkg        *types.Package
+	fields     []*FieldBuilder
+	underlying types.Type
+}
+
+// NewType starts building a named type.
+func NewType(name string) *TypeBuilder {
+	return &TypeBuilder{name: name, pkg: FixturePackage}
+}
+
+// Package sets the package that declares the type.
+func (b *TypeBuilder) Package(pkg *types.Package) *TypeBuilder {
+	b.pkg = pkg
+	return b
+}
+
+// Doc sets the text of the type's doc comment.
+func (b *TypeBuilder) Doc(doc string) *TypeBuilder {
+	b.doc = doc
+	return b
+}
+
+// Field adds fields to a struct type.
+func (b *TypeBuilder) Field(fields ...*FieldBuilder) *TypeBuilder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Underlying sets the underlying type of a type that is not a struct, such
+// as types.Typ[types.Int] for an enumeration.
+func (b *TypeBuilder) Underlying(t types.Type) *TypeBuilder {
+	b.underlying = t
+	return b
+}
+
+// Build returns the type's model. Its Object is a newly created type name
+// whose type is a types.Named with the fields or underlying type given, so
+// templates that inspect the type checked objects behave as they do for
+// parsed source. Spec and the Field of each field model are nil since the
+// type has no declaration.
+func (b *TypeBuilder) Build() *gen.TypeModel {
+	m := &gen.TypeModel{
+		Name:   b.name,
+		Doc:    b.doc,
+		Object: types.NewTypeName(token.NoPos, b.pkg, b.name, nil),
+	}
+
+	underlying := b.underlying
+	if underlying == nil {
+		vars := make([]*types.Var, len(b.fields))
+		tags := make([]string, len(b.fields))
+		m.Fields = make([]*gen.FieldModel, len(b.fields))
+		for i, f := range b.fields {
+			fm := f.build(b.pkg)
+			m.Fields[i] = fm
+			vars[i], tags[i] = fm.Object, fm.Tag
+		}
+		underlying = types.NewStruct(vars, tags)
+	}
+	types.NewNamed(m.Object, underlying, nil)
+	return m
+}
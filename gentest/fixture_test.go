@@ -0,0 +1,52 @@
+package gentest
+
+import (
+	"go/types"
+	"testing"
+)
+
+func TestTypeBuilder(t *testing.T) {
+	base := NewType("Base").Field(NewField("ID", types.Typ[types.Int])).Build()
+
+	m := NewType("User").
+		Doc("User is a user.\n").
+		Field(
+			NewEmbedded(base.Object.Type()),
+			NewField("Name", types.Typ[types.String]).Tag("`json:\"name,omitempty\"`").Doc("Name is the user's name.\n"),
+			NewField("age", types.Typ[types.Int]).Comment("in years\n").Tag(`bad"`),
+		).
+		Build()
+
+	if m.Name != "User" || m.Doc != "User is a user.\n" || m.Object.Pkg() != FixturePackage {
+		t.Errorf("got type model %+v", m)
+	}
+	st, ok := m.Object.Type().Underlying().(*types.Struct)
+	if !ok || st.NumFields() != 3 {
+		t.Fatalf("got underlying type %v, wanted struct with 3 fields", m.Object.Type().Underlying())
+	}
+	if got, want := st.Tag(1), `json:"name,omitempty"`; got != want {
+		t.Errorf("got struct tag %q, wanted %q", got, want)
+	}
+
+	embedded, name, age := m.Fields[0], m.Fields[1], m.Fields[2]
+	if embedded.Name != "Base" || !embedded.Embedded || !embedded.Object.Embedded() || st.Field(0) != embedded.Object {
+		t.Errorf("got embedded field %+v", embedded)
+	}
+	if tag, ok := name.Tags.Get("json"); !ok || tag.Value != "name,omitempty" || !name.Exported || name.Doc != "Name is the user's name.\n" {
+		t.Errorf("got field %+v", name)
+	}
+	if age.Exported || len(age.Tags) != 0 || age.Comment != "in years\n" {
+		t.Errorf("got field %+v", age)
+	}
+
+	sel, _, _ := types.LookupFieldOrMethod(m.Object.Type(), false, FixturePackage, "ID")
+	if sel == nil {
+		t.Errorf("promoted field of built type not found")
+	}
+
+	pkg := types.NewPackage("example.com/colors", "colors")
+	color := NewType("Color").Package(pkg).Underlying(types.Typ[types.Int]).Build()
+	if color.Fields != nil || color.Object.Type().Underlying() != types.Typ[types.Int] || color.Object.Pkg() != pkg {
+		t.Errorf("got type model %+v", color)
+	}
+}
@@ -0,0 +1,48 @@
+package gentest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+// UpdateEnv is the environment variable that causes ExecTemplate to rewrite
+// golden files with the output of the template instead of comparing them,
+// for example when run as GENTEST_UPDATE=1 go test.
+const UpdateEnv = "GENTEST_UPDATE"
+
+// ExecTemplate renders tmpl with data, typically a model built with
+// TypeBuilder or FieldBuilder, and compares the result with the contents of
+// the golden file, reporting a test failure with a unified diff if they
+// differ. If the UpdateEnv environment variable is set to a non-empty value
+// the golden file is written with the result instead, creating its directory
+// if necessary. ExecTemplate reports a fatal error if the template fails to
+// render.
+func ExecTemplate(t testing.TB, tmpl *gen.TemplateType, data any, golden string) {
+	t.Helper()
+	got, err := tmpl.Render(data)
+	if err != nil {
+		t.Fatalf("render template: %v", err)
+	}
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file: %v (set %s=1 to create it)", err, UpdateEnv)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("template output differs from %s (set %s=1 to update it):\n%s", golden, UpdateEnv, gen.UnifiedDiff(golden, "got", want, got))
+	}
+}
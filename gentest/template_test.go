@@ -0,0 +1,91 @@
+package gentest
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const fieldsTemplate = `package {{.Object.Pkg.Name}}
+
+// {{.Name}}Fields lists the fields of {{.Name}}.
+var {{.Name}}Fields = []string{
+{{- range .Fields}}
+	"{{.Name}}", // {{range .Tags}}{{if eq .Key "json"}}{{.Name}}{{end}}{{end}}
+{{- end}}
+}
+`
+
+func userFixture() *gen.TypeModel {
+	return NewType("User").Field(
+		NewField("Name", types.Typ[types.String]).Tag(`json:"name"`),
+		NewField("Email", types.Typ[types.String]).Tag(`json:"email,omitempty"`),
+	).Build()
+}
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+// errFatal stops a function run with a recorder when it reports a fatal
+// error.
+var errFatal = errors.New("fatal")
+
+// run calls f, recovering from a fatal error reported to the recorder.
+func (r *recorder) run(f func()) {
+	defer func() {
+		if v := recover(); v != nil && v != errFatal {
+			panic(v)
+		}
+	}()
+	f()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	panic(errFatal)
+}
+
+func TestExecTemplate(t *testing.T) {
+	tmpl, err := gen.NewTemplateType("fields", fieldsTemplate, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv(UpdateEnv, "")
+	ExecTemplate(t, tmpl, userFixture(), filepath.Join("testdata", "template", "user.golden"))
+
+	r := &recorder{TB: t}
+	other := NewType("User").Field(NewField("Name", types.Typ[types.String]).Tag(`json:"name"`)).Build()
+	r.run(func() { ExecTemplate(r, tmpl, other, filepath.Join("testdata", "template", "user.golden")) })
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], `-	"Email", // email`) {
+		t.Errorf("got failures %q, wanted one with a diff", r.failures)
+	}
+
+	r = &recorder{TB: t}
+	golden := filepath.Join(t.TempDir(), "new", "user.golden")
+	r.run(func() { ExecTemplate(r, tmpl, userFixture(), golden) })
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], UpdateEnv) {
+		t.Errorf("got failures %q, wanted one for the missing golden file", r.failures)
+	}
+
+	t.Setenv(UpdateEnv, "1")
+	ExecTemplate(t, tmpl, userFixture(), golden)
+	want, _ := os.ReadFile(filepath.Join("testdata", "template", "user.golden"))
+	if got, err := os.ReadFile(golden); err != nil || string(got) != string(want) {
+		t.Errorf("got golden file %q, %v, wanted %q", got, err, want)
+	}
+}
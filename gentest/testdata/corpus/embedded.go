@@ -0,0 +1,41 @@
+package corpus
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Guarded embeds a type from another package whose fields are all unexported.
+type Guarded struct {
+	sync.Mutex
+	Count int
+}
+
+// Buffered embeds a pointer to a type from another package.
+type Buffered struct {
+	*strings.Builder
+	Name string
+}
+
+// Layered embeds a struct which itself embeds a foreign type.
+type Layered struct {
+	Guarded
+	bytes.Buffer
+}
+
+// private is an unexported type that is embedded in an exported one.
+type private struct {
+	secret string
+	Shown  string
+}
+
+// Exposed promotes the exported field of an unexported embedded type.
+type Exposed struct {
+	private
+}
+
+// Anonymous embeds an interface from another package.
+type Anonymous struct {
+	sync.Locker
+}
@@ -0,0 +1,64 @@
+package corpus
+
+// Number is a constraint union of the built in numeric types.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// List is a generic singly linked list.
+type List[T any] struct {
+	head *node[T]
+	size int
+}
+
+type node[T any] struct {
+	val  T
+	next *node[T]
+}
+
+// Push adds v to the front of the list.
+func (l *List[T]) Push(v T) {
+	l.head = &node[T]{val: v, next: l.head}
+	l.size++
+}
+
+// Len reports the number of elements in the list.
+func (l *List[T]) Len() int { return l.size }
+
+// Map is a generic map wrapper with a comparable key.
+type Map[K comparable, V any] struct {
+	m map[K]V
+}
+
+// Get returns the value stored under k.
+func (m Map[K, V]) Get(k K) (V, bool) {
+	v, ok := m.m[k]
+	return v, ok
+}
+
+// Pair holds two values of possibly different types.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// IntList is an instantiation of a generic type.
+type IntList = List[int]
+
+// Sum adds all the values in xs.
+func Sum[N Number](xs ...N) N {
+	var total N
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+// Keys returns the keys of m in unspecified order.
+func Keys[M ~map[K]V, K comparable, V any](m M) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,43 @@
+package corpus
+
+// Tree is directly recursive through a pointer.
+type Tree struct {
+	Value       int
+	Left, Right *Tree
+}
+
+// Graph is recursive through a map of slices.
+type Graph struct {
+	Edges map[string][]*Graph
+}
+
+// Expr and Binary are mutually recursive.
+type Expr interface {
+	Eval() int
+}
+
+// Binary is an Expr made of two other Exprs.
+type Binary struct {
+	Op   byte
+	L, R Expr
+}
+
+// Eval evaluates the expression.
+func (b Binary) Eval() int {
+	if b.Op == '+' {
+		return b.L.Eval() + b.R.Eval()
+	}
+	return b.L.Eval() * b.R.Eval()
+}
+
+// StateFn is a function type that returns itself.
+type StateFn func() StateFn
+
+// Nested is recursive through a channel of slices of itself.
+type Nested chan []Nested
+
+// Generic recursion through a type parameter.
+type Chain[T any] struct {
+	Value T
+	Next  *Chain[T]
+}
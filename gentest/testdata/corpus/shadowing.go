@@ -0,0 +1,46 @@
+package corpus
+
+// Base declares a method that is shadowed by types that embed it.
+type Base struct {
+	ID int
+}
+
+// Name returns the name of the base.
+func (Base) Name() string { return "base" }
+
+// Describe is only declared on Base and is promoted to embedders.
+func (b *Base) Describe() string { return "base" }
+
+// Derived shadows Base.Name with its own method.
+type Derived struct {
+	Base
+}
+
+// Name returns the name of the derived type.
+func (Derived) Name() string { return "derived" }
+
+// Other also declares Name, making it ambiguous when embedded next to Base.
+type Other struct {
+	ID string
+}
+
+// Name returns the name of other.
+func (Other) Name() string { return "other" }
+
+// Ambiguous embeds two types at the same depth that both declare Name and ID,
+// so neither is promoted.
+type Ambiguous struct {
+	Base
+	Other
+}
+
+// FieldShadow declares a field with the same name as a promoted method.
+type FieldShadow struct {
+	Base
+	Describe string
+}
+
+// Deep embeds Derived so that Derived.Name shadows Base.Name at a deeper level.
+type Deep struct {
+	Derived
+}
@@ -0,0 +1,181 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrUncommittedChanges is returned when a generated file would overwrite
+// changes that have not been committed to git.
+var ErrUncommittedChanges = errors.New("file has uncommitted changes")
+
+// GitRepo is the working tree of a git repository containing generated
+// files. Its methods run the git command, which must be installed.
+type GitRepo struct {
+	// Dir is the top level directory of the working tree.
+	Dir string
+}
+
+// FindGitRepo returns the git repository whose working tree contains dir.
+func FindGitRepo(dir string) (*GitRepo, error) {
+	out, err := gitCommand(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	return &GitRepo{Dir: filepath.FromSlash(strings.TrimSpace(string(out)))}, nil
+}
+
+// Stage adds the named files to the git index, such as after writing
+// generated files so they can be reviewed and committed together.
+func (r *GitRepo) Stage(filenames ...string) error {
+	if len(filenames) == 0 {
+		return nil
+	}
+	paths, err := r.paths(filenames)
+	if err != nil {
+		return err
+	}
+	_, err = gitCommand(r.Dir, append([]string{"add", "--"}, paths...)...)
+	return err
+}
+
+// Modified returns the names of the files, of those named, whose contents in
+// the working tree or index differ from the last commit. Files not tracked
+// by git are not included. The names are returned as given, in the order
+// given.
+func (r *GitRepo) Modified(filenames ...string) ([]string, error) {
+	if len(filenames) == 0 {
+		return nil, nil
+	}
+	paths, err := r.paths(filenames)
+	if err != nil {
+		return nil, err
+	}
+	out, err := gitCommand(r.Dir, append([]string{"status", "--porcelain", "-z", "--untracked-files=no", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		changed[entry[3:]] = true
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // skip the source of a rename or copy
+		}
+	}
+
+	var modified []string
+	for i, p := range paths {
+		if changed[p] {
+			modified = append(modified, filenames[i])
+		}
+	}
+	return modified, nil
+}
+
+// CheckClean verifies that none of the files that outputs, keyed by filename,
+// are about to be written to have uncommitted changes. Overwriting such a file
+// would lose manual edits made since it was last generated or committed. The
+// error returned wraps ErrUncommittedChanges for each such file.
+func (r *GitRepo) CheckClean(outputs map[string]*Output) error {
+	filenames := make([]string, 0, len(outputs))
+	for filename := range outputs {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	modified, err := r.Modified(filenames...)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, filename := range modified {
+		errs = append(errs, fmt.Errorf("%s: %w", filename, ErrUncommittedChanges))
+	}
+	return errors.Join(errs...)
+}
+
+// DiffStat returns a summary of the changes to the named files in the
+// working tree relative to the index, in the format of git diff --stat. Files
+// not tracked by git are summarized as new files.
+func (r *GitRepo) DiffStat(filenames ...string) (string, error) {
+	if len(filenames) == 0 {
+		return "", nil
+	}
+	paths, err := r.paths(filenames)
+	if err != nil {
+		return "", err
+	}
+	out, err := gitCommand(r.Dir, append([]string{"ls-files", "--others", "--exclude-standard", "-z", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	untracked := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(untracked) == 1 && untracked[0] == "" {
+		untracked = nil
+	}
+
+	// Marking untracked files with intent to add includes them in the diff
+	// without staging their contents. The marks are removed afterwards.
+	if len(untracked) > 0 {
+		if _, err := gitCommand(r.Dir, append([]string{"add", "--intent-to-add", "--"}, untracked...)...); err != nil {
+			return "", err
+		}
+		defer gitCommand(r.Dir, append([]string{"rm", "--cached", "--quiet", "--"}, untracked...)...)
+	}
+	out, err = gitCommand(r.Dir, append([]string{"diff", "--stat", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// paths converts filenames to slash separated paths relative to the top
+// level of the working tree.
+func (r *GitRepo) paths(filenames []string) ([]string, error) {
+	paths := make([]string, len(filenames))
+	for i, filename := range filenames {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, err
+		}
+		// The top level directory reported by git has symbolic links
+		// resolved.
+		if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+			abs = filepath.Join(dir, filepath.Base(abs))
+		}
+		rel, err := filepath.Rel(r.Dir, abs)
+		if err != nil {
+			return nil, err
+		}
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s: not in git repository %s", filename, r.Dir)
+		}
+		paths[i] = filepath.ToSlash(rel)
+	}
+	return paths, nil
+}
+
+// gitCommand runs git with args in dir and returns its output, including
+// git's error output in any error returned.
+func gitCommand(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=gen", "-c", "user.email=gen@example.com"}, args...)
+		if _, err := gitCommand(dir, args...); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write := func(name, src string) string {
+		t.Helper()
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return filename
+	}
+
+	git("init", "--quiet")
+	clean := write("clean.go", "package p\n")
+	edited := write("sub/edited.go", "package p\n")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "initial")
+	write("sub/edited.go", "package p\n\n// manual change\n")
+	created := write("created.go", "package p\n\nconst C = 1\n")
+
+	repo, err := FindGitRepo(filepath.Join(dir, "sub"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	modified, err := repo.Modified(clean, edited, created)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{edited}; !reflect.DeepEqual(modified, want) {
+		t.Errorf("got modified %+v, wanted %+v", modified, want)
+	}
+
+	err = repo.CheckClean(map[string]*Output{clean: NewOutput("gen"), edited: NewOutput("gen")})
+	if !errors.Is(err, ErrUncommittedChanges) || !strings.Contains(err.Error(), "edited.go") {
+		t.Errorf("got error %v, wanted %v for edited.go", err, ErrUncommittedChanges)
+	}
+	if err := repo.CheckClean(map[string]*Output{clean: NewOutput("gen"), created: NewOutput("gen")}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	stat, err := repo.DiffStat(edited, created, clean)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"sub/edited.go | 2 ++", "created.go    | 3 +++", "2 files changed, 5 insertions(+)"} {
+		if !strings.Contains(stat, want) {
+			t.Errorf("got diff stat:\n%s\nwanted it to contain %q", stat, want)
+		}
+	}
+	if modified, _ := repo.Modified(created); len(modified) != 0 {
+		t.Errorf("DiffStat left %+v in the index", modified)
+	}
+
+	if err := repo.Stage(edited, created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status, err := gitCommand(dir, "status", "--porcelain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := string(status), "A  created.go\nM  sub/edited.go\n"; got != want {
+		t.Errorf("got status %q, wanted %q", got, want)
+	}
+
+	if _, err := repo.Modified(filepath.Join(t.TempDir(), "outside.go")); err == nil {
+		t.Errorf("got no error for file outside repository, wanted one")
+	}
+	if _, err := FindGitRepo(t.TempDir()); err == nil {
+		t.Errorf("got no error outside a repository, wanted one")
+	}
+}
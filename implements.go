@@ -0,0 +1,234 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// MissingMethod describes an interface method that a type does not
+// implement.
+type MissingMethod struct {
+	// Name is the name of the method.
+	Name string
+
+	// Want is the method declared by the interface.
+	Want *types.Func
+
+	// Have is the type's method with the same name but a different
+	// signature, or nil if the type has no method with that name.
+	Have *types.Func
+
+	// PointerOnly is true if the method exists with the required signature
+	// but only in the method set of a pointer to the type.
+	PointerOnly bool
+}
+
+func (m MissingMethod) String() string {
+	switch {
+	case m.PointerOnly:
+		return fmt.Sprintf("method %s has pointer receiver", m.Name)
+	case m.Have != nil:
+		return fmt.Sprintf("wrong type for method %s: have %s, want %s", m.Name, m.Have.Type(), m.Want.Type())
+	default:
+		return fmt.Sprintf("missing method %s", m.Name)
+	}
+}
+
+// Implements reports whether the named type satisfies the named interface,
+// returning the interface methods that are not implemented. The type must be
+// declared in fs and may be prefixed with an asterisk to check the pointer
+// type. The interface may be declared in fs or qualified by the name or
+// import path of another package, such as "io.Reader" or
+// "encoding/json.Marshaler".
+func (fs *FileSet) Implements(typeName, interfaceName string) (bool, []MissingMethod, error) {
+	t, err := fs.lookupType(typeName)
+	if err != nil {
+		return false, nil, err
+	}
+	iface, err := fs.lookupInterface(interfaceName)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if types.Implements(t, iface) {
+		return true, nil, nil
+	}
+
+	var missing []MissingMethod
+	valueSet := types.NewMethodSet(t)
+	var ptrSet *types.MethodSet
+	if _, isPtr := t.(*types.Pointer); !isPtr && !types.IsInterface(t) {
+		ptrSet = types.NewMethodSet(types.NewPointer(t))
+	}
+
+	for i := 0; i < iface.NumMethods(); i++ {
+		want := iface.Method(i)
+		if sel := valueSet.Lookup(want.Pkg(), want.Name()); sel != nil {
+			have := sel.Obj().(*types.Func)
+			if types.Identical(have.Type(), want.Type()) {
+				continue
+			}
+			missing = append(missing, MissingMethod{Name: want.Name(), Want: want, Have: have})
+			continue
+		}
+
+		m := MissingMethod{Name: want.Name(), Want: want}
+		if ptrSet != nil {
+			if sel := ptrSet.Lookup(want.Pkg(), want.Name()); sel != nil {
+				have := sel.Obj().(*types.Func)
+				if types.Identical(have.Type(), want.Type()) {
+					m.PointerOnly = true
+				} else {
+					m.Have = have
+				}
+			}
+		}
+		missing = append(missing, m)
+	}
+	return false, missing, nil
+}
+
+// Implementer describes a type that implements an interface.
+type Implementer struct {
+	// Type is the implementing type.
+	Type *TypeModel
+
+	// Pointer is true if only a pointer to the type implements the
+	// interface.
+	Pointer bool
+}
+
+// Implementers returns the named types declared at package level in fs that
+// implement the named interface, either directly or through a pointer, in the
+// order they are declared. Interface types, aliases and generic types are not
+// included. The interface is named as for Implements.
+func (fs *FileSet) Implementers(interfaceName string) ([]Implementer, error) {
+	iface, err := fs.lookupInterface(interfaceName)
+	if err != nil {
+		return nil, err
+	}
+	return fs.implementers(iface), nil
+}
+
+// implementers returns the named types declared at package level in fs that
+// implement iface.
+func (fs *FileSet) implementers(iface *types.Interface) []Implementer {
+	var impls []Implementer
+	for _, tm := range fs.Types() {
+		t := tm.Object.Type()
+		if tm.Object.IsAlias() || len(tm.TypeParams) > 0 || types.IsInterface(t) {
+			continue
+		}
+		switch {
+		case types.Implements(t, iface):
+			impls = append(impls, Implementer{Type: tm})
+		case types.Implements(types.NewPointer(t), iface):
+			impls = append(impls, Implementer{Type: tm, Pointer: true})
+		}
+	}
+	return impls
+}
+
+// Assertion returns a variable declaration that asserts at compile time that
+// the named type implements the named interface, such as
+// var _ io.Reader = (*T)(nil). Packages referred to by the declaration are
+// added to im. Names are interpreted as for Implements.
+func (fs *FileSet) Assertion(typeName, interfaceName string, im *Imports) (string, error) {
+	t, err := fs.lookupType(typeName)
+	if err != nil {
+		return "", err
+	}
+	obj, err := fs.LookupInterface(interfaceName)
+	if err != nil {
+		return "", err
+	}
+
+	q := im.Qualifier(fs.Package)
+	iface := types.TypeString(obj.Type(), q)
+	if p, ok := t.(*types.Pointer); ok {
+		return fmt.Sprintf("var _ %s = (*%s)(nil)", iface, types.TypeString(p.Elem(), q)), nil
+	}
+	typ := types.TypeString(t, q)
+	if _, ok := t.Underlying().(*types.Struct); ok {
+		return fmt.Sprintf("var _ %s = %s{}", iface, typ), nil
+	}
+	return fmt.Sprintf("var _ %s = *new(%s)", iface, typ), nil
+}
+
+// lookupType returns the type named by name, which may be prefixed by an
+// asterisk to denote a pointer.
+func (fs *FileSet) lookupType(name string) (types.Type, error) {
+	ptr := strings.HasPrefix(name, "*")
+	obj, ok := fs.Lookup(strings.TrimPrefix(name, "*")).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	if ptr {
+		return types.NewPointer(obj.Type()), nil
+	}
+	return obj.Type(), nil
+}
+
+// lookupInterface returns the interface named by name.
+func (fs *FileSet) lookupInterface(name string) (*types.Interface, error) {
+	obj, err := fs.LookupInterface(name)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Type().Underlying().(*types.Interface), nil
+}
+
+// LookupInterface returns the type name of the interface named by name,
+// which may be declared in fs or qualified by the name or import path of
+// another package, such as "io.Reader".
+func (fs *FileSet) LookupInterface(name string) (*types.TypeName, error) {
+	obj, err := fs.lookupQualified(name)
+	if err != nil {
+		return nil, err
+	}
+	tn, ok := obj.(*types.TypeName)
+	if !ok || !types.IsInterface(tn.Type()) {
+		return nil, fmt.Errorf("%s is not an interface type", name)
+	}
+	return tn, nil
+}
+
+// lookupQualified returns the package level object named by name. An
+// unqualified name refers to an object in fs. A qualified name refers to an
+// object in a package imported by fs, identified by its name or import path,
+// or failing that to a package loaded by import path.
+func (fs *FileSet) lookupQualified(name string) (types.Object, error) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		if obj := fs.Lookup(name); obj != nil {
+			return obj, nil
+		}
+		if obj := types.Universe.Lookup(name); obj != nil {
+			return obj, nil
+		}
+		return nil, fmt.Errorf("%s not found", name)
+	}
+
+	qual, sel := name[:i], name[i+1:]
+	var pkg *types.Package
+	for _, imp := range fs.Package.Imports() {
+		if imp.Path() == qual || imp.Name() == qual {
+			pkg = imp
+			break
+		}
+	}
+	if pkg == nil {
+		var err error
+		pkg, err = fs.baseImporter().Import(qual)
+		if err != nil {
+			return nil, fmt.Errorf("package %s not found: %w", qual, err)
+		}
+	}
+
+	obj := pkg.Scope().Lookup(sel)
+	if obj == nil {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	return obj, nil
+}
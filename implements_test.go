@@ -0,0 +1,180 @@
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImplements(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "io"
+
+		var _ io.Reader
+
+		type Shape interface {
+			Area() float64
+			Name() string
+		}
+
+		type Square struct{}
+
+		func (Square) Area() float64 { return 0 }
+		func (Square) Name() string  { return "" }
+
+		type Circle struct{}
+
+		func (*Circle) Area() float64 { return 0 }
+		func (Circle) Name() int       { return 0 }
+
+		type File struct{}
+
+		func (*File) Read(p []byte) (int, error) { return 0, nil }
+
+		type Count int
+
+		func (Count) String() string { return "" }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		typeName  string
+		iface     string
+		want      bool
+		missing   []string
+		assertion string
+	}{
+		{typeName: "Square", iface: "Shape", want: true, assertion: "var _ Shape = Square{}"},
+		{typeName: "*Square", iface: "Shape", want: true, assertion: "var _ Shape = (*Square)(nil)"},
+		{
+			typeName: "Circle",
+			iface:    "Shape",
+			want:     false,
+			missing: []string{
+				"method Area has pointer receiver",
+				"wrong type for method Name: have func() int, want func() string",
+			},
+		},
+		{typeName: "File", iface: "io.Reader", want: false, missing: []string{"method Read has pointer receiver"}},
+		{typeName: "*File", iface: "io.Reader", want: true, assertion: "var _ io.Reader = (*File)(nil)"},
+		{typeName: "Count", iface: "fmt.Stringer", want: true, assertion: "var _ fmt.Stringer = *new(Count)"},
+		{typeName: "Count", iface: "encoding/json.Marshaler", want: false, missing: []string{"missing method MarshalJSON"}},
+		{typeName: "Square", iface: "error", want: false, missing: []string{"missing method Error"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.typeName+" "+tc.iface, func(t *testing.T) {
+			got, missing, err := fs.Implements(tc.typeName, tc.iface)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+
+			msgs := []string{}
+			for _, m := range missing {
+				msgs = append(msgs, m.String())
+			}
+			if tc.missing == nil {
+				tc.missing = []string{}
+			}
+			if !reflect.DeepEqual(msgs, tc.missing) {
+				t.Errorf("got missing %+v, wanted %+v", msgs, tc.missing)
+			}
+
+			if tc.assertion != "" {
+				a, err := fs.Assertion(tc.typeName, tc.iface, NewImports())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if a != tc.assertion {
+					t.Errorf("got assertion %q, wanted %q", a, tc.assertion)
+				}
+			}
+		})
+	}
+
+	if _, _, err := fs.Implements("Missing", "Shape"); err == nil {
+		t.Errorf("got no error for missing type")
+	}
+	if _, _, err := fs.Implements("Square", "Square"); err == nil {
+		t.Errorf("got no error for non-interface")
+	}
+	if _, _, err := fs.Implements("Square", "nosuchpkg.X"); err == nil {
+		t.Errorf("got no error for missing package")
+	}
+}
+
+func TestImplementers(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type Shape interface {
+			isShape()
+		}
+
+		type Square struct{}
+
+		func (Square) isShape() {}
+
+		type Alias = Square
+
+		type Polygon interface {
+			Shape
+			Sides() int
+		}
+
+		type Circle struct{}
+
+		func (*Circle) isShape() {}
+
+		type Box[T any] struct{}
+
+		func (Box[T]) isShape() {}
+
+		type Point struct{}
+
+		type Count int
+
+		func (Count) String() string { return "" }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		iface string
+		want  []string
+	}{
+		{iface: "Shape", want: []string{"Square", "*Circle"}},
+		{iface: "Polygon", want: []string{}},
+		{iface: "fmt.Stringer", want: []string{"Count"}},
+		{iface: "any", want: []string{"Square", "Circle", "Point", "Count"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.iface, func(t *testing.T) {
+			impls, err := fs.Implementers(tc.iface)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []string{}
+			for _, impl := range impls {
+				name := impl.Type.Name
+				if impl.Pointer {
+					name = "*" + name
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := fs.Implementers("Square"); err == nil {
+		t.Errorf("got no error for non-interface")
+	}
+}
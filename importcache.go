@@ -0,0 +1,362 @@
+package gen
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+)
+
+// DefaultMaxCachedPackages is the number of packages an ImporterCache created
+// with a non-positive limit may hold.
+const DefaultMaxCachedPackages = 2000
+
+// ImporterCache shares the types of imported packages between FileSets loaded
+// in the same process, such as by a long running server or a watcher, so that
+// the standard library and common dependencies are loaded from export data
+// once rather than for every package. Use WithImporterCache to load a FileSet
+// using a cache. An ImporterCache is safe for concurrent use.
+//
+// Packages are cached per build configuration. Standard library packages are
+// shared by all modules while other packages are cached separately for each
+// module since different modules may depend on different versions. The number
+// of cached packages is bounded; when the bound is exceeded the packages of
+// the least recently used modules are evicted. Evicting the standard library
+// of a build configuration also evicts the modules that depend on it. Evicted
+// packages are loaded again when next imported and are distinct from the
+// previously loaded packages, so types from FileSets loaded before and after
+// an eviction should not be compared.
+//
+// The packages of a module are discarded automatically when its go.mod or
+// go.sum file changes. Call Invalidate when the source files of a package
+// in a module change so that packages importing it see the changes.
+//
+// The positions of objects in cached packages refer to the cache's FileSet
+// rather than the FileSet of the importing package.
+type ImporterCache struct {
+	// FileSet records the positions of objects in cached packages.
+	FileSet *token.FileSet
+
+	mu      sync.Mutex
+	max     int
+	size    int
+	scopes  map[scopeKey]*list.Element
+	lru     *list.List        // of *cacheScope, most recently used first
+	modSums map[string]string // digest of go.mod and go.sum by module root
+}
+
+// scopeKey identifies a set of packages that share type identity.
+type scopeKey struct {
+	config string // build configuration
+	module string // module root directory, or empty for the standard library
+}
+
+// cacheScope holds the packages and export data locations for a scopeKey.
+type cacheScope struct {
+	key      scopeKey
+	modSum   string // digest of go.mod and go.sum when the scope was created
+	packages map[string]*types.Package
+	exports  map[string]string
+}
+
+// NewImporterCache creates a cache that holds at most maxPackages packages. If
+// maxPackages is not positive DefaultMaxCachedPackages is used.
+func NewImporterCache(maxPackages int) *ImporterCache {
+	if maxPackages <= 0 {
+		maxPackages = DefaultMaxCachedPackages
+	}
+	return &ImporterCache{
+		FileSet: token.NewFileSet(),
+		max:     maxPackages,
+		scopes:  make(map[scopeKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Len returns the number of packages held in the cache.
+func (c *ImporterCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// WithImporterCache loads the types of the package's dependencies using the
+// shared cache c instead of a private importer. The cache is not used when
+// the import mode is ImportSource.
+func WithImporterCache(c *ImporterCache) Option {
+	return func(o *options) {
+		o.cache = c
+	}
+}
+
+// importer returns an importer that resolves imports from packages in the
+// module enclosing dir using the cache.
+func (c *ImporterCache) importer(dir string, ctxt *build.Context) *cachedImporter {
+	root := moduleDir(dir)
+	if root == "" {
+		root, _ = filepath.Abs(dir)
+	}
+	c.mu.Lock()
+	c.checkModuleLocked(root)
+	c.mu.Unlock()
+
+	return &cachedImporter{
+		cache:  c,
+		dir:    dir,
+		ctxt:   ctxt,
+		std:    scopeKey{config: configKey(ctxt)},
+		module: scopeKey{config: configKey(ctxt), module: root},
+	}
+}
+
+// checkModuleLocked discards the cached packages of the module rooted at root
+// if its go.mod or go.sum file has changed since they were loaded, since the
+// versions of its dependencies may have changed.
+func (c *ImporterCache) checkModuleLocked(root string) {
+	sum := hashFiles(filepath.Join(root, "go.mod"), filepath.Join(root, "go.sum"))
+	for key, e := range c.scopes {
+		if s := e.Value.(*cacheScope); key.module == root && s.modSum != sum {
+			c.removeLocked(s)
+		}
+	}
+	if c.modSums == nil {
+		c.modSums = make(map[string]string)
+	}
+	c.modSums[root] = sum
+}
+
+// Invalidate discards the cached types of the packages that belong to the
+// module enclosing dir, such as after their source files change. The
+// module's dependencies and the standard library remain cached.
+func (c *ImporterCache) Invalidate(dir string) {
+	root := moduleDir(dir)
+	if root == "" {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return
+	}
+	modPath := modfile.ModulePath(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.scopes {
+		if key.module != root {
+			continue
+		}
+		s := e.Value.(*cacheScope)
+		for p := range s.packages {
+			if p == modPath || strings.HasPrefix(p, modPath+"/") {
+				delete(s.packages, p)
+				c.size--
+			}
+		}
+		for p := range s.exports {
+			if p == modPath || strings.HasPrefix(p, modPath+"/") {
+				delete(s.exports, p)
+			}
+		}
+	}
+}
+
+// hashFiles returns a digest of the names and contents of files. Missing
+// files contribute only their names.
+func hashFiles(files ...string) string {
+	h := sha256.New()
+	for _, f := range files {
+		io.WriteString(h, f)
+		h.Write([]byte{0})
+		if data, err := os.ReadFile(f); err == nil {
+			h.Write(data)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// configKey describes the parts of a build configuration that affect export
+// data.
+func configKey(ctxt *build.Context) string {
+	return fmt.Sprintf("%s/%s cgo=%t tags=%s", ctxt.GOOS, ctxt.GOARCH, ctxt.CgoEnabled, strings.Join(ctxt.BuildTags, ","))
+}
+
+// cachedImporter is a types.Importer that loads packages through an
+// ImporterCache.
+type cachedImporter struct {
+	cache  *ImporterCache
+	dir    string
+	ctxt   *build.Context
+	std    scopeKey
+	module scopeKey
+}
+
+func (imp *cachedImporter) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	c := imp.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	std := c.scope(imp.std)
+	mod := c.scope(imp.module)
+	own := mod
+	if isStdImport(path) {
+		own = std
+	}
+	if pkg, ok := own.packages[path]; ok && pkg.Complete() {
+		return pkg, nil
+	}
+
+	export, ok := own.exports[path]
+	if !ok {
+		if err := imp.listLocked(std, mod, path); err != nil {
+			return nil, err
+		}
+		if export, ok = own.exports[path]; !ok {
+			return nil, fmt.Errorf("no export data for %q", path)
+		}
+	}
+
+	// Export data refers to the packages it depends on by path. Read it with
+	// a view of both scopes and then record each package in its own scope.
+	packages := make(map[string]*types.Package, len(std.packages)+len(mod.packages))
+	for p, pkg := range std.packages {
+		packages[p] = pkg
+	}
+	for p, pkg := range mod.packages {
+		packages[p] = pkg
+	}
+	pkg, err := readExportData(c.FileSet, packages, path, export)
+	if err != nil {
+		return nil, err
+	}
+	for p, pkg := range packages {
+		s := mod
+		if isStdImport(p) {
+			s = std
+		}
+		if _, ok := s.packages[p]; !ok {
+			s.packages[p] = pkg
+			c.size++
+		}
+	}
+
+	c.evictLocked(std, mod)
+	return pkg, nil
+}
+
+// list locates the export data of the packages with the given paths and their
+// dependencies.
+func (imp *cachedImporter) list(paths ...string) error {
+	c := imp.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return imp.listLocked(c.scope(imp.std), c.scope(imp.module), paths...)
+}
+
+func (imp *cachedImporter) listLocked(std, mod *cacheScope, paths ...string) error {
+	exports, err := listExports(imp.dir, imp.ctxt, paths...)
+	if err != nil {
+		return err
+	}
+	for p, export := range exports {
+		if isStdImport(p) {
+			std.exports[p] = export
+		} else {
+			mod.exports[p] = export
+		}
+	}
+	return nil
+}
+
+// scope returns the scope for key, creating it if necessary, and marks it as
+// the most recently used.
+func (c *ImporterCache) scope(key scopeKey) *cacheScope {
+	if e, ok := c.scopes[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*cacheScope)
+	}
+	s := &cacheScope{
+		key:      key,
+		modSum:   c.modSums[key.module],
+		packages: make(map[string]*types.Package),
+		exports:  make(map[string]string),
+	}
+	c.scopes[key] = c.lru.PushFront(s)
+	return s
+}
+
+// evictLocked removes the least recently used scopes until the cache is
+// within its bound. The scopes in keep are never evicted.
+func (c *ImporterCache) evictLocked(keep ...*cacheScope) {
+	for c.size > c.max {
+		victim := c.victimLocked(keep)
+		if victim == nil {
+			return
+		}
+		if victim.key.module == "" {
+			// Modules share type identity with the standard library so
+			// they cannot outlive it.
+			for _, e := range c.scopes {
+				if s := e.Value.(*cacheScope); s != victim && s.key.config == victim.key.config {
+					c.removeLocked(s)
+				}
+			}
+		}
+		c.removeLocked(victim)
+	}
+}
+
+// victimLocked returns the least recently used scope that can be evicted
+// without evicting any of the scopes in keep, or nil if there is none.
+func (c *ImporterCache) victimLocked(keep []*cacheScope) *cacheScope {
+	for e := c.lru.Back(); e != nil; e = e.Prev() {
+		s := e.Value.(*cacheScope)
+		if containsScope(keep, s) {
+			continue
+		}
+		if s.key.module == "" && sharesConfig(keep, s) {
+			continue
+		}
+		return s
+	}
+	return nil
+}
+
+// sharesConfig reports whether any of scopes has the build configuration of s.
+func sharesConfig(scopes []*cacheScope, s *cacheScope) bool {
+	for _, k := range scopes {
+		if k.key.config == s.key.config {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ImporterCache) removeLocked(s *cacheScope) {
+	c.size -= len(s.packages)
+	c.lru.Remove(c.scopes[s.key])
+	delete(c.scopes, s.key)
+}
+
+func containsScope(scopes []*cacheScope, s *cacheScope) bool {
+	for _, k := range scopes {
+		if k == s {
+			return true
+		}
+	}
+	return false
+}
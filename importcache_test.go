@@ -0,0 +1,73 @@
+package gen
+
+import (
+	"go/types"
+	"testing"
+)
+
+func TestImporterCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	src := "package p\n\nimport (\n\t\"time\"\n\n\t\"example.com/p/dep\"\n)\n\nvar X = dep.Wait(time.Second)\n"
+	dep := "package dep\n\nimport \"time\"\n\nfunc Wait(d time.Duration) time.Duration { return d }\n"
+	dir1 := writeTestModule(t, map[string]string{"p.go": src, "dep/dep.go": dep})
+	dir2 := writeTestModule(t, map[string]string{"p.go": src, "dep/dep.go": dep})
+
+	imported := func(fs *FileSet, path string) *types.Package {
+		t.Helper()
+		for _, pkg := range fs.Package.Imports() {
+			if pkg.Path() == path {
+				return pkg
+			}
+		}
+		t.Fatalf("package %s not imported", path)
+		return nil
+	}
+
+	c := NewImporterCache(0)
+	fs1, err := FileSetFromDir(dir1, WithImporterCache(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fs1again, err := FileSetFromDir(dir1, WithImporterCache(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fs2, err := FileSetFromDir(dir2, WithImporterCache(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if imported(fs1, "time") != imported(fs2, "time") {
+		t.Errorf("standard library package not shared between modules")
+	}
+	if imported(fs1, "example.com/p/dep") != imported(fs1again, "example.com/p/dep") {
+		t.Errorf("dependency not shared within a module")
+	}
+	if imported(fs1, "example.com/p/dep") == imported(fs2, "example.com/p/dep") {
+		t.Errorf("dependency shared between modules")
+	}
+	if c.Len() == 0 {
+		t.Errorf("cache is empty")
+	}
+
+	// A cache too small to hold both modules evicts the least recently used
+	// module but keeps the standard library it shares with the other.
+	small := NewImporterCache(1)
+	if _, err := FileSetFromDir(dir1, WithImporterCache(small)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := FileSetFromDir(dir2, WithImporterCache(small)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := len(small.scopes), 2; got != want {
+		t.Errorf("got %d cached scopes, wanted %d", got, want)
+	}
+	for key := range small.scopes {
+		if key.module == moduleDir(dir1) {
+			t.Errorf("least recently used module was not evicted")
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+// newBaseImporter creates the importer used to resolve the imports of the
+// package according to the FileSet's import mode.
+func (fs *FileSet) newBaseImporter() types.Importer {
+	dir := fs.importDir
+	if dir == "" {
+		dir = fs.Dir
+		if fs.fsys != nil {
+			dir = currentDir
+		}
+	}
+
+	switch {
+	case fs.opts.importMode == ImportSource,
+		fs.opts.importMode == ImportAuto && fs.opts.overlaysOutside(fs.Dir):
+		return newSourceImporter(fs.FileSet, dir, fs.opts.buildContext())
+	case fs.opts.importMode == ImportExportData:
+		if fs.opts.cache != nil {
+			return fs.opts.cache.importer(dir, fs.opts.buildContext())
+		}
+		return newGoListImporter(fs.FileSet, dir, fs.opts.buildContext())
+	}
+
+	paths := fs.importPaths()
+	if c := fs.opts.cache; c != nil {
+		imp := c.importer(dir, fs.opts.buildContext())
+		if len(paths) > 0 {
+			if err := imp.list(paths...); err != nil {
+				return newSourceImporter(fs.FileSet, dir, fs.opts.buildContext())
+			}
+		}
+		return imp
+	}
+
+	if fs.importDir == "" && !fs.opts.customBuild() {
+		stdOnly := true
+		for _, p := range paths {
+			if !isStdImport(p) {
+				stdOnly = false
+				break
+			}
+		}
+		if stdOnly {
+			return importer.Default()
+		}
+	}
+
+	// Listing the imports up front builds export data for all of the
+	// dependencies at once and detects whether it can be produced at all.
+	imp := newGoListImporter(fs.FileSet, dir, fs.opts.buildContext())
+	if len(paths) > 0 {
+		if err := imp.list(paths...); err != nil {
+			return newSourceImporter(fs.FileSet, dir, fs.opts.buildContext())
+		}
+	}
+	return imp
+}
+
+// importPaths returns the sorted, distinct paths imported by the files of
+// fs, excluding the pseudo packages unsafe and C.
+func (fs *FileSet) importPaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, f := range fs.AstFiles {
+		for _, spec := range f.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || p == "unsafe" || p == "C" || seen[p] {
+				continue
+			}
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// goListImporter is a types.Importer that uses the go command to build and
+// locate export data for packages using a specific build configuration.
+type goListImporter struct {
+	fset     *token.FileSet
+	dir      string
+	ctxt     *build.Context
+	packages map[string]*types.Package
+	exports  map[string]string
+}
+
+func newGoListImporter(fset *token.FileSet, dir string, ctxt *build.Context) *goListImporter {
+	return &goListImporter{
+		fset:     fset,
+		dir:      dir,
+		ctxt:     ctxt,
+		packages: make(map[string]*types.Package),
+		exports:  make(map[string]string),
+	}
+}
+
+func (imp *goListImporter) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if pkg, ok := imp.packages[path]; ok && pkg.Complete() {
+		return pkg, nil
+	}
+
+	export, ok := imp.exports[path]
+	if !ok {
+		if err := imp.list(path); err != nil {
+			return nil, err
+		}
+		export, ok = imp.exports[path]
+		if !ok {
+			return nil, fmt.Errorf("no export data for %q", path)
+		}
+	}
+	return readExportData(imp.fset, imp.packages, path, export)
+}
+
+// list runs the go command to find the export data of the packages with the
+// given paths and all of their dependencies.
+func (imp *goListImporter) list(paths ...string) error {
+	exports, err := listExports(imp.dir, imp.ctxt, paths...)
+	if err != nil {
+		return err
+	}
+	for path, export := range exports {
+		imp.exports[path] = export
+	}
+	return nil
+}
+
+// readExportData reads the package with the given path from the export data
+// file named export. Packages referred to by the export data are looked up
+// in and added to packages.
+func readExportData(fset *token.FileSet, packages map[string]*types.Package, path, export string) (*types.Package, error) {
+	f, err := os.Open(export)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := gcexportdata.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("reading export data for %q: %w", path, err)
+	}
+	return gcexportdata.Read(r, fset, packages, path)
+}
+
+// listExports runs the go command in dir to build export data for the
+// packages with the given paths and all of their dependencies using the build
+// configuration of ctxt. It returns the names of the export data files keyed
+// by import path.
+func listExports(dir string, ctxt *build.Context, paths ...string) (map[string]string, error) {
+	args := []string{"list", "-deps", "-export", "-f", "{{.ImportPath}}\t{{.Export}}"}
+	if len(ctxt.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(ctxt.BuildTags, ","))
+	}
+	args = append(args, paths...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS="+ctxt.GOOS, "GOARCH="+ctxt.GOARCH)
+	if !ctxt.CgoEnabled {
+		cmd.Env = append(cmd.Env, "CGO_ENABLED=0")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list %s: %w: %s", strings.Join(paths, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	exports := make(map[string]string)
+	s := bufio.NewScanner(&stdout)
+	for s.Scan() {
+		importPath, export, ok := strings.Cut(s.Text(), "\t")
+		if ok && export != "" {
+			exports[importPath] = export
+		}
+	}
+	return exports, s.Err()
+}
+
+// sourceImporter is a types.Importer that type checks the source code of
+// imported packages. Errors in imported packages are ignored so that the
+// declarations of packages that do not compile can still be loaded. Cgo is
+// disabled so packages with cgo and pure Go implementations use the latter.
+type sourceImporter struct {
+	fset     *token.FileSet
+	ctxt     *build.Context
+	sizes    types.Sizes
+	packages map[string]*types.Package
+}
+
+func newSourceImporter(fset *token.FileSet, dir string, ctxt *build.Context) *sourceImporter {
+	ctxt.Dir = dir
+	ctxt.CgoEnabled = false
+	return &sourceImporter{
+		fset:     fset,
+		ctxt:     ctxt,
+		sizes:    types.SizesFor("gc", ctxt.GOARCH),
+		packages: make(map[string]*types.Package),
+	}
+}
+
+// importContext locates and selects the files of the package with the given
+// import path, as ctxt.Import does. The go command is not used to locate
+// packages in modules when ctxt has file system hooks, such as those that
+// apply an overlay, so the package is located without the hooks and its
+// files are then selected with them.
+func importContext(ctxt *build.Context, path, dir string) (*build.Package, error) {
+	if ctxt.ReadDir == nil && ctxt.OpenFile == nil {
+		return ctxt.Import(path, dir, 0)
+	}
+	plain := *ctxt
+	plain.ReadDir, plain.OpenFile = nil, nil
+	found, err := plain.Import(path, dir, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	bp, err := ctxt.ImportDir(found.Dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	bp.ImportPath = found.ImportPath
+	return bp, nil
+}
+
+func (imp *sourceImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, imp.ctxt.Dir, 0)
+}
+
+func (imp *sourceImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	bp, err := importContext(imp.ctxt, path, dir)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := imp.packages[bp.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through package %q", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+	imp.packages[bp.ImportPath] = nil
+
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		filename := filepath.Join(bp.Dir, name)
+		src, err := readSource(imp.ctxt, filename)
+		if err != nil {
+			delete(imp.packages, bp.ImportPath)
+			return nil, err
+		}
+		f, err := parser.ParseFile(imp.fset, filename, src, parser.SkipObjectResolution)
+		if err != nil {
+			delete(imp.packages, bp.ImportPath)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	config := types.Config{
+		Importer:         imp,
+		Sizes:            imp.sizes,
+		IgnoreFuncBodies: true,
+		Error:            func(error) {},
+	}
+	pkg, _ := config.Check(bp.ImportPath, imp.fset, files, nil)
+	imp.packages[bp.ImportPath] = pkg
+	return pkg, nil
+}
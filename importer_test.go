@@ -0,0 +1,54 @@
+package gen
+
+import (
+	"testing"
+)
+
+func TestImportModes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":              "package p\n\nimport (\n\t\"time\"\n\n\t\"example.com/p/dep\"\n)\n\nvar X = dep.Wait(time.Second)\n",
+		"dep/dep.go":        "package dep\n\nimport \"time\"\n\nfunc Wait(d time.Duration) time.Duration { return d }\n",
+		"std/std.go":        "package std\n\nimport \"strings\"\n\nvar Y = strings.ToUpper(\"y\")\n",
+		"broken/b.go":       "package broken\n\nimport \"example.com/p/broken/bad\"\n\nvar Z = bad.Z\n",
+		"broken/bad/bad.go": "package bad\n\nvar Z int = \"not an int\"\n",
+	})
+
+	testCases := []struct {
+		dir        string
+		mode       ImportMode
+		exportData bool
+		err        bool
+	}{
+		{dir: dir, mode: ImportAuto, exportData: true},
+		{dir: dir, mode: ImportExportData, exportData: true},
+		{dir: dir, mode: ImportSource},
+		{dir: dir + "/std", mode: ImportAuto},
+		{dir: dir + "/std", mode: ImportExportData, exportData: true},
+		{dir: dir + "/broken", mode: ImportAuto},
+		{dir: dir + "/broken", mode: ImportExportData, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			fs, err := FileSetFromDir(tc.dir, WithImportMode(tc.mode))
+			if tc.err {
+				if err == nil {
+					t.Fatalf("got no error, wanted one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, exportData := fs.baseImporter().(*goListImporter)
+			if exportData != tc.exportData {
+				t.Errorf("got export data importer %v, wanted %v", exportData, tc.exportData)
+			}
+		})
+	}
+}
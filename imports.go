@@ -0,0 +1,190 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Imports tracks the packages that generated code needs to import and the
+// names used to refer to them. Conflicting names are resolved automatically by
+// assigning a unique alias to later additions.
+type Imports struct {
+	names map[string]string // import path to name used in generated code
+	paths map[string]string // name used in generated code to import path
+}
+
+// NewImports creates an empty set of imports.
+func NewImports() *Imports {
+	return &Imports{
+		names: make(map[string]string),
+		paths: make(map[string]string),
+	}
+}
+
+// Add records that generated code imports the package with the given path and
+// returns the name that should be used to qualify identifiers from it. If
+// alias is empty then a name is derived from the last element of the import
+// path. If the requested name is already used by a different package then a
+// numeric suffix is appended to make it unique. The blank identifier and "."
+// are not names and may be given as the alias of any number of packages.
+// Adding a path that has already been added returns the name assigned
+// previously.
+func (im *Imports) Add(path, alias string) string {
+	if name, ok := im.names[path]; ok {
+		return name
+	}
+
+	if alias == "" {
+		alias = defaultImportName(path)
+	}
+
+	if isSpecialImportName(alias) {
+		im.names[path] = alias
+		return alias
+	}
+
+	name := alias
+	for i := 2; ; i++ {
+		if _, used := im.paths[name]; !used {
+			break
+		}
+		name = alias + strconv.Itoa(i)
+	}
+
+	im.names[path] = name
+	im.paths[name] = path
+	return name
+}
+
+// isSpecialImportName reports whether name is the blank identifier or ".",
+// which import a package for its side effects or into the file block rather
+// than naming it.
+func isSpecialImportName(name string) bool {
+	return name == "_" || name == "."
+}
+
+// Name returns the name assigned to the package with the given import path and
+// whether the path has been added.
+func (im *Imports) Name(path string) (string, bool) {
+	name, ok := im.names[path]
+	return name, ok
+}
+
+// Len returns the number of imported packages.
+func (im *Imports) Len() int {
+	return len(im.names)
+}
+
+// Paths returns the import paths that have been added, sorted alphabetically.
+func (im *Imports) Paths() []string {
+	paths := make([]string, 0, len(im.names))
+	for p := range im.names {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Qualifier returns a types.Qualifier for rendering types in code that belongs
+// to the package local. Packages other than local are added to the imports as
+// they are encountered.
+func (im *Imports) Qualifier(local *types.Package) types.Qualifier {
+	return func(pkg *types.Package) string {
+		if pkg == local || (local != nil && pkg.Path() == local.Path()) {
+			return ""
+		}
+		return im.Add(pkg.Path(), pkg.Name())
+	}
+}
+
+// Block renders the import declaration for the added packages, grouping
+// standard library packages before all others. An alias is only written when
+// the assigned name differs from the last element of the import path, or
+// when it is the blank identifier or ".". Block
+// returns an empty string if no packages have been added.
+func (im *Imports) Block() string {
+	if len(im.names) == 0 {
+		return ""
+	}
+
+	var std, other []string
+	for _, p := range im.Paths() {
+		spec := strconv.Quote(p)
+		if name := im.names[p]; name != path.Base(p) || isSpecialImportName(name) {
+			spec = name + " " + spec
+		}
+		if isStdImport(p) {
+			std = append(std, spec)
+		} else {
+			other = append(other, spec)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, spec := range std {
+		fmt.Fprintf(&b, "\t%s\n", spec)
+	}
+	if len(std) > 0 && len(other) > 0 {
+		b.WriteString("\n")
+	}
+	for _, spec := range other {
+		fmt.Fprintf(&b, "\t%s\n", spec)
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+// defaultImportName derives a package name from an import path, ignoring any
+// major version suffix and replacing characters that are not valid in an
+// identifier.
+func defaultImportName(p string) string {
+	elems := strings.Split(p, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && strings.HasPrefix(p, "gopkg.in/") {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z'):
+			b.WriteRune(r)
+		case '0' <= r && r <= '9':
+			if b.Len() == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "pkg"
+	}
+	return b.String()
+}
+
+// isMajorVersion reports whether s is a module major version suffix such as v2.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// isStdImport reports whether p looks like the import path of a standard
+// library package.
+func isStdImport(p string) bool {
+	first, _, _ := strings.Cut(p, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,114 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestImportsAdd(t *testing.T) {
+	testCases := []struct {
+		path  string
+		alias string
+		want  string
+	}{
+		{path: "fmt", want: "fmt"},
+		{path: "fmt", alias: "other", want: "fmt"},
+		{path: "math/rand", want: "rand"},
+		{path: "crypto/rand", want: "rand2"},
+		{path: "crypto/rand", want: "rand2"},
+		{path: "github.com/x/rand", alias: "xrand", want: "xrand"},
+		{path: "gopkg.in/yaml.v3", want: "yaml"},
+		{path: "github.com/a/b/v2", want: "b"},
+		{path: "github.com/a/go-text", want: "text"},
+		{path: "example.com/my-lib", want: "my_lib"},
+	}
+
+	im := NewImports()
+	for _, tc := range testCases {
+		if got := im.Add(tc.path, tc.alias); got != tc.want {
+			t.Errorf("Add(%q, %q): got %q, wanted %q", tc.path, tc.alias, got, tc.want)
+		}
+	}
+
+	if im.Len() != 8 {
+		t.Errorf("got %d imports, wanted 8", im.Len())
+	}
+}
+
+func TestImportsBlock(t *testing.T) {
+	im := NewImports()
+	if got := im.Block(); got != "" {
+		t.Errorf("got %q for empty imports, wanted empty string", got)
+	}
+
+	im.Add("strings", "")
+	im.Add("math/rand", "")
+	im.Add("crypto/rand", "")
+	im.Add("github.com/a/b/v2", "")
+	im.Add("fmt", "")
+
+	want := `import (
+	rand2 "crypto/rand"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	b "github.com/a/b/v2"
+)
+`
+	if got := im.Block(); got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestImportsSpecialNames(t *testing.T) {
+	im := NewImports()
+	for _, p := range []string{"embed", "net/http/pprof"} {
+		if got := im.Add(p, "_"); got != "_" {
+			t.Errorf("Add(%q, \"_\"): got %q, wanted \"_\"", p, got)
+		}
+	}
+	for _, p := range []string{"math", "strings"} {
+		if got := im.Add(p, "."); got != "." {
+			t.Errorf("Add(%q, \".\"): got %q, wanted \".\"", p, got)
+		}
+	}
+	if got := im.Add("fmt", ""); got != "fmt" {
+		t.Errorf("got %q, wanted \"fmt\"", got)
+	}
+
+	want := `import (
+	_ "embed"
+	"fmt"
+	. "math"
+	_ "net/http/pprof"
+	. "strings"
+)
+`
+	if got := im.Block(); got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestImportsQualifier(t *testing.T) {
+	local := types.NewPackage("example.com/local", "local")
+	other := types.NewPackage("example.com/other", "other")
+
+	im := NewImports()
+	q := im.Qualifier(local)
+
+	typ := types.NewSlice(types.NewNamed(types.NewTypeName(0, other, "T", nil), types.Typ[types.Int], nil))
+	if got, want := types.TypeString(typ, q), "[]other.T"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	typ = types.NewSlice(types.NewNamed(types.NewTypeName(0, local, "U", nil), types.Typ[types.Int], nil))
+	if got, want := types.TypeString(typ, q), "[]U"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if got, want := im.Paths(), []string{"example.com/other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
@@ -0,0 +1,284 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InlineBudget is the maximum cost of a function that the gc compiler will
+// consider for inlining. Generators that want their output to be inlined
+// should keep small helper functions under this budget, for example by
+// splitting large switch statements across several functions with
+// SwitchFunc.
+const InlineBudget = 80
+
+// SwitchFunc describes a function whose body is a switch statement that
+// maps each case to a result, such as the String method of an enumeration
+// or a lookup by key. Its String method writes the function, splitting the
+// switch into small leaf functions when it has more than MaxCases cases so
+// that each part is cheap enough for the compiler to inline.
+type SwitchFunc struct {
+	// Decl is the declaration of the function without its body, such as
+	// "func (k Kind) String() string".
+	Decl string
+
+	// Helper is the name of the leaf functions holding the parts of a split
+	// switch, to which 1, 2 and so on are appended.
+	Helper string
+
+	// Params is the parameter list of the leaf functions, such as "k Kind",
+	// and Args the arguments the function passes to them, such as "k".
+	Params, Args string
+
+	// Result is the type of the result, such as "string".
+	Result string
+
+	// Tag is the expression switched on. It is empty for a switch whose case
+	// expressions are conditions.
+	Tag string
+
+	// Cases holds the cases of the switch in order.
+	Cases []SwitchCase
+
+	// Default is the result when no case matches.
+	Default string
+
+	// MaxCases is the largest number of cases held by one function. If it
+	// is zero, the switch is not split.
+	MaxCases int
+}
+
+// SwitchCase is a case of a SwitchFunc.
+type SwitchCase struct {
+	// Exprs is the list of case expressions, such as `"a", "b"`.
+	Exprs string
+
+	// Result is the expression returned for the case.
+	Result string
+}
+
+// String returns the source of the function and of its leaf functions,
+// separated by blank lines. When the switch is split, the function calls
+// each leaf in turn until one of them reports a match.
+func (sf *SwitchFunc) String() string {
+	var b strings.Builder
+	if sf.MaxCases <= 0 || len(sf.Cases) <= sf.MaxCases {
+		fmt.Fprintf(&b, "%s {\n", sf.Decl)
+		sf.writeSwitch(&b, sf.Cases, "")
+		fmt.Fprintf(&b, "\treturn %s\n}\n", sf.Default)
+		return b.String()
+	}
+
+	var parts [][]SwitchCase
+	for cases := sf.Cases; len(cases) > 0; {
+		n := min(sf.MaxCases, len(cases))
+		parts = append(parts, cases[:n])
+		cases = cases[n:]
+	}
+
+	fmt.Fprintf(&b, "%s {\n", sf.Decl)
+	for i := range parts {
+		fmt.Fprintf(&b, "\tif r, ok := %s%d(%s); ok {\n\t\treturn r\n\t}\n", sf.Helper, i+1, sf.Args)
+	}
+	fmt.Fprintf(&b, "\treturn %s\n}\n", sf.Default)
+	for i, cases := range parts {
+		fmt.Fprintf(&b, "\nfunc %s%d(%s) (%s, bool) {\n", sf.Helper, i+1, sf.Params, sf.Result)
+		sf.writeSwitch(&b, cases, ", true")
+		fmt.Fprintf(&b, "\tvar zero %s\n\treturn zero, false\n}\n", sf.Result)
+	}
+	return b.String()
+}
+
+// writeSwitch writes a switch over cases to b, returning the result of each
+// case followed by suffix.
+func (sf *SwitchFunc) writeSwitch(b *strings.Builder, cases []SwitchCase, suffix string) {
+	if sf.Tag == "" {
+		b.WriteString("\tswitch {\n")
+	} else {
+		fmt.Fprintf(b, "\tswitch %s {\n", sf.Tag)
+	}
+	for _, c := range cases {
+		fmt.Fprintf(b, "\tcase %s:\n\t\treturn %s%s\n", c.Exprs, c.Result, suffix)
+	}
+	b.WriteString("\t}\n")
+}
+
+// InlineCost is the compiler's assessment of whether a function can be
+// inlined.
+type InlineCost struct {
+	// Func is the name of the function. Methods are named using their
+	// receiver's base type, such as "T.Len". Function literals are named
+	// after their enclosing function with a suffix, such as "F.func1".
+	Func string
+
+	// Pos is the position of the function declaration. The filename is
+	// absolute.
+	Pos token.Position
+
+	// CanInline reports whether the compiler can inline the function.
+	CanInline bool
+
+	// Cost is the inlining cost computed by the compiler. It is zero if the
+	// function could not be inlined for a reason unrelated to its size.
+	Cost int
+
+	// Reason holds the compiler's explanation when the function cannot be
+	// inlined.
+	Reason string
+}
+
+var (
+	canInlineRx    = regexp.MustCompile(`^can inline (\S+) with cost (\d+)`)
+	cannotInlineRx = regexp.MustCompile(`^cannot inline (\S+): (.*)$`)
+	tooComplexRx   = regexp.MustCompile(`cost (\d+) exceeds budget`)
+)
+
+// InlineCosts runs the compiler's inlining analysis on the package in dir and
+// returns the cost of each function it reports. It requires the go command and
+// a package that builds successfully.
+func InlineCosts(dir string) ([]InlineCost, error) {
+	diags, err := compilerDiagnostics(dir, "-m=2")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var costs []InlineCost
+	for _, d := range diags {
+		var c InlineCost
+		if m := canInlineRx.FindStringSubmatch(d.Message); m != nil {
+			c.Func = compilerFuncName(m[1])
+			c.CanInline = true
+			c.Cost, _ = strconv.Atoi(m[2])
+		} else if m := cannotInlineRx.FindStringSubmatch(d.Message); m != nil {
+			c.Func = compilerFuncName(m[1])
+			c.Reason = m[2]
+			if cm := tooComplexRx.FindStringSubmatch(m[2]); cm != nil {
+				c.Cost, _ = strconv.Atoi(cm[1])
+			}
+		} else {
+			continue
+		}
+
+		// Generic functions are reported once per shape; keep the first.
+		key := d.Pos.String() + " " + c.Func
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		c.Pos = d.Pos
+		costs = append(costs, c)
+	}
+	return costs, nil
+}
+
+// compilerFuncName converts a function name printed by the compiler into the
+// form used by gen, removing pointer receiver notation and type arguments.
+func compilerFuncName(name string) string {
+	if i := strings.Index(name, "["); i >= 0 {
+		if j := strings.LastIndex(name, "]"); j > i {
+			name = name[:i] + name[j+1:]
+		}
+	}
+	if strings.HasPrefix(name, "(*") {
+		if i := strings.Index(name, ")"); i > 0 {
+			name = name[2:i] + name[i+1:]
+		}
+	}
+	return name
+}
+
+// inlineCostRx matches an inline cost annotation written by
+// AnnotateInlineCosts.
+var inlineCostRx = regexp.MustCompile(`(?m)^[ \t]*//go:inline-cost \d+\n`)
+
+// AnnotateInlineCosts runs the compiler's inlining analysis on the package
+// containing the generated file filename and rewrites the file so that each
+// function declaration is preceded by a //go:inline-cost comment holding its
+// inlining cost. Existing annotations are replaced. The file must carry the
+// generated code header.
+func AnnotateInlineCosts(filename string) error {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !IsGenerated(src) {
+		return fmt.Errorf("%s: %w", filename, ErrNotGenerated)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	costs, err := InlineCosts(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]int)
+	for _, c := range costs {
+		if c.Cost > 0 && sameFile(c.Pos.Filename, filename) {
+			byName[c.Func] = c.Cost
+		}
+	}
+
+	src = inlineCostRx.ReplaceAll(src, nil)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	tf := fset.File(f.Pos())
+
+	var out []byte
+	last := 0
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		cost, ok := byName[declFuncName(fd)]
+		if !ok {
+			continue
+		}
+		offset := tf.Offset(tf.LineStart(tf.Line(fd.Type.Func)))
+		out = append(out, src[last:offset]...)
+		out = append(out, fmt.Sprintf("//go:inline-cost %d\n", cost)...)
+		last = offset
+	}
+	out = append(out, src[last:]...)
+
+	return writeFileAtomic(filename, out, info.Mode().Perm())
+}
+
+// declFuncName returns the name of a declared function in the form used by
+// gen, such as "F" or "T.M".
+func declFuncName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	if id := embeddedIdent(fd.Recv.List[0].Type); id != nil {
+		return id.Name + "." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
+
+// sameFile reports whether a and b name the same file.
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ia, ib)
+}
@@ -0,0 +1,165 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCompilerFuncName(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "F", want: "F"},
+		{name: "(*T).Len", want: "T.Len"},
+		{name: "T.Cap", want: "T.Cap"},
+		{name: "Sum[go.shape.int]", want: "Sum"},
+		{name: "(*List[go.shape.int]).Push", want: "List.Push"},
+		{name: "Big.func1", want: "Big.func1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := compilerFuncName(tc.name); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+const inlineTestSrc = `// Code generated by test; DO NOT EDIT.
+
+package p
+
+type T struct{ b []byte }
+
+// Len returns the length.
+func (t *T) Len() int { return len(t.b) }
+
+func Big(x int) int {
+	switch x {
+	case 1:
+		return x * 2
+	case 2:
+		return x * 3
+	}
+	for i := 0; i < x; i++ {
+		x += i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i
+		x += i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i * i
+	}
+	return x
+}
+`
+
+func TestAnnotateInlineCosts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{"p_gen.go": inlineTestSrc})
+	filename := filepath.Join(dir, "p_gen.go")
+
+	costs, err := InlineCosts(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byName := map[string]InlineCost{}
+	for _, c := range costs {
+		byName[c.Func] = c
+	}
+	if c := byName["T.Len"]; !c.CanInline || c.Cost == 0 || c.Cost > InlineBudget {
+		t.Errorf("unexpected cost for T.Len: %+v", c)
+	}
+	if c := byName["Big"]; c.CanInline || c.Cost <= InlineBudget {
+		t.Errorf("unexpected cost for Big: %+v", c)
+	}
+
+	// Annotating twice should produce the same result
+	for i := 0; i < 2; i++ {
+		if err := AnnotateInlineCosts(filename); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := strings.Count(string(src), "//go:inline-cost"); n != 2 {
+		t.Errorf("got %d annotations, wanted 2:\n%s", n, src)
+	}
+	if !strings.Contains(string(src), "// Len returns the length.\n//go:inline-cost ") {
+		t.Errorf("annotation not placed after doc comment:\n%s", src)
+	}
+
+	// The annotated file must still build
+	if _, err := InlineCosts(dir); err != nil {
+		t.Errorf("annotated file does not build: %v", err)
+	}
+}
+
+func TestAnnotateInlineCostsNotGenerated(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{"p.go": "package p\n"})
+	if err := AnnotateInlineCosts(filepath.Join(dir, "p.go")); err == nil {
+		t.Errorf("got no error for file without generated header")
+	}
+}
+
+func TestSwitchFunc(t *testing.T) {
+	sf := SwitchFunc{
+		Decl:    "func (k Kind) String() string",
+		Helper:  "kindString",
+		Params:  "k Kind",
+		Args:    "k",
+		Result:  "string",
+		Tag:     "k",
+		Default: `"unknown"`,
+	}
+	for i := 0; i < 40; i++ {
+		sf.Cases = append(sf.Cases, SwitchCase{Exprs: strconv.Itoa(i), Result: strconv.Quote("k" + strconv.Itoa(i))})
+	}
+
+	whole := sf.String()
+	if strings.Contains(whole, "kindString") {
+		t.Errorf("switch was split without MaxCases:\n%s", whole)
+	}
+
+	sf.MaxCases = 8
+	split := sf.String()
+	for i := 1; i <= 5; i++ {
+		if !strings.Contains(split, fmt.Sprintf("func kindString%d(k Kind) (string, bool) {", i)) {
+			t.Errorf("missing leaf function kindString%d:\n%s", i, split)
+		}
+	}
+	if strings.Contains(split, "kindString6") {
+		t.Errorf("unexpected leaf function kindString6:\n%s", split)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	src := "// Code generated by test; DO NOT EDIT.\n\npackage p\n\ntype Kind int\n\n" + split
+	dir := writeTestModule(t, map[string]string{"p_gen.go": src})
+	costs, err := InlineCosts(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leaves := 0
+	for _, c := range costs {
+		if !strings.HasPrefix(c.Func, "kindString") {
+			continue
+		}
+		leaves++
+		if !c.CanInline || c.Cost > InlineBudget {
+			t.Errorf("leaf function cannot be inlined: %+v", c)
+		}
+	}
+	if leaves != 5 {
+		t.Errorf("got costs for %d leaf functions, wanted 5: %+v", leaves, costs)
+	}
+}
@@ -0,0 +1,127 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+)
+
+// Instantiation describes a generic type instantiated with concrete type
+// arguments, such as Set[int] for the generic type Set. The types of its
+// fields and the signatures of its methods have the type arguments
+// substituted for the type parameters, so generators can emit code for the
+// instantiated type without substituting type parameter names textually.
+type Instantiation struct {
+	// Generic is the model of the generic type that was instantiated.
+	Generic *TypeModel
+
+	// TypeArgs holds the type arguments, in the order of the type parameters.
+	TypeArgs []types.Type
+
+	// Type is the instantiated type.
+	Type *types.Named
+
+	// Fields holds the fields of a struct type in declaration order with
+	// their types substituted. Other details, such as doc comments and tags,
+	// are those of the generic type's fields. It is empty for other kinds of
+	// type.
+	Fields []*FieldModel
+
+	// Methods holds the methods that may be called on a pointer to the
+	// instantiated type, sorted by name, with their signatures substituted.
+	Methods []*MethodModel
+}
+
+// TypeArgList returns the type arguments written as the type argument list
+// of the instantiation, such as "[string, int]", using q to qualify the names
+// of types.
+func (in *Instantiation) TypeArgList(q types.Qualifier) string {
+	s := "["
+	for i, t := range in.TypeArgs {
+		if i > 0 {
+			s += ", "
+		}
+		s += types.TypeString(t, q)
+	}
+	return s + "]"
+}
+
+// Instantiate instantiates the named generic type with typeArgs. It reports
+// an error if the type is not declared, is not generic, or if the type
+// arguments do not satisfy the type parameters' constraints.
+func (fs *FileSet) Instantiate(name string, typeArgs ...types.Type) (*Instantiation, error) {
+	tm, ok := fs.Type(name)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	if len(tm.TypeParams) == 0 {
+		return nil, fmt.Errorf("type %s is not generic", name)
+	}
+	if len(typeArgs) != len(tm.TypeParams) {
+		return nil, fmt.Errorf("type %s: got %d type arguments, wanted %d", name, len(typeArgs), len(tm.TypeParams))
+	}
+
+	t, err := types.Instantiate(nil, tm.Object.Type(), typeArgs, true)
+	if err != nil {
+		return nil, fmt.Errorf("instantiate %s: %w", name, err)
+	}
+	named := t.(*types.Named)
+
+	in := &Instantiation{
+		Generic:  tm,
+		TypeArgs: typeArgs,
+		Type:     named,
+		Methods:  fs.methodModels(types.NewMethodSet(types.NewPointer(named))),
+	}
+
+	if st, ok := named.Underlying().(*types.Struct); ok {
+		generic := make(map[string]*FieldModel, len(tm.Fields))
+		for _, f := range tm.Fields {
+			generic[f.Name] = f
+		}
+		fields := make([]FieldModel, 0, st.NumFields())
+		for i := 0; i < st.NumFields(); i++ {
+			v := st.Field(i)
+			f := FieldModel{
+				Name:     v.Name(),
+				Type:     v.Type(),
+				Embedded: v.Embedded(),
+				Exported: v.Exported(),
+				Tag:      st.Tag(i),
+				Object:   v,
+			}
+			if g, ok := generic[v.Name()]; ok {
+				f.Doc, f.Comment, f.Tags, f.Field = g.Doc, g.Comment, g.Tags, g.Field
+			}
+			fields = append(fields, f)
+			in.Fields = append(in.Fields, &fields[i])
+		}
+	}
+
+	return in, nil
+}
+
+// EvalType evaluates the type expression expr, such as "map[string]int" or
+// "[]time.Duration", in the scope of the package. Packages referred to by
+// expr must be imported by one of the package's files, and expr is evaluated
+// in the scope of the first file that can resolve it.
+func (fs *FileSet) EvalType(expr string) (types.Type, error) {
+	pos := []token.Pos{token.NoPos}
+	for _, f := range fs.AstFiles {
+		pos = append(pos, f.Name.Pos())
+	}
+
+	var err error
+	for _, p := range pos {
+		var tv types.TypeAndValue
+		tv, err = types.Eval(fs.FileSet, fs.Package, p, expr)
+		if err != nil {
+			continue
+		}
+		if !tv.IsType() {
+			return nil, fmt.Errorf("%s is not a type", expr)
+		}
+		return tv.Type, nil
+	}
+	return nil, fmt.Errorf("evaluate type %q: %w", expr, err)
+}
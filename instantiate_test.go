@@ -0,0 +1,120 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestInstantiate(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "time"
+
+		type Pair[K comparable, V any] struct {
+			// Key is the key.
+			Key   K ` + "`json:\"key\"`" + `
+			Value V
+			Next  *Pair[K, V]
+			At    time.Time
+		}
+
+		func (p *Pair[K, V]) Set(v V) *Pair[K, V] { p.Value = v; return p }
+
+		func (p Pair[K, V]) Get() (K, V) { return p.Key, p.Value }
+
+		type Plain struct{}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+
+	str, err := fs.EvalType("string")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dur, err := fs.EvalType("[]time.Duration")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	in, err := fs.Instantiate("Pair", str, dur)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := types.TypeString(in.Type, q), "Pair[string, []time.Duration]"; got != want {
+		t.Errorf("got type %q, wanted %q", got, want)
+	}
+	if got, want := in.TypeArgList(q), "[string, []time.Duration]"; got != want {
+		t.Errorf("got type args %q, wanted %q", got, want)
+	}
+
+	fields := []string{}
+	for _, f := range in.Fields {
+		fields = append(fields, f.Name+" "+types.TypeString(f.Type, q))
+	}
+	wantFields := []string{"Key string", "Value []time.Duration", "Next *Pair[string, []time.Duration]", "At time.Time"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("got fields %+v, wanted %+v", fields, wantFields)
+	}
+	if got, want := in.Fields[0].Doc, "Key is the key.\n"; got != want {
+		t.Errorf("got doc %q, wanted %q", got, want)
+	}
+	if got, want := in.Fields[0].Tag, `json:"key"`; got != want {
+		t.Errorf("got tag %q, wanted %q", got, want)
+	}
+
+	methods := []string{}
+	for _, m := range in.Methods {
+		methods = append(methods, m.Name+" "+types.TypeString(m.Signature(), q))
+	}
+	wantMethods := []string{
+		"Get func() (string, []time.Duration)",
+		"Set func(v []time.Duration) *Pair[string, []time.Duration]",
+	}
+	if !reflect.DeepEqual(methods, wantMethods) {
+		t.Errorf("got methods %+v, wanted %+v", methods, wantMethods)
+	}
+}
+
+func TestInstantiateErrors(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type Set[T comparable] map[T]struct{}
+
+		type Plain struct{}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fn, err := fs.EvalType("func()")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	integer := types.Typ[types.Int]
+
+	testCases := []struct {
+		name string
+		args []types.Type
+	}{
+		{name: "Missing", args: []types.Type{integer}},
+		{name: "Plain", args: []types.Type{integer}},
+		{name: "Set", args: []types.Type{integer, integer}},
+		{name: "Set", args: []types.Type{fn}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := fs.Instantiate(tc.name, tc.args...); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+
+	if _, err := fs.EvalType("Missing"); err == nil {
+		t.Errorf("got no error evaluating undeclared type, wanted one")
+	}
+}
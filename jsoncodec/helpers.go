@@ -0,0 +1,358 @@
+package jsoncodec
+
+// helperImports holds the import paths used by helpers.
+var helperImports = []string{"fmt", "math", "strconv", "unicode/utf16", "unicode/utf8"}
+
+// helpers holds the encoding and decoding functions shared by the generated
+// methods. Strings and floating point numbers are encoded exactly as
+// encoding/json encodes them.
+const helpers = `
+// jsoncodecHex holds the hexadecimal digits used in escapes.
+const jsoncodecHex = "0123456789abcdef"
+
+// jsoncodecAppendString appends s to b as a JSON string, escaping the
+// characters that encoding/json escapes.
+func jsoncodecAppendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', jsoncodecHex[c>>4], jsoncodecHex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', jsoncodecHex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// jsoncodecAppendFloat appends f to b as a JSON number, formatted for the
+// given bit size.
+func jsoncodecAppendFloat(b []byte, f float64, bits int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// Shorten an exponent such as e-07 to e-7.
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+// jsoncodecDecoder reads JSON values from data.
+type jsoncodecDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *jsoncodecDecoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("json: "+format+" at offset %d", append(args, d.pos)...)
+}
+
+// peek skips white space and returns the next byte, or zero at the end of
+// the data.
+func (d *jsoncodecDecoder) peek() byte {
+	for d.pos < len(d.data) {
+		switch c := d.data[d.pos]; c {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// unexpected returns an error for the next byte, which does not begin a
+// value of the wanted kind.
+func (d *jsoncodecDecoder) unexpected(want string) error {
+	if d.peek() == 0 {
+		return d.errorf("unexpected end of input reading %s", want)
+	}
+	return d.errorf("invalid character %q reading %s", d.data[d.pos], want)
+}
+
+// end returns an error if anything but white space follows the value read.
+func (d *jsoncodecDecoder) end() error {
+	if d.peek() != 0 {
+		return d.errorf("invalid character %q after top-level value", d.data[d.pos])
+	}
+	return nil
+}
+
+// literal consumes s if it is next.
+func (d *jsoncodecDecoder) literal(s string) bool {
+	if d.peek() != s[0] || len(d.data)-d.pos < len(s) || string(d.data[d.pos:d.pos+len(s)]) != s {
+		return false
+	}
+	d.pos += len(s)
+	return true
+}
+
+// null consumes null if it is next.
+func (d *jsoncodecDecoder) null() bool {
+	return d.literal("null")
+}
+
+func (d *jsoncodecDecoder) boolean() (bool, error) {
+	switch {
+	case d.literal("true"):
+		return true, nil
+	case d.literal("false"):
+		return false, nil
+	}
+	return false, d.unexpected("boolean")
+}
+
+// number returns the text of the next number.
+func (d *jsoncodecDecoder) number() (string, error) {
+	d.peek()
+	start := d.pos
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		if c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			d.pos++
+			continue
+		}
+		break
+	}
+	if d.pos == start {
+		return "", d.unexpected("number")
+	}
+	return string(d.data[start:d.pos]), nil
+}
+
+// str returns the next string with its escapes decoded.
+func (d *jsoncodecDecoder) str() (string, error) {
+	if d.peek() != '"' {
+		return "", d.unexpected("string")
+	}
+	d.pos++
+	start := d.pos
+	for d.pos < len(d.data) {
+		switch c := d.data[d.pos]; {
+		case c == '"':
+			s := string(d.data[start:d.pos])
+			d.pos++
+			return s, nil
+		case c == '\\':
+			return d.escaped(append([]byte(nil), d.data[start:d.pos]...))
+		case c < 0x20:
+			return "", d.errorf("invalid character %q in string", c)
+		}
+		d.pos++
+	}
+	return "", d.errorf("unexpected end of input reading string")
+}
+
+// escaped returns the rest of a string that contains escapes, appended to
+// the part already read.
+func (d *jsoncodecDecoder) escaped(b []byte) (string, error) {
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return string(b), nil
+		case c < 0x20:
+			return "", d.errorf("invalid character %q in string", c)
+		case c != '\\':
+			b = append(b, c)
+			d.pos++
+			continue
+		}
+		d.pos++
+		if d.pos == len(d.data) {
+			break
+		}
+		c = d.data[d.pos]
+		d.pos++
+		switch c {
+		case '"', '\\', '/':
+			b = append(b, c)
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'u':
+			r, ok := d.hex4()
+			if !ok {
+				return "", d.errorf("invalid unicode escape in string")
+			}
+			if utf16.IsSurrogate(r) {
+				r2 := utf8.RuneError
+				if d.literal("\\u") {
+					if r2, ok = d.hex4(); !ok {
+						return "", d.errorf("invalid unicode escape in string")
+					}
+				}
+				r = utf16.DecodeRune(r, r2)
+			}
+			b = utf8.AppendRune(b, r)
+		default:
+			return "", d.errorf("invalid escape %q in string", c)
+		}
+	}
+	return "", d.errorf("unexpected end of input reading string")
+}
+
+// hex4 reads the four hexadecimal digits of a unicode escape.
+func (d *jsoncodecDecoder) hex4() (rune, bool) {
+	if len(d.data)-d.pos < 4 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(string(d.data[d.pos:d.pos+4]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	d.pos += 4
+	return rune(n), true
+}
+
+// object reads an object, calling f for each member with the decoder
+// positioned at the member's value. f must read the value.
+func (d *jsoncodecDecoder) object(f func(key string) error) error {
+	if d.peek() != '{' {
+		return d.unexpected("object")
+	}
+	d.pos++
+	if d.peek() == '}' {
+		d.pos++
+		return nil
+	}
+	for {
+		key, err := d.str()
+		if err != nil {
+			return err
+		}
+		if d.peek() != ':' {
+			return d.unexpected("object")
+		}
+		d.pos++
+		if err := f(key); err != nil {
+			return err
+		}
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case '}':
+			d.pos++
+			return nil
+		default:
+			return d.unexpected("object")
+		}
+	}
+}
+
+// array reads an array, calling f with the decoder positioned at each
+// element. f must read the element.
+func (d *jsoncodecDecoder) array(f func() error) error {
+	if d.peek() != '[' {
+		return d.unexpected("array")
+	}
+	d.pos++
+	if d.peek() == ']' {
+		d.pos++
+		return nil
+	}
+	for {
+		if err := f(); err != nil {
+			return err
+		}
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case ']':
+			d.pos++
+			return nil
+		default:
+			return d.unexpected("array")
+		}
+	}
+}
+
+// raw reads the next value and returns its text.
+func (d *jsoncodecDecoder) raw() ([]byte, error) {
+	var err error
+	c := d.peek()
+	start := d.pos
+	switch c {
+	case '{':
+		err = d.object(func(string) error { return d.skip() })
+	case '[':
+		err = d.array(d.skip)
+	case '"':
+		_, err = d.str()
+	case 't', 'f':
+		_, err = d.boolean()
+	case 'n':
+		if !d.null() {
+			err = d.unexpected("value")
+		}
+	default:
+		_, err = d.number()
+	}
+	return d.data[start:d.pos], err
+}
+
+// skip reads and discards the next value.
+func (d *jsoncodecDecoder) skip() error {
+	_, err := d.raw()
+	return err
+}
+`
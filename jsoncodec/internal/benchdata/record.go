@@ -0,0 +1,21 @@
+// Package benchdata holds a type with methods generated by jsoncodec, for
+// benchmarking them against encoding/json. The methods are in record_json.go,
+// which is checked against the generator by the tests of jsoncodec.
+package benchdata
+
+import "time"
+
+// Record is a typical API payload.
+type Record struct {
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Email    string            `json:"email,omitempty"`
+	Active   bool              `json:"active"`
+	Score    float64           `json:"score"`
+	Tags     []string          `json:"tags"`
+	Counts   []int32           `json:"counts"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Parent   *int64            `json:"parent"`
+	Created  time.Time         `json:"created"`
+	Checksum []byte            `json:"checksum"`
+}
@@ -0,0 +1,106 @@
+package benchdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// plainRecord has the fields of Record without its methods, so that
+// encoding/json encodes it by reflection.
+type plainRecord Record
+
+func newRecord() Record {
+	parent := int64(41)
+	return Record{
+		ID:       42,
+		Name:     "Ada Lovelace <ada@example.com>",
+		Email:    "ada@example.com",
+		Active:   true,
+		Score:    98.625,
+		Tags:     []string{"admin", "founder", "math"},
+		Counts:   []int32{1, 2, 3, 5, 8, 13},
+		Attrs:    map[string]string{"lang": "en", "tz": "Europe/London", "team": "engines"},
+		Parent:   &parent,
+		Created:  time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		Checksum: []byte{0xde, 0xad, 0xbe, 0xef},
+	}
+}
+
+func TestMarshalMatchesEncodingJSON(t *testing.T) {
+	r := newRecord()
+	got, err := r.MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := json.Marshal(plainRecord(r))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+
+	var decoded Record
+	if err := decoded.UnmarshalJSON(want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := decoded.MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(again, want) {
+		t.Errorf("got %s after decoding, wanted %s", again, want)
+	}
+}
+
+func BenchmarkMarshalGenerated(b *testing.B) {
+	r := newRecord()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.MarshalJSON(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalEncodingJSON(b *testing.B) {
+	r := plainRecord(newRecord())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalGenerated(b *testing.B) {
+	data, err := json.Marshal(plainRecord(newRecord()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var r Record
+		if err := r.UnmarshalJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalEncodingJSON(b *testing.B) {
+	data, err := json.Marshal(plainRecord(newRecord()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var r plainRecord
+		if err := json.Unmarshal(data, &r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,618 @@
+// Package jsoncodec generates MarshalJSON and UnmarshalJSON methods for struct
+// types that encode and decode JSON without reflection, in the style of
+// github.com/mailru/easyjson.
+//
+// The generated methods follow the conventions of encoding/json: fields are
+// named by their json struct tags, fields tagged "-" and unexported fields
+// are skipped, the omitempty option leaves out empty values and the fields of
+// embedded structs are promoted. Values are encoded as encoding/json encodes
+// them, with map keys sorted, byte slices in base64 and nil slices, maps and
+// pointers as null. Types with MarshalJSON and UnmarshalJSON or MarshalText
+// and UnmarshalText methods, such as time.Time, are encoded by calling them.
+//
+// Decoding differs from encoding/json in that object keys must match field
+// names exactly rather than ignoring case. Unknown keys are skipped and null
+// leaves a field unchanged unless it is a pointer, slice or map, which is set
+// to nil.
+//
+// Each generated type also has unexported appendJSON and decodeJSON methods
+// that the methods of the other generated types call directly, and the
+// output holds the encoding and decoding helpers they share.
+package jsoncodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/iand/gen"
+)
+
+// generator writes the methods of a set of struct types.
+type generator struct {
+	fs      *gen.FileSet
+	imports *gen.Imports
+	qual    types.Qualifier
+	gen     map[*types.Named]bool // types whose methods are being generated
+	vars    int                   // counter for the names of local variables
+	usesErr bool                  // the current appendJSON method uses err
+}
+
+// Generate writes MarshalJSON and UnmarshalJSON methods to o for each of the
+// named struct types, or for every non-generic struct type declared in fs if
+// no names are given. It returns an error if a field has a type that cannot
+// be encoded, such as an interface, channel or function.
+func Generate(fs *gen.FileSet, o *gen.Output, typeNames ...string) error {
+	var named []*types.Named
+	if len(typeNames) == 0 {
+		for _, tm := range fs.Types() {
+			n, ok := tm.Object.Type().(*types.Named)
+			if !ok || tm.Object.IsAlias() || n.TypeParams().Len() > 0 {
+				continue
+			}
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				named = append(named, n)
+			}
+		}
+	} else {
+		for _, name := range typeNames {
+			tn, ok := fs.Lookup(name).(*types.TypeName)
+			if !ok {
+				return fmt.Errorf("type %s not found", name)
+			}
+			n, ok := tn.Type().(*types.Named)
+			if !ok || tn.IsAlias() {
+				return fmt.Errorf("%s is not a named type", name)
+			}
+			if n.TypeParams().Len() > 0 {
+				return fmt.Errorf("cannot generate methods for generic type %s", name)
+			}
+			if _, ok := n.Underlying().(*types.Struct); !ok {
+				return fmt.Errorf("%s is not a struct type", name)
+			}
+			named = append(named, n)
+		}
+	}
+
+	imports := gen.NewImports()
+	g := &generator{
+		fs:      fs,
+		imports: imports,
+		qual:    imports.Qualifier(fs.Package),
+		gen:     make(map[*types.Named]bool),
+	}
+	for _, n := range named {
+		g.gen[n] = true
+	}
+
+	var body bytes.Buffer
+	for _, n := range named {
+		if err := g.methods(&body, n); err != nil {
+			return err
+		}
+	}
+	if len(named) > 0 {
+		for _, path := range helperImports {
+			imports.Add(path, "")
+		}
+		body.WriteString(helpers)
+	}
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	o.Printf("%s\n", imports.Block())
+	o.Write(body.Bytes())
+	return nil
+}
+
+// field is a struct field that is encoded as a member of a JSON object.
+type field struct {
+	name      string // the member name
+	expr      string // the selector of the field, relative to the receiver
+	typ       types.Type
+	omitEmpty bool
+}
+
+// fields returns the encoded fields of the struct type n.
+func (g *generator) fields(n *types.Named) ([]*field, error) {
+	typeName := n.Obj().Name()
+	models, err := g.fs.FieldsOf(typeName, gen.FlattenEmbedded(true))
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields promoted through an embedded struct that has a json tag name
+	// are encoded within it rather than promoted.
+	tagged := make(map[string]bool)
+	var fields []*field
+	for _, f := range models {
+		if f.Promoted && tagged[f.Path[0]] {
+			continue
+		}
+		tag, ok := f.Tags.Get("json")
+		if ok && tag.Name == "-" && len(tag.Options) == 0 {
+			continue
+		}
+		if f.Embedded && tag.Name == "" {
+			if _, ok := f.Type.Underlying().(*types.Struct); ok {
+				continue
+			}
+			if p, ok := f.Type.(*types.Pointer); ok {
+				if _, ok := p.Elem().Underlying().(*types.Struct); ok {
+					return nil, fmt.Errorf("%s.%s: embedded pointer fields are not supported", typeName, f.Name)
+				}
+			}
+		}
+		if f.Embedded && !f.Promoted && tag.Name != "" {
+			tagged[f.Name] = true
+		}
+		if !f.Exported {
+			continue
+		}
+		if tag.HasOption("string") {
+			return nil, fmt.Errorf("%s.%s: the string option of json tags is not supported", typeName, f.Name)
+		}
+
+		name := f.Name
+		if tag.Name != "" {
+			name = tag.Name
+		}
+		fields = append(fields, &field{
+			name:      name,
+			expr:      strings.Join(append(append([]string(nil), f.Path...), f.Name), "."),
+			typ:       f.Type,
+			omitEmpty: tag.HasOption("omitempty"),
+		})
+	}
+	return fields, nil
+}
+
+// methods writes the methods of n to w.
+func (g *generator) methods(w *bytes.Buffer, n *types.Named) error {
+	fields, err := g.fields(n)
+	if err != nil {
+		return err
+	}
+	name := n.Obj().Name()
+	recv := gen.ReceiverName(name)
+
+	if err := g.marshal(w, name, recv, fields); err != nil {
+		return err
+	}
+	return g.unmarshal(w, name, recv, fields)
+}
+
+// marshal writes the MarshalJSON and appendJSON methods of the named type.
+func (g *generator) marshal(w *bytes.Buffer, name, recv string, fields []*field) error {
+	g.vars, g.usesErr = 0, false
+	var body bytes.Buffer
+	body.WriteString("\tbuf = append(buf, '{')\n")
+
+	// written records whether a member has been written before the current
+	// one: never, always or only at run time, when it is found by
+	// comparing the length of b with its length after the opening brace.
+	const (
+		never = iota
+		always
+		maybe
+	)
+	written := never
+	usesStart := false
+	for _, f := range fields {
+		x := recv + "." + f.expr
+		indent := "\t"
+		if f.omitEmpty {
+			if empty, ok := emptyExpr(x, f.typ); ok {
+				fmt.Fprintf(&body, "\tif !(%s) {\n", empty)
+				indent = "\t\t"
+			}
+		}
+		key, err := json.Marshal(f.name)
+		if err != nil {
+			return err
+		}
+		switch written {
+		case never:
+			fmt.Fprintf(&body, "%sbuf = append(buf, %s...)\n", indent, strconv.Quote(string(key)+":"))
+		case always:
+			fmt.Fprintf(&body, "%sbuf = append(buf, %s...)\n", indent, strconv.Quote(","+string(key)+":"))
+		case maybe:
+			fmt.Fprintf(&body, "%sif len(buf) > start {\n%s\tbuf = append(buf, ',')\n%s}\n", indent, indent, indent)
+			fmt.Fprintf(&body, "%sbuf = append(buf, %s...)\n", indent, strconv.Quote(string(key)+":"))
+			usesStart = true
+		}
+		if err := g.encode(&body, indent, x, f.typ); err != nil {
+			return fmt.Errorf("%s.%s: %w", name, f.expr, err)
+		}
+		if indent != "\t" {
+			body.WriteString("\t}\n")
+			if written == never {
+				written = maybe
+			}
+		} else {
+			written = always
+		}
+	}
+	body.WriteString("\tbuf = append(buf, '}')\n")
+
+	fmt.Fprintf(w, "\n// MarshalJSON implements the json.Marshaler interface.\n")
+	fmt.Fprintf(w, "func (%s %s) MarshalJSON() ([]byte, error) {\n", recv, name)
+	fmt.Fprintf(w, "\treturn %s.appendJSON(make([]byte, 0, 64))\n}\n", recv)
+	fmt.Fprintf(w, "\n// appendJSON appends the JSON encoding of %s to buf.\n", recv)
+	fmt.Fprintf(w, "func (%s %s) appendJSON(buf []byte) ([]byte, error) {\n", recv, name)
+	if g.usesErr {
+		w.WriteString("\tvar err error\n")
+	}
+	if usesStart {
+		w.WriteString("\tstart := len(buf) + 1\n")
+	}
+	w.Write(body.Bytes())
+	w.WriteString("\treturn buf, nil\n}\n")
+	return nil
+}
+
+// unmarshal writes the UnmarshalJSON and decodeJSON methods of the named type.
+func (g *generator) unmarshal(w *bytes.Buffer, name, recv string, fields []*field) error {
+	g.vars = 0
+	fmt.Fprintf(w, "\n// UnmarshalJSON implements the json.Unmarshaler interface.\n")
+	fmt.Fprintf(w, "func (%s *%s) UnmarshalJSON(data []byte) error {\n", recv, name)
+	fmt.Fprintf(w, "\tdec := jsoncodecDecoder{data: data}\n")
+	fmt.Fprintf(w, "\tif err := %s.decodeJSON(&dec); err != nil {\n\t\treturn err\n\t}\n", recv)
+	fmt.Fprintf(w, "\treturn dec.end()\n}\n")
+
+	fmt.Fprintf(w, "\n// decodeJSON decodes the next value read by dec into %s.\n", recv)
+	fmt.Fprintf(w, "func (%s *%s) decodeJSON(dec *jsoncodecDecoder) error {\n", recv, name)
+	fmt.Fprintf(w, "\tif dec.null() {\n\t\treturn nil\n\t}\n")
+	if len(fields) == 0 {
+		fmt.Fprintf(w, "\treturn dec.object(func(string) error {\n\t\treturn dec.skip()\n\t})\n}\n")
+		return nil
+	}
+	fmt.Fprintf(w, "\treturn dec.object(func(key string) error {\n\t\tswitch key {\n")
+	for _, f := range fields {
+		fmt.Fprintf(w, "\t\tcase %s:\n", strconv.Quote(f.name))
+		if err := g.decode(w, "\t\t\t", recv+"."+f.expr, f.typ); err != nil {
+			return fmt.Errorf("%s.%s: %w", name, f.expr, err)
+		}
+	}
+	fmt.Fprintf(w, "\t\tdefault:\n\t\t\treturn dec.skip()\n\t\t}\n\t\treturn nil\n\t})\n}\n")
+	return nil
+}
+
+// emptyExpr returns an expression reporting whether the value x of type t is
+// empty, as defined for the omitempty option. The result is false for
+// structs, which are never empty.
+func emptyExpr(x string, t types.Type) (string, bool) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "!" + x, true
+		case u.Info()&types.IsNumeric != 0:
+			return x + " == 0", true
+		case u.Info()&types.IsString != 0:
+			return x + ` == ""`, true
+		}
+	case *types.Slice, *types.Map, *types.Array:
+		return "len(" + x + ") == 0", true
+	case *types.Pointer, *types.Interface:
+		return x + " == nil", true
+	}
+	return "", false
+}
+
+// suffix returns a new suffix for the names of local variables.
+func (g *generator) suffix() string {
+	g.vars++
+	return strconv.Itoa(g.vars)
+}
+
+// encode writes statements to w that append the JSON encoding of the value x
+// of type t to the byte slice b.
+func (g *generator) encode(w *bytes.Buffer, indent, x string, t types.Type) error {
+	if n, ok := t.(*types.Named); ok && g.gen[n] {
+		fmt.Fprintf(w, "%sif buf, err = %s.appendJSON(buf); err != nil {\n%s\treturn nil, err\n%s}\n", indent, operand(x), indent, indent)
+		g.usesErr = true
+		return nil
+	}
+	if p, ok := t.(*types.Pointer); ok {
+		// Methods are not called on nil pointers, which encode as null.
+		return g.encodePointer(w, indent, x, p)
+	}
+	if hasMethod(t, "MarshalJSON", 0, 2) {
+		r := "raw" + g.suffix()
+		fmt.Fprintf(w, "%s%s, err := %s.MarshalJSON()\n", indent, r, operand(x))
+		fmt.Fprintf(w, "%sif err != nil {\n%s\treturn nil, err\n%s}\n", indent, indent, indent)
+		fmt.Fprintf(w, "%sbuf = append(buf, %s...)\n", indent, r)
+		return nil
+	}
+	if hasMethod(t, "MarshalText", 0, 2) {
+		r := "text" + g.suffix()
+		fmt.Fprintf(w, "%s%s, err := %s.MarshalText()\n", indent, r, operand(x))
+		fmt.Fprintf(w, "%sif err != nil {\n%s\treturn nil, err\n%s}\n", indent, indent, indent)
+		fmt.Fprintf(w, "%sbuf = jsoncodecAppendString(buf, string(%s))\n", indent, r)
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			fmt.Fprintf(w, "%sbuf = %s.AppendBool(buf, %s)\n", indent, g.imports.Add("strconv", ""), g.convert(x, t, types.Typ[types.Bool]))
+		case info&types.IsUnsigned != 0:
+			fmt.Fprintf(w, "%sbuf = %s.AppendUint(buf, %s, 10)\n", indent, g.imports.Add("strconv", ""), g.convert(x, t, types.Typ[types.Uint64]))
+		case info&types.IsInteger != 0:
+			fmt.Fprintf(w, "%sbuf = %s.AppendInt(buf, %s, 10)\n", indent, g.imports.Add("strconv", ""), g.convert(x, t, types.Typ[types.Int64]))
+		case info&types.IsFloat != 0:
+			fmt.Fprintf(w, "%sif buf, err = jsoncodecAppendFloat(buf, %s, %d); err != nil {\n%s\treturn nil, err\n%s}\n", indent, g.convert(x, t, types.Typ[types.Float64]), floatBits(u), indent, indent)
+			g.usesErr = true
+		case info&types.IsString != 0:
+			fmt.Fprintf(w, "%sbuf = jsoncodecAppendString(buf, %s)\n", indent, g.convert(x, t, types.Typ[types.String]))
+		default:
+			return fmt.Errorf("cannot encode values of type %s", types.TypeString(t, g.qual))
+		}
+		return nil
+	case *types.Slice:
+		fmt.Fprintf(w, "%sif %s == nil {\n%s\tbuf = append(buf, \"null\"...)\n%s} else {\n", indent, x, indent, indent)
+		if isByte(u.Elem()) {
+			fmt.Fprintf(w, "%s\tbuf = append(buf, '\"')\n", indent)
+			fmt.Fprintf(w, "%s\tbuf = %s.StdEncoding.AppendEncode(buf, %s)\n", indent, g.imports.Add("encoding/base64", ""), x)
+			fmt.Fprintf(w, "%s\tbuf = append(buf, '\"')\n", indent)
+		} else if err := g.encodeElems(w, indent+"\t", x, u.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Array:
+		return g.encodeElems(w, indent, x, u.Elem())
+	case *types.Map:
+		if !isString(u.Key()) {
+			return fmt.Errorf("cannot encode maps with keys of type %s", types.TypeString(u.Key(), g.qual))
+		}
+		n := g.suffix()
+		keys, i, k := "keys"+n, "i"+n, "k"+n
+		fmt.Fprintf(w, "%sif %s == nil {\n%s\tbuf = append(buf, \"null\"...)\n%s} else {\n", indent, x, indent, indent)
+		fmt.Fprintf(w, "%s\t%s := make([]string, 0, len(%s))\n", indent, keys, x)
+		fmt.Fprintf(w, "%s\tfor %s := range %s {\n%s\t\t%s = append(%s, %s)\n%s\t}\n", indent, k, x, indent, keys, keys, g.convert(k, u.Key(), types.Typ[types.String]), indent)
+		fmt.Fprintf(w, "%s\t%s.Strings(%s)\n", indent, g.imports.Add("sort", ""), keys)
+		fmt.Fprintf(w, "%s\tbuf = append(buf, '{')\n", indent)
+		fmt.Fprintf(w, "%s\tfor %s, %s := range %s {\n", indent, i, k, keys)
+		fmt.Fprintf(w, "%s\t\tif %s > 0 {\n%s\t\t\tbuf = append(buf, ',')\n%s\t\t}\n", indent, i, indent, indent)
+		fmt.Fprintf(w, "%s\t\tbuf = jsoncodecAppendString(buf, %s)\n", indent, k)
+		fmt.Fprintf(w, "%s\t\tbuf = append(buf, ':')\n", indent)
+		if err := g.encode(w, indent+"\t\t", fmt.Sprintf("%s[%s]", operand(x), g.convert(k, types.Typ[types.String], u.Key())), u.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t}\n", indent)
+		fmt.Fprintf(w, "%s\tbuf = append(buf, '}')\n", indent)
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Pointer:
+		return g.encodePointer(w, indent, x, u)
+	}
+	return fmt.Errorf("cannot encode values of type %s", types.TypeString(t, g.qual))
+}
+
+// encodePointer writes statements that append the value pointed to by x, or
+// null if x is nil.
+func (g *generator) encodePointer(w *bytes.Buffer, indent, x string, t *types.Pointer) error {
+	fmt.Fprintf(w, "%sif %s == nil {\n%s\tbuf = append(buf, \"null\"...)\n%s} else {\n", indent, x, indent, indent)
+	if err := g.encode(w, indent+"\t", "*"+x, t.Elem()); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+	return nil
+}
+
+// encodeElems writes statements that append the elements of the slice or
+// array x as a JSON array.
+func (g *generator) encodeElems(w *bytes.Buffer, indent, x string, elem types.Type) error {
+	n := g.suffix()
+	i, v := "i"+n, "v"+n
+	fmt.Fprintf(w, "%sbuf = append(buf, '[')\n", indent)
+	fmt.Fprintf(w, "%sfor %s, %s := range %s {\n", indent, i, v, x)
+	fmt.Fprintf(w, "%s\tif %s > 0 {\n%s\t\tbuf = append(buf, ',')\n%s\t}\n", indent, i, indent, indent)
+	if err := g.encode(w, indent+"\t", v, elem); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+	fmt.Fprintf(w, "%sbuf = append(buf, ']')\n", indent)
+	return nil
+}
+
+// decode writes statements to w that decode the next value read by the
+// decoder d into the variable v of type t. The statements return any error.
+func (g *generator) decode(w *bytes.Buffer, indent, v string, t types.Type) error {
+	if n, ok := t.(*types.Named); ok && g.gen[n] {
+		fmt.Fprintf(w, "%sif err := %s.decodeJSON(dec); err != nil {\n%s\treturn err\n%s}\n", indent, operand(v), indent, indent)
+		return nil
+	}
+	if hasMethod(types.NewPointer(t), "UnmarshalJSON", 1, 1) {
+		r := "raw" + g.suffix()
+		fmt.Fprintf(w, "%s%s, err := dec.raw()\n", indent, r)
+		fmt.Fprintf(w, "%sif err != nil {\n%s\treturn err\n%s}\n", indent, indent, indent)
+		fmt.Fprintf(w, "%sif err := %s.UnmarshalJSON(%s); err != nil {\n%s\treturn err\n%s}\n", indent, operand(v), r, indent, indent)
+		return nil
+	}
+	if hasMethod(types.NewPointer(t), "UnmarshalText", 1, 1) {
+		s := "s" + g.suffix()
+		fmt.Fprintf(w, "%sif !dec.null() {\n", indent)
+		fmt.Fprintf(w, "%s\t%s, err := dec.str()\n", indent, s)
+		fmt.Fprintf(w, "%s\tif err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+		fmt.Fprintf(w, "%s\tif err := %s.UnmarshalText([]byte(%s)); err != nil {\n%s\t\treturn err\n%s\t}\n", indent, operand(v), s, indent, indent)
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	}
+
+	typ := types.TypeString(t, g.qual)
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		var read, parse string
+		var from types.Type
+		switch {
+		case info&types.IsBoolean != 0:
+			read, from = "dec.boolean()", types.Typ[types.Bool]
+		case info&types.IsUnsigned != 0:
+			read, from = "dec.number()", types.Typ[types.Uint64]
+			parse = fmt.Sprintf("%s.ParseUint(%%s, 10, %d)", g.imports.Add("strconv", ""), intBits(u))
+		case info&types.IsInteger != 0:
+			read, from = "dec.number()", types.Typ[types.Int64]
+			parse = fmt.Sprintf("%s.ParseInt(%%s, 10, %d)", g.imports.Add("strconv", ""), intBits(u))
+		case info&types.IsFloat != 0:
+			read, from = "dec.number()", types.Typ[types.Float64]
+			parse = fmt.Sprintf("%s.ParseFloat(%%s, %d)", g.imports.Add("strconv", ""), floatBits(u))
+		case info&types.IsString != 0:
+			read, from = "dec.str()", types.Typ[types.String]
+		default:
+			return fmt.Errorf("cannot decode values of type %s", typ)
+		}
+		sfx := g.suffix()
+		x := "x" + sfx
+		fmt.Fprintf(w, "%sif !dec.null() {\n", indent)
+		fmt.Fprintf(w, "%s\t%s, err := %s\n", indent, x, read)
+		fmt.Fprintf(w, "%s\tif err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+		if parse != "" {
+			n := "n" + sfx
+			fmt.Fprintf(w, "%s\t%s, err := %s\n", indent, n, fmt.Sprintf(parse, x))
+			fmt.Fprintf(w, "%s\tif err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+			x = n
+		}
+		fmt.Fprintf(w, "%s\t%s = %s\n", indent, v, g.convert(x, from, t))
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Slice:
+		fmt.Fprintf(w, "%sif dec.null() {\n%s\t%s = nil\n%s} else {\n", indent, indent, v, indent)
+		if isByte(u.Elem()) {
+			n := g.suffix()
+			s, data := "s"+n, "data"+n
+			fmt.Fprintf(w, "%s\t%s, err := dec.str()\n", indent, s)
+			fmt.Fprintf(w, "%s\tif err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+			fmt.Fprintf(w, "%s\t%s, err := %s.StdEncoding.DecodeString(%s)\n", indent, data, g.imports.Add("encoding/base64", ""), s)
+			fmt.Fprintf(w, "%s\tif err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+			fmt.Fprintf(w, "%s\t%s = %s\n", indent, v, g.convert(data, types.NewSlice(types.Typ[types.Byte]), t))
+		} else {
+			n := g.suffix()
+			s, e := "s"+n, "e"+n
+			fmt.Fprintf(w, "%s\t%s := make(%s, 0)\n", indent, s, typ)
+			fmt.Fprintf(w, "%s\tif err := dec.array(func() error {\n", indent)
+			fmt.Fprintf(w, "%s\t\tvar %s %s\n", indent, e, types.TypeString(u.Elem(), g.qual))
+			if err := g.decode(w, indent+"\t\t", e, u.Elem()); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\t\t%s = append(%s, %s)\n", indent, s, s, e)
+			fmt.Fprintf(w, "%s\t\treturn nil\n", indent)
+			fmt.Fprintf(w, "%s\t}); err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+			fmt.Fprintf(w, "%s\t%s = %s\n", indent, v, s)
+		}
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Array:
+		i := "i" + g.suffix()
+		fmt.Fprintf(w, "%sif !dec.null() {\n", indent)
+		fmt.Fprintf(w, "%s\t%s := 0\n", indent, i)
+		fmt.Fprintf(w, "%s\tif err := dec.array(func() error {\n", indent)
+		fmt.Fprintf(w, "%s\t\tif %s >= len(%s) {\n%s\t\t\treturn dec.skip()\n%s\t\t}\n", indent, i, v, indent, indent)
+		if err := g.decode(w, indent+"\t\t", fmt.Sprintf("%s[%s]", operand(v), i), u.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t\t%s++\n", indent, i)
+		fmt.Fprintf(w, "%s\t\treturn nil\n", indent)
+		fmt.Fprintf(w, "%s\t}); err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+		fmt.Fprintf(w, "%s\tclear(%s[%s:])\n", indent, operand(v), i)
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Map:
+		if !isString(u.Key()) {
+			return fmt.Errorf("cannot decode maps with keys of type %s", types.TypeString(u.Key(), g.qual))
+		}
+		n := g.suffix()
+		k, e := "k"+n, "e"+n
+		fmt.Fprintf(w, "%sif dec.null() {\n%s\t%s = nil\n%s} else {\n", indent, indent, v, indent)
+		fmt.Fprintf(w, "%s\tif %s == nil {\n%s\t\t%s = make(%s)\n%s\t}\n", indent, v, indent, v, typ, indent)
+		fmt.Fprintf(w, "%s\tif err := dec.object(func(%s string) error {\n", indent, k)
+		fmt.Fprintf(w, "%s\t\tvar %s %s\n", indent, e, types.TypeString(u.Elem(), g.qual))
+		if err := g.decode(w, indent+"\t\t", e, u.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t\t%s[%s] = %s\n", indent, operand(v), g.convert(k, types.Typ[types.String], u.Key()), e)
+		fmt.Fprintf(w, "%s\t\treturn nil\n", indent)
+		fmt.Fprintf(w, "%s\t}); err != nil {\n%s\t\treturn err\n%s\t}\n", indent, indent, indent)
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	case *types.Pointer:
+		fmt.Fprintf(w, "%sif dec.null() {\n%s\t%s = nil\n%s} else {\n", indent, indent, v, indent)
+		fmt.Fprintf(w, "%s\tif %s == nil {\n%s\t\t%s = new(%s)\n%s\t}\n", indent, v, indent, v, types.TypeString(u.Elem(), g.qual), indent)
+		if err := g.decode(w, indent+"\t", "*"+v, u.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s}\n", indent)
+		return nil
+	}
+	return fmt.Errorf("cannot decode values of type %s", typ)
+}
+
+// convert returns the expression x of type from converted to type to,
+// omitting the conversion if the types are identical.
+func (g *generator) convert(x string, from, to types.Type) string {
+	if types.Identical(from, to) {
+		return x
+	}
+	return types.TypeString(to, g.qual) + "(" + x + ")"
+}
+
+// hasMethod reports whether values of type t have the named method with the
+// given numbers of parameters and results.
+func hasMethod(t types.Type, name string, params, results int) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == params && sig.Results().Len() == results
+}
+
+// operand returns the expression x in a form that may be followed by a
+// selector or index, parenthesizing a pointer indirection.
+func operand(x string) string {
+	if strings.HasPrefix(x, "*") {
+		return "(" + x + ")"
+	}
+	return x
+}
+
+func isByte(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+func isString(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+// intBits returns the bit size of the integer type t for strconv, which is
+// zero for int and uint.
+func intBits(t *types.Basic) int {
+	switch t.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32:
+		return 32
+	case types.Int64, types.Uint64, types.Uintptr:
+		return 64
+	}
+	return 0
+}
+
+func floatBits(t *types.Basic) int {
+	if t.Kind() == types.Float32 {
+		return 32
+	}
+	return 64
+}
@@ -0,0 +1,312 @@
+package jsoncodec
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"errors"
+	"time"
+)
+
+type Color int
+
+var colorNames = []string{"red", "green", "blue"}
+
+func (c Color) MarshalText() ([]byte, error) {
+	if int(c) >= len(colorNames) {
+		return nil, errors.New("bad color")
+	}
+	return []byte(colorNames[c]), nil
+}
+
+func (c *Color) UnmarshalText(b []byte) error {
+	for i, name := range colorNames {
+		if name == string(b) {
+			*c = Color(i)
+			return nil
+		}
+	}
+	return errors.New("bad color")
+}
+
+type Level int8
+
+type Base struct {
+	ID      int64     ` + "`json:\"id\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+
+type inner struct {
+	Note string
+}
+
+type Audit struct {
+	By string ` + "`json:\"by\"`" + `
+}
+
+type Item struct {
+	SKU   string  ` + "`json:\"sku\"`" + `
+	Price float64 ` + "`json:\"price\"`" + `
+	Qty   uint16  ` + "`json:\"qty,omitempty\"`" + `
+}
+
+type Order struct {
+	Base
+	inner
+	Audit    ` + "`json:\"audit\"`" + `
+	Customer string              ` + "`json:\"customer\"`" + `
+	Items    []Item              ` + "`json:\"items\"`" + `
+	Primary  *Item               ` + "`json:\"primary,omitempty\"`" + `
+	Tags     map[string]string   ` + "`json:\"tags,omitempty\"`" + `
+	Scores   [3]float32          ` + "`json:\"scores\"`" + `
+	Blob     []byte              ` + "`json:\"blob\"`" + `
+	Paid     bool                ` + "`json:\"paid\"`" + `
+	Color    Color               ` + "`json:\"color\"`" + `
+	Level    Level
+	Nested   map[string][]*Item  ` + "`json:\"nested\"`" + `
+	Expires  *time.Time          ` + "`json:\"expires\"`" + `
+	Ignored  string              ` + "`json:\"-\"`" + `
+	Dash     string              ` + "`json:\"-,\"`" + `
+	secret   string
+}
+
+type Patch struct {
+	Name  string ` + "`json:\"name,omitempty\"`" + `
+	Count int    ` + "`json:\"count,omitempty\"`" + `
+	Done  bool   ` + "`json:\"done\"`" + `
+}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("jsoncodec")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"func (o Order) MarshalJSON() ([]byte, error)",
+		"func (o *Order) UnmarshalJSON(data []byte) error",
+		`buf = append(buf, ",\"customer\":"...)`,
+		`buf = append(buf, ",\"Note\":"...)`,
+		`if buf, err = o.Audit.appendJSON(buf); err != nil {`,
+		`raw1, err := o.Base.Created.MarshalJSON()`,
+		`if !(o.Primary == nil) {`,
+		`case "customer":`,
+		`case "-":`,
+		"base64.StdEncoding.AppendEncode(buf, o.Blob)",
+		"if len(buf) > start {",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	for _, unwanted := range []string{"Ignored", "secret"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated source encodes field %s:\n%s", unwanted, src)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		typ  string
+	}{
+		{name: "missing", src: "package p\n", typ: "T"},
+		{name: "not struct", src: "package p\ntype T int\n", typ: "T"},
+		{name: "generic", src: "package p\ntype T[E any] struct{ V E }\n", typ: "T"},
+		{name: "interface field", src: "package p\ntype T struct{ V any }\n", typ: "T"},
+		{name: "func field", src: "package p\ntype T struct{ F func() }\n", typ: "T"},
+		{name: "int keys", src: "package p\ntype T struct{ M map[int]string }\n", typ: "T"},
+		{name: "string option", src: "package p\ntype T struct{ N int `json:\",string\"` }\n", typ: "T"},
+		{name: "embedded pointer", src: "package p\ntype E struct{ A int }\ntype T struct{ *E }\n", typ: "T"},
+		{name: "other struct", src: "package p\ntype E struct{ A int }\ntype T struct{ E E }\n", typ: "T"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := gen.NewFileSetFromTexts(tc.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := Generate(fs, gen.NewOutput("jsoncodec"), tc.typ); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+// testProgram checks the generated methods of package p against
+// encoding/json applied to the same types, declared without methods in
+// package q.
+const testProgram = `package p_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"example.com/p"
+	"example.com/p/q"
+)
+
+var documents = []string{
+	` + "`{}`" + `,
+	` + "`null`" + `,
+	` + "`{\"id\":7,\"created\":\"2024-01-02T03:04:05.123456789Z\",\"Note\":\"a \\\"note\\\" \\u00e9\\ud83d\\ude00 <b>&\\u2028\",\"audit\":{\"by\":\"me\"}}`" + `,
+	` + "`{\"customer\":\"Ann\",\"items\":[{\"sku\":\"x\",\"price\":1.5,\"qty\":2},{\"sku\":\"y\",\"price\":1e-7}],\"primary\":{\"sku\":\"z\",\"price\":1e21}}`" + `,
+	` + "` { \"tags\" : { \"b\" : \"2\" , \"a\" : \"1\" } , \"scores\" : [0.1, 2.5, -3, 4] , \"blob\" : \"aGVsbG8=\" , \"paid\" : true } `" + `,
+	` + "`{\"color\":\"blue\",\"Level\":-5,\"nested\":{\"k\":[null,{\"sku\":\"n\",\"price\":0}]},\"expires\":\"2025-06-07T08:09:10Z\",\"unknown\":{\"a\":[1,true,null,\"s\"]},\"-\":\"dash\"}`" + `,
+	` + "`{\"items\":null,\"tags\":null,\"blob\":null,\"nested\":{},\"items\":[],\"scores\":[1]}`" + `,
+}
+
+func TestMatchesEncodingJSON(t *testing.T) {
+	for _, doc := range documents {
+		var want q.Order
+		if err := json.Unmarshal([]byte(doc), &want); err != nil {
+			t.Fatalf("encoding/json: %v", err)
+		}
+		var got p.Order
+		if err := got.UnmarshalJSON([]byte(doc)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", doc, err)
+		}
+
+		wantJSON, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("encoding/json: %v", err)
+		}
+		gotJSON, err := got.MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON: %v", err)
+		}
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("document %s\ngot:  %s\nwant: %s", doc, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	for _, doc := range []string{` + "`{}`, `{\"name\":\"x\"}`, `{\"count\":2,\"done\":true}`, `{\"name\":\"x\",\"count\":2}`" + `} {
+		var want q.Patch
+		if err := json.Unmarshal([]byte(doc), &want); err != nil {
+			t.Fatalf("encoding/json: %v", err)
+		}
+		var got p.Patch
+		if err := got.UnmarshalJSON([]byte(doc)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", doc, err)
+		}
+		wantJSON, _ := json.Marshal(want)
+		gotJSON, _ := got.MarshalJSON()
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("document %s\ngot:  %s\nwant: %s", doc, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, doc := range []string{` + "`{`, `{\"id\":\"7\"}`, `{\"customer\":1}`, `{\"items\":{}}`, `{} x`, `{\"color\":\"pink\"}`, `[]`" + `} {
+		var o p.Order
+		if err := o.UnmarshalJSON([]byte(doc)); err == nil {
+			t.Errorf("UnmarshalJSON(%s): got no error", doc)
+		}
+	}
+}
+`
+
+func TestGeneratedMatchesEncodingJSON(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.22\n",
+		"p.go":      testSrc,
+		"p_test.go": testProgram,
+		"q/q.go":    strings.Replace(testSrc, "package p", "package q", 1),
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "p.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("jsoncodec")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "p_json.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated methods failed: %v\n%s\n%s", err, out, src)
+	}
+}
+
+// TestBenchdataUpToDate checks that the generated code benchmarked in
+// internal/benchdata matches the output of the generator.
+func TestBenchdataUpToDate(t *testing.T) {
+	dir := filepath.Join("internal", "benchdata")
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "record.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("jsoncodec")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	filename := filepath.Join(dir, "record_json.go")
+	if os.Getenv("JSONCODEC_UPDATE") != "" {
+		if err := os.WriteFile(filename, got, 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date (set JSONCODEC_UPDATE=1 to update it):\n%s", filename, gen.UnifiedDiff(filename, "generated", want, got))
+	}
+}
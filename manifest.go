@@ -0,0 +1,190 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestName is the conventional name of the file holding a Manifest.
+const ManifestName = "gen.manifest.json"
+
+// Manifest records the provenance of the files written by a run of a
+// generator: the generator and its version, the arguments it was run with,
+// and the files it read and wrote with digests of their contents. A manifest
+// kept alongside generated code supports reproducibility audits, with
+// Manifest.Changed reporting the files that differ from those recorded, and
+// the removal of stale files, with Manifest.StaleOutputs reporting the
+// outputs of the recorded run that a later run no longer writes.
+//
+// The paths of the files are relative to the directory holding the manifest
+// and use forward slashes, so that a manifest checked into version control
+// does not depend on where the repository is checked out.
+type Manifest struct {
+	// Generator is the name of the generator.
+	Generator string `json:"generator"`
+
+	// Version identifies the version of the generator, if known.
+	Version string `json:"version,omitempty"`
+
+	// Args holds the command line arguments of the run.
+	Args []string `json:"args,omitempty"`
+
+	// Inputs lists the files read by the run, such as the Go source files of
+	// the package and any template, sorted by path.
+	Inputs []ManifestFile `json:"inputs"`
+
+	// Outputs lists the files written by the run, sorted by path.
+	Outputs []ManifestFile `json:"outputs"`
+}
+
+// ManifestFile records a file read or written by a generator.
+type ManifestFile struct {
+	// Path is the path of the file relative to the directory holding the
+	// manifest, with forward slashes.
+	Path string `json:"path"`
+
+	// SHA256 is the hex encoded SHA-256 digest of the contents of the file.
+	SHA256 string `json:"sha256"`
+}
+
+// NewManifest returns a manifest for a run of the named generator that read
+// the files named by inputs and wrote the files named by outputs, recording
+// the digests of their current contents. Paths are recorded relative to dir,
+// the directory that will hold the manifest.
+func NewManifest(dir, generator, version string, args, inputs, outputs []string) (*Manifest, error) {
+	m := &Manifest{
+		Generator: generator,
+		Version:   version,
+		Args:      args,
+	}
+	var err error
+	if m.Inputs, err = manifestFiles(dir, inputs); err != nil {
+		return nil, err
+	}
+	if m.Outputs, err = manifestFiles(dir, outputs); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// manifestFiles returns the records of the named files, relative to dir and
+// sorted by path.
+func manifestFiles(dir string, filenames []string) ([]ManifestFile, error) {
+	files := []ManifestFile{}
+	seen := make(map[string]bool)
+	for _, filename := range filenames {
+		rel, err := manifestPath(dir, filename)
+		if err != nil {
+			return nil, err
+		}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		sum, err := fileSHA256(filename)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ManifestFile{Path: rel, SHA256: sum})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// manifestPath returns the path of filename relative to dir with forward
+// slashes.
+func manifestPath(dir, filename string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// fileSHA256 returns the hex encoded SHA-256 digest of the contents of the
+// file filename.
+func fileSHA256(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ReadManifest reads the manifest in the file filename.
+func ReadManifest(filename string) (*Manifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("read manifest %s: %w", filename, err)
+	}
+	return &m, nil
+}
+
+// WriteFile writes the manifest to the file filename as indented JSON,
+// replacing it atomically.
+func (m *Manifest) WriteFile(filename string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, append(data, '\n'), 0o644)
+}
+
+// Changed returns the paths of the files recorded in the manifest whose
+// contents differ from those recorded, including files that no longer exist,
+// with the inputs first and then the outputs. dir is the directory holding
+// the manifest. An empty result means the recorded run can be trusted to
+// reproduce the outputs, given the same generator version and arguments.
+func (m *Manifest) Changed(dir string) ([]string, error) {
+	var changed []string
+	for _, files := range [][]ManifestFile{m.Inputs, m.Outputs} {
+		for _, f := range files {
+			sum, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(f.Path)))
+			if errors.Is(err, fs.ErrNotExist) {
+				changed = append(changed, f.Path)
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if sum != f.SHA256 {
+				changed = append(changed, f.Path)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// StaleOutputs returns the names of the outputs recorded in the manifest
+// that are not among produced, the files written by a later run. dir is the
+// directory holding the manifest, which the names are joined to. Such files
+// are typically stale and may be removed, for example with PruneStale.
+func (m *Manifest) StaleOutputs(dir string, produced []string) []string {
+	var stale []string
+	for _, f := range m.Outputs {
+		filename := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if !isProduced(filename, produced) {
+			stale = append(stale, filename)
+		}
+	}
+	return stale
+}
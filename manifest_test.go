@@ -0,0 +1,73 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"p.go":             "package p\n",
+		"tmpl/enum.tmpl":   "{{.}}",
+		"color_gen.go":     "// Code generated by enumgen; DO NOT EDIT.\n\npackage p\n",
+		"sub/shape_gen.go": "// Code generated by enumgen; DO NOT EDIT.\n\npackage sub\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	inputs := []string{filepath.Join(dir, "tmpl", "enum.tmpl"), filepath.Join(dir, "p.go")}
+	outputs := []string{filepath.Join(dir, "sub", "shape_gen.go"), filepath.Join(dir, "color_gen.go")}
+	m, err := NewManifest(dir, "enumgen", "v1.0.0", []string{"-type", "Color"}, inputs, outputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	for _, f := range append(m.Inputs, m.Outputs...) {
+		paths = append(paths, f.Path)
+		if len(f.SHA256) != 64 {
+			t.Errorf("%s: got digest %q, wanted a hex encoded SHA-256", f.Path, f.SHA256)
+		}
+	}
+	if want := []string{"p.go", "tmpl/enum.tmpl", "color_gen.go", "sub/shape_gen.go"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %v, wanted %v", paths, want)
+	}
+
+	filename := filepath.Join(dir, ManifestName)
+	if err := m.WriteFile(filename); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := ReadManifest(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("got manifest %+v, wanted %+v", got, m)
+	}
+
+	if changed, err := got.Changed(dir); err != nil || len(changed) != 0 {
+		t.Errorf("got changed %v, %v, wanted none", changed, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n\ntype Color int\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "color_gen.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed, err := got.Changed(dir); err != nil || !reflect.DeepEqual(changed, []string{"p.go", "color_gen.go"}) {
+		t.Errorf("got changed %v, %v, wanted p.go and color_gen.go", changed, err)
+	}
+
+	stale := got.StaleOutputs(dir, []string{filepath.Join(dir, "color_gen.go")})
+	if want := []string{filepath.Join(dir, "sub", "shape_gen.go")}; !reflect.DeepEqual(stale, want) {
+		t.Errorf("got stale outputs %v, wanted %v", stale, want)
+	}
+}
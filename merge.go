@@ -0,0 +1,366 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ExternalModel is a model of types from a source of truth outside the Go
+// code, such as the tables of a database schema found by introspection or
+// the schemas of an OpenAPI document, in a neutral form that a Merger can
+// reconcile with the types declared in Go. Its JSON encoding, read by
+// ReadExternalModel, looks like:
+//
+//	{
+//	  "source": "postgres",
+//	  "types": [
+//	    {
+//	      "name": "users",
+//	      "fields": [
+//	        {"name": "id", "type": "bigint", "attrs": {"primary_key": true}},
+//	        {"name": "email", "type": "text", "attrs": {"nullable": true}}
+//	      ]
+//	    }
+//	  ]
+//	}
+type ExternalModel struct {
+	// Source describes where the model came from, such as postgres.
+	Source string `json:"source,omitempty"`
+
+	// Types holds the types of the model.
+	Types []*ExternalType `json:"types"`
+}
+
+// ExternalType is a type of an ExternalModel, such as a table.
+type ExternalType struct {
+	// Name is the name of the type.
+	Name string `json:"name"`
+
+	// Fields holds the fields of the type, such as the columns of a table.
+	Fields []*ExternalField `json:"fields,omitempty"`
+
+	// Attrs holds any other information about the type.
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// ExternalField is a field of an ExternalType.
+type ExternalField struct {
+	// Name is the name of the field.
+	Name string `json:"name"`
+
+	// Type is the type of the field in the external model's own terms, such
+	// as text or integer.
+	Type string `json:"type,omitempty"`
+
+	// Attrs holds any other information about the field.
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// ReadExternalModel reads an ExternalModel encoded as JSON from filename.
+func ReadExternalModel(filename string) (*ExternalModel, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m ExternalModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &m, nil
+}
+
+// Merger merges the models of types declared in Go with an ExternalModel,
+// pairing each Go type with the external type of the same name and each of
+// its fields with the external field of the same name. The merged model lets
+// a generator emit code from both, such as a data access layer that uses the
+// column types of a database, and reports the drift between them. The zero
+// Merger matches names ignoring case, underscores and hyphens, so UserID
+// matches user_id.
+type Merger struct {
+	// Tag, if not empty, is the struct tag key, such as db, whose name gives
+	// the external name of a field. Fields whose tag name is - are not
+	// merged. Fields without the tag are matched by their Go name.
+	Tag string
+
+	// TypeName, if not nil, returns the external name of a Go type, such as
+	// the table name users for the type User.
+	TypeName func(*TypeModel) string
+
+	// Key, if not nil, normalizes the Go and external names before they are
+	// compared.
+	Key func(name string) string
+
+	// Compatible, if not nil, reports whether the Go type of a field is
+	// compatible with the type of the external field it is merged with. An
+	// incompatible pair is reported as drift. If nil, types are not
+	// compared.
+	Compatible func(f *FieldModel, ef *ExternalField) bool
+
+	// Hook, if not nil, is called with each merged type once its fields
+	// have been paired, in the order of the types of the merged model. It
+	// may add to the type's Attrs, such as to record details from the
+	// external model for a template, and may report drift of its own with
+	// MergedModel.AddDrift. An error stops the merge.
+	Hook func(m *MergedModel, t *MergedType) error
+}
+
+// MergedModel is the result of merging Go types with an ExternalModel.
+type MergedModel struct {
+	// Types holds the merged types: those found in Go, in the order given,
+	// followed by the external types with no Go counterpart, in the order of
+	// the external model.
+	Types []*MergedType
+
+	// Drift holds the differences found between the Go types and the
+	// external model, in the order of Types.
+	Drift []Drift
+}
+
+// MergedType pairs a Go type with its counterpart in the external model.
+type MergedType struct {
+	// Name is the name of the Go type, or the external name if there is no
+	// Go type.
+	Name string
+
+	// Go is the model of the Go type, or nil if the type is only found in
+	// the external model.
+	Go *TypeModel
+
+	// External is the external type, or nil if the type is only found in
+	// Go.
+	External *ExternalType
+
+	// Fields holds the merged fields: those of the Go type in declaration
+	// order, followed by the external fields with no Go counterpart.
+	Fields []*MergedField
+
+	// Attrs holds values recorded by the Merger's Hook.
+	Attrs map[string]any
+}
+
+// MergedField pairs a field of a Go type with its counterpart in the
+// external model.
+type MergedField struct {
+	// Name is the name of the Go field, or the external name if there is
+	// no Go field.
+	Name string
+
+	// Go is the model of the Go field, or nil if the field is only found in
+	// the external model.
+	Go *FieldModel
+
+	// External is the external field, or nil if the field is only found in
+	// Go.
+	External *ExternalField
+}
+
+// DriftKind classifies a difference between Go and an external model.
+type DriftKind string
+
+// Kinds of drift.
+const (
+	// DriftMissingInGo is a type or field found only in the external model.
+	DriftMissingInGo DriftKind = "missing_in_go"
+
+	// DriftMissingExternally is a type or field found only in Go.
+	DriftMissingExternally DriftKind = "missing_externally"
+
+	// DriftTypeMismatch is a field whose Go type is not compatible with its
+	// external type.
+	DriftTypeMismatch DriftKind = "type_mismatch"
+
+	// DriftOther is drift reported by a merge hook.
+	DriftOther DriftKind = "other"
+)
+
+// Drift is a difference between Go and an external model.
+type Drift struct {
+	// Kind classifies the difference.
+	Kind DriftKind `json:"kind"`
+
+	// Type is the name of the merged type.
+	Type string `json:"type"`
+
+	// Field is the name of the merged field, or empty if the drift concerns
+	// the whole type.
+	Field string `json:"field,omitempty"`
+
+	// Detail describes the difference.
+	Detail string `json:"detail"`
+}
+
+// String returns a description of the drift, such as "User.Email: missing
+// externally".
+func (d Drift) String() string {
+	name := d.Type
+	if d.Field != "" {
+		name += "." + d.Field
+	}
+	return name + ": " + d.Detail
+}
+
+// Merge merges the Go types with the external model ext. Types other than
+// struct types are merged as types without fields.
+func (mg *Merger) Merge(types []*TypeModel, ext *ExternalModel) (*MergedModel, error) {
+	m := new(MergedModel)
+
+	externalTypes := make(map[string]*ExternalType, len(ext.Types))
+	for _, et := range ext.Types {
+		externalTypes[mg.key(et.Name)] = et
+	}
+	matched := make(map[*ExternalType]bool)
+	for _, tm := range types {
+		name := tm.Name
+		if mg.TypeName != nil {
+			name = mg.TypeName(tm)
+		}
+		mt := &MergedType{Name: tm.Name, Go: tm, External: externalTypes[mg.key(name)]}
+		if mt.External == nil {
+			m.AddDrift(Drift{Kind: DriftMissingExternally, Type: mt.Name, Detail: "missing externally"})
+		} else if matched[mt.External] {
+			return nil, fmt.Errorf("types %s and %s both match external type %s", matchedBy(m, mt.External), tm.Name, mt.External.Name)
+		} else {
+			matched[mt.External] = true
+		}
+		mg.mergeFields(m, mt)
+		m.Types = append(m.Types, mt)
+	}
+	for _, et := range ext.Types {
+		if matched[et] {
+			continue
+		}
+		mt := &MergedType{Name: et.Name, External: et}
+		m.AddDrift(Drift{Kind: DriftMissingInGo, Type: mt.Name, Detail: "missing in Go"})
+		mg.mergeFields(m, mt)
+		m.Types = append(m.Types, mt)
+	}
+
+	if mg.Hook != nil {
+		for _, mt := range m.Types {
+			if err := mg.Hook(m, mt); err != nil {
+				return nil, fmt.Errorf("merge %s: %w", mt.Name, err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// matchedBy returns the name of the merged type already paired with et.
+func matchedBy(m *MergedModel, et *ExternalType) string {
+	for _, mt := range m.Types {
+		if mt.External == et {
+			return mt.Name
+		}
+	}
+	return ""
+}
+
+// mergeFields pairs the fields of mt, recording drift in m. Fields are only
+// reported as missing when both sides of the type exist.
+func (mg *Merger) mergeFields(m *MergedModel, mt *MergedType) {
+	both := mt.Go != nil && mt.External != nil
+
+	externalFields := make(map[string]*ExternalField)
+	if mt.External != nil {
+		for _, ef := range mt.External.Fields {
+			externalFields[mg.key(ef.Name)] = ef
+		}
+	}
+	matched := make(map[*ExternalField]bool)
+	if mt.Go != nil {
+		for _, f := range mt.Go.Fields {
+			name, ok := mg.fieldName(f)
+			if !ok {
+				continue
+			}
+			mf := &MergedField{Name: f.Name, Go: f, External: externalFields[mg.key(name)]}
+			switch {
+			case mf.External == nil || matched[mf.External]:
+				mf.External = nil
+				if both {
+					m.AddDrift(Drift{Kind: DriftMissingExternally, Type: mt.Name, Field: mf.Name, Detail: "missing externally"})
+				}
+			case mg.Compatible != nil && !mg.Compatible(f, mf.External):
+				matched[mf.External] = true
+				m.AddDrift(Drift{
+					Kind:   DriftTypeMismatch,
+					Type:   mt.Name,
+					Field:  mf.Name,
+					Detail: fmt.Sprintf("Go type %s is not compatible with %s", f.Type, mf.External.Type),
+				})
+			default:
+				matched[mf.External] = true
+			}
+			mt.Fields = append(mt.Fields, mf)
+		}
+	}
+	if mt.External != nil {
+		for _, ef := range mt.External.Fields {
+			if matched[ef] {
+				continue
+			}
+			if both {
+				m.AddDrift(Drift{Kind: DriftMissingInGo, Type: mt.Name, Field: ef.Name, Detail: "missing in Go"})
+			}
+			mt.Fields = append(mt.Fields, &MergedField{Name: ef.Name, External: ef})
+		}
+	}
+}
+
+// fieldName returns the external name of the Go field f. The boolean result
+// is false if the field is excluded from the merge by its tag.
+func (mg *Merger) fieldName(f *FieldModel) (string, bool) {
+	if mg.Tag != "" {
+		if tag, ok := f.Tags.Get(mg.Tag); ok && tag.Name != "" {
+			return tag.Name, tag.Name != "-"
+		}
+	}
+	return f.Name, true
+}
+
+// key normalizes a name for comparison.
+func (mg *Merger) key(name string) string {
+	if mg.Key != nil {
+		return mg.Key(name)
+	}
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// AddDrift records drift found by a merge hook. Drift of an unknown kind is
+// recorded as DriftOther.
+func (m *MergedModel) AddDrift(d Drift) {
+	if d.Kind == "" {
+		d.Kind = DriftOther
+	}
+	m.Drift = append(m.Drift, d)
+}
+
+// Type returns the merged type with the given name.
+func (m *MergedModel) Type(name string) (*MergedType, bool) {
+	for _, mt := range m.Types {
+		if mt.Name == name {
+			return mt, true
+		}
+	}
+	return nil, false
+}
+
+// WriteDriftReport writes the drift as JSON to w, sorted by type and field,
+// as a list of objects with the fields kind, type, field and detail. An
+// empty list is written if there is no drift.
+func (m *MergedModel) WriteDriftReport(w io.Writer) error {
+	drift := append([]Drift{}, m.Drift...)
+	sort.SliceStable(drift, func(i, j int) bool {
+		if drift[i].Type != drift[j].Type {
+			return drift[i].Type < drift[j].Type
+		}
+		return drift[i].Field < drift[j].Field
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(drift)
+}
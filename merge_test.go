@@ -0,0 +1,245 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const mergeSrc = `package p
+
+type User struct {
+	ID       int64
+	Email    string ` + "`db:\"email_address\"`" + `
+	Nickname string
+	Age      string
+	cache    map[string]string ` + "`db:\"-\"`" + `
+}
+
+type Session struct {
+	Token string
+}
+`
+
+const mergeExternal = `{
+	"source": "postgres",
+	"types": [
+		{
+			"name": "user",
+			"fields": [
+				{"name": "id", "type": "bigint", "attrs": {"primary_key": true}},
+				{"name": "email_address", "type": "text"},
+				{"name": "age", "type": "integer"},
+				{"name": "created_at", "type": "timestamp"}
+			]
+		},
+		{"name": "audit_log", "fields": [{"name": "id", "type": "bigint"}]}
+	]
+}`
+
+var mergeTypes = map[string][]string{
+	"bigint":    {"int64"},
+	"integer":   {"int", "int32", "int64"},
+	"text":      {"string"},
+	"timestamp": {"time.Time"},
+}
+
+func testMerger() *Merger {
+	return &Merger{
+		Tag: "db",
+		Compatible: func(f *FieldModel, ef *ExternalField) bool {
+			for _, name := range mergeTypes[ef.Type] {
+				if types.TypeString(f.Type, nil) == name {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	fs, err := NewFileSetFromTexts(mergeSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ext ExternalModel
+	if err := json.Unmarshal([]byte(mergeExternal), &ext); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, err := testMerger().Merge(fs.Types(), &ext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var gotTypes []string
+	for _, mt := range m.Types {
+		gotTypes = append(gotTypes, mt.Name)
+	}
+	wantTypes := []string{"User", "Session", "audit_log"}
+	if !reflect.DeepEqual(gotTypes, wantTypes) {
+		t.Errorf("got types %q, wanted %q", gotTypes, wantTypes)
+	}
+
+	user, ok := m.Type("User")
+	if !ok {
+		t.Fatalf("User not found")
+	}
+	if user.External != ext.Types[0] {
+		t.Errorf("got external type %+v, wanted user", user.External)
+	}
+	var gotFields []string
+	for _, mf := range user.Fields {
+		name := mf.Name
+		if mf.Go != nil && mf.External != nil {
+			name += "=" + mf.External.Name
+		}
+		gotFields = append(gotFields, name)
+	}
+	wantFields := []string{"ID=id", "Email=email_address", "Nickname", "Age=age", "created_at"}
+	if !reflect.DeepEqual(gotFields, wantFields) {
+		t.Errorf("got fields %q, wanted %q", gotFields, wantFields)
+	}
+
+	var gotDrift []string
+	for _, d := range m.Drift {
+		gotDrift = append(gotDrift, string(d.Kind)+" "+d.String())
+	}
+	wantDrift := []string{
+		"missing_externally User.Nickname: missing externally",
+		"type_mismatch User.Age: Go type string is not compatible with integer",
+		"missing_in_go User.created_at: missing in Go",
+		"missing_externally Session: missing externally",
+		"missing_in_go audit_log: missing in Go",
+	}
+	if !reflect.DeepEqual(gotDrift, wantDrift) {
+		t.Errorf("got drift %q, wanted %q", gotDrift, wantDrift)
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	fs, err := NewFileSetFromTexts(mergeSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ext := &ExternalModel{Types: []*ExternalType{
+		{Name: "Sessions", Fields: []*ExternalField{{Name: "TOKEN"}}},
+	}}
+
+	t.Run("type name", func(t *testing.T) {
+		mg := &Merger{
+			TypeName: func(tm *TypeModel) string { return tm.Name + "s" },
+			Key:      func(name string) string { return name },
+		}
+		m, err := mg.Merge(fs.Types(), ext)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		session, _ := m.Type("Session")
+		if session.External != ext.Types[0] {
+			t.Fatalf("got external type %+v, wanted Sessions", session.External)
+		}
+		// Key is case sensitive, so Token does not match TOKEN.
+		if len(session.Fields) != 2 || session.Fields[0].External != nil {
+			t.Errorf("got fields %+v, wanted Token and TOKEN unmatched", session.Fields)
+		}
+	})
+
+	t.Run("hook", func(t *testing.T) {
+		mg := &Merger{
+			TypeName: func(tm *TypeModel) string { return tm.Name + "s" },
+			Hook: func(m *MergedModel, mt *MergedType) error {
+				if mt.Go == nil || mt.External == nil {
+					return nil
+				}
+				mt.Attrs = map[string]any{"table": mt.External.Name}
+				m.AddDrift(Drift{Type: mt.Name, Detail: "checked"})
+				return nil
+			},
+		}
+		m, err := mg.Merge(fs.Types(), ext)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		session, _ := m.Type("Session")
+		if got := session.Attrs["table"]; got != "Sessions" {
+			t.Errorf("got table %v, wanted Sessions", got)
+		}
+		last := m.Drift[len(m.Drift)-1]
+		if want := (Drift{Kind: DriftOther, Type: "Session", Detail: "checked"}); last != want {
+			t.Errorf("got drift %+v, wanted %+v", last, want)
+		}
+	})
+
+	t.Run("hook error", func(t *testing.T) {
+		errHook := errors.New("hook failed")
+		mg := &Merger{Hook: func(*MergedModel, *MergedType) error { return errHook }}
+		if _, err := mg.Merge(fs.Types(), ext); !errors.Is(err, errHook) {
+			t.Errorf("got error %v, wanted %v", err, errHook)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		mg := &Merger{TypeName: func(*TypeModel) string { return "sessions" }}
+		if _, err := mg.Merge(fs.Types(), ext); err == nil {
+			t.Errorf("got no error, wanted error for two types matching sessions")
+		}
+	})
+}
+
+func TestReadExternalModel(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(filename, []byte(mergeExternal), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ext, err := ReadExternalModel(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ext.Source != "postgres" || len(ext.Types) != 2 || len(ext.Types[0].Fields) != 4 {
+		t.Errorf("got %+v, wanted postgres model with 2 types", ext)
+	}
+	if got := ext.Types[0].Fields[0].Attrs["primary_key"]; got != true {
+		t.Errorf("got primary_key %v, wanted true", got)
+	}
+
+	if err := os.WriteFile(filename, []byte("{"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ReadExternalModel(filename); err == nil {
+		t.Errorf("got no error, wanted error for malformed JSON")
+	}
+}
+
+func TestWriteDriftReport(t *testing.T) {
+	m := &MergedModel{Drift: []Drift{
+		{Kind: DriftMissingInGo, Type: "b", Detail: "missing in Go"},
+		{Kind: DriftTypeMismatch, Type: "A", Field: "X", Detail: "mismatch"},
+	}}
+	var buf bytes.Buffer
+	if err := m.WriteDriftReport(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []Drift
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Drift{m.Drift[1], m.Drift[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	buf.Reset()
+	if err := new(MergedModel).WriteDriftReport(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("got %q, wanted empty list", got)
+	}
+}
@@ -0,0 +1,124 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// MethodSet holds the methods of a named type.
+type MethodSet struct {
+	// Value holds the methods that may be called on a value of the type,
+	// sorted by name.
+	Value []*MethodModel
+
+	// Pointer holds the methods that may be called on a pointer to the type,
+	// sorted by name. It is a superset of Value for non-interface types.
+	Pointer []*MethodModel
+}
+
+// MethodModel describes a method in the method set of a type.
+type MethodModel struct {
+	// Name is the name of the method.
+	Name string
+
+	// Recv is the name of the type that declares the method. For a promoted
+	// method it differs from the type whose method set contains it. Recv is
+	// empty for a method declared by an unnamed interface.
+	Recv string
+
+	// PointerRecv is true if the method is declared with a pointer receiver.
+	PointerRecv bool
+
+	// Promoted is true if the method is promoted from an embedded field.
+	Promoted bool
+
+	// Path holds the names of the embedded fields through which a promoted
+	// method is reached, outermost first. It is empty for methods that are
+	// not promoted.
+	Path []string
+
+	// Params holds the method's parameters.
+	Params []*ParamModel
+
+	// Results holds the method's results.
+	Results []*ParamModel
+
+	// Variadic is true if the final parameter is variadic.
+	Variadic bool
+
+	// Doc is the text of the method's doc comment. It is empty for methods
+	// declared outside the package.
+	Doc string
+
+	// Object is the type checked method object.
+	Object *types.Func
+}
+
+// Signature returns the type checked signature of the method.
+func (m *MethodModel) Signature() *types.Signature {
+	return m.Object.Type().(*types.Signature)
+}
+
+// MethodsOf returns the value and pointer method sets of the named package
+// level type, including methods promoted from embedded fields.
+func (fs *FileSet) MethodsOf(typeName string) (*MethodSet, error) {
+	obj, ok := fs.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", typeName)
+	}
+
+	t := obj.Type()
+	return &MethodSet{
+		Value:   fs.methodModels(types.NewMethodSet(t)),
+		Pointer: fs.methodModels(types.NewMethodSet(types.NewPointer(t))),
+	}, nil
+}
+
+// methodModels creates models of each method in a method set.
+func (fs *FileSet) methodModels(ms *types.MethodSet) []*MethodModel {
+	methods := make([]MethodModel, ms.Len())
+	models := make([]*MethodModel, 0, ms.Len())
+	for i := 0; i < ms.Len(); i++ {
+		sel := ms.At(i)
+		fn := sel.Obj().(*types.Func)
+		sig := fn.Type().(*types.Signature)
+
+		m := &methods[i]
+		m.Name = fn.Name()
+		m.Params, m.Results = paramModels(sig.Params(), sig.Results())
+		m.Variadic = sig.Variadic()
+		m.Doc = fs.DocFor(fn)
+		m.Object = fn
+
+		if recv := sig.Recv(); recv != nil {
+			_, m.PointerRecv = recv.Type().(*types.Pointer)
+			if n := NamedOf(recv.Type()); n != nil {
+				m.Recv = n.Obj().Name()
+			}
+		}
+
+		if idx := sel.Index(); len(idx) > 1 {
+			m.Promoted = true
+			m.Path = embeddingPath(sel.Recv(), idx[:len(idx)-1])
+		}
+
+		models = append(models, m)
+	}
+	return models
+}
+
+// embeddingPath converts a sequence of field indices starting at type t into
+// the names of the embedded fields they select.
+func embeddingPath(t types.Type, idx []int) []string {
+	path := make([]string, 0, len(idx))
+	for _, i := range idx {
+		st, ok := Deref(t).Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		f := st.Field(i)
+		path = append(path, f.Name())
+		t = f.Type()
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMethodsOf(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "sync"
+
+		type Base struct{}
+
+		func (Base) Name() string { return "" }
+		func (*Base) SetName(name string) {}
+
+		type Inner struct{ Base }
+
+		type Outer struct {
+			Inner
+			sync.Mutex
+		}
+
+		func (o Outer) Value(xs ...int) (n int, err error) { return 0, nil }
+
+		type Iface interface {
+			Do(x int) error
+		}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ms, err := fs.MethodsOf("Outer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	describe := func(ms []*MethodModel) []string {
+		s := []string{}
+		for _, m := range ms {
+			d := m.Recv + "." + m.Name
+			if m.PointerRecv {
+				d = "*" + d
+			}
+			if m.Promoted {
+				d += " via " + strings.Join(m.Path, ".")
+			}
+			s = append(s, d)
+		}
+		return s
+	}
+
+	wantValue := []string{"Base.Name via Inner.Base", "Outer.Value"}
+	if got := describe(ms.Value); !reflect.DeepEqual(got, wantValue) {
+		t.Errorf("got value methods %+v, wanted %+v", got, wantValue)
+	}
+
+	wantPointer := []string{
+		"*Mutex.Lock via Mutex",
+		"Base.Name via Inner.Base",
+		"*Base.SetName via Inner.Base",
+		"*Mutex.TryLock via Mutex",
+		"*Mutex.Unlock via Mutex",
+		"Outer.Value",
+	}
+	if got := describe(ms.Pointer); !reflect.DeepEqual(got, wantPointer) {
+		t.Errorf("got pointer methods %+v, wanted %+v", got, wantPointer)
+	}
+
+	value := ms.Value[1]
+	if !value.Variadic || len(value.Params) != 1 || value.Params[0].Name != "xs" || len(value.Results) != 2 || value.Results[1].Name != "err" {
+		t.Errorf("unexpected signature decomposition for Value: %+v", value)
+	}
+
+	ms, err = fs.MethodsOf("Iface")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := describe(ms.Value), []string{"Iface.Do"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got interface methods %+v, wanted %+v", got, want)
+	}
+
+	if _, err := fs.MethodsOf("Missing"); err == nil {
+		t.Errorf("got no error for missing type")
+	}
+}
@@ -0,0 +1,260 @@
+// Package mock generates mock implementations of interfaces for use in tests.
+//
+// For an interface Store with a method Get(key string) ([]byte, error) the
+// generated MockStore type has a GetFunc field that, when set, is called to
+// produce the results of Get, and a GetCalls field that records the
+// arguments of every call. Methods whose func field is nil return zero
+// values. Mocks are safe for concurrent use, although the func and calls
+// fields should only be accessed while no calls are in progress.
+//
+// When expectations are enabled the mock additionally supports registering
+// expected calls in the style of github.com/stretchr/testify/mock:
+//
+//	m.On("Get", "key").Return([]byte("value"), nil).Times(1)
+//	...
+//	m.AssertExpectations(t)
+//
+// An expectation registered without arguments matches any call to the
+// method. Arguments are otherwise compared with reflect.DeepEqual.
+package mock
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/iand/gen"
+)
+
+// Options configures the generated mock.
+type Options struct {
+	// Name is the name of the mock type. The default is the name of the
+	// interface prefixed by "Mock".
+	Name string
+
+	// Package is the name of the package the mock is generated into. The
+	// default is the package of the FileSet. When a different package is
+	// named, types declared in the FileSet are qualified by its import path.
+	Package string
+
+	// Expectations adds testify style On, Return, Times and
+	// AssertExpectations methods to the mock.
+	Expectations bool
+}
+
+// model is the data supplied to the mock template.
+type model struct {
+	Package      string
+	Name         string
+	Interface    string
+	Imports      []importSpec
+	Sync         string
+	Reflect      string
+	Expectations bool
+	Methods      []*method
+	ResultFunc   string
+}
+
+type importSpec struct {
+	Path, Name string
+}
+
+type method struct {
+	Name       string
+	CallType   string
+	Params     []*param
+	ParamList  string
+	ResultList string
+	Args       string
+	Results    []*result
+}
+
+type param struct {
+	Name, Field, Type string
+}
+
+type result struct {
+	Type, Zero string
+}
+
+var identRx = regexp.MustCompile(`[\pL_][\pL\pN_]*`)
+
+// Generate writes a mock implementation of the named interface to o. The
+// interface may be declared in fs or qualified by the name or import path of
+// another package, such as "io.ReadCloser".
+func Generate(fs *gen.FileSet, o *gen.Output, iface string, opts Options) error {
+	obj, err := fs.LookupInterface(iface)
+	if err != nil {
+		return err
+	}
+	if n, ok := obj.Type().(*types.Named); ok && n.TypeParams().Len() > 0 {
+		return fmt.Errorf("interface %s is generic", iface)
+	}
+	it := obj.Type().Underlying().(*types.Interface)
+	if it.NumMethods() == 0 {
+		return fmt.Errorf("interface %s has no methods", iface)
+	}
+
+	m := &model{
+		Package:      opts.Package,
+		Name:         opts.Name,
+		Expectations: opts.Expectations,
+	}
+	if m.Package == "" {
+		m.Package = fs.Package.Name()
+	}
+	if m.Name == "" {
+		m.Name = "Mock" + obj.Name()
+	}
+	m.ResultFunc = lowerFirst(m.Name) + "Result"
+
+	imports := gen.NewImports()
+	m.Sync = imports.Add("sync", "")
+	if m.Expectations {
+		m.Reflect = imports.Add("reflect", "")
+	}
+
+	var q types.Qualifier
+	if m.Package == fs.Package.Name() {
+		q = imports.Qualifier(fs.Package)
+	} else {
+		local := fs.ImportPath()
+		q = func(p *types.Package) string {
+			if p == fs.Package {
+				return imports.Add(local, p.Name())
+			}
+			return imports.Add(p.Path(), p.Name())
+		}
+	}
+	m.Interface = types.TypeString(obj.Type(), q)
+
+	reserved := map[string]bool{
+		"On":                 m.Expectations,
+		"AssertExpectations": m.Expectations,
+		"expected":           m.Expectations,
+	}
+	for i := 0; i < it.NumMethods(); i++ {
+		fn := it.Method(i)
+		for _, field := range []string{fn.Name() + "Func", fn.Name() + "Calls"} {
+			if lookupMethod(it, field) {
+				return fmt.Errorf("method %s conflicts with the generated field for method %s", field, fn.Name())
+			}
+		}
+		if reserved[fn.Name()] {
+			return fmt.Errorf("method %s conflicts with generated expectation method", fn.Name())
+		}
+		m.Methods = append(m.Methods, newMethod(m.Name, fn, q))
+	}
+
+	for _, path := range imports.Paths() {
+		name, _ := imports.Name(path)
+		m.Imports = append(m.Imports, importSpec{Path: path, Name: name})
+	}
+
+	src, err := mockTemplate.Render(m)
+	if err != nil {
+		return err
+	}
+	_, err = o.Write(src)
+	return err
+}
+
+// newMethod builds the template model of the mocked method fn.
+func newMethod(mockName string, fn *types.Func, q types.Qualifier) *method {
+	sig := fn.Type().(*types.Signature)
+	mm := &method{
+		Name:     fn.Name(),
+		CallType: mockName + fn.Name() + "Call",
+	}
+
+	// Parameter names must not shadow the receiver, the locals of the
+	// method body or any identifier used to spell a type in the body.
+	used := map[string]bool{"m": true, "results": true, "ok": true}
+	for _, tup := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tup.Len(); i++ {
+			for _, id := range identRx.FindAllString(types.TypeString(tup.At(i).Type(), q), -1) {
+				used[id] = true
+			}
+		}
+	}
+
+	fields := map[string]bool{}
+	var params, args []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		name := v.Name()
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		name = unique(name, used)
+		field := unique(upperFirst(name), fields)
+
+		typ := types.TypeString(v.Type(), q)
+		paramType := typ
+		arg := name
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			paramType = "..." + types.TypeString(v.Type().(*types.Slice).Elem(), q)
+			arg += "..."
+		}
+
+		mm.Params = append(mm.Params, &param{Name: name, Field: field, Type: typ})
+		params = append(params, name+" "+paramType)
+		args = append(args, arg)
+	}
+	mm.ParamList = strings.Join(params, ", ")
+	mm.Args = strings.Join(args, ", ")
+
+	var results []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		t := sig.Results().At(i).Type()
+		r := &result{Type: types.TypeString(t, q), Zero: gen.ZeroValue(t, q)}
+		mm.Results = append(mm.Results, r)
+		results = append(results, r.Type)
+	}
+	switch len(results) {
+	case 0:
+	case 1:
+		mm.ResultList = " " + results[0]
+	default:
+		mm.ResultList = " (" + strings.Join(results, ", ") + ")"
+	}
+
+	return mm
+}
+
+// lookupMethod reports whether it has a method named name.
+func lookupMethod(it *types.Interface, name string) bool {
+	for i := 0; i < it.NumMethods(); i++ {
+		if it.Method(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unique returns name, or name with a numeric suffix if it has already been
+// used, and records the result as used.
+func unique(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// lowerFirst lowercases the first letter of s.
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// upperFirst uppercases the first letter of s.
+func upperFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
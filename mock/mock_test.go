@@ -0,0 +1,255 @@
+package mock
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"context"
+	"io"
+)
+
+type Option func(*Options)
+
+type Options struct{ Retries int }
+
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(context.Context, string, []byte) error
+	List(prefix string, opts ...Option) []string
+	Dump(io io.Writer) (n int64, err error)
+	Close()
+}
+`
+
+const testUse = `package p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMockFuncs(t *testing.T) {
+	m := &MockStore{
+		GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+			return []byte(key), nil
+		},
+	}
+	var s Store = m
+
+	got, err := s.Get(context.Background(), "k")
+	if err != nil || string(got) != "k" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if err := s.Put(context.Background(), "k", nil); err != nil {
+		t.Errorf("got %v from unset func", err)
+	}
+	s.List("a", nil, nil)
+	s.Close()
+
+	if len(m.GetCalls) != 1 || m.GetCalls[0].Key != "k" {
+		t.Errorf("got calls %+v", m.GetCalls)
+	}
+	if len(m.PutCalls) != 1 || m.PutCalls[0].Arg1 != "k" {
+		t.Errorf("got calls %+v", m.PutCalls)
+	}
+	if len(m.ListCalls) != 1 || len(m.ListCalls[0].Opts) != 2 {
+		t.Errorf("got calls %+v", m.ListCalls)
+	}
+	if len(m.CloseCalls) != 1 {
+		t.Errorf("got calls %+v", m.CloseCalls)
+	}
+}
+
+func TestMockExpectations(t *testing.T) {
+	m := &MockStore{}
+	boom := errors.New("boom")
+	m.On("Get", context.Background(), "k").Return([]byte("v"), nil).Once()
+	m.On("Put").Return(boom)
+	m.On("Close")
+
+	got, err := m.Get(context.Background(), "k")
+	if err != nil || string(got) != "v" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := m.Get(context.Background(), "k"); err != nil {
+		t.Errorf("got %v after expectation exhausted", err)
+	}
+	if err := m.Put(context.Background(), "x", nil); err != boom {
+		t.Errorf("got %v, wanted %v", err, boom)
+	}
+
+	rec := &recorder{}
+	if m.AssertExpectations(rec) {
+		t.Errorf("expectations met without call to Close")
+	}
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "Close") {
+		t.Errorf("got errors %q", rec.errors)
+	}
+
+	m.Close()
+	if !m.AssertExpectations(t) {
+		t.Errorf("expectations not met")
+	}
+}
+
+type recorder struct{ errors []string }
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("mock")
+	if err := Generate(fs, o, "Store", Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"type MockStore struct {",
+		"GetFunc func(ctx context.Context, key string) ([]byte, error)",
+		"PutCalls []MockStorePutCall",
+		"func (m *MockStore) Put(arg0 context.Context, arg1 string, arg2 []byte) error {",
+		"func (m *MockStore) List(prefix string, opts ...Option) []string {",
+		"return m.ListFunc(prefix, opts...)",
+		"func (m *MockStore) Dump(io2 io.Writer) (int64, error) {",
+		"var _ Store = (*MockStore)(nil)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	for _, unwanted := range []string{"On(", "reflect"} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated source unexpectedly contains %q:\n%s", unwanted, src)
+		}
+	}
+}
+
+func TestGenerateOptions(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("mock")
+	if err := Generate(fs, o, "io.ReadCloser", Options{Name: "FakeReader", Package: "fakes", Expectations: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"package fakes",
+		"type FakeReader struct {",
+		"var _ io.ReadCloser = (*FakeReader)(nil)",
+		"func (m *FakeReader) On(method string, args ...any) *FakeReaderExpectation {",
+		"return fakeReaderResult[int](results, 0), fakeReaderResult[error](results, 1)",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(`package p
+		type Empty interface{}
+		type Clash interface {
+			Get()
+			GetFunc()
+		}
+		type Reserved interface {
+			On()
+		}
+		type Generic[T any] interface {
+			Get() T
+		}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		iface string
+		opts  Options
+	}{
+		{iface: "Missing"},
+		{iface: "Empty"},
+		{iface: "Clash"},
+		{iface: "Reserved", opts: Options{Expectations: true}},
+		{iface: "Generic"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.iface, func(t *testing.T) {
+			if err := Generate(fs, gen.NewOutput("mock"), tc.iface, tc.opts); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+func TestGeneratedMockWorks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.21\n",
+		"p.go":      testSrc,
+		"p_test.go": testUse,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "p.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("mock")
+	if err := Generate(fs, o, "Store", Options{Expectations: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "mock_gen.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", "-v", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated mock failed: %v\n%s\n%s", err, out, src)
+	}
+}
@@ -0,0 +1,171 @@
+package mock
+
+import (
+	"github.com/iand/gen"
+)
+
+// mockTemplate is the built-in template used to generate mocks.
+var mockTemplate = mustTemplate("mock", mockText)
+
+func mustTemplate(name, text string) *gen.TemplateType {
+	tt, err := gen.NewTemplateType(name, text, nil)
+	if err != nil {
+		panic(err)
+	}
+	return tt
+}
+
+const mockText = `package {{.Package}}
+{{range .Imports}}{{$name := import .Path .Name}}{{end}}
+// {{.Name}} is a mock implementation of {{.Interface}}.
+type {{.Name}} struct {
+	mu {{.Sync}}.Mutex
+{{range .Methods}}
+	// {{.Name}}Func is called by {{.Name}} if set.
+	{{.Name}}Func func({{.ParamList}}){{.ResultList}}
+
+	// {{.Name}}Calls records the arguments of each call to {{.Name}}.
+	{{.Name}}Calls []{{.CallType}}
+{{end}}
+{{- if .Expectations}}
+	expectations []*{{.Name}}Expectation
+{{- end}}
+}
+
+var _ {{.Interface}} = (*{{.Name}})(nil)
+{{range $m := .Methods}}
+// {{.CallType}} records the arguments of a call to {{$.Name}}.{{.Name}}.
+type {{.CallType}} struct {
+{{- range .Params}}
+	{{.Field}} {{.Type}}
+{{- end}}
+}
+
+// {{.Name}} records the call and returns the results of {{.Name}}Func if it is set.
+{{- if $.Expectations}} Otherwise the
+// results of the first matching expectation are returned.
+{{- end}} Otherwise zero values are returned.
+func (m *{{$.Name}}) {{.Name}}({{.ParamList}}){{.ResultList}} {
+	m.mu.Lock()
+	m.{{.Name}}Calls = append(m.{{.Name}}Calls, {{.CallType}}{
+	{{- range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Field}}: {{$p.Name}}{{end -}}
+	})
+	m.mu.Unlock()
+
+	if m.{{.Name}}Func != nil {
+		{{if .Results}}return {{end}}m.{{.Name}}Func({{.Args}})
+		{{- if not .Results}}
+		return
+		{{- end}}
+	}
+{{- if $.Expectations}}
+{{- if .Results}}
+	if results, ok := m.expected("{{.Name}}"{{range .Params}}, {{.Name}}{{end}}); ok {
+		return {{range $i, $r := .Results}}{{if $i}}, {{end}}{{$.ResultFunc}}[{{$r.Type}}](results, {{$i}}){{end}}
+	}
+{{- else}}
+	m.expected("{{.Name}}"{{range .Params}}, {{.Name}}{{end}})
+{{- end}}
+{{- end}}
+{{- if .Results}}
+	return {{range $i, $r := .Results}}{{if $i}}, {{end}}{{$r.Zero}}{{end}}
+{{- end}}
+}
+{{end}}
+{{- if .Expectations}}
+// {{.Name}}Expectation is an expected call to a method of {{.Name}}.
+type {{.Name}}Expectation struct {
+	// Method is the name of the expected method.
+	Method string
+
+	// Args holds the expected arguments. A nil slice matches any arguments.
+	Args []any
+
+	// Returns holds the results returned by the call.
+	Returns []any
+
+	times int
+	calls int
+}
+
+// Return sets the results returned by the expected call.
+func (e *{{.Name}}Expectation) Return(values ...any) *{{.Name}}Expectation {
+	e.Returns = values
+	return e
+}
+
+// Times sets the number of times the call is expected to be made. By default
+// the call is expected to be made at least once.
+func (e *{{.Name}}Expectation) Times(n int) *{{.Name}}Expectation {
+	e.times = n
+	return e
+}
+
+// Once is shorthand for Times(1).
+func (e *{{.Name}}Expectation) Once() *{{.Name}}Expectation {
+	return e.Times(1)
+}
+
+// On registers an expected call to the named method with the given arguments.
+// If no arguments are given the expectation matches any call to the method.
+func (m *{{.Name}}) On(method string, args ...any) *{{.Name}}Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &{{.Name}}Expectation{Method: method, Args: args}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// AssertExpectations reports an error to t for each expected call that was
+// not made the expected number of times and reports whether all expectations
+// were met.
+func (m *{{.Name}}) AssertExpectations(t interface {
+	Helper()
+	Errorf(format string, args ...any)
+}) bool {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ok := true
+	for _, e := range m.expectations {
+		switch {
+		case e.times > 0 && e.calls != e.times:
+			t.Errorf("expected call %s%v made %d times, wanted %d", e.Method, e.Args, e.calls, e.times)
+			ok = false
+		case e.calls == 0:
+			t.Errorf("expected call %s%v was not made", e.Method, e.Args)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// expected finds the first expectation matching a call to method with args,
+// records the call against it and returns its results.
+func (m *{{.Name}}) expected(method string, args ...any) ([]any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if e.Method != method || (e.times > 0 && e.calls >= e.times) {
+			continue
+		}
+		if e.Args != nil && !{{.Reflect}}.DeepEqual(e.Args, args) {
+			continue
+		}
+		e.calls++
+		return e.Returns, true
+	}
+	return nil, false
+}
+
+// {{.ResultFunc}} returns the i'th of the results of an expectation, or the
+// zero value of T if the result is absent or has a different type.
+func {{.ResultFunc}}[T any](results []any, i int) T {
+	var v T
+	if i < len(results) {
+		v, _ = results[i].(T)
+	}
+	return v
+}
+{{- end}}
+`
@@ -0,0 +1,382 @@
+package gen
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// TypeModel describes a named type declared in a FileSet.
+type TypeModel struct {
+	// Name is the name of the type.
+	Name string
+
+	// Doc is the text of the type's doc comment.
+	Doc string
+
+	// TypeParams holds the type parameters of a generic type. It is empty if
+	// the type is not generic.
+	TypeParams []*TypeParamModel
+
+	// Fields holds the fields of a struct type in declaration order. It is
+	// empty for other kinds of type.
+	Fields []*FieldModel
+
+	// Spec is the type's declaration.
+	Spec *ast.TypeSpec
+
+	// Object is the type checked type name object.
+	Object *types.TypeName
+}
+
+// FieldModel describes a field of a struct type.
+type FieldModel struct {
+	// Name is the name of the field. The name of an embedded field is the
+	// name of its type.
+	Name string
+
+	// Type is the type of the field.
+	Type types.Type
+
+	// Embedded is true if the field is an embedded field.
+	Embedded bool
+
+	// Promoted is true if the field is promoted from an embedded field. Only
+	// FieldsOf with FlattenEmbedded returns promoted fields.
+	Promoted bool
+
+	// Path holds the names of the embedded fields through which a promoted
+	// field is reached, outermost first. It is empty for fields that are not
+	// promoted.
+	Path []string
+
+	// Exported is true if the field is exported.
+	Exported bool
+
+	// Doc is the text of the field's doc comment.
+	Doc string
+
+	// Comment is the text of the field's line comment.
+	Comment string
+
+	// Tag is the raw, unquoted struct tag of the field.
+	Tag string
+
+	// Tags holds the parsed struct tag. It is empty if the tag is malformed.
+	Tags Tags
+
+	// Field is the declaration of the field. A single declaration may
+	// declare several fields.
+	Field *ast.Field
+
+	// Object is the type checked field object.
+	Object *types.Var
+}
+
+// FuncModel describes a function or method declared in a FileSet.
+type FuncModel struct {
+	// Name is the name of the function.
+	Name string
+
+	// Recv is the name of the receiver's base type if the function is a
+	// method, or empty otherwise.
+	Recv string
+
+	// PointerRecv is true if the function is a method with a pointer receiver.
+	PointerRecv bool
+
+	// Doc is the text of the function's doc comment.
+	Doc string
+
+	// TypeParams holds the type parameters of a generic function. For a
+	// method it holds the type parameters of the receiver's generic type,
+	// named as in the receiver of the method's declaration.
+	TypeParams []*TypeParamModel
+
+	// Params holds the function's parameters. The receiver is not included.
+	Params []*ParamModel
+
+	// Results holds the function's results.
+	Results []*ParamModel
+
+	// Variadic is true if the final parameter is variadic.
+	Variadic bool
+
+	// Pure is a best-effort indication that the function has no side effects:
+	// it does not write to package level variables or through pointers it was
+	// given, does not perform I/O or communicate on channels and only calls
+	// functions that are themselves pure. A false value does not mean the
+	// function definitely has side effects.
+	Pure bool
+
+	// Decl is the function's declaration.
+	Decl *ast.FuncDecl
+
+	// Object is the type checked function object.
+	Object *types.Func
+}
+
+// FullName returns the name of the function qualified by its receiver type
+// name for methods, such as "T.String".
+func (m *FuncModel) FullName() string {
+	if m.Recv == "" {
+		return m.Name
+	}
+	return m.Recv + "." + m.Name
+}
+
+// Signature returns the type checked signature of the function.
+func (m *FuncModel) Signature() *types.Signature {
+	return m.Object.Type().(*types.Signature)
+}
+
+// ParamModel describes a parameter or result of a function.
+type ParamModel struct {
+	// Name is the name of the parameter, which may be empty.
+	Name string
+
+	// Type is the type of the parameter. The type of a variadic parameter is
+	// a slice.
+	Type types.Type
+}
+
+// Types returns models of all the named types declared at package level in fs
+// in the order they are declared.
+func (fs *FileSet) Types() []*TypeModel {
+	n := 0
+	fs.eachTypeSpec(func(*ast.GenDecl, *ast.TypeSpec) bool {
+		n++
+		return true
+	})
+
+	// Allocate all of the models at once rather than one at a time.
+	backing := make([]TypeModel, n)
+	models := make([]*TypeModel, 0, n)
+	fs.eachTypeSpec(func(gd *ast.GenDecl, ts *ast.TypeSpec) bool {
+		m := &backing[len(models)]
+		if fs.initTypeModel(m, gd, ts) {
+			models = append(models, m)
+		}
+		return true
+	})
+	return models
+}
+
+// Type returns a model of the named type declared at package level. The
+// boolean result is false if no such type is declared.
+func (fs *FileSet) Type(name string) (*TypeModel, bool) {
+	var m *TypeModel
+	fs.eachTypeSpec(func(gd *ast.GenDecl, ts *ast.TypeSpec) bool {
+		if ts.Name.Name != name {
+			return true
+		}
+		m = new(TypeModel)
+		if !fs.initTypeModel(m, gd, ts) {
+			m = nil
+		}
+		return m == nil
+	})
+	return m, m != nil
+}
+
+// eachTypeSpec calls f for each package level type declaration in fs until f
+// returns false. Unlike EachType it does not descend into function bodies.
+func (fs *FileSet) eachTypeSpec(f func(*ast.GenDecl, *ast.TypeSpec) bool) {
+	for _, file := range fs.AstFiles {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				if !f(gd, spec.(*ast.TypeSpec)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// initTypeModel initializes m as a model of the type declared by ts. It
+// reports false if ts does not declare a type checked type. The models of a
+// struct's fields are allocated together to limit allocations when modelling
+// packages with many types.
+func (fs *FileSet) initTypeModel(m *TypeModel, gd *ast.GenDecl, ts *ast.TypeSpec) bool {
+	obj, ok := fs.TypeInfo.Defs[ts.Name].(*types.TypeName)
+	if !ok {
+		return false
+	}
+
+	*m = TypeModel{
+		Name:   ts.Name.Name,
+		Doc:    ts.Doc.Text(),
+		Spec:   ts,
+		Object: obj,
+	}
+	if m.Doc == "" && len(gd.Specs) == 1 {
+		m.Doc = gd.Doc.Text()
+	}
+	if named, ok := obj.Type().(*types.Named); ok {
+		m.TypeParams = typeParamModels(named.TypeParams())
+	}
+
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return true
+	}
+
+	n := st.Fields.NumFields()
+	if n == 0 {
+		return true
+	}
+	fields := make([]FieldModel, 0, n)
+	m.Fields = make([]*FieldModel, 0, n)
+	for _, field := range st.Fields.List {
+		tag := ""
+		tags := Tags{}
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+			if parsed, err := ParseTags(tag); err == nil {
+				tags = parsed
+			}
+		}
+		doc, comment := field.Doc.Text(), field.Comment.Text()
+
+		idents := field.Names
+		if len(idents) == 0 {
+			idents = []*ast.Ident{embeddedIdent(field.Type)}
+		}
+		for _, id := range idents {
+			v, ok := fs.TypeInfo.Defs[id].(*types.Var)
+			if !ok {
+				continue
+			}
+			fields = append(fields, FieldModel{
+				Name:     v.Name(),
+				Type:     v.Type(),
+				Embedded: v.Embedded(),
+				Exported: v.Exported(),
+				Doc:      doc,
+				Comment:  comment,
+				Tag:      tag,
+				Tags:     tags,
+				Field:    field,
+				Object:   v,
+			})
+			m.Fields = append(m.Fields, &fields[len(fields)-1])
+		}
+	}
+	return true
+}
+
+// embeddedIdent returns the identifier naming the type of an embedded field.
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// Funcs returns models of all the functions and methods declared in fs in the
+// order they are declared.
+func (fs *FileSet) Funcs() []*FuncModel {
+	purity := newPurityChecker(fs)
+
+	models := make([]*FuncModel, 0, len(purity.decls))
+	fs.EachFunc(func(decl *ast.FuncDecl) bool {
+		if m := fs.funcModel(decl); m != nil {
+			m.Pure = purity.isPure(m.Object)
+			models = append(models, m)
+		}
+		return true
+	})
+	return models
+}
+
+// Func returns a model of the named function. Methods are named by their
+// receiver's base type name and method name separated by a dot, such as
+// "T.String". The boolean result is false if no such function is declared.
+func (fs *FileSet) Func(name string) (*FuncModel, bool) {
+	recv, fn, isMethod := strings.Cut(name, ".")
+	if !isMethod {
+		recv, fn = "", name
+	}
+
+	var m *FuncModel
+	fs.EachFunc(func(decl *ast.FuncDecl) bool {
+		if decl.Name.Name != fn || (decl.Recv == nil) != (recv == "") {
+			return true
+		}
+		if fm := fs.funcModel(decl); fm != nil && fm.Recv == recv {
+			m = fm
+			return false
+		}
+		return true
+	})
+	if m == nil {
+		return nil, false
+	}
+	m.Pure = newPurityChecker(fs).isPure(m.Object)
+	return m, true
+}
+
+// funcModel creates a model of the function declared by decl.
+func (fs *FileSet) funcModel(decl *ast.FuncDecl) *FuncModel {
+	obj, ok := fs.TypeInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	sig := obj.Type().(*types.Signature)
+
+	m := &FuncModel{
+		Name:     decl.Name.Name,
+		Doc:      decl.Doc.Text(),
+		Variadic: sig.Variadic(),
+		Decl:     decl,
+		Object:   obj,
+	}
+	m.Params, m.Results = paramModels(sig.Params(), sig.Results())
+
+	if recv := sig.Recv(); recv != nil {
+		_, m.PointerRecv = recv.Type().(*types.Pointer)
+		m.Recv = recvTypeName(recv.Type())
+		m.TypeParams = typeParamModels(sig.RecvTypeParams())
+	} else {
+		m.TypeParams = typeParamModels(sig.TypeParams())
+	}
+
+	return m
+}
+
+// paramModels creates models of each variable in the parameter and result
+// tuples of a signature, allocating all of the models together.
+func paramModels(params, results *types.Tuple) ([]*ParamModel, []*ParamModel) {
+	n := params.Len() + results.Len()
+	models := make([]ParamModel, n)
+	ptrs := make([]*ParamModel, n)
+	for i := range models {
+		var v *types.Var
+		if i < params.Len() {
+			v = params.At(i)
+		} else {
+			v = results.At(i - params.Len())
+		}
+		models[i] = ParamModel{Name: v.Name(), Type: v.Type()}
+		ptrs[i] = &models[i]
+	}
+	return ptrs[:params.Len():params.Len()], ptrs[params.Len():]
+}
@@ -0,0 +1,230 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFuncs(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		// X does something.
+		func X(a string, b ...int) (n int, err error) { return 0, nil }
+
+		type T struct{}
+
+		func (T) Value() {}
+
+		func (t *T) Pointer(int) bool { return false }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type summary struct {
+		FullName    string
+		PointerRecv bool
+		Doc         string
+		Params      []string
+		Results     []string
+		Variadic    bool
+	}
+
+	names := func(ps []*ParamModel) []string {
+		s := []string{}
+		for _, p := range ps {
+			s = append(s, p.Name+" "+p.Type.String())
+		}
+		return s
+	}
+
+	got := []summary{}
+	for _, m := range fs.Funcs() {
+		got = append(got, summary{
+			FullName:    m.FullName(),
+			PointerRecv: m.PointerRecv,
+			Doc:         m.Doc,
+			Params:      names(m.Params),
+			Results:     names(m.Results),
+			Variadic:    m.Variadic,
+		})
+	}
+
+	want := []summary{
+		{
+			FullName: "X",
+			Doc:      "X does something.\n",
+			Params:   []string{"a string", "b []int"},
+			Results:  []string{"n int", "err error"},
+			Variadic: true,
+		},
+		{
+			FullName: "T.Value",
+			Params:   []string{},
+			Results:  []string{},
+		},
+		{
+			FullName:    "T.Pointer",
+			PointerRecv: true,
+			Params:      []string{" int"},
+			Results:     []string{" bool"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if _, ok := fs.Func("T.Pointer"); !ok {
+		t.Errorf("T.Pointer not found")
+	}
+	if _, ok := fs.Func("Pointer"); ok {
+		t.Errorf("method found without receiver name")
+	}
+}
+
+func TestTypes(t *testing.T) {
+	fs, err := NewFileSetFromTexts("package p\n" +
+		"import \"sync\"\n" +
+		"// X is documented.\n" +
+		"type X struct {\n" +
+		"\t// A is a field.\n" +
+		"\tA, b string `json:\"a\"`\n" +
+		"\tsync.Mutex\n" +
+		"\t*Y // embedded pointer\n" +
+		"}\n" +
+		"type (\n" +
+		"\t// Y is documented in a group.\n" +
+		"\tY int\n" +
+		"\tZ interface{}\n" +
+		")\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tms := fs.Types()
+	names := []string{}
+	for _, tm := range tms {
+		names = append(names, tm.Name)
+	}
+	if want := []string{"X", "Y", "Z"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %+v, wanted %+v", names, want)
+	}
+
+	if got, want := tms[0].Doc, "X is documented.\n"; got != want {
+		t.Errorf("got doc %q, wanted %q", got, want)
+	}
+	if got, want := tms[1].Doc, "Y is documented in a group.\n"; got != want {
+		t.Errorf("got doc %q, wanted %q", got, want)
+	}
+
+	type summary struct {
+		Name     string
+		Type     string
+		Embedded bool
+		Exported bool
+		Doc      string
+		Comment  string
+		Tag      string
+	}
+
+	got := []summary{}
+	for _, f := range tms[0].Fields {
+		got = append(got, summary{
+			Name:     f.Name,
+			Type:     types.TypeString(f.Type, types.RelativeTo(fs.Package)),
+			Embedded: f.Embedded,
+			Exported: f.Exported,
+			Doc:      f.Doc,
+			Comment:  f.Comment,
+			Tag:      f.Tag,
+		})
+	}
+
+	want := []summary{
+		{Name: "A", Type: "string", Exported: true, Doc: "A is a field.\n", Tag: `json:"a"`},
+		{Name: "b", Type: "string", Doc: "A is a field.\n", Tag: `json:"a"`},
+		{Name: "Mutex", Type: "sync.Mutex", Embedded: true, Exported: true},
+		{Name: "Y", Type: "*Y", Embedded: true, Exported: true, Comment: "embedded pointer\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if _, ok := fs.Type("Missing"); ok {
+		t.Errorf("unexpected type found")
+	}
+}
+
+// largePackage returns the source of a package declaring n struct types, each
+// with several fields and methods, representative of generated or schema
+// derived packages.
+func largePackage(n int) string {
+	var sb strings.Builder
+	sb.WriteString("package p\n\nimport \"time\"\n\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "// T%d is a type.\ntype T%d struct {\n", i, i)
+		sb.WriteString("\t// ID identifies the value.\n\tID int64 `json:\"id\" db:\"id\"`\n")
+		sb.WriteString("\tName, Description string `json:\"name,omitempty\"`\n")
+		sb.WriteString("\tCreated time.Time // when it was created\n")
+		sb.WriteString("\tTags []string\n\tattrs map[string]any\n}\n\n")
+		fmt.Fprintf(&sb, "func (t *T%d) SetName(name string) { t.Name = name }\n\n", i)
+		fmt.Fprintf(&sb, "func (t T%d) Lookup(key string, def any) (any, bool) { v, ok := t.attrs[key]; if !ok { return def, false }; return v, true }\n\n", i)
+	}
+	return sb.String()
+}
+
+func BenchmarkTypes(b *testing.B) {
+	fs, err := NewFileSetFromTexts(largePackage(2000))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fs.Types()
+	}
+}
+
+func BenchmarkType(b *testing.B) {
+	fs, err := NewFileSetFromTexts(largePackage(2000))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := fs.Type("T1999"); !ok {
+			b.Fatalf("type not found")
+		}
+	}
+}
+
+func BenchmarkFuncs(b *testing.B) {
+	fs, err := NewFileSetFromTexts(largePackage(2000))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fs.Funcs()
+	}
+}
+
+func BenchmarkMethods(b *testing.B) {
+	fs, err := NewFileSetFromTexts(largePackage(2000))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fs.MethodsOf("T1999"); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON Schema object, in the dialect used by OpenAPI 3.0 to
+// describe request and response bodies. Schemas are produced from Go types
+// by FileSet.Schemas and FileSet.OpenAPI and are encoded with encoding/json.
+type Schema struct {
+	// Ref refers to a schema in the components of an OpenAPI document, such
+	// as "#/components/schemas/User". Other fields are empty when Ref is set,
+	// except that a nullable reference is wrapped in AllOf.
+	Ref string `json:"$ref,omitempty"`
+
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// OpenAPIDocument is an OpenAPI 3.0 document holding schemas as components.
+// It has no paths, so it serves as a library of schemas that other documents
+// refer to, or as a starting point to which paths are added.
+type OpenAPIDocument struct {
+	OpenAPI    string            `json:"openapi"`
+	Info       OpenAPIInfo       `json:"info"`
+	Paths      map[string]any    `json:"paths"`
+	Components OpenAPIComponents `json:"components"`
+}
+
+// OpenAPIInfo is the info object of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPIComponents holds the reusable schemas of an OpenAPI document, keyed
+// by type name.
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// OpenAPI returns an OpenAPI 3.0 document with the given title and version
+// whose components are the schemas of the named types, as returned by
+// Schemas.
+func (fs *FileSet) OpenAPI(title, version string, typeNames ...string) (*OpenAPIDocument, error) {
+	schemas, err := fs.Schemas(typeNames...)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIDocument{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: title, Version: version},
+		Paths:      map[string]any{},
+		Components: OpenAPIComponents{Schemas: schemas},
+	}, nil
+}
+
+// Schemas returns JSON Schemas describing how the named struct types, and
+// the named types declared in fs that they refer to, are encoded by
+// encoding/json. The schemas are keyed by type name and refer to each other
+// with references of the form "#/components/schemas/Name".
+//
+// The schema of a struct has a property for each field that encoding/json
+// would encode, named by its json tag. Fields tagged "-" and unexported
+// fields are omitted, fields without the omitempty option are required, and
+// the fields of embedded structs without a json name are promoted into the
+// struct, except where a field of the same name is found at a shallower
+// depth. A pointer, slice or map is nullable unless its field has the
+// omitempty option. Enumerations, as found by Enums, list their values.
+// Types implementing json.Marshaler are described by an empty schema, which
+// allows any value, and types implementing encoding.TextMarshaler are strings.
+// time.Time is a string with the date-time format. Doc comments become
+// descriptions.
+func (fs *FileSet) Schemas(typeNames ...string) (map[string]*Schema, error) {
+	sc := &schemaConverter{
+		fs:      fs,
+		models:  make(map[*types.TypeName]*TypeModel),
+		fields:  make(map[*types.Var]*FieldModel),
+		enums:   make(map[*types.TypeName]*EnumModel),
+		schemas: make(map[string]*Schema),
+	}
+	for _, tm := range fs.Types() {
+		sc.models[tm.Object] = tm
+		for _, f := range tm.Fields {
+			sc.fields[f.Object] = f
+		}
+	}
+	for _, em := range fs.Enums() {
+		sc.enums[em.Type.Object] = em
+	}
+
+	for _, name := range typeNames {
+		tm, ok := fs.Type(name)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+		if _, ok := tm.Object.Type().Underlying().(*types.Struct); !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		sc.define(sc.models[tm.Object])
+	}
+	for len(sc.queue) > 0 {
+		tm := sc.queue[0]
+		sc.queue = sc.queue[1:]
+		sc.define(tm)
+	}
+	return sc.schemas, nil
+}
+
+// schemaConverter converts the types of a FileSet to schemas.
+type schemaConverter struct {
+	fs      *FileSet
+	models  map[*types.TypeName]*TypeModel
+	fields  map[*types.Var]*FieldModel
+	enums   map[*types.TypeName]*EnumModel
+	schemas map[string]*Schema
+	queue   []*TypeModel // types referred to that have yet to be defined
+}
+
+// define adds the schema of the type described by tm to the schemas.
+func (sc *schemaConverter) define(tm *TypeModel) {
+	if _, ok := sc.schemas[tm.Name]; ok {
+		return
+	}
+	// Record the type before converting it so that recursive references
+	// are not queued again.
+	s := &Schema{}
+	sc.schemas[tm.Name] = s
+	*s = *sc.underlying(tm.Object)
+	s.Description = strings.TrimSpace(tm.Doc)
+}
+
+// underlying returns the schema of the named type tn, described in full
+// rather than by reference.
+func (sc *schemaConverter) underlying(tn *types.TypeName) *Schema {
+	t := tn.Type()
+	if em, ok := sc.enums[tn]; ok {
+		s := sc.schema(t.Underlying())
+		for _, v := range em.Values {
+			s.Enum = append(s.Enum, constantJSON(v.Value))
+		}
+		return s
+	}
+	if st, ok := t.Underlying().(*types.Struct); ok {
+		return sc.object(st)
+	}
+	return sc.schema(t.Underlying())
+}
+
+// object returns the schema of a struct.
+func (sc *schemaConverter) object(st *types.Struct) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	depths := make(map[string]int)
+	sc.addProperties(s, st, 0, depths, make(map[*types.Struct]bool))
+	sort.Strings(s.Required)
+	return s
+}
+
+// addProperties adds the properties for the fields of st, found at the given
+// depth of embedding, to s.
+func (sc *schemaConverter) addProperties(s *Schema, st *types.Struct, depth int, depths map[string]int, visiting map[*types.Struct]bool) {
+	if visiting[st] {
+		return
+	}
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tags, _ := ParseTags(st.Tag(i))
+		tag, tagged := tags.Get("json")
+		if tagged && tag.Name == "-" && len(tag.Options) == 0 {
+			continue
+		}
+
+		if f.Embedded() && (!tagged || tag.Name == "") {
+			t := f.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if est, ok := t.Underlying().(*types.Struct); ok {
+				sc.addProperties(s, est, depth+1, depths, visiting)
+				continue
+			}
+		}
+		if !f.Exported() {
+			continue
+		}
+
+		name := f.Name()
+		if tagged && tag.Name != "" {
+			name = tag.Name
+		}
+		if d, ok := depths[name]; ok && d <= depth {
+			continue
+		}
+		depths[name] = depth
+
+		omitempty := tagged && tag.HasOption("omitempty")
+		var prop *Schema
+		if tagged && tag.HasOption("string") {
+			prop = &Schema{Type: "string"}
+		} else {
+			prop = sc.nullable(f.Type(), !omitempty)
+		}
+		if fm, ok := sc.fields[f]; ok && prop.Ref == "" {
+			prop.Description = strings.TrimSpace(fm.Doc + fm.Comment)
+		}
+		s.Properties[name] = prop
+		s.Required = removeString(s.Required, name)
+		if !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nullable returns the schema of t, marking pointers, slices and maps as
+// nullable if nullable is true.
+func (sc *schemaConverter) nullable(t types.Type, nullable bool) *Schema {
+	s := sc.schema(t)
+	if !nullable {
+		return s
+	}
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map:
+		if s.Ref != "" {
+			return &Schema{AllOf: []*Schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+	}
+	return s
+}
+
+// schema returns the schema of t, referring to the named types of fs.
+func (sc *schemaConverter) schema(t types.Type) *Schema {
+	if s, ok := sc.marshaler(t); ok {
+		return s
+	}
+
+	switch t := t.(type) {
+	case *types.Named:
+		if tm, ok := sc.models[t.Obj()]; ok && t.TypeArgs().Len() == 0 {
+			if _, defined := sc.schemas[tm.Name]; !defined {
+				sc.queue = append(sc.queue, tm)
+			}
+			return &Schema{Ref: "#/components/schemas/" + tm.Name}
+		}
+		return sc.schema(t.Underlying())
+	case *types.Alias:
+		return sc.schema(types.Unalias(t))
+	case *types.Basic:
+		return basicSchema(t)
+	case *types.Pointer:
+		return sc.schema(t.Elem())
+	case *types.Slice:
+		if b, ok := t.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: sc.schema(t.Elem())}
+	case *types.Array:
+		return &Schema{Type: "array", Items: sc.schema(t.Elem())}
+	case *types.Map:
+		return &Schema{Type: "object", AdditionalProperties: sc.schema(t.Elem())}
+	case *types.Struct:
+		return sc.object(t)
+	}
+	// Interfaces and types that encoding/json cannot encode allow any value.
+	return &Schema{}
+}
+
+// marshaler returns the schema of t if it is encoded by a method: time.Time,
+// or a type implementing json.Marshaler or encoding.TextMarshaler.
+func (sc *schemaConverter) marshaler(t types.Type) (*Schema, bool) {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time" {
+		return &Schema{Type: "string", Format: "date-time"}, true
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return nil, false
+	}
+	mset := types.NewMethodSet(types.NewPointer(t))
+	if hasMethod(mset, "MarshalJSON") {
+		return &Schema{}, true
+	}
+	if hasMethod(mset, "MarshalText") {
+		return &Schema{Type: "string"}, true
+	}
+	return nil, false
+}
+
+// hasMethod reports whether mset has a method with the given name.
+func hasMethod(mset *types.MethodSet, name string) bool {
+	for i := 0; i < mset.Len(); i++ {
+		if mset.At(i).Obj().Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// basicSchema returns the schema of a basic type.
+func basicSchema(b *types.Basic) *Schema {
+	switch b.Kind() {
+	case types.Bool, types.UntypedBool:
+		return &Schema{Type: "boolean"}
+	case types.Int8, types.Int16, types.Int32, types.Uint8, types.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case types.Int, types.Int64, types.Uint, types.Uint32, types.Uint64, types.Uintptr, types.UntypedInt, types.UntypedRune:
+		return &Schema{Type: "integer", Format: "int64"}
+	case types.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case types.Float64, types.UntypedFloat:
+		return &Schema{Type: "number", Format: "double"}
+	case types.String, types.UntypedString:
+		return &Schema{Type: "string"}
+	}
+	// Complex numbers and unsafe pointers cannot be encoded.
+	return &Schema{}
+}
+
+// constantJSON returns the value of a constant as it is encoded in JSON.
+func constantJSON(v constant.Value) any {
+	switch v.Kind() {
+	case constant.Bool:
+		return constant.BoolVal(v)
+	case constant.String:
+		return constant.StringVal(v)
+	case constant.Int:
+		if i, ok := constant.Int64Val(v); ok {
+			return i
+		}
+		if u, ok := constant.Uint64Val(v); ok {
+			return u
+		}
+	case constant.Float:
+		f, _ := constant.Float64Val(v)
+		return f
+	}
+	return json.RawMessage(v.ExactString())
+}
+
+// removeString returns list without the element s.
+func removeString(list []string, s string) []string {
+	for i, e := range list {
+		if e == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
@@ -0,0 +1,82 @@
+package gen
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const openAPISrc = `package p
+
+import "time"
+
+// Status is the state of an order.
+type Status string
+
+const (
+	Pending Status = "pending"
+	Shipped Status = "shipped"
+)
+
+type Base struct {
+	ID      int64     ` + "`json:\"id\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+
+// Order is an order.
+type Order struct {
+	Base
+	// Items are the things ordered.
+	Items    []Item            ` + "`json:\"items\"`" + `
+	Status   Status            ` + "`json:\"status\"`" + `
+	Note     *string           ` + "`json:\"note,omitempty\"`" + `
+	Parent   *Order            ` + "`json:\"parent\"`" + `
+	Labels   map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	Total    float64           ` + "`json:\"total,string\"`" + `
+	Secret   string            ` + "`json:\"-\"`" + `
+	internal int
+}
+
+type Item struct {
+	Name string
+	Qty  uint8 ` + "`json:\"qty,omitempty\"`" + `
+	Data []byte
+}
+`
+
+func TestSchemas(t *testing.T) {
+	fs, err := NewFileSetFromTexts(openAPISrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, err := fs.OpenAPI("Orders", "1.0.0", "Order")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"openapi":"3.0.3","info":{"title":"Orders","version":"1.0.0"},"paths":{},"components":{"schemas":{` +
+		`"Item":{"type":"object","properties":{"Data":{"type":"string","format":"byte","nullable":true},"Name":{"type":"string"},"qty":{"type":"integer","format":"int32"}},"required":["Data","Name"]},` +
+		`"Order":{"type":"object","description":"Order is an order.","properties":{` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"id":{"type":"integer","format":"int64"},` +
+		`"items":{"type":"array","description":"Items are the things ordered.","nullable":true,"items":{"$ref":"#/components/schemas/Item"}},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"note":{"type":"string"},` +
+		`"parent":{"nullable":true,"allOf":[{"$ref":"#/components/schemas/Order"}]},` +
+		`"status":{"$ref":"#/components/schemas/Status"},` +
+		`"total":{"type":"string"}},` +
+		`"required":["created","id","items","parent","status","total"]},` +
+		`"Status":{"type":"string","description":"Status is the state of an order.","enum":["pending","shipped"]}}}}`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	if _, err := fs.Schemas("Status"); err == nil {
+		t.Errorf("got no error for a type that is not a struct")
+	}
+	if _, err := fs.Schemas("Missing"); err == nil {
+		t.Errorf("got no error for a missing type")
+	}
+}
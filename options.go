@@ -0,0 +1,221 @@
+package gen
+
+import (
+	"context"
+	"go/build"
+	"log/slog"
+	"path/filepath"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Option configures how a FileSet is loaded.
+type Option func(*options)
+
+// options holds the configuration used when loading a FileSet.
+type options struct {
+	tests      bool
+	tags       []string
+	goos       string
+	goarch     string
+	importMode ImportMode
+	cache      *ImporterCache
+	lenient    bool
+	exclude    []string
+	stubs      map[string][]byte
+	overlay    map[string][]byte // keyed by absolute file name
+	ctx        context.Context
+	log        *slog.Logger
+	ssa        bool
+	ssaMode    ssa.BuilderMode
+}
+
+// newOptions applies opts to the default configuration.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTests controls whether test files are loaded along with the package's
+// source files when loading a directory. When include is true the package's
+// own _test.go files are type checked as part of the package and any external
+// test package (package p_test) is loaded into the FileSet's XTest field.
+func WithTests(include bool) Option {
+	return func(o *options) {
+		o.tests = include
+	}
+}
+
+// WithBuildTags adds build tags that are treated as satisfied when selecting
+// the files to load from a directory and when resolving imported packages.
+func WithBuildTags(tags ...string) Option {
+	return func(o *options) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithGOOS sets the target operating system used when selecting the files to
+// load from a directory and when resolving imported packages. The default is
+// the host operating system.
+func WithGOOS(goos string) Option {
+	return func(o *options) {
+		o.goos = goos
+	}
+}
+
+// WithGOARCH sets the target architecture used when selecting the files to load
+// from a directory, resolving imported packages and computing type sizes. The
+// default is the host architecture.
+func WithGOARCH(goarch string) Option {
+	return func(o *options) {
+		o.goarch = goarch
+	}
+}
+
+// WithLenient controls whether a package that does not compile is loaded as
+// far as possible rather than failing. This is useful when a package cannot
+// compile until code has been generated for it, such as when hand-written
+// code refers to a generated type. When lenient is true, files with syntax
+// errors are kept as far as they could be parsed, type checking continues
+// past errors, imports that cannot be resolved leave the names they declare
+// undefined and imports of "C" are accepted without running cgo. The errors
+// found are recorded in the FileSet's Errors field instead of being returned,
+// and the type information of the code without errors is complete.
+func WithLenient(lenient bool) Option {
+	return func(o *options) {
+		o.lenient = lenient
+	}
+}
+
+// WithContext sets a context that cancels loading. Once ctx is done, no
+// further files are parsed and no further packages are imported, and loading
+// fails with the context's error.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithLogger sets a logger that reports the progress of loading: each file
+// parsed, at debug level, and the package loaded, with the number of files
+// and types and the time taken. By default nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.log = l
+	}
+}
+
+// logger returns the logger set with WithLogger, or a logger that discards
+// its records.
+func (o *options) logger() *slog.Logger {
+	if o.log == nil {
+		return discardLogger
+	}
+	return o.log
+}
+
+// discardLogger discards all records.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// canceled returns the error of the context set with WithContext if it is
+// done, or nil.
+func (o *options) canceled() error {
+	if o.ctx == nil {
+		return nil
+	}
+	return o.ctx.Err()
+}
+
+// WithExclude excludes the files whose names match any of the patterns, in
+// the syntax of filepath.Match, from the files loaded from a directory. A
+// pattern is matched against the base name of each file, so *_gen.go
+// excludes generated files that would otherwise prevent a package from
+// being loaded because they are out of date.
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithStub supplies the source of a file that is loaded along with the files
+// of a directory when the file named filename, a base name such as
+// color_gen.go, is not among them because it does not exist or has been
+// excluded with WithExclude. A stub declares just enough, such as the names
+// that hand-written code refers to, for the package to be type checked
+// before the file has been generated, so that code can be generated from
+// scratch in a clean checkout. The stub is loaded regardless of its build
+// constraints.
+func WithStub(filename string, src []byte) Option {
+	return func(o *options) {
+		if o.stubs == nil {
+			o.stubs = make(map[string][]byte)
+		}
+		o.stubs[filename] = src
+	}
+}
+
+// excluded reports whether the file with the given name is excluded by the
+// WithExclude option.
+func (o *options) excluded(name string) bool {
+	for _, pattern := range o.exclude {
+		if ok, _ := filepath.Match(pattern, filepath.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportMode controls how the types of a package's dependencies are loaded.
+type ImportMode int
+
+const (
+	// ImportAuto loads dependencies from export data produced by the go
+	// command, falling back to type checking their source code if export
+	// data cannot be produced, for example because a dependency does not
+	// compile. Packages that only import the standard library use the
+	// compiler's default importer.
+	ImportAuto ImportMode = iota
+
+	// ImportExportData always loads dependencies from export data produced
+	// by the go command.
+	ImportExportData
+
+	// ImportSource always loads dependencies by type checking their source
+	// code. This is slower than using export data but does not require
+	// dependencies to compile. Cgo is disabled when selecting the files of
+	// dependencies in this mode.
+	ImportSource
+)
+
+// WithImportMode sets how the types of the package's dependencies are loaded.
+// The default is ImportAuto.
+func WithImportMode(mode ImportMode) Option {
+	return func(o *options) {
+		o.importMode = mode
+	}
+}
+
+// buildContext returns the build context described by the options.
+func (o *options) buildContext() *build.Context {
+	ctxt := build.Default
+	ctxt.BuildTags = append(append([]string{}, build.Default.BuildTags...), o.tags...)
+	if o.goos != "" && o.goos != ctxt.GOOS {
+		ctxt.GOOS = o.goos
+		ctxt.CgoEnabled = false
+	}
+	if o.goarch != "" && o.goarch != ctxt.GOARCH {
+		ctxt.GOARCH = o.goarch
+		ctxt.CgoEnabled = false
+	}
+	o.applyOverlay(&ctxt)
+	return &ctxt
+}
+
+// customBuild reports whether the options describe a build configuration that
+// differs from the default.
+func (o *options) customBuild() bool {
+	return len(o.tags) > 0 || o.goos != "" || o.goarch != ""
+}
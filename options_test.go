@@ -0,0 +1,181 @@
+package gen
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBuildConstraintOptions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":             "package p\n\nvar X = platform\n",
+		"p_linux.go":       "package p\n\nconst platform = \"linux\"\n",
+		"p_darwin.go":      "package p\n\nconst platform = \"darwin\"\n",
+		"p_windows.go":     "package p\n\nimport \"syscall\"\n\nconst platform = \"windows\"\n\nvar _, _ = syscall.UTF16FromString(platform)\n",
+		"integration.go":   "//go:build integration\n\npackage p\n\nvar Integration = true\n",
+		"p_arm64.go":       "package p\n\nconst Arch = \"arm64\"\n",
+		"p_amd64.go":       "package p\n\nconst Arch = \"amd64\"\n",
+		"p_other_arch.go":  "//go:build !arm64 && !amd64\n\npackage p\n\nconst Arch = \"other\"\n",
+		"p_other_os.go":    "//go:build !linux && !darwin && !windows\n\npackage p\n\nconst platform = \"other\"\n",
+		"p_integration.go": "//go:build integration && windows\n\npackage p\n\nvar WindowsIntegration = true\n",
+	})
+
+	testCases := []struct {
+		opts  []Option
+		files []string
+	}{
+		{
+			opts:  []Option{WithGOOS("linux"), WithGOARCH("amd64")},
+			files: []string{"p.go", "p_amd64.go", "p_linux.go"},
+		},
+		{
+			opts:  []Option{WithGOOS("darwin"), WithGOARCH("arm64"), WithBuildTags("integration")},
+			files: []string{"integration.go", "p.go", "p_arm64.go", "p_darwin.go"},
+		},
+		{
+			opts:  []Option{WithGOOS("windows"), WithGOARCH("amd64"), WithBuildTags("integration")},
+			files: []string{"integration.go", "p.go", "p_amd64.go", "p_integration.go", "p_windows.go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			fs, err := FileSetFromDir(dir, tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			files := []string{}
+			for _, f := range fs.AstFiles {
+				files = append(files, fs.FileSet.File(f.Pos()).Name()[len(dir)+1:])
+			}
+			sort.Strings(files)
+			if !reflect.DeepEqual(files, tc.files) {
+				t.Errorf("got %+v, wanted %+v", files, tc.files)
+			}
+		})
+	}
+}
+
+func TestBuildContext(t *testing.T) {
+	o := newOptions([]Option{WithGOOS("plan9"), WithGOARCH("386"), WithBuildTags("a", "b")})
+	ctxt := o.buildContext()
+	if ctxt.GOOS != "plan9" || ctxt.GOARCH != "386" || ctxt.CgoEnabled {
+		t.Errorf("unexpected build context %+v", ctxt)
+	}
+	if got, want := ctxt.BuildTags, []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got tags %+v, wanted %+v", got, want)
+	}
+
+	if o := newOptions(nil); o.customBuild() {
+		t.Errorf("default options reported as custom build")
+	}
+}
+
+func TestWithLenient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go": `package p
+
+import (
+	"strings"
+
+	"example.com/p/missing"
+)
+
+// Color is a hand-written type whose String method is generated.
+type Color int
+
+// Describe uses the generated method and a package that does not exist.
+func Describe(c Color) string {
+	return strings.ToUpper(c.String()) + missing.Suffix
+}
+
+var Names = colorNames
+`,
+		"q.go": "package p\n\ntype Kept struct{ A int }\n\nfunc Broken() {\n\treturn 1 +\n}\n",
+	})
+
+	if _, err := FileSetFromDir(dir); err == nil {
+		t.Fatalf("got no error loading a broken package without WithLenient")
+	}
+
+	fs, err := FileSetFromDir(dir, WithLenient(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fs.Errors) < 3 {
+		t.Errorf("got errors %v, wanted at least the syntax error, the import and colorNames", fs.Errors)
+	}
+	phases := map[Phase]bool{}
+	for _, e := range fs.Errors {
+		phases[e.Phase] = true
+	}
+	if !phases[PhaseParse] || !phases[PhaseTypeCheck] {
+		t.Errorf("got errors %v, wanted parse and type check errors", fs.Errors)
+	}
+
+	for _, name := range []string{"Color", "Kept"} {
+		if _, ok := fs.Type(name); !ok {
+			t.Errorf("type %s not found", name)
+		}
+	}
+	if fs.Lookup("Describe") == nil {
+		t.Errorf("function Describe not found")
+	}
+}
+
+func TestWithExcludeAndStub(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"color.go": "package p\n\ntype Color int\n\nfunc Describe(c Color) string { return c.String() + colorNames[0] }\n",
+		// The generated file is out of date: it refers to a constant that
+		// has been removed.
+		"color_gen.go": "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n\nvar colorNames = []string{Red.String()}\n\nfunc (c Color) String() string { return \"\" }\n",
+	})
+	stub := []byte("package p\n\nvar colorNames []string\n\nfunc (c Color) String() string { panic(\"stub\") }\n")
+
+	if _, err := FileSetFromDir(dir); err == nil {
+		t.Fatalf("got no error loading a package with an out of date generated file")
+	}
+	if _, err := FileSetFromDir(dir, WithExclude("*_gen.go")); err == nil {
+		t.Fatalf("got no error loading a package without its generated file")
+	}
+
+	fs, err := FileSetFromDir(dir, WithExclude("*_gen.go"), WithStub("color_gen.go", stub))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fs.Files) != 2 || fs.Lookup("colorNames") == nil {
+		t.Errorf("got files %q, wanted color.go and the stub", fs.Files)
+	}
+
+	// A stub is only used when the file is not loaded.
+	fs, err = FileSetFromDir(dir, WithStub("color_gen.go", []byte("package p\n\nvar Unused int\n")), WithLenient(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.Lookup("Unused") != nil {
+		t.Errorf("stub used when the file exists")
+	}
+
+	// A stub may be the only file in a directory.
+	empty := writeTestModule(t, map[string]string{})
+	fs, err = FileSetFromDir(empty, WithStub("p_gen.go", []byte("package p\n\nconst X = 1\n")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.Lookup("X") == nil {
+		t.Errorf("stub not loaded into an empty directory")
+	}
+}
@@ -0,0 +1,38 @@
+package gen
+
+import (
+	"cmp"
+	"slices"
+	"text/template"
+)
+
+// Generated code must not depend on the order of map iteration, which
+// varies between runs, or the output changes from one run to the next and
+// every regeneration produces a spurious diff. The models built from a
+// FileSet list types, fields, methods and constants in the order they are
+// declared in the source. Data held in maps must be put in order before it
+// is rendered: SortedKeys orders the keys of a map, and a template's range
+// action over a map already visits its keys in sorted order. The
+// gentest.AssertDeterministic helper checks that a generator renders the
+// same output twice.
+
+// SortedKeys returns the keys of m in ascending order. Ranging over the
+// result rather than over m gives a deterministic order.
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// sortedTemplates returns the templates associated with t ordered by name,
+// since Template.Templates returns them in an unspecified order.
+func sortedTemplates(t *template.Template) []*template.Template {
+	tmpls := t.Templates()
+	slices.SortFunc(tmpls, func(a, b *template.Template) int {
+		return cmp.Compare(a.Name(), b.Name())
+	})
+	return tmpls
+}
@@ -0,0 +1,31 @@
+package gen
+
+import (
+	"slices"
+	"testing"
+	"text/template"
+)
+
+func TestSortedKeys(t *testing.T) {
+	m := map[string]int{"b": 2, "c": 3, "a": 1, "d": 4}
+	if got, want := SortedKeys(m), []string{"a", "b", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+	if got := SortedKeys(map[int]bool{3: true, 1: true, 2: false}); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("got %v, wanted [1 2 3]", got)
+	}
+	if got := SortedKeys(map[string]int(nil)); len(got) != 0 {
+		t.Errorf("got %v, wanted no keys", got)
+	}
+}
+
+func TestSortedTemplates(t *testing.T) {
+	tmpl := template.Must(template.New("main").Parse(`{{define "z"}}z{{end}}{{define "b"}}b{{end}}{{define "m"}}m{{end}}`))
+	var names []string
+	for _, t := range sortedTemplates(tmpl) {
+		names = append(names, t.Name())
+	}
+	if want := []string{"b", "m", "main", "z"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, wanted %v", names, want)
+	}
+}
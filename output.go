@@ -0,0 +1,251 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"golang.org/x/tools/imports"
+)
+
+// ErrNotGenerated is returned when an attempt is made to overwrite a file that
+// does not carry the standard generated code header.
+var ErrNotGenerated = errors.New("file exists and was not generated")
+
+// generatedRx matches the line that marks a file as generated, as described
+// at https://golang.org/s/generatedcode
+var generatedRx = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// Output accumulates generated Go source code and writes it to a file with the
+// canonical generated code header. Output implements io.Writer so it may be
+// used as the destination for template execution.
+type Output struct {
+	// Generator is the name of the program generating the code. It is used
+	// in the generated code header.
+	Generator string
+
+	// Force permits WriteFile to overwrite an existing file that does not
+	// carry the generated code header.
+	Force bool
+
+	// ParallelFormat causes Source to format the top-level declarations of
+	// the source code concurrently, which is faster for very large files.
+	// The whole file is formatted at once if any declaration cannot be.
+	ParallelFormat bool
+
+	// FixImports causes Source to adjust the import declarations of the
+	// source code as goimports does when formatting it: imports that are not
+	// used are removed and packages that are referred to but not imported
+	// are added, so generators need not track their imports exactly.
+	FixImports bool
+
+	// RecordOwnership causes Source to append a trailer recording the
+	// generator that owns each declaration. Declarations are owned by
+	// Generator unless assigned to another generator with Own.
+	RecordOwnership bool
+
+	buf    bytes.Buffer
+	owners map[string]string // owners of declarations assigned with Own
+}
+
+// NewOutput creates an Output for code generated by the named generator.
+func NewOutput(generator string) *Output {
+	return &Output{Generator: generator}
+}
+
+// Write appends p to the accumulated source code.
+func (o *Output) Write(p []byte) (int, error) {
+	return o.buf.Write(p)
+}
+
+// Printf formats according to a format specifier and appends the result to the
+// accumulated source code.
+func (o *Output) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&o.buf, format, args...)
+}
+
+// Header returns the generated code header that will be prepended to the
+// accumulated source code.
+func (o *Output) Header() string {
+	return fmt.Sprintf("// Code generated by %s; DO NOT EDIT.\n\n", o.Generator)
+}
+
+// Bytes returns the unformatted source code accumulated so far, without the
+// header.
+func (o *Output) Bytes() []byte {
+	return o.buf.Bytes()
+}
+
+// Reset discards all accumulated source code and the owners of declarations
+// assigned with Own.
+func (o *Output) Reset() {
+	o.buf.Reset()
+	o.owners = nil
+}
+
+// Source returns the accumulated source code prefixed by the generated code
+// header and formatted with gofmt. The header is omitted if the source
+// already contains one. If RecordOwnership is set the ownership trailer is
+// appended, replacing any trailer in the accumulated source code.
+func (o *Output) Source() ([]byte, error) {
+	src := o.buf.Bytes()
+	if !IsGenerated(src) {
+		src = append([]byte(o.Header()), src...)
+	}
+	if o.RecordOwnership {
+		src = stripOwnership(src)
+	}
+	formatted, err := formatSource(src, o.ParallelFormat, o.FixImports)
+	if err != nil {
+		return nil, fmt.Errorf("format generated source: %w", err)
+	}
+	if o.RecordOwnership {
+		ow, err := o.ownership(formatted)
+		if err != nil {
+			return nil, err
+		}
+		formatted = appendOwnership(formatted, ow)
+	}
+	return formatted, nil
+}
+
+// formatSource formats src with gofmt, formatting its declarations
+// concurrently if parallel is true. If fixImports is true src is instead
+// formatted by goimports, which also removes unused imports and adds missing
+// ones. Syntax errors are reported as an ErrorList.
+func formatSource(src []byte, parallel, fixImports bool) ([]byte, error) {
+	var formatted []byte
+	var err error
+	switch {
+	case fixImports:
+		formatted, err = imports.Process("", src, &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	case parallel:
+		formatted, err = formatParallel(src)
+	default:
+		formatted, err = format.Source(src)
+	}
+	if err != nil {
+		return nil, syntaxErrors(err, PhaseFormat, sources{"": src})
+	}
+	return formatted, nil
+}
+
+// WriteFile formats the accumulated source code and writes it to filename.
+// The file is written atomically by writing to a temporary file in the same
+// directory and renaming it over the original so a failed generation can never
+// leave a partially written file behind. WriteFile refuses to overwrite an
+// existing file that lacks the generated code header unless Force is set.
+func (o *Output) WriteFile(filename string) error {
+	src, err := o.Source()
+	if err != nil {
+		return err
+	}
+	perm, err := checkTarget(filename, o.Force)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, src, perm)
+}
+
+// checkTarget verifies that filename may be written, refusing to overwrite a
+// file that lacks the generated code header unless force is set, and returns
+// the permissions the written file should have.
+func checkTarget(filename string, force bool) (fs.FileMode, error) {
+	perm := fs.FileMode(0o644)
+	if info, err := os.Stat(filename); err == nil {
+		if !force {
+			existing, err := os.ReadFile(filename)
+			if err != nil {
+				return 0, err
+			}
+			if !IsGenerated(existing) {
+				return 0, fmt.Errorf("%s: %w", filename, ErrNotGenerated)
+			}
+		}
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	return perm, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// filename and renames it to filename once it has been completely written.
+func writeFileAtomic(filename string, data []byte, perm fs.FileMode) error {
+	tmp, err := writeTemp(filename, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeTemp writes data to a new temporary file in the same directory as
+// filename with permissions perm and returns the temporary file's name.
+func writeTemp(filename string, data []byte, perm fs.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// IsGenerated reports whether src contains the standard generated code header
+// line before its package clause.
+func IsGenerated(src []byte) bool {
+	return generatedHeader(src) != nil
+}
+
+// generatedHeader returns the generated code header line of src, or nil if
+// it has none. As the convention requires, the header must appear before the
+// first text that is not a comment or blank line, normally the package
+// clause. Lines are read without a limit on their length.
+func generatedHeader(src []byte) []byte {
+	inComment := false
+	for len(src) > 0 {
+		var line []byte
+		line, src, _ = bytes.Cut(src, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case inComment:
+			inComment = !bytes.Contains(trimmed, []byte("*/"))
+		case generatedRx.Match(line):
+			return line
+		case len(trimmed) == 0, bytes.HasPrefix(trimmed, []byte("//")):
+		case bytes.HasPrefix(trimmed, []byte("/*")):
+			inComment = !bytes.Contains(trimmed[2:], []byte("*/"))
+		default:
+			return nil
+		}
+	}
+	return nil
+}
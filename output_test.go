@@ -0,0 +1,178 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputSource(t *testing.T) {
+	o := NewOutput("gentool")
+	o.Printf("package p\n")
+	o.Printf("func   X( ) int { return %d }\n", 1)
+
+	got, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "// Code generated by gentool; DO NOT EDIT.\n\npackage p\n\nfunc X() int { return 1 }\n"
+	if string(got) != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestOutputSourceFixImports(t *testing.T) {
+	o := NewOutput("gentool")
+	o.FixImports = true
+	o.Printf("package p\nimport \"os\"\nfunc X() string { return strings.TrimSpace(\" x \") }\n")
+
+	got, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "// Code generated by gentool; DO NOT EDIT.\n\npackage p\n\nimport \"strings\"\n\nfunc X() string { return strings.TrimSpace(\" x \") }\n"
+	if string(got) != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestOutputSourceInvalid(t *testing.T) {
+	o := NewOutput("gentool")
+	o.Printf("package p\nfunc {")
+
+	if _, err := o.Source(); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	testCases := []struct {
+		src  string
+		want bool
+	}{
+		{src: "// Code generated by x; DO NOT EDIT.\n\npackage p\n", want: true},
+		{src: "// Copyright\n\n// Code generated by go generate. DO NOT EDIT.\npackage p\n", want: true},
+		{src: "package p\n", want: false},
+		{src: "// Code generated by x\npackage p\n", want: false},
+		{src: "/*\nLicense\n*/\n\n//go:build ignore\n\n// Code generated by x; DO NOT EDIT.\n\npackage p\n", want: true},
+		{src: "package p\n\n// Code generated by x; DO NOT EDIT.\n", want: false},
+		{src: "// " + strings.Repeat("x", 100_000) + "\n// Code generated by x; DO NOT EDIT.\r\n\r\npackage p\n", want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			if got := IsGenerated([]byte(tc.src)); got != tc.want {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOutputWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	generated := filepath.Join(dir, "gen.go")
+	manual := filepath.Join(dir, "manual.go")
+
+	if err := os.WriteFile(manual, []byte("package p\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := NewOutput("gentool")
+	o.Printf("package p\n")
+
+	if err := o.WriteFile(generated); err != nil {
+		t.Fatalf("unexpected error writing new file: %v", err)
+	}
+
+	// Overwriting a previously generated file is allowed
+	if err := o.WriteFile(generated); err != nil {
+		t.Fatalf("unexpected error overwriting generated file: %v", err)
+	}
+
+	if err := o.WriteFile(manual); !errors.Is(err, ErrNotGenerated) {
+		t.Fatalf("got error %v, wanted %v", err, ErrNotGenerated)
+	}
+
+	o.Force = true
+	if err := o.WriteFile(manual); err != nil {
+		t.Fatalf("unexpected error forcing overwrite: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("got %d files, wanted 2 (temporary files left behind?)", len(entries))
+	}
+}
+
+func TestOutputSourceParallel(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+	}{
+		{name: "large", src: largePackage(200)},
+		{name: "single", src: "package p\nfunc   X( ) int { return 1 }\n"},
+		{name: "raw string", src: "package p\n\nvar s = `\n\nfunc   G() {}\n`\n\nfunc   F() {}\n"},
+		{name: "unformatted", src: "package p\nimport (\n\"fmt\"\n)\nvar x=1 // one\nvar yyy = 2 // two\n\n\n\nfunc F(){\nfmt.Println( x )\n}\n\n\n// T is a type.\ntype T struct{\nA int `json:\"a\"`\nBbbbb string\n}\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := NewOutput("gentool")
+			o.Printf("%s", tc.src)
+			want, err := o.Source()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			o.ParallelFormat = true
+			got, err := o.Source()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestOutputSourceParallelInvalid(t *testing.T) {
+	o := NewOutput("gentool")
+	o.ParallelFormat = true
+	o.Printf("package p\n\nfunc F() {}\n\nfunc {\n")
+
+	_, err := o.Source()
+	if err == nil {
+		t.Fatalf("got no error, wanted one")
+	}
+	// The error comes from formatting the whole file so refers to its lines.
+	if want := "7:6"; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, wanted it to mention %s", err, want)
+	}
+}
+
+func BenchmarkOutputSource(b *testing.B) {
+	src := largePackage(5000)
+	for _, parallel := range []bool{false, true} {
+		b.Run(fmt.Sprintf("parallel=%v", parallel), func(b *testing.B) {
+			o := NewOutput("gentool")
+			o.ParallelFormat = parallel
+			o.Printf("%s", src)
+			b.SetBytes(int64(len(src)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := o.Source(); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
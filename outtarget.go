@@ -0,0 +1,116 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// OutputTarget is the package to which generated code is written, which may
+// be the package of the FileSet the code is generated from or a different
+// package, such as one that holds generated mocks. It renders references to
+// the types of the source package as the target package must write them.
+type OutputTarget struct {
+	// Dir is the directory of the target package.
+	Dir string
+
+	// Name is the name of the target package, as written in the package
+	// clause of generated files.
+	Name string
+
+	// ImportPath is the import path of the target package.
+	ImportPath string
+
+	source     *types.Package
+	sourcePath string // import path of the source package
+}
+
+// Target returns the OutputTarget for code written to the package of fs.
+func (fs *FileSet) Target() *OutputTarget {
+	path, err := fs.importPath()
+	if err != nil {
+		path = fs.Package.Path()
+	}
+	return &OutputTarget{
+		Dir:        fs.Dir,
+		Name:       fs.Package.Name(),
+		ImportPath: path,
+		source:     fs.Package,
+		sourcePath: path,
+	}
+}
+
+// TargetDir returns the OutputTarget for code generated from fs that is
+// written to the package in dir, such as "mocks", with the given package
+// name. A relative dir is relative to fs.Dir. If name is empty the base name
+// of dir is used. The import path of the target package is derived from the
+// module enclosing dir, so dir must be within a module, though it need not
+// exist yet.
+func (fs *FileSet) TargetDir(dir, name string) (*OutputTarget, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(fs.Dir, dir)
+	}
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("invalid package name %q", name)
+	}
+	sourcePath, err := fs.importPath()
+	if err != nil {
+		return nil, err
+	}
+	path := dirImportPath(dir)
+	if path == "" {
+		return nil, fmt.Errorf("%s: not in a module", dir)
+	}
+	return &OutputTarget{
+		Dir:        dir,
+		Name:       name,
+		ImportPath: path,
+		source:     fs.Package,
+		sourcePath: sourcePath,
+	}, nil
+}
+
+// IsSource reports whether the target is the package that the code is
+// generated from.
+func (t *OutputTarget) IsSource() bool {
+	return t.ImportPath == t.sourcePath
+}
+
+// Filename returns the name of the file with the given base name in the
+// target package.
+func (t *OutputTarget) Filename(name string) string {
+	return filepath.Join(t.Dir, name)
+}
+
+// Qualifier returns a types.Qualifier for rendering types in code written to
+// the target package. Types of the target package are unqualified, and
+// packages other than the target, including the source package when it is
+// not the target, are added to im as they are encountered.
+func (t *OutputTarget) Qualifier(im *Imports) types.Qualifier {
+	return func(pkg *types.Package) string {
+		path := pkg.Path()
+		if pkg == t.source {
+			path = t.sourcePath
+		}
+		if path == t.ImportPath {
+			return ""
+		}
+		return im.Add(path, pkg.Name())
+	}
+}
+
+// Accessible reports whether code in the target package may refer to obj,
+// which it may unless obj is unexported and declared in another package.
+func (t *OutputTarget) Accessible(obj types.Object) bool {
+	if obj.Exported() || obj.Pkg() == nil {
+		return true
+	}
+	if obj.Pkg() == t.source {
+		return t.IsSource()
+	}
+	return obj.Pkg().Path() == t.ImportPath
+}
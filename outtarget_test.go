@@ -0,0 +1,53 @@
+package gen
+
+import (
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOutputTarget(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n\ntype T struct{ n int }\n\ntype u int\n\nvar V map[u]*T\n",
+	})
+	fs, err := FileSetFromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	typ := fs.Lookup("V").Type()
+
+	src := fs.Target()
+	if !src.IsSource() || src.Name != "p" || src.ImportPath != "example.com/p" {
+		t.Errorf("got %+v, wanted the source package", src)
+	}
+	im := NewImports()
+	if got, want := types.TypeString(typ, src.Qualifier(im)), "map[u]*T"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if im.Len() != 0 {
+		t.Errorf("got imports %v, wanted none", im.Paths())
+	}
+
+	mocks, err := fs.TargetDir("mocks", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mocks.IsSource() || mocks.Name != "mocks" || mocks.ImportPath != "example.com/p/mocks" || mocks.Filename("t.go") != filepath.Join(dir, "mocks", "t.go") {
+		t.Errorf("got %+v, wanted the mocks package", mocks)
+	}
+	im = NewImports()
+	if got, want := types.TypeString(typ, mocks.Qualifier(im)), "map[p.u]*p.T"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if got, want := im.Paths(), []string{"example.com/p"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+	if !mocks.Accessible(fs.Lookup("T")) || mocks.Accessible(fs.Lookup("u")) || !src.Accessible(fs.Lookup("u")) {
+		t.Errorf("got wrong accessibility of unexported type u")
+	}
+
+	if _, err := fs.TargetDir("mocks", "not-a-name"); err == nil {
+		t.Errorf("got no error for an invalid package name")
+	}
+}
@@ -0,0 +1,138 @@
+package gen
+
+import (
+	"bytes"
+	"go/build"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WithOverlay substitutes in-memory contents for files when loading, as the
+// Overlay field of golang.org/x/tools/go/packages does, so that tools such
+// as editors can generate code from unsaved buffers. overlay maps file names
+// to their contents. A file that exists on disk is read from the overlay
+// instead, and a file that does not is added to its directory. Relative
+// names are resolved against the current directory. Overlays may be given
+// more than once; later contents replace earlier ones for the same file.
+//
+// Overlays apply to the files of the loaded package, including the selection
+// of files by build constraints, and to dependencies loaded from source. In
+// ImportAuto mode, dependencies are loaded from source whenever the overlay
+// holds files outside the directory of the loaded package, since the export
+// data built by the go command does not reflect overlays.
+func WithOverlay(overlay map[string][]byte) Option {
+	return func(o *options) {
+		if o.overlay == nil {
+			o.overlay = make(map[string][]byte, len(overlay))
+		}
+		for name, src := range overlay {
+			if abs, err := filepath.Abs(name); err == nil {
+				o.overlay[abs] = src
+			}
+		}
+	}
+}
+
+// overlaid returns the overlay contents of the file filename.
+func (o *options) overlaid(filename string) ([]byte, bool) {
+	if len(o.overlay) == 0 {
+		return nil, false
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, false
+	}
+	src, ok := o.overlay[abs]
+	return src, ok
+}
+
+// overlaysOutside reports whether the overlay holds files outside dir.
+func (o *options) overlaysOutside(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return len(o.overlay) > 0
+	}
+	for name := range o.overlay {
+		if filepath.Dir(name) != abs {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOverlay sets the file system hooks of ctxt so that the files of the
+// overlay are listed in their directories and read from memory.
+func (o *options) applyOverlay(ctxt *build.Context) {
+	if len(o.overlay) == 0 {
+		return
+	}
+	ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		if src, ok := o.overlaid(path); ok {
+			return io.NopCloser(bytes.NewReader(src)), nil
+		}
+		return os.Open(path)
+	}
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		var infos []fs.FileInfo
+		listed := make(map[string]bool)
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+			name := filepath.Join(abs, e.Name())
+			if src, ok := o.overlay[name]; ok {
+				info = overlayInfo{name: e.Name(), size: int64(len(src))}
+			}
+			listed[name] = true
+			infos = append(infos, info)
+		}
+		for name, src := range o.overlay {
+			if filepath.Dir(name) == abs && !listed[name] {
+				infos = append(infos, overlayInfo{name: filepath.Base(name), size: int64(len(src))})
+			}
+		}
+		if len(infos) == 0 && err != nil {
+			return nil, err
+		}
+		return infos, nil
+	}
+}
+
+// readSource returns the contents of the source file filename, read through
+// the file system hooks of ctxt if it has them.
+func readSource(ctxt *build.Context, filename string) ([]byte, error) {
+	if ctxt.OpenFile == nil {
+		return os.ReadFile(filename)
+	}
+	f, err := ctxt.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// overlayInfo describes a file whose contents come from an overlay.
+type overlayInfo struct {
+	name string
+	size int64
+}
+
+func (fi overlayInfo) Name() string       { return fi.name }
+func (fi overlayInfo) Size() int64        { return fi.size }
+func (fi overlayInfo) Mode() fs.FileMode  { return 0o644 }
+func (fi overlayInfo) ModTime() time.Time { return time.Time{} }
+func (fi overlayInfo) IsDir() bool        { return false }
+func (fi overlayInfo) Sys() any           { return nil }
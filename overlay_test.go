@@ -0,0 +1,64 @@
+package gen
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWithOverlay(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n\nfunc Old() {}\n",
+	})
+	fs, err := FileSetFromDir(dir, WithOverlay(map[string][]byte{
+		filepath.Join(dir, "p.go"):       []byte("package p\n\nfunc New() {}\n"),
+		filepath.Join(dir, "extra.go"):   []byte("package p\n\nconst Extra = 1\n"),
+		filepath.Join(dir, "ignored.go"): []byte("//go:build ignore\n\npackage p\n\nconst Ignored = 1\n"),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.Lookup("Old") != nil || fs.Lookup("New") == nil {
+		t.Errorf("got disk contents of p.go, wanted overlay")
+	}
+	if fs.Lookup("Extra") == nil {
+		t.Errorf("file added by overlay not loaded")
+	}
+	if fs.Lookup("Ignored") != nil {
+		t.Errorf("build constraints not applied to overlay file")
+	}
+
+	// A file may exist only in the overlay.
+	filename := filepath.Join(dir, "only.go")
+	fs, err = NewFileSet([]string{filename}, WithOverlay(map[string][]byte{
+		filename: []byte("package p\n\nconst Only = 1\n"),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.Lookup("Only") == nil {
+		t.Errorf("overlay-only file not loaded")
+	}
+}
+
+func TestWithOverlayDependency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":       "package p\n\nimport \"example.com/p/dep\"\n\nvar V = dep.Added()\n",
+		"dep/dep.go": "package dep\n",
+	})
+	if _, err := FileSetFromDir(dir); err == nil {
+		t.Fatalf("got no error loading a package that uses an undefined function")
+	}
+	fs, err := FileSetFromDir(dir, WithOverlay(map[string][]byte{
+		filepath.Join(dir, "dep", "dep.go"): []byte("package dep\n\nfunc Added() int { return 1 }\n"),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.Lookup("V") == nil {
+		t.Errorf("got no V")
+	}
+}
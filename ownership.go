@@ -0,0 +1,177 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ownerPrefix is the directive prefix of the trailer comments that record
+// which generator owns each declaration in a generated file.
+const ownerPrefix = "gen:owner"
+
+// maxOwnerLine is the length beyond which the names owned by a generator are
+// split over several trailer comments.
+const maxOwnerLine = 100
+
+// Ownership maps the names of the declarations in a generated file to the
+// names of the generators that own them. Methods are named by their
+// receiver's base type name and method name separated by a dot, such as
+// "T.String".
+//
+// Ownership is recorded in a trailer of comments at the end of the file, one
+// or more for each generator, such as
+//
+//	//gen:owner stringer Color.String Shape.String
+//
+// which allows tools to tell which generator produced a declaration when
+// several contribute to a package or a file.
+type Ownership map[string]string
+
+// Generators returns the sorted names of the generators that own
+// declarations.
+func (ow Ownership) Generators() []string {
+	seen := make(map[string]bool)
+	var gens []string
+	for _, g := range ow {
+		if !seen[g] {
+			seen[g] = true
+			gens = append(gens, g)
+		}
+	}
+	sort.Strings(gens)
+	return gens
+}
+
+// Owned returns the sorted names of the declarations owned by generator.
+func (ow Ownership) Owned(generator string) []string {
+	var names []string
+	for name, g := range ow {
+		if g == generator {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadOwnership reads the ownership trailer of a generated file's source. It
+// returns an empty Ownership if src has no trailer.
+func ReadOwnership(src []byte) Ownership {
+	ow := Ownership{}
+	s := bufio.NewScanner(bytes.NewReader(src))
+	s.Buffer(nil, len(src)+1)
+	for s.Scan() {
+		d, ok := ParseDirective(ownerPrefix, s.Text())
+		if !ok || d.Name == "" {
+			continue
+		}
+		generator := d.Name
+		if uq, err := strconv.Unquote(generator); err == nil {
+			generator = uq
+		}
+		for _, name := range d.Keys {
+			ow[name] = generator
+		}
+	}
+	return ow
+}
+
+// ReadFileOwnership reads the ownership trailer of the generated file named
+// filename.
+func ReadFileOwnership(filename string) (Ownership, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ReadOwnership(src), nil
+}
+
+// Own records that the declarations with the given names are owned by
+// generator rather than by o's Generator, for outputs that combine the code
+// of several generators. It has no effect unless RecordOwnership is set.
+func (o *Output) Own(generator string, names ...string) {
+	if o.owners == nil {
+		o.owners = make(map[string]string)
+	}
+	for _, name := range names {
+		o.owners[name] = generator
+	}
+}
+
+// ownership determines the owner of each declaration in the formatted source
+// src.
+func (o *Output) ownership(src []byte) (Ownership, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	ow := Ownership{}
+	eachDeclaredName(f, func(name string, _ token.Pos) {
+		if g, ok := o.owners[name]; ok {
+			ow[name] = g
+		} else {
+			ow[name] = o.Generator
+		}
+	})
+	return ow, nil
+}
+
+// appendOwnership appends the ownership trailer for ow to the formatted
+// source src.
+func appendOwnership(src []byte, ow Ownership) []byte {
+	if len(ow) == 0 {
+		return src
+	}
+	src = append(src, '\n')
+	for _, g := range ow.Generators() {
+		line := "//" + ownerPrefix + " " + quoteDirectiveField(g)
+		n := 0
+		for _, name := range ow.Owned(g) {
+			if n > 0 && len(line)+1+len(name) > maxOwnerLine {
+				src = append(src, line...)
+				src = append(src, '\n')
+				line, n = "//"+ownerPrefix+" "+quoteDirectiveField(g), 0
+			}
+			line += " " + name
+			n++
+		}
+		src = append(src, line...)
+		src = append(src, '\n')
+	}
+	return src
+}
+
+// stripOwnership removes any ownership trailer comments from src.
+func stripOwnership(src []byte) []byte {
+	marker := []byte("//" + ownerPrefix + " ")
+	if !bytes.Contains(src, marker) {
+		return src
+	}
+	var out []byte
+	for len(src) > 0 {
+		line := src
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			line = src[:i+1]
+		}
+		if !bytes.HasPrefix(line, marker) {
+			out = append(out, line...)
+		}
+		src = src[len(line):]
+	}
+	return out
+}
+
+// quoteDirectiveField quotes s if it would otherwise be split into several
+// fields of a directive.
+func quoteDirectiveField(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\"`=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOutputOwnership(t *testing.T) {
+	o := NewOutput("go run ./cmd/gen")
+	o.RecordOwnership = true
+	o.Printf("package p\n\ntype Color int\n\nfunc (c Color) String() string { return \"\" }\n\n")
+	o.Printf("var (\n\tA, _ = 1, 2\n)\n\nfunc init() {}\n\n")
+	for i := 0; i < 20; i++ {
+		o.Printf("const LongConstantName%02d = %d\n", i, i)
+	}
+	o.Own("enumer", "Color.String")
+
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	trailer := src[strings.Index(string(src), "\n//gen:owner")+1:]
+	for _, line := range strings.Split(strings.TrimSuffix(string(trailer), "\n"), "\n") {
+		if !strings.HasPrefix(line, "//gen:owner ") {
+			t.Errorf("got trailer line %q", line)
+		}
+		if len(line) > maxOwnerLine+len("LongConstantName00") {
+			t.Errorf("trailer line too long: %q", line)
+		}
+	}
+
+	ow := ReadOwnership(src)
+	if got, want := ow.Generators(), []string{"enumer", "go run ./cmd/gen"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got generators %+v, wanted %+v", got, want)
+	}
+	if got, want := ow.Owned("enumer"), []string{"Color.String"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got owned %+v, wanted %+v", got, want)
+	}
+	if got, want := len(ow.Owned("go run ./cmd/gen")), 22; got != want {
+		t.Errorf("got %d declarations, wanted %d", got, want)
+	}
+	if ow["A"] != "go run ./cmd/gen" {
+		t.Errorf("got owner %q for A", ow["A"])
+	}
+
+	// Regenerating from source that includes a trailer replaces it.
+	again := NewOutput("go run ./cmd/gen")
+	again.RecordOwnership = true
+	again.Write(src)
+	src2, err := again.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ReadOwnership(src2); got["Color.String"] != "go run ./cmd/gen" || len(got) != len(ow) {
+		t.Errorf("got ownership %+v after regenerating", got)
+	}
+	if strings.Contains(string(src2), "//gen:owner enumer") {
+		t.Errorf("previous trailer was not replaced:\n%s", src2)
+	}
+
+	// Regeneration is stable.
+	third := NewOutput("go run ./cmd/gen")
+	third.RecordOwnership = true
+	third.Write(src2)
+	src3, err := third.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(src3) != string(src2) {
+		t.Errorf("got:\n%s\nwanted:\n%s", src3, src2)
+	}
+}
+
+func TestReadFileOwnership(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "gen.go")
+	src := "// Code generated by x; DO NOT EDIT.\n\npackage p\n\nfunc F() {}\n\n//gen:owner x F\n"
+	if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ow, err := ReadFileOwnership(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Ownership{"F": "x"}); !reflect.DeepEqual(ow, want) {
+		t.Errorf("got %+v, wanted %+v", ow, want)
+	}
+
+	if got := ReadOwnership([]byte("package p\n")); len(got) != 0 {
+		t.Errorf("got %+v, wanted no ownership", got)
+	}
+}
@@ -0,0 +1,79 @@
+package gen
+
+import (
+	"go/ast"
+	"runtime"
+	"sync"
+)
+
+// ParallelInspect traverses the files in fs concurrently, calling f for the
+// nodes of each file as Inspect does. The index of the file in AstFiles is
+// passed to f along with the node. Each file is traversed by a single
+// goroutine, so the nodes of a file are visited in position order, but the
+// files are traversed in no particular order and f must be safe for
+// concurrent use. Use a Collector to gather results in a deterministic order.
+//
+// ParallelInspect is intended for large packages, with thousands of files,
+// where a single threaded traversal dominates the time taken to generate code.
+func (fs *FileSet) ParallelInspect(f func(file int, node ast.Node) bool) {
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for n := min(runtime.GOMAXPROCS(0), len(fs.AstFiles)); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				ast.Inspect(fs.AstFiles[i], func(node ast.Node) bool {
+					return f(i, node)
+				})
+			}
+		}()
+	}
+	for i := range fs.AstFiles {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// Collector gathers values produced by a concurrent traversal such as
+// ParallelInspect and returns them in the order a sequential traversal would
+// have produced them. A Collector is safe for concurrent use.
+type Collector[T any] struct {
+	mu    sync.Mutex
+	files map[int][]T
+}
+
+// NewCollector creates an empty Collector.
+func NewCollector[T any]() *Collector[T] {
+	return &Collector[T]{files: make(map[int][]T)}
+}
+
+// Add records v as produced while traversing the file with the given index.
+// Values added for the same file are kept in the order they were added, which
+// is position order when added by a ParallelInspect callback.
+func (c *Collector[T]) Add(file int, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[file] = append(c.files[file], v)
+}
+
+// Results returns the values added to the collector, ordered by the index of
+// the file they were produced from and then by the order they were added.
+func (c *Collector[T]) Results() []T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	max, n := -1, 0
+	for file, vs := range c.files {
+		if file > max {
+			max = file
+		}
+		n += len(vs)
+	}
+	results := make([]T, 0, n)
+	for file := 0; file <= max; file++ {
+		results = append(results, c.files[file]...)
+	}
+	return results
+}
@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"testing"
+)
+
+func TestParallelInspect(t *testing.T) {
+	var texts []string
+	for i := 0; i < 50; i++ {
+		texts = append(texts, fmt.Sprintf(`package p
+
+			type T%[1]d struct{ A, B int }
+
+			func F%[1]d() { type local%[1]d int }
+
+			var V%[1]d = T%[1]d{}
+		`, i))
+	}
+	fs, err := NewFileSetFromTexts(texts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{}
+	fs.Inspect(func(node ast.Node) bool {
+		if id, ok := node.(*ast.Ident); ok {
+			want = append(want, id.Name)
+		}
+		return true
+	})
+
+	c := NewCollector[string]()
+	fs.ParallelInspect(func(file int, node ast.Node) bool {
+		if id, ok := node.(*ast.Ident); ok {
+			c.Add(file, id.Name)
+		}
+		return true
+	})
+	if got := c.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	// Each functions visit declarations in file and position order.
+	types := []string{}
+	fs.EachType(func(ts *ast.TypeSpec) bool {
+		types = append(types, ts.Name.Name)
+		return true
+	})
+	if got, want := types[:4], []string{"T0", "local0", "T1", "local1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestCollectorEmpty(t *testing.T) {
+	c := NewCollector[int]()
+	if got := c.Results(); len(got) != 0 {
+		t.Errorf("got %+v, wanted no results", got)
+	}
+}
@@ -0,0 +1,83 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrChangesNeeded is returned by CheckOutputs when the files on disk differ
+// from the outputs, meaning the generated code is out of date.
+var ErrChangesNeeded = errors.New("generated files are out of date")
+
+// CheckOutputs compares the outputs, keyed by the filename they would be
+// written to, with the files on disk without writing anything. It returns a
+// unified diff of the changes writing them would make, as produced by Patch,
+// and ErrChangesNeeded if there are any. It is intended for checks, such as in
+// continuous integration, that generated code has been regenerated.
+func CheckOutputs(outputs map[string]*Output) ([]byte, error) {
+	diff, err := Patch(outputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(diff) > 0 {
+		return diff, ErrChangesNeeded
+	}
+	return nil, nil
+}
+
+// Patch returns a single unified diff covering the changes that writing the
+// outputs, keyed by the filename they would be written to, would make to the
+// files on disk. Nothing is written. Files are named relative to the current
+// directory with a/ and b/ prefixes, and files that do not yet exist are
+// compared with /dev/null, so the patch can be applied with git apply or
+// patch -p1 from the same directory of another checkout. Outputs are checked
+// as they would be by WriteFile, so the patch never overwrites a file that
+// was not generated unless Force is set. Patch returns an empty patch if the
+// outputs match the files on disk.
+func Patch(outputs map[string]*Output) ([]byte, error) {
+	filenames := make([]string, 0, len(outputs))
+	for filename := range outputs {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var patch bytes.Buffer
+	var errs []error
+	for _, filename := range filenames {
+		p, err := preparePending(filename, outputs[filename])
+		if err == nil {
+			err = p.readPrevious()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		name := patchName(filename)
+		oldName := "a/" + name
+		if !p.existed {
+			oldName = "/dev/null"
+		}
+		patch.Write(UnifiedDiff(oldName, "b/"+name, p.previous, p.src))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return patch.Bytes(), nil
+}
+
+// patchName returns the slash separated name of filename relative to the
+// current directory, if it is within it.
+func patchName(filename string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if abs, err := filepath.Abs(filename); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil && filepath.IsLocal(rel) {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(filename)
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	existing := "// Code generated by gen; DO NOT EDIT.\n\npackage p\n\nconst A = 1\n"
+	if err := os.WriteFile("a.go", []byte(existing), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile("same.go", []byte(existing), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := func(src string) *Output {
+		o := NewOutput("gen")
+		o.Printf("%s", src)
+		return o
+	}
+	outputs := map[string]*Output{
+		filepath.Join(dir, "a.go"): output("package p\n\nconst A = 2\n"),
+		"same.go":                  output("package p\n\nconst A = 1\n"),
+		"sub/b.go":                 output("package p\n"),
+	}
+	patch, err := Patch(outputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "--- a/a.go\n+++ b/a.go\n@@ -2,4 +2,4 @@\n \n package p\n \n-const A = 1\n+const A = 2\n" +
+		"--- /dev/null\n+++ b/sub/b.go\n@@ -0,0 +1,3 @@\n+// Code generated by gen; DO NOT EDIT.\n+\n+package p\n"
+	if string(patch) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", patch, want)
+	}
+	if got, _ := os.ReadFile("a.go"); string(got) != existing {
+		t.Errorf("Patch changed a.go")
+	}
+
+	if err := os.WriteFile("manual.go", []byte("package p\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Patch(map[string]*Output{"manual.go": output("package p\n")}); !errors.Is(err, ErrNotGenerated) {
+		t.Errorf("got error %v, wanted %v", err, ErrNotGenerated)
+	}
+}
+
+func TestRunnerPatch(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	var stdout strings.Builder
+	r := stringerRunner()
+	r.Stdout = &stdout
+	if err := r.Run([]string{"-type", "Color", "-patch", "-", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "+func (Color) String() string") {
+		t.Errorf("got patch:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "color_stringer.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("output was written in patch mode")
+	}
+
+	patchFile := filepath.Join(t.TempDir(), "gen.patch")
+	if err := r.Run([]string{"-type", "Color", "-patch", patchFile, dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, err := os.ReadFile(patchFile); err != nil || string(got) != stdout.String() {
+		t.Errorf("got patch file %q, %v, wanted %q", got, err, stdout.String())
+	}
+}
+
+func TestCheckOutputs(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.go")
+	o := NewOutput("gen")
+	o.Printf("package p\n")
+
+	diff, err := CheckOutputs(map[string]*Output{filename: o})
+	if !errors.Is(err, ErrChangesNeeded) || !strings.Contains(string(diff), "+package p") {
+		t.Errorf("got %q, %v, wanted diff and %v", diff, err, ErrChangesNeeded)
+	}
+	if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("CheckOutputs wrote the output")
+	}
+
+	if err := o.WriteFile(filename); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff, err := CheckOutputs(map[string]*Output{filename: o}); err != nil || len(diff) != 0 {
+		t.Errorf("got %q, %v, wanted no diff and no error", diff, err)
+	}
+}
+
+func TestRunnerDiff(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	var stdout strings.Builder
+	r := stringerRunner()
+	r.Stdout = &stdout
+	if err := r.Run([]string{"-type", "Color", "-diff", dir}); !errors.Is(err, ErrChangesNeeded) {
+		t.Errorf("got error %v, wanted %v", err, ErrChangesNeeded)
+	}
+	if !strings.Contains(stdout.String(), "+func (Color) String() string") {
+		t.Errorf("got diff:\n%s", stdout.String())
+	}
+
+	if err := r.Run([]string{"-type", "Color", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stdout.Reset()
+	if err := r.Run([]string{"-type", "Color", "-diff", dir}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("got diff for up to date output:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,335 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// Stage identifies a stage of a Pipeline.
+type Stage int
+
+const (
+	// StageLoad loads the package the code is generated from.
+	StageLoad Stage = iota
+
+	// StageAnalyze builds the data that the Render stage renders.
+	StageAnalyze
+
+	// StageRender produces the outputs.
+	StageRender
+
+	// StageFormat formats the source code of the outputs.
+	StageFormat
+
+	// StageWrite writes the formatted source code to files.
+	StageWrite
+
+	numStages = int(StageWrite) + 1
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageLoad:
+		return "load"
+	case StageAnalyze:
+		return "analyze"
+	case StageRender:
+		return "render"
+	case StageFormat:
+		return "format"
+	case StageWrite:
+		return "write"
+	}
+	return fmt.Sprintf("Stage(%d)", int(s))
+}
+
+// StageFunc is the function of a stage of a Pipeline, or a hook run before
+// or after a stage. It reads and updates the state of the run.
+type StageFunc func(ctx context.Context, run *PipelineRun) error
+
+// Pipeline runs a generator as a sequence of stages: Load, Analyze, Render,
+// Format and Write. Each stage is a function that updates a PipelineRun, and
+// hooks registered with Before and After run around it, giving a place for
+// behavior that cuts across generators. For example, a hook after the
+// Analyze stage can add to the data rendered, a hook after the Format stage
+// can post-process the formatted source, and a hook before the Write stage
+// can veto writing by returning an error or by removing files from
+// PipelineRun.Sources.
+//
+// The Format and Write stages are provided by the Pipeline: Format formats
+// each output as Output.Source does, and Write writes the formatted source
+// with the all-or-nothing semantics of WriteOutputs.
+type Pipeline struct {
+	// Name is the name of the generator. It is used in the generated code
+	// header of each output.
+	Name string
+
+	// Load loads the package into PipelineRun.FileSet. If nil, the package
+	// is loaded from the arguments of the run with NewFileSetContext and
+	// Options.
+	Load StageFunc
+
+	// Options control how the package is loaded when Load is nil.
+	Options []Option
+
+	// Analyze, if not nil, sets PipelineRun.Data from the loaded package.
+	Analyze StageFunc
+
+	// Render produces the outputs, typically with PipelineRun.Output.
+	Render StageFunc
+
+	// Force permits the Write stage to overwrite existing files that do not
+	// carry the generated code header.
+	Force bool
+
+	// TypeCheck, if true, makes the Write stage type check the sources of
+	// the run together with the package before writing them, failing with
+	// ErrNotCompiled if they do not compile. See FileSet.CheckCompiles.
+	TypeCheck bool
+
+	// CheckConflicts, if true, makes the Write stage check the sources of
+	// the run for identifiers declared more than once in their packages
+	// before writing them, failing with a *ConflictError if any are. Files
+	// the run would prune are not counted. See the CheckConflicts function.
+	CheckConflicts bool
+
+	// Prune, if not empty, is a pattern in the syntax of filepath.Match,
+	// such as *_gen.go, matching the names of the files the pipeline
+	// writes. After the Write stage writes the sources of the run, the
+	// files matching Prune in the package directory and the directories of
+	// the outputs that were generated by Name but are not outputs of the
+	// run are deleted, as PruneStale does, and the run fails if Name is
+	// empty.
+	Prune string
+
+	// Logger, if not nil, reports the time taken by each stage, the progress
+	// of loading the package, as WithLogger does, and each file written.
+	Logger *slog.Logger
+
+	before, after [numStages][]StageFunc
+}
+
+// PipelineRun is the state of a single run of a Pipeline, passed to each of
+// its stages and hooks.
+type PipelineRun struct {
+	// Args holds the arguments the pipeline was run with, the package
+	// directory or the Go source files to load.
+	Args []string
+
+	// FileSet holds the package loaded by the Load stage.
+	FileSet *FileSet
+
+	// Data holds the data built by the Analyze stage for the Render stage,
+	// such as the data passed to a template.
+	Data any
+
+	// Outputs holds the outputs produced by the Render stage, keyed by the
+	// filename they are written to.
+	Outputs map[string]*Output
+
+	// Sources holds the formatted source code of the outputs, keyed by
+	// filename, set by the Format stage. The Write stage writes exactly
+	// these files.
+	Sources map[string][]byte
+
+	name string
+}
+
+// Before registers f to run before the given stage. Hooks run in the order
+// they are registered. Before panics if stage is not one of the stages of a
+// Pipeline.
+func (p *Pipeline) Before(stage Stage, f StageFunc) {
+	checkStage("Before", stage)
+	p.before[stage] = append(p.before[stage], f)
+}
+
+// After registers f to run after the given stage. Hooks run in the order
+// they are registered. After panics if stage is not one of the stages of a
+// Pipeline.
+func (p *Pipeline) After(stage Stage, f StageFunc) {
+	checkStage("After", stage)
+	p.after[stage] = append(p.after[stage], f)
+}
+
+// checkStage panics if stage, passed to the named method, is not a stage of
+// a Pipeline.
+func checkStage(method string, stage Stage) {
+	if stage < 0 || int(stage) >= numStages {
+		panic(fmt.Sprintf("gen: Pipeline.%s called with invalid stage %v", method, stage))
+	}
+}
+
+// Run runs the stages of the pipeline in order with their hooks, loading the
+// package from args. It stops at the first error returned by a stage or
+// hook, or when ctx is done, and returns the state of the run so far. No
+// files are written unless the Write stage is reached.
+func (p *Pipeline) Run(ctx context.Context, args ...string) (*PipelineRun, error) {
+	if p.Render == nil {
+		return nil, errors.New("pipeline has no Render stage")
+	}
+	run := &PipelineRun{
+		Args:    args,
+		Outputs: make(map[string]*Output),
+		Sources: make(map[string][]byte),
+		name:    p.Name,
+	}
+	stages := [numStages]StageFunc{
+		StageLoad:    p.Load,
+		StageAnalyze: p.Analyze,
+		StageRender:  p.Render,
+		StageFormat:  formatStage,
+		StageWrite:   p.writeStage,
+	}
+	if stages[StageLoad] == nil {
+		stages[StageLoad] = p.loadStage
+	}
+
+	for stage, f := range stages {
+		start := time.Now()
+		funcs := append(append(append([]StageFunc(nil), p.before[stage]...), f), p.after[stage]...)
+		for _, f := range funcs {
+			if f == nil {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return run, err
+			}
+			if err := f(ctx, run); err != nil {
+				return run, err
+			}
+		}
+		p.logger().Info("finished stage", "stage", Stage(stage), "duration", time.Since(start))
+	}
+	return run, nil
+}
+
+func (p *Pipeline) logger() *slog.Logger {
+	if p.Logger == nil {
+		return discardLogger
+	}
+	return p.Logger
+}
+
+// Output returns the output that will be written to filename, creating it if
+// necessary. A relative filename is relative to the directory of the loaded
+// package.
+func (run *PipelineRun) Output(filename string) *Output {
+	if !filepath.IsAbs(filename) && run.FileSet != nil {
+		filename = filepath.Join(run.FileSet.Dir, filename)
+	}
+	for name, o := range run.Outputs {
+		if samePath(name, filename) {
+			return o
+		}
+	}
+	o := NewOutput(run.name)
+	run.Outputs[filename] = o
+	return o
+}
+
+// loadStage loads the package named by the arguments of the run.
+func (p *Pipeline) loadStage(ctx context.Context, run *PipelineRun) error {
+	opts := p.Options[:len(p.Options):len(p.Options)]
+	if p.Logger != nil {
+		opts = append(opts, WithLogger(p.Logger))
+	}
+	fs, err := NewFileSetContext(ctx, run.Args, opts...)
+	if err != nil {
+		return err
+	}
+	run.FileSet = fs
+	return nil
+}
+
+// formatStage formats the source of each output into the sources of the run,
+// reporting every output that fails to format.
+func formatStage(ctx context.Context, run *PipelineRun) error {
+	var errs []error
+	for _, filename := range SortedKeys(run.Outputs) {
+		src, err := run.Outputs[filename].Source()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+			continue
+		}
+		run.Sources[filename] = src
+	}
+	return errors.Join(errs...)
+}
+
+// checkConflicts checks the sources of the run for conflicting
+// declarations.
+func (p *Pipeline) checkConflicts(run *PipelineRun) error {
+	ctxt := &build.Default
+	if run.FileSet != nil {
+		ctxt = run.FileSet.opts.buildContext()
+	}
+	generators := make(map[string]string, len(run.Sources))
+	for filename := range run.Sources {
+		generators[filename] = p.Name
+		if o := run.Outputs[filename]; o != nil {
+			generators[filename] = o.Generator
+		}
+	}
+	return checkConflicts(ctxt, run.Sources, generators, prunable(p.Prune, p.Name))
+}
+
+// writeStage writes the sources of the run with all-or-nothing semantics,
+// first type checking them if the pipeline has TypeCheck set and then
+// pruning stale files if it has Prune set.
+func (p *Pipeline) writeStage(ctx context.Context, run *PipelineRun) error {
+	if p.TypeCheck && run.FileSet != nil {
+		if err := run.FileSet.CheckCompiles(run.Sources); err != nil {
+			return err
+		}
+	}
+	if p.CheckConflicts {
+		if err := p.checkConflicts(run); err != nil {
+			return err
+		}
+	}
+	var errs []error
+	var todo []*pendingFile
+	for _, filename := range SortedKeys(run.Sources) {
+		o := run.Outputs[filename]
+		if o == nil {
+			o = NewOutput(p.Name)
+		}
+		perm, err := checkTarget(filename, o.Force || p.Force)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		todo = append(todo, &pendingFile{filename: filename, src: run.Sources[filename], perm: perm})
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if err := writePending(todo); err != nil {
+		return err
+	}
+	for _, f := range todo {
+		p.logger().Info("wrote file", "file", f.filename, "bytes", len(f.src))
+	}
+	if p.Prune != "" && run.FileSet != nil {
+		// Files of outputs whose writing was vetoed are not stale.
+		produced := SortedKeys(run.Sources)
+		for _, filename := range SortedKeys(run.Outputs) {
+			if _, ok := run.Sources[filename]; !ok {
+				produced = append(produced, filename)
+			}
+		}
+		removed, err := pruneOutputs(run.FileSet.Dir, p.Prune, p.Name, produced)
+		for _, filename := range removed {
+			p.logger().Info("removed stale file", "file", filename)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n\ntype Color int\n\ntype Size int\n",
+	})
+
+	var stages []string
+	record := func(name string) StageFunc {
+		return func(ctx context.Context, run *PipelineRun) error {
+			stages = append(stages, name)
+			return nil
+		}
+	}
+
+	p := &Pipeline{
+		Name: "names",
+		Analyze: func(ctx context.Context, run *PipelineRun) error {
+			data := map[string]any{}
+			var names []string
+			for _, tm := range run.FileSet.Types() {
+				names = append(names, tm.Name)
+			}
+			data["Types"] = names
+			run.Data = data
+			return nil
+		},
+		Render: func(ctx context.Context, run *PipelineRun) error {
+			data := run.Data.(map[string]any)
+			o := run.Output("names.go")
+			o.Printf("package p\n\n// %s\nvar names = %#v\n", data["Comment"], data["Types"])
+			return nil
+		},
+	}
+	p.Before(StageLoad, record("before load"))
+	p.After(StageAnalyze, func(ctx context.Context, run *PipelineRun) error {
+		run.Data.(map[string]any)["Comment"] = "injected"
+		return nil
+	})
+	p.After(StageFormat, func(ctx context.Context, run *PipelineRun) error {
+		for name, src := range run.Sources {
+			run.Sources[name] = bytes.ReplaceAll(src, []byte("var names"), []byte("var typeNames"))
+		}
+		return nil
+	})
+	p.Before(StageWrite, record("before write"))
+	p.After(StageWrite, record("after write"))
+
+	run, err := p.Run(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"before load", "before write", "after write"}; !reflect.DeepEqual(stages, want) {
+		t.Errorf("got stages %v, wanted %v", stages, want)
+	}
+
+	filename := filepath.Join(dir, "names.go")
+	if _, ok := run.Outputs[filename]; !ok {
+		t.Errorf("output %s not recorded", filename)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"// Code generated by names; DO NOT EDIT.", "// injected", `var typeNames = []string{"Color", "Size"}`} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("written file does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPipelineVeto(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n",
+	})
+	render := func(ctx context.Context, run *PipelineRun) error {
+		run.Output("a.go").Printf("package p\n")
+		run.Output("b.go").Printf("package p\n")
+		return nil
+	}
+
+	errVeto := errors.New("vetoed")
+	p := &Pipeline{Name: "veto", Render: render}
+	p.Before(StageWrite, func(ctx context.Context, run *PipelineRun) error {
+		return errVeto
+	})
+	if _, err := p.Run(context.Background(), dir); !errors.Is(err, errVeto) {
+		t.Errorf("got error %v, wanted %v", err, errVeto)
+	}
+	for _, name := range []string{"a.go", "b.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s written after veto", name)
+		}
+	}
+
+	p = &Pipeline{Name: "veto", Render: render}
+	p.Before(StageWrite, func(ctx context.Context, run *PipelineRun) error {
+		delete(run.Sources, filepath.Join(dir, "b.go"))
+		return nil
+	})
+	if _, err := p.Run(context.Background(), dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.go")); err != nil {
+		t.Errorf("a.go not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.go")); err == nil {
+		t.Errorf("b.go written after its source was removed")
+	}
+}
+
+func TestPipelinePrune(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go":       "package p\n",
+		"old_gen.go": "// Code generated by prune; DO NOT EDIT.\n\npackage p\n",
+		"b_gen.go":   "// Code generated by prune; DO NOT EDIT.\n\npackage p\n",
+	})
+	p := &Pipeline{
+		Name:  "prune",
+		Prune: "*_gen.go",
+		Render: func(ctx context.Context, run *PipelineRun) error {
+			run.Output("a_gen.go").Printf("package p\n")
+			run.Output("b_gen.go").Printf("package p\n")
+			return nil
+		},
+	}
+	p.Before(StageWrite, func(ctx context.Context, run *PipelineRun) error {
+		delete(run.Sources, filepath.Join(dir, "b_gen.go"))
+		return nil
+	})
+	if _, err := p.Run(context.Background(), dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old_gen.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale old_gen.go not pruned")
+	}
+	for _, name := range []string{"a_gen.go", "b_gen.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s pruned: %v", name, err)
+		}
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n",
+	})
+
+	if _, err := (&Pipeline{}).Run(context.Background(), dir); err == nil {
+		t.Errorf("got no error for a pipeline without a Render stage, wanted one")
+	}
+
+	rendered := false
+	p := &Pipeline{
+		Name: "bad",
+		Render: func(ctx context.Context, run *PipelineRun) error {
+			rendered = true
+			run.Output("bad.go").Printf("package p\n\nfunc {\n")
+			return nil
+		},
+	}
+	if _, err := p.Run(context.Background(), dir); err == nil {
+		t.Errorf("got no error for output that does not format, wanted one")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.go")); err == nil {
+		t.Errorf("bad.go written despite format error")
+	}
+
+	checked := &Pipeline{
+		Name:      "undefined",
+		TypeCheck: true,
+		Render: func(ctx context.Context, run *PipelineRun) error {
+			run.Output("undefined.go").Printf("package p\n\nvar x = undefined\n")
+			return nil
+		},
+	}
+	if _, err := checked.Run(context.Background(), dir); !errors.Is(err, ErrNotCompiled) {
+		t.Errorf("got error %v, wanted %v", err, ErrNotCompiled)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "undefined.go")); err == nil {
+		t.Errorf("undefined.go written despite type errors")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rendered = false
+	if _, err := p.Run(ctx, dir); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+	if rendered {
+		t.Errorf("canceled pipeline ran its Render stage")
+	}
+}
+
+func TestPipelineLogger(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n",
+	})
+	var buf bytes.Buffer
+	p := &Pipeline{
+		Name:   "logged",
+		Logger: slog.New(slog.NewTextHandler(&buf, nil)),
+		Render: func(ctx context.Context, run *PipelineRun) error {
+			run.Output("a.go").Printf("package p\n")
+			return nil
+		},
+	}
+	if _, err := p.Run(context.Background(), dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	log := buf.String()
+	for _, want := range []string{
+		`msg="loaded package" dir=` + dir,
+		`msg="finished stage" stage=load duration=`,
+		`msg="finished stage" stage=write duration=`,
+		`msg="wrote file" file=` + filepath.Join(dir, "a.go"),
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log does not contain %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "parsed file") {
+		t.Errorf("log contains debug records:\n%s", log)
+	}
+}
+
+func TestPipelineForce(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n",
+		"a.go": "package p\n\n// written by hand\n",
+	})
+	p := &Pipeline{
+		Name:  "force",
+		Force: true,
+		Render: func(ctx context.Context, run *PipelineRun) error {
+			run.Output("a.go").Printf("package p\n")
+			return nil
+		},
+	}
+	run, err := p.Run(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filename := filepath.Join(dir, "a.go")
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsGenerated(src) {
+		t.Errorf("a.go not overwritten:\n%s", src)
+	}
+	if run.Outputs[filename].Force {
+		t.Errorf("pipeline set Force on its output")
+	}
+}
+
+func TestPipelineInvalidStage(t *testing.T) {
+	hook := func(ctx context.Context, run *PipelineRun) error { return nil }
+	for _, register := range []func(*Pipeline){
+		func(p *Pipeline) { p.Before(Stage(-1), hook) },
+		func(p *Pipeline) { p.After(StageWrite+1, hook) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("got no panic for an invalid stage, wanted one")
+				}
+			}()
+			register(&Pipeline{})
+		}()
+	}
+}
+
+func TestPipelineCheckConflicts(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go":       "package p\n\nfunc Manual() {}\n",
+		"old_gen.go": "// Code generated by conflicts; DO NOT EDIT.\n\npackage p\n\nfunc Gen() {}\n",
+	})
+	render := func(name string) StageFunc {
+		return func(ctx context.Context, run *PipelineRun) error {
+			run.Output("new_gen.go").Printf("package p\n\nfunc %s() {}\n", name)
+			return nil
+		}
+	}
+
+	p := &Pipeline{Name: "conflicts", CheckConflicts: true, Render: render("Manual")}
+	var ce *ConflictError
+	if _, err := p.Run(context.Background(), dir); !errors.As(err, &ce) {
+		t.Errorf("got error %v, wanted a *ConflictError", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new_gen.go")); err == nil {
+		t.Errorf("conflicting output was written")
+	}
+
+	p = &Pipeline{Name: "conflicts", CheckConflicts: true, Prune: "*_gen.go", Render: render("Gen")}
+	if _, err := p.Run(context.Background(), dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
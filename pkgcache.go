@@ -0,0 +1,149 @@
+package gen
+
+import (
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PackageCache keeps FileSets loaded for use by a long running process, such
+// as a server that regenerates code when an editor saves a file. Loading a
+// directory whose package is unchanged since it was last loaded returns the
+// cached FileSet, and the types of dependencies are shared between packages
+// through an ImporterCache, so incremental loads only type check the packages
+// that changed. A PackageCache is safe for concurrent use, but the FileSets it
+// returns must not be modified.
+//
+// A package is reloaded when any of the Go source files in its directory or in
+// the directories of the packages it imports from the same module change, or
+// when the go.mod or go.sum file of its module changes.
+type PackageCache struct {
+	// Importers holds the types of the dependencies of cached packages.
+	Importers *ImporterCache
+
+	opts    []Option
+	mu      sync.Mutex
+	entries map[string]*packageEntry // by absolute directory
+}
+
+type packageEntry struct {
+	fs   *FileSet
+	dirs []string // directories of the package and its imports from the same module
+	sum  string   // digest of the files in dirs and the module's go.mod and go.sum
+}
+
+// NewPackageCache creates an empty cache that loads packages using opts. The
+// cache supplies its own ImporterCache, overriding any given in opts.
+func NewPackageCache(opts ...Option) *PackageCache {
+	c := &PackageCache{
+		Importers: NewImporterCache(0),
+		entries:   make(map[string]*packageEntry),
+	}
+	c.opts = append(append([]Option{}, opts...), WithImporterCache(c.Importers))
+	return c
+}
+
+// Load returns the FileSet for the package in dir, loading it if it is not
+// cached or has changed since it was loaded.
+func (c *PackageCache) Load(dir string) (*FileSet, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[abs]; ok {
+		if packageSum(e.dirs) == e.sum {
+			return e.fs, nil
+		}
+		c.invalidateLocked(abs)
+	}
+
+	fs, err := FileSetFromDir(abs, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+	dirs := append([]string{abs}, localImportDirs(fs.Package, abs)...)
+	c.entries[abs] = &packageEntry{
+		fs:   fs,
+		dirs: dirs,
+		sum:  packageSum(dirs),
+	}
+	return fs, nil
+}
+
+// Invalidate discards the cached FileSet for the package in dir, such as when
+// a file watcher reports a change. Packages are also reloaded automatically
+// when Load finds that their files have changed.
+func (c *PackageCache) Invalidate(dir string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(abs)
+}
+
+// invalidateLocked discards the entry for dir and the types of the packages
+// of its module held by the importer cache, which may depend on it.
+func (c *PackageCache) invalidateLocked(dir string) {
+	delete(c.entries, dir)
+	c.Importers.Invalidate(dir)
+}
+
+// localImportDirs returns the directories of the packages imported directly
+// or indirectly by pkg that belong to the module enclosing dir.
+func localImportDirs(pkg *types.Package, dir string) []string {
+	root := moduleDir(dir)
+	if root == "" {
+		return nil
+	}
+	modPath := dirImportPath(root)
+
+	var dirs []string
+	seen := make(map[*types.Package]bool)
+	var walk func(p *types.Package)
+	walk = func(p *types.Package) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		if rel, ok := strings.CutPrefix(p.Path(), modPath); ok && (rel == "" || rel[0] == '/') {
+			dirs = append(dirs, filepath.Join(root, filepath.FromSlash(rel)))
+		}
+		for _, imp := range p.Imports() {
+			walk(imp)
+		}
+	}
+	for _, imp := range pkg.Imports() {
+		walk(imp)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// packageSum returns a digest of the Go source files in dirs and the go.mod
+// and go.sum files of the module enclosing the first directory.
+func packageSum(dirs []string) string {
+	return hashFiles(packageFiles(dirs)...)
+}
+
+// packageFiles returns the Go source files in dirs followed by the go.mod and
+// go.sum files of the module enclosing the first directory.
+func packageFiles(dirs []string) []string {
+	var files []string
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	if root := moduleDir(dirs[0]); root != "" {
+		files = append(files, filepath.Join(root, "go.mod"), filepath.Join(root, "go.sum"))
+	}
+	return files
+}
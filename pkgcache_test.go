@@ -0,0 +1,74 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":       "package p\n\nimport \"example.com/p/dep\"\n\nvar X = dep.V\n",
+		"dep/dep.go": "package dep\n\nvar V int\n",
+		"other/o.go": "package other\n\nimport \"strings\"\n\nvar Y = strings.ToUpper(\"y\")\n",
+	})
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	c := NewPackageCache()
+	load := func(d string) *FileSet {
+		t.Helper()
+		fs, err := c.Load(d)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return fs
+	}
+	typeOfX := func(fs *FileSet) string {
+		return fs.Lookup("X").Type().String()
+	}
+
+	fs := load(dir)
+	other := load(filepath.Join(dir, "other"))
+	if got := typeOfX(fs); got != "int" {
+		t.Errorf("got type %s, wanted int", got)
+	}
+	if load(dir) != fs {
+		t.Errorf("unchanged package was reloaded")
+	}
+
+	// Changing a dependency in the same module reloads the package.
+	write("dep/dep.go", "package dep\n\nvar V string\n")
+	fs2 := load(dir)
+	if fs2 == fs {
+		t.Fatalf("package not reloaded after dependency changed")
+	}
+	if got := typeOfX(fs2); got != "string" {
+		t.Errorf("got type %s, wanted string", got)
+	}
+
+	// Changing the package's own files reloads it.
+	write("p.go", "package p\n\nimport \"example.com/p/dep\"\n\nvar X = []string{dep.V}\n")
+	fs3 := load(dir)
+	if got := typeOfX(fs3); got != "[]string" {
+		t.Errorf("got type %s, wanted []string", got)
+	}
+
+	// Changing go.mod reloads every package in the module.
+	write("go.mod", "module example.com/p\n\ngo 1.22\n")
+	if load(filepath.Join(dir, "other")) == other {
+		t.Errorf("package not reloaded after go.mod changed")
+	}
+
+	c.Invalidate(dir)
+	if load(dir) == fs3 {
+		t.Errorf("package not reloaded after Invalidate")
+	}
+}
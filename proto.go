@@ -0,0 +1,671 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// ProtoFile describes a Protocol Buffers file in proto3 syntax: its messages
+// and enums. It is produced from the struct types of a FileSet by
+// FileSet.ProtoFile, rendered as a .proto file by String, read from one by
+// ParseProto and turned back into Go declarations by GoSource, so that teams
+// moving to gRPC can bootstrap their .proto files from existing Go types.
+// Only the features needed to describe plain structs are supported: nested
+// declarations, oneofs and services are not.
+type ProtoFile struct {
+	// Package is the proto package name, such as acme.orders.v1.
+	Package string
+
+	// GoPackage is the value of the go_package option, or empty if the file
+	// does not have one.
+	GoPackage string
+
+	// Imports holds the files imported, such as
+	// google/protobuf/timestamp.proto.
+	Imports []string
+
+	// Messages holds the messages in the order they are declared.
+	Messages []*ProtoMessage
+
+	// Enums holds the enums in the order they are declared.
+	Enums []*ProtoEnum
+}
+
+// ProtoMessage describes a message.
+type ProtoMessage struct {
+	// Name is the name of the message.
+	Name string
+
+	// Fields holds the fields of the message in the order they are declared.
+	Fields []*ProtoField
+}
+
+// ProtoField describes a field of a message.
+type ProtoField struct {
+	// Name is the name of the field, in snake case.
+	Name string
+
+	// Type is the type of the field, such as int64, a message or enum name,
+	// or google.protobuf.Timestamp. It is the value type of a map field.
+	Type string
+
+	// KeyType is the key type of a map field, or empty for other fields.
+	KeyType string
+
+	// Number is the field number.
+	Number int
+
+	// Repeated is true for a repeated field.
+	Repeated bool
+
+	// Optional is true for a field with explicit presence.
+	Optional bool
+}
+
+// ProtoEnum describes an enum.
+type ProtoEnum struct {
+	// Name is the name of the enum.
+	Name string
+
+	// Values holds the values of the enum in the order they are declared.
+	Values []*ProtoEnumValue
+}
+
+// ProtoEnumValue describes a value of an enum.
+type ProtoEnumValue struct {
+	// Name is the name of the value, in upper snake case.
+	Name string
+
+	// Number is the value's number.
+	Number int
+}
+
+// Well known types used for Go types with no scalar equivalent.
+const (
+	protoTimestamp = "google.protobuf.Timestamp"
+	protoDuration  = "google.protobuf.Duration"
+)
+
+// protoImports maps well known types to the files declaring them.
+var protoImports = map[string]string{
+	protoTimestamp: "google/protobuf/timestamp.proto",
+	protoDuration:  "google/protobuf/duration.proto",
+}
+
+// ProtoFile returns a description of the named struct types as messages of
+// a .proto file with the given package name. Struct types and integer
+// enumerations, as found by Enums, that the types refer to are described as
+// well.
+//
+// Each exported field becomes a message field named by the field name in
+// snake case and numbered in declaration order, except that fields tagged
+// proto:"-" are skipped and the fields of embedded structs are promoted into
+// the message. Integer, floating point, boolean and string types map to the
+// scalar type of the same size, []byte to bytes, time.Time and
+// time.Duration to the well known Timestamp and Duration types, slices to
+// repeated fields, maps to map fields and pointers to optional fields. Go's
+// int and uint map to int64 and uint64. Enumerations that lack a zero value
+// are given one named UNSPECIFIED, prefixed by the enum name, as proto3
+// requires.
+func (fs *FileSet) ProtoFile(pkg string, typeNames ...string) (*ProtoFile, error) {
+	pc := &protoConverter{
+		fs:      fs,
+		file:    &ProtoFile{Package: pkg},
+		enums:   make(map[*types.TypeName]*EnumModel),
+		queued:  make(map[*types.TypeName]bool),
+		imports: make(map[string]bool),
+	}
+	if path, err := fs.importPath(); err == nil {
+		pc.file.GoPackage = path
+	}
+	for _, em := range fs.Enums() {
+		pc.enums[em.Type.Object] = em
+	}
+
+	for _, name := range typeNames {
+		tm, ok := fs.Type(name)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+		if _, ok := tm.Object.Type().Underlying().(*types.Struct); !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		pc.enqueue(tm.Object)
+	}
+	for len(pc.queue) > 0 {
+		tn := pc.queue[0]
+		pc.queue = pc.queue[1:]
+		if err := pc.define(tn); err != nil {
+			return nil, err
+		}
+	}
+
+	for imp := range pc.imports {
+		pc.file.Imports = append(pc.file.Imports, imp)
+	}
+	sort.Strings(pc.file.Imports)
+	return pc.file, nil
+}
+
+// protoConverter converts the types of a FileSet to messages and enums.
+type protoConverter struct {
+	fs      *FileSet
+	file    *ProtoFile
+	enums   map[*types.TypeName]*EnumModel
+	queue   []*types.TypeName // types referred to that have yet to be defined
+	queued  map[*types.TypeName]bool
+	imports map[string]bool
+}
+
+// enqueue records that the message or enum for tn is to be defined.
+func (pc *protoConverter) enqueue(tn *types.TypeName) {
+	if !pc.queued[tn] {
+		pc.queued[tn] = true
+		pc.queue = append(pc.queue, tn)
+	}
+}
+
+// define adds the message or enum for tn to the file.
+func (pc *protoConverter) define(tn *types.TypeName) error {
+	if em, ok := pc.enums[tn]; ok {
+		pc.file.Enums = append(pc.file.Enums, protoEnum(em))
+		return nil
+	}
+
+	fields, err := pc.fs.FieldsOf(tn.Name(), FlattenEmbedded(true))
+	if err != nil {
+		return err
+	}
+	msg := &ProtoMessage{Name: tn.Name()}
+	for _, f := range fields {
+		if tag, ok := f.Tags.Get("proto"); ok && tag.Name == "-" {
+			continue
+		}
+		if !f.Exported || f.Embedded && isStructOrPointer(f.Type) {
+			continue
+		}
+		pf := &ProtoField{Name: SnakeCase(f.Name), Number: len(msg.Fields) + 1}
+		if err := pc.fieldType(pf, f.Type); err != nil {
+			return fmt.Errorf("%s.%s: %w", tn.Name(), f.Name, err)
+		}
+		msg.Fields = append(msg.Fields, pf)
+	}
+	pc.file.Messages = append(pc.file.Messages, msg)
+	return nil
+}
+
+// isStructOrPointer reports whether t is a struct or a pointer to one.
+func isStructOrPointer(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+// fieldType sets the type of pf to describe the Go type t.
+func (pc *protoConverter) fieldType(pf *ProtoField, t types.Type) error {
+	var err error
+	switch u := t.(type) {
+	case *types.Pointer:
+		pf.Optional = true
+		pf.Type, err = pc.scalar(u.Elem())
+		return err
+	case *types.Slice:
+		if isBytes(u) {
+			break
+		}
+		pf.Repeated = true
+		elem := u.Elem()
+		if p, ok := elem.(*types.Pointer); ok {
+			elem = p.Elem()
+		}
+		pf.Type, err = pc.scalar(elem)
+		return err
+	case *types.Map:
+		if pf.KeyType, err = pc.scalar(u.Key()); err != nil {
+			return err
+		}
+		switch pf.KeyType {
+		case "string", "bool", "int32", "int64", "uint32", "uint64":
+		default:
+			return fmt.Errorf("map key type %s not allowed", u.Key())
+		}
+		elem := u.Elem()
+		if p, ok := elem.(*types.Pointer); ok {
+			elem = p.Elem()
+		}
+		pf.Type, err = pc.scalar(elem)
+		return err
+	}
+	pf.Type, err = pc.scalar(t)
+	return err
+}
+
+// scalar returns the proto type of a value of the Go type t, which may not
+// be a slice or map other than []byte.
+func (pc *protoConverter) scalar(t types.Type) (string, error) {
+	if n, ok := t.(*types.Named); ok {
+		obj := n.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" {
+			switch obj.Name() {
+			case "Time":
+				pc.imports[protoImports[protoTimestamp]] = true
+				return protoTimestamp, nil
+			case "Duration":
+				pc.imports[protoImports[protoDuration]] = true
+				return protoDuration, nil
+			}
+		}
+		if obj.Pkg() == pc.fs.Package {
+			if _, ok := pc.enums[obj]; ok && isInteger(n) {
+				pc.enqueue(obj)
+				return obj.Name(), nil
+			}
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				pc.enqueue(obj)
+				return obj.Name(), nil
+			}
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Bool:
+			return "bool", nil
+		case types.Int8, types.Int16, types.Int32:
+			return "int32", nil
+		case types.Int, types.Int64:
+			return "int64", nil
+		case types.Uint8, types.Uint16, types.Uint32:
+			return "uint32", nil
+		case types.Uint, types.Uint64:
+			return "uint64", nil
+		case types.Float32:
+			return "float", nil
+		case types.Float64:
+			return "double", nil
+		case types.String:
+			return "string", nil
+		}
+	case *types.Slice:
+		if isBytes(u) {
+			return "bytes", nil
+		}
+	}
+	return "", fmt.Errorf("no proto type for %s", t)
+}
+
+// isBytes reports whether s is a slice of bytes.
+func isBytes(s *types.Slice) bool {
+	b, ok := s.Elem().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+// isInteger reports whether t has an integer underlying type.
+func isInteger(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsInteger != 0
+}
+
+// protoEnum returns the enum describing em.
+func protoEnum(em *EnumModel) *ProtoEnum {
+	e := &ProtoEnum{Name: em.Name}
+	hasZero := false
+	for _, v := range em.Values {
+		n, _ := constant.Int64Val(v.Value)
+		hasZero = hasZero || n == 0
+		e.Values = append(e.Values, &ProtoEnumValue{Name: strings.ToUpper(SnakeCase(v.Name)), Number: int(n)})
+	}
+	if !hasZero {
+		zero := &ProtoEnumValue{Name: strings.ToUpper(SnakeCase(em.Name)) + "_UNSPECIFIED"}
+		e.Values = append([]*ProtoEnumValue{zero}, e.Values...)
+	}
+	return e
+}
+
+// String returns the file in the .proto language.
+func (f *ProtoFile) String() string {
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n")
+	if f.Package != "" {
+		fmt.Fprintf(&b, "\npackage %s;\n", f.Package)
+	}
+	if len(f.Imports) > 0 {
+		b.WriteString("\n")
+		for _, imp := range f.Imports {
+			fmt.Fprintf(&b, "import %s;\n", strconv.Quote(imp))
+		}
+	}
+	if f.GoPackage != "" {
+		fmt.Fprintf(&b, "\noption go_package = %s;\n", strconv.Quote(f.GoPackage))
+	}
+	for _, m := range f.Messages {
+		fmt.Fprintf(&b, "\nmessage %s {\n", m.Name)
+		for _, fd := range m.Fields {
+			b.WriteString("  ")
+			switch {
+			case fd.KeyType != "":
+				fmt.Fprintf(&b, "map<%s, %s>", fd.KeyType, fd.Type)
+			case fd.Repeated:
+				b.WriteString("repeated " + fd.Type)
+			case fd.Optional:
+				b.WriteString("optional " + fd.Type)
+			default:
+				b.WriteString(fd.Type)
+			}
+			fmt.Fprintf(&b, " %s = %d;\n", fd.Name, fd.Number)
+		}
+		b.WriteString("}\n")
+	}
+	for _, e := range f.Enums {
+		fmt.Fprintf(&b, "\nenum %s {\n", e.Name)
+		for _, v := range e.Values {
+			fmt.Fprintf(&b, "  %s = %d;\n", v.Name, v.Number)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// ParseProto parses a .proto file in proto3 syntax, as written by
+// ProtoFile.String. Options other than go_package, reserved statements and
+// field options are ignored. It is an error for the file to contain nested
+// declarations, oneofs, services or extensions.
+func ParseProto(src []byte) (*ProtoFile, error) {
+	p := &protoParser{}
+	p.s.Init(bytes.NewReader(src))
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanStrings | scanner.ScanComments | scanner.SkipComments
+	p.s.IsIdentRune = func(ch rune, i int) bool {
+		return ch == '_' || ch == '.' && i > 0 || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' && i > 0
+	}
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		p.fail(msg)
+	}
+	p.next()
+
+	f := &ProtoFile{}
+	for p.err == nil && p.tok != scanner.EOF {
+		switch kw := p.ident(); kw {
+		case "syntax":
+			p.expect("=")
+			if syntax := p.str(); syntax != "proto3" && p.err == nil {
+				p.fail(fmt.Sprintf("syntax %q not supported", syntax))
+			}
+			p.expect(";")
+		case "package":
+			f.Package = p.ident()
+			p.expect(";")
+		case "import":
+			if p.lit == "public" || p.lit == "weak" {
+				p.next()
+			}
+			f.Imports = append(f.Imports, p.str())
+			p.expect(";")
+		case "option":
+			name := p.ident()
+			p.expect("=")
+			if name == "go_package" {
+				f.GoPackage = p.str()
+			} else {
+				p.next()
+			}
+			p.expect(";")
+		case "message":
+			if m := p.message(); m != nil {
+				f.Messages = append(f.Messages, m)
+			}
+		case "enum":
+			if e := p.enum(); e != nil {
+				f.Enums = append(f.Enums, e)
+			}
+		default:
+			if p.err == nil {
+				p.fail(fmt.Sprintf("%s not supported", kw))
+			}
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return f, nil
+}
+
+// protoParser reads the tokens of a .proto file.
+type protoParser struct {
+	s   scanner.Scanner
+	tok rune
+	lit string
+	err error
+}
+
+func (p *protoParser) next() {
+	p.tok = p.s.Scan()
+	p.lit = p.s.TokenText()
+}
+
+// fail records the first error found.
+func (p *protoParser) fail(msg string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %s", p.s.Position, msg)
+	}
+	p.tok = scanner.EOF
+}
+
+func (p *protoParser) expect(lit string) {
+	if p.lit != lit {
+		p.fail(fmt.Sprintf("got %q, wanted %q", p.lit, lit))
+		return
+	}
+	p.next()
+}
+
+func (p *protoParser) ident() string {
+	if p.tok != scanner.Ident {
+		p.fail(fmt.Sprintf("got %q, wanted identifier", p.lit))
+		return ""
+	}
+	lit := p.lit
+	p.next()
+	return lit
+}
+
+func (p *protoParser) str() string {
+	if p.tok != scanner.String {
+		p.fail(fmt.Sprintf("got %q, wanted string", p.lit))
+		return ""
+	}
+	s, err := strconv.Unquote(p.lit)
+	if err != nil {
+		p.fail(err.Error())
+	}
+	p.next()
+	return s
+}
+
+func (p *protoParser) number() int {
+	neg := p.lit == "-"
+	if neg {
+		p.next()
+	}
+	if p.tok != scanner.Int {
+		p.fail(fmt.Sprintf("got %q, wanted number", p.lit))
+		return 0
+	}
+	n, err := strconv.ParseInt(p.lit, 0, 32)
+	if err != nil {
+		p.fail(err.Error())
+	}
+	p.next()
+	if neg {
+		n = -n
+	}
+	return int(n)
+}
+
+// skipStatement skips to the end of the current statement.
+func (p *protoParser) skipStatement() {
+	for p.tok != scanner.EOF && p.lit != ";" {
+		p.next()
+	}
+	p.next()
+}
+
+// skipOptions skips the options in brackets that may follow a field or
+// enum value.
+func (p *protoParser) skipOptions() {
+	if p.lit != "[" {
+		return
+	}
+	for p.tok != scanner.EOF && p.lit != "]" {
+		p.next()
+	}
+	p.expect("]")
+}
+
+func (p *protoParser) message() *ProtoMessage {
+	m := &ProtoMessage{Name: p.ident()}
+	p.expect("{")
+	for p.err == nil && p.lit != "}" {
+		fd := &ProtoField{}
+		switch p.lit {
+		case "option", "reserved":
+			p.skipStatement()
+			continue
+		case "message", "enum", "oneof", "extensions", "extend", "group":
+			p.fail(fmt.Sprintf("%s in message %s not supported", p.lit, m.Name))
+			continue
+		case "repeated":
+			fd.Repeated = true
+			p.next()
+		case "optional":
+			fd.Optional = true
+			p.next()
+		case "map":
+			p.next()
+			p.expect("<")
+			fd.KeyType = p.ident()
+			p.expect(",")
+		}
+		fd.Type = p.ident()
+		if fd.KeyType != "" {
+			p.expect(">")
+		}
+		fd.Name = p.ident()
+		p.expect("=")
+		fd.Number = p.number()
+		p.skipOptions()
+		p.expect(";")
+		m.Fields = append(m.Fields, fd)
+	}
+	p.expect("}")
+	return m
+}
+
+func (p *protoParser) enum() *ProtoEnum {
+	e := &ProtoEnum{Name: p.ident()}
+	p.expect("{")
+	for p.err == nil && p.lit != "}" {
+		if p.lit == "option" || p.lit == "reserved" {
+			p.skipStatement()
+			continue
+		}
+		v := &ProtoEnumValue{Name: p.ident()}
+		p.expect("=")
+		v.Number = p.number()
+		p.skipOptions()
+		p.expect(";")
+		e.Values = append(e.Values, v)
+	}
+	p.expect("}")
+	return e
+}
+
+// protoGoTypes maps scalar and well known proto types to Go types.
+var protoGoTypes = map[string]string{
+	"double": "float64", "float": "float32",
+	"int32": "int32", "sint32": "int32", "sfixed32": "int32",
+	"int64": "int64", "sint64": "int64", "sfixed64": "int64",
+	"uint32": "uint32", "fixed32": "uint32",
+	"uint64": "uint64", "fixed64": "uint64",
+	"bool": "bool", "string": "string", "bytes": "[]byte",
+	protoTimestamp: "time.Time", protoDuration: "time.Duration",
+}
+
+// GoSource returns Go declarations, in a file of the named package, for the
+// messages and enums of f: a struct type for each message and an int32 type
+// with a constant for each value for each enum. Field and constant names are
+// the proto names in Pascal case, and each field has a json tag holding its
+// proto name. Optional fields are pointers, repeated fields slices and map
+// fields maps. Converting Go types with ProtoFile and back again with
+// GoSource yields equivalent types, though sizes follow the proto types, so
+// an int becomes an int64, and embedded fields are flattened.
+func (f *ProtoFile) GoSource(pkgName string) ([]byte, error) {
+	messages := make(map[string]bool)
+	for _, m := range f.Messages {
+		messages[m.Name] = true
+	}
+	enums := make(map[string]bool)
+	for _, e := range f.Enums {
+		enums[e.Name] = true
+	}
+	usesTime := false
+	goType := func(t string) (string, error) {
+		if messages[t] || enums[t] {
+			return t, nil
+		}
+		if gt, ok := protoGoTypes[t]; ok {
+			usesTime = usesTime || strings.HasPrefix(gt, "time.")
+			return gt, nil
+		}
+		return "", fmt.Errorf("unknown proto type %s", t)
+	}
+
+	var body bytes.Buffer
+	for _, m := range f.Messages {
+		fmt.Fprintf(&body, "\ntype %s struct {\n", m.Name)
+		for _, fd := range m.Fields {
+			typ, err := goType(fd.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", m.Name, fd.Name, err)
+			}
+			switch {
+			case fd.KeyType != "":
+				key, err := goType(fd.KeyType)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", m.Name, fd.Name, err)
+				}
+				typ = "map[" + key + "]" + typ
+			case fd.Repeated:
+				typ = "[]" + typ
+			case fd.Optional:
+				typ = "*" + typ
+			}
+			fmt.Fprintf(&body, "\t%s %s `json:%q`\n", PascalCase(fd.Name), typ, fd.Name)
+		}
+		body.WriteString("}\n")
+	}
+	for _, e := range f.Enums {
+		fmt.Fprintf(&body, "\ntype %s int32\n\nconst (\n", e.Name)
+		for _, v := range e.Values {
+			fmt.Fprintf(&body, "\t%s %s = %d\n", PascalCase(strings.ToLower(v.Name)), e.Name, v.Number)
+		}
+		body.WriteString(")\n")
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "package %s\n", pkgName)
+	if usesTime {
+		src.WriteString("\nimport \"time\"\n")
+	}
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
@@ -0,0 +1,159 @@
+package gen
+
+import (
+	"testing"
+)
+
+const protoSrc = `package p
+
+import "time"
+
+type Status int
+
+const (
+	Pending Status = iota + 1
+	Shipped
+)
+
+type Base struct {
+	ID int64
+}
+
+type Order struct {
+	Base
+	Items    []*Item
+	Status   Status
+	Note     *string
+	Labels   map[string]int32
+	PlacedAt time.Time
+	Payload  []byte
+	Skipped  string ` + "`proto:\"-\"`" + `
+	internal int
+}
+
+type Item struct {
+	SKU string
+	Qty uint
+}
+`
+
+const protoWant = `syntax = "proto3";
+
+package acme.orders;
+
+import "google/protobuf/timestamp.proto";
+
+message Order {
+  int64 id = 1;
+  repeated Item items = 2;
+  Status status = 3;
+  optional string note = 4;
+  map<string, int32> labels = 5;
+  google.protobuf.Timestamp placed_at = 6;
+  bytes payload = 7;
+}
+
+message Item {
+  string sku = 1;
+  uint64 qty = 2;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  PENDING = 1;
+  SHIPPED = 2;
+}
+`
+
+func TestProtoFile(t *testing.T) {
+	fs, err := NewFileSetFromTexts(protoSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pf, err := fs.ProtoFile("acme.orders", "Order")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := pf.String(); got != protoWant {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, protoWant)
+	}
+
+	if _, err := fs.ProtoFile("p", "Status"); err == nil {
+		t.Errorf("got no error for a type that is not a struct")
+	}
+	bad, err := NewFileSetFromTexts("package p\n\ntype T struct{ C chan int }\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := bad.ProtoFile("p", "T"); err == nil {
+		t.Errorf("got no error for an unsupported field type")
+	}
+}
+
+func TestParseProto(t *testing.T) {
+	pf, err := ParseProto([]byte(protoWant))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := pf.String(); got != protoWant {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, protoWant)
+	}
+
+	got, err := pf.GoSource("p")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "package p\n\nimport \"time\"\n\n" +
+		"type Order struct {\n" +
+		"\tID       int64            `json:\"id\"`\n" +
+		"\tItems    []Item           `json:\"items\"`\n" +
+		"\tStatus   Status           `json:\"status\"`\n" +
+		"\tNote     *string          `json:\"note\"`\n" +
+		"\tLabels   map[string]int32 `json:\"labels\"`\n" +
+		"\tPlacedAt time.Time        `json:\"placed_at\"`\n" +
+		"\tPayload  []byte           `json:\"payload\"`\n" +
+		"}\n\n" +
+		"type Item struct {\n" +
+		"\tSku string `json:\"sku\"`\n" +
+		"\tQty uint64 `json:\"qty\"`\n" +
+		"}\n\n" +
+		"type Status int32\n\n" +
+		"const (\n" +
+		"\tStatusUnspecified Status = 0\n" +
+		"\tPending           Status = 1\n" +
+		"\tShipped           Status = 2\n" +
+		")\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	// The generated Go source loads and converts back to the same file.
+	fs, err := NewFileSetFromTexts(string(got))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	back, err := fs.ProtoFile("acme.orders", "Order")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if back.String() != protoWant {
+		t.Errorf("round trip got:\n%s\nwanted:\n%s", back, protoWant)
+	}
+
+	testCases := []struct {
+		name string
+		src  string
+	}{
+		{name: "proto2", src: `syntax = "proto2";`},
+		{name: "nested", src: "message A { message B {} }"},
+		{name: "service", src: "service S {}"},
+		{name: "missing number", src: "message A { int32 x = ; }"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseProto([]byte(tc.src)); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
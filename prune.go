@@ -0,0 +1,117 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// generatedByRx matches a generated code header naming its generator, such
+// as the header written by Output or the similar "// Code generated by
+// protoc-gen-go. DO NOT EDIT." form.
+var generatedByRx = regexp.MustCompile(`^// Code generated by (.+?)[.;]? DO NOT EDIT\.$`)
+
+// GeneratedBy returns the name of the generator recorded in the generated
+// code header of src, or the empty string if src has no such header or the
+// header does not name a generator.
+func GeneratedBy(src []byte) string {
+	if m := generatedByRx.FindSubmatch(generatedHeader(src)); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// PruneStale deletes the files in the directory dir whose base names match
+// pattern, in the syntax of filepath.Match, and whose generated code header
+// names generator, except those listed in produced. It is intended to be
+// called after a run of generator has written the files in produced, so that
+// files it generated in earlier runs but no longer generates, such as the
+// output for a type that has been deleted, do not linger. Files written by
+// hand or by other generators are never deleted. Subdirectories are not
+// searched. PruneStale returns the names of the files deleted, in sorted
+// order. It returns an error if generator is empty, since files with
+// headers that do not name their generator cannot be attributed to it.
+func PruneStale(dir, pattern, generator string, produced []string) ([]string, error) {
+	if generator == "" {
+		return nil, errors.New("prune stale files: no generator name")
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, e.Name()); !ok {
+			continue
+		}
+		filename := filepath.Join(dir, e.Name())
+		if isProduced(filename, produced) {
+			continue
+		}
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return removed, err
+		}
+		if !IsGenerated(src) || GeneratedBy(src) != generator {
+			continue
+		}
+		if err := os.Remove(filename); err != nil {
+			return removed, err
+		}
+		removed = append(removed, filename)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// isProduced reports whether filename is one of the produced files.
+func isProduced(filename string, produced []string) bool {
+	for _, p := range produced {
+		if samePath(filename, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// prunable returns a function reporting whether PruneStale would delete an
+// existing file, with the given name and contents, that is not produced by
+// a run of generator that prunes files matching pattern. It returns nil if
+// pattern or generator is empty, when nothing is pruned.
+func prunable(pattern, generator string) func(filename string, src []byte) bool {
+	if pattern == "" || generator == "" {
+		return nil
+	}
+	return func(filename string, src []byte) bool {
+		ok, _ := filepath.Match(pattern, filepath.Base(filename))
+		return ok && IsGenerated(src) && GeneratedBy(src) == generator
+	}
+}
+
+// pruneOutputs deletes the stale files generated by generator that match
+// pattern in the directories of the outputs and in dir, keeping the
+// outputs.
+func pruneOutputs(dir, pattern, generator string, outputs []string) ([]string, error) {
+	dirs := map[string]bool{filepath.Clean(dir): true}
+	for _, filename := range outputs {
+		dirs[filepath.Dir(filename)] = true
+	}
+	var removed []string
+	for _, d := range SortedKeys(dirs) {
+		r, err := PruneStale(d, pattern, generator, outputs)
+		removed = append(removed, r...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
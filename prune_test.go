@@ -0,0 +1,72 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGeneratedBy(t *testing.T) {
+	testCases := []struct {
+		src  string
+		want string
+	}{
+		{src: "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n", want: "stringer"},
+		{src: "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage p\n", want: "protoc-gen-go"},
+		{src: "// Copyright\n\n// Code generated by gen -type T; DO NOT EDIT.\n\npackage p\n", want: "gen -type T"},
+		{src: "// Code generated from x.proto. DO NOT EDIT.\n\npackage p\n", want: ""},
+		{src: "package p\n", want: ""},
+	}
+	for _, tc := range testCases {
+		if got := GeneratedBy([]byte(tc.src)); got != tc.want {
+			t.Errorf("GeneratedBy(%q) = %q, wanted %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestPruneStale(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"color_gen.go":  "// Code generated by enumgen; DO NOT EDIT.\n\npackage p\n",
+		"shape_gen.go":  "// Code generated by enumgen; DO NOT EDIT.\n\npackage p\n",
+		"size_gen.go":   "// Code generated by enumgen; DO NOT EDIT.\n\npackage p\n",
+		"other_gen.go":  "// Code generated by other; DO NOT EDIT.\n\npackage p\n",
+		"manual_gen.go": "package p\n",
+		"p.go":          "// Code generated by enumgen; DO NOT EDIT.\n\npackage p\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub_gen.go"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	removed, err := PruneStale(dir, "*_gen.go", "enumgen", []string{filepath.Join(dir, "color_gen.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{filepath.Join(dir, "shape_gen.go"), filepath.Join(dir, "size_gen.go")}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("got removed %v, wanted %v", removed, want)
+	}
+	for _, name := range []string{"color_gen.go", "other_gen.go", "manual_gen.go", "p.go", "sub_gen.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s removed: %v", name, err)
+		}
+	}
+
+	if _, err := PruneStale(dir, "[", "enumgen", nil); err == nil {
+		t.Errorf("got no error for a bad pattern, wanted one")
+	}
+
+	// A file without a generated code header is never removed, even when
+	// no generator would be named by its header.
+	if removed, err := PruneStale(dir, "*.go", "", nil); err == nil || len(removed) != 0 {
+		t.Errorf("got removed %v, %v, wanted an error for an empty generator name", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "manual_gen.go")); err != nil {
+		t.Errorf("manual_gen.go removed: %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package gen
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// purePackages lists standard library packages whose functions are assumed
+// to be free of side effects, although their methods with pointer, slice or
+// map receivers may modify the receiver. Functions of packages in neither
+// list are assumed to have side effects.
+var purePackages = map[string]bool{
+	"bytes":         true,
+	"cmp":           true,
+	"errors":        true,
+	"math":          true,
+	"math/bits":     true,
+	"math/cmplx":    true,
+	"path":          true,
+	"strconv":       true,
+	"strings":       true,
+	"unicode":       true,
+	"unicode/utf16": true,
+	"unicode/utf8":  true,
+}
+
+// mutatingPackages lists standard library packages whose functions have no
+// side effects other than modifying the contents of their arguments, such
+// as sorting a slice in place.
+var mutatingPackages = map[string]bool{
+	"maps":   true,
+	"slices": true,
+	"sort":   true,
+}
+
+// purityChecker determines whether functions in a FileSet are free of side
+// effects. A function is pure if its body has no side effects of its own
+// and every package function it calls is pure, which is resolved for all
+// the functions of the package at once so that mutually recursive functions
+// are judged together.
+type purityChecker struct {
+	fs    *FileSet
+	decls map[*types.Func]*ast.FuncDecl
+	memo  map[*types.Func]bool // nil until resolved
+}
+
+func newPurityChecker(fs *FileSet) *purityChecker {
+	pc := &purityChecker{
+		fs:    fs,
+		decls: make(map[*types.Func]*ast.FuncDecl),
+	}
+	fs.EachFunc(func(decl *ast.FuncDecl) bool {
+		if obj, ok := fs.TypeInfo.Defs[decl.Name].(*types.Func); ok {
+			pc.decls[obj] = decl
+		}
+		return true
+	})
+	return pc
+}
+
+// isPure reports whether fn appears to be free of side effects.
+func (pc *purityChecker) isPure(fn *types.Func) bool {
+	if pc.memo == nil {
+		pc.resolve()
+	}
+	return pc.memo[fn]
+}
+
+// resolve determines the purity of every function of the package. Functions
+// whose own bodies are pure are first assumed to be pure, then any that call
+// an impure function are marked impure until no more change, so a cycle of
+// calls is pure only if every function in it is.
+func (pc *purityChecker) resolve() {
+	pc.memo = make(map[*types.Func]bool, len(pc.decls))
+	calls := make(map[*types.Func][]*types.Func, len(pc.decls))
+	for fn, decl := range pc.decls {
+		if decl.Body == nil {
+			continue
+		}
+		pc.memo[fn], calls[fn] = pc.checkBody(decl)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for fn, callees := range calls {
+			if !pc.memo[fn] {
+				continue
+			}
+			for _, callee := range callees {
+				if !pc.memo[callee] {
+					pc.memo[fn] = false
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// checkBody reports whether the body of decl is free of side effects of its
+// own, and returns the package functions it calls, on whose purity its own
+// depends.
+func (pc *purityChecker) checkBody(decl *ast.FuncDecl) (bool, []*types.Func) {
+	pure := true
+	var calls []*types.Func
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if !pure {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.GoStmt, *ast.SendStmt, *ast.SelectStmt:
+			pure = false
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				pure = false
+			}
+		case *ast.RangeStmt:
+			if tv, ok := pc.fs.TypeInfo.Types[n.X]; ok {
+				if _, isChan := tv.Type.Underlying().(*types.Chan); isChan {
+					pure = false
+				}
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if !pc.isLocalTarget(decl, lhs) {
+					pure = false
+				}
+			}
+		case *ast.IncDecStmt:
+			if !pc.isLocalTarget(decl, n.X) {
+				pure = false
+			}
+		case *ast.CallExpr:
+			ok, callee := pc.isPureCall(decl, n)
+			if !ok {
+				pure = false
+			} else if callee != nil {
+				calls = append(calls, callee)
+			}
+		}
+		return pure
+	})
+	return pure, calls
+}
+
+// isLocalTarget reports whether assigning to expr only affects state local to
+// the function declared by decl.
+func (pc *purityChecker) isLocalTarget(decl *ast.FuncDecl, expr ast.Expr) bool {
+	return pc.isLocalWrite(decl, expr, true)
+}
+
+// isLocalWrite reports whether writing to expr, or to the value it refers to
+// when direct is false, only affects state local to the function declared by
+// decl.
+func (pc *purityChecker) isLocalWrite(decl *ast.FuncDecl, expr ast.Expr, direct bool) bool {
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+			continue
+		case *ast.SelectorExpr:
+			if tv, ok := pc.fs.TypeInfo.Types[e.X]; ok {
+				if _, isPtr := tv.Type.Underlying().(*types.Pointer); isPtr {
+					direct = false
+				}
+			}
+			expr = e.X
+			continue
+		case *ast.IndexExpr:
+			if tv, ok := pc.fs.TypeInfo.Types[e.X]; ok {
+				if _, isArray := tv.Type.Underlying().(*types.Array); !isArray {
+					direct = false
+				}
+			}
+			expr = e.X
+			continue
+		case *ast.StarExpr:
+			direct = false
+			expr = e.X
+			continue
+		case *ast.Ident:
+			if e.Name == "_" {
+				return true
+			}
+			obj := pc.fs.TypeInfo.ObjectOf(e)
+			v, ok := obj.(*types.Var)
+			if !ok {
+				return false
+			}
+			if v.Parent() == pc.fs.Package.Scope() {
+				return false
+			}
+			// Writing through a pointer, slice or map held by a local
+			// variable may modify state shared with the caller if the
+			// variable is a parameter or receiver.
+			if !direct && pc.isParam(decl, v) {
+				return false
+			}
+			return pc.declaredWithin(decl, v)
+		default:
+			return false
+		}
+	}
+}
+
+// isParam reports whether v is a parameter, result or receiver of decl.
+func (pc *purityChecker) isParam(decl *ast.FuncDecl, v *types.Var) bool {
+	return v.Pos() >= decl.Type.Pos() && v.Pos() < decl.Type.End() ||
+		decl.Recv != nil && v.Pos() >= decl.Recv.Pos() && v.Pos() < decl.Recv.End()
+}
+
+// declaredWithin reports whether v is declared inside decl, including its
+// parameters and receiver.
+func (pc *purityChecker) declaredWithin(decl *ast.FuncDecl, v *types.Var) bool {
+	return v.Pos() >= decl.Pos() && v.Pos() < decl.End()
+}
+
+// isPureCall reports whether the call is believed to be free of side
+// effects. A call of a package function is pure only if that function is,
+// so it is returned to be resolved later.
+func (pc *purityChecker) isPureCall(decl *ast.FuncDecl, call *ast.CallExpr) (bool, *types.Func) {
+	fun := ast.Unparen(call.Fun)
+
+	// Conversions are pure
+	if tv, ok := pc.fs.TypeInfo.Types[fun]; ok && tv.IsType() {
+		return true, nil
+	}
+
+	var obj types.Object
+	var recv ast.Expr
+	switch f := fun.(type) {
+	case *ast.Ident:
+		obj = pc.fs.TypeInfo.Uses[f]
+	case *ast.SelectorExpr:
+		obj = pc.fs.TypeInfo.Uses[f.Sel]
+		if sel, ok := pc.fs.TypeInfo.Selections[f]; ok {
+			switch sel.Kind() {
+			case types.MethodVal:
+				recv = f.X
+			case types.MethodExpr:
+				if len(call.Args) > 0 {
+					recv = call.Args[0]
+				}
+			}
+		}
+	case *ast.IndexExpr:
+		// Explicit instantiation of a generic function
+		if id, ok := f.X.(*ast.Ident); ok {
+			obj = pc.fs.TypeInfo.Uses[id]
+		}
+	case *ast.FuncLit:
+		return true, nil // the body is inspected as part of the enclosing function
+	}
+
+	switch obj := obj.(type) {
+	case *types.Builtin:
+		switch obj.Name() {
+		case "print", "println", "close", "recover":
+			return false, nil
+		case "delete", "clear", "copy":
+			// These modify the contents of their first argument
+			return len(call.Args) > 0 && pc.isLocalWrite(decl, call.Args[0], false), nil
+		}
+		return true, nil
+	case *types.Func:
+		if obj.Pkg() == nil {
+			return true, nil // method of the error interface
+		}
+		sig, _ := obj.Type().(*types.Signature)
+		if sig != nil && sig.Recv() != nil && types.IsInterface(sig.Recv().Type()) {
+			return false, nil // dynamic call
+		}
+		if obj.Pkg() != pc.fs.Package {
+			return pc.isPureExternalCall(decl, obj, recv, call.Args), nil
+		}
+		return true, obj.Origin()
+	}
+
+	// Calls of function values cannot be resolved statically
+	return false, nil
+}
+
+// isPureExternalCall reports whether a call of fn, a function from another
+// package, with the given receiver and arguments is assumed to be free of
+// side effects. A method with a pointer, slice or map receiver may modify
+// its receiver,
+// so the call is pure only if the receiver is local to the function declared
+// by decl, as is a call of a function of mutatingPackages with each argument
+// it could modify.
+func (pc *purityChecker) isPureExternalCall(decl *ast.FuncDecl, fn *types.Func, recv ast.Expr, args []ast.Expr) bool {
+	path := fn.Pkg().Path()
+	switch {
+	case path == "fmt":
+		// Only the functions that format to a string or byte slice are pure
+		return strings.HasPrefix(fn.Name(), "Sprint") || strings.HasPrefix(fn.Name(), "Append") || fn.Name() == "Errorf"
+	case mutatingPackages[path]:
+		for _, arg := range args {
+			if tv, ok := pc.fs.TypeInfo.Types[arg]; ok && isReference(tv.Type) && !pc.isLocalWrite(decl, arg, false) {
+				return false
+			}
+		}
+	case !purePackages[path]:
+		return false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil || !isReference(sig.Recv().Type()) {
+		return true
+	}
+	return recv != nil && pc.isLocalWrite(decl, recv, false)
+}
+
+// isReference reports whether values of type t refer to data that a function
+// they are passed to could modify.
+func isReference(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Interface, *types.Signature:
+		return true
+	}
+	return false
+}
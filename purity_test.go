@@ -0,0 +1,184 @@
+package gen
+
+import (
+	"testing"
+)
+
+func TestFuncPurity(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import (
+			"fmt"
+			"os"
+			"strings"
+		)
+
+		var counter int
+
+		type T struct{ n int; items []int }
+
+		func Add(a, b int) int { return a + b }
+
+		func Upper(s string) string { return strings.ToUpper(s) }
+
+		func Format(n int) string { return fmt.Sprintf("%d", n) }
+
+		func Print(n int) { fmt.Println(n) }
+
+		func Env() string { return os.Getenv("HOME") }
+
+		func Incr() int { counter++; return counter }
+
+		func CallsIncr() int { return Incr() + 1 }
+
+		func CallsAdd() int { return Add(1, 2) }
+
+		func Fact(n int) int {
+			if n <= 1 {
+				return 1
+			}
+			return n * Fact(n-1)
+		}
+
+		func Local() []int {
+			xs := make([]int, 3)
+			xs[0] = 1
+			t := &T{}
+			t.n = 2
+			return xs
+		}
+
+		func (t T) Value() int { t.n = 3; return t.n }
+
+		func (t *T) Set(n int) { t.n = n }
+
+		func Mutate(xs []int) { xs[0] = 1 }
+
+		func Send(c chan int) { c <- 1 }
+
+		func Spawn() { go Add(1, 2) }
+
+		func Dynamic(f func() int) int { return f() }
+
+		func Stringify(s fmt.Stringer) string { return s.String() }
+
+		func Delete(m map[string]int) { delete(m, "x") }
+
+		func DeleteLocal() { m := map[string]int{}; delete(m, "x") }
+
+		func Closure() int {
+			f := func() int { return 1 }
+			return f()
+		}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]bool{
+		"Add":         true,
+		"Upper":       true,
+		"Format":      true,
+		"Print":       false,
+		"Env":         false,
+		"Incr":        false,
+		"CallsIncr":   false,
+		"CallsAdd":    true,
+		"Fact":        true,
+		"Local":       true,
+		"T.Value":     true,
+		"T.Set":       false,
+		"Mutate":      false,
+		"Send":        false,
+		"Spawn":       false,
+		"Dynamic":     false,
+		"Stringify":   false,
+		"Delete":      false,
+		"DeleteLocal": true,
+		"Closure":     false,
+	}
+
+	for _, m := range fs.Funcs() {
+		w, ok := want[m.FullName()]
+		if !ok {
+			t.Errorf("unexpected function %s", m.FullName())
+			continue
+		}
+		if m.Pure != w {
+			t.Errorf("%s: got pure=%v, wanted %v", m.FullName(), m.Pure, w)
+		}
+	}
+}
+
+func TestFuncPurityCalls(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import (
+			"bytes"
+			"regexp"
+			"slices"
+			"sort"
+			"strings"
+		)
+
+		var g int
+
+		var buf bytes.Buffer
+
+		func A() { B(); g = 1 }
+
+		func B() { A() }
+
+		func Even(n int) bool { if n == 0 { return true }; return Odd(n - 1) }
+
+		func Odd(n int) bool { if n == 0 { return false }; return Even(n - 1) }
+
+		func WriteParam(b *bytes.Buffer) { b.WriteString("x") }
+
+		func WriteGlobal() { buf.WriteString("x") }
+
+		func WriteLocal() string {
+			var sb strings.Builder
+			sb.WriteString("x")
+			return sb.String()
+		}
+
+		func SortParam(xs []int) { sort.Ints(xs) }
+
+		func SortLocal() []int {
+			xs := []int{3, 1, 2}
+			slices.Sort(xs)
+			return xs
+		}
+
+		func Contains(xs []int) bool { return slices.Contains(xs, 1) }
+
+		func Unknown(s string) bool { return regexp.MustCompile(s).MatchString("x") }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]bool{
+		"A":           false,
+		"B":           false,
+		"Even":        true,
+		"Odd":         true,
+		"WriteParam":  false,
+		"WriteGlobal": false,
+		"WriteLocal":  true,
+		"SortParam":   false,
+		"SortLocal":   true,
+		"Contains":    false,
+		"Unknown":     false,
+	}
+
+	for _, m := range fs.Funcs() {
+		if m.Pure != want[m.FullName()] {
+			t.Errorf("%s: got pure=%v, wanted %v", m.FullName(), m.Pure, want[m.FullName()])
+		}
+	}
+	if m, ok := fs.Func("B"); !ok || m.Pure {
+		t.Errorf("got pure B from Func, wanted impure")
+	}
+}
@@ -0,0 +1,397 @@
+// Package random generates functions that construct random values of the
+// types declared in a package, for use in property based tests, fuzzing and
+// benchmarks.
+//
+// For each type T the generated NewRandomT(r *rand.Rand) T function returns a
+// value with every field populated. Values honor the min, max, gte, lte, len
+// and oneof constraints of validate struct tags in the style of
+// github.com/go-playground/validator: for numbers they bound the value and
+// for strings, slices and maps they bound the length. A named type with a set
+// of declared constants, such as an enumeration, takes one of those constants.
+// Pointers, slices and maps are left empty beyond a fixed nesting depth so
+// that recursive types produce finite values. Fields whose types cannot be
+// constructed, such as channels, functions, interfaces and types from other
+// packages, are left with their zero value.
+package random
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iand/gen"
+)
+
+// MaxDepth is the depth of nested values beyond which pointers, slices and
+// maps are left empty.
+const MaxDepth = 3
+
+// constraints holds the limits placed on a field's random value by its
+// validate struct tag.
+type constraints struct {
+	min, max string
+	oneof    []string
+}
+
+// constrained reports whether any constraints are present.
+func (c constraints) constrained() bool {
+	return c.min != "" || c.max != "" || len(c.oneof) > 0
+}
+
+// parseConstraints reads constraints from a validate struct tag.
+func parseConstraints(tags gen.Tags) constraints {
+	var c constraints
+	tag, ok := tags.Get("validate")
+	if !ok {
+		return c
+	}
+	for _, part := range strings.Split(tag.Value, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "min", "gte":
+			c.min = value
+		case "max", "lte":
+			c.max = value
+		case "len":
+			c.min, c.max = value, value
+		case "oneof":
+			c.oneof = strings.Fields(value)
+		}
+	}
+	return c
+}
+
+// Options configures a Generator.
+type Options struct {
+	// Prefix is prepended to the names of the unexported helper functions
+	// that construct values. The default is "newRandom". Generators that
+	// emit several sets of helpers into one package must use distinct
+	// prefixes.
+	Prefix string
+
+	// Include reports whether a struct field should be given a random
+	// value. If nil, all fields are populated.
+	Include func(f *types.Var, tags gen.Tags) bool
+}
+
+// Generator writes functions that construct random values of the named types
+// of a package. Helpers are written on demand as types are requested and
+// may be retrieved with WriteTo once all types have been requested.
+type Generator struct {
+	fs      *gen.FileSet
+	imports *gen.Imports
+	qual    types.Qualifier
+	opts    Options
+
+	helpers    bytes.Buffer
+	emitted    map[*types.TypeName]bool
+	enums      map[*types.TypeName][]string
+	needString bool
+}
+
+// NewGenerator creates a Generator for the types declared in fs. Packages
+// referred to by the generated code are added to imports.
+func NewGenerator(fs *gen.FileSet, imports *gen.Imports, opts Options) *Generator {
+	if opts.Prefix == "" {
+		opts.Prefix = "newRandom"
+	}
+	g := &Generator{
+		fs:      fs,
+		imports: imports,
+		qual:    imports.Qualifier(fs.Package),
+		opts:    opts,
+		emitted: make(map[*types.TypeName]bool),
+		enums:   make(map[*types.TypeName][]string),
+	}
+	g.findEnums()
+	return g
+}
+
+// findEnums records the constants declared for each named type in the package.
+func (g *Generator) findEnums() {
+	scope := g.fs.Package.Scope()
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if !ok || name == "_" {
+			continue
+		}
+		if n, ok := c.Type().(*types.Named); ok && n.Obj().Pkg() == g.fs.Package {
+			g.enums[n.Obj()] = append(g.enums[n.Obj()], name)
+		}
+	}
+	for _, names := range g.enums {
+		sort.Slice(names, func(i, j int) bool {
+			return scope.Lookup(names[i]).Pos() < scope.Lookup(names[j]).Pos()
+		})
+	}
+}
+
+// Supported reports whether a helper can be written for the named type n.
+// Generic types and types from other packages are not supported.
+func (g *Generator) Supported(n *types.Named) bool {
+	return n.Obj().Pkg() == g.fs.Package && n.TypeParams().Len() == 0 && n.TypeArgs().Len() == 0
+}
+
+// Helper ensures that the helper function constructing values of the named
+// type n has been written and returns its name. The helper has the signature
+// func(r *rand.Rand, depth int) T and should be called with a depth of zero.
+func (g *Generator) Helper(n *types.Named) string {
+	name := g.opts.Prefix + n.Obj().Name()
+	if g.emitted[n.Obj()] {
+		return name
+	}
+	g.emitted[n.Obj()] = true
+
+	typ := types.TypeString(n, g.qual)
+	var body bytes.Buffer
+	if consts := g.enums[n.Obj()]; len(consts) > 0 {
+		fmt.Fprintf(&body, "\tv = []%s{%s}[r.Intn(%d)]\n", typ, strings.Join(consts, ", "), len(consts))
+	} else if st, ok := n.Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			tags, err := gen.ParseTags(st.Tag(i))
+			if err != nil {
+				tags = gen.Tags{}
+			}
+			if f.Name() == "_" || (g.opts.Include != nil && !g.opts.Include(f, tags)) {
+				continue
+			}
+			if x := g.expr(f.Type(), parseConstraints(tags)); x != "" {
+				fmt.Fprintf(&body, "\tv.%s = %s\n", f.Name(), x)
+			}
+		}
+	} else if x := g.expr(n.Underlying(), constraints{}); x != "" {
+		fmt.Fprintf(&body, "\tv = %s(%s)\n", typ, x)
+	}
+
+	fmt.Fprintf(&g.helpers, "\n// %s returns a random value of type %s.\n", name, n.Obj().Name())
+	fmt.Fprintf(&g.helpers, "func %s(r *%s.Rand, depth int) %s {\n", name, g.rand(), typ)
+	fmt.Fprintf(&g.helpers, "\tvar v %s\n", typ)
+	fmt.Fprintf(&g.helpers, "\tif depth > %d {\n\t\treturn v\n\t}\n", MaxDepth)
+	g.helpers.Write(body.Bytes())
+	g.helpers.WriteString("\treturn v\n}\n")
+	return name
+}
+
+// rand returns the name used to refer to the math/rand package.
+func (g *Generator) rand() string {
+	return g.imports.Add("math/rand", "")
+}
+
+// stringHelperName returns the name of the function that constructs random
+// strings.
+func (g *Generator) stringHelperName() string {
+	return g.opts.Prefix + "_string"
+}
+
+// expr returns an expression that evaluates to a random value of type t, or
+// an empty string if no random value can be constructed. The expression may
+// refer to the variables r and depth.
+func (g *Generator) expr(t types.Type, c constraints) string {
+	// The type is only rendered when used so that packages are only
+	// imported if the expression refers to them.
+	typ := func() string { return types.TypeString(t, g.qual) }
+
+	switch t := types.Unalias(t).(type) {
+	case *types.Named:
+		if b, ok := t.Underlying().(*types.Basic); ok && c.constrained() {
+			if x := g.expr(b, c); x != "" {
+				return typ() + "(" + x + ")"
+			}
+		}
+		if !g.Supported(t) {
+			return ""
+		}
+		return g.Helper(t) + "(r, depth+1)"
+
+	case *types.Basic:
+		if len(c.oneof) > 0 {
+			return g.oneof(t, c.oneof)
+		}
+		info := t.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			return "r.Intn(2) == 1"
+		case info&types.IsString != 0:
+			g.needString = true
+			return fmt.Sprintf("%s(%s(r, %s, %s))", typ(), g.stringHelperName(), orDefault(c.min, "0"), orDefault(c.max, "12"))
+		case info&types.IsInteger != 0:
+			lo, hi := "-100", "100"
+			if info&types.IsUnsigned != 0 {
+				lo = "0"
+			}
+			if c.min != "" {
+				lo = c.min
+				if c.max == "" {
+					hi = lo + "+100"
+				}
+			}
+			if c.max != "" {
+				hi = c.max
+				if c.min == "" && info&types.IsUnsigned == 0 {
+					lo = hi + "-100"
+				}
+			}
+			return fmt.Sprintf("%s(int64(%s) + r.Int63n(int64(%s)-int64(%s)+1))", typ(), lo, hi, lo)
+		case info&types.IsFloat != 0:
+			lo, hi := orDefault(c.min, "-100"), orDefault(c.max, "100")
+			return fmt.Sprintf("%s(float64(%s) + r.Float64()*(float64(%s)-float64(%s)))", typ(), lo, hi, lo)
+		}
+		return ""
+
+	case *types.Pointer:
+		elem := g.expr(t.Elem(), c)
+		if elem == "" {
+			return ""
+		}
+		return fmt.Sprintf("func() %s {\n if depth > %d || r.Intn(4) == 0 {\n return nil\n }\n v := %s\n return &v\n }()", typ(), MaxDepth, elem)
+
+	case *types.Slice:
+		elem := g.expr(t.Elem(), constraints{})
+		if elem == "" {
+			return ""
+		}
+		return fmt.Sprintf("func() %s {\n if depth > %d {\n return nil\n }\n s := make(%s, %s)\n for i := range s {\n s[i] = %s\n }\n return s\n }()",
+			typ(), MaxDepth, typ(), g.length(c), elem)
+
+	case *types.Array:
+		elem := g.expr(t.Elem(), constraints{})
+		if elem == "" {
+			return ""
+		}
+		return fmt.Sprintf("func() (a %s) {\n for i := range a {\n a[i] = %s\n }\n return a\n }()", typ(), elem)
+
+	case *types.Map:
+		key := g.expr(t.Key(), constraints{})
+		elem := g.expr(t.Elem(), constraints{})
+		if key == "" || elem == "" {
+			return ""
+		}
+		return fmt.Sprintf("func() %s {\n if depth > %d {\n return nil\n }\n m := make(%s)\n for i, n := 0, %s; i < n; i++ {\n m[%s] = %s\n }\n return m\n }()",
+			typ(), MaxDepth, typ(), g.length(c), key, elem)
+
+	case *types.Struct:
+		// Anonymous structs are filled field by field
+		var b strings.Builder
+		fmt.Fprintf(&b, "func() (v %s) {\n", typ())
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			if f.Name() == "_" {
+				continue
+			}
+			if x := g.expr(f.Type(), constraints{}); x != "" {
+				fmt.Fprintf(&b, " v.%s = %s\n", f.Name(), x)
+			}
+		}
+		b.WriteString(" return v\n }()")
+		return b.String()
+	}
+
+	return ""
+}
+
+// length returns an expression for a random collection length honoring the
+// min and max constraints.
+func (g *Generator) length(c constraints) string {
+	lo, hi := orDefault(c.min, "0"), orDefault(c.max, "4")
+	if lo == hi {
+		return lo
+	}
+	return fmt.Sprintf("%s + r.Intn(%s-%s+1)", lo, hi, lo)
+}
+
+// oneof returns an expression choosing one of the literal values at random.
+func (g *Generator) oneof(t *types.Basic, values []string) string {
+	lits := make([]string, len(values))
+	for i, v := range values {
+		if t.Info()&types.IsString != 0 {
+			lits[i] = strconv.Quote(v)
+		} else {
+			lits[i] = v
+		}
+	}
+	return fmt.Sprintf("[]%s{%s}[r.Intn(%d)]", types.TypeString(t, g.qual), strings.Join(lits, ", "), len(lits))
+}
+
+// WriteTo writes the helper functions requested so far to w.
+func (g *Generator) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	b.Write(g.helpers.Bytes())
+	if g.needString {
+		fmt.Fprintf(&b, `
+// %[1]s returns a random string of letters with a length between min
+// and max inclusive.
+func %[1]s(r *%[2]s.Rand, min, max int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, min+r.Intn(max-min+1))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
+`, g.stringHelperName(), g.rand())
+	}
+	return b.WriteTo(w)
+}
+
+// Generate writes a NewRandomT constructor to o for each of the named types,
+// or for every supported type declared in fs if no names are given.
+func Generate(fs *gen.FileSet, o *gen.Output, typeNames ...string) error {
+	var named []*types.Named
+	if len(typeNames) == 0 {
+		for _, tm := range fs.Types() {
+			if n, ok := tm.Object.Type().(*types.Named); ok && !tm.Object.IsAlias() {
+				named = append(named, n)
+			}
+		}
+	} else {
+		for _, name := range typeNames {
+			tn, ok := fs.Lookup(name).(*types.TypeName)
+			if !ok {
+				return fmt.Errorf("type %s not found", name)
+			}
+			n, ok := tn.Type().(*types.Named)
+			if !ok || tn.IsAlias() {
+				return fmt.Errorf("%s is not a named type", name)
+			}
+			named = append(named, n)
+		}
+	}
+
+	imports := gen.NewImports()
+	g := NewGenerator(fs, imports, Options{})
+	var body bytes.Buffer
+	for _, n := range named {
+		if !g.Supported(n) {
+			if len(typeNames) > 0 {
+				return fmt.Errorf("cannot construct random values of generic type %s", n.Obj().Name())
+			}
+			continue
+		}
+		if _, isIface := n.Underlying().(*types.Interface); isIface {
+			continue
+		}
+		typ := types.TypeString(n, g.qual)
+		fmt.Fprintf(&body, "\n// NewRandom%s returns a random value of type %s.\n", n.Obj().Name(), typ)
+		fmt.Fprintf(&body, "func NewRandom%s(r *%s.Rand) %s {\n\treturn %s(r, 0)\n}\n", n.Obj().Name(), g.rand(), typ, g.Helper(n))
+	}
+	if _, err := g.WriteTo(&body); err != nil {
+		return err
+	}
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	o.Printf("%s\n", imports.Block())
+	o.Write(body.Bytes())
+	return nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
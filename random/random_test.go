@@ -0,0 +1,153 @@
+package random
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import "time"
+
+type Color int
+
+const (
+	Red Color = iota + 1
+	Green
+	Blue
+)
+
+type Node struct {
+	Value    int
+	Children []*Node
+}
+
+type User struct {
+	Name    string ` + "`validate:\"min=2,max=5\"`" + `
+	Age     int    ` + "`validate:\"gte=18,lte=65\"`" + `
+	Role    string ` + "`validate:\"oneof=admin user\"`" + `
+	Tags    []string ` + "`validate:\"len=3\"`" + `
+	Color   Color
+	Tree    *Node
+	Created time.Time
+	Notify  chan int
+	private float32
+}
+
+type List[T any] struct{ Items []T }
+`
+
+const checkSrc = `package p
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		u := NewRandomUser(r)
+		if len(u.Name) < 2 || len(u.Name) > 5 {
+			t.Fatalf("name %q violates length constraint", u.Name)
+		}
+		if u.Age < 18 || u.Age > 65 {
+			t.Fatalf("age %d violates range constraint", u.Age)
+		}
+		if u.Role != "admin" && u.Role != "user" {
+			t.Fatalf("role %q violates oneof constraint", u.Role)
+		}
+		if len(u.Tags) != 3 {
+			t.Fatalf("tags %v violate len constraint", u.Tags)
+		}
+		if u.Color != Red && u.Color != Green && u.Color != Blue {
+			t.Fatalf("color %d is not a declared constant", u.Color)
+		}
+		if u.Notify != nil {
+			t.Fatalf("channel should be left nil")
+		}
+	}
+	_ = NewRandomNode(r)
+	_ = NewRandomColor(r)
+}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("random")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"func NewRandomColor(r *rand.Rand) Color",
+		"func NewRandomNode(r *rand.Rand) Node",
+		"func NewRandomUser(r *rand.Rand) User",
+		"v = []Color{Red, Green, Blue}[r.Intn(3)]",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "NewRandomList") {
+		t.Errorf("generated constructor for generic type:\n%s", src)
+	}
+
+	if err := Generate(fs, gen.NewOutput("random"), "List"); err == nil {
+		t.Errorf("got no error for generic type")
+	}
+	if err := Generate(fs, gen.NewOutput("random"), "Missing"); err == nil {
+		t.Errorf("got no error for missing type")
+	}
+}
+
+func TestGeneratedConstructorsHonorConstraints(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":        "module example.com/p\n\ngo 1.21\n",
+		"p.go":          testSrc,
+		"check_test.go": checkSrc,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("random")
+	if err := Generate(fs, o, "Color", "Node", "User"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "random_gen.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated constructors failed: %v\n%s\n%s", err, out, src)
+	}
+}
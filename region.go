@@ -0,0 +1,249 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Region marker directives. A generated region is the lines between a begin
+// marker and the matching end marker, such as
+//
+//	switch c {
+//	//gen:begin colors
+//	case Red:
+//		return "red"
+//	//gen:end colors
+//	}
+//
+// which lets a generator own a block inside a file that is otherwise
+// maintained by hand. A marker is a line comment alone on its line, so text
+// that looks like a marker inside a string literal is not one.
+const (
+	regionBegin = "//gen:begin"
+	regionEnd   = "//gen:end"
+)
+
+// ErrNoRegion is returned when a generated region is not found in a file.
+var ErrNoRegion = errors.New("region not found")
+
+// Region is a generated region of a source file, delimited by begin and end
+// markers. Regions are found with FindRegions.
+type Region struct {
+	// Name is the name given by the region's markers.
+	Name string
+
+	// Line is the line number of the begin marker.
+	Line int
+
+	// Indent is the indentation of the begin marker. Contents written to the
+	// region are indented to match it.
+	Indent string
+
+	// Start and End are the offsets in the source of the start and end of
+	// the region's contents: the line after the begin marker and the line of
+	// the end marker.
+	Start, End int
+}
+
+// FindRegions returns the generated regions of src in the order they appear.
+// Each region must be ended by an end marker with the same name, and regions
+// may not be nested or share a name.
+func FindRegions(src []byte) ([]Region, error) {
+	var regions []Region
+	var open *Region
+	seen := make(map[string]int)
+
+	s, file := scanSource(src)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT {
+			continue
+		}
+		offset := file.Offset(pos)
+		lineStart := bytes.LastIndexByte(src[:offset], '\n') + 1
+		indent := src[lineStart:offset]
+		if len(bytes.TrimLeft(indent, " \t")) > 0 {
+			continue
+		}
+		next := len(src)
+		if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+			next = offset + i + 1
+		}
+		line := file.Line(pos)
+		trimmed := strings.TrimSpace(lit)
+		switch {
+		case isRegionMarker(trimmed, regionBegin):
+			name := strings.TrimSpace(trimmed[len(regionBegin):])
+			switch {
+			case name == "":
+				return nil, fmt.Errorf("line %d: region has no name", line)
+			case open != nil:
+				return nil, fmt.Errorf("line %d: region %s begins inside region %s", line, name, open.Name)
+			case seen[name] > 0:
+				return nil, fmt.Errorf("line %d: region %s already begins at line %d", line, name, seen[name])
+			}
+			seen[name] = line
+			open = &Region{Name: name, Line: line, Indent: string(indent), Start: next}
+		case isRegionMarker(trimmed, regionEnd):
+			name := strings.TrimSpace(trimmed[len(regionEnd):])
+			if open == nil || open.Name != name {
+				return nil, fmt.Errorf("line %d: end of region %s that has not begun", line, name)
+			}
+			open.End = lineStart
+			regions = append(regions, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		return nil, fmt.Errorf("line %d: region %s is not ended", open.Line, open.Name)
+	}
+	return regions, nil
+}
+
+// scanSource returns a scanner of the Go tokens and comments of src and the
+// file its positions refer to. Errors are ignored so that files that are not
+// Go source can be scanned for comments too.
+func scanSource(src []byte) (*scanner.Scanner, *token.File) {
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	return &s, file
+}
+
+// isRegionMarker reports whether the trimmed line is a marker directive.
+func isRegionMarker(line, marker string) bool {
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+// ReplaceRegions returns src with the contents of its generated regions
+// replaced by the contents given for them, keyed by region name. Each line
+// of the new contents is indented to match the region's begin marker.
+// Regions that are not given are left unchanged. It is an error wrapping
+// ErrNoRegion for a region to be given that src does not contain.
+func ReplaceRegions(src []byte, contents map[string][]byte) ([]byte, error) {
+	regions, err := FindRegions(src)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Region, len(regions))
+	for _, r := range regions {
+		byName[r.Name] = r
+	}
+	var errs []error
+	for name := range contents {
+		if _, ok := byName[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrNoRegion))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	last := 0
+	for _, r := range regions {
+		content, ok := contents[r.Name]
+		if !ok {
+			continue
+		}
+		out = append(out, src[last:r.Start]...)
+		out = append(out, indentLines(content, r.Indent)...)
+		last = r.End
+	}
+	return append(out, src[last:]...), nil
+}
+
+// indentLines returns the lines of text prefixed by indent, ending in a
+// newline. Blank lines and lines that continue a raw string literal, whose
+// contents would change, are not indented.
+func indentLines(text []byte, indent string) []byte {
+	text = bytes.Trim(text, "\n")
+	if len(text) == 0 {
+		return nil
+	}
+	raw := rawStringLines(text)
+	var b bytes.Buffer
+	for i, line := range bytes.Split(text, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 && !raw[i+1] {
+			b.WriteString(indent)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// rawStringLines returns the numbers of the lines of src that continue a
+// raw string literal begun on an earlier line.
+func rawStringLines(src []byte) map[int]bool {
+	lines := make(map[int]bool)
+	s, file := scanSource(src)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.STRING || !strings.HasPrefix(lit, "`") {
+			continue
+		}
+		first := file.Line(pos)
+		for i := 1; i <= strings.Count(lit, "\n"); i++ {
+			lines[first+i] = true
+		}
+	}
+	return lines
+}
+
+// WriteRegions replaces the contents of the generated regions of the file
+// filename, as ReplaceRegions does, leaving the rest of the file untouched.
+// The file is written atomically, and only if it changes. The edited source
+// of a Go file must parse, so a region's contents cannot break the code
+// around it.
+func WriteRegions(filename string, contents map[string][]byte) error {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	out, err := ReplaceRegions(src, contents)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if bytes.Equal(out, src) {
+		return nil
+	}
+	if filepath.Ext(filename) == ".go" {
+		if _, err := parser.ParseFile(token.NewFileSet(), filename, out, parser.SkipObjectResolution); err != nil {
+			return fmt.Errorf("edited source does not parse: %w", err)
+		}
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, out, info.Mode().Perm())
+}
+
+// ReplaceRegion replaces the contents of the generated region with the given
+// name in the edited file, as ReplaceRegions does.
+func (r *Rewriter) ReplaceRegion(name string, content []byte) error {
+	regions, err := FindRegions(r.src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.Filename, err)
+	}
+	for _, reg := range regions {
+		if reg.Name == name {
+			return r.splice(reg.Start, reg.End, indentLines(content, reg.Indent))
+		}
+	}
+	return fmt.Errorf("%s: %s: %w", r.Filename, name, ErrNoRegion)
+}
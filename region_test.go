@@ -0,0 +1,181 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const regionSrc = `package p
+
+// String is maintained by hand apart from its cases.
+func (c Color) String() string {
+	switch c {
+	//gen:begin colors
+	case Red:
+		return "red"
+	//gen:end colors
+	}
+	return "unknown" // hand-written
+}
+
+//gen:begin names
+var names = []string{"red"}
+
+//gen:end names
+`
+
+func TestFindRegions(t *testing.T) {
+	regions, err := FindRegions([]byte(regionSrc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(regions) != 2 {
+		t.Fatalf("got %d regions, wanted 2", len(regions))
+	}
+	r := regions[0]
+	if r.Name != "colors" || r.Line != 6 || r.Indent != "\t" {
+		t.Errorf("got %+v, wanted colors at line 6 indented by a tab", r)
+	}
+	if got, want := regionSrc[r.Start:r.End], "\tcase Red:\n\t\treturn \"red\"\n"; got != want {
+		t.Errorf("got contents %q, wanted %q", got, want)
+	}
+	if regions[1].Name != "names" || regions[1].Indent != "" {
+		t.Errorf("got %+v, wanted names", regions[1])
+	}
+
+	// Markers in string literals and after code are not markers.
+	regions, err = FindRegions([]byte("package p\n\nconst doc = `\n//gen:begin a\n`\n\nvar x = 1 //gen:end a\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(regions) != 0 {
+		t.Errorf("got regions %+v, wanted none", regions)
+	}
+
+	testCases := []struct {
+		name string
+		src  string
+	}{
+		{name: "not ended", src: "//gen:begin a\n"},
+		{name: "not begun", src: "//gen:end a\n"},
+		{name: "mismatched", src: "//gen:begin a\n//gen:end b\n"},
+		{name: "nested", src: "//gen:begin a\n//gen:begin b\n//gen:end b\n//gen:end a\n"},
+		{name: "duplicate", src: "//gen:begin a\n//gen:end a\n//gen:begin a\n//gen:end a\n"},
+		{name: "unnamed", src: "//gen:begin\n//gen:end\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := FindRegions([]byte(tc.src)); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+func TestReplaceRegions(t *testing.T) {
+	got, err := ReplaceRegions([]byte(regionSrc), map[string][]byte{
+		"colors": []byte("case Red:\n\treturn \"red\"\n\ncase Green:\n\treturn \"green\"\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `package p
+
+// String is maintained by hand apart from its cases.
+func (c Color) String() string {
+	switch c {
+	//gen:begin colors
+	case Red:
+		return "red"
+
+	case Green:
+		return "green"
+	//gen:end colors
+	}
+	return "unknown" // hand-written
+}
+
+//gen:begin names
+var names = []string{"red"}
+
+//gen:end names
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	got, err = ReplaceRegions([]byte(regionSrc), map[string][]byte{"names": nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := regionSrc[:len(regionSrc)-len("var names = []string{\"red\"}\n\n//gen:end names\n")] + "//gen:end names\n"; string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	got, err = ReplaceRegions([]byte(regionSrc), map[string][]byte{
+		"colors": []byte("case Red:\n\treturn `red\n  and\n` + \"\"\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "\tcase Red:\n\t\treturn `red\n  and\n` + \"\"\n\t//gen:end colors\n"; !strings.Contains(string(got), want) {
+		t.Errorf("raw string continuation lines indented:\n%s", got)
+	}
+
+	if _, err := ReplaceRegions([]byte(regionSrc), map[string][]byte{"missing": nil}); !errors.Is(err, ErrNoRegion) {
+		t.Errorf("got error %v, wanted %v", err, ErrNoRegion)
+	}
+}
+
+func TestWriteRegions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "color.go")
+	if err := os.WriteFile(filename, []byte(regionSrc), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := WriteRegions(filename, map[string][]byte{"names": []byte("var names = []string{\"red\", \"green\"}")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src := mustReadFile(t, filename)
+	regions, err := FindRegions([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := src[regions[1].Start:regions[1].End], "var names = []string{\"red\", \"green\"}\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if err := WriteRegions(filename, map[string][]byte{"colors": []byte("case Red: {")}); err == nil {
+		t.Errorf("got no error for contents that do not parse")
+	}
+	if got := mustReadFile(t, filename); got != src {
+		t.Errorf("file changed by a rejected edit:\n%s", got)
+	}
+}
+
+func TestRewriterReplaceRegion(t *testing.T) {
+	r, err := NewRewriter("color.go", []byte(regionSrc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.ReplaceRegion("colors", []byte("case Blue:\nreturn \"blue\"")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.ReplaceRegion("missing", nil); !errors.Is(err, ErrNoRegion) {
+		t.Errorf("got error %v, wanted %v", err, ErrNoRegion)
+	}
+	got, err := r.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	regions, err := FindRegions(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := string(got[regions[0].Start:regions[0].End]), "\tcase Blue:\n\t\treturn \"blue\"\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package gen
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLoadRemote(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that downloads modules")
+	}
+
+	// Keep the remote modules out of the user's cache directory without
+	// moving the go command's build cache.
+	gocache, err := exec.Command("go", "env", "GOCACHE").Output()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GOCACHE", strings.TrimSpace(string(gocache)))
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	testCases := []struct {
+		pkg  string
+		name string
+		obj  string
+	}{
+		{pkg: "semver", name: "semver", obj: "Compare"},
+		{pkg: "golang.org/x/mod/modfile", name: "modfile", obj: "Parse"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pkg, func(t *testing.T) {
+			fs, err := LoadRemote("golang.org/x/mod@v0.41.0", tc.pkg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fs.Package.Name(); got != tc.name {
+				t.Errorf("got package %q, wanted %q", got, tc.name)
+			}
+			if fs.Lookup(tc.obj) == nil {
+				t.Errorf("%s not found in package", tc.obj)
+			}
+			if got, want := fs.ImportPath(), "golang.org/x/mod/"+tc.name; got != want {
+				t.Errorf("got import path %q, wanted %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadRemoteErrors(t *testing.T) {
+	for _, mod := range []string{"golang.org/x/mod", "golang.org/x/mod@", "Bad Path@v1.0.0"} {
+		if _, err := LoadRemote(mod, ""); err == nil {
+			t.Errorf("got no error for %q", mod)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// Renderer executes the templates of a TemplateType to produce several Go
+// source files from one generation run, such as one file per type or a file
+// and its accompanying test file. Files are collected by Emit and written
+// together by Flush with all-or-nothing semantics: if any file fails to
+// render, format or write then none of the files are changed.
+type Renderer struct {
+	// Template holds the templates executed by Emit.
+	Template *TemplateType
+
+	// Engine, if not nil, is used by Emit instead of Template to produce the
+	// source code of each file, which is then formatted when flushed.
+	Engine Engine
+
+	// Generator is the name of the program generating the code. It is used
+	// in the generated code header of each file.
+	Generator string
+
+	// Force permits Flush to overwrite existing files that do not carry the
+	// generated code header.
+	Force bool
+
+	// Logger, if not nil, reports each template rendered, at debug level,
+	// and each file written, with the time taken.
+	Logger *slog.Logger
+
+	files []*renderedFile
+	errs  []error // errors from Emit since the last Flush
+}
+
+type renderedFile struct {
+	filename string
+	out      *Output
+}
+
+// NewRenderer creates a Renderer that executes the templates of tt for code
+// generated by the named generator.
+func NewRenderer(generator string, tt *TemplateType) *Renderer {
+	return &Renderer{Template: tt, Generator: generator}
+}
+
+// NewEngineRenderer creates a Renderer that executes the templates of e for
+// code generated by the named generator.
+func NewEngineRenderer(generator string, e Engine) *Renderer {
+	return &Renderer{Engine: e, Generator: generator}
+}
+
+// Emit executes the template with the given name, or the root template if
+// tmplName is empty, with data and records the result to be written to
+// filename by the next call to Flush. The Engine executes the template if
+// one is set. It is an error to emit the same filename twice before
+// flushing. An error returned by Emit is also reported
+// by the next call to Flush, which then writes none of the emitted files.
+func (r *Renderer) Emit(filename, tmplName string, data interface{}) error {
+	return r.EmitContext(context.Background(), filename, tmplName, data)
+}
+
+// EmitContext is like Emit but stops executing the template and returns the
+// context's error once ctx is done.
+func (r *Renderer) EmitContext(ctx context.Context, filename, tmplName string, data interface{}) error {
+	err := r.emit(ctx, filename, tmplName, data)
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	return err
+}
+
+func (r *Renderer) emit(ctx context.Context, filename, tmplName string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, f := range r.files {
+		if samePath(f.filename, filename) {
+			return fmt.Errorf("%s: file already emitted", filename)
+		}
+	}
+	out := &Output{Generator: r.Generator, Force: r.Force}
+	if r.Engine != nil {
+		start := time.Now()
+		if err := r.Engine.Execute(tmplName, data, out); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		r.logger().Debug("rendered template", "template", tmplName, "file", filename, "bytes", len(out.Bytes()), "duration", time.Since(start))
+		r.files = append(r.files, &renderedFile{filename: filename, out: out})
+		return nil
+	}
+
+	if tmplName == "" {
+		tmplName = r.Template.Template.Name()
+	}
+	start := time.Now()
+	// The source is formatted once, by the Output when it is flushed.
+	src, err := r.Template.render(ctx, tmplName, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	r.logger().Debug("rendered template", "template", tmplName, "file", filename, "bytes", len(src), "duration", time.Since(start))
+	out.ParallelFormat = r.Template.ParallelFormat
+	out.FixImports = r.Template.FixImports
+	out.Write(src)
+	r.files = append(r.files, &renderedFile{filename: filename, out: out})
+	return nil
+}
+
+func (r *Renderer) logger() *slog.Logger {
+	if r.Logger == nil {
+		return discardLogger
+	}
+	return r.Logger
+}
+
+// Files returns the names of the files emitted since the last Flush, in the
+// order they were emitted.
+func (r *Renderer) Files() []string {
+	names := make([]string, len(r.files))
+	for i, f := range r.files {
+		names[i] = f.filename
+	}
+	return names
+}
+
+// Discard forgets the files emitted and the errors reported since the last
+// Flush without writing anything.
+func (r *Renderer) Discard() {
+	r.files = nil
+	r.errs = nil
+}
+
+// Flush formats all emitted files and writes them. Nothing is written unless
+// every call to Emit succeeded and every file formats successfully and may be
+// written, reporting every problem found. Each file is written to a temporary file first and the temporary
+// files are renamed into place only once all have been written; if renaming
+// fails the files already replaced are restored. The emitted files are
+// forgotten once Flush returns, whether or not it succeeds.
+func (r *Renderer) Flush() error {
+	return r.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush but stops formatting files and returns the
+// context's error, writing nothing, if ctx is done before the files are
+// written.
+func (r *Renderer) FlushContext(ctx context.Context) error {
+	start := time.Now()
+	files, errs := r.files, r.errs
+	r.files, r.errs = nil, nil
+
+	var todo []*pendingFile
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f.out.Force = r.Force
+		p, err := preparePending(f.filename, f.out)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		todo = append(todo, p)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := writePending(todo); err != nil {
+		return err
+	}
+	for _, p := range todo {
+		r.logger().Info("wrote file", "file", p.filename, "bytes", len(p.src))
+	}
+	r.logger().Info("flushed files", "files", len(todo), "duration", time.Since(start))
+	return nil
+}
+
+// samePath reports whether a and b name the same file.
+func samePath(a, b string) bool {
+	if a == b {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,254 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const rendererTemplates = `{{define "type"}}package p
+
+type {{.}} struct{}
+
+func (x {{.}}) String() string { return {{import "fmt"}}.Sprint("{{.}}") }
+{{end}}
+{{define "test"}}package p
+
+import "testing"
+
+func Test{{.}}(t *testing.T) {}
+{{end}}
+{{define "broken"}}package p
+
+func {
+{{end}}`
+
+func newTestRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	tt, err := NewTemplateType("files", rendererTemplates, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return NewRenderer("gentool", tt)
+}
+
+func TestRendererFlush(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRenderer(t)
+
+	for _, name := range []string{"Alpha", "Beta"} {
+		filename := filepath.Join(dir, strings.ToLower(name)+"_gen.go")
+		if err := r.Emit(filename, "type", name); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := r.Emit(filepath.Join(dir, "alpha_gen_test.go"), "test", "Alpha"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(r.Files()); got != 3 {
+		t.Errorf("got %d files, wanted 3", got)
+	}
+
+	if err := r.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(r.Files()); got != 0 {
+		t.Errorf("got %d files after flush, wanted 0", got)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "beta_gen.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `// Code generated by gentool; DO NOT EDIT.
+
+package p
+
+import (
+	"fmt"
+)
+
+type Beta struct{}
+
+func (x Beta) String() string { return fmt.Sprint("Beta") }
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("got %d files, wanted 3 (temporary files left behind?)", len(entries))
+	}
+}
+
+func TestRendererFlushAllOrNothing(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(t *testing.T, r *Renderer, dir string)
+		want  error
+	}{
+		{
+			name: "format error",
+			setup: func(t *testing.T, r *Renderer, dir string) {
+				// The source is formatted, and the error found, by Flush.
+				if err := r.Emit(filepath.Join(dir, "broken_gen.go"), "broken", nil); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "duplicate file",
+			setup: func(t *testing.T, r *Renderer, dir string) {
+				if err := r.Emit(filepath.Join(dir, "new_gen.go"), "type", "Other"); err == nil {
+					t.Fatalf("got no error, wanted one")
+				}
+			},
+		},
+		{
+			name: "not generated",
+			setup: func(t *testing.T, r *Renderer, dir string) {
+				filename := filepath.Join(dir, "manual.go")
+				if err := os.WriteFile(filename, []byte("package p\n"), 0o644); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err := r.Emit(filename, "type", "Manual"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			want: ErrNotGenerated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			existing := filepath.Join(dir, "existing_gen.go")
+			original := "// Code generated by gentool; DO NOT EDIT.\n\npackage p\n"
+			if err := os.WriteFile(existing, []byte(original), 0o644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r := newTestRenderer(t)
+			if err := r.Emit(existing, "type", "Existing"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := r.Emit(filepath.Join(dir, "new_gen.go"), "type", "New"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tc.setup(t, r, dir)
+
+			err := r.Flush()
+			if err == nil {
+				t.Fatalf("got no error, wanted one")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("got error %v, wanted %v", err, tc.want)
+			}
+
+			got, err := os.ReadFile(existing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != original {
+				t.Errorf("existing file was modified: %s", got)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "new_gen.go")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("got %v for new file, wanted it not to exist", err)
+			}
+		})
+	}
+}
+
+func TestRendererContext(t *testing.T) {
+	tt, err := NewTemplateType("root", rendererTemplates, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dir := t.TempDir()
+	r := NewRenderer("gen", tt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.EmitContext(ctx, filepath.Join(dir, "a.go"), "type", "A"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	if err := r.EmitContext(ctx, filepath.Join(dir, "b.go"), "type", "B"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+	r.Discard()
+
+	if err := r.Emit(filepath.Join(dir, "a.go"), "type", "A"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.FlushContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.go")); err == nil {
+		t.Errorf("a.go written after cancellation")
+	}
+}
+
+func TestRendererLogger(t *testing.T) {
+	tt, err := NewTemplateType("root", rendererTemplates, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	r := NewRenderer("gen", tt)
+	r.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	filename := filepath.Join(dir, "a.go")
+	if err := r.Emit(filename, "type", "A"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	log := buf.String()
+	for _, want := range []string{
+		`msg="rendered template" template=type file=` + filename + " bytes=",
+		`msg="wrote file" file=` + filename + " bytes=",
+		`msg="flushed files" files=1 duration=`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log does not contain %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestRendererFixImports(t *testing.T) {
+	tt, err := NewTemplateType("fix", "package p\n\nimport \"os\"\n\nfunc Print() { fmt.Println(\"x\") }\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tt.FixImports = true
+	r := NewRenderer("gentool", tt)
+
+	filename := filepath.Join(t.TempDir(), "fix_gen.go")
+	if err := r.Emit(filename, "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := r.files[0].out; !got.FixImports {
+		t.Errorf("FixImports not set on the output")
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(got), "import \"fmt\"\n") || strings.Contains(string(got), "\"os\"") {
+		t.Errorf("imports not fixed:\n%s", got)
+	}
+}
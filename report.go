@@ -0,0 +1,220 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report summarizes the changes a generation run makes to the files on disk
+// in a form that review automation, such as a bot commenting on a pull
+// request, can consume. Its JSON encoding is a stable, documented format:
+//
+//	{
+//	  "generator": "stringer",
+//	  "files": [
+//	    {
+//	      "path": "color_string.go",
+//	      "status": "modified",
+//	      "added": ["Color.GoString"],
+//	      "removed": ["Color.Name"],
+//	      "generators": ["stringer"]
+//	    }
+//	  ],
+//	  "stale": ["shape_string.go"]
+//	}
+//
+// Paths are slash separated and relative to the current directory when they
+// are within it. Declarations are named as by CheckConflicts, with methods
+// named T.M. Empty lists are omitted, except for files.
+type Report struct {
+	// Generator is the name of the generator that made the run.
+	Generator string `json:"generator"`
+
+	// Files describes each file written by the run, ordered by path.
+	Files []FileReport `json:"files"`
+
+	// Stale lists existing files produced by the generator, in the
+	// directories of the files written, that the run did not produce and
+	// that are therefore likely to be out of date or obsolete.
+	Stale []string `json:"stale,omitempty"`
+}
+
+// FileReport describes the change made to one file by a generation run.
+type FileReport struct {
+	// Path is the path of the file.
+	Path string `json:"path"`
+
+	// Status is "added" for a new file, "modified" for a changed file and
+	// "unchanged" for a file whose contents are not changed.
+	Status string `json:"status"`
+
+	// Added lists the declarations that the file did not previously have,
+	// sorted by name.
+	Added []string `json:"added,omitempty"`
+
+	// Removed lists the declarations that the file no longer has, sorted by
+	// name.
+	Removed []string `json:"removed,omitempty"`
+
+	// Generators lists the generators that own the declarations of the
+	// file, sorted by name.
+	Generators []string `json:"generators,omitempty"`
+}
+
+// File statuses used in a FileReport.
+const (
+	FileAdded     = "added"
+	FileModified  = "modified"
+	FileUnchanged = "unchanged"
+)
+
+// NewReport reports the changes that writing the outputs, keyed by the
+// filename they will be written to, makes to the files on disk. It should be
+// called before the outputs are written.
+func NewReport(generator string, outputs map[string]*Output) (*Report, error) {
+	r := &Report{Generator: generator, Files: []FileReport{}}
+	dirs := make(map[string]bool)
+	for filename, o := range outputs {
+		src, err := o.Source()
+		if err != nil {
+			return nil, err
+		}
+		ow, err := o.ownership(src)
+		if err != nil {
+			return nil, err
+		}
+
+		fr := FileReport{
+			Path:       patchName(filename),
+			Status:     FileAdded,
+			Generators: ow.Generators(),
+		}
+		if len(fr.Generators) == 0 {
+			fr.Generators = []string{o.Generator}
+		}
+		previous, err := os.ReadFile(filename)
+		if err == nil {
+			fr.Status = FileModified
+			if bytes.Equal(previous, src) {
+				fr.Status = FileUnchanged
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		fr.Added, fr.Removed = declChanges(previous, src)
+		r.Files = append(r.Files, fr)
+
+		if abs, err := filepath.Abs(filename); err == nil {
+			dirs[filepath.Dir(abs)] = true
+		}
+	}
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+
+	for dir := range dirs {
+		names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if producedBy(name, generator) && !inOutputs(name, outputs) {
+				r.Stale = append(r.Stale, patchName(name))
+			}
+		}
+	}
+	sort.Strings(r.Stale)
+	return r, nil
+}
+
+// WriteJSON writes the report to w as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// declChanges returns the names of the declarations in src but not previous
+// and in previous but not src.
+func declChanges(previous, src []byte) (added, removed []string) {
+	before, after := declaredNames(previous), declaredNames(src)
+	for name := range after {
+		if !before[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range before {
+		if !after[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// declaredNames returns the names declared by the Go source src, or none if
+// it cannot be parsed.
+func declaredNames(src []byte) map[string]bool {
+	names := make(map[string]bool)
+	if len(src) == 0 {
+		return names
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return names
+	}
+	eachDeclaredName(f, func(name string, _ token.Pos) {
+		names[name] = true
+	})
+	return names
+}
+
+// producedBy reports whether the file was produced by the named generator,
+// according to its generated code header or ownership trailer.
+func producedBy(filename, generator string) bool {
+	src, err := os.ReadFile(filename)
+	if err != nil || !IsGenerated(src) {
+		return false
+	}
+	if headerGenerator(src) == generator {
+		return true
+	}
+	for _, g := range ReadOwnership(src).Generators() {
+		if g == generator {
+			return true
+		}
+	}
+	return false
+}
+
+// headerGenerator returns the name of the generator given by the generated
+// code header of src, or an empty string if it has no header in the form
+// written by Output.
+func headerGenerator(src []byte) string {
+	line := generatedHeader(src)
+	if line == nil {
+		return ""
+	}
+	g, ok := strings.CutPrefix(string(line), "// Code generated by ")
+	if !ok {
+		return ""
+	}
+	g, _ = strings.CutSuffix(g, "; DO NOT EDIT.")
+	return g
+}
+
+// inOutputs reports whether filename is one of the outputs.
+func inOutputs(filename string, outputs map[string]*Output) bool {
+	for name := range outputs {
+		if samePath(name, filename) {
+			return true
+		}
+	}
+	return false
+}
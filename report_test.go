@@ -0,0 +1,96 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	files := map[string]string{
+		"color_string.go": "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n\nfunc (Color) Name() string { return \"\" }\n\nfunc (Color) String() string { return \"\" }\n",
+		"same.go":         "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n\nconst Same = 1\n",
+		"shape_string.go": "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n\nfunc (Shape) String() string { return \"\" }\n",
+		"owned.go":        "// Code generated by other; DO NOT EDIT.\n\npackage p\n\nconst X = 1\n\n//gen:owner stringer X\n",
+		"other.go":        "// Code generated by other; DO NOT EDIT.\n\npackage p\n",
+		"manual.go":       "package p\n\ntype Color int\n\ntype Shape int\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	color := NewOutput("stringer")
+	color.RecordOwnership = true
+	color.Printf("package p\n\nfunc (Color) String() string { return \"\" }\n\nfunc (Color) GoString() string { return \"\" }\n")
+	color.Own("gostringer", "Color.GoString")
+	same := NewOutput("stringer")
+	same.Printf("package p\n\nconst Same = 1\n")
+	added := NewOutput("stringer")
+	added.Printf("package p\n")
+
+	r, err := NewReport("stringer", map[string]*Output{
+		filepath.Join(dir, "color_string.go"): color,
+		"same.go":                             same,
+		"sub/new.go":                          added,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &Report{
+		Generator: "stringer",
+		Files: []FileReport{
+			{Path: "color_string.go", Status: FileModified, Added: []string{"Color.GoString"}, Removed: []string{"Color.Name"}, Generators: []string{"gostringer", "stringer"}},
+			{Path: "same.go", Status: FileUnchanged, Generators: []string{"stringer"}},
+			{Path: "sub/new.go", Status: FileAdded, Generators: []string{"stringer"}},
+		},
+		Stale: []string{"owned.go", "shape_string.go"},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("got %+v, wanted %+v", r, want)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, want) {
+		t.Errorf("got %+v after decoding, wanted %+v", decoded, want)
+	}
+	if !strings.Contains(buf.String(), `"status": "unchanged"`) || strings.Contains(buf.String(), `"removed": null`) {
+		t.Errorf("got JSON:\n%s", buf.String())
+	}
+}
+
+func TestRunnerReport(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	var stdout bytes.Buffer
+	r := stringerRunner()
+	r.Stdout = &stdout
+	if err := r.Run([]string{"-type", "Color", "-report", "-", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Files) != 1 || report.Files[0].Status != FileAdded || !reflect.DeepEqual(report.Files[0].Added, []string{"Color.String"}) {
+		t.Errorf("got report %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "color_stringer.go")); err != nil {
+		t.Errorf("output was not written: %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrNoDecl is returned by the methods of Rewriter when the named declaration
+// is not found in the file.
+var ErrNoDecl = errors.New("declaration not found")
+
+// Rewriter edits the declarations of an existing Go source file, such as a
+// hand-written file that a generator adds a method to, while leaving the
+// rest of the file untouched. Edits splice source code into the file's text
+// rather than printing a modified syntax tree, so the comments and layout of
+// the code that is not edited are preserved exactly. Each edit is checked by
+// parsing the edited file; an edit that would leave the file unparseable is
+// rejected and the file is left as it was.
+//
+// Declarations are named as by the rest of gen: F for a function, T.M for a
+// method and the declared name for a type, constant or variable. A name
+// declared in a parenthesized group of several specifications, such as a
+// constant of an enumeration, names the whole group.
+type Rewriter struct {
+	// Filename is the name of the file being edited.
+	Filename string
+
+	src  []byte
+	fset *token.FileSet
+	file *ast.File
+}
+
+// NewRewriter returns a Rewriter for the Go source file filename. If src is
+// nil the source is read from filename.
+func NewRewriter(filename string, src []byte) (*Rewriter, error) {
+	if src == nil {
+		var err error
+		src, err = os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := &Rewriter{Filename: filename}
+	if err := r.reset(src); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reset makes src the source of the file being edited.
+func (r *Rewriter) reset(src []byte) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, r.Filename, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	r.src, r.fset, r.file = src, fset, f
+	return nil
+}
+
+// File returns the syntax tree of the edited file. It is replaced by each
+// edit, so it must not be retained across edits.
+func (r *Rewriter) File() *ast.File {
+	return r.file
+}
+
+// FileSet returns the file set holding the positions of File.
+func (r *Rewriter) FileSet() *token.FileSet {
+	return r.fset
+}
+
+// Decl returns the declaration with the given name.
+func (r *Rewriter) Decl(name string) (ast.Decl, bool) {
+	for _, decl := range r.file.Decls {
+		if declares(decl, name) {
+			return decl, true
+		}
+	}
+	return nil, false
+}
+
+// declares reports whether decl declares name.
+func declares(decl ast.Decl, name string) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return declFuncName(decl) == name
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name.Name == name {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, id := range spec.Names {
+					if id.Name == name {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Source returns the edited source formatted with gofmt.
+func (r *Rewriter) Source() ([]byte, error) {
+	return format.Source(r.src)
+}
+
+// WriteFile formats the edited source and writes it atomically to the file
+// it was read from, keeping the file's permissions.
+func (r *Rewriter) WriteFile() error {
+	src, err := r.Source()
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(r.Filename); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return writeFileAtomic(r.Filename, src, perm)
+}
+
+// Replace replaces the declaration with the given name, including its doc
+// comment, with code, which holds zero or more declarations.
+func (r *Rewriter) Replace(name, code string) error {
+	start, end, err := r.declRange(name)
+	if err != nil {
+		return err
+	}
+	return r.splice(start, end, block(code))
+}
+
+// Delete removes the declaration with the given name, including its doc
+// comment.
+func (r *Rewriter) Delete(name string) error {
+	start, end, err := r.declRange(name)
+	if err != nil {
+		return err
+	}
+	// Remove the blank line that separated the declaration from the next.
+	if bytes.HasPrefix(r.src[end:], []byte("\n")) {
+		end++
+	}
+	return r.splice(start, end, nil)
+}
+
+// InsertBefore inserts code, which holds one or more declarations, before
+// the declaration with the given name and its doc comment.
+func (r *Rewriter) InsertBefore(name, code string) error {
+	start, _, err := r.declRange(name)
+	if err != nil {
+		return err
+	}
+	return r.splice(start, start, append(block(code), '\n'))
+}
+
+// InsertAfter inserts code, which holds one or more declarations, after the
+// declaration with the given name.
+func (r *Rewriter) InsertAfter(name, code string) error {
+	_, end, err := r.declRange(name)
+	if err != nil {
+		return err
+	}
+	return r.splice(end, end, append([]byte("\n"), block(code)...))
+}
+
+// Append inserts code, which holds one or more declarations, at the end of
+// the file.
+func (r *Rewriter) Append(code string) error {
+	end := len(bytes.TrimRight(r.src, "\n"))
+	return r.splice(end, len(r.src), append([]byte("\n\n"), block(code)...))
+}
+
+// AddMethod inserts code, which holds one or more declarations such as a
+// method of the type typeName, after the last method of the type declared in
+// the file, or after the type's declaration if none are, or at the end of the
+// file if the type is not declared in it.
+func (r *Rewriter) AddMethod(typeName, code string) error {
+	after := ""
+	for _, decl := range r.file.Decls {
+		switch {
+		case declares(decl, typeName):
+			if after == "" {
+				after = typeName
+			}
+		case isMethodOf(decl, typeName):
+			after = declFuncName(decl.(*ast.FuncDecl))
+		}
+	}
+	if after == "" {
+		return r.Append(code)
+	}
+	return r.InsertAfter(after, code)
+}
+
+// isMethodOf reports whether decl declares a method of the type typeName.
+func isMethodOf(decl ast.Decl, typeName string) bool {
+	fd, ok := decl.(*ast.FuncDecl)
+	if !ok || fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return false
+	}
+	id := embeddedIdent(fd.Recv.List[0].Type)
+	return id != nil && id.Name == typeName
+}
+
+// ReplaceBody replaces the body of the function or method with the given
+// name with body, which holds the statements of the new body without the
+// enclosing braces. The function's signature and doc comment are kept.
+func (r *Rewriter) ReplaceBody(name, body string) error {
+	decl, ok := r.Decl(name)
+	if !ok {
+		return fmt.Errorf("%s: %s: %w", r.Filename, name, ErrNoDecl)
+	}
+	fd, ok := decl.(*ast.FuncDecl)
+	if !ok {
+		return fmt.Errorf("%s: %s is not a function", r.Filename, name)
+	}
+	text := []byte("{\n" + strings.Trim(body, "\n") + "\n}")
+	if fd.Body == nil {
+		end := r.offset(fd.End())
+		return r.splice(end, end, append([]byte(" "), text...))
+	}
+	return r.splice(r.offset(fd.Body.Lbrace), r.offset(fd.Body.Rbrace)+1, text)
+}
+
+// AddImport adds an import of the package path, with the given local name
+// unless name is empty, if the file does not already import it.
+func (r *Rewriter) AddImport(name, path string) error {
+	var last *ast.GenDecl
+	for _, decl := range r.file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		last = gd
+		for _, spec := range gd.Specs {
+			is := spec.(*ast.ImportSpec)
+			existing, _ := strconv.Unquote(is.Path.Value)
+			if existing != path {
+				continue
+			}
+			if (is.Name == nil && name == "") || (is.Name != nil && is.Name.Name == name) {
+				return nil
+			}
+		}
+	}
+
+	spec := strconv.Quote(path)
+	if name != "" {
+		spec = name + " " + spec
+	}
+	switch {
+	case last == nil:
+		end := r.offset(r.file.Name.End())
+		return r.splice(end, end, []byte("\n\nimport "+spec))
+	case last.Rparen.IsValid():
+		rparen := r.offset(last.Rparen)
+		return r.splice(rparen, rparen, []byte("\t"+spec+"\n"))
+	default:
+		end := r.offset(last.End())
+		return r.splice(end, end, []byte("\nimport "+spec))
+	}
+}
+
+// declRange returns the offsets of the start and end of the declaration with
+// the given name. The range starts at the beginning of the line holding the
+// declaration or its doc comment and ends after the newline that ends the
+// declaration, including any comment on its last line.
+func (r *Rewriter) declRange(name string) (int, int, error) {
+	decl, ok := r.Decl(name)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s: %s: %w", r.Filename, name, ErrNoDecl)
+	}
+	var doc *ast.CommentGroup
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		doc = decl.Doc
+	case *ast.GenDecl:
+		doc = decl.Doc
+	}
+	start := decl.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	s := r.offset(start)
+	for s > 0 && r.src[s-1] != '\n' {
+		s--
+	}
+	e := r.offset(decl.End())
+	if i := bytes.IndexByte(r.src[e:], '\n'); i >= 0 {
+		e += i + 1
+	} else {
+		e = len(r.src)
+	}
+	return s, e, nil
+}
+
+// block returns code as a block of lines ending in a newline.
+func block(code string) []byte {
+	return []byte(strings.Trim(code, "\n") + "\n")
+}
+
+// offset returns the offset in the source of pos.
+func (r *Rewriter) offset(pos token.Pos) int {
+	return r.fset.Position(pos).Offset
+}
+
+// splice replaces the source between offsets start and end with text,
+// rejecting the edit if the result does not parse.
+func (r *Rewriter) splice(start, end int, text []byte) error {
+	src := make([]byte, 0, len(r.src)-(end-start)+len(text))
+	src = append(src, r.src[:start]...)
+	src = append(src, text...)
+	src = append(src, r.src[end:]...)
+	if err := r.reset(src); err != nil {
+		return fmt.Errorf("edited source does not parse: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const rewriteSrc = `package p
+
+// Hand-written comment that must survive.
+
+import "fmt"
+
+// User is a user.
+type User struct {
+	Name string // the name
+}
+
+// Greet greets the user.
+func (u *User) Greet() {
+	fmt.Println("hello", u.Name)
+}
+
+const (
+	A = iota
+	B
+)
+
+// Old is no longer needed.
+func Old() int { return 1 } // trailing
+`
+
+func TestRewriter(t *testing.T) {
+	testCases := []struct {
+		name string
+		edit func(r *Rewriter) error
+		want string
+	}{
+		{
+			name: "replace",
+			edit: func(r *Rewriter) error {
+				return r.Replace("Old", "// New replaces Old.\nfunc New() int { return 2 }")
+			},
+			want: `package p
+
+// Hand-written comment that must survive.
+
+import "fmt"
+
+// User is a user.
+type User struct {
+	Name string // the name
+}
+
+// Greet greets the user.
+func (u *User) Greet() {
+	fmt.Println("hello", u.Name)
+}
+
+const (
+	A = iota
+	B
+)
+
+// New replaces Old.
+func New() int { return 2 }
+`,
+		},
+		{
+			name: "delete",
+			edit: func(r *Rewriter) error {
+				return r.Delete("B")
+			},
+			want: `package p
+
+// Hand-written comment that must survive.
+
+import "fmt"
+
+// User is a user.
+type User struct {
+	Name string // the name
+}
+
+// Greet greets the user.
+func (u *User) Greet() {
+	fmt.Println("hello", u.Name)
+}
+
+// Old is no longer needed.
+func Old() int { return 1 } // trailing
+`,
+		},
+		{
+			name: "add method",
+			edit: func(r *Rewriter) error {
+				if err := r.AddImport("", "strings"); err != nil {
+					return err
+				}
+				return r.AddMethod("User", "// Upper returns the upper case name.\nfunc (u *User) Upper() string {\nreturn strings.ToUpper(u.Name)\n}")
+			},
+			want: `package p
+
+// Hand-written comment that must survive.
+
+import "fmt"
+import "strings"
+
+// User is a user.
+type User struct {
+	Name string // the name
+}
+
+// Greet greets the user.
+func (u *User) Greet() {
+	fmt.Println("hello", u.Name)
+}
+
+// Upper returns the upper case name.
+func (u *User) Upper() string {
+	return strings.ToUpper(u.Name)
+}
+
+const (
+	A = iota
+	B
+)
+
+// Old is no longer needed.
+func Old() int { return 1 } // trailing
+`,
+		},
+		{
+			name: "replace body",
+			edit: func(r *Rewriter) error {
+				return r.ReplaceBody("User.Greet", "fmt.Println(\"hi\", u.Name)")
+			},
+			want: `package p
+
+// Hand-written comment that must survive.
+
+import "fmt"
+
+// User is a user.
+type User struct {
+	Name string // the name
+}
+
+// Greet greets the user.
+func (u *User) Greet() {
+	fmt.Println("hi", u.Name)
+}
+
+const (
+	A = iota
+	B
+)
+
+// Old is no longer needed.
+func Old() int { return 1 } // trailing
+`,
+		},
+		{
+			name: "insert",
+			edit: func(r *Rewriter) error {
+				if err := r.InsertBefore("User", "type ID int"); err != nil {
+					return err
+				}
+				if err := r.InsertAfter("User", "type Users []User"); err != nil {
+					return err
+				}
+				return r.Append("var Default User")
+			},
+			want: `package p
+
+// Hand-written comment that must survive.
+
+import "fmt"
+
+type ID int
+
+// User is a user.
+type User struct {
+	Name string // the name
+}
+
+type Users []User
+
+// Greet greets the user.
+func (u *User) Greet() {
+	fmt.Println("hello", u.Name)
+}
+
+const (
+	A = iota
+	B
+)
+
+// Old is no longer needed.
+func Old() int { return 1 } // trailing
+
+var Default User
+`,
+		},
+		{
+			name: "existing import",
+			edit: func(r *Rewriter) error {
+				return r.AddImport("", "fmt")
+			},
+			want: rewriteSrc,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewRewriter("p.go", []byte(rewriteSrc))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := tc.edit(r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := r.Source()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got:\n%s\nwanted:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRewriterErrors(t *testing.T) {
+	r, err := NewRewriter("p.go", []byte(rewriteSrc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := r.Replace("Missing", "func Missing() {}"); !errors.Is(err, ErrNoDecl) {
+		t.Errorf("got error %v, wanted %v", err, ErrNoDecl)
+	}
+	if err := r.ReplaceBody("User", "return"); err == nil {
+		t.Errorf("got no error replacing the body of a type")
+	}
+	if err := r.Replace("Old", "func Old() {"); err == nil {
+		t.Errorf("got no error for an edit that does not parse")
+	}
+	got, err := r.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != rewriteSrc {
+		t.Errorf("rejected edit changed the source:\n%s", got)
+	}
+}
+
+func TestRewriterWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "p.go")
+	if err := os.WriteFile(filename, []byte(rewriteSrc), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := NewRewriter(filename, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.AddImport("str", "strings"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Append("var _ = str.ToUpper"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.WriteFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewFileSetFromTexts(mustReadFile(t, filename)); err != nil {
+		t.Errorf("rewritten file does not type check: %v", err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("got permissions %v, wanted 0600", perm)
+	}
+}
+
+func mustReadFile(t *testing.T, filename string) string {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(data)
+}
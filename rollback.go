@@ -0,0 +1,194 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+)
+
+// WriteOutputs writes several outputs, keyed by the filename they are written
+// to, with all-or-nothing semantics. Nothing is written unless every output
+// formats successfully and may be written, reporting every problem found.
+// Each output is written to a temporary file first and the temporary files are
+// renamed into place only once all have been written; if renaming fails the
+// files already replaced are restored, so the files are never left
+// half-regenerated.
+func WriteOutputs(outputs map[string]*Output) error {
+	filenames := make([]string, 0, len(outputs))
+	for filename := range outputs {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var errs []error
+	var todo []*pendingFile
+	for _, filename := range filenames {
+		p, err := preparePending(filename, outputs[filename])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		todo = append(todo, p)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return writePending(todo)
+}
+
+// Transaction records the files written during a generation run that writes
+// its outputs one at a time, so that they can be restored if the run fails
+// part way through. A Transaction is safe for concurrent use.
+type Transaction struct {
+	mu      sync.Mutex
+	written []*pendingFile
+}
+
+// NewTransaction creates an empty Transaction.
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// WriteFile writes the formatted source of o to filename as Output.WriteFile
+// does, remembering the file's previous contents so that Rollback can restore
+// them.
+func (tx *Transaction) WriteFile(filename string, o *Output) error {
+	p, err := preparePending(filename, o)
+	if err != nil {
+		return err
+	}
+	if err := p.readPrevious(); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(p.filename, p.src, p.perm); err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.written = append(tx.written, p)
+	return nil
+}
+
+// Files returns the names of the files written by the transaction, in the
+// order they were written.
+func (tx *Transaction) Files() []string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	names := make([]string, len(tx.written))
+	for i, p := range tx.written {
+		names[i] = p.filename
+	}
+	return names
+}
+
+// Rollback restores the files written by the transaction to their contents
+// before the transaction wrote them, removing files that did not previously
+// exist. Files are restored in the reverse of the order they were written.
+// The transaction is empty once Rollback returns, whether or not it succeeds.
+func (tx *Transaction) Rollback() error {
+	tx.mu.Lock()
+	written := tx.written
+	tx.written = nil
+	tx.mu.Unlock()
+
+	var errs []error
+	for i := len(written) - 1; i >= 0; i-- {
+		if err := written[i].restore(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Commit forgets the files written by the transaction so they are no longer
+// restored by Rollback.
+func (tx *Transaction) Commit() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.written = nil
+}
+
+// pendingFile is formatted source waiting to be written to a file, along with
+// what is needed to restore the file afterwards.
+type pendingFile struct {
+	filename string
+	src      []byte
+	perm     fs.FileMode
+	tmp      string
+	existed  bool
+	previous []byte // contents before writing
+}
+
+// preparePending formats the source of o and checks that it may be written to
+// filename.
+func preparePending(filename string, o *Output) (*pendingFile, error) {
+	src, err := o.Source()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	perm, err := checkTarget(filename, o.Force)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingFile{filename: filename, src: src, perm: perm}, nil
+}
+
+// readPrevious records the current contents of the file, if it exists.
+func (p *pendingFile) readPrevious() error {
+	previous, err := os.ReadFile(p.filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	p.existed, p.previous = err == nil, previous
+	return nil
+}
+
+// restore returns the file to its contents before it was written.
+func (p *pendingFile) restore() error {
+	if !p.existed {
+		if err := os.Remove(p.filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(p.filename, p.previous, p.perm)
+}
+
+// writePending writes each of the pending files to a temporary file and then
+// renames them into place, restoring the files already replaced if a rename
+// fails.
+func writePending(todo []*pendingFile) error {
+	removeTemps := func() {
+		for _, p := range todo {
+			if p.tmp != "" {
+				os.Remove(p.tmp)
+			}
+		}
+	}
+	for _, p := range todo {
+		err := p.readPrevious()
+		if err == nil {
+			p.tmp, err = writeTemp(p.filename, p.src, p.perm)
+		}
+		if err != nil {
+			removeTemps()
+			return err
+		}
+	}
+
+	for i, p := range todo {
+		if err := os.Rename(p.tmp, p.filename); err != nil {
+			removeTemps()
+			for _, done := range todo[:i] {
+				done.restore()
+			}
+			return err
+		}
+		p.tmp = ""
+	}
+	return nil
+}
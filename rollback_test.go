@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.go")
+	previous := "// Code generated by old; DO NOT EDIT.\n\npackage p\n\nconst Old = 1\n"
+	if err := os.WriteFile(existing, []byte(previous), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handwritten := filepath.Join(dir, "handwritten.go")
+	if err := os.WriteFile(handwritten, []byte("package p\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := func(src string) *Output {
+		o := NewOutput("gen")
+		o.Printf("%s", src)
+		return o
+	}
+
+	// A single failure prevents any file being written.
+	err := WriteOutputs(map[string]*Output{
+		existing:                     output("package p\n\nconst New = 1\n"),
+		filepath.Join(dir, "new.go"): output("package p\n\nconst Other = 1\n"),
+		handwritten:                  output("package p\n\nconst H = 1\n"),
+	})
+	if !errors.Is(err, ErrNotGenerated) {
+		t.Fatalf("got error %v, wanted %v", err, ErrNotGenerated)
+	}
+	if got, _ := os.ReadFile(existing); string(got) != previous {
+		t.Errorf("existing file was changed:\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("new file was written")
+	}
+
+	err = WriteOutputs(map[string]*Output{
+		existing:                     output("package p\n\nconst New = 1\n"),
+		filepath.Join(dir, "new.go"): output("package p\n\nconst Other = 1\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := os.ReadFile(existing); !IsGenerated(got) || string(got) == previous {
+		t.Errorf("existing file was not regenerated:\n%s", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("got %d files, wanted 3 with no temporary files left behind", len(entries))
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.go")
+	previous := "// Code generated by old; DO NOT EDIT.\n\npackage p\n\nconst Old = 1\n"
+	if err := os.WriteFile(existing, []byte(previous), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	created := filepath.Join(dir, "created.go")
+
+	tx := NewTransaction()
+	for _, filename := range []string{existing, created} {
+		o := NewOutput("gen")
+		o.Printf("package p\n\nconst %s = 2\n", filepath.Base(filename)[:1])
+		if err := tx.WriteFile(filename, o); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got, want := tx.Files(), []string{existing, created}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	// A failure part way through the run does not record a file.
+	bad := NewOutput("gen")
+	bad.Printf("package p\n\nfunc {\n")
+	if err := tx.WriteFile(filepath.Join(dir, "bad.go"), bad); err == nil {
+		t.Fatalf("got no error for invalid source, wanted one")
+	}
+	if got := len(tx.Files()); got != 2 {
+		t.Errorf("got %d files, wanted 2", got)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(existing)
+	if err != nil || string(got) != previous {
+		t.Errorf("got %q, %v, wanted previous contents restored", got, err)
+	}
+	if info, err := os.Stat(existing); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("permissions of restored file were not preserved")
+	}
+	if _, err := os.Stat(created); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("created file was not removed")
+	}
+	if len(tx.Files()) != 0 {
+		t.Errorf("got files after rollback")
+	}
+
+	// Committed files are not rolled back.
+	o := NewOutput("gen")
+	o.Printf("package p\n\nconst C = 3\n")
+	if err := tx.WriteFile(created, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx.Commit()
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(created); err != nil {
+		t.Errorf("committed file was removed: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package roundtrip generates property based tests that check that values
+// survive a round trip through a type's marshaling methods.
+//
+// A marshaler pair is a method MarshalX() ([]byte, error) together with a
+// method UnmarshalX([]byte) error in the pointer method set of the same type,
+// for example MarshalJSON and UnmarshalJSON. For every pair found the
+// generated test constructs random values of the type, marshals and
+// unmarshals each one and checks that the result is deeply equal to the
+// original. Random values are constructed by the random package, honoring
+// validate struct tags and enumerated constants. Only exported fields are
+// populated, and fields whose struct tag for the format, such as json:"-",
+// excludes them are left zero.
+package roundtrip
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+
+	"github.com/iand/gen"
+	"github.com/iand/gen/random"
+)
+
+// Iterations is the number of random values checked by each generated test.
+const Iterations = 100
+
+// Pair is a marshaler pair found on a type.
+type Pair struct {
+	// Type is the name of the type.
+	Type string
+
+	// Format is the suffix shared by the method names, such as JSON.
+	Format string
+}
+
+// Pairs returns the marshaler pairs of the named types declared in fs, in
+// declaration order.
+func Pairs(fs *gen.FileSet) ([]Pair, error) {
+	var pairs []Pair
+	for _, tm := range fs.Types() {
+		if tm.Object.IsAlias() {
+			continue
+		}
+		if n, ok := tm.Object.Type().(*types.Named); ok && n.TypeParams().Len() > 0 {
+			continue
+		}
+		ms, err := fs.MethodsOf(tm.Name)
+		if err != nil {
+			return nil, err
+		}
+		methods := make(map[string]*gen.MethodModel)
+		for _, m := range ms.Pointer {
+			methods[m.Name] = m
+		}
+		for _, m := range ms.Pointer {
+			format, ok := strings.CutPrefix(m.Name, "Marshal")
+			if !ok || !isMarshal(m) {
+				continue
+			}
+			if um, ok := methods["Unmarshal"+format]; ok && isUnmarshal(um) {
+				pairs = append(pairs, Pair{Type: tm.Name, Format: format})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// isMarshal reports whether m has the signature func() ([]byte, error).
+func isMarshal(m *gen.MethodModel) bool {
+	return len(m.Params) == 0 && len(m.Results) == 2 && isByteSlice(m.Results[0].Type) && isError(m.Results[1].Type)
+}
+
+// isUnmarshal reports whether m has the signature func([]byte) error.
+func isUnmarshal(m *gen.MethodModel) bool {
+	return len(m.Params) == 1 && len(m.Results) == 1 && isByteSlice(m.Params[0].Type) && isError(m.Results[0].Type)
+}
+
+func isByteSlice(t types.Type) bool {
+	s, ok := t.Underlying().(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// Generate writes a test file for the package in fs to o containing a
+// roundtrip test for every marshaler pair. It returns an error if no pairs
+// are found.
+func Generate(fs *gen.FileSet, o *gen.Output) error {
+	pairs, err := Pairs(fs)
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		return fmt.Errorf("no marshaler pairs found in package %s", fs.Package.Name())
+	}
+
+	imports := gen.NewImports()
+	generators := make(map[string]*random.Generator)
+	var formats []string
+	var tests bytes.Buffer
+	for _, p := range pairs {
+		// Helpers are shared by all pairs of the same format since the
+		// fields they populate depend on the format's struct tag key.
+		g, ok := generators[p.Format]
+		if !ok {
+			tagKey := strings.ToLower(p.Format)
+			g = random.NewGenerator(fs, imports, random.Options{
+				Prefix: "random" + p.Format,
+				Include: func(f *types.Var, tags gen.Tags) bool {
+					if !f.Exported() {
+						return false
+					}
+					tag, ok := tags.Get(tagKey)
+					return !ok || tag.Name != "-"
+				},
+			})
+			generators[p.Format] = g
+			formats = append(formats, p.Format)
+		}
+		helper := g.Helper(fs.Lookup(p.Type).Type().(*types.Named))
+
+		fmt.Fprintf(&tests, `
+func Test%[1]sRoundtrip%[2]s(t *%[3]s.T) {
+	r := %[4]s.New(%[4]s.NewSource(1))
+	for i := 0; i < %[5]d; i++ {
+		want := %[6]s(r, 0)
+		data, err := want.Marshal%[2]s()
+		if err != nil {
+			t.Fatalf("Marshal%[2]s: %%v", err)
+		}
+		var got %[1]s
+		if err := got.Unmarshal%[2]s(data); err != nil {
+			t.Fatalf("Unmarshal%[2]s: %%v", err)
+		}
+		if !%[7]s.DeepEqual(want, got) {
+			t.Fatalf("roundtrip mismatch\nwant: %%#v\ngot:  %%#v\ndata: %%s", want, got, data)
+		}
+	}
+}
+`, p.Type, p.Format, imports.Add("testing", ""), imports.Add("math/rand", ""), Iterations, helper, imports.Add("reflect", ""))
+	}
+
+	for _, format := range formats {
+		if _, err := generators[format].WriteTo(&tests); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(o, "package %s\n\n", fs.Package.Name())
+	o.Printf("%s\n", imports.Block())
+	o.Write(tests.Bytes())
+	return nil
+}
@@ -0,0 +1,129 @@
+package roundtrip
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type Color int
+
+func (c Color) MarshalText() ([]byte, error) { return []byte(strconv.Itoa(int(c))), nil }
+
+func (c *Color) UnmarshalText(b []byte) error {
+	n, err := strconv.Atoi(string(b))
+	*c = Color(n)
+	return err
+}
+
+type Address struct {
+	Street string
+	Zip    int ` + "`validate:\"min=10000,max=99999\"`" + `
+}
+
+type User struct {
+	Name    string ` + "`json:\"name\" validate:\"min=1,max=8\"`" + `
+	Age     uint8  ` + "`validate:\"max=120\"`" + `
+	Score   float64
+	Tags    []string
+	Attrs   map[string]int
+	Home    *Address
+	Color   Color ` + "`validate:\"oneof=1 2 3\"`" + `
+	Friends []*User
+	Grid    [2]bool
+	Skip    int ` + "`json:\"-\"`" + `
+	secret  string
+}
+
+type userAlias User
+
+func (u User) MarshalJSON() ([]byte, error) { return json.Marshal(userAlias(u)) }
+
+func (u *User) UnmarshalJSON(b []byte) error { return json.Unmarshal(b, (*userAlias)(u)) }
+
+type Partial struct{}
+
+func (Partial) MarshalYAML() ([]byte, error) { return nil, nil }
+`
+
+func TestPairs(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pairs, err := Pairs(fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Pair{{Type: "Color", Format: "Text"}, {Type: "User", Format: "JSON"}}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("got %+v, wanted %+v", pairs, want)
+	}
+}
+
+func TestGenerateNoPairs(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts("package p\ntype T struct{}\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Generate(fs, gen.NewOutput("test")); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+}
+
+func TestGeneratedTestsPass(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/p\n\ngo 1.21\n",
+		"p.go":   testSrc,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("roundtrip")
+	if err := Generate(fs, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "roundtrip_gen_test.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", "-v", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated tests failed: %v\n%s\n%s", err, out, src)
+	}
+	for _, name := range []string{"TestColorRoundtripText", "TestUserRoundtripJSON"} {
+		if !strings.Contains(string(out), "--- PASS: "+name) {
+			t.Errorf("%s did not pass:\n%s", name, out)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// RunCache records the runs of generators built on Runner so that repeating
+// a run whose inputs have not changed returns without parsing and type
+// checking the package, which dominates the cost of most generators. It is
+// intended for repositories that run many generators with go generate, where
+// most runs regenerate identical code.
+//
+// A run is identified by a digest of the generator's Name and Version, its
+// command line arguments, the working directory, the environment set by go
+// generate, GOOS, GOARCH, GOFLAGS and CGO_ENABLED, and the contents of the
+// template given with the -template flag. The Runner's Options, Funcs and
+// Flags are not part of the digest, so a generator whose behaviour they
+// change must change its Version too. After a run writes its outputs the
+// cache records a digest of its inputs, which are the Go source files of the
+// package and of the packages it imports from the same module, the module's
+// go.mod and go.sum files and any files passed to Job.DependsOn, together
+// with its outputs. A later run with the same identity is skipped if none of
+// these files has been added, removed or changed.
+//
+// Entries are small files that are never removed by the cache itself; the
+// directory may be deleted at any time.
+type RunCache struct {
+	// Dir is the directory holding the cache entries.
+	Dir string
+}
+
+// DefaultRunCache returns a RunCache in the gen/runs directory of the user's
+// cache directory.
+func DefaultRunCache() (*RunCache, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &RunCache{Dir: filepath.Join(cache, "gen", "runs")}, nil
+}
+
+// runEntry is the record of a run kept by a RunCache.
+type runEntry struct {
+	Dirs  []string `json:"dirs"`  // package directory followed by its imports from the same module
+	Files []string `json:"files"` // other inputs and the outputs
+	Sum   string   `json:"sum"`   // digest of the package files of Dirs and of Files
+}
+
+// sum returns the digest of the files recorded by e as they are now.
+func (e *runEntry) sum() string {
+	return hashFiles(append(packageFiles(e.Dirs), e.Files...)...)
+}
+
+// fresh reports whether the cache holds the run identified by key and its
+// inputs and outputs are unchanged.
+func (c *RunCache) fresh(key string) bool {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	var e runEntry
+	if err := json.Unmarshal(data, &e); err != nil || len(e.Dirs) == 0 {
+		return false
+	}
+	return e.sum() == e.Sum
+}
+
+// store records the run of job identified by key, which wrote outputs.
+func (c *RunCache) store(key string, job *Job, outputs map[string]*Output) error {
+	dir, err := filepath.Abs(job.FileSet.Dir)
+	if err != nil {
+		return err
+	}
+	e := &runEntry{Dirs: append([]string{dir}, localImportDirs(job.FileSet.Package, dir)...)}
+	for _, f := range job.inputs {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		e.Files = append(e.Files, abs)
+	}
+	for filename := range outputs {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return err
+		}
+		e.Files = append(e.Files, abs)
+	}
+	sort.Strings(e.Files)
+	e.Sum = e.sum()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// path returns the name of the file holding the entry for key.
+func (c *RunCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// cacheEnv lists the environment variables that identify a run.
+var cacheEnv = []string{"GOFILE", "GOLINE", "GOPACKAGE", "GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED"}
+
+// cacheKey returns the key identifying the run of job in the Runner's Cache.
+func (r *Runner) cacheKey(job *Job) (string, error) {
+	version := r.Version
+	if version == "" {
+		var err error
+		if version, err = executableDigest(); err != nil {
+			return "", fmt.Errorf("generator version: %w", err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "name %q\nversion %q\ndir %q\n", r.Name, version, wd)
+	for _, arg := range job.cmdline {
+		fmt.Fprintf(h, "arg %q\n", arg)
+	}
+	for _, name := range cacheEnv {
+		fmt.Fprintf(h, "env %s=%q\n", name, os.Getenv(name))
+	}
+	fmt.Fprintf(h, "inputs %s\n", hashFiles(job.inputs...))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// executableDigest returns a digest of the running executable.
+var executableDigest = sync.OnceValues(func() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+})
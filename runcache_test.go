@@ -0,0 +1,131 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCache(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	schema := filepath.Join(dir, "schema.txt")
+	if err := os.WriteFile(schema, []byte("v1"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runs := 0
+	r := stringerRunner()
+	r.Version = "v1.0.0"
+	r.Cache = &RunCache{Dir: t.TempDir()}
+	generate := r.Generate
+	r.Generate = func(j *Job) error {
+		runs++
+		j.DependsOn(schema)
+		return generate(j)
+	}
+	output := filepath.Join(dir, "p_stringer.go")
+
+	testCases := []struct {
+		name    string
+		args    []string
+		change  func()
+		wantRun bool
+	}{
+		{name: "first", wantRun: true},
+		{name: "unchanged"},
+		{
+			name:    "source changed",
+			change:  func() { appendFile(t, filepath.Join(dir, "color.go"), "\ntype Size int\n") },
+			wantRun: true,
+		},
+		{name: "unchanged after source change"},
+		{
+			name:    "new source file",
+			change:  func() { appendFile(t, filepath.Join(dir, "extra.go"), "package p\n") },
+			wantRun: true,
+		},
+		{
+			name:    "output edited",
+			change:  func() { appendFile(t, output, "// edited\n") },
+			wantRun: true,
+		},
+		{
+			name: "output removed",
+			change: func() {
+				if err := os.Remove(output); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+			wantRun: true,
+		},
+		{
+			name:    "dependency changed",
+			change:  func() { appendFile(t, schema, "v2") },
+			wantRun: true,
+		},
+		{
+			name:    "different arguments",
+			args:    []string{"-type", "Shape"},
+			wantRun: true,
+		},
+		{name: "forced", args: []string{"-force"}, wantRun: true},
+		{
+			name:    "new version",
+			change:  func() { r.Version = "v1.1.0" },
+			wantRun: true,
+		},
+		{name: "unchanged at new version"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.change != nil {
+				tc.change()
+			}
+			before := runs
+			if err := r.Run(append(tc.args, dir)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := runs > before; got != tc.wantRun {
+				t.Errorf("got generator run %v, wanted %v", got, tc.wantRun)
+			}
+			if _, err := os.Stat(output); err != nil {
+				t.Errorf("output missing: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunCacheSkipsDiff(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	runs := 0
+	r := stringerRunner()
+	r.Version = "v1.0.0"
+	r.Cache = &RunCache{Dir: t.TempDir()}
+	generate := r.Generate
+	r.Generate = func(j *Job) error {
+		runs++
+		return generate(j)
+	}
+	if err := r.Run([]string{dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Run([]string{"-diff", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("got %d runs, wanted 2", runs)
+	}
+}
+
+func appendFile(t *testing.T, filename, text string) {
+	t.Helper()
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
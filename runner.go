@@ -0,0 +1,614 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Runner implements the plumbing common to code generators run by go
+// generate: parsing flags, reading the environment set by go generate,
+// loading the package, invoking the generator and writing its outputs. A
+// generator built on Runner is typically a main package whose main function
+// calls Main:
+//
+//	func main() {
+//		r := &gen.Runner{Name: "stringer", Generate: generate}
+//		r.Main()
+//	}
+//
+// The command accepts the flags
+//
+//	-type T,U      the types to generate code for, available as Job.Types
+//	-output file   the file written by the default output
+//	-template file a template, available as Job.Template
+//	-definition file
+//	               load the types from a Definition in file instead of
+//	               from Go source
+//	-only-generator, -only-type
+//	               regenerate a subset of the outputs, see Selection
+//	-patch file    write a unified diff of the changes to file, or to
+//	               standard output if file is -, instead of writing the
+//	               outputs
+//	-report file   write a JSON Report of the changes to file, or to
+//	               standard output if file is -
+//	-diff          write a unified diff of the changes to standard output
+//	               instead of writing the outputs, failing with
+//	               ErrChangesNeeded if there are any
+//	-force         regenerate even if the Cache reports the outputs up to
+//	               date
+//
+// followed by the package directory or the Go source files to load. If none
+// are given the package in the current directory is loaded, which is the
+// directory of the file containing the go:generate directive when run by go
+// generate.
+type Runner struct {
+	// Name is the name of the generator, such as stringer. It is used in the
+	// generated code header of each output, in error messages and in the
+	// name of the default output file.
+	Name string
+
+	// Generate is called with the loaded package to generate code into the
+	// outputs of the job.
+	Generate func(*Job) error
+
+	// Flags, if not nil, is called to register additional flags before the
+	// command line is parsed.
+	Flags func(*flag.FlagSet)
+
+	// Funcs holds additional functions made available to the template
+	// loaded with the -template flag.
+	Funcs template.FuncMap
+
+	// Dialect, if not nil, extends the language of the template loaded with
+	// the -template flag.
+	Dialect *Dialect
+
+	// Options control how the package is loaded.
+	Options []Option
+
+	// Budget limits the amount of code generated into each package. The
+	// zero Budget is unlimited.
+	Budget Budget
+
+	// TypeCheck, if true, type checks the outputs together with the package
+	// before anything is written, so that a run whose generated code does
+	// not compile fails with ErrNotCompiled instead of writing broken files.
+	// See FileSet.CheckCompiles.
+	TypeCheck bool
+
+	// CheckConflicts, if true, checks the outputs for identifiers declared
+	// more than once in their packages before anything is written, so that
+	// a run that would cause a redeclaration fails with a *ConflictError
+	// naming the generators responsible. Files the run would prune are not
+	// counted. See the CheckConflicts function.
+	CheckConflicts bool
+
+	// Prune, if not empty, is a pattern in the syntax of filepath.Match,
+	// such as *_gen.go, matching the names of the files the generator
+	// writes. After a run writes its outputs, the files matching Prune in
+	// the package directory and the directories of the outputs that were
+	// generated by Name but not written by the run are deleted, as
+	// PruneStale does, and the run fails if Name is empty. Nothing is pruned
+	// when a subset of the outputs is selected with -only-generator or
+	// -only-type.
+	Prune string
+
+	// Manifest, if not empty, names a file, such as ManifestName, to which
+	// a Manifest of each run that writes its outputs is written. A relative
+	// name is relative to the package directory. The manifest records the
+	// Go source files of the package, any template or definition file and
+	// any files passed to Job.DependsOn as inputs. Its version is Version,
+	// or a digest of the running executable if Version is empty.
+	Manifest string
+
+	// Version identifies the version of the generator for the Cache. A run
+	// cached by one version is not reused by another. If empty, a digest of
+	// the running executable is used.
+	Version string
+
+	// Cache, if not nil, records the inputs and outputs of each run so that
+	// a run whose inputs and outputs are unchanged since it was last made
+	// returns without loading the package. See RunCache.
+	Cache *RunCache
+
+	// Stdout is where the -diff flag writes its diff and where a patch or
+	// report is written when the -patch or -report flag names the file -.
+	// If nil, os.Stdout is used.
+	Stdout io.Writer
+
+	// Stderr is where Main reports errors and usage. If nil, os.Stderr is
+	// used.
+	Stderr io.Writer
+
+	// Logger, if not nil, reports the progress of a run: the loading of the
+	// package, as WithLogger does, the time taken to generate the outputs
+	// and each file written.
+	Logger *slog.Logger
+}
+
+// Job is a single run of a generator, holding the loaded package, the
+// settings from the command line and environment, and the outputs generated.
+type Job struct {
+	// FileSet holds the loaded package.
+	FileSet *FileSet
+
+	// Types holds the names given with the -type flag. If the flag is not
+	// given and the go:generate directive is attached to a type, Types holds
+	// the name of that type.
+	Types []string
+
+	// Template is the template loaded with the -template flag, or nil if the
+	// flag was not given.
+	Template *TemplateType
+
+	// Env holds the environment set by go generate.
+	Env GenerateEnv
+
+	// Target is the declaration the go:generate directive is attached to,
+	// found with TargetAt, or nil if the generator was not run by go
+	// generate or the directive is not attached to a declaration in the
+	// loaded package, such as a directive that applies to the whole file.
+	Target *Target
+
+	// Args holds the command line arguments remaining after the flags.
+	Args []string
+
+	name    string
+	cmdline []string // the arguments the job was run with
+	output  string
+	patch   string
+	report  string
+	diff    bool
+	force   bool
+	def     string // the definition file given with -definition
+	sel     Selection
+	inputs  []string // files other than Go source read by the job
+	outputs map[string]*Output
+}
+
+// GenerateEnv holds the environment variables set by go generate when it
+// runs a generator. The fields are empty when the generator is run directly.
+type GenerateEnv struct {
+	// File is the base name of the file containing the go:generate
+	// directive, from GOFILE.
+	File string
+
+	// Package is the name of the package containing the directive, from
+	// GOPACKAGE.
+	Package string
+
+	// Line is the line number of the directive, from GOLINE.
+	Line int
+}
+
+// ReadGenerateEnv reads the environment variables set by go generate.
+func ReadGenerateEnv() (GenerateEnv, error) {
+	env := GenerateEnv{
+		File:    os.Getenv("GOFILE"),
+		Package: os.Getenv("GOPACKAGE"),
+	}
+	if line := os.Getenv("GOLINE"); line != "" {
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return GenerateEnv{}, fmt.Errorf("invalid GOLINE %q: %w", line, err)
+		}
+		env.Line = n
+	}
+	return env, nil
+}
+
+// Output returns the output that will be written to filename, creating it if
+// necessary. A relative filename is relative to the package directory. An
+// empty filename names the default output: the file given with the -output
+// flag or, if none was given, a file named after the first type given with
+// the -type flag, the file containing the go:generate directive or the
+// package, followed by the generator's name, such as color_stringer.go.
+func (j *Job) Output(filename string) *Output {
+	if filename == "" {
+		filename = j.defaultOutput()
+	}
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(j.FileSet.Dir, filename)
+	}
+	for name, o := range j.outputs {
+		if samePath(name, filename) {
+			return o
+		}
+	}
+	o := NewOutput(j.name)
+	j.outputs[filename] = o
+	return o
+}
+
+// DependsOn records that the generated code depends on the contents of files
+// other than the Go source files of the package and its imports, such as a
+// schema read by the generator, so that the run is repeated when they change.
+// It matters only when the Runner has a Cache.
+func (j *Job) DependsOn(files ...string) {
+	j.inputs = append(j.inputs, files...)
+}
+
+// Outputs returns the outputs created by the job, keyed by the filename they
+// will be written to.
+func (j *Job) Outputs() map[string]*Output {
+	return j.outputs
+}
+
+// defaultOutput returns the name of the default output file.
+func (j *Job) defaultOutput() string {
+	if j.output != "" {
+		return j.output
+	}
+	var base string
+	switch {
+	case len(j.Types) > 0:
+		base = j.Types[0]
+	case j.Env.File != "":
+		base = strings.TrimSuffix(j.Env.File, ".go")
+	case j.FileSet.Package != nil:
+		base = j.FileSet.Package.Name()
+	}
+	return strings.ToLower(base) + "_" + fileNamePart(j.name) + ".go"
+}
+
+// fileNamePart converts a generator name into a form usable in a file name.
+func fileNamePart(name string) string {
+	name = strings.ToLower(filepath.Base(name))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Main runs the generator with the command line arguments of the process. It
+// reports any error and exits with a non-zero status if the run fails. Errors
+// located in source code are each reported with the offending line.
+func (r *Runner) Main() {
+	if err := r.Run(os.Args[1:]); err != nil {
+		switch errs := Errors(err); {
+		case errors.Is(err, flag.ErrHelp):
+		case len(errs) > 0:
+			for _, e := range errs {
+				fmt.Fprintf(r.stderr(), "%s: %s\n", r.Name, e.Diagnostic())
+			}
+		default:
+			fmt.Fprintf(r.stderr(), "%s: %v\n", r.Name, err)
+		}
+		os.Exit(1)
+	}
+}
+
+// Run runs the generator with the command line arguments args, which exclude
+// the program name. Outputs are written with the all-or-nothing semantics of
+// WriteOutputs after any Selection given on the command line is applied and
+// the outputs are checked against the Runner's Budget. With the -report flag
+// a Report of the changes is written first. With the -diff flag the outputs
+// are not written and the result of CheckOutputs is reported instead, and
+// with the -patch flag a patch produced by Patch is written instead.
+//
+// If the Runner has a Cache, a run that writes its outputs returns without
+// loading the package when the Cache holds a run with the same inputs whose
+// outputs are unchanged on disk, unless the -force flag is given.
+func (r *Runner) Run(args []string) error {
+	job, err := r.parse(args)
+	if err != nil {
+		return err
+	}
+	cached := r.Cache != nil && !job.diff && job.patch == "" && job.report == ""
+	var key string
+	if cached {
+		key, err = r.cacheKey(job)
+		if err != nil {
+			return err
+		}
+		if !job.force && r.Cache.fresh(key) {
+			r.logger().Info("outputs up to date", "generator", r.Name)
+			return nil
+		}
+	}
+	if err := r.load(job); err != nil {
+		return err
+	}
+	outputs, err := job.selected()
+	if err != nil {
+		return err
+	}
+	if err := r.Budget.Check(outputs); err != nil {
+		return err
+	}
+	if r.TypeCheck {
+		sources, err := outputSources(outputs)
+		if err != nil {
+			return err
+		}
+		if err := job.FileSet.CheckCompiles(sources); err != nil {
+			return err
+		}
+	}
+	if r.CheckConflicts {
+		sources, err := outputSources(outputs)
+		if err != nil {
+			return err
+		}
+		var ignore func(string, []byte) bool
+		if job.sel.IsZero() {
+			ignore = prunable(r.Prune, r.Name)
+		}
+		if err := checkConflicts(job.FileSet.opts.buildContext(), sources, outputGenerators(outputs), ignore); err != nil {
+			return err
+		}
+	}
+	if job.report != "" {
+		report, err := NewReport(r.Name, outputs)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := report.WriteJSON(&buf); err != nil {
+			return err
+		}
+		if err := r.writeArtifact(job.report, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if job.diff {
+		diff, err := CheckOutputs(outputs)
+		if len(diff) > 0 {
+			if _, werr := r.stdout().Write(diff); werr != nil {
+				return werr
+			}
+		}
+		return err
+	}
+	if job.patch != "" {
+		patch, err := Patch(outputs)
+		if err != nil {
+			return err
+		}
+		return r.writeArtifact(job.patch, patch)
+	}
+	if err := WriteOutputs(outputs); err != nil {
+		return err
+	}
+	for _, filename := range SortedKeys(outputs) {
+		r.logger().Info("wrote file", "file", filename)
+	}
+	if r.Prune != "" && job.sel.IsZero() {
+		removed, err := pruneOutputs(job.FileSet.Dir, r.Prune, r.Name, SortedKeys(outputs))
+		for _, filename := range removed {
+			r.logger().Info("removed stale file", "file", filename)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if r.Manifest != "" {
+		if err := r.writeManifest(job, outputs); err != nil {
+			return err
+		}
+	}
+	if cached {
+		return r.Cache.store(key, job, outputs)
+	}
+	return nil
+}
+
+// writeManifest writes the manifest of job, which wrote outputs.
+func (r *Runner) writeManifest(job *Job, outputs map[string]*Output) error {
+	filename := r.Manifest
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(job.FileSet.Dir, filename)
+	}
+	version := r.Version
+	if version == "" {
+		var err error
+		if version, err = executableDigest(); err != nil {
+			return fmt.Errorf("generator version: %w", err)
+		}
+	}
+	// Files loaded from memory, such as stubs, are not recorded.
+	var inputs []string
+	for _, f := range job.FileSet.Files {
+		if _, ok := job.FileSet.contents[f]; !ok {
+			inputs = append(inputs, f)
+		}
+	}
+	inputs = append(inputs, job.inputs...)
+	m, err := NewManifest(filepath.Dir(filename), r.Name, version, job.cmdline, inputs, SortedKeys(outputs))
+	if err != nil {
+		return err
+	}
+	if err := m.WriteFile(filename); err != nil {
+		return err
+	}
+	r.logger().Info("wrote manifest", "file", filename)
+	return nil
+}
+
+// writeArtifact writes data produced by a run, such as a patch, to filename,
+// or to standard output if filename is -.
+func (r *Runner) writeArtifact(filename string, data []byte) error {
+	if filename == "-" {
+		_, err := r.stdout().Write(data)
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// Prepare parses args, loads the package and calls Generate, returning the
+// job with the generated outputs without writing them.
+func (r *Runner) Prepare(args []string) (*Job, error) {
+	job, err := r.parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// parse parses args and reads the environment and the template, returning a
+// job whose package has not been loaded.
+func (r *Runner) parse(args []string) (*Job, error) {
+	if r.Generate == nil {
+		return nil, errors.New("no Generate function")
+	}
+
+	var (
+		types, output, tmplFile, patch, report, def string
+		sel                                         Selection
+		diff, force                                 bool
+	)
+	fset := flag.NewFlagSet(r.Name, flag.ContinueOnError)
+	fset.SetOutput(r.stderr())
+	fset.StringVar(&types, "type", "", "comma separated list of type names")
+	fset.StringVar(&output, "output", "", "output file name")
+	fset.StringVar(&tmplFile, "template", "", "template file")
+	fset.StringVar(&def, "definition", "", "load the types from the definition `file` (YAML, TOML or JSON) instead of Go source")
+	fset.BoolVar(&diff, "diff", false, "write a diff of the changes to standard output instead of writing the outputs, failing if there are any")
+	fset.StringVar(&report, "report", "", "write a JSON report of the changes to `file` (- for standard output)")
+	fset.StringVar(&patch, "patch", "", "write a unified diff of the changes to `file` (- for standard output) instead of writing the outputs")
+	fset.BoolVar(&force, "force", false, "regenerate the outputs even if the run cache reports them up to date")
+	sel.RegisterFlags(fset)
+	if r.Flags != nil {
+		r.Flags(fset)
+	}
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "usage: %s [flags] [dir | files...]\n", r.Name)
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return nil, err
+	}
+
+	env, err := ReadGenerateEnv()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		Types:   splitList(types),
+		Env:     env,
+		Args:    fset.Args(),
+		name:    r.Name,
+		cmdline: args,
+		output:  output,
+		patch:   patch,
+		report:  report,
+		diff:    diff,
+		force:   force,
+		def:     def,
+		sel:     sel,
+		outputs: make(map[string]*Output),
+	}
+
+	if tmplFile != "" {
+		text, err := os.ReadFile(tmplFile)
+		if err != nil {
+			return nil, err
+		}
+		if r.Dialect != nil {
+			job.Template, err = NewTemplateTypeDialect(filepath.Base(tmplFile), string(text), r.Funcs, r.Dialect)
+		} else {
+			job.Template, err = NewTemplateType(filepath.Base(tmplFile), string(text), r.Funcs)
+		}
+		if err != nil {
+			return nil, err
+		}
+		job.inputs = append(job.inputs, tmplFile)
+	}
+	if def != "" {
+		job.inputs = append(job.inputs, def)
+	}
+	return job, nil
+}
+
+// load loads the package of job and calls Generate.
+func (r *Runner) load(job *Job) error {
+	opts := r.Options[:len(r.Options):len(r.Options)]
+	if r.Logger != nil {
+		opts = append(opts, WithLogger(r.Logger))
+	}
+	var err error
+	if job.def != "" {
+		job.FileSet, err = loadDefinition(job.def, opts...)
+	} else {
+		job.FileSet, err = NewFileSet(job.Args, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	if job.Env.File != "" && job.Env.Line > 0 {
+		if t, err := job.FileSet.TargetAt(job.Env.File, job.Env.Line); err == nil {
+			job.Target = t
+			if len(job.Types) == 0 && t.IsType() {
+				job.Types = []string{t.Name}
+			}
+		}
+	}
+	for _, name := range job.Types {
+		if _, ok := job.FileSet.Type(name); !ok {
+			return fmt.Errorf("type %s not found in package %s", name, job.FileSet.Package.Name())
+		}
+	}
+
+	start := time.Now()
+	if err := r.Generate(job); err != nil {
+		return err
+	}
+	r.logger().Info("generated outputs", "generator", r.Name, "outputs", len(job.outputs), "duration", time.Since(start))
+	return nil
+}
+
+// loadDefinition creates a FileSet from the definition in filename, as if
+// its types were declared in a Go source file of the same name alongside it.
+func loadDefinition(filename string, opts ...Option) (*FileSet, error) {
+	d, err := ReadDefinition(filename)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".go"
+	return NewFileSetFromDefinition(d, filepath.Dir(filename), base, opts...)
+}
+
+// selected returns the outputs of the job selected on the command line.
+func (j *Job) selected() (map[string]*Output, error) {
+	if len(j.outputs) == 0 {
+		return nil, errors.New("no output generated")
+	}
+	return j.sel.Filter(j.outputs), nil
+}
+
+func (r *Runner) stdout() io.Writer {
+	if r.Stdout != nil {
+		return r.Stdout
+	}
+	return os.Stdout
+}
+
+func (r *Runner) logger() *slog.Logger {
+	if r.Logger == nil {
+		return discardLogger
+	}
+	return r.Logger
+}
+
+func (r *Runner) stderr() io.Writer {
+	if r.Stderr != nil {
+		return r.Stderr
+	}
+	return os.Stderr
+}
@@ -0,0 +1,250 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRunnerPackage writes a small package for a Runner to load and returns
+// its directory.
+func writeRunnerPackage(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":   "module example.com/p\n\ngo 1.21\n",
+		"color.go": "package p\n\n//go:generate stringer -type Color\n\ntype Color int\n\ntype Shape int\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return dir
+}
+
+// stringerRunner returns a Runner that generates a String method for each
+// type given with the -type flag.
+func stringerRunner() *Runner {
+	return &Runner{
+		Name: "stringer",
+		Generate: func(j *Job) error {
+			o := j.Output("")
+			o.Printf("package %s\n\n", j.FileSet.Package.Name())
+			for _, name := range j.Types {
+				o.Printf("func (%s) String() string { return %q }\n\n", name, name)
+			}
+			return nil
+		},
+	}
+}
+
+func TestRunner(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	t.Setenv("GOFILE", "color.go")
+	t.Setenv("GOPACKAGE", "p")
+	t.Setenv("GOLINE", "3")
+
+	r := stringerRunner()
+	if err := r.Run([]string{"-type", "Color", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "color_stringer.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"// Code generated by stringer; DO NOT EDIT.", `func (Color) String() string { return "Color" }`} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("got:\n%s\nwanted it to contain %q", got, want)
+		}
+	}
+
+	if err := r.Run([]string{"-type", "Shape", "-output", "shape_string.go", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "shape_string.go")); err != nil {
+		t.Errorf("output named by -output was not written: %v", err)
+	}
+
+	if err := r.Run([]string{"-type", "Missing", dir}); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("got error %v, wanted one naming the missing type", err)
+	}
+
+	dir = writeRunnerPackage(t)
+	r.Budget = Budget{MaxDecls: 1}
+	var be *BudgetError
+	if err := r.Run([]string{"-type", "Color,Shape", "-output", "budget.go", dir}); !errors.As(err, &be) {
+		t.Errorf("got error %v, wanted a *BudgetError", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "budget.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("output exceeding budget was written")
+	}
+
+	dir = writeRunnerPackage(t)
+	r = stringerRunner()
+	r.TypeCheck = true
+	if err := r.Run([]string{"-type", "Color", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Run([]string{"-type", "Missing", "-output", "missing.go", dir}); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+	if err := r.Run([]string{"-type", "Color", "-output", "dup.go", dir}); !errors.Is(err, ErrNotCompiled) {
+		t.Errorf("got error %v, wanted %v", err, ErrNotCompiled)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dup.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("output that does not compile was written")
+	}
+
+	dir = writeRunnerPackage(t)
+	r = stringerRunner()
+	r.Prune = "*_stringer.go"
+	for _, args := range [][]string{
+		{"-type", "Color", "-output", "color_stringer.go", dir},
+		{"-type", "Shape", "-output", "shape_stringer.go", "-only-type", "Color", dir},
+	} {
+		if err := r.Run(args); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "color_stringer.go")); err != nil {
+		t.Errorf("output pruned by a run selecting a subset of outputs: %v", err)
+	}
+	if err := r.Run([]string{"-type", "Shape", "-output", "shape_stringer.go", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "color_stringer.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale output not pruned")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "shape_stringer.go")); err != nil {
+		t.Errorf("output of the run pruned: %v", err)
+	}
+
+	dir = writeRunnerPackage(t)
+	r = stringerRunner()
+	r.CheckConflicts = true
+	r.Prune = "*_stringer.go"
+	if err := os.WriteFile(filepath.Join(dir, "shape.go"), []byte("package p\n\nfunc (Shape) String() string { return \"\" }\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ce *ConflictError
+	if err := r.Run([]string{"-type", "Shape", "-output", "shape_stringer.go", dir}); !errors.As(err, &ce) {
+		t.Errorf("got error %v, wanted a *ConflictError", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "shape_stringer.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("conflicting output was written")
+	}
+	// A declaration moving to a new file does not conflict with the old
+	// file, which is pruned.
+	if err := r.Run([]string{"-type", "Color", "-output", "color_stringer.go", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Run([]string{"-type", "Color", "-output", "moved_stringer.go", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir = writeRunnerPackage(t)
+	r = stringerRunner()
+	r.Version = "v1.2.3"
+	r.Manifest = ManifestName
+	if err := r.Run([]string{"-type", "Color", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Generator != "stringer" || m.Version != "v1.2.3" || len(m.Inputs) != 1 || m.Inputs[0].Path != "color.go" || len(m.Outputs) != 1 || m.Outputs[0].Path != "color_stringer.go" {
+		t.Errorf("got manifest %+v, wanted one recording color.go and color_stringer.go", m)
+	}
+}
+
+func TestRunnerPrepare(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	tmpl := filepath.Join(t.TempDir(), "string.tmpl")
+	if err := os.WriteFile(tmpl, []byte("package {{.}}\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GOFILE", "color.go")
+	t.Setenv("GOPACKAGE", "p")
+	t.Setenv("GOLINE", "3")
+
+	var verbose bool
+	r := &Runner{
+		Name: "go run ./cmd/gen",
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&verbose, "v", false, "verbose")
+		},
+		Generate: func(j *Job) error {
+			src, err := j.Template.Render(j.FileSet.Package.Name())
+			if err != nil {
+				return err
+			}
+			j.Output("").Write(src)
+			j.Output("extra.go").Printf("package p\n")
+			j.Output(filepath.Join(j.FileSet.Dir, "extra.go")).Printf("\nconst X = 1\n")
+			return nil
+		},
+	}
+	job, err := r.Prepare([]string{"-v", "-template", tmpl, dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verbose {
+		t.Errorf("additional flag was not parsed")
+	}
+	if want := (GenerateEnv{File: "color.go", Package: "p", Line: 3}); job.Env != want {
+		t.Errorf("got env %+v, wanted %+v", job.Env, want)
+	}
+	outputs := job.Outputs()
+	if len(outputs) != 2 {
+		t.Fatalf("got %d outputs, wanted 2", len(outputs))
+	}
+	if _, ok := outputs[filepath.Join(dir, "color_gen.go")]; !ok {
+		t.Errorf("got outputs %v, wanted default output named after the file and generator", outputs)
+	}
+	if src := outputs[filepath.Join(dir, "extra.go")].Bytes(); string(src) != "package p\n\nconst X = 1\n" {
+		t.Errorf("got extra output %q", src)
+	}
+
+	t.Setenv("GOLINE", "x")
+	if _, err := r.Prepare([]string{dir}); err == nil {
+		t.Errorf("got no error for invalid GOLINE, wanted one")
+	}
+
+	var stderr bytes.Buffer
+	r.Stderr = &stderr
+	if _, err := r.Prepare([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("got error %v, wanted %v", err, flag.ErrHelp)
+	}
+	if !strings.Contains(stderr.String(), "usage: go run ./cmd/gen") {
+		t.Errorf("got usage %q", stderr.String())
+	}
+}
+
+func TestRunnerLogger(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	var buf bytes.Buffer
+	r := stringerRunner()
+	r.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if err := r.Run([]string{"-type", "Color", dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	log := buf.String()
+	for _, want := range []string{
+		`msg="parsed file" file=` + filepath.Join(dir, "color.go"),
+		`msg="loaded package" dir=` + dir + " package=p files=1 types=2 duration=",
+		`msg="generated outputs" generator=stringer outputs=1 duration=`,
+		`msg="wrote file" file=` + filepath.Join(dir, "color_stringer.go"),
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log does not contain %q:\n%s", want, log)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gen
+
+import (
+	"flag"
+	"strings"
+)
+
+// Selection restricts regeneration to the outputs owned by particular
+// generators or that declare code for particular types, so that a small
+// change to one generator does not require every generated file in a project
+// to be rewritten. The zero Selection selects every output.
+//
+// Ownership is determined from the outputs themselves and from the ownership
+// trailers of the files they would replace, so an output is also selected if
+// the file it replaces was produced by a selected generator or declared code
+// for a selected type.
+type Selection struct {
+	// Generators holds the names of the generators whose outputs are
+	// selected.
+	Generators []string
+
+	// Types holds the names of the types whose generated code is selected.
+	// An output declares code for a type if it declares the type itself or
+	// any of its methods.
+	Types []string
+}
+
+// RegisterFlags registers the -only-generator and -only-type flags on fset,
+// each taking a comma separated list of names, to populate s.
+func (s *Selection) RegisterFlags(fset *flag.FlagSet) {
+	fset.Func("only-generator", "regenerate only the outputs of the named generators (comma separated)", func(v string) error {
+		s.Generators = append(s.Generators, splitList(v)...)
+		return nil
+	})
+	fset.Func("only-type", "regenerate only the code generated for the named types (comma separated)", func(v string) error {
+		s.Types = append(s.Types, splitList(v)...)
+		return nil
+	})
+}
+
+// IsZero reports whether s selects every output.
+func (s Selection) IsZero() bool {
+	return len(s.Generators) == 0 && len(s.Types) == 0
+}
+
+// Selects reports whether the output o, which will be written to filename,
+// is selected. An output is selected if it matches both the generators and
+// the types of s, where an empty list matches every output.
+func (s Selection) Selects(filename string, o *Output) bool {
+	if s.IsZero() {
+		return true
+	}
+
+	ow := Ownership{}
+	if existing, err := ReadFileOwnership(filename); err == nil {
+		for name, g := range existing {
+			ow[name] = g
+		}
+	}
+	if src, err := o.Source(); err == nil {
+		if current, err := o.ownership(src); err == nil {
+			for name, g := range current {
+				ow[name] = g
+			}
+		}
+	}
+
+	return s.matchGenerators(o, ow) && s.matchTypes(ow)
+}
+
+func (s Selection) matchGenerators(o *Output, ow Ownership) bool {
+	if len(s.Generators) == 0 {
+		return true
+	}
+	for _, g := range s.Generators {
+		if g == o.Generator {
+			return true
+		}
+		for _, owner := range ow {
+			if g == owner {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s Selection) matchTypes(ow Ownership) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for name := range ow {
+		base, _, _ := strings.Cut(name, ".")
+		for _, t := range s.Types {
+			if base == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter returns the outputs, keyed by the filename they will be written to,
+// that are selected by s.
+func (s Selection) Filter(outputs map[string]*Output) map[string]*Output {
+	selected := make(map[string]*Output, len(outputs))
+	for filename, o := range outputs {
+		if s.Selects(filename, o) {
+			selected[filename] = o
+		}
+	}
+	return selected
+}
+
+// splitList splits a comma separated list, omitting empty elements.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
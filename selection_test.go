@@ -0,0 +1,105 @@
+package gen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSelection(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.go")
+	src := "// Code generated by stringer; DO NOT EDIT.\n\npackage p\n\nfunc (Shape) String() string { return \"\" }\n\n//gen:owner stringer Shape.String\n"
+	if err := os.WriteFile(existing, []byte(src), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	colors := NewOutput("enumer")
+	colors.Printf("package p\n\ntype Color int\n\nfunc (c Color) String() string { return \"\" }\n")
+	colors.Own("stringer", "Color.String")
+
+	mocks := NewOutput("mockgen")
+	mocks.Printf("package p\n\ntype MockStore struct{}\n")
+
+	shapes := NewOutput("enumer")
+	shapes.Printf("package p\n\ntype Unused int\n")
+
+	outputs := map[string]*Output{
+		filepath.Join(dir, "colors.go"): colors,
+		filepath.Join(dir, "mocks.go"):  mocks,
+		existing:                        shapes,
+	}
+
+	testCases := []struct {
+		name string
+		sel  Selection
+		want []string
+	}{
+		{
+			name: "zero",
+			want: []string{"colors.go", "existing.go", "mocks.go"},
+		},
+		{
+			name: "generator",
+			sel:  Selection{Generators: []string{"mockgen"}},
+			want: []string{"mocks.go"},
+		},
+		{
+			name: "owned declaration",
+			sel:  Selection{Generators: []string{"stringer"}},
+			want: []string{"colors.go", "existing.go"},
+		},
+		{
+			name: "type",
+			sel:  Selection{Types: []string{"Color", "MockStore"}},
+			want: []string{"colors.go", "mocks.go"},
+		},
+		{
+			name: "method of type in existing file",
+			sel:  Selection{Types: []string{"Shape"}},
+			want: []string{"existing.go"},
+		},
+		{
+			name: "generator and type",
+			sel:  Selection{Generators: []string{"enumer"}, Types: []string{"Unused"}},
+			want: []string{"existing.go"},
+		},
+		{
+			name: "no match",
+			sel:  Selection{Types: []string{"Missing"}},
+			want: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for filename := range tc.sel.Filter(outputs) {
+				got = append(got, filepath.Base(filename))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectionRegisterFlags(t *testing.T) {
+	var sel Selection
+	fset := flag.NewFlagSet("gen", flag.ContinueOnError)
+	sel.RegisterFlags(fset)
+	if err := fset.Parse([]string{"-only-generator", "enumer, stringer", "-only-type", "Color", "-only-type", "Shape,"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Selection{Generators: []string{"enumer", "stringer"}, Types: []string{"Color", "Shape"}}
+	if !reflect.DeepEqual(sel, want) {
+		t.Errorf("got %+v, wanted %+v", sel, want)
+	}
+	if sel.IsZero() {
+		t.Errorf("got zero selection after parsing flags")
+	}
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"fmt"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionBump is the part of a semantic version incremented by a release.
+type VersionBump int
+
+const (
+	// PatchBump increments the patch version, as for a release without API
+	// changes.
+	PatchBump VersionBump = iota
+
+	// MinorBump increments the minor version, as for a release that only
+	// adds to the API.
+	MinorBump
+
+	// MajorBump increments the major version, as for a release that removes
+	// or changes symbols of the API.
+	MajorBump
+)
+
+func (b VersionBump) String() string {
+	switch b {
+	case PatchBump:
+		return "patch"
+	case MinorBump:
+		return "minor"
+	case MajorBump:
+		return "major"
+	}
+	return fmt.Sprintf("VersionBump(%d)", int(b))
+}
+
+// RequiredBump returns the smallest version bump that semantic versioning
+// permits for a release with the given API changes: a major bump if any
+// symbol was removed or changed, a minor bump if symbols were only added and
+// a patch bump otherwise.
+func RequiredBump(changes []APIChange) VersionBump {
+	bump := PatchBump
+	for _, c := range changes {
+		switch c.Kind {
+		case Added:
+			bump = max(bump, MinorBump)
+		case Removed, Changed:
+			return MajorBump
+		}
+	}
+	return bump
+}
+
+// VersionError is returned by CheckVersion when a release's version does not
+// reflect its API changes.
+type VersionError struct {
+	// Old and New are the versions of the previous and new releases.
+	Old, New string
+
+	// Bump is the version bump made by the new release.
+	Bump VersionBump
+
+	// Required is the version bump required by the API changes.
+	Required VersionBump
+
+	// Changes holds the API changes that require the larger bump.
+	Changes []APIChange
+}
+
+func (e *VersionError) Error() string {
+	msg := fmt.Sprintf("%s to %s is a %s release but the API changes require a %s release", e.Old, e.New, e.Bump, e.Required)
+	if len(e.Changes) > 0 {
+		msg += ": " + e.Changes[0].String()
+	}
+	if len(e.Changes) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Changes)-1)
+	}
+	return msg
+}
+
+// CheckVersion reports whether the release of version newVersion following
+// oldVersion, with the given API changes, follows semantic versioning as
+// used by Go modules. Versions are written with a leading v, such as v1.2.3.
+// A release with incompatible changes needs a new major version, except
+// that before v1.0.0 the API is not stable and a new minor version is
+// enough. It returns a *VersionError if the version bump is too small, and
+// an error if either version is invalid or newVersion does not follow
+// oldVersion.
+func CheckVersion(oldVersion, newVersion string, changes []APIChange) error {
+	for _, v := range []string{oldVersion, newVersion} {
+		if !semver.IsValid(v) {
+			return fmt.Errorf("invalid semantic version %q", v)
+		}
+	}
+	if semver.Compare(newVersion, oldVersion) <= 0 {
+		return fmt.Errorf("version %s does not follow %s", newVersion, oldVersion)
+	}
+
+	bump := PatchBump
+	switch {
+	case semver.Major(newVersion) != semver.Major(oldVersion):
+		bump = MajorBump
+	case semver.MajorMinor(newVersion) != semver.MajorMinor(oldVersion):
+		bump = MinorBump
+	}
+	required := RequiredBump(changes)
+	if required == MajorBump && semver.Major(oldVersion) == "v0" {
+		required = MinorBump
+	}
+	if bump >= required {
+		return nil
+	}
+
+	var culprits []APIChange
+	for _, c := range changes {
+		if required == MinorBump || c.Kind != Added {
+			culprits = append(culprits, c)
+		}
+	}
+	return &VersionError{Old: oldVersion, New: newVersion, Bump: bump, Required: required, Changes: culprits}
+}
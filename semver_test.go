@@ -0,0 +1,70 @@
+package gen
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequiredBump(t *testing.T) {
+	testCases := []struct {
+		name    string
+		changes []APIChange
+		want    VersionBump
+	}{
+		{name: "none", want: PatchBump},
+		{name: "added", changes: []APIChange{{Kind: Added, Symbol: "F"}}, want: MinorBump},
+		{name: "changed", changes: []APIChange{{Kind: Added, Symbol: "F"}, {Kind: Changed, Symbol: "G"}}, want: MajorBump},
+		{name: "removed", changes: []APIChange{{Kind: Removed, Symbol: "G"}}, want: MajorBump},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RequiredBump(tc.changes); got != tc.want {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	added := []APIChange{{Kind: Added, Symbol: "F", New: "func F()"}}
+	removed := []APIChange{{Kind: Added, Symbol: "F", New: "func F()"}, {Kind: Removed, Symbol: "G", Old: "func G()"}}
+
+	testCases := []struct {
+		name     string
+		old, new string
+		changes  []APIChange
+		required VersionBump // if not PatchBump, a *VersionError is wanted
+		invalid  bool
+	}{
+		{name: "patch", old: "v1.2.3", new: "v1.2.4"},
+		{name: "minor", old: "v1.2.3", new: "v1.3.0", changes: added},
+		{name: "major", old: "v1.2.3", new: "v2.0.0", changes: removed},
+		{name: "unstable", old: "v0.2.3", new: "v0.3.0", changes: removed},
+		{name: "patch with additions", old: "v1.2.3", new: "v1.2.4", changes: added, required: MinorBump},
+		{name: "minor with removals", old: "v1.2.3", new: "v1.3.0", changes: removed, required: MajorBump},
+		{name: "unstable patch with removals", old: "v0.2.3", new: "v0.2.4", changes: removed, required: MinorBump},
+		{name: "invalid", old: "1.2.3", new: "v1.2.4", invalid: true},
+		{name: "not later", old: "v1.2.3", new: "v1.2.3", invalid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckVersion(tc.old, tc.new, tc.changes)
+			var ve *VersionError
+			switch {
+			case tc.invalid:
+				if err == nil || errors.As(err, &ve) {
+					t.Errorf("got error %v, wanted one for invalid versions", err)
+				}
+			case tc.required != PatchBump:
+				if !errors.As(err, &ve) {
+					t.Fatalf("got error %v, wanted a *VersionError", err)
+				}
+				if ve.Required != tc.required || len(ve.Changes) == 0 {
+					t.Errorf("got %+v, wanted required bump %v with the changes requiring it", ve, tc.required)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SizeImpact describes how much of a package's compiled size is attributable
+// to its generated files.
+type SizeImpact struct {
+	// Dir is the directory of the package.
+	Dir string
+
+	// Generated holds the names of the generated files in the package.
+	Generated []string
+
+	// With is the size in bytes of the compiled package.
+	With int64
+
+	// Without is the size in bytes of the compiled package when the
+	// generated files are excluded.
+	Without int64
+}
+
+// Delta returns the number of bytes the generated files add to the compiled
+// package.
+func (s *SizeImpact) Delta() int64 {
+	return s.With - s.Without
+}
+
+// String summarises the size impact.
+func (s *SizeImpact) String() string {
+	pct := 0.0
+	if s.With > 0 {
+		pct = 100 * float64(s.Delta()) / float64(s.With)
+	}
+	return fmt.Sprintf("%s: %d bytes with %d generated files, %d bytes without, generated code accounts for %d bytes (%.1f%%)",
+		s.Dir, s.With, len(s.Generated), s.Without, s.Delta(), pct)
+}
+
+// MeasureSizeImpact compiles the package in dir twice, once as it is and once
+// with every file carrying the generated code header excluded, and reports
+// the difference in compiled size. The package must still compile without its
+// generated files. It requires the go command.
+func MeasureSizeImpact(dir string) (*SizeImpact, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SizeImpact{Dir: dir}
+	overlay := struct{ Replace map[string]string }{Replace: map[string]string{}}
+
+	matches, err := filepath.Glob(filepath.Join(absDir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		if strings.HasSuffix(m, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(m)
+		if err != nil {
+			return nil, err
+		}
+		if IsGenerated(src) {
+			s.Generated = append(s.Generated, filepath.Base(m))
+			overlay.Replace[m] = ""
+		}
+	}
+
+	s.With, err = compiledSize(absDir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Generated) == 0 {
+		s.Without = s.With
+		return s, nil
+	}
+
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "gen-overlay-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	s.Without, err = compiledSize(absDir, f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("package does not build without generated files: %w", err)
+	}
+	return s, nil
+}
+
+// compiledSize builds the package in dir, optionally using an overlay file, and
+// returns the size of the compiled package archive.
+func compiledSize(dir, overlay string) (int64, error) {
+	args := []string{"list", "-export", "-f", "{{.Export}}"}
+	if overlay != "" {
+		args = append(args, "-overlay", overlay)
+	}
+	args = append(args, ".")
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	export := strings.TrimSpace(stdout.String())
+	if export == "" {
+		return 0, fmt.Errorf("no compiled package produced for %s", dir)
+	}
+	info, err := os.Stat(export)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,55 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMeasureSizeImpact(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	var table strings.Builder
+	table.WriteString("// Code generated by test; DO NOT EDIT.\n\npackage p\n\nvar table = map[string]int{\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&table, "\t\"key%d\": %d,\n", i, i)
+	}
+	table.WriteString("}\n\nfunc Lookup(k string) int { return table[k] }\n")
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":       "package p\n\nfunc X() int { return 1 }\n",
+		"table.go":   table.String(),
+		"p_test.go":  "// Code generated by test; DO NOT EDIT.\n\npackage p\n",
+		"manual.go":  "package p\n\n// Code generated by hand, not really\n",
+		"sub/sub.go": "// Code generated by test; DO NOT EDIT.\n\npackage sub\n",
+	})
+
+	s, err := MeasureSizeImpact(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(s.Generated) != 1 || s.Generated[0] != "table.go" {
+		t.Errorf("got generated files %+v, wanted [table.go]", s.Generated)
+	}
+	if s.Delta() <= 0 {
+		t.Errorf("got delta %d, wanted positive: %s", s.Delta(), s)
+	}
+}
+
+func TestMeasureSizeImpactBroken(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go":     "package p\n\nvar Y = X\n",
+		"p_gen.go": "// Code generated by test; DO NOT EDIT.\n\npackage p\n\nvar X = 1\n",
+	})
+
+	if _, err := MeasureSizeImpact(dir); err == nil {
+		t.Errorf("got no error for package depending on generated code")
+	}
+}
@@ -0,0 +1,144 @@
+package gen
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Clone returns a snapshot of the type model: a deep copy of the model and
+// of its fields and type parameters.
+//
+// Models returned by the methods of FileSet may be read concurrently by any
+// number of goroutines. They share the parsed syntax trees and type checked
+// objects of the FileSet, which must be treated as read only. Code that
+// modifies a model, such as a template function that sorts the fields of a
+// model in place or memoizes results in it, must work on a snapshot so that
+// templates executing concurrently for the same model do not race. A
+// snapshot shares nothing with the original that can be modified through the
+// model.
+func (m *TypeModel) Clone() *TypeModel {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.TypeParams = cloneTypeParams(m.TypeParams)
+	if m.Fields != nil {
+		c.Fields = make([]*FieldModel, len(m.Fields))
+		for i, f := range m.Fields {
+			c.Fields[i] = f.Clone()
+		}
+	}
+	return &c
+}
+
+// Clone returns a snapshot of the field model: a deep copy of the model and
+// of its path and tags.
+func (m *FieldModel) Clone() *FieldModel {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.Path = cloneSlice(m.Path)
+	if m.Tags != nil {
+		c.Tags = make(Tags, len(m.Tags))
+		for i, t := range m.Tags {
+			t.Options = cloneSlice(t.Options)
+			c.Tags[i] = t
+		}
+	}
+	return &c
+}
+
+// Clone returns a snapshot of the function model: a deep copy of the model
+// and of its type parameters, parameters and results.
+func (m *FuncModel) Clone() *FuncModel {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.TypeParams = cloneTypeParams(m.TypeParams)
+	c.Params = cloneParams(m.Params)
+	c.Results = cloneParams(m.Results)
+	return &c
+}
+
+// Clone returns a snapshot of the method model: a deep copy of the model and
+// of its path, parameters and results.
+func (m *MethodModel) Clone() *MethodModel {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.Path = cloneSlice(m.Path)
+	c.Params = cloneParams(m.Params)
+	c.Results = cloneParams(m.Results)
+	return &c
+}
+
+func cloneTypeParams(tps []*TypeParamModel) []*TypeParamModel {
+	if tps == nil {
+		return nil
+	}
+	c := make([]*TypeParamModel, len(tps))
+	for i, tp := range tps {
+		v := *tp
+		c[i] = &v
+	}
+	return c
+}
+
+func cloneParams(ps []*ParamModel) []*ParamModel {
+	if ps == nil {
+		return nil
+	}
+	backing := make([]ParamModel, len(ps))
+	c := make([]*ParamModel, len(ps))
+	for i, p := range ps {
+		backing[i] = *p
+		c[i] = &backing[i]
+	}
+	return c
+}
+
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append([]T(nil), s...)
+}
+
+// RenderEach applies the root template to each element of data concurrently
+// and returns the generated source code for each, in the same order. The
+// template may be executed concurrently since each execution uses its own
+// copy of the template. Elements of data are passed to the template as given,
+// so elements that share models should be snapshots made with Clone if the
+// template or its functions modify them. The first error encountered, in the
+// order of data, is returned.
+func (tt *TemplateType) RenderEach(data []any) ([][]byte, error) {
+	results := make([][]byte, len(data))
+	errs := make([]error, len(data))
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for n := min(runtime.GOMAXPROCS(0), len(data)); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i], errs[i] = tt.Render(data[i])
+			}
+		}()
+	}
+	for i := range data {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,107 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTypeModelClone(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type Pair[K comparable, V any] struct {
+			Key   K ` + "`json:\"key,omitempty\"`" + `
+			Value V
+		}
+
+		func Swap[T any](a, b T) (T, T) { return b, a }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, _ := fs.Type("Pair")
+	c := m.Clone()
+	if !reflect.DeepEqual(c, m) {
+		t.Fatalf("got clone %+v, wanted %+v", c, m)
+	}
+
+	c.Fields[0].Name = "Changed"
+	c.Fields[0].Tags[0].Options[0] = "string"
+	c.Fields[0], c.Fields[1] = c.Fields[1], c.Fields[0]
+	c.TypeParams[0].Name = "X"
+	if m.Fields[0].Name != "Key" || m.Fields[0].Tags[0].Options[0] != "omitempty" || m.TypeParams[0].Name != "K" {
+		t.Errorf("modifying the clone changed the original: %+v", m)
+	}
+
+	fm, _ := fs.Func("Swap")
+	fc := fm.Clone()
+	fc.Params[0].Name = "x"
+	fc.Results = fc.Results[:1]
+	fc.TypeParams[0].Name = "U"
+	if fm.Params[0].Name != "a" || len(fm.Results) != 2 || fm.TypeParams[0].Name != "T" {
+		t.Errorf("modifying the clone changed the original: %+v", fm)
+	}
+
+	var nilType *TypeModel
+	if nilType.Clone() != nil {
+		t.Errorf("got non-nil clone of nil model")
+	}
+}
+
+func TestRenderEach(t *testing.T) {
+	var decls []string
+	for i := 0; i < 50; i++ {
+		decls = append(decls, fmt.Sprintf("type T%d struct { C, B, A int }", i))
+	}
+	fs, err := NewFileSetFromTexts("package p\n\n" + strings.Join(decls, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// sortFields modifies the model it is given, which is safe only because
+	// each execution receives its own snapshot.
+	funcs := template.FuncMap{
+		"sortFields": func(m *TypeModel) []*FieldModel {
+			sort.Slice(m.Fields, func(i, j int) bool { return m.Fields[i].Name < m.Fields[j].Name })
+			return m.Fields
+		},
+	}
+	tt, err := NewTemplateType("fields", "package p\n\nvar {{.Name}}Fields = []string{ {{range sortFields .}}{{printf \"%q\" .Name}}, {{end}} }\n", funcs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	shared, _ := fs.Type("T0")
+	data := []any{}
+	for _, m := range fs.Types() {
+		data = append(data, m.Clone(), shared.Clone())
+	}
+	results, err := tt.RenderEach(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(data) {
+		t.Fatalf("got %d results, wanted %d", len(results), len(data))
+	}
+	for i, src := range results {
+		name := data[i].(*TypeModel).Name
+		want := fmt.Sprintf("var %sFields = []string{\"A\", \"B\", \"C\"}", name)
+		if !strings.Contains(string(src), want) {
+			t.Errorf("result %d: got:\n%s\nwanted it to contain %q", i, src, want)
+		}
+	}
+	if shared.Fields[0].Name != "C" {
+		t.Errorf("rendering changed the original model")
+	}
+
+	bad, err := NewTemplateType("bad", "{{.Missing}}", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := bad.RenderEach([]any{shared}); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+}
@@ -0,0 +1,591 @@
+// Package specialize generates concrete copies of generic types and
+// functions, specialized for particular type arguments. Specializations are
+// useful in hot paths where the overhead of generic dispatch matters, and to
+// provide non-generic APIs for code that targets older versions of Go.
+//
+// Specializations are requested with directives in the doc comment of a
+// generic declaration, one for each list of type arguments:
+//
+//	//gen:specialize int
+//	//gen:specialize string
+//	type Set[T comparable] map[T]struct{}
+//
+//	//gen:specialize types="string, []byte" as=IndexBytes
+//	func Index[K comparable, V any](vs []V, key func(V) K) map[K]V
+//
+// The type arguments are written as they would appear in a type argument
+// list, either as the directive's name or, if they contain spaces, with the
+// types argument. Types from other packages must be imported by a file of the
+// package. The specialized declaration is named by the as argument or, by
+// default, by Mangle, so the directives above produce the types SetInt and
+// SetString and the function IndexBytes.
+//
+// The specialization of a type includes its methods. References within a
+// specialized declaration to the declaration itself or to another
+// specialization generated in the same run, such as the recursive type
+// *Node[T] in a linked list, refer to the specialized declaration. Other
+// generic code that the declaration uses is instantiated with the type
+// arguments but remains generic. Comments within the declarations are not
+// copied.
+package specialize
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/iand/gen"
+)
+
+// Prefix is the prefix of the directives that request specializations.
+const Prefix = "gen:specialize"
+
+// Request describes a specialization of a generic type or function.
+type Request struct {
+	// Name is the name of the generic type or function.
+	Name string
+
+	// TypeArgs holds the type arguments, in the order of the type
+	// parameters.
+	TypeArgs []types.Type
+
+	// As is the name of the specialized declaration. If empty, the name
+	// produced by Mangle is used.
+	As string
+}
+
+// Requests returns the specializations requested by the directives in fs, in
+// the order of the directives. It returns an error if a directive is not
+// attached to a generic type or function or its type arguments cannot be
+// evaluated.
+func Requests(fs *gen.FileSet) ([]Request, error) {
+	var reqs []Request
+	var errs []error
+	fs.EachDirective(Prefix, func(d *gen.Directive) bool {
+		pos := fs.FileSet.Position(d.Pos)
+		if d.Object == nil {
+			errs = append(errs, fmt.Errorf("%s: %s directive must document a generic type or function", pos, Prefix))
+			return true
+		}
+		list := d.Name
+		if v, ok := d.Arg("types"); ok {
+			list = v
+		}
+		args, err := evalTypeArgs(fs, list)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pos, err))
+			return true
+		}
+		as, _ := d.Arg("as")
+		reqs = append(reqs, Request{Name: d.Object.Name(), TypeArgs: args, As: as})
+		return true
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return reqs, nil
+}
+
+// evalTypeArgs evaluates a comma separated list of type arguments.
+func evalTypeArgs(fs *gen.FileSet, list string) ([]types.Type, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, fmt.Errorf("no type arguments")
+	}
+	expr, err := parser.ParseExpr("_[" + list + "]")
+	if err != nil {
+		return nil, fmt.Errorf("invalid type arguments %q", list)
+	}
+	var indices []ast.Expr
+	switch expr := expr.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{expr.Index}
+	case *ast.IndexListExpr:
+		indices = expr.Indices
+	default:
+		return nil, fmt.Errorf("invalid type arguments %q", list)
+	}
+
+	args := make([]types.Type, len(indices))
+	for i, index := range indices {
+		if args[i], err = fs.EvalType(types.ExprString(index)); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// Mangle returns the default name of the specialization of the generic
+// declaration name for typeArgs: the name followed by a description of each
+// type argument, such as SetInt for Set[int], CacheStringPtrUser for
+// Cache[string, *User] and MapSliceByteTimeDuration for Map[[]byte,
+// time.Duration]. Types declared in pkg are not qualified by their package
+// name.
+func Mangle(name string, typeArgs []types.Type, pkg *types.Package) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, t := range typeArgs {
+		mangleType(&b, t, pkg)
+	}
+	return b.String()
+}
+
+func mangleType(b *strings.Builder, t types.Type, pkg *types.Package) {
+	switch t := t.(type) {
+	case *types.Basic:
+		b.WriteString(upperFirst(t.Name()))
+	case *types.Alias:
+		mangleObject(b, t.Obj(), pkg)
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			mangleType(b, t.TypeArgs().At(i), pkg)
+		}
+	case *types.Named:
+		mangleObject(b, t.Obj(), pkg)
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			mangleType(b, t.TypeArgs().At(i), pkg)
+		}
+	case *types.Pointer:
+		b.WriteString("Ptr")
+		mangleType(b, t.Elem(), pkg)
+	case *types.Slice:
+		b.WriteString("Slice")
+		mangleType(b, t.Elem(), pkg)
+	case *types.Array:
+		fmt.Fprintf(b, "Array%d", t.Len())
+		mangleType(b, t.Elem(), pkg)
+	case *types.Map:
+		b.WriteString("Map")
+		mangleType(b, t.Key(), pkg)
+		mangleType(b, t.Elem(), pkg)
+	case *types.Chan:
+		b.WriteString("Chan")
+		mangleType(b, t.Elem(), pkg)
+	case *types.Signature:
+		b.WriteString("Func")
+	case *types.Struct:
+		b.WriteString("Struct")
+	case *types.Interface:
+		if t.Empty() {
+			b.WriteString("Any")
+		} else {
+			b.WriteString("Interface")
+		}
+	default:
+		b.WriteString("T")
+	}
+}
+
+func mangleObject(b *strings.Builder, obj types.Object, pkg *types.Package) {
+	if obj.Pkg() != nil && obj.Pkg() != pkg {
+		b.WriteString(upperFirst(obj.Pkg().Name()))
+	}
+	b.WriteString(upperFirst(obj.Name()))
+}
+
+func upperFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// Generate writes the specializations reqs of declarations in fs to o, in
+// the order given. It returns an error if a request does not name a generic
+// type or function of the package or its type arguments do not satisfy the
+// type parameters' constraints.
+func Generate(fs *gen.FileSet, o *gen.Output, reqs []Request) error {
+	s := &specializer{
+		fs:      fs,
+		imports: gen.NewImports(),
+	}
+	s.qualifier = s.imports.Qualifier(fs.Package)
+	for _, req := range reqs {
+		sp, err := s.resolve(req)
+		if err != nil {
+			return err
+		}
+		s.specs = append(s.specs, sp)
+	}
+
+	var body bytes.Buffer
+	for _, sp := range s.specs {
+		if err := s.write(&body, sp); err != nil {
+			return err
+		}
+	}
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	if block := s.imports.Block(); block != "" {
+		o.Printf("%s\n", block)
+	}
+	o.Write(body.Bytes())
+	return nil
+}
+
+// specializer generates the specializations for a single output.
+type specializer struct {
+	fs        *gen.FileSet
+	imports   *gen.Imports
+	qualifier types.Qualifier
+	specs     []*specialization
+}
+
+// specialization is a request resolved against the package.
+type specialization struct {
+	obj      types.Object // the generic type name or function
+	typeArgs []types.Type
+	name     string
+	spec     *ast.TypeSpec   // the declaration of a type
+	decls    []*ast.FuncDecl // the declaration of a function, or the methods of a type
+}
+
+// resolve finds the declarations of the generic named by req.
+func (s *specializer) resolve(req Request) (*specialization, error) {
+	obj := s.fs.Package.Scope().Lookup(req.Name)
+	if obj == nil {
+		return nil, fmt.Errorf("%s not found in package %s", req.Name, s.fs.Package.Name())
+	}
+	var tparams *types.TypeParamList
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		if named, ok := obj.Type().(*types.Named); ok && !obj.IsAlias() {
+			tparams = named.TypeParams()
+		}
+	case *types.Func:
+		tparams = obj.Signature().TypeParams()
+	}
+	if tparams.Len() == 0 {
+		return nil, fmt.Errorf("%s is not a generic type or function", req.Name)
+	}
+	if len(req.TypeArgs) != tparams.Len() {
+		return nil, fmt.Errorf("%s: got %d type arguments, wanted %d", req.Name, len(req.TypeArgs), tparams.Len())
+	}
+	if _, err := types.Instantiate(nil, obj.Type(), req.TypeArgs, true); err != nil {
+		return nil, fmt.Errorf("specialize %s: %w", req.Name, err)
+	}
+
+	sp := &specialization{obj: obj, typeArgs: req.TypeArgs, name: req.As}
+	if sp.name == "" {
+		sp.name = Mangle(req.Name, req.TypeArgs, s.fs.Package)
+	}
+	for _, f := range s.fs.AstFiles {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok && s.fs.TypeInfo.Defs[ts.Name] == obj {
+						sp.spec = ts
+					}
+				}
+			case *ast.FuncDecl:
+				if s.fs.TypeInfo.Defs[decl.Name] == obj || s.receiverObject(decl) == obj {
+					sp.decls = append(sp.decls, decl)
+				}
+			}
+		}
+	}
+	return sp, nil
+}
+
+// receiverObject returns the type name of the receiver of the method decl, or
+// nil if decl is a function.
+func (s *specializer) receiverObject(decl *ast.FuncDecl) types.Object {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return nil
+	}
+	fn, ok := s.fs.TypeInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	recv := fn.Signature().Recv().Type()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	if named, ok := recv.(*types.Named); ok {
+		return named.Origin().Obj()
+	}
+	return nil
+}
+
+// write writes the specialized declarations of sp to w.
+func (s *specializer) write(w *bytes.Buffer, sp *specialization) error {
+	args := make([]string, len(sp.typeArgs))
+	for i, t := range sp.typeArgs {
+		args[i] = types.TypeString(t, s.qualifier)
+	}
+	fmt.Fprintf(w, "// %s is %s specialized for [%s].\n", sp.name, sp.obj.Name(), strings.Join(args, ", "))
+
+	if sp.spec != nil {
+		named := sp.obj.Type().(*types.Named)
+		orig := make(map[ast.Node]ast.Node)
+		spec := clone(sp.spec, orig).(*ast.TypeSpec)
+		spec.Doc, spec.Comment, spec.TypeParams = nil, nil, nil
+		spec.Name = &ast.Ident{NamePos: spec.Name.NamePos, Name: sp.name}
+		decl := &ast.GenDecl{Tok: token.TYPE, TokPos: spec.Pos(), Specs: []ast.Spec{spec}}
+		if err := s.print(w, decl, orig, s.subst(named.TypeParams(), sp.typeArgs)); err != nil {
+			return err
+		}
+	}
+	for _, fd := range sp.decls {
+		orig := make(map[ast.Node]ast.Node)
+		decl := clone(fd, orig).(*ast.FuncDecl)
+		decl.Doc = nil
+		sig := s.fs.TypeInfo.Defs[fd.Name].(*types.Func).Signature()
+		tparams := sig.TypeParams()
+		if decl.Recv != nil {
+			tparams = sig.RecvTypeParams()
+			field := decl.Recv.List[0]
+			name := &ast.Ident{NamePos: field.Type.Pos(), Name: sp.name}
+			if star, ok := field.Type.(*ast.StarExpr); ok {
+				star.X = name
+			} else {
+				field.Type = name
+			}
+		} else {
+			decl.Name = &ast.Ident{NamePos: decl.Name.NamePos, Name: sp.name}
+			decl.Type.TypeParams = nil
+		}
+		if err := s.print(w, decl, orig, s.subst(tparams, sp.typeArgs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subst maps the type parameters tparams to typeArgs.
+func (s *specializer) subst(tparams *types.TypeParamList, typeArgs []types.Type) map[*types.TypeName]types.Type {
+	m := make(map[*types.TypeName]types.Type, tparams.Len())
+	for i := 0; i < tparams.Len(); i++ {
+		m[tparams.At(i).Obj()] = typeArgs[i]
+	}
+	return m
+}
+
+// print rewrites the copied declaration decl, whose nodes map to the nodes of
+// the generic declaration in orig, substituting type arguments for the type
+// parameters in subst, and writes it to w.
+func (s *specializer) print(w *bytes.Buffer, decl ast.Decl, orig map[ast.Node]ast.Node, subst map[*types.TypeName]types.Type) error {
+	info := s.fs.TypeInfo
+	decl = astutil.Apply(decl, nil, func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.Ident:
+			o, _ := orig[n].(*ast.Ident)
+			if o == nil {
+				return true
+			}
+			if isIndexed(c) {
+				return true
+			}
+			if name, ok := s.instanceName(o, subst); ok {
+				c.Replace(&ast.Ident{NamePos: n.NamePos, Name: name})
+				return true
+			}
+			switch obj := info.Uses[o].(type) {
+			case *types.TypeName:
+				if t, ok := subst[obj]; ok {
+					c.Replace(&ast.Ident{NamePos: n.NamePos, Name: s.typeExpr(t, c)})
+				}
+			case *types.PkgName:
+				n.Name = s.imports.Add(obj.Imported().Path(), obj.Name())
+			}
+		case *ast.IndexExpr:
+			s.replaceInstance(c, n.X, orig, subst)
+		case *ast.IndexListExpr:
+			s.replaceInstance(c, n.X, orig, subst)
+		}
+		return true
+	}).(ast.Decl)
+
+	if err := printer.Fprint(w, s.fs.FileSet, decl); err != nil {
+		return err
+	}
+	w.WriteString("\n\n")
+	return nil
+}
+
+// isIndexed reports whether the identifier at c is the generic operand of an
+// index expression, which is rewritten as a whole.
+func isIndexed(c *astutil.Cursor) bool {
+	switch c.Parent().(type) {
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return c.Name() == "X"
+	}
+	return false
+}
+
+// replaceInstance replaces the instantiation at c, whose generic operand is
+// x, with the name of its specialization if there is one.
+func (s *specializer) replaceInstance(c *astutil.Cursor, x ast.Expr, orig map[ast.Node]ast.Node, subst map[*types.TypeName]types.Type) {
+	id, ok := x.(*ast.Ident)
+	if !ok {
+		return
+	}
+	o, _ := orig[id].(*ast.Ident)
+	if o == nil {
+		return
+	}
+	if name, ok := s.instanceName(o, subst); ok {
+		c.Replace(&ast.Ident{NamePos: c.Node().Pos(), Name: name})
+	}
+}
+
+// instanceName returns the name of the specialization referred to by the
+// instantiation of a generic at the identifier id once the type parameters
+// in subst are substituted.
+func (s *specializer) instanceName(id *ast.Ident, subst map[*types.TypeName]types.Type) (string, bool) {
+	inst, ok := s.fs.TypeInfo.Instances[id]
+	if !ok {
+		return "", false
+	}
+	obj := s.fs.TypeInfo.Uses[id]
+	args := make([]types.Type, inst.TypeArgs.Len())
+	for i := range args {
+		t := inst.TypeArgs.At(i)
+		if tp, ok := t.(*types.TypeParam); ok {
+			if t, ok = subst[tp.Obj()]; !ok {
+				return "", false
+			}
+		} else if hasTypeParam(t) {
+			return "", false
+		}
+		args[i] = t
+	}
+	for _, sp := range s.specs {
+		if sp.obj == obj && identicalTypes(sp.typeArgs, args) {
+			return sp.name, true
+		}
+	}
+	return "", false
+}
+
+// typeExpr returns t written as a type expression at c, parenthesized if it
+// is the operand of a conversion or selector and is not a type name.
+func (s *specializer) typeExpr(t types.Type, c *astutil.Cursor) string {
+	expr := types.TypeString(t, s.qualifier)
+	switch c.Parent().(type) {
+	case *ast.CallExpr, *ast.SelectorExpr:
+		if c.Name() == "Fun" || c.Name() == "X" {
+			if !isTypeName(expr) {
+				return "(" + expr + ")"
+			}
+		}
+	}
+	return expr
+}
+
+// isTypeName reports whether expr is a possibly qualified type name.
+func isTypeName(expr string) bool {
+	pkg, name, ok := strings.Cut(expr, ".")
+	if !ok {
+		return token.IsIdentifier(expr)
+	}
+	return token.IsIdentifier(pkg) && token.IsIdentifier(name)
+}
+
+func identicalTypes(a, b []types.Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !types.Identical(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTypeParam reports whether t refers to a type parameter.
+func hasTypeParam(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		return hasTypeParam(t.Elem())
+	case *types.Slice:
+		return hasTypeParam(t.Elem())
+	case *types.Array:
+		return hasTypeParam(t.Elem())
+	case *types.Chan:
+		return hasTypeParam(t.Elem())
+	case *types.Map:
+		return hasTypeParam(t.Key()) || hasTypeParam(t.Elem())
+	case *types.Named:
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if hasTypeParam(t.TypeArgs().At(i)) {
+				return true
+			}
+		}
+	case *types.Signature:
+		return hasTypeParam(t.Params()) || hasTypeParam(t.Results())
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			if hasTypeParam(t.At(i).Type()) {
+				return true
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if hasTypeParam(t.Field(i).Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	objectType = reflect.TypeOf((*ast.Object)(nil))
+	scopeType  = reflect.TypeOf((*ast.Scope)(nil))
+)
+
+// clone returns a deep copy of the syntax tree n, recording the original of
+// each copied node in orig. The copy keeps the positions of the original but
+// not its deprecated object resolution.
+func clone(n ast.Node, orig map[ast.Node]ast.Node) ast.Node {
+	return cloneValue(reflect.ValueOf(n), orig).Interface().(ast.Node)
+}
+
+func cloneValue(v reflect.Value, orig map[ast.Node]ast.Node) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || v.Type() == objectType || v.Type() == scopeType {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem(), orig))
+		if n, ok := v.Interface().(ast.Node); ok {
+			orig[c.Interface().(ast.Node)] = n
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(cloneValue(v.Field(i), orig))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i), orig))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem(), orig))
+		return c
+	}
+	return v
+}
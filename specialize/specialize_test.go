@@ -0,0 +1,218 @@
+package specialize
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+import (
+	"strings"
+	"time"
+)
+
+//gen:specialize int
+//gen:specialize string
+type Set[T comparable] map[T]struct{}
+
+func (s Set[T]) Add(v T) { s[v] = struct{}{} }
+
+func (s Set[T]) Union(o Set[T]) Set[T] {
+	u := Set[T]{}
+	for v := range s {
+		u.Add(v)
+	}
+	for v := range o {
+		u.Add(v)
+	}
+	return u
+}
+
+//gen:specialize *time.Duration
+type Node[T any] struct {
+	Value T
+	Next  *Node[T]
+}
+
+func (n *Node[T]) Len() int {
+	if n == nil {
+		return 0
+	}
+	return 1 + n.Next.Len()
+}
+
+func (n *Node[T]) Deref() T { return T(n.Value) }
+
+var Timeout time.Duration
+
+//gen:specialize types="string, []byte" as=IndexBytes
+func Index[K comparable, V any](vs []V, key func(V) K) map[K]V {
+	m := make(map[K]V, len(vs))
+	for _, v := range vs {
+		m[key(v)] = v
+	}
+	return m
+}
+
+//gen:specialize string
+func Join[T ~string](vs []T) string {
+	ss := make([]string, len(vs))
+	for i, v := range vs {
+		ss[i] = string(v)
+	}
+	return strings.Join(ss, ",")
+}
+`
+
+func TestRequests(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reqs, err := Requests(fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type request struct {
+		Name     string
+		TypeArgs string
+		As       string
+	}
+	var got []request
+	for _, r := range reqs {
+		args := make([]string, len(r.TypeArgs))
+		for i, t := range r.TypeArgs {
+			args[i] = t.String()
+		}
+		got = append(got, request{Name: r.Name, TypeArgs: strings.Join(args, ", "), As: r.As})
+	}
+	want := []request{
+		{Name: "Set", TypeArgs: "int"},
+		{Name: "Set", TypeArgs: "string"},
+		{Name: "Node", TypeArgs: "*time.Duration"},
+		{Name: "Index", TypeArgs: "string, []byte", As: "IndexBytes"},
+		{Name: "Join", TypeArgs: "string"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestMangle(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts("package p\n\nimport \"time\"\n\ntype User struct{}\n\nvar _ time.Duration\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		args string
+		want string
+	}{
+		{args: "int", want: "SetInt"},
+		{args: "string, *User", want: "SetStringPtrUser"},
+		{args: "[]byte, time.Duration", want: "SetSliceByteTimeDuration"},
+		{args: "map[string][2]bool", want: "SetMapStringArray2Bool"},
+		{args: "any, func()", want: "SetAnyFunc"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.args, func(t *testing.T) {
+			args, err := evalTypeArgs(fs, tc.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := Mangle("Set", args, fs.Package); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reqs, err := Requests(fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o := gen.NewOutput("specialize")
+	if err := Generate(fs, o, reqs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, o.Bytes())
+	}
+
+	// The specializations must type check alongside the generic code.
+	both, err := gen.NewFileSetFromTexts(testSrc, string(src))
+	if err != nil {
+		t.Fatalf("generated code does not type check: %v\n%s", err, src)
+	}
+
+	wantTypes := map[string]string{
+		"SetInt":              "map[int]struct{}",
+		"SetString":           "map[string]struct{}",
+		"NodePtrTimeDuration": "struct{Value *time.Duration; Next *NodePtrTimeDuration}",
+		"IndexBytes":          "func(vs [][]byte, key func([]byte) string) map[string][]byte",
+		"JoinString":          "func(vs []string) string",
+	}
+	for name, want := range wantTypes {
+		obj := both.Package.Scope().Lookup(name)
+		if obj == nil {
+			t.Errorf("%s not generated:\n%s", name, src)
+			continue
+		}
+		typ := obj.Type()
+		if _, ok := obj.(*types.TypeName); ok {
+			typ = typ.Underlying()
+		}
+		got := types.TypeString(typ, types.RelativeTo(both.Package))
+		if got != want {
+			t.Errorf("%s: got %s, wanted %s", name, got, want)
+		}
+	}
+
+	for _, want := range []string{
+		"func (s SetInt) Union(o SetInt) SetInt {",
+		"u := SetInt{}",
+		"func (n *NodePtrTimeDuration) Deref() *time.Duration { return (*time.Duration)(n.Value) }",
+		"// IndexBytes is Index specialized for [string, []byte].",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("got:\n%s\nwanted it to contain %q", src, want)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts("package p\n\ntype Set[T comparable] map[T]bool\n\ntype Plain int\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{name: "missing", req: Request{Name: "Missing", TypeArgs: []types.Type{types.Typ[types.Int]}}, want: "not found"},
+		{name: "not generic", req: Request{Name: "Plain", TypeArgs: []types.Type{types.Typ[types.Int]}}, want: "not a generic"},
+		{name: "count", req: Request{Name: "Set"}, want: "got 0 type arguments"},
+		{name: "constraint", req: Request{Name: "Set", TypeArgs: []types.Type{types.NewSlice(types.Typ[types.Int])}}, want: "comparable"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Generate(fs, gen.NewOutput("specialize"), []Request{tc.req})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v, wanted one containing %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// Table describes the database table that a struct type maps to, for
+// generators that emit schema definitions or data access code. Tables are
+// created with FileSet.Table.
+type Table struct {
+	// Name is the name of the table.
+	Name string
+
+	// Type is the model of the struct type.
+	Type *TypeModel
+
+	// Columns holds the table's columns in the order of the struct's fields.
+	Columns []*Column
+}
+
+// PrimaryKey returns the columns that form the table's primary key.
+func (t *Table) PrimaryKey() []*Column {
+	var pk []*Column
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			pk = append(pk, c)
+		}
+	}
+	return pk
+}
+
+// ColumnNames returns the names of the table's columns.
+func (t *Table) ColumnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Column describes a column of a Table, mapped from a struct field.
+type Column struct {
+	// Name is the name of the column.
+	Name string
+
+	// Field is the model of the struct field.
+	Field *FieldModel
+
+	// Type is the Go type of the column's values: the field's type, without
+	// the pointer or database/sql Null wrapper that makes it nullable.
+	Type types.Type
+
+	// Nullable is true if the field is a pointer or a database/sql Null
+	// type, such as sql.NullString, so the column may hold NULL.
+	Nullable bool
+
+	// PrimaryKey is true if the column is part of the primary key.
+	PrimaryKey bool
+
+	// Unique is true if the column's values must be unique.
+	Unique bool
+
+	// SQLType is the SQL type of the column given by the field's tag, or
+	// empty if the dialect chooses the type.
+	SQLType string
+}
+
+// Table returns the table that the named struct type maps to. If name is
+// empty the table is named by the plural of the type name in snake case, so
+// type OrderItem maps to order_items.
+//
+// Each exported field maps to a column named by its db tag, as used by
+// packages such as sqlx, or by the field name in snake case if it has none.
+// Fields tagged "-" are skipped, and the fields of embedded structs without
+// a db tag are promoted into the table. Options following the column name in
+// the tag mark the column as part of the primary key (pk), as unique
+// (unique) or give its SQL type (type=numeric(10,2)), which must be the last
+// option. A field named ID is the primary key if no field is marked.
+func (fs *FileSet) Table(typeName, name string) (*Table, error) {
+	tm, ok := fs.Type(typeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", typeName)
+	}
+	fields, err := fs.FieldsOf(typeName, FlattenEmbedded(true))
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = SnakeCase(Plural(typeName))
+	}
+
+	t := &Table{Name: name, Type: tm}
+	for _, f := range fields {
+		tag, tagged := f.Tags.Get("db")
+		if tagged && tag.Name == "-" {
+			continue
+		}
+		if f.Embedded && (!tagged || tag.Name == "") {
+			if _, ok := f.Type.Underlying().(*types.Struct); ok {
+				continue
+			}
+			if p, ok := f.Type.(*types.Pointer); ok {
+				if _, ok := p.Elem().Underlying().(*types.Struct); ok {
+					continue
+				}
+			}
+		}
+		if !f.Exported || (f.Promoted && embeddedTagged(fields, f)) {
+			continue
+		}
+
+		c := &Column{Name: SnakeCase(f.Name), Field: f}
+		c.Type, c.Nullable = nullableType(f.Type)
+		if tagged {
+			if tag.Name != "" {
+				c.Name = tag.Name
+			}
+		options:
+			for i, opt := range tag.Options {
+				switch {
+				case opt == "pk":
+					c.PrimaryKey = true
+				case opt == "unique":
+					c.Unique = true
+				case strings.HasPrefix(opt, "type="):
+					// The type may itself contain commas.
+					c.SQLType = strings.TrimPrefix(strings.Join(tag.Options[i:], ","), "type=")
+					break options
+				default:
+					return nil, fmt.Errorf("%s.%s: unknown db tag option %q", typeName, f.Name, opt)
+				}
+			}
+		}
+		t.Columns = append(t.Columns, c)
+	}
+
+	if len(t.PrimaryKey()) == 0 {
+		for _, c := range t.Columns {
+			if c.Field.Name == "ID" {
+				c.PrimaryKey = true
+			}
+		}
+	}
+	return t, nil
+}
+
+// embeddedTagged reports whether the promoted field f is reached through an
+// embedded field that has a db tag, and so is stored in a single column.
+func embeddedTagged(fields []*FieldModel, f *FieldModel) bool {
+	for _, e := range fields {
+		if e.Embedded && !e.Promoted && len(f.Path) > 0 && e.Name == f.Path[0] {
+			tag, ok := e.Tags.Get("db")
+			return ok && tag.Name != ""
+		}
+	}
+	return false
+}
+
+// nullableType returns the type of the values held by a field of type t and
+// whether the field may hold NULL.
+func nullableType(t types.Type) (types.Type, bool) {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem(), true
+	}
+	n, ok := t.(*types.Named)
+	if !ok || n.Obj().Pkg() == nil || n.Obj().Pkg().Path() != "database/sql" || !strings.HasPrefix(n.Obj().Name(), "Null") {
+		return t, false
+	}
+	st, ok := n.Underlying().(*types.Struct)
+	if !ok || st.NumFields() == 0 {
+		return t, false
+	}
+	// The value is held by the first field of sql.NullString and the
+	// others, such as String or V for sql.Null[T].
+	return st.Field(0).Type(), true
+}
+
+// SQLDialect describes the SQL of a database system, for generating
+// statements from Tables. PostgresDialect, MySQLDialect and SQLiteDialect are
+// provided; other systems may be supported by implementing the interface.
+type SQLDialect interface {
+	// ColumnType returns the SQL type of a column whose values have the Go
+	// type t, or an error if the type is not supported.
+	ColumnType(t types.Type) (string, error)
+
+	// QuoteIdent quotes the name of a table or column.
+	QuoteIdent(name string) string
+
+	// Placeholder returns the placeholder for the nth parameter of a
+	// statement, counting from 1.
+	Placeholder(n int) string
+}
+
+// sqlDialect is an SQLDialect that maps the kinds of Go types to SQL types.
+type sqlDialect struct {
+	quote    string
+	numbered bool              // placeholders are $1, $2 rather than ?
+	types    map[string]string // SQL types keyed by sqlKind
+}
+
+var (
+	// PostgresDialect is the SQLDialect of PostgreSQL.
+	PostgresDialect SQLDialect = &sqlDialect{
+		quote:    `"`,
+		numbered: true,
+		types: map[string]string{
+			"bool": "boolean", "int16": "smallint", "int32": "integer", "int64": "bigint",
+			"float32": "real", "float64": "double precision", "string": "text",
+			"bytes": "bytea", "time": "timestamp with time zone",
+		},
+	}
+
+	// MySQLDialect is the SQLDialect of MySQL.
+	MySQLDialect SQLDialect = &sqlDialect{
+		quote: "`",
+		types: map[string]string{
+			"bool": "boolean", "int16": "smallint", "int32": "int", "int64": "bigint",
+			"float32": "float", "float64": "double", "string": "text",
+			"bytes": "blob", "time": "datetime(6)",
+		},
+	}
+
+	// SQLiteDialect is the SQLDialect of SQLite.
+	SQLiteDialect SQLDialect = &sqlDialect{
+		quote: `"`,
+		types: map[string]string{
+			"bool": "boolean", "int16": "integer", "int32": "integer", "int64": "integer",
+			"float32": "real", "float64": "real", "string": "text",
+			"bytes": "blob", "time": "datetime",
+		},
+	}
+)
+
+func (d *sqlDialect) ColumnType(t types.Type) (string, error) {
+	kind := sqlKind(t)
+	if typ, ok := d.types[kind]; ok {
+		return typ, nil
+	}
+	return "", fmt.Errorf("no SQL type for %s", t)
+}
+
+func (d *sqlDialect) QuoteIdent(name string) string {
+	return d.quote + strings.ReplaceAll(name, d.quote, d.quote+d.quote) + d.quote
+}
+
+func (d *sqlDialect) Placeholder(n int) string {
+	if d.numbered {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// sqlKind classifies a Go type by the kind of SQL type that holds its
+// values: bool, int16, int32, int64, float32, float64, string, bytes or
+// time. It returns an empty string for other types.
+func sqlKind(t types.Type) string {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time" {
+		return "time"
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Bool:
+			return "bool"
+		case types.Int8, types.Int16, types.Uint8:
+			return "int16"
+		case types.Int32, types.Uint16:
+			return "int32"
+		case types.Int, types.Int64, types.Uint, types.Uint32, types.Uint64:
+			return "int64"
+		case types.Float32:
+			return "float32"
+		case types.Float64:
+			return "float64"
+		case types.String:
+			return "string"
+		}
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "bytes"
+		}
+	}
+	return ""
+}
+
+// CreateTable returns a CREATE TABLE statement for t in the given dialect.
+// Columns are NOT NULL unless they are nullable.
+func CreateTable(t *Table, d SQLDialect) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", d.QuoteIdent(t.Name))
+	for i, c := range t.Columns {
+		typ := c.SQLType
+		if typ == "" {
+			var err error
+			if typ, err = d.ColumnType(c.Type); err != nil {
+				return "", fmt.Errorf("column %s: %w", c.Name, err)
+			}
+		}
+		if i > 0 {
+			b.WriteString(",\n")
+		}
+		fmt.Fprintf(&b, "\t%s %s", d.QuoteIdent(c.Name), typ)
+		if !c.Nullable {
+			b.WriteString(" NOT NULL")
+		}
+		if c.Unique {
+			b.WriteString(" UNIQUE")
+		}
+	}
+	if pk := t.PrimaryKey(); len(pk) > 0 {
+		names := make([]string, len(pk))
+		for i, c := range pk {
+			names[i] = d.QuoteIdent(c.Name)
+		}
+		fmt.Fprintf(&b, ",\n\tPRIMARY KEY (%s)", strings.Join(names, ", "))
+	}
+	b.WriteString("\n);\n")
+	return b.String(), nil
+}
+
+// InsertStatement returns an INSERT statement for all the columns of t in the
+// given dialect, with a placeholder for the value of each column in order.
+func InsertStatement(t *Table, d SQLDialect) string {
+	cols := make([]string, len(t.Columns))
+	params := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		cols[i] = d.QuoteIdent(c.Name)
+		params[i] = d.Placeholder(i + 1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", d.QuoteIdent(t.Name), strings.Join(cols, ", "), strings.Join(params, ", "))
+}
@@ -0,0 +1,106 @@
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sqlModelSrc = `package p
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Timestamps struct {
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+type OrderItem struct {
+	Timestamps
+	OrderID  int64          ` + "`db:\"order_id,pk\"`" + `
+	Line     int32          ` + "`db:\"line,pk\"`" + `
+	SKU      string         ` + "`db:\"sku,unique\"`" + `
+	Price    float64        ` + "`db:\"price,type=numeric(10,2)\"`" + `
+	Note     sql.NullString
+	Ignored  string ` + "`db:\"-\"`" + `
+	internal int
+}
+
+type User struct {
+	ID   int
+	Name string
+}
+`
+
+func TestTable(t *testing.T) {
+	fs, err := NewFileSetFromTexts(sqlModelSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table, err := fs.Table("OrderItem", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.Name != "order_items" {
+		t.Errorf("got table name %q, wanted order_items", table.Name)
+	}
+	want := []string{"created_at", "updated_at", "order_id", "line", "sku", "price", "note"}
+	if got := table.ColumnNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	got, err := CreateTable(table, PostgresDialect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantSQL := `CREATE TABLE "order_items" (
+	"created_at" timestamp with time zone NOT NULL,
+	"updated_at" timestamp with time zone,
+	"order_id" bigint NOT NULL,
+	"line" integer NOT NULL,
+	"sku" text NOT NULL UNIQUE,
+	"price" numeric(10,2) NOT NULL,
+	"note" text,
+	PRIMARY KEY ("order_id", "line")
+);
+`
+	if got != wantSQL {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, wantSQL)
+	}
+
+	users, err := fs.Table("User", "accounts")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pk := users.PrimaryKey(); len(pk) != 1 || pk[0].Name != "id" {
+		t.Errorf("got primary key %+v, wanted id", pk)
+	}
+	if got, want := InsertStatement(users, MySQLDialect), "INSERT INTO `accounts` (`id`, `name`) VALUES (?, ?)"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if got, want := InsertStatement(users, PostgresDialect), `INSERT INTO "accounts" ("id", "name") VALUES ($1, $2)`; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestTableErrors(t *testing.T) {
+	fs, err := NewFileSetFromTexts("package p\n\ntype T struct {\n\tA int `db:\"a,bogus\"`\n\tB chan int\n}\n\ntype U struct{ C chan int }\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := fs.Table("T", ""); err == nil {
+		t.Errorf("got no error for an unknown tag option")
+	}
+	if _, err := fs.Table("Missing", ""); err == nil {
+		t.Errorf("got no error for a missing type")
+	}
+	u, err := fs.Table("U", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := CreateTable(u, SQLiteDialect); err == nil {
+		t.Errorf("got no error for an unsupported column type")
+	}
+}
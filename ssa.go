@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// WithSSA builds the SSA form of the loaded package, for generators that
+// need dataflow analysis such as finding the struct fields that are ever
+// written. The SSA package is available from FileSet.SSA and, for a
+// Workspace, the program holding all its packages from Workspace.SSA. The
+// builder modes are combined; if none are given, generic functions are
+// instantiated with ssa.InstantiateGenerics. SSA form is not built for a
+// package that has errors.
+func WithSSA(modes ...ssa.BuilderMode) Option {
+	return func(o *options) {
+		o.ssa = true
+		o.ssaMode = 0
+		for _, m := range modes {
+			o.ssaMode |= m
+		}
+		if len(modes) == 0 {
+			o.ssaMode = ssa.InstantiateGenerics
+		}
+	}
+}
+
+// SSA returns the SSA form of the package in fs, built because fs was
+// loaded with the WithSSA option, or nil if it was not.
+func (fs *FileSet) SSA() *ssa.Package {
+	return fs.ssa
+}
+
+// buildSSA builds the SSA form of the package in fs if it was requested with
+// WithSSA.
+func (fs *FileSet) buildSSA() {
+	if !fs.opts.ssa || len(fs.Errors) > 0 {
+		return
+	}
+	fs.ssa, _ = fs.BuildSSA(fs.opts.ssaMode)
+}
+
+// BuildSSA constructs the SSA form of the package in fs using the supplied
+// builder mode. SSA form is built from the syntax and type information already
+// held by fs so no further parsing or type checking of the package is needed.
+// Imported packages are represented by their type information only and have no
+// function bodies. It returns an error if the package has errors, since SSA
+// form cannot be built from code that does not type check.
+func (fs *FileSet) BuildSSA(mode ssa.BuilderMode) (*ssa.Package, error) {
+	if len(fs.Errors) > 0 {
+		return nil, fmt.Errorf("build ssa: package has errors: %w", fs.Errors)
+	}
+	_, pkgs := newSSAProgram(fs.FileSet, mode, []*FileSet{fs})
+	pkgs[0].Build()
+	return pkgs[0], nil
+}
+
+// newSSAProgram creates a program holding the SSA packages of fss, created
+// from their syntax and returned in the same order, and the packages they
+// import, represented by their type information only. The packages are not
+// built.
+func newSSAProgram(fset *token.FileSet, mode ssa.BuilderMode, fss []*FileSet) (*ssa.Program, []*ssa.Package) {
+	prog := ssa.NewProgram(fset, mode)
+
+	created := make(map[*types.Package]bool)
+	for _, fs := range fss {
+		created[fs.Package] = true
+	}
+	var createAll func(pkgs []*types.Package)
+	createAll = func(pkgs []*types.Package) {
+		for _, p := range pkgs {
+			if created[p] {
+				continue
+			}
+			created[p] = true
+			prog.CreatePackage(p, nil, nil, true)
+			createAll(p.Imports())
+		}
+	}
+	pkgs := make([]*ssa.Package, len(fss))
+	for i, fs := range fss {
+		createAll(fs.Package.Imports())
+		pkgs[i] = prog.CreatePackage(fs.Package, fs.AstFiles, fs.TypeInfo, false)
+	}
+	return prog, pkgs
+}
@@ -0,0 +1,128 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestBuildSSA(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+		import "strings"
+
+		var count int
+
+		func X(s string) string {
+			count++
+			return strings.ToUpper(s)
+		}
+
+		func Y[T any](v T) T { return v }
+
+		func Z() int { return Y(1) }
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pkg, err := fs.BuildSSA(ssa.InstantiateGenerics)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"X", "Z"} {
+		fn := pkg.Func(name)
+		if fn == nil {
+			t.Fatalf("function %s not found", name)
+		}
+		if len(fn.Blocks) == 0 {
+			t.Errorf("function %s has no blocks", name)
+		}
+	}
+
+	if pkg.Var("count") == nil {
+		t.Errorf("global count not found")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n\nvar X = undefined\n",
+	})
+	lenient, err := FileSetFromDir(dir, WithLenient(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := lenient.BuildSSA(ssa.InstantiateGenerics); err == nil {
+		t.Errorf("got no error for a package with errors, wanted one")
+	}
+	if _, err := lenient.CallGraph(); err == nil {
+		t.Errorf("got no error building the call graph of a package with errors, wanted one")
+	}
+}
+
+func TestWithSSA(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"p.go": `package p
+
+type Config struct {
+	Name    string
+	Retries int
+	Debug   bool
+}
+
+func (c *Config) SetName(name string) { c.Name = name }
+
+func New() *Config { return &Config{Retries: 3} }
+`,
+	})
+
+	fs, err := FileSetFromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fs.SSA() != nil {
+		t.Errorf("SSA built without WithSSA")
+	}
+
+	fs, err = FileSetFromDir(dir, WithSSA())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pkg := fs.SSA()
+	if pkg == nil {
+		t.Fatalf("SSA not built with WithSSA")
+	}
+
+	// Find the fields of Config that are ever written.
+	written := make(map[string]bool)
+	for _, m := range pkg.Members {
+		var fns []*ssa.Function
+		switch m := m.(type) {
+		case *ssa.Function:
+			fns = append(fns, m)
+		case *ssa.Type:
+			mset := pkg.Prog.MethodSets.MethodSet(types.NewPointer(m.Type()))
+			for i := 0; i < mset.Len(); i++ {
+				fns = append(fns, pkg.Prog.MethodValue(mset.At(i)))
+			}
+		}
+		for _, fn := range fns {
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					store, ok := instr.(*ssa.Store)
+					if !ok {
+						continue
+					}
+					if fa, ok := store.Addr.(*ssa.FieldAddr); ok {
+						st := fa.X.Type().Underlying().(*types.Pointer).Elem().Underlying().(*types.Struct)
+						written[st.Field(fa.Field).Name()] = true
+					}
+				}
+			}
+		}
+	}
+	if want := map[string]bool{"Name": true, "Retries": true}; !reflect.DeepEqual(written, want) {
+		t.Errorf("got written fields %v, wanted %v", written, want)
+	}
+}
@@ -0,0 +1,138 @@
+package gen
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// StreamFile applies the template tt to data and writes the generated source
+// code to filename without holding the whole of it in memory, bounding the
+// memory needed to generate very large files such as lookup tables. The
+// accumulated source code of o is not used, but its Generator and Force
+// settings apply as they do for WriteFile, and like WriteFile the file is
+// replaced atomically.
+//
+// The output of the template is written to a temporary file as it is
+// produced. Once execution is complete, and so all of the imports the
+// template declared are known, the output is read back a declaration at a
+// time, formatted if tt.Format is set and written to a second temporary file
+// that replaces filename. Only the largest declaration needs to be held in
+// memory at once.
+func (o *Output) StreamFile(filename string, tt *TemplateType, data interface{}) error {
+	perm, err := checkTarget(filename, o.Force)
+	if err != nil {
+		return err
+	}
+
+	body, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.body")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		body.Close()
+		os.Remove(body.Name())
+	}()
+
+	imports := NewImports()
+	tmpl, err := tt.Template.Clone()
+	if err != nil {
+		return err
+	}
+	tmpl = tmpl.Funcs(importFuncs(imports))
+
+	bw := bufio.NewWriter(body)
+	if err := tmpl.Execute(bw, data); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := out.Name()
+	if err := o.streamSource(out, body, imports, tt.Format); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// streamSource copies the source code read from r to w a declaration at a
+// time, adding the generated code header and the import block for imports to
+// the first declaration and formatting each if format is true.
+func (o *Output) streamSource(w io.Writer, r io.Reader, imports *Imports, format bool) error {
+	bw := bufio.NewWriter(w)
+	chunker := newDeclChunker(func(chunk []byte, first bool) error {
+		if first {
+			var err error
+			if chunk, err = insertImports(chunk, imports); err != nil {
+				return err
+			}
+			if !IsGenerated(chunk) {
+				chunk = append([]byte(o.Header()), chunk...)
+			}
+		}
+		if !format {
+			// Unformatted chunks retain the blank lines that separate them.
+			_, err := bw.Write(chunk)
+			return err
+		}
+		if !first {
+			bw.WriteString("\n")
+		}
+		formatted, err := formatChunk(chunk, first)
+		if err != nil {
+			return fmt.Errorf("format generated source: %w", err)
+		}
+		_, err = bw.Write(formatted)
+		return err
+	})
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := chunker.line(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := chunker.close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,194 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOutputStreamFile(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{
+			name: "table",
+			text: `package {{.Package}}
+
+// Table holds values.
+var Table = []{{import "time"}}.Duration{
+{{range .Values}}	{{.}},
+{{end}}}
+
+{{range .Values}}
+// Value{{.}} returns {{.}}.
+func Value{{.}}() int { return {{.}} }
+{{end}}
+`,
+		},
+		{
+			name: "alignment",
+			text: `// Package comment.
+package {{.Package}}
+import "strings"
+const (
+	A = 1 // a
+	Bbb = 2 // b
+)
+var x = 1 // one
+var yyy = 2 // two
+
+
+type T struct {
+	Name string ` + "`json:\"name\"`" + `
+	Value int
+}
+/* block
+   comment */
+func (t T) String() string { return strings.ToUpper(t.Name) }
+
+var raw = ` + "`" + `
+func notADecl() {
+
+type alsoNot int
+` + "`" + `
+
+func F() {
+// column zero comment
+
+	x := {{import "fmt"}}.Sprint(1)
+	_ = x
+}
+`,
+		},
+		{
+			name: "single",
+			text: `package {{.Package}}
+func F() {}`,
+		},
+	}
+
+	data := map[string]interface{}{
+		"Package": "p",
+		"Values":  []int{1, 2, 3, 4, 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tt, err := NewTemplateType(tc.name, tc.text, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The streamed file should match the file written from memory.
+			src, err := tt.Render(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			o := NewOutput("gentool")
+			o.Write(src)
+			want, err := o.Source()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "gen.go")
+			if err := o.StreamFile(filename, tt, data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("got %d files, wanted 1 (temporary files left behind?)", len(entries))
+			}
+		})
+	}
+}
+
+func TestOutputStreamFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	manual := filepath.Join(dir, "manual.go")
+	if err := os.WriteFile(manual, []byte("package p\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tt, err := NewTemplateType("test", "package p\n\nfunc F() {}\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o := NewOutput("gentool")
+	if err := o.StreamFile(manual, tt, nil); !errors.Is(err, ErrNotGenerated) {
+		t.Errorf("got error %v, wanted %v", err, ErrNotGenerated)
+	}
+
+	broken, err := NewTemplateType("broken", "package p\n\nfunc F() {}\n\nfunc {\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	generated := filepath.Join(dir, "gen.go")
+	if err := o.StreamFile(generated, broken, nil); err == nil {
+		t.Errorf("got no error, wanted one")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files, wanted 1", len(entries))
+	}
+}
+
+func TestDeclChunker(t *testing.T) {
+	src := "// Code generated by x; DO NOT EDIT.\n\npackage p\n\nimport \"fmt\"\n\n" +
+		"// A is documented.\nvar A = 1\nvar B = 2\n\n\n" +
+		"func F() {\n\nfunc() {}()\n}\n\n" +
+		"var s = `\n\nfunc G() {}\n`\n\n" +
+		"// trailing comment\n"
+
+	var chunks []string
+	c := newDeclChunker(func(chunk []byte, first bool) error {
+		if first != (len(chunks) == 0) {
+			t.Errorf("got first %v for chunk %d", first, len(chunks))
+		}
+		chunks = append(chunks, string(chunk))
+		return nil
+	})
+	for _, line := range strings.SplitAfter(src, "\n") {
+		if line == "" {
+			continue
+		}
+		if err := c.line([]byte(line)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := c.close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"// Code generated by x; DO NOT EDIT.\n\npackage p\n\n",
+		"import \"fmt\"\n\n",
+		"// A is documented.\nvar A = 1\nvar B = 2\n\n\n",
+		"func F() {\n\nfunc() {}()\n}\n\n",
+		"var s = `\n\nfunc G() {}\n`\n\n",
+		"// trailing comment\n",
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("got %q, wanted %q", chunks, want)
+	}
+}
@@ -0,0 +1,150 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+// Stubs holds skeleton method implementations produced by GenerateStubs.
+type Stubs struct {
+	// Receiver is the receiver used by each method, such as "f *File".
+	Receiver string
+
+	// Imports holds the packages referred to by the method signatures.
+	Imports *Imports
+
+	// Methods holds the source of each method declaration in the order the
+	// methods appear in the interface's method set.
+	Methods []string
+}
+
+// String returns the method declarations separated by blank lines.
+func (s *Stubs) String() string {
+	return strings.Join(s.Methods, "\n")
+}
+
+// GenerateStubs returns skeleton implementations of the methods of the named
+// interface for the given receiver. The interface is named as for
+// Implements. The receiver may be a type, such as "*File", or a name and a
+// type, such as "f *File". If no name is given one is derived from the type.
+// When the receiver type is declared in fs, methods it already implements are
+// omitted. Each stub keeps the parameter names declared by the interface and
+// returns the zero values of its results.
+func (fs *FileSet) GenerateStubs(iface string, receiver string) (*Stubs, error) {
+	obj, err := fs.LookupInterface(iface)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := obj.Type().(*types.Named); ok && n.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("interface %s is generic", iface)
+	}
+	it := obj.Type().Underlying().(*types.Interface)
+
+	recvName, recvType, err := parseReceiver(receiver)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing *types.MethodSet
+	if t, err := fs.lookupType(recvType); err == nil {
+		existing = types.NewMethodSet(t)
+	}
+
+	im := NewImports()
+	q := im.Qualifier(fs.Package)
+	stubs := &Stubs{
+		Receiver: recvName + " " + recvType,
+		Imports:  im,
+	}
+
+	for i := 0; i < it.NumMethods(); i++ {
+		m := it.Method(i)
+		if existing != nil {
+			if sel := existing.Lookup(m.Pkg(), m.Name()); sel != nil && types.Identical(sel.Obj().Type(), m.Type()) {
+				continue
+			}
+		}
+		stubs.Methods = append(stubs.Methods, stubMethod(stubs.Receiver, recvName, m, q))
+	}
+
+	return stubs, nil
+}
+
+// stubMethod returns the source of a skeleton implementation of m.
+func stubMethod(recv, recvName string, m *types.Func, q types.Qualifier) string {
+	sig := m.Type().(*types.Signature)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "func (%s) %s(%s)", recv, m.Name(), stubParams(sig.Params(), sig.Variadic(), recvName, q))
+
+	results := sig.Results()
+	switch {
+	case results.Len() == 1 && results.At(0).Name() == "":
+		fmt.Fprintf(&b, " %s", types.TypeString(results.At(0).Type(), q))
+	case results.Len() > 0:
+		fmt.Fprintf(&b, " (%s)", stubParams(results, false, recvName, q))
+	}
+
+	b.WriteString(" {\n\t// TODO: implement\n")
+	if results.Len() > 0 {
+		zeros := make([]string, results.Len())
+		for i := range zeros {
+			zeros[i] = ZeroValue(results.At(i).Type(), q)
+		}
+		fmt.Fprintf(&b, "\treturn %s\n", strings.Join(zeros, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// stubParams formats a parameter or result list. Names that would shadow the
+// receiver are given a numeric suffix.
+func stubParams(tup *types.Tuple, variadic bool, recvName string, q types.Qualifier) string {
+	params := make([]string, tup.Len())
+	for i := 0; i < tup.Len(); i++ {
+		v := tup.At(i)
+		typ := types.TypeString(v.Type(), q)
+		if variadic && i == tup.Len()-1 {
+			typ = "..." + types.TypeString(v.Type().(*types.Slice).Elem(), q)
+		}
+
+		name := v.Name()
+		if name == recvName && name != "_" {
+			name += "2"
+		}
+		if name == "" {
+			params[i] = typ
+		} else {
+			params[i] = name + " " + typ
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+// parseReceiver splits a receiver such as "f *File" into its name and type,
+// deriving a name from the type if none is given.
+func parseReceiver(receiver string) (string, string, error) {
+	fields := strings.Fields(receiver)
+	switch len(fields) {
+	case 1:
+		typ := fields[0]
+		base := strings.TrimPrefix(typ, "*")
+		if !token.IsIdentifier(base) {
+			return "", "", fmt.Errorf("invalid receiver type %q", typ)
+		}
+		return string(unicode.ToLower([]rune(base)[0])), typ, nil
+	case 2:
+		name, typ := fields[0], fields[1]
+		if !token.IsIdentifier(name) && name != "_" {
+			return "", "", fmt.Errorf("invalid receiver name %q", name)
+		}
+		if !token.IsIdentifier(strings.TrimPrefix(typ, "*")) {
+			return "", "", fmt.Errorf("invalid receiver type %q", typ)
+		}
+		return name, typ, nil
+	}
+	return "", "", fmt.Errorf("invalid receiver %q", receiver)
+}
@@ -0,0 +1,88 @@
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateStubs(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "io"
+
+		var _ io.Reader
+
+		type Store interface {
+			Get(key string) (value []byte, ok bool)
+			Put(string, []byte) error
+			Keys(prefix string, w io.Writer) []string
+			Log(s string, args ...any)
+			Close()
+		}
+
+		type Memory struct{}
+
+		func (*Memory) Close() {}
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		iface    string
+		receiver string
+		methods  []string
+		imports  []string
+	}{
+		{
+			iface:    "Store",
+			receiver: "*Memory",
+			methods: []string{
+				"func (m *Memory) Get(key string) (value []byte, ok bool) {\n\t// TODO: implement\n\treturn nil, false\n}\n",
+				"func (m *Memory) Keys(prefix string, w io.Writer) []string {\n\t// TODO: implement\n\treturn nil\n}\n",
+				"func (m *Memory) Log(s string, args ...any) {\n\t// TODO: implement\n}\n",
+				"func (m *Memory) Put(string, []byte) error {\n\t// TODO: implement\n\treturn nil\n}\n",
+			},
+			imports: []string{"io"},
+		},
+		{
+			iface:    "io.ReadCloser",
+			receiver: "s stub",
+			methods: []string{
+				"func (s stub) Close() error {\n\t// TODO: implement\n\treturn nil\n}\n",
+				"func (s stub) Read(p []byte) (n int, err error) {\n\t// TODO: implement\n\treturn 0, nil\n}\n",
+			},
+			imports: []string{},
+		},
+		{
+			iface:    "io.Writer",
+			receiver: "p *Pipe",
+			methods: []string{
+				"func (p *Pipe) Write(p2 []byte) (n int, err error) {\n\t// TODO: implement\n\treturn 0, nil\n}\n",
+			},
+			imports: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.iface, func(t *testing.T) {
+			stubs, err := fs.GenerateStubs(tc.iface, tc.receiver)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(stubs.Methods, tc.methods) {
+				t.Errorf("got %q, wanted %q", stubs.Methods, tc.methods)
+			}
+			if got := stubs.Imports.Paths(); !reflect.DeepEqual(got, tc.imports) {
+				t.Errorf("got imports %+v, wanted %+v", got, tc.imports)
+			}
+		})
+	}
+
+	if _, err := fs.GenerateStubs("Memory", "*T"); err == nil {
+		t.Errorf("got no error for non-interface")
+	}
+	if _, err := fs.GenerateStubs("Store", "a b c"); err == nil {
+		t.Errorf("got no error for invalid receiver")
+	}
+}
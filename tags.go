@@ -0,0 +1,132 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tag is a single key of a struct field tag, such as json:"name,omitempty".
+// The value is split at commas into a name and a list of options.
+type Tag struct {
+	// Key is the tag key, such as json.
+	Key string
+
+	// Value is the complete, unquoted tag value.
+	Value string
+
+	// Name is the part of the value before the first comma.
+	Name string
+
+	// Options holds the comma separated parts of the value after the name.
+	Options []string
+}
+
+// HasOption reports whether the tag includes the given option.
+func (t Tag) HasOption(opt string) bool {
+	for _, o := range t.Options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags is a parsed struct field tag, holding each key in the order it appears.
+type Tags []Tag
+
+// ParseTags parses a struct field tag in the conventional format described by
+// reflect.StructTag. The tag may be supplied with or without the surrounding
+// back quotes of a raw string literal.
+func ParseTags(tag string) (Tags, error) {
+	if len(tag) >= 2 && tag[0] == '`' && tag[len(tag)-1] == '`' {
+		tag = tag[1 : len(tag)-1]
+	}
+
+	tags := Tags{}
+	for {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			return tags, nil
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, fmt.Errorf("malformed struct tag %q", tag)
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("malformed value for struct tag key %q", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed value for struct tag key %q: %w", key, err)
+		}
+		tag = tag[i+1:]
+
+		t := Tag{Key: key, Value: value}
+		parts := strings.Split(value, ",")
+		t.Name = parts[0]
+		if len(parts) > 1 {
+			t.Options = parts[1:]
+		}
+		tags = append(tags, t)
+	}
+}
+
+// Get returns the tag with the given key and whether it was present.
+func (ts Tags) Get(key string) (Tag, bool) {
+	for _, t := range ts {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return Tag{}, false
+}
+
+// Keys returns the keys of the tags in the order they appear.
+func (ts Tags) Keys() []string {
+	keys := make([]string, len(ts))
+	for i, t := range ts {
+		keys[i] = t.Key
+	}
+	return keys
+}
+
+// String formats the tags in the conventional struct tag format, without
+// surrounding back quotes.
+func (ts Tags) String() string {
+	parts := make([]string, len(ts))
+	for i, t := range ts {
+		parts[i] = t.Key + ":" + strconv.Quote(t.Value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Tags returns the parsed tags of the named field of the named struct type.
+func (fs *FileSet) Tags(typeName, fieldName string) (Tags, error) {
+	tm, ok := fs.Type(typeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", typeName)
+	}
+	for _, f := range tm.Fields {
+		if f.Name == fieldName {
+			return ParseTags(f.Tag)
+		}
+	}
+	return nil, fmt.Errorf("field %s not found in type %s", fieldName, typeName)
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	testCases := []struct {
+		tag     string
+		want    Tags
+		wantErr bool
+	}{
+		{
+			tag:  ``,
+			want: Tags{},
+		},
+		{
+			tag: `json:"name,omitempty"`,
+			want: Tags{
+				{Key: "json", Value: "name,omitempty", Name: "name", Options: []string{"omitempty"}},
+			},
+		},
+		{
+			tag: "`json:\"-\" db:\"user_id\"`",
+			want: Tags{
+				{Key: "json", Value: "-", Name: "-"},
+				{Key: "db", Value: "user_id", Name: "user_id"},
+			},
+		},
+		{
+			tag: `validate:"min=1,max=10"  xml:",attr"`,
+			want: Tags{
+				{Key: "validate", Value: "min=1,max=10", Name: "min=1", Options: []string{"max=10"}},
+				{Key: "xml", Value: ",attr", Name: "", Options: []string{"attr"}},
+			},
+		},
+		{
+			tag: `quoted:"a\"b"`,
+			want: Tags{
+				{Key: "quoted", Value: `a"b`, Name: `a"b`},
+			},
+		},
+		{tag: `json`, wantErr: true},
+		{tag: `json:name`, wantErr: true},
+		{tag: `json:"name`, wantErr: true},
+		{tag: `:"name"`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.tag, func(t *testing.T) {
+			got, err := ParseTags(tc.tag)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, wanted error: %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTagsGet(t *testing.T) {
+	tags, err := ParseTags(`json:"id,string,omitempty" db:"id"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tag, ok := tags.Get("json")
+	if !ok {
+		t.Fatalf("json tag not found")
+	}
+	if !tag.HasOption("omitempty") || !tag.HasOption("string") || tag.HasOption("id") {
+		t.Errorf("unexpected options %+v", tag.Options)
+	}
+
+	if _, ok := tags.Get("yaml"); ok {
+		t.Errorf("unexpected yaml tag")
+	}
+
+	if got, want := tags.Keys(), []string{"json", "db"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+
+	if got, want := tags.String(), `json:"id,string,omitempty" db:"id"`; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestFileSetTags(t *testing.T) {
+	fs, err := NewFileSetFromTexts("package p\ntype User struct {\n\tID int `json:\"id\" db:\"user_id,pk\"`\n\tName string\n}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tags, err := fs.Tags("User", "ID")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db, ok := tags.Get("db")
+	if !ok || db.Name != "user_id" || !db.HasOption("pk") {
+		t.Errorf("got %+v, wanted user_id with pk option", db)
+	}
+
+	tags, err = fs.Tags("User", "Name")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("got %+v, wanted no tags", tags)
+	}
+
+	if _, err := fs.Tags("User", "Missing"); err == nil {
+		t.Errorf("got no error for missing field")
+	}
+	if _, err := fs.Tags("Missing", "ID"); err == nil {
+		t.Errorf("got no error for missing type")
+	}
+}
@@ -0,0 +1,130 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// Target is the declaration that a go:generate directive is attached to.
+type Target struct {
+	// Name is the name of the declared type, function, constant or variable.
+	// Methods are named T.M. A specification declaring several constants or
+	// variables is named after the first.
+	Name string
+
+	// Node is the declaration: a *ast.TypeSpec, *ast.FuncDecl or
+	// *ast.ValueSpec.
+	Node ast.Node
+
+	// Object is the type checked object declared, or nil for a blank
+	// constant or variable.
+	Object types.Object
+}
+
+// IsType reports whether the target declares a type.
+func (t *Target) IsType() bool {
+	_, ok := t.Node.(*ast.TypeSpec)
+	return ok
+}
+
+// TargetAt returns the declaration that a go:generate directive on the given
+// line of a file is attached to. The directive must be part of the
+// declaration's doc comment, or in a comment separated from the declaration
+// or its doc comment by no more than a blank line. A directive above a
+// grouped declaration is attached to its first specification, and within
+// the group each specification is a declaration in its own right. The file
+// is identified by its name, such as the GOFILE set by go generate, or by
+// its path. The line is one-based, like the GOLINE set by go generate. It
+// returns an error if the directive is not attached to a declaration, such
+// as a directive that applies to the whole file.
+func (fs *FileSet) TargetAt(filename string, line int) (*Target, error) {
+	file, tf := fs.fileNamed(filename)
+	if file == nil {
+		return nil, fmt.Errorf("file %s not found in package", filename)
+	}
+
+	var directive *ast.CommentGroup
+	for _, g := range file.Comments {
+		if g.Pos() > file.Name.End() && tf.Line(g.Pos()) <= line && line <= tf.Line(g.End()) {
+			directive = g
+			break
+		}
+	}
+	attached := func(doc *ast.CommentGroup, pos token.Pos) bool {
+		if directive == nil {
+			return false
+		}
+		if doc == directive {
+			return true
+		}
+		top := pos
+		if doc != nil {
+			top = doc.Pos()
+		}
+		return top > directive.End() && tf.Line(top)-tf.Line(directive.End()) <= 2
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if attached(decl.Doc, decl.Pos()) {
+				return &Target{Name: funcDeclName(decl), Node: decl, Object: fs.TypeInfo.Defs[decl.Name]}, nil
+			}
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT || len(decl.Specs) == 0 {
+				continue
+			}
+			if attached(decl.Doc, decl.Pos()) {
+				return fs.specTarget(decl.Specs[0]), nil
+			}
+			if !decl.Lparen.IsValid() {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				var doc *ast.CommentGroup
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					doc = spec.Doc
+				case *ast.ValueSpec:
+					doc = spec.Doc
+				}
+				if attached(doc, spec.Pos()) {
+					return fs.specTarget(spec), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s:%d: directive is not attached to a declaration", filename, line)
+}
+
+// specTarget returns the target declared by a type or value specification.
+func (fs *FileSet) specTarget(spec ast.Spec) *Target {
+	node, obj := fs.specObject(spec)
+	t := &Target{Node: node, Object: obj}
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		t.Name = spec.Name.Name
+	case *ast.ValueSpec:
+		t.Name = spec.Names[0].Name
+	}
+	return t
+}
+
+// fileNamed returns the parsed file with the given path, or the given base
+// name if filename has no directory.
+func (fs *FileSet) fileNamed(filename string) (*ast.File, *token.File) {
+	for _, f := range fs.AstFiles {
+		tf := fs.FileSet.File(f.Pos())
+		if tf == nil {
+			continue
+		}
+		name := tf.Name()
+		if samePath(name, filename) || (filepath.Base(filename) == filename && filepath.Base(name) == filename) {
+			return f, tf
+		}
+	}
+	return nil, nil
+}
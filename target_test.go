@@ -0,0 +1,146 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTargetAt(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+import "fmt"
+
+//go:generate stringer
+type Color int
+
+//go:generate gen
+
+// Shape is a shape.
+type Shape int
+
+type (
+	//go:generate gen
+	A int
+	//go:generate gen
+	B int
+)
+
+//go:generate gen
+func (c Color) Print() { fmt.Println(c) }
+
+//go:generate gen
+const _, Max = 0, 10
+
+//go:generate gen
+`, `package p
+
+//go:generate gen
+func F() {}
+`, `//go:generate gen
+
+package p
+
+//go:generate gen
+
+
+type Far int
+
+type Near int
+//go:generate gen
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		file string
+		line int
+		want string
+		typ  bool
+	}{
+		{file: "0.go", line: 5, want: "Color", typ: true},
+		{file: "0.go", line: 8, want: "Shape", typ: true},
+		{file: "0.go", line: 14, want: "A", typ: true},
+		{file: "0.go", line: 16, want: "B", typ: true},
+		{file: "0.go", line: 20, want: "Color.Print"},
+		{file: "0.go", line: 23, want: "_"},
+		{file: "1.go", line: 3, want: "F"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			got, err := fs.TargetAt(tc.file, tc.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Name != tc.want || got.IsType() != tc.typ {
+				t.Errorf("got %s (type %v), wanted %s (type %v)", got.Name, got.IsType(), tc.want, tc.typ)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		file string
+		line int
+	}{
+		{file: "0.go", line: 26}, // at the end of the file
+		{file: "0.go", line: 21}, // not a comment
+		{file: "2.go", line: 1},  // above the package clause
+		{file: "2.go", line: 5},  // separated by two blank lines
+		{file: "2.go", line: 11}, // after the declarations
+	} {
+		if got, err := fs.TargetAt(tc.file, tc.line); err == nil {
+			t.Errorf("%s:%d: got target %s, wanted an error", tc.file, tc.line, got.Name)
+		}
+	}
+	if _, err := fs.TargetAt("missing.go", 1); err == nil {
+		t.Errorf("got no error for missing file, wanted one")
+	}
+}
+
+func TestRunnerTarget(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	t.Setenv("GOFILE", "color.go")
+	t.Setenv("GOPACKAGE", "p")
+	t.Setenv("GOLINE", "3")
+
+	r := stringerRunner()
+	if err := r.Run([]string{dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "color_stringer.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(got), "func (Color) String()") || strings.Contains(string(got), "Shape") {
+		t.Errorf("got:\n%s\nwanted code for the type following the directive only", got)
+	}
+}
+
+func TestRunnerFileDirective(t *testing.T) {
+	dir := writeRunnerPackage(t)
+	if err := os.WriteFile(filepath.Join(dir, "gen.go"), []byte("//go:generate stringer\n\npackage p\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GOFILE", "gen.go")
+	t.Setenv("GOPACKAGE", "p")
+	t.Setenv("GOLINE", "1")
+
+	var types []string
+	r := stringerRunner()
+	generate := r.Generate
+	r.Generate = func(j *Job) error {
+		if j.Target != nil {
+			t.Errorf("got target %s for a file level directive, wanted none", j.Target.Name)
+		}
+		types = j.Types
+		return generate(j)
+	}
+	if err := r.Run([]string{dir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(types) != 0 {
+		t.Errorf("got types %v, wanted none", types)
+	}
+}
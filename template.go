@@ -0,0 +1,174 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"text/template"
+)
+
+// TemplateType is a text template that generates Go source code. Templates may
+// call the import function to declare that the generated code depends on a
+// package. The function returns the name to use when referring to the package
+// so a template can write {{import "fmt"}}.Println and the matching import
+// declaration is emitted after the package clause of the generated code.
+// An optional second argument supplies a preferred alias. A TemplateType may
+// be executed by multiple goroutines concurrently.
+type TemplateType struct {
+	// Template is the parsed template.
+	Template *template.Template
+
+	// Format controls whether the generated code is formatted with gofmt.
+	Format bool
+
+	// ParallelFormat causes the top-level declarations of the generated code
+	// to be formatted concurrently when Format is set, which is faster for
+	// very large outputs.
+	ParallelFormat bool
+
+	// FixImports causes the generated code to be formatted with goimports
+	// rather than gofmt when Format is set: unused imports are removed and
+	// missing imports of the standard library, or of packages that can be
+	// found from the current directory, are added. Templates then need not
+	// declare their imports exactly. ParallelFormat is ignored.
+	FixImports bool
+}
+
+// NewTemplateType parses text as a template with the given name. The supplied
+// functions, which may be nil, are added to the template's function map
+// alongside the import function.
+func NewTemplateType(name, text string, funcs template.FuncMap) (*TemplateType, error) {
+	tmpl := template.New(name).Funcs(importFuncs(NewImports()))
+	if funcs != nil {
+		tmpl = tmpl.Funcs(funcs)
+	}
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateType{
+		Template: tmpl,
+		Format:   true,
+	}, nil
+}
+
+// Execute applies the template to data and writes the generated source code
+// to w.
+func (tt *TemplateType) Execute(w io.Writer, data interface{}) error {
+	src, err := tt.Render(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// Render applies the template to data and returns the generated source code.
+func (tt *TemplateType) Render(data interface{}) ([]byte, error) {
+	return tt.RenderTemplate(tt.Template.Name(), data)
+}
+
+// RenderTemplate applies the template associated with tt that has the given
+// name, such as one declared with a define action, to data and returns the
+// generated source code.
+func (tt *TemplateType) RenderTemplate(name string, data interface{}) ([]byte, error) {
+	return tt.RenderTemplateContext(context.Background(), name, data)
+}
+
+// RenderTemplateContext is like RenderTemplate but stops executing the
+// template and returns the context's error once ctx is done.
+func (tt *TemplateType) RenderTemplateContext(ctx context.Context, name string, data interface{}) ([]byte, error) {
+	src, err := tt.render(ctx, name, data)
+	if err != nil {
+		return nil, err
+	}
+	if tt.Format {
+		formatted, err := formatSource(src, tt.ParallelFormat, tt.FixImports)
+		if err != nil {
+			return nil, fmt.Errorf("format generated source: %w", err)
+		}
+		src = formatted
+	}
+	return src, nil
+}
+
+// render executes the named template with data and returns the source code
+// it produces, with its imports declared but not formatted.
+func (tt *TemplateType) render(ctx context.Context, name string, data interface{}) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	imports := NewImports()
+	tmpl, err := tt.Template.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl = tmpl.Funcs(importFuncs(imports))
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(contextWriter{ctx: ctx, w: &buf}, name, data); err != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, templateError(err)
+	}
+
+	return insertImports(buf.Bytes(), imports)
+}
+
+// contextWriter is a writer that fails with the error of its context once
+// the context is done, which stops the execution of a template writing to
+// it.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// importFuncs returns the template functions that record imports in im.
+func importFuncs(im *Imports) template.FuncMap {
+	return template.FuncMap{
+		"import": func(path string, alias ...string) (string, error) {
+			if len(alias) > 1 {
+				return "", fmt.Errorf("import: too many arguments")
+			}
+			if len(alias) == 1 {
+				return im.Add(path, alias[0]), nil
+			}
+			return im.Add(path, ""), nil
+		},
+	}
+}
+
+// insertImports inserts the import block for im directly after the package
+// clause in src.
+func insertImports(src []byte, im *Imports) ([]byte, error) {
+	if im.Len() == 0 {
+		return src, nil
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, fmt.Errorf("locate package clause: %w", err)
+	}
+	offset := fset.Position(f.Name.End()).Offset
+
+	out := make([]byte, 0, len(src)+im.Len()*32)
+	out = append(out, src[:offset]...)
+	out = append(out, "\n\n"...)
+	out = append(out, im.Block()...)
+	out = append(out, src[offset:]...)
+	return out, nil
+}
@@ -0,0 +1,153 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTemplateTypeImports(t *testing.T) {
+	tt, err := NewTemplateType("test", `// Header comment
+package {{.Package}}
+
+func Print() {
+	{{import "fmt"}}.Println({{import "strings"}}.ToUpper("x"))
+	{{import "fmt"}}.Println({{import "example.com/strings" "xstrings"}}.Value)
+}
+`, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := tt.Execute(&sb, map[string]string{"Package": "p"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `// Header comment
+package p
+
+import (
+	"fmt"
+	"strings"
+
+	xstrings "example.com/strings"
+)
+
+func Print() {
+	fmt.Println(strings.ToUpper("x"))
+	fmt.Println(xstrings.Value)
+}
+`
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestTemplateTypeNoImports(t *testing.T) {
+	tt, err := NewTemplateType("test", "package p\nconst X = {{.}}\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := tt.Render(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "package p\n\nconst X = 1\n"; string(got) != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestTemplateTypeExecutionsAreIndependent(t *testing.T) {
+	tt, err := NewTemplateType("test", `package p
+{{if .}}var _ = {{import "fmt"}}.Sprint{{end}}
+`, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := tt.Render(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := tt.Render(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(got), "import") {
+		t.Errorf("imports leaked between executions:\n%s", got)
+	}
+}
+
+func TestTemplateTypeFixImports(t *testing.T) {
+	tt, err := NewTemplateType("test", `package p
+
+import "os"
+
+func Print() {
+	fmt.Println({{import "strings"}}.ToUpper("x"))
+}
+`, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tt.FixImports = true
+
+	got, err := tt.Render(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `package p
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Print() {
+	fmt.Println(strings.ToUpper("x"))
+}
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
+
+func TestRenderTemplateContext(t *testing.T) {
+	cancel := func() {}
+	calls := 0
+	tt, err := NewTemplateType("test", `package p
+{{range .}}{{cancel}}var _ = {{.}}
+{{end}}`, map[string]any{
+		"cancel": func() string {
+			if calls++; calls == 2 {
+				cancel()
+			}
+			return ""
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	src, err := tt.RenderTemplateContext(context.Background(), "test", []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(src), "var _ = 3") {
+		t.Errorf("rendered source is incomplete:\n%s", src)
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	cancel = cancelCtx
+	calls = 0
+	if _, err := tt.RenderTemplateContext(ctx, "test", []int{1, 2, 3}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, wanted %v", err, context.Canceled)
+	}
+	if calls != 2 {
+		t.Errorf("template executed %d iterations after cancellation, wanted it to stop", calls-2)
+	}
+}
@@ -0,0 +1,102 @@
+package gen
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"text/template"
+)
+
+// LoadTemplates parses the files in fsys whose base names match pattern, as
+// interpreted by path.Match, searching the whole tree. It is intended for
+// loading a generator's built-in templates from an embed.FS. Each template is
+// named by its slash-separated path in fsys, such as "enum/string.tmpl", and
+// may invoke the others by that name. The supplied functions, which may be
+// nil, are added to the templates' function map alongside the import
+// function, as with NewTemplateType.
+//
+// The Template of the returned TemplateType is the first file in lexical
+// order, so Render executes it; use RenderTemplate to execute the others.
+// It is an error for no files to match.
+func LoadTemplates(fsys fs.FS, pattern string, funcs template.FuncMap) (*TemplateType, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ok, _ := path.Match(pattern, d.Name()); ok && !d.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no templates match %q", pattern)
+	}
+
+	var tmpl *template.Template
+	for _, name := range names {
+		text, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var t *template.Template
+		if tmpl == nil {
+			tmpl = template.New(name).Funcs(importFuncs(NewImports()))
+			if funcs != nil {
+				tmpl = tmpl.Funcs(funcs)
+			}
+			t = tmpl
+		} else {
+			t = tmpl.New(name)
+		}
+		if _, err := t.Parse(string(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &TemplateType{
+		Template: tmpl,
+		Format:   true,
+	}, nil
+}
+
+// OverrideTemplates replaces templates of tt with the files of the same name
+// in the directory dir, so that users of a generator can customize its
+// built-in templates without changing the generator. A template named
+// "enum/string.tmpl" is replaced by the file enum/string.tmpl under dir.
+// Templates without a file in dir, and files in dir that do not name a
+// template, are ignored. It does nothing if dir is empty. OverrideTemplates
+// must not be called concurrently with executing tt.
+func (tt *TemplateType) OverrideTemplates(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	fsys := os.DirFS(dir)
+	for _, t := range sortedTemplates(tt.Template) {
+		name := t.Name()
+		if !fs.ValidPath(name) {
+			continue
+		}
+		text, err := fs.ReadFile(fsys, name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := t.Parse(string(text)); err != nil {
+			return fmt.Errorf("override %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.tmpl":        {Data: []byte("package {{.}}\n\n{{template \"decls/var.tmpl\" .}}\n")},
+		"decls/var.tmpl":   {Data: []byte("var Name = {{quote .}}\n")},
+		"decls/notes.txt":  {Data: []byte("not a template")},
+		"decls/const.tmpl": {Data: []byte("const Name = {{quote .}}\n")},
+	}
+	funcs := template.FuncMap{"quote": func(s string) string { return `"` + s + `"` }}
+	tt, err := LoadTemplates(fsys, "*.tmpl", funcs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := tt.Template.Name(), "decls/const.tmpl"; got != want {
+		t.Errorf("got root template %q, wanted %q", got, want)
+	}
+	if tt.Template.Lookup("decls/notes.txt") != nil {
+		t.Errorf("loaded file that does not match the pattern")
+	}
+	got, err := tt.RenderTemplate("file.tmpl", "p")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "package p\n\nvar Name = \"p\"\n"; string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	if _, err := LoadTemplates(fsys, "*.gotmpl", nil); err == nil {
+		t.Errorf("got no error when no templates match")
+	}
+}
+
+func TestOverrideTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.tmpl":      {Data: []byte("package {{.}}\n\n{{template \"decls/var.tmpl\" .}}\n")},
+		"decls/var.tmpl": {Data: []byte("var Name = 1\n")},
+	}
+	tt, err := LoadTemplates(fsys, "*.tmpl", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "decls"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "decls", "var.tmpl"), []byte("var Name = 2\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "unknown.tmpl"), []byte("{{"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tt.OverrideTemplates(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := tt.RenderTemplate("file.tmpl", "p")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "package p\n\nvar Name = 2\n"; string(got) != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+
+	if err := tt.OverrideTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("got no error for a missing directory")
+	}
+}
@@ -0,0 +1,349 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// TypeOf returns the type of expression e, or nil if it is not found. For an
+// identifier that denotes an object the type of the object is returned.
+func (fs *FileSet) TypeOf(e ast.Expr) types.Type {
+	return fs.TypeInfo.TypeOf(e)
+}
+
+// ObjectOf returns the object denoted by the identifier id, whether the
+// identifier declares or uses the object. It returns nil if id does not
+// denote an object.
+func (fs *FileSet) ObjectOf(id *ast.Ident) types.Object {
+	return fs.TypeInfo.ObjectOf(id)
+}
+
+// Lookup returns the package level object with the given name, or nil if no
+// such object is declared.
+func (fs *FileSet) Lookup(name string) types.Object {
+	return fs.Package.Scope().Lookup(name)
+}
+
+// Underlying returns the underlying type of the package level type with the
+// given name, or nil if no such type is declared. Aliases are resolved to the
+// type they denote and any leading asterisks in name are ignored, so
+// Underlying("*T") returns the same type as Underlying("T").
+func (fs *FileSet) Underlying(name string) types.Type {
+	obj, ok := fs.Lookup(strings.TrimLeft(name, "*")).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	return Deref(types.Unalias(obj.Type())).Underlying()
+}
+
+// Deref returns the type pointed to by t after removing all levels of pointer
+// indirection. Aliases are resolved to the type they denote. If t is not a
+// pointer it is returned unchanged.
+func Deref(t types.Type) types.Type {
+	for {
+		t = types.Unalias(t)
+		p, ok := t.(*types.Pointer)
+		if !ok {
+			return t
+		}
+		t = p.Elem()
+	}
+}
+
+// NamedOf returns the named type that t refers to after resolving aliases and
+// removing pointer indirection. It returns nil if t does not refer to a named
+// type.
+func NamedOf(t types.Type) *types.Named {
+	n, _ := Deref(t).(*types.Named)
+	return n
+}
+
+// TypeKind is the kind of type described by a TypeInfo.
+type TypeKind int
+
+const (
+	// KindBasic is a predeclared type such as int or string, or
+	// unsafe.Pointer.
+	KindBasic TypeKind = iota
+
+	// KindNamed is a defined type or an alias, such as time.Time.
+	KindNamed
+
+	// KindPointer is a pointer type.
+	KindPointer
+
+	// KindSlice is a slice type.
+	KindSlice
+
+	// KindArray is an array type.
+	KindArray
+
+	// KindMap is a map type.
+	KindMap
+
+	// KindChan is a channel type.
+	KindChan
+
+	// KindFunc is a function type.
+	KindFunc
+
+	// KindStruct is an anonymous struct type.
+	KindStruct
+
+	// KindInterface is an anonymous interface type.
+	KindInterface
+
+	// KindTypeParam is a type parameter.
+	KindTypeParam
+)
+
+func (k TypeKind) String() string {
+	switch k {
+	case KindBasic:
+		return "basic"
+	case KindNamed:
+		return "named"
+	case KindPointer:
+		return "pointer"
+	case KindSlice:
+		return "slice"
+	case KindArray:
+		return "array"
+	case KindMap:
+		return "map"
+	case KindChan:
+		return "chan"
+	case KindFunc:
+		return "func"
+	case KindStruct:
+		return "struct"
+	case KindInterface:
+		return "interface"
+	case KindTypeParam:
+		return "type parameter"
+	}
+	return fmt.Sprintf("TypeKind(%d)", int(k))
+}
+
+// TypeInfo describes a type expression decomposed into its parts, so that
+// generators can take apart a type such as map[string][]*pkg.Foo without
+// switching over the types of go/types themselves. The description is
+// recursive: the map is described by a TypeInfo whose Key describes string
+// and whose Elem describes []*pkg.Foo, and so on down to the named type
+// pkg.Foo. Named types are not decomposed further.
+type TypeInfo struct {
+	// Kind is the kind of type.
+	Kind TypeKind
+
+	// Type is the described type.
+	Type types.Type
+
+	// Elem describes the element type of a pointer, slice, array, map or
+	// channel. It is nil for other kinds.
+	Elem *TypeInfo
+
+	// Key describes the key type of a map. It is nil for other kinds.
+	Key *TypeInfo
+
+	// Len is the length of an array.
+	Len int64
+
+	// Dir is the direction of a channel.
+	Dir types.ChanDir
+
+	// Name is the name of a basic type, named type or type parameter.
+	Name string
+
+	// Package is the package declaring a named type. It is nil for types
+	// declared in the universe scope, such as error.
+	Package *types.Package
+
+	// TypeArgs describes the type arguments of an instantiated named type.
+	TypeArgs []*TypeInfo
+
+	// Params and Results describe the parameter and result types of a
+	// function.
+	Params  []*TypeInfo
+	Results []*TypeInfo
+
+	// Variadic is true if the final parameter of a function is variadic.
+	// Its description is of the slice type of the parameter, such as
+	// []string for ...string.
+	Variadic bool
+}
+
+// NewTypeInfo returns the description of t.
+func NewTypeInfo(t types.Type) *TypeInfo {
+	ti := &TypeInfo{Type: t}
+	switch t := t.(type) {
+	case *types.Basic:
+		ti.Kind, ti.Name = KindBasic, t.Name()
+	case *types.Named:
+		ti.Kind = KindNamed
+		ti.setName(t.Obj(), t.TypeArgs())
+	case *types.Alias:
+		ti.Kind = KindNamed
+		ti.setName(t.Obj(), t.TypeArgs())
+	case *types.Pointer:
+		ti.Kind, ti.Elem = KindPointer, NewTypeInfo(t.Elem())
+	case *types.Slice:
+		ti.Kind, ti.Elem = KindSlice, NewTypeInfo(t.Elem())
+	case *types.Array:
+		ti.Kind, ti.Elem, ti.Len = KindArray, NewTypeInfo(t.Elem()), t.Len()
+	case *types.Map:
+		ti.Kind, ti.Key, ti.Elem = KindMap, NewTypeInfo(t.Key()), NewTypeInfo(t.Elem())
+	case *types.Chan:
+		ti.Kind, ti.Elem, ti.Dir = KindChan, NewTypeInfo(t.Elem()), t.Dir()
+	case *types.Signature:
+		ti.Kind, ti.Variadic = KindFunc, t.Variadic()
+		for i := 0; i < t.Params().Len(); i++ {
+			ti.Params = append(ti.Params, NewTypeInfo(t.Params().At(i).Type()))
+		}
+		for i := 0; i < t.Results().Len(); i++ {
+			ti.Results = append(ti.Results, NewTypeInfo(t.Results().At(i).Type()))
+		}
+	case *types.Struct:
+		ti.Kind = KindStruct
+	case *types.Interface:
+		ti.Kind = KindInterface
+	case *types.TypeParam:
+		ti.Kind, ti.Name = KindTypeParam, t.Obj().Name()
+	}
+	return ti
+}
+
+// setName sets the name fields of ti to describe the type named by obj
+// instantiated with args.
+func (ti *TypeInfo) setName(obj *types.TypeName, args *types.TypeList) {
+	ti.Name, ti.Package = obj.Name(), obj.Pkg()
+	for i := 0; i < args.Len(); i++ {
+		ti.TypeArgs = append(ti.TypeArgs, NewTypeInfo(args.At(i)))
+	}
+}
+
+// Core returns the description of the type at the core of ti, found by
+// following Elem, such as pkg.Foo for map[string][]*pkg.Foo.
+func (ti *TypeInfo) Core() *TypeInfo {
+	for ti.Elem != nil {
+		ti = ti.Elem
+	}
+	return ti
+}
+
+// Render returns the described type written in Go syntax, qualifying the
+// names of named types by the package names given by the qualifier q, as
+// types.TypeString does. The result is built from the parts of ti rather
+// than from Type, so a generator may change the parts, such as replacing
+// the Elem of a slice, before rendering.
+func (ti *TypeInfo) Render(q types.Qualifier) string {
+	var b strings.Builder
+	ti.render(&b, q)
+	return b.String()
+}
+
+// String returns the described type written in Go syntax with names
+// qualified by their package paths.
+func (ti *TypeInfo) String() string {
+	return ti.Render(nil)
+}
+
+func (ti *TypeInfo) render(b *strings.Builder, q types.Qualifier) {
+	switch ti.Kind {
+	case KindBasic, KindStruct, KindInterface:
+		b.WriteString(types.TypeString(ti.Type, q))
+	case KindNamed:
+		if ti.Package != nil {
+			name := ti.Package.Path()
+			if q != nil {
+				name = q(ti.Package)
+			}
+			if name != "" {
+				b.WriteString(name + ".")
+			}
+		}
+		b.WriteString(ti.Name)
+		if len(ti.TypeArgs) > 0 {
+			b.WriteByte('[')
+			for i, arg := range ti.TypeArgs {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				arg.render(b, q)
+			}
+			b.WriteByte(']')
+		}
+	case KindTypeParam:
+		b.WriteString(ti.Name)
+	case KindPointer:
+		b.WriteByte('*')
+		ti.Elem.render(b, q)
+	case KindSlice:
+		b.WriteString("[]")
+		ti.Elem.render(b, q)
+	case KindArray:
+		b.WriteString("[" + strconv.FormatInt(ti.Len, 10) + "]")
+		ti.Elem.render(b, q)
+	case KindMap:
+		b.WriteString("map[")
+		ti.Key.render(b, q)
+		b.WriteByte(']')
+		ti.Elem.render(b, q)
+	case KindChan:
+		parens := false
+		switch ti.Dir {
+		case types.SendRecv:
+			b.WriteString("chan ")
+			// chan (<-chan T) would otherwise be read as chan<- (chan T).
+			parens = ti.Elem.Kind == KindChan && ti.Elem.Dir == types.RecvOnly
+		case types.SendOnly:
+			b.WriteString("chan<- ")
+		case types.RecvOnly:
+			b.WriteString("<-chan ")
+		}
+		if parens {
+			b.WriteByte('(')
+		}
+		ti.Elem.render(b, q)
+		if parens {
+			b.WriteByte(')')
+		}
+	case KindFunc:
+		b.WriteString("func")
+		ti.renderSignature(b, q)
+	}
+}
+
+// renderSignature writes the parameters and results of a function type.
+func (ti *TypeInfo) renderSignature(b *strings.Builder, q types.Qualifier) {
+	b.WriteByte('(')
+	for i, p := range ti.Params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if ti.Variadic && i == len(ti.Params)-1 && p.Kind == KindSlice {
+			b.WriteString("...")
+			p = p.Elem
+		}
+		p.render(b, q)
+	}
+	b.WriteByte(')')
+
+	switch len(ti.Results) {
+	case 0:
+	case 1:
+		b.WriteByte(' ')
+		ti.Results[0].render(b, q)
+	default:
+		b.WriteString(" (")
+		for i, r := range ti.Results {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			r.render(b, q)
+		}
+		b.WriteByte(')')
+	}
+}
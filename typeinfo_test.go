@@ -0,0 +1,164 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+	"testing"
+)
+
+func TestTypeHelpers(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		type S struct{ A int }
+		type P *S
+		type A = S
+		type PA = *A
+		type N int
+
+		var v = &S{}
+		var n N
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "S", want: "struct{A int}"},
+		{name: "*S", want: "struct{A int}"},
+		{name: "A", want: "struct{A int}"},
+		{name: "PA", want: "struct{A int}"},
+		{name: "P", want: "*S"},
+		{name: "N", want: "int"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := fs.Underlying(tc.name)
+			if u == nil {
+				t.Fatalf("got nil type")
+			}
+			if got := types.TypeString(u, types.RelativeTo(fs.Package)); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+
+	if u := fs.Underlying("v"); u != nil {
+		t.Errorf("got %v for a variable, wanted nil", u)
+	}
+	if u := fs.Underlying("Missing"); u != nil {
+		t.Errorf("got %v for a missing type, wanted nil", u)
+	}
+
+	var vIdent *ast.Ident
+	fs.EachVar(func(vs *ast.ValueSpec) bool {
+		if vs.Names[0].Name == "v" {
+			vIdent = vs.Names[0]
+		}
+		return true
+	})
+
+	obj := fs.ObjectOf(vIdent)
+	if obj == nil || obj.Name() != "v" {
+		t.Fatalf("got object %v, wanted v", obj)
+	}
+
+	typ := fs.TypeOf(vIdent)
+	if typ == nil {
+		t.Fatalf("got nil type for v")
+	}
+	if named := NamedOf(typ); named == nil || named.Obj().Name() != "S" {
+		t.Errorf("got named type %v, wanted S", named)
+	}
+
+	if NamedOf(types.Typ[types.Int]) != nil {
+		t.Errorf("got named type for int")
+	}
+}
+
+func TestTypeInfo(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "time"
+
+		type Foo struct{}
+		type List[T any] []T
+		type Error = error
+
+		var (
+			v1 map[string][]*time.Time
+			v2 [4]chan<- Foo
+			v3 chan (<-chan int)
+			v4 func(string, ...*Foo) (int, error)
+			v5 List[map[int]Foo]
+			v6 struct{ A time.Duration }
+			v7 func()
+			v8 Error
+		)
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := types.RelativeTo(fs.Package)
+
+	testCases := []struct {
+		name string
+		kind TypeKind
+		want string
+	}{
+		{name: "v1", kind: KindMap, want: "map[string][]*time.Time"},
+		{name: "v2", kind: KindArray, want: "[4]chan<- Foo"},
+		{name: "v3", kind: KindChan, want: "chan (<-chan int)"},
+		{name: "v4", kind: KindFunc, want: "func(string, ...*Foo) (int, error)"},
+		{name: "v5", kind: KindNamed, want: "List[map[int]Foo]"},
+		{name: "v6", kind: KindStruct, want: "struct{A time.Duration}"},
+		{name: "v7", kind: KindFunc, want: "func()"},
+		{name: "v8", kind: KindNamed, want: "Error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ti := NewTypeInfo(fs.Lookup(tc.name).Type())
+			if ti.Kind != tc.kind {
+				t.Errorf("got kind %v, wanted %v", ti.Kind, tc.kind)
+			}
+			if got := ti.Render(q); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+
+	ti := NewTypeInfo(fs.Lookup("v1").Type())
+	if ti.Key.Kind != KindBasic || ti.Key.Name != "string" {
+		t.Errorf("got key %+v, wanted string", ti.Key)
+	}
+	core := ti.Core()
+	if core.Kind != KindNamed || core.Name != "Time" || core.Package.Path() != "time" {
+		t.Errorf("got core %+v, wanted time.Time", core)
+	}
+	if got, want := ti.String(), "map[string][]*time.Time"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	// Changing a part changes the rendering.
+	ti.Elem = NewTypeInfo(fs.Lookup("v2").Type())
+	if got, want := ti.Render(q), "map[string][4]chan<- Foo"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	arr := NewTypeInfo(fs.Lookup("v2").Type())
+	if arr.Len != 4 || arr.Elem.Dir != types.SendOnly {
+		t.Errorf("got %+v, wanted array of 4 send only channels", arr)
+	}
+	list := NewTypeInfo(fs.Lookup("v5").Type())
+	if len(list.TypeArgs) != 1 || list.TypeArgs[0].Kind != KindMap {
+		t.Errorf("got type args %+v, wanted one map", list.TypeArgs)
+	}
+	fn := NewTypeInfo(fs.Lookup("v4").Type())
+	if !fn.Variadic || len(fn.Params) != 2 || len(fn.Results) != 2 {
+		t.Errorf("got %+v, wanted variadic func with 2 params and 2 results", fn)
+	}
+}
@@ -0,0 +1,125 @@
+package gen
+
+import (
+	"go/token"
+	"go/types"
+)
+
+// TypeRef describes a reference to a type, such as the type of a field,
+// decomposed so that generators can refer to the type from generated code
+// without parsing type expressions. A reference to a composite type, such as
+// []*api.User, describes its outermost type constructor with the Is fields
+// and its element with Elem. The name fields describe the named type at the
+// core of the reference, found by following Elem, which is api.User for
+// []*api.User and for map[string]*api.User.
+type TypeRef struct {
+	// Type is the referenced type.
+	Type types.Type
+
+	// IsPointer, IsSlice, IsArray, IsMap and IsChan report the outermost
+	// type constructor of the reference. At most one of them is true.
+	IsPointer bool
+	IsSlice   bool
+	IsArray   bool
+	IsMap     bool
+	IsChan    bool
+
+	// Elem is the reference to the element type of a pointer, slice, array,
+	// map or channel. It is nil for other types.
+	Elem *TypeRef
+
+	// Key is the reference to the key type of a map. It is nil for other
+	// types.
+	Key *TypeRef
+
+	// Name is the name of the core named type or alias, such as User, or of
+	// a predeclared type, such as string. It is empty if the core type is
+	// not named, such as an anonymous struct or function type.
+	Name string
+
+	// PackageName is the name of the package that declares the core named
+	// type, such as api. It is empty for predeclared types.
+	PackageName string
+
+	// ImportPath is the import path of the package that declares the core
+	// named type, such as example.com/m/api. It is empty for predeclared
+	// types.
+	ImportPath string
+
+	// Qualified is the name of the core named type qualified by the name of
+	// its package, such as api.User, or the name alone for predeclared
+	// types.
+	Qualified string
+
+	// Exported is true if the core named type may be referred to from other
+	// packages: it is exported or predeclared.
+	Exported bool
+
+	// TypeArgs holds references to the type arguments of an instantiated
+	// core generic type, such as the int of api.List[int].
+	TypeArgs []*TypeRef
+}
+
+// NewTypeRef returns the reference to t.
+func NewTypeRef(t types.Type) *TypeRef {
+	r := &TypeRef{Type: t}
+	switch u := t.(type) {
+	case *types.Pointer:
+		r.IsPointer, r.Elem = true, NewTypeRef(u.Elem())
+	case *types.Slice:
+		r.IsSlice, r.Elem = true, NewTypeRef(u.Elem())
+	case *types.Array:
+		r.IsArray, r.Elem = true, NewTypeRef(u.Elem())
+	case *types.Map:
+		r.IsMap, r.Key, r.Elem = true, NewTypeRef(u.Key()), NewTypeRef(u.Elem())
+	case *types.Chan:
+		r.IsChan, r.Elem = true, NewTypeRef(u.Elem())
+	case *types.Basic:
+		r.Name, r.Qualified, r.Exported = u.Name(), u.Name(), true
+	case *types.Named:
+		r.setName(u.Obj(), u.TypeArgs())
+	case *types.Alias:
+		r.setName(u.Obj(), u.TypeArgs())
+	}
+	if r.Elem != nil {
+		r.Name, r.PackageName, r.ImportPath = r.Elem.Name, r.Elem.PackageName, r.Elem.ImportPath
+		r.Qualified, r.Exported, r.TypeArgs = r.Elem.Qualified, r.Elem.Exported, r.Elem.TypeArgs
+	}
+	return r
+}
+
+// setName sets the name fields of r to describe the type named by obj
+// instantiated with args.
+func (r *TypeRef) setName(obj *types.TypeName, args *types.TypeList) {
+	r.Name, r.Qualified = obj.Name(), obj.Name()
+	r.Exported = obj.Pkg() == nil || token.IsExported(obj.Name())
+	if obj.Pkg() != nil {
+		r.PackageName, r.ImportPath = obj.Pkg().Name(), obj.Pkg().Path()
+		r.Qualified = r.PackageName + "." + r.Name
+	}
+	for i := 0; i < args.Len(); i++ {
+		r.TypeArgs = append(r.TypeArgs, NewTypeRef(args.At(i)))
+	}
+}
+
+// String returns the referenced type written as it would be in the package
+// pkg, qualifying the names of types declared in other packages by their
+// package names.
+func (r *TypeRef) String(pkg *types.Package) string {
+	return types.TypeString(r.Type, func(p *types.Package) string {
+		if p == pkg || (pkg != nil && p.Path() == pkg.Path()) {
+			return ""
+		}
+		return p.Name()
+	})
+}
+
+// TypeRef returns the reference to the type of the field.
+func (m *FieldModel) TypeRef() *TypeRef {
+	return NewTypeRef(m.Type)
+}
+
+// TypeRef returns the reference to the type of the parameter.
+func (m *ParamModel) TypeRef() *TypeRef {
+	return NewTypeRef(m.Type)
+}
@@ -0,0 +1,97 @@
+package gen
+
+import (
+	"testing"
+)
+
+func TestTypeRef(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+import (
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+type local struct{}
+
+type T struct {
+	Timeout  time.Duration
+	Link     *url.URL
+	Links    []*url.URL
+	ByName   map[string]time.Duration
+	Err      error
+	Count    int
+	Events   chan int
+	Grid     [2]local
+	Anon     struct{ X int }
+	Counter  atomic.Pointer[url.URL]
+}
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tm, _ := fs.Type("T")
+
+	type ref struct {
+		Constructor string
+		Name        string
+		PackageName string
+		ImportPath  string
+		Qualified   string
+		Exported    bool
+		String      string
+	}
+	want := map[string]ref{
+		"Timeout": {Name: "Duration", PackageName: "time", ImportPath: "time", Qualified: "time.Duration", Exported: true, String: "time.Duration"},
+		"Link":    {Constructor: "pointer", Name: "URL", PackageName: "url", ImportPath: "net/url", Qualified: "url.URL", Exported: true, String: "*url.URL"},
+		"Links":   {Constructor: "slice", Name: "URL", PackageName: "url", ImportPath: "net/url", Qualified: "url.URL", Exported: true, String: "[]*url.URL"},
+		"ByName":  {Constructor: "map", Name: "Duration", PackageName: "time", ImportPath: "time", Qualified: "time.Duration", Exported: true, String: "map[string]time.Duration"},
+		"Err":     {Name: "error", Qualified: "error", Exported: true, String: "error"},
+		"Count":   {Name: "int", Qualified: "int", Exported: true, String: "int"},
+		"Events":  {Constructor: "chan", Name: "int", Qualified: "int", Exported: true, String: "chan int"},
+		"Grid":    {Constructor: "array", Name: "local", PackageName: "p", ImportPath: fs.Package.Path(), Qualified: "p.local", String: "[2]local"},
+		"Anon":    {String: "struct{X int}"},
+		"Counter": {Name: "Pointer", PackageName: "atomic", ImportPath: "sync/atomic", Qualified: "atomic.Pointer", Exported: true, String: "atomic.Pointer[url.URL]"},
+	}
+
+	for _, f := range tm.Fields {
+		t.Run(f.Name, func(t *testing.T) {
+			r := f.TypeRef()
+			got := ref{
+				Name:        r.Name,
+				PackageName: r.PackageName,
+				ImportPath:  r.ImportPath,
+				Qualified:   r.Qualified,
+				Exported:    r.Exported,
+				String:      r.String(fs.Package),
+			}
+			switch {
+			case r.IsPointer:
+				got.Constructor = "pointer"
+			case r.IsSlice:
+				got.Constructor = "slice"
+			case r.IsArray:
+				got.Constructor = "array"
+			case r.IsMap:
+				got.Constructor = "map"
+			case r.IsChan:
+				got.Constructor = "chan"
+			}
+			if got != want[f.Name] {
+				t.Errorf("got %+v, wanted %+v", got, want[f.Name])
+			}
+		})
+	}
+
+	links := tm.Fields[2].TypeRef()
+	if !links.Elem.IsPointer || links.Elem.Elem.IsPointer || links.Elem.Elem.Qualified != "url.URL" {
+		t.Errorf("got element %+v, wanted a pointer to url.URL", links.Elem)
+	}
+	if byName := tm.Fields[3].TypeRef(); byName.Key == nil || byName.Key.Name != "string" {
+		t.Errorf("got key %+v, wanted string", byName.Key)
+	}
+	if counter := tm.Fields[9].TypeRef(); len(counter.TypeArgs) != 1 || counter.TypeArgs[0].Qualified != "url.URL" {
+		t.Errorf("got type arguments %+v, wanted url.URL", counter.TypeArgs)
+	}
+}
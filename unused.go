@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Unused returns the unexported package level types, functions, constants
+// and variables declared in fs that are not used anywhere in it, ordered by
+// their position in the source. A use within the declaration itself, such as
+// a recursive call or a method's receiver type, does not count, but a use
+// from another unused declaration does, so removing the declarations
+// returned may leave others unused. Methods, init functions and the main
+// function of a main package are never reported, since they may be used
+// without being named.
+func (fs *FileSet) Unused() []types.Object {
+	used := make(map[types.Object]bool)
+	fs.addUses(used)
+	var unused []types.Object
+	for _, obj := range fs.unusedCandidates(used) {
+		if !obj.Exported() {
+			unused = append(unused, obj)
+		}
+	}
+	return unused
+}
+
+// Unused returns the package level types, functions, constants and
+// variables declared in the packages of the workspace that are not used
+// anywhere in it, ordered by package path and then by position. It is like
+// FileSet.Unused for each package, except that exported declarations are
+// reported too unless they are used by a package of the workspace or
+// declared in a test file. An exported declaration of a package intended to
+// be imported by code outside the workspace may be reported even though it
+// is part of that package's API.
+func (w *Workspace) Unused() []types.Object {
+	used := make(map[types.Object]bool)
+	for _, fs := range w.Packages {
+		fs.addUses(used)
+	}
+	var unused []types.Object
+	for _, fs := range w.Packages {
+		for _, obj := range fs.unusedCandidates(used) {
+			if obj.Exported() && strings.HasSuffix(fs.FileSet.Position(obj.Pos()).Filename, "_test.go") {
+				continue
+			}
+			unused = append(unused, obj)
+		}
+	}
+	return unused
+}
+
+// unusedCandidates returns the package level declarations of fs that could
+// be unused and are not in used, ordered by position.
+func (fs *FileSet) unusedCandidates(used map[types.Object]bool) []types.Object {
+	var objs []types.Object
+	scope := fs.Package.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if used[obj] || name == "init" || (name == "main" && fs.Package.Name() == "main") {
+			continue
+		}
+		switch obj.(type) {
+		case *types.TypeName, *types.Func, *types.Const, *types.Var:
+			objs = append(objs, obj)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Pos() < objs[j].Pos() })
+	return objs
+}
+
+// addUses records in used the objects used in fs outside their own
+// declarations.
+func (fs *FileSet) addUses(used map[types.Object]bool) {
+	for _, file := range fs.AstFiles {
+		for _, decl := range file.Decls {
+			self := make(map[types.Object]bool)
+			var nodes []ast.Node
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					self[fs.TypeInfo.Defs[decl.Name]] = true
+				}
+				// The receiver is not a use of its type.
+				nodes = append(nodes, decl.Type)
+				if decl.Body != nil {
+					nodes = append(nodes, decl.Body)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						self[fs.TypeInfo.Defs[spec.Name]] = true
+					case *ast.ValueSpec:
+						for _, id := range spec.Names {
+							self[fs.TypeInfo.Defs[id]] = true
+						}
+					}
+				}
+				nodes = append(nodes, decl)
+			}
+			for _, n := range nodes {
+				ast.Inspect(n, func(n ast.Node) bool {
+					id, ok := n.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					obj := fs.TypeInfo.Uses[id]
+					if fn, ok := obj.(*types.Func); ok {
+						obj = fn.Origin()
+					}
+					if obj != nil && !self[obj] {
+						used[obj] = true
+					}
+					return true
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func objectNames(objs []types.Object) []string {
+	names := []string{}
+	for _, obj := range objs {
+		names = append(names, obj.Name())
+	}
+	return names
+}
+
+func TestUnused(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+import "strings"
+
+const (
+	maxSize = 10
+	minSize = 1
+)
+
+var cache = map[string]int{}
+
+type config struct{ name string }
+
+func (c *config) normalize() { c.name = strings.TrimSpace(c.name) }
+
+type helper int
+
+func (h helper) String() string { return "helper" }
+
+func recurse(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return recurse(n - 1)
+}
+
+func identity[T any](v T) T { return v }
+
+func Size() int { return identity(maxSize) }
+
+func Load() { cache["x"] = 1 }
+
+func init() { _ = helper(0) }
+
+var _ = minSize
+
+func Exported() {}
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := objectNames(fs.Unused())
+	if want := []string{"config", "recurse"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestWorkspaceUnused(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"api/api.go":      "package api\n\ntype User struct{}\n\ntype Group struct{}\n\nfunc unused() {}\n",
+		"api/api_test.go": "package api\n\nimport \"testing\"\n\nfunc TestUser(t *testing.T) { _ = User{} }\n",
+		"cmd/main.go":     "package main\n\nimport \"example.com/p/api\"\n\nfunc main() { _ = api.User{} }\n",
+	})
+	w, err := LoadWorkspace(dir, []string{"./..."}, WithTests(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := objectNames(w.Unused())
+	if want := []string{"Group", "unused"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
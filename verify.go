@@ -0,0 +1,97 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// StaleFile describes a generated file that does not match the output that
+// would be written to it.
+type StaleFile struct {
+	// Filename is the name of the file.
+	Filename string
+
+	// Missing is true if the file does not exist.
+	Missing bool
+
+	// Diff is a unified diff of the changes that regenerating the file would
+	// make, ignoring timestamps in its header.
+	Diff []byte
+}
+
+// timestampRx matches a date, optionally followed by a time, such as
+// 2006-01-02 or 2006-01-02T15:04:05Z.
+var timestampRx = regexp.MustCompile(`\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?`)
+
+// Verify compares the outputs, keyed by the filename they would be written
+// to, with the files on disk byte for byte and returns the files that are
+// stale, ordered by filename. Comment lines before the package clause that
+// differ only in a timestamp, such as a generation date in a header, are
+// treated as equal. Nothing is written. Verify is intended for tests that
+// assert generated code is fresh:
+//
+//	stale, err := gen.Verify(outputs)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	for _, s := range stale {
+//		t.Errorf("%s is out of date:\n%s", s.Filename, s.Diff)
+//	}
+//
+// An error is returned only if an output cannot be formatted or a file
+// cannot be read.
+func Verify(outputs map[string]*Output) ([]StaleFile, error) {
+	filenames := make([]string, 0, len(outputs))
+	for filename := range outputs {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var stale []StaleFile
+	for _, filename := range filenames {
+		src, err := outputs[filename].Source()
+		if err != nil {
+			return nil, err
+		}
+		existing, err := os.ReadFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			stale = append(stale, StaleFile{
+				Filename: filename,
+				Missing:  true,
+				Diff:     UnifiedDiff("/dev/null", filename, nil, src),
+			})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if bytes.Equal(existing, src) || bytes.Equal(withoutTimestamps(existing), withoutTimestamps(src)) {
+			continue
+		}
+		stale = append(stale, StaleFile{
+			Filename: filename,
+			Diff:     UnifiedDiff(filename, filename, withoutTimestamps(existing), withoutTimestamps(src)),
+		})
+	}
+	return stale, nil
+}
+
+// withoutTimestamps returns src with the timestamps removed from the comment
+// lines that precede the package clause.
+func withoutTimestamps(src []byte) []byte {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("package ")) {
+			break
+		}
+		if bytes.HasPrefix(trimmed, []byte("//")) {
+			lines[i] = timestampRx.ReplaceAll(line, nil)
+		}
+	}
+	return bytes.Join(lines, nil)
+}
@@ -0,0 +1,64 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return filename
+	}
+	output := func(header, src string) *Output {
+		o := NewOutput("gen")
+		o.Printf("%s%s", header, src)
+		return o
+	}
+
+	const header = "// Code generated by gen; DO NOT EDIT.\n\n"
+	fresh := write("fresh.go", header+"package p\n\nconst A = 1\n")
+	dated := write("dated.go", header+"// Generated at 2023-01-02T15:04:05Z.\n\npackage p\n\n// Since 2023-01-02.\nconst A = 1\n")
+	outdated := write("outdated.go", header+"package p\n\nconst A = 1\n")
+	missing := filepath.Join(dir, "missing.go")
+
+	stale, err := Verify(map[string]*Output{
+		fresh:    output("", "package p\n\nconst A = 1\n"),
+		dated:    output("// Generated at 2024-06-07T08:09:10+01:00.\n\n", "package p\n\n// Since 2023-01-02.\nconst A = 1\n"),
+		outdated: output("", "package p\n\nconst A = 2\n"),
+		missing:  output("", "package p\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stale) != 2 {
+		t.Fatalf("got %d stale files, wanted 2: %+v", len(stale), stale)
+	}
+	if stale[0].Filename != missing || !stale[0].Missing || !strings.Contains(string(stale[0].Diff), "+package p") {
+		t.Errorf("got %+v for missing file", stale[0])
+	}
+	if stale[1].Filename != outdated || stale[1].Missing || !strings.Contains(string(stale[1].Diff), "-const A = 1\n+const A = 2\n") {
+		t.Errorf("got %+v for outdated file", stale[1])
+	}
+
+	// Timestamps after the package clause are significant.
+	stale, err = Verify(map[string]*Output{
+		dated: output("// Generated at 2023-01-02T15:04:05Z.\n\n", "package p\n\n// Since 2024-01-02.\nconst A = 1\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stale) != 1 {
+		t.Errorf("got %d stale files, wanted 1", len(stale))
+	}
+
+	if _, err := Verify(map[string]*Output{fresh: output("", "package p\n\nfunc {\n")}); err == nil {
+		t.Errorf("got no error for invalid output, wanted one")
+	}
+}
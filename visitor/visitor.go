@@ -0,0 +1,117 @@
+// Package visitor generates visitor interfaces and dispatch functions for
+// sealed interfaces.
+//
+// A sealed interface has an unexported method, often called a marker, so
+// that only the types declared in its package can implement it:
+//
+//	type Event interface {
+//		isEvent()
+//	}
+//
+// For such an interface the generated code is a visitor interface with a
+// method for each implementing type declared in the package, and a function
+// that calls the method for the dynamic type of a value:
+//
+//	type EventVisitor interface {
+//		VisitCreated(x Created)
+//		VisitDeleted(x *Deleted)
+//	}
+//
+//	func VisitEvent(x Event, v EventVisitor)
+//
+// A type whose methods have value receivers is visited by value and one that
+// implements the interface only through a pointer is visited by pointer. The
+// visitor and dispatch function of an unexported interface are unexported.
+package visitor
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"github.com/iand/gen"
+)
+
+// Generate writes the visitor interface and dispatch function of the named
+// interface to o. It returns an error if the interface is not declared in
+// fs, is not sealed or has no implementations.
+func Generate(fs *gen.FileSet, o *gen.Output, interfaceName string) error {
+	tn, ok := fs.Lookup(interfaceName).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("type %s not found", interfaceName)
+	}
+	iface, ok := tn.Type().Underlying().(*types.Interface)
+	if !ok {
+		return fmt.Errorf("%s is not an interface type", interfaceName)
+	}
+	if !sealed(iface) {
+		return fmt.Errorf("%s is not sealed: it has no unexported methods", interfaceName)
+	}
+	impls, err := fs.Implementers(interfaceName)
+	if err != nil {
+		return err
+	}
+	if len(impls) == 0 {
+		return fmt.Errorf("no types implement %s", interfaceName)
+	}
+	seen := make(map[string]string)
+	for _, impl := range impls {
+		name := gen.Export(impl.Type.Name)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("types %s and %s both have visitor method Visit%s", other, impl.Type.Name, name)
+		}
+		seen[name] = impl.Type.Name
+	}
+
+	imports := gen.NewImports()
+	qual := imports.Qualifier(fs.Package)
+	visitorName := interfaceName + "Visitor"
+	dispatchName := "Visit" + gen.Export(interfaceName)
+	if !token.IsExported(interfaceName) {
+		dispatchName = "visit" + gen.Export(interfaceName)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "\n// %s has a method for each type implementing %s.\n", visitorName, interfaceName)
+	fmt.Fprintf(&body, "type %s interface {\n", visitorName)
+	for _, impl := range impls {
+		fmt.Fprintf(&body, "\tVisit%s(x %s)\n", gen.Export(impl.Type.Name), caseType(impl, qual))
+	}
+	body.WriteString("}\n")
+
+	fmt.Fprintf(&body, "\n// %s calls the method of v for the dynamic type of x. It panics if x is nil\n", dispatchName)
+	fmt.Fprintf(&body, "// or its type is not one of the types implementing %s.\n", interfaceName)
+	fmt.Fprintf(&body, "func %s(x %s, v %s) {\n", dispatchName, interfaceName, visitorName)
+	body.WriteString("\tswitch x := x.(type) {\n")
+	for _, impl := range impls {
+		fmt.Fprintf(&body, "\tcase %s:\n\t\tv.Visit%s(x)\n", caseType(impl, qual), gen.Export(impl.Type.Name))
+	}
+	fmt.Fprintf(&body, "\tdefault:\n\t\tpanic(%s.Sprintf(\"%s: unexpected type %%T\", x))\n", imports.Add("fmt", ""), dispatchName)
+	body.WriteString("\t}\n}\n")
+
+	o.Printf("package %s\n\n", fs.Package.Name())
+	o.Printf("%s\n", imports.Block())
+	o.Write(body.Bytes())
+	return nil
+}
+
+// sealed reports whether iface has an unexported method, so that it can only
+// be implemented by types declared in its own package.
+func sealed(iface *types.Interface) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		if !iface.Method(i).Exported() {
+			return true
+		}
+	}
+	return false
+}
+
+// caseType returns the type visited for impl.
+func caseType(impl gen.Implementer, qual types.Qualifier) string {
+	t := impl.Type.Object.Type()
+	if impl.Pointer {
+		t = types.NewPointer(t)
+	}
+	return types.TypeString(t, qual)
+}
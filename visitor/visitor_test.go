@@ -0,0 +1,189 @@
+package visitor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/gen"
+)
+
+const testSrc = `package p
+
+type Event interface {
+	isEvent()
+}
+
+type Created struct {
+	Name string
+}
+
+func (Created) isEvent() {}
+
+type Deleted struct {
+	Name string
+}
+
+func (*Deleted) isEvent() {}
+
+type Renamed struct {
+	From, To string
+}
+
+func (Renamed) isEvent() {}
+
+type node interface {
+	String() string
+	node()
+}
+
+type leaf int
+
+func (leaf) String() string { return "leaf" }
+func (leaf) node()          {}
+`
+
+func TestGenerate(t *testing.T) {
+	fs, err := gen.NewFileSetFromTexts(testSrc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("visitor")
+	if err := Generate(fs, o, "Event"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err := o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"type EventVisitor interface {\n\tVisitCreated(x Created)\n\tVisitDeleted(x *Deleted)\n\tVisitRenamed(x Renamed)\n}",
+		"func VisitEvent(x Event, v EventVisitor) {",
+		"\tcase *Deleted:\n\t\tv.VisitDeleted(x)\n",
+		`panic(fmt.Sprintf("VisitEvent: unexpected type %T", x))`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+
+	o = gen.NewOutput("visitor")
+	if err := Generate(fs, o, "node"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src, err = o.Source()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"type nodeVisitor interface {\n\tVisitLeaf(x leaf)\n}",
+		"func visitNode(x node, v nodeVisitor) {",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source does not contain %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		typ  string
+	}{
+		{name: "missing", src: "package p\n", typ: "T"},
+		{name: "not interface", src: "package p\ntype T struct{}\n", typ: "T"},
+		{name: "not sealed", src: "package p\ntype T interface{ M() }\ntype U int\nfunc (U) M() {}\n", typ: "T"},
+		{name: "no implementations", src: "package p\ntype T interface{ t() }\n", typ: "T"},
+		{name: "method clash", src: "package p\ntype T interface{ t() }\ntype u int\nfunc (u) t() {}\ntype U int\nfunc (U) t() {}\n", typ: "T"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := gen.NewFileSetFromTexts(tc.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := Generate(fs, gen.NewOutput("visitor"), tc.typ); err == nil {
+				t.Errorf("got no error, wanted one")
+			}
+		})
+	}
+}
+
+const testProgram = `package p
+
+import "testing"
+
+type recorder []string
+
+func (r *recorder) VisitCreated(x Created)  { *r = append(*r, "created "+x.Name) }
+func (r *recorder) VisitDeleted(x *Deleted) { *r = append(*r, "deleted "+x.Name) }
+func (r *recorder) VisitRenamed(x Renamed)  { *r = append(*r, "renamed "+x.From+" "+x.To) }
+
+func TestVisit(t *testing.T) {
+	var r recorder
+	for _, e := range []Event{Created{"a"}, &Deleted{"b"}, Renamed{"c", "d"}} {
+		VisitEvent(e, &r)
+	}
+	want := []string{"created a", "deleted b", "renamed c d"}
+	if len(r) != len(want) {
+		t.Fatalf("got %v, wanted %v", r, want)
+	}
+	for i := range want {
+		if r[i] != want[i] {
+			t.Errorf("got %v, wanted %v", r, want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("got no panic for nil event")
+		}
+	}()
+	VisitEvent(nil, &r)
+}
+`
+
+func TestGeneratedVisitor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.21\n",
+		"p.go":      testSrc,
+		"p_test.go": testProgram,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	fs, err := gen.NewFileSet([]string{filepath.Join(dir, "p.go")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o := gen.NewOutput("visitor")
+	if err := Generate(fs, o, "Event"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.WriteFile(filepath.Join(dir, "event_visitor.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		src, _ := o.Source()
+		t.Fatalf("generated visitor failed: %v\n%s\n%s", err, out, src)
+	}
+}
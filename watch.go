@@ -0,0 +1,208 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the time a Watcher waits after the last change to a
+// directory before regenerating it, unless another is given with
+// WatchDebounce.
+const DefaultDebounce = 100 * time.Millisecond
+
+// WatchOption configures a Watcher.
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	debounce time.Duration
+	load     []Option
+	report   func(dir string, err error)
+}
+
+// WatchDebounce sets the time a Watcher waits after the last change to a
+// directory before regenerating it, so that a burst of changes, such as an
+// editor saving several files or a version control checkout, causes a single
+// regeneration.
+func WatchDebounce(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.debounce = d
+	}
+}
+
+// WatchLoadOptions sets the options used to load the watched packages.
+func WatchLoadOptions(opts ...Option) WatchOption {
+	return func(o *watchOptions) {
+		o.load = append(o.load, opts...)
+	}
+}
+
+// WatchErrors sets the function called with the errors that occur while
+// watching, such as a package that fails to load or a generation that fails.
+// dir is the directory being regenerated, or empty for an error reported by
+// the file system. By default errors are written to standard error.
+func WatchErrors(report func(dir string, err error)) WatchOption {
+	return func(o *watchOptions) {
+		o.report = report
+	}
+}
+
+// Watcher regenerates code when the Go source files of packages change. It
+// is created by Watch.
+type Watcher struct {
+	fsw      *fsnotify.Watcher
+	cache    *PackageCache
+	dirs     map[string]bool
+	generate func(*FileSet) error
+	opts     watchOptions
+	cancel   context.CancelFunc // cancels loading when the Watcher is closed
+	done     chan struct{}
+}
+
+// Watch loads the package in each of dirs and calls generate with it, then
+// watches the directories and repeats this for a package whenever a Go source
+// file in its directory is created, written, removed or renamed, providing a
+// development mode for generators. Changes are debounced, see WatchDebounce,
+// and files whose contents carry the standard generated code header, such as
+// the outputs written by generate, are ignored so that regeneration does not
+// trigger itself. Files whose names start with . or _, which the go command
+// ignores, are also ignored. Packages are loaded through a PackageCache, so
+// regeneration only type checks the packages that changed.
+//
+// Errors returned by generate are reported as configured by WatchErrors and
+// do not stop the Watcher. Watch returns once watching has started; the
+// initial generation runs in the background. Call Close to stop watching.
+func Watch(dirs []string, generate func(*FileSet) error, opts ...WatchOption) (*Watcher, error) {
+	o := watchOptions{
+		debounce: DefaultDebounce,
+		report: func(dir string, err error) {
+			if dir == "" {
+				fmt.Fprintf(os.Stderr, "gen: watch: %v\n", err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "gen: %s: %v\n", dir, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{
+		fsw:      fsw,
+		cache:    NewPackageCache(append(o.load[:len(o.load):len(o.load)], WithContext(ctx))...),
+		dirs:     make(map[string]bool, len(dirs)),
+		generate: generate,
+		opts:     o,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err == nil {
+			err = fsw.Add(abs)
+		}
+		if err != nil {
+			cancel()
+			fsw.Close()
+			return nil, fmt.Errorf("watch %s: %w", dir, err)
+		}
+		w.dirs[abs] = true
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching, cancels the loading of any package in progress and
+// waits for any generation in progress to finish.
+func (w *Watcher) Close() error {
+	w.cancel()
+	err := w.fsw.Close()
+	<-w.done
+	return err
+}
+
+// run handles file system events until the Watcher is closed.
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	pending := make(map[string]bool, len(w.dirs))
+	for dir := range w.dirs {
+		pending[dir] = true
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if dir, ok := w.changed(ev); ok {
+				pending[dir] = true
+				timer.Reset(w.opts.debounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.opts.report("", err)
+		case <-timer.C:
+			dirs := make([]string, 0, len(pending))
+			for dir := range pending {
+				dirs = append(dirs, dir)
+			}
+			sort.Strings(dirs)
+			clear(pending)
+			for _, dir := range dirs {
+				w.regenerate(dir)
+			}
+		}
+	}
+}
+
+// changed reports the watched directory whose package is changed by ev, if
+// any.
+func (w *Watcher) changed(ev fsnotify.Event) (string, bool) {
+	if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Remove) && !ev.Has(fsnotify.Rename) {
+		return "", false
+	}
+	base := filepath.Base(ev.Name)
+	if !strings.HasSuffix(base, ".go") || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") {
+		return "", false
+	}
+	dir := filepath.Dir(ev.Name)
+	if !w.dirs[dir] {
+		return "", false
+	}
+	if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write) {
+		if src, err := os.ReadFile(ev.Name); err == nil && IsGenerated(src) {
+			return "", false
+		}
+	}
+	return dir, true
+}
+
+// regenerate loads the package in dir and calls the generate function.
+func (w *Watcher) regenerate(dir string) {
+	fs, err := w.cache.Load(dir)
+	if err == nil {
+		err = w.generate(fs)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		w.opts.report(dir, err)
+	}
+}
@@ -0,0 +1,86 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"p.go": "package p\n\ntype A int\n",
+	})
+	output := filepath.Join(dir, "types_gen.go")
+
+	runs := make(chan []string, 10)
+	generate := func(fs *FileSet) error {
+		var names []string
+		for _, tm := range fs.Types() {
+			names = append(names, tm.Name)
+		}
+		o := NewOutput("watchtest")
+		o.Printf("package p\n\nconst NumTypes = %d\n", len(names))
+		if err := o.WriteFile(output); err != nil {
+			return err
+		}
+		runs <- names
+		return nil
+	}
+	errs := make(chan error, 10)
+	w, err := Watch([]string{dir}, generate, WatchDebounce(20*time.Millisecond), WatchErrors(func(dir string, err error) {
+		errs <- err
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	wait := func(want int) {
+		t.Helper()
+		select {
+		case names := <-runs:
+			if len(names) != want {
+				t.Errorf("got types %v, wanted %d", names, want)
+			}
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for generation")
+		}
+	}
+	quiet := func() {
+		t.Helper()
+		select {
+		case names := <-runs:
+			t.Errorf("unexpected generation with types %v", names)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	wait(1)
+	quiet() // writing the generated file must not trigger a regeneration
+
+	for _, src := range []string{"package p\n\ntype B int\n", "package p\n\ntype C int\n"} {
+		name := filepath.Join(dir, "b.go")
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	wait(2)
+	quiet()
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	quiet()
+
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+}
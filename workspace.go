@@ -0,0 +1,352 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Workspace is a set of packages loaded together, such as all the packages
+// of a module matched by ./..., for generators that aggregate declarations
+// from many packages, for example into a registry file. The packages share a
+// token.FileSet and the types of their dependencies, and a package of the
+// workspace that imports another sees the same type checked package, so
+// types from different packages of the workspace can be compared and
+// resolved against each other.
+type Workspace struct {
+	// FileSet records the positions of the files of all the packages.
+	FileSet *token.FileSet
+
+	// Packages holds the packages of the workspace, sorted by import path.
+	// Each has the workspace's FileSet.
+	Packages []*FileSet
+
+	byPath map[string]*FileSet
+	ssa    *ssa.Program
+}
+
+// listedPackage is the description of a package reported by go list.
+type listedPackage struct {
+	Dir         string
+	ImportPath  string
+	GoFiles     []string
+	TestGoFiles []string
+	Imports     []string
+	TestImports []string
+	Error       *struct{ Err string }
+}
+
+// LoadWorkspace loads the packages matched by patterns, such as ./... or
+// example.com/m/api/..., interpreted by the go command in dir. Options control
+// how the packages are loaded; with WithTests the packages' own test files
+// are included but external test packages are not loaded. It returns an error
+// if no package matches or a package fails to type check.
+func LoadWorkspace(dir string, patterns []string, opts ...Option) (*Workspace, error) {
+	o := newOptions(opts)
+	ctxt := o.buildContext()
+	listed, err := listPackages(dir, o, patterns)
+	if err != nil {
+		return nil, err
+	}
+	if len(listed) == 0 {
+		return nil, fmt.Errorf("no packages match %s", strings.Join(patterns, " "))
+	}
+
+	w := &Workspace{
+		FileSet: token.NewFileSet(),
+		byPath:  make(map[string]*FileSet, len(listed)),
+	}
+	byPath := make(map[string]*listedPackage, len(listed))
+	external := make(map[string]bool)
+	for _, p := range listed {
+		byPath[p.ImportPath] = p
+	}
+	for _, p := range listed {
+		for _, imp := range p.imports(o.tests) {
+			if byPath[imp] == nil && imp != "unsafe" && imp != "C" {
+				external[imp] = true
+			}
+		}
+	}
+
+	// The dependencies outside the workspace are loaded by a single importer
+	// so that they have the same types in every package.
+	var base types.Importer
+	switch {
+	case o.importMode == ImportSource:
+		base = newSourceImporter(w.FileSet, dir, ctxt)
+	case o.cache != nil && o.importMode == ImportExportData:
+		base = o.cache.importer(dir, ctxt)
+	default:
+		paths := make([]string, 0, len(external))
+		for p := range external {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		imp := newGoListImporter(w.FileSet, dir, ctxt)
+		base = imp
+		if len(paths) > 0 {
+			if err := imp.list(paths...); err != nil {
+				if o.importMode == ImportExportData {
+					return nil, err
+				}
+				base = newSourceImporter(w.FileSet, dir, ctxt)
+			}
+		}
+	}
+	imp := &overrideImporter{base: base, pkgs: make(map[string]*types.Package, len(listed))}
+
+	// Type check the packages so that each is checked after the packages of
+	// the workspace that it imports.
+	state := make(map[string]int) // 1 while visiting, 2 once loaded
+	var load func(p *listedPackage) error
+	load = func(p *listedPackage) error {
+		switch state[p.ImportPath] {
+		case 1:
+			return fmt.Errorf("import cycle through %s", p.ImportPath)
+		case 2:
+			return nil
+		}
+		state[p.ImportPath] = 1
+		for _, path := range p.imports(o.tests) {
+			if dep, ok := byPath[path]; ok {
+				if err := load(dep); err != nil {
+					return err
+				}
+			}
+		}
+
+		fs := &FileSet{
+			Dir:      p.Dir,
+			FileSet:  w.FileSet,
+			opts:     o,
+			importer: imp,
+			base:     base,
+			pkgPath:  p.ImportPath,
+		}
+		files := p.GoFiles
+		if o.tests {
+			files = append(append([]string{}, files...), p.TestGoFiles...)
+		}
+		for _, f := range files {
+			name := filepath.Join(p.Dir, f)
+			af, err := parser.ParseFile(w.FileSet, name, nil, parser.ParseComments)
+			if err != nil {
+				return syntaxErrors(err, PhaseParse, make(sources))
+			}
+			fs.Files = append(fs.Files, name)
+			fs.AstFiles = append(fs.AstFiles, af)
+		}
+		if _, err := fs.Parse(); err != nil {
+			return fmt.Errorf("%s: %w", p.ImportPath, err)
+		}
+
+		imp.pkgs[p.ImportPath] = fs.Package
+		w.byPath[p.ImportPath] = fs
+		w.Packages = append(w.Packages, fs)
+		state[p.ImportPath] = 2
+		return nil
+	}
+	for _, p := range listed {
+		if err := load(p); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(w.Packages, func(i, j int) bool {
+		return w.Packages[i].Package.Path() < w.Packages[j].Package.Path()
+	})
+	if o.ssa && !w.hasErrors() {
+		prog, pkgs := newSSAProgram(w.FileSet, o.ssaMode, w.Packages)
+		prog.Build()
+		for i, fs := range w.Packages {
+			fs.ssa = pkgs[i]
+		}
+		w.ssa = prog
+	}
+	return w, nil
+}
+
+// hasErrors reports whether any package of the workspace has errors.
+func (w *Workspace) hasErrors() bool {
+	for _, fs := range w.Packages {
+		if len(fs.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SSA returns the SSA program holding the packages of the workspace, built
+// because the workspace was loaded with the WithSSA option, or nil if it was
+// not. Each package's SSA form is also available from FileSet.SSA. Functions
+// of one package of the workspace that call another refer to the same SSA
+// functions, so dataflow can be followed across the workspace.
+func (w *Workspace) SSA() *ssa.Program {
+	return w.ssa
+}
+
+// imports returns the paths imported by the package, including those of its
+// test files if tests is true.
+func (p *listedPackage) imports(tests bool) []string {
+	if !tests {
+		return p.Imports
+	}
+	return append(append([]string{}, p.Imports...), p.TestImports...)
+}
+
+// listPackages lists the packages matched by patterns in dir, sorted by
+// import path.
+func listPackages(dir string, o options, patterns []string) ([]*listedPackage, error) {
+	ctxt := o.buildContext()
+	args := []string{"list", "-json=Dir,ImportPath,GoFiles,TestGoFiles,Imports,TestImports,Error"}
+	if len(ctxt.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(ctxt.BuildTags, ","))
+	}
+	args = append(args, patterns...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "GOOS="+ctxt.GOOS, "GOARCH="+ctxt.GOARCH)
+	if !ctxt.CgoEnabled {
+		cmd.Env = append(cmd.Env, "CGO_ENABLED=0")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list %s: %w: %s", strings.Join(patterns, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	var listed []*listedPackage
+	dec := json.NewDecoder(&stdout)
+	for {
+		p := new(listedPackage)
+		if err := dec.Decode(p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+		if p.Error != nil {
+			return nil, fmt.Errorf("%s: %s", p.ImportPath, p.Error.Err)
+		}
+		if len(p.GoFiles) == 0 && (!o.tests || len(p.TestGoFiles) == 0) {
+			continue
+		}
+		listed = append(listed, p)
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].ImportPath < listed[j].ImportPath
+	})
+	return listed, nil
+}
+
+// Package returns the package of the workspace with the given import path.
+func (w *Workspace) Package(path string) (*FileSet, bool) {
+	fs, ok := w.byPath[path]
+	return fs, ok
+}
+
+// PackageOf returns the package of the workspace that declares obj.
+func (w *Workspace) PackageOf(obj types.Object) (*FileSet, bool) {
+	if obj == nil || obj.Pkg() == nil {
+		return nil, false
+	}
+	fs, ok := w.byPath[obj.Pkg().Path()]
+	if !ok || fs.Package != obj.Pkg() {
+		return nil, false
+	}
+	return fs, true
+}
+
+// Lookup resolves a reference to a package level declaration of the
+// workspace written as a qualified name, such as example.com/m/api.User, or
+// as a method, such as example.com/m/api.User.Validate.
+func (w *Workspace) Lookup(ref string) (types.Object, bool) {
+	// The package path may contain dots, so the name starts after the last
+	// slash.
+	slash := strings.LastIndex(ref, "/")
+	dot := strings.Index(ref[slash+1:], ".")
+	if dot < 0 {
+		return nil, false
+	}
+	path, name := ref[:slash+1+dot], ref[slash+1+dot+1:]
+	fs, ok := w.byPath[path]
+	if !ok {
+		return nil, false
+	}
+	typeName, method, isMethod := strings.Cut(name, ".")
+	obj := fs.Package.Scope().Lookup(typeName)
+	if obj == nil || !isMethod {
+		return obj, obj != nil
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, false
+	}
+	m, _, _ := types.LookupFieldOrMethod(types.NewPointer(obj.Type()), true, fs.Package, method)
+	if _, ok := m.(*types.Func); !ok {
+		return nil, false
+	}
+	return m, true
+}
+
+// Types returns the models of the package level named types declared in
+// every package of the workspace, ordered by package import path and then
+// as Types orders them.
+func (w *Workspace) Types() []*TypeModel {
+	var models []*TypeModel
+	for _, fs := range w.Packages {
+		models = append(models, fs.Types()...)
+	}
+	return models
+}
+
+// Implementers returns the named types declared at package level in every
+// package of the workspace that implement the named interface, ordered by
+// package import path and then as FileSet.Implementers orders them. The
+// interface may be named as for Lookup, such as example.com/m/api.Handler,
+// or by the import path or name of a package imported by the workspace, such
+// as io.Reader.
+func (w *Workspace) Implementers(interfaceName string) ([]Implementer, error) {
+	if len(w.Packages) == 0 {
+		return nil, fmt.Errorf("%s not found", interfaceName)
+	}
+	var iface *types.Interface
+	if obj, ok := w.Lookup(interfaceName); ok {
+		tn, ok := obj.(*types.TypeName)
+		if !ok || !types.IsInterface(tn.Type()) {
+			return nil, fmt.Errorf("%s is not an interface type", interfaceName)
+		}
+		iface = tn.Type().Underlying().(*types.Interface)
+	} else {
+		var err error
+		if iface, err = w.Packages[0].lookupInterface(interfaceName); err != nil {
+			return nil, err
+		}
+	}
+
+	var impls []Implementer
+	for _, fs := range w.Packages {
+		impls = append(impls, fs.implementers(iface)...)
+	}
+	return impls, nil
+}
+
+// Inspect traverses the syntax trees of every package of the workspace in
+// order, calling f as ast.Inspect does.
+func (w *Workspace) Inspect(f func(ast.Node) bool) {
+	for _, fs := range w.Packages {
+		fs.Inspect(f)
+	}
+}
@@ -0,0 +1,181 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestLoadWorkspace(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"store/store.go":  "package store\n\nimport (\n\t\"strings\"\n\n\t\"example.com/p/api\"\n)\n\ntype Store struct{ Users []api.User }\n\nfunc (s *Store) Save(u api.User) { u.Name = strings.TrimSpace(u.Name) }\n",
+		"api/api.go":      "package api\n\ntype User struct{ Name string }\n\nfunc (u *User) Validate() error { return nil }\n",
+		"api/api_test.go": "package api\n\ntype fixture struct{}\n",
+		"empty/doc.txt":   "not a package\n",
+	})
+
+	w, err := LoadWorkspace(dir, []string{"./..."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var paths []string
+	for _, fs := range w.Packages {
+		paths = append(paths, fs.Package.Path())
+		if fs.FileSet != w.FileSet {
+			t.Errorf("%s: FileSet not shared", fs.Package.Path())
+		}
+	}
+	if want := []string{"example.com/p/api", "example.com/p/store"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got packages %v, wanted %v", paths, want)
+	}
+
+	user, ok := w.Lookup("example.com/p/api.User")
+	if !ok {
+		t.Fatalf("api.User not found")
+	}
+	store, _ := w.Package("example.com/p/store")
+	tm, ok := store.Type("Store")
+	if !ok {
+		t.Fatalf("store.Store not found")
+	}
+	elem := tm.Fields[0].Type.(*types.Slice).Elem()
+	if !types.Identical(elem, user.Type()) {
+		t.Errorf("store's api.User is not identical to api's")
+	}
+
+	if fs, ok := w.PackageOf(user); !ok || fs.Package.Name() != "api" {
+		t.Errorf("got package %v, wanted api", fs)
+	}
+	if _, ok := w.Lookup("example.com/p/api.User.Validate"); !ok {
+		t.Errorf("method api.User.Validate not found")
+	}
+	for _, ref := range []string{"example.com/p/api.Missing", "example.com/p/api.User.Missing", "example.com/p/missing.User", "User"} {
+		if _, ok := w.Lookup(ref); ok {
+			t.Errorf("%s: found, wanted not found", ref)
+		}
+	}
+
+	var names []string
+	for _, tm := range w.Types() {
+		names = append(names, tm.Name)
+	}
+	if want := []string{"User", "Store"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got types %v, wanted %v", names, want)
+	}
+
+	w, err = LoadWorkspace(dir, []string{"./api"}, WithTests(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := w.Packages[0].Type("fixture"); !ok {
+		t.Errorf("test files not loaded with WithTests")
+	}
+
+	if _, err := LoadWorkspace(dir, []string{"./missing"}); err == nil {
+		t.Errorf("got no error for a pattern matching no packages, wanted one")
+	}
+}
+
+func TestWorkspaceImplementers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"api/api.go":       "package api\n\ntype Handler interface{ Handle() }\n\ntype Func func()\n\nfunc (f Func) Handle() { f() }\n",
+		"store/store.go":   "package store\n\nimport \"fmt\"\n\ntype Store struct{}\n\nfunc (*Store) Handle() {}\n\nfunc (*Store) String() string { return fmt.Sprint(\"store\") }\n",
+		"store/cache.go":   "package store\n\ntype cache int\n\nfunc (cache) Handle() {}\n",
+		"other/other.go":   "package other\n\ntype Other struct{}\n",
+		"other/handler.go": "package other\n\ntype Handler interface{ Handle() }\n",
+	})
+
+	w, err := LoadWorkspace(dir, []string{"./..."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		iface string
+		want  []string
+	}{
+		{iface: "example.com/p/api.Handler", want: []string{"api.Func", "store.cache", "*store.Store"}},
+		{iface: "fmt.Stringer", want: []string{"*store.Store"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.iface, func(t *testing.T) {
+			impls, err := w.Implementers(tc.iface)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, impl := range impls {
+				name := impl.Type.Object.Pkg().Name() + "." + impl.Type.Name
+				if impl.Pointer {
+					name = "*" + name
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+
+	for _, name := range []string{"example.com/p/api.Func", "example.com/p/api.Missing", "nosuchpkg.X"} {
+		if _, err := w.Implementers(name); err == nil {
+			t.Errorf("%s: got no error, wanted one", name)
+		}
+	}
+}
+
+func TestWorkspaceSSA(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that invokes the go command")
+	}
+
+	dir := writeTestModule(t, map[string]string{
+		"api/api.go":     "package api\n\nfunc Normalize(s string) string { return s }\n",
+		"store/store.go": "package store\n\nimport \"example.com/p/api\"\n\nfunc Save(s string) string { return api.Normalize(s) }\n",
+	})
+
+	w, err := LoadWorkspace(dir, []string{"./..."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.SSA() != nil {
+		t.Errorf("SSA built without WithSSA")
+	}
+
+	w, err = LoadWorkspace(dir, []string{"./..."}, WithSSA())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.SSA() == nil {
+		t.Fatalf("SSA not built with WithSSA")
+	}
+	api, _ := w.Package("example.com/p/api")
+	store, _ := w.Package("example.com/p/store")
+	if api.SSA() == nil || store.SSA() == nil || api.SSA().Prog != w.SSA() || store.SSA().Prog != w.SSA() {
+		t.Fatalf("packages not built in the workspace's SSA program")
+	}
+
+	// The call in store resolves to the function built from api's source.
+	var callee *ssa.Function
+	for _, b := range store.SSA().Func("Save").Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				callee = call.Call.StaticCallee()
+			}
+		}
+	}
+	if normalize := api.SSA().Func("Normalize"); callee == nil || callee != normalize || len(normalize.Blocks) == 0 {
+		t.Errorf("got callee %v, wanted api.Normalize with a body", callee)
+	}
+}
@@ -0,0 +1,152 @@
+package gen
+
+import (
+	"go/types"
+	"strings"
+)
+
+// ZeroValue returns a Go expression for the zero value of type t, suitable
+// for use as an argument, a return value or the right hand side of an
+// assignment to a variable of type t. Types are rendered using the qualifier
+// q, which may be nil.
+func ZeroValue(t types.Type, q types.Qualifier) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		default:
+			// unsafe.Pointer and untyped nil
+			return "nil"
+		}
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature:
+		return "nil"
+	case *types.Interface:
+		if _, isParam := t.(*types.TypeParam); isParam {
+			return "*new(" + types.TypeString(t, q) + ")"
+		}
+		return "nil"
+	case *types.Struct, *types.Array:
+		return types.TypeString(t, q) + "{}"
+	}
+	return "*new(" + types.TypeString(t, q) + ")"
+}
+
+// ZeroValueExpr returns a Go expression for the zero value of type t whose
+// type is t itself, unlike ZeroValue whose result may be an untyped constant
+// or nil that only takes on type t when assigned. The result may be used
+// where the type must be inferred from the expression, such as in a short
+// variable declaration or as an argument of type any: for a named type N
+// with underlying type int it is N(0), for []byte it is []byte(nil) and for
+// float64 it is float64(0). Types are rendered using the qualifier q, which
+// may be nil.
+func ZeroValueExpr(t types.Type, q types.Qualifier) string {
+	if _, isParam := t.(*types.TypeParam); isParam {
+		return ZeroValue(t, q)
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Kind() == types.UntypedNil {
+			return "nil"
+		}
+		for _, k := range []types.BasicKind{types.Int, types.String, types.Bool} {
+			if types.Identical(t, types.Typ[k]) {
+				return ZeroValue(t, q)
+			}
+		}
+		return conversion(t, ZeroValue(t, q), q)
+	case *types.Struct, *types.Array:
+		return ZeroValue(t, q)
+	}
+	return conversion(t, "nil", q)
+}
+
+// conversion returns the conversion of the expression x to type t.
+func conversion(t types.Type, x string, q types.Qualifier) string {
+	name := types.TypeString(t, q)
+	switch types.Unalias(t).(type) {
+	case *types.Pointer, *types.Chan, *types.Signature:
+		// Parenthesize types that would otherwise not parse as the operand
+		// of a conversion, such as *T and func().
+		name = "(" + name + ")"
+	}
+	return name + "(" + x + ")"
+}
+
+// CompositeLiteral returns a Go composite literal of type t with example
+// contents, suitable as a starting point in generated code such as tests and
+// builders. Each field of a struct is set to its zero value, or to a
+// composite literal for fields of struct, slice, array and map types, and
+// slices, arrays and maps have a single element. Pointers to structs are
+// written as &T{...}. Unexported fields are only included when the struct
+// is declared in the package that the qualifier q treats as local, that is,
+// for which q returns an empty name. A field whose named type is already
+// being written is set to its zero value so that recursive types terminate.
+// For types that have no composite literal, CompositeLiteral returns the
+// result of ZeroValueExpr.
+func CompositeLiteral(t types.Type, q types.Qualifier) string {
+	if lit, ok := compositeLiteral(t, q, make(map[*types.TypeName]bool)); ok {
+		return lit
+	}
+	return ZeroValueExpr(t, q)
+}
+
+// compositeLiteral returns a composite literal of type t, reporting false if
+// t has no composite literal. seen holds the named types being written.
+func compositeLiteral(t types.Type, q types.Qualifier, seen map[*types.TypeName]bool) (string, bool) {
+	if n, ok := types.Unalias(t).(*types.Named); ok {
+		if seen[n.Obj()] {
+			return "", false
+		}
+		seen[n.Obj()] = true
+		defer delete(seen, n.Obj())
+	}
+	// element returns the literal for an element or field of type t.
+	element := func(t types.Type) string {
+		if lit, ok := compositeLiteral(t, q, seen); ok {
+			return lit
+		}
+		return ZeroValue(t, q)
+	}
+
+	name := types.TypeString(t, q)
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		var fields []string
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if f.Name() == "_" || (!f.Exported() && !isLocal(f.Pkg(), q)) {
+				continue
+			}
+			fields = append(fields, f.Name()+": "+element(f.Type()))
+		}
+		return name + "{" + strings.Join(fields, ", ") + "}", true
+	case *types.Pointer:
+		if _, isStruct := u.Elem().Underlying().(*types.Struct); isStruct {
+			if lit, ok := compositeLiteral(u.Elem(), q, seen); ok {
+				return "&" + lit, true
+			}
+		}
+		return "", false
+	case *types.Slice:
+		return name + "{" + element(u.Elem()) + "}", true
+	case *types.Array:
+		if u.Len() == 0 {
+			return name + "{}", true
+		}
+		return name + "{" + element(u.Elem()) + "}", true
+	case *types.Map:
+		return name + "{" + ZeroValue(u.Key(), q) + ": " + element(u.Elem()) + "}", true
+	}
+	return "", false
+}
+
+// isLocal reports whether the qualifier q writes the names declared in pkg
+// without qualification.
+func isLocal(pkg *types.Package, q types.Qualifier) bool {
+	return pkg == nil || (q != nil && q(pkg) == "")
+}
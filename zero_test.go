@@ -0,0 +1,230 @@
+package gen
+
+import (
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestZeroValue(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "time"
+
+		type S struct{ A int }
+		type N int
+		type B bool
+		type Str string
+		type F func()
+		type I interface{ M() }
+
+		func G[T any, C comparable, P ~int | ~string]() {}
+
+		var (
+			a int
+			b float64
+			c string
+			d bool
+			e *S
+			f []int
+			g map[string]int
+			h chan int
+			i F
+			j I
+			k S
+			l [3]int
+			m N
+			n B
+			o Str
+			p error
+			q time.Time
+			r struct{ X int }
+			s time.Duration
+			u any
+		)
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[string]string{
+		"a": "0",
+		"b": "0",
+		"c": `""`,
+		"d": "false",
+		"e": "nil",
+		"f": "nil",
+		"g": "nil",
+		"h": "nil",
+		"i": "nil",
+		"j": "nil",
+		"k": "S{}",
+		"l": "[3]int{}",
+		"m": "0",
+		"n": "false",
+		"o": `""`,
+		"p": "nil",
+		"q": "time.Time{}",
+		"r": "struct{X int}{}",
+		"s": "0",
+		"u": "nil",
+	}
+
+	q := types.RelativeTo(fs.Package)
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := ZeroValue(fs.Lookup(name).Type(), q); got != want {
+				t.Errorf("got %q, wanted %q", got, want)
+			}
+		})
+	}
+
+	sig := fs.Lookup("G").Type().(*types.Signature)
+	for i, want := range []string{"*new(T)", "*new(C)", "*new(P)"} {
+		if got := ZeroValue(sig.TypeParams().At(i), q); got != want {
+			t.Errorf("type parameter %d: got %q, wanted %q", i, got, want)
+		}
+	}
+}
+
+func TestZeroValueExpr(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "time"
+
+		type S struct{ A int }
+		type N int
+		type Str string
+		type F func()
+		type I interface{ M() }
+		type P *S
+
+		var (
+			a int
+			b float64
+			c string
+			d bool
+			e *S
+			f []byte
+			g map[string]int
+			h chan int
+			i F
+			j I
+			k S
+			m N
+			o Str
+			p error
+			q time.Duration
+			r func(int) error
+			s P
+			u any
+			v byte
+		)
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[string]string{
+		"a": "0",
+		"b": "float64(0)",
+		"c": `""`,
+		"d": "false",
+		"e": "(*S)(nil)",
+		"f": "[]byte(nil)",
+		"g": "map[string]int(nil)",
+		"h": "(chan int)(nil)",
+		"i": "F(nil)",
+		"j": "I(nil)",
+		"k": "S{}",
+		"m": "N(0)",
+		"o": `Str("")`,
+		"p": "error(nil)",
+		"q": "time.Duration(0)",
+		"r": "(func(int) error)(nil)",
+		"s": "P(nil)",
+		"u": "any(nil)",
+		"v": "byte(0)",
+	}
+
+	q := types.RelativeTo(fs.Package)
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := ZeroValueExpr(fs.Lookup(name).Type(), q); got != want {
+				t.Errorf("got %q, wanted %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCompositeLiteral(t *testing.T) {
+	fs, err := NewFileSetFromTexts(`package p
+
+		import "time"
+
+		type User struct {
+			Name    string
+			Age     float64
+			Created time.Time
+			Tags    []string
+			Meta    map[string]*Meta
+			Parent  *User
+			Pair    [2]int
+			secret  string
+			_       int
+		}
+
+		type Meta struct {
+			Key   string
+			Items []Item
+		}
+
+		type Item struct{ N int }
+
+		type Node struct {
+			Children []Node
+		}
+
+		var (
+			a User
+			b *Meta
+			c []Item
+			d map[string]int
+			e int
+			f *int
+			g Node
+			h [0]int
+		)
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[string]string{
+		"a": `User{Name: "", Age: 0, Created: time.Time{}, Tags: []string{""}, ` +
+			`Meta: map[string]*Meta{"": &Meta{Key: "", Items: []Item{Item{N: 0}}}}, ` +
+			`Parent: nil, Pair: [2]int{0}, secret: ""}`,
+		"b": `&Meta{Key: "", Items: []Item{Item{N: 0}}}`,
+		"c": `[]Item{Item{N: 0}}`,
+		"d": `map[string]int{"": 0}`,
+		"e": "0",
+		"f": "(*int)(nil)",
+		"g": "Node{Children: []Node{Node{}}}",
+		"h": "[0]int{}",
+	}
+
+	q := types.RelativeTo(fs.Package)
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := CompositeLiteral(fs.Lookup(name).Type(), q); got != want {
+				t.Errorf("got %q, wanted %q", got, want)
+			}
+		})
+	}
+
+	// Unexported fields of structs from other packages are left out.
+	other := func(p *types.Package) string { return p.Name() }
+	if got, want := CompositeLiteral(fs.Lookup("a").Type(), other), "p.User{Name: "; got[:len(want)] != want || strings.Contains(got, "secret") {
+		t.Errorf("got %q, wanted literal without secret", got)
+	}
+}